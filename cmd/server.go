@@ -28,7 +28,9 @@ import (
 
 	"github.com/runatlantis/atlantis/server"
 	"github.com/runatlantis/atlantis/server/core/config/valid"
+	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/vcs"
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketcloud"
 	"github.com/runatlantis/atlantis/server/logging"
 )
@@ -50,22 +52,36 @@ const (
 	ADTokenFlag                      = "azuredevops-token" // nolint: gosec
 	ADUserFlag                       = "azuredevops-user"
 	ADHostnameFlag                   = "azuredevops-hostname"
+	ADHTTPProxyFlag                  = "azuredevops-http-proxy"
 	AllowCommandsFlag                = "allow-commands"
 	AllowForkPRsFlag                 = "allow-fork-prs"
 	AllowRepoConfigFlag              = "allow-repo-config"
+	AllowSkipStateLockFlag           = "allow-skip-state-lock"
+	ApplyAllowlistFlag               = "apply-allowlist"
+	ApplyFromPlanSnapshotFlag        = "apply-from-plan-snapshot"
+	ApplySummaryCommentFlag          = "apply-summary-comment"
+	ApplyTimeoutFlag                 = "apply-timeout-seconds"
 	AtlantisURLFlag                  = "atlantis-url"
+	AuditLogFileFlag                 = "audit-log-file"
+	AuditLogURLFlag                  = "audit-log-url"
 	AutomergeFlag                    = "automerge"
 	ParallelPlanFlag                 = "parallel-plan"
 	ParallelApplyFlag                = "parallel-apply"
 	AutoplanModules                  = "autoplan-modules"
 	AutoplanModulesFromProjects      = "autoplan-modules-from-projects"
 	AutoplanFileListFlag             = "autoplan-file-list"
+	AutoplanCommentNoProjectsFlag    = "autoplan-comment-no-projects"
+	AutoplanDebounceSecondsFlag      = "autoplan-debounce-seconds"
+	AutoplanLabelPrefixFlag          = "autoplan-label-prefix"
 	BitbucketBaseURLFlag             = "bitbucket-base-url"
+	BitbucketHTTPProxyFlag           = "bitbucket-http-proxy"
 	BitbucketTokenFlag               = "bitbucket-token"
 	BitbucketUserFlag                = "bitbucket-user"
 	BitbucketWebhookSecretFlag       = "bitbucket-webhook-secret"
 	CheckoutDepthFlag                = "checkout-depth"
 	CheckoutStrategyFlag             = "checkout-strategy"
+	CodeOwnersApprovedApplyFlag      = "codeowners-approved-apply"
+	CodeOwnersPlanCommentsFlag       = "codeowners-plan-comments"
 	ConfigFlag                       = "config"
 	DataDirFlag                      = "data-dir"
 	DefaultTFVersionFlag             = "default-tf-version"
@@ -77,15 +93,23 @@ const (
 	DisableRepoLockingFlag           = "disable-repo-locking"
 	DisableUnlockLabelFlag           = "disable-unlock-label"
 	DiscardApprovalOnPlanFlag        = "discard-approval-on-plan"
+	EditPlanCommentsFlag             = "edit-plan-comments"
 	EmojiReaction                    = "emoji-reaction"
+	EnableAdhocWorkspacesFlag        = "enable-adhoc-workspaces"
+	EnableFmtCheckFlag               = "enable-fmt-check"
 	EnablePolicyChecksFlag           = "enable-policy-checks"
 	EnableRegExpCmdFlag              = "enable-regexp-cmd"
 	EnableDiffMarkdownFormat         = "enable-diff-markdown-format"
 	ExecutableName                   = "executable-name"
+	FailOnCommentErrorFlag           = "fail-on-comment-error"
 	FailOnPreWorkflowHookError       = "fail-on-pre-workflow-hook-error"
 	HideUnchangedPlanComments        = "hide-unchanged-plan-comments"
+	GHAlternateBaseURLsFlag          = "gh-alternate-base-urls"
+	GHDeploymentTimeoutFlag          = "gh-deployment-timeout-seconds"
 	GHHostnameFlag                   = "gh-hostname"
+	GHHTTPProxyFlag                  = "gh-http-proxy"
 	GHTeamAllowlistFlag              = "gh-team-allowlist"
+	GHStatusTokenFlag                = "gh-status-token" // nolint: gosec
 	GHTokenFlag                      = "gh-token"
 	GHUserFlag                       = "gh-user"
 	GHAppIDFlag                      = "gh-app-id"
@@ -93,20 +117,43 @@ const (
 	GHAppKeyFileFlag                 = "gh-app-key-file"
 	GHAppSlugFlag                    = "gh-app-slug"
 	GHOrganizationFlag               = "gh-org"
+	GHPlanGistThresholdFlag          = "gh-plan-gist-threshold"
+	GHPlanReviewsFlag                = "gh-plan-reviews"
 	GHWebhookSecretFlag              = "gh-webhook-secret"               // nolint: gosec
 	GHAllowMergeableBypassApply      = "gh-allow-mergeable-bypass-apply" // nolint: gosec
+	GitCloneRetriesFlag              = "git-clone-retries"
+	GitCloneRetryDelayFlag           = "git-clone-retry-delay-seconds"
 	GitlabHostnameFlag               = "gitlab-hostname"
+	GitlabHTTPProxyFlag              = "gitlab-http-proxy"
+	GitlabStatusTokenFlag            = "gitlab-status-token" // nolint: gosec
 	GitlabTokenFlag                  = "gitlab-token"
 	GitlabUserFlag                   = "gitlab-user"
 	GitlabWebhookSecretFlag          = "gitlab-webhook-secret" // nolint: gosec
 	IncludeGitUntrackedFiles         = "include-git-untracked-files"
+	InitBackendArgsFlag              = "init-backend-args"
 	APISecretFlag                    = "api-secret"
 	HidePrevPlanComments             = "hide-prev-plan-comments"
 	QuietPolicyChecks                = "quiet-policy-checks"
+	LockCommentsFlag                 = "lock-comments"
 	LockingDBType                    = "locking-db-type"
 	LogLevelFlag                     = "log-level"
+	LogModuleLevelsFlag              = "log-module-levels"
 	MarkdownTemplateOverridesDirFlag = "markdown-template-overrides-dir"
+	MaxConcurrentClonesFlag          = "max-concurrent-clones"
+	MaxConcurrentPreWorkflowHooks    = "max-concurrent-pre-workflow-hooks"
+	MaxPlanAgeFlag                   = "max-plan-age"
+	MinimizePlanHeadersFlag          = "minimize-plan-headers"
 	ParallelPoolSize                 = "parallel-pool-size"
+	PlanEncryptionKeyFlag            = "plan-encryption-key"      // nolint: gosec
+	PlanEncryptionOldKeysFlag        = "plan-encryption-old-keys" // nolint: gosec
+	PlanCommentFooterFlag            = "plan-comment-footer"
+	PlanJSONFlag                     = "plan-json"
+	PlanRateLimitFlag                = "plan-rate-limit"
+	PlanTimeoutFlag                  = "plan-timeout-seconds"
+	PolicyCheckCommentOrderFlag      = "policy-check-comment-order"
+	PreWorkflowHookMaxOutputFlag     = "pre-workflow-hook-max-output-bytes"
+	PreWorkflowHookRetriesFlag       = "pre-workflow-hook-status-retries"
+	PreWorkflowHookRetryDelayFlag    = "pre-workflow-hook-status-retry-delay-seconds"
 	StatsNamespace                   = "stats-namespace"
 	AllowDraftPRs                    = "allow-draft-prs"
 	PortFlag                         = "port"
@@ -118,6 +165,8 @@ const (
 	RedisInsecureSkipVerify          = "redis-insecure-skip-verify"
 	RepoConfigFlag                   = "repo-config"
 	RepoConfigJSONFlag               = "repo-config-json"
+	RepoConfigFileFlag               = "repo-config-file"
+	UnauthorizedCommentContactFlag   = "unauthorized-comment-contact"
 	// RepoWhitelistFlag is deprecated for RepoAllowlistFlag.
 	RepoWhitelistFlag          = "repo-whitelist"
 	RepoAllowlistFlag          = "repo-allowlist"
@@ -132,11 +181,20 @@ const (
 	SSLCertFileFlag            = "ssl-cert-file"
 	SSLKeyFileFlag             = "ssl-key-file"
 	RestrictFileList           = "restrict-file-list"
+	RetryStalePlansFlag        = "retry-stale-plans"
+	ScheduledReplanCronFlag    = "scheduled-replan-cron"
+	ScheduledReplanLabelFlag   = "scheduled-replan-label"
 	TFDownloadFlag             = "tf-download"
 	TFDownloadURLFlag          = "tf-download-url"
+	TFEnvVarsFlag              = "tf-env-vars"
+	TFParallelismFlag          = "tf-parallelism"
+	TFPluginCacheDirFlag       = "tf-plugin-cache-dir"
+	UseTFInitCache             = "use-tf-init-cache"
 	UseTFPluginCache           = "use-tf-plugin-cache"
 	VarFileAllowlistFlag       = "var-file-allowlist"
+	VCSNoProxyFlag             = "vcs-no-proxy"
 	VCSStatusName              = "vcs-status-name"
+	TFEAPIRunsFlag             = "tfe-api-runs"
 	TFEHostnameFlag            = "tfe-hostname"
 	TFELocalExecutionModeFlag  = "tfe-local-execution-mode"
 	TFETokenFlag               = "tfe-token"
@@ -144,7 +202,14 @@ const (
 	WebBasicAuthFlag           = "web-basic-auth"
 	WebUsernameFlag            = "web-username"
 	WebPasswordFlag            = "web-password"
+	WebhookCaptureDirFlag      = "webhook-capture-dir"
+	WebhookCaptureMaxCountFlag = "webhook-capture-max-count"
 	WebsocketCheckOrigin       = "websocket-check-origin"
+	WorkflowHookShellArgsFlag  = "workflow-hook-shell-args"
+	WorkingDirLockScopeFlag    = "working-dir-lock-scope"
+	WorkingDirKeepCountFlag    = "working-dir-keep-count"
+	WorkingDirKeepHoursFlag    = "working-dir-keep-hours"
+	WorkingDirKeepOnFailure    = "working-dir-keep-on-failure"
 
 	// NOTE: Must manually set these as defaults in the setDefaults function.
 	DefaultADBasicUser                  = ""
@@ -159,17 +224,22 @@ const (
 	DefaultEmojiReaction                = "eyes"
 	DefaultExecutableName               = "atlantis"
 	DefaultMarkdownTemplateOverridesDir = "~/.markdown_templates"
+	DefaultGHDeploymentTimeout          = 1800
 	DefaultGHHostname                   = "github.com"
 	DefaultGitlabHostname               = "gitlab.com"
 	DefaultLockingDBType                = "boltdb"
 	DefaultLogLevel                     = "info"
 	DefaultParallelPoolSize             = 15
 	DefaultStatsNamespace               = "atlantis"
+	DefaultPolicyCheckCommentOrder      = string(events.AfterPlanPolicyCheckCommentOrder)
+	DefaultGitCloneRetryDelay           = 1
 	DefaultPort                         = 4141
+	DefaultPreWorkflowHookRetryDelay    = 1
 	DefaultRedisDB                      = 0
 	DefaultRedisPort                    = 6379
 	DefaultRedisTLSEnabled              = false
 	DefaultRedisInsecureSkipVerify      = false
+	DefaultRepoConfigFile               = valid.DefaultAtlantisFile
 	DefaultTFDownloadURL                = "https://releases.hashicorp.com"
 	DefaultTFDownload                   = true
 	DefaultTFEHostname                  = "app.terraform.io"
@@ -177,6 +247,8 @@ const (
 	DefaultWebBasicAuth                 = false
 	DefaultWebUsername                  = "atlantis"
 	DefaultWebPassword                  = "atlantis"
+	DefaultWebhookCaptureMaxCount       = 1000
+	DefaultWorkingDirLockScope          = string(events.PathWorkingDirLockScope)
 )
 
 var stringFlags = map[string]stringFlag{
@@ -202,13 +274,31 @@ var stringFlags = map[string]stringFlag{
 		description:  "Azure DevOps hostname to support cloud and self hosted instances.",
 		defaultValue: "dev.azure.com",
 	},
+	ADHTTPProxyFlag: {
+		description: "URL of the HTTP proxy to use for requests to Azure DevOps, ex. http://proxy.example.com:3128." +
+			" Defaults to the HTTPS_PROXY environment variable.",
+	},
 	AllowCommandsFlag: {
 		description:  "Comma separated list of acceptable atlantis commands.",
 		defaultValue: DefaultAllowCommands,
 	},
+	ApplyAllowlistFlag: {
+		description: "Comma separated list of users and teams (prefixed with 'team:') allowed to run apply, ex. 'alice,team:platform'. Defaults to everyone.",
+	},
+	ApplySummaryCommentFlag: {
+		description: fmt.Sprintf("Comment a summary of the result and changes of each project after applying more than one project. %q comments"+
+			" it after the output of the applies and %q comments it instead of their output, except the output of failed applies. Disabled by default.",
+			events.AppendApplySummaryComments, events.OnlyApplySummaryComments),
+	},
 	AtlantisURLFlag: {
 		description: "URL that Atlantis can be reached at. Defaults to http://$(hostname):$port where $port is from --" + PortFlag + ". Supports a base path ex. https://example.com/basepath.",
 	},
+	AuditLogFileFlag: {
+		description: "File to append an audit record of every command to, one JSON object per line.",
+	},
+	AuditLogURLFlag: {
+		description: "URL to post an audit record of every command to as JSON.",
+	},
 	AutoplanModulesFromProjects: {
 		description: "Comma separated list of file patterns to select projects Atlantis will index for module dependencies." +
 			" Indexed projects will automatically be planned if a module they depend on is modified." +
@@ -235,6 +325,10 @@ var stringFlags = map[string]stringFlag{
 			" If using Bitbucket Cloud (bitbucket.org), do not set.",
 		defaultValue: DefaultBitbucketBaseURL,
 	},
+	BitbucketHTTPProxyFlag: {
+		description: "URL of the HTTP proxy to use for requests to Bitbucket, ex. http://proxy.example.com:3128." +
+			" Defaults to the HTTPS_PROXY environment variable.",
+	},
 	BitbucketWebhookSecretFlag: {
 		description: "Secret used to validate Bitbucket webhooks. Only Bitbucket Server supports webhook secrets." +
 			" SECURITY WARNING: If not specified, Atlantis won't be able to validate that the incoming webhook call came from Bitbucket. " +
@@ -250,6 +344,11 @@ var stringFlags = map[string]stringFlag{
 			" after the pull request is merged.",
 		defaultValue: "branch",
 	},
+	CodeOwnersPlanCommentsFlag: {
+		description: fmt.Sprintf("How plan comments use the owners of projects in the repo's CODEOWNERS file. %q groups the projects"+
+			" in the plan comment by their owners and %q comments the plans of each owners' projects separately. Disabled by default.",
+			events.GroupCodeOwnersComments, events.SplitCodeOwnersComments),
+	},
 	ConfigFlag: {
 		description: "Path to yaml config file where flag values can also be set.",
 	},
@@ -257,6 +356,11 @@ var stringFlags = map[string]stringFlag{
 		description:  "Path to directory to store Atlantis data.",
 		defaultValue: DefaultDataDir,
 	},
+	AutoplanLabelPrefixFlag: {
+		description: "Prefix of pull request labels that select the projects to autoplan, ex. 'atlantis/plan:' for labels like 'atlantis/plan:networking'." +
+			" Labels name the project or, for projects without a name, its dir. Other projects are left to be planned with a plan comment." +
+			" If a pull request has no such labels, all modified projects are autoplanned.",
+	},
 	DisableAutoplanLabelFlag: {
 		description:  "Pull request label to disable atlantis auto planning feature only if present.",
 		defaultValue: "",
@@ -273,6 +377,14 @@ var stringFlags = map[string]stringFlag{
 		description:  "Comment command executable name.",
 		defaultValue: DefaultExecutableName,
 	},
+	GHHTTPProxyFlag: {
+		description: "URL of the HTTP proxy to use for requests to GitHub, ex. http://proxy.example.com:3128." +
+			" Defaults to the HTTPS_PROXY environment variable.",
+	},
+	GHAlternateBaseURLsFlag: {
+		description: "Comma separated list of the base URLs of other GitHub Enterprise instances, ex. 'https://ghe2.example.com'." +
+			" Repos whose server-side repo config sets vcs_base_url to one of them use that instance with the same --" + GHUserFlag + " and --" + GHTokenFlag + ".",
+	},
 	GHHostnameFlag: {
 		description:  "Hostname of your Github Enterprise installation. If using github.com, no need to set.",
 		defaultValue: DefaultGHHostname,
@@ -292,6 +404,10 @@ var stringFlags = map[string]stringFlag{
 		description:  "GitHub username of API user.",
 		defaultValue: "",
 	},
+	GHStatusTokenFlag: {
+		description: "GitHub token used instead of --" + GHTokenFlag + " or the GitHub App to update commit statuses, so they're rate limited separately from comments." +
+			" Can also be specified via the ATLANTIS_GH_STATUS_TOKEN environment variable.",
+	},
 	GHTokenFlag: {
 		description: "GitHub token of API user. Can also be specified via the ATLANTIS_GH_TOKEN environment variable.",
 	},
@@ -316,6 +432,10 @@ var stringFlags = map[string]stringFlag{
 			"This means that an attacker could spoof calls to Atlantis and cause it to perform malicious actions. " +
 			"Should be specified via the ATLANTIS_GH_WEBHOOK_SECRET environment variable.",
 	},
+	GitlabHTTPProxyFlag: {
+		description: "URL of the HTTP proxy to use for requests to GitLab, ex. http://proxy.example.com:3128." +
+			" Defaults to the HTTPS_PROXY environment variable.",
+	},
 	GitlabHostnameFlag: {
 		description:  "Hostname of your GitLab Enterprise installation. If using gitlab.com, no need to set.",
 		defaultValue: DefaultGitlabHostname,
@@ -323,6 +443,10 @@ var stringFlags = map[string]stringFlag{
 	GitlabUserFlag: {
 		description: "GitLab username of API user.",
 	},
+	GitlabStatusTokenFlag: {
+		description: "GitLab token used instead of --" + GitlabTokenFlag + " to update commit statuses, so they're rate limited separately from comments." +
+			" Can also be specified via the ATLANTIS_GITLAB_STATUS_TOKEN environment variable.",
+	},
 	GitlabTokenFlag: {
 		description: "GitLab token of API user. Can also be specified via the ATLANTIS_GITLAB_TOKEN environment variable.",
 	},
@@ -335,6 +459,10 @@ var stringFlags = map[string]stringFlag{
 	APISecretFlag: {
 		description: "Secret used to validate requests made to the /api/* endpoints",
 	},
+	InitBackendArgsFlag: {
+		description: "JSON object mapping a Terraform backend type to the args to add to terraform init for projects using that backend," +
+			` ex. '{"s3": ["-backend-config=backend/s3.hcl"]}'. Args from the workflow's init step override them.`,
+	},
 	LockingDBType: {
 		description:  "The locking database type to use for storing plan and apply locks.",
 		defaultValue: DefaultLockingDBType,
@@ -343,10 +471,43 @@ var stringFlags = map[string]stringFlag{
 		description:  "Log level. Either debug, info, warn, or error.",
 		defaultValue: DefaultLogLevel,
 	},
+	LogModuleLevelsFlag: {
+		description: "Comma-separated list of module=level pairs overriding --" + LogLevelFlag + " for those modules, ex. 'events=debug,vcs=warn'. Modules are 'events' and 'vcs'.",
+	},
 	MarkdownTemplateOverridesDirFlag: {
 		description:  "Directory for custom overrides to the markdown templates used for comments.",
 		defaultValue: DefaultMarkdownTemplateOverridesDir,
 	},
+	MaxPlanAgeFlag: {
+		description: "Max age of a plan that can be applied, ex. '24h'. Applying an older plan fails and asks for the project to be planned again." +
+			" Defaults to no max age.",
+	},
+	ScheduledReplanCronFlag: {
+		description: "Cron schedule, ex. '0 2 * * *', on which open pull requests that have been planned are planned again to catch drift." +
+			" Uses the server's time zone. Defaults to never.",
+	},
+	ScheduledReplanLabelFlag: {
+		description: "Only plan pull requests with this label on --" + ScheduledReplanCronFlag + ". Defaults to all pull requests.",
+	},
+	WorkflowHookShellArgsFlag: {
+		description: "Shell args used to run pre and post workflow hooks that don't set shellArgs. Defaults to '-c'.",
+	},
+	PlanEncryptionKeyFlag: {
+		description: "Key used to encrypt planfiles stored on disk. Planfiles are decrypted transparently before they're used." +
+			" If not set, planfiles aren't encrypted." +
+			" Should be specified via the ATLANTIS_PLAN_ENCRYPTION_KEY environment variable for security.",
+	},
+	PlanEncryptionOldKeysFlag: {
+		description: "Comma-separated list of previous --" + PlanEncryptionKeyFlag + " values." +
+			" Planfiles encrypted with one of these keys can still be decrypted and are re-encrypted with the current key." +
+			" Should be specified via the ATLANTIS_PLAN_ENCRYPTION_OLD_KEYS environment variable for security.",
+	},
+	PolicyCheckCommentOrderFlag: {
+		description: fmt.Sprintf("Where policy check results are commented relative to the plan comment. One of %q (in a comment after it),"+
+			" %q (in a comment before it) or %q (below each project's plan in the plan comment).",
+			events.AfterPlanPolicyCheckCommentOrder, events.BeforePlanPolicyCheckCommentOrder, events.MergedPolicyCheckCommentOrder),
+		defaultValue: DefaultPolicyCheckCommentOrder,
+	},
 	StatsNamespace: {
 		description:  "Namespace for aggregating stats.",
 		defaultValue: DefaultStatsNamespace,
@@ -363,6 +524,11 @@ var stringFlags = map[string]stringFlag{
 	RepoConfigJSONFlag: {
 		description: "Specify repo config as a JSON string. Useful if you don't want to write a config file to disk.",
 	},
+	RepoConfigFileFlag: {
+		description: "Path of the repo-level config file relative to the root of each repo, ex. 'infra/atlantis.yaml'." +
+			" Sets the default of repo_config_file in the server-side repo config, a repo that sets repo_config_file uses its own file.",
+		defaultValue: DefaultRepoConfigFile,
+	},
 	RepoAllowlistFlag: {
 		description: "Comma separated list of repositories that Atlantis will operate on. " +
 			"The format is {hostname}/{owner}/{repo}, ex. github.com/runatlantis/atlantis. '*' matches any characters until the next comma. Examples: " +
@@ -386,6 +552,14 @@ var stringFlags = map[string]stringFlag{
 		description:  "Base URL to download Terraform versions from.",
 		defaultValue: DefaultTFDownloadURL,
 	},
+	TFEnvVarsFlag: {
+		description: `JSON object of environment variables to set for every step run for projects, ex. '{"TF_IN_AUTOMATION": "1"}'.` +
+			" Variables set by the workflow's env and multienv steps override them.",
+	},
+	TFPluginCacheDirFlag: {
+		description: "Directory Terraform caches providers in when --" + UseTFPluginCache + " is set, ex. a persistent volume so providers aren't downloaded again after restarts." +
+			" Defaults to the plugin-cache dir inside --" + DataDirFlag + ".",
+	},
 	TFEHostnameFlag: {
 		description:  "Hostname of your Terraform Enterprise installation. If using Terraform Cloud no need to set.",
 		defaultValue: DefaultTFEHostname,
@@ -399,10 +573,19 @@ var stringFlags = map[string]stringFlag{
 		description: "Terraform version to default to (ex. v0.12.0). Will download if not yet on disk." +
 			" If not set, Atlantis uses the terraform binary in its PATH.",
 	},
+	UnauthorizedCommentContactFlag: {
+		description: "Who users are told to contact to get access when they aren't allowed to run a command, ex. @org/platform-team." +
+			" The comment can be customized with the unauthorized template in --markdown-template-overrides-dir.",
+	},
 	VarFileAllowlistFlag: {
 		description: "Comma-separated list of additional paths where variable definition files can be read from." +
 			" If this argument is not provided, it defaults to Atlantis' data directory, determined by the --data-dir argument.",
 	},
+	VCSNoProxyFlag: {
+		description: "Comma separated list of hosts, domains and CIDRs that VCS requests are made to directly, ignoring the" +
+			" --gh-http-proxy, --gitlab-http-proxy, --bitbucket-http-proxy and --azuredevops-http-proxy flags." +
+			" Uses the same format as the NO_PROXY environment variable, which it defaults to.",
+	},
 	VCSStatusName: {
 		description:  "Name used to identify Atlantis for pull request statuses.",
 		defaultValue: DefaultVCSStatusName,
@@ -415,6 +598,17 @@ var stringFlags = map[string]stringFlag{
 		description:  "Password used for Web Basic Authentication on Atlantis HTTP Middleware",
 		defaultValue: DefaultWebPassword,
 	},
+	WebhookCaptureDirFlag: {
+		description: "Directory to store received webhooks in, with secrets scrubbed, to debug how they were handled." +
+			" Only webhooks that pass validation are stored. Stored webhooks can be replayed as a dry run with" +
+			" POST /events/replay?id=<webhook id>, which requires --api-secret.",
+	},
+	WorkingDirLockScopeFlag: {
+		description: fmt.Sprintf("How much of a pull request's working dir a command locks while it runs. One of %q (the whole pull request),"+
+			" %q (all projects in a workspace) or %q (each dir and workspace, the default).",
+			events.RepoWorkingDirLockScope, events.WorkspaceWorkingDirLockScope, events.PathWorkingDirLockScope),
+		defaultValue: DefaultWorkingDirLockScope,
+	},
 }
 
 var boolFlags = map[string]boolFlag{
@@ -422,6 +616,11 @@ var boolFlags = map[string]boolFlag{
 		description:  "Allow Atlantis to run on pull requests from forks. A security issue for public repos.",
 		defaultValue: false,
 	},
+	AllowSkipStateLockFlag: {
+		description: "Allow users to apply without locking the Terraform state with 'atlantis apply --skip-state-lock' or '-- -lock=false'," +
+			" ex. to recover from a stale lock. Two applies running at once can corrupt the state.",
+		defaultValue: false,
+	},
 	AllowRepoConfigFlag: {
 		description: "Allow repositories to use atlantis.yaml files to customize the commands Atlantis runs." +
 			" Should only be enabled in a trusted environment since it enables a pull request to run arbitrary commands" +
@@ -429,14 +628,33 @@ var boolFlags = map[string]boolFlag{
 		defaultValue: false,
 		hidden:       true,
 	},
+	PlanJSONFlag: {
+		description: "Store the JSON of each plan, from 'terraform show -json', and link to it from the plan comment" +
+			" so tools can download it. It's deleted when the pull request is closed.",
+		defaultValue: false,
+	},
+	ApplyFromPlanSnapshotFlag: {
+		description: "Copy the working dir of each project once it's planned and apply the project from that copy," +
+			" so it's applied with exactly the files it was planned with even if the clone changed since.",
+		defaultValue: false,
+	},
 	AutoplanModules: {
 		description:  "Automatically plan projects that have a changed module from the local repository.",
 		defaultValue: false,
 	},
+	AutoplanCommentNoProjectsFlag: {
+		description:  "Comment on pull requests when autoplan finds no Terraform projects to plan. By default Atlantis doesn't comment.",
+		defaultValue: false,
+	},
 	AutomergeFlag: {
 		description:  "Automatically merge pull requests when all plans are successfully applied.",
 		defaultValue: false,
 	},
+	CodeOwnersApprovedApplyFlag: {
+		description: "Only apply projects owned, in the base branch's CODEOWNERS file, by a user who approved the pull request." +
+			" Projects without owners are applied as usual. GitHub only.",
+		defaultValue: false,
+	},
 	DisableApplyAllFlag: {
 		description:  "Disable \"atlantis apply\" command without any flags (i.e. apply all). A specific project/workspace/directory has to be specified for applies.",
 		defaultValue: false,
@@ -456,6 +674,19 @@ var boolFlags = map[string]boolFlag{
 		description:  "Enables the discarding of approval if a new plan has been executed. Currently only Github is supported",
 		defaultValue: false,
 	},
+	EditPlanCommentsFlag: {
+		description: "Edit the previous plan comment in place when the same projects are planned again instead of adding a new comment. " +
+			"VCS support is limited to: GitHub, GitLab.",
+		defaultValue: false,
+	},
+	EnableAdhocWorkspacesFlag: {
+		description:  "Allow running commands in workspaces that aren't configured for the dir in atlantis.yaml, ex. \"atlantis plan -d infra -w staging\". The dir's project config is used for the workspace.",
+		defaultValue: false,
+	},
+	EnableFmtCheckFlag: {
+		description:  "Run terraform fmt -check on each project before planning it and fail the plan if files aren't formatted. The check can be skipped with \"atlantis plan --skip-fmt-check\".",
+		defaultValue: false,
+	},
 	EnablePolicyChecksFlag: {
 		description:  "Enable atlantis to run user defined policy checks.  This is explicitly disabled for TFE/TFC backends since plan files are inaccessible.",
 		defaultValue: false,
@@ -468,6 +699,10 @@ var boolFlags = map[string]boolFlag{
 		description:  "Enable Atlantis to format Terraform plan output into a markdown-diff friendly format for color-coding purposes.",
 		defaultValue: false,
 	},
+	FailOnCommentErrorFlag: {
+		description:  "Fail the commit status of a command if its results can't be commented on the pull request. By default commenting is best-effort.",
+		defaultValue: false,
+	},
 	FailOnPreWorkflowHookError: {
 		description:  "Fail and do not run the requested Atlantis command if any of the pre workflow hooks error.",
 		defaultValue: false,
@@ -476,6 +711,10 @@ var boolFlags = map[string]boolFlag{
 		description:  "Feature flag to enable functionality to allow mergeable check to ignore apply required check",
 		defaultValue: false,
 	},
+	GHPlanReviewsFlag: {
+		description:  "Post the plan results of all projects on GitHub pull requests as a single review that comments instead of as a comment.",
+		defaultValue: false,
+	},
 	AllowDraftPRs: {
 		description:  "Enable autoplan for Github Draft Pull Requests",
 		defaultValue: false,
@@ -489,6 +728,10 @@ var boolFlags = map[string]boolFlag{
 		description:  "Include git untracked files in the Atlantis modified file scope.",
 		defaultValue: false,
 	},
+	LockCommentsFlag: {
+		description:  "Comment on pull requests, with timestamps, when Atlantis acquires and releases their project locks.",
+		defaultValue: false,
+	},
 	ParallelPlanFlag: {
 		description:  "Run plan operations in parallel.",
 		defaultValue: false,
@@ -539,6 +782,15 @@ var boolFlags = map[string]boolFlag{
 		description:  "Toggle off folding in markdown output.",
 		defaultValue: false,
 	},
+	MinimizePlanHeadersFlag: {
+		description:  "Replace the \"Ran Plan for ...\" headers of plan comments with each project's name and change summary.",
+		defaultValue: false,
+	},
+	PlanCommentFooterFlag: {
+		description: "End plan comments with a footer showing the versions of Atlantis and Terraform and how long the plans took." +
+			" Its planCommentFooter template can be overridden with --" + MarkdownTemplateOverridesDirFlag + ".",
+		defaultValue: false,
+	},
 	WriteGitCredsFlag: {
 		description: "Write out a .git-credentials file with the provider user and token to allow cloning private modules over HTTPS or SSH." +
 			" This writes secrets to disk and should only be enabled in a secure environment.",
@@ -552,6 +804,11 @@ var boolFlags = map[string]boolFlag{
 		description:  "Allow Atlantis to list & download Terraform versions. Setting this to false can be helpful in air-gapped environments.",
 		defaultValue: DefaultTFDownload,
 	},
+	TFEAPIRunsFlag: {
+		description: "Plan and apply projects that use a cloud block or remote backend with runs driven through the Terraform Cloud/Enterprise API" +
+			" instead of the terraform CLI. Requires --" + TFETokenFlag + ".",
+		defaultValue: false,
+	},
 	TFELocalExecutionModeFlag: {
 		description:  "Enable if you're using local execution mode (instead of TFE/C's remote execution mode).",
 		defaultValue: false,
@@ -564,6 +821,10 @@ var boolFlags = map[string]boolFlag{
 		description:  "Block plan requests from projects outside the files modified in the pull request.",
 		defaultValue: false,
 	},
+	RetryStalePlansFlag: {
+		description:  "Plan and apply again, once, projects whose apply failed because their plan was stale.",
+		defaultValue: false,
+	},
 	WebsocketCheckOrigin: {
 		description:  "Enable websocket origin check",
 		defaultValue: false,
@@ -572,22 +833,93 @@ var boolFlags = map[string]boolFlag{
 		description:  "Remove no-changes plan comments from the pull request.",
 		defaultValue: false,
 	},
+	UseTFInitCache: {
+		description:  "Skip terraform init for projects that were already initialized with the same backend and module configuration and committed lock file.",
+		defaultValue: false,
+	},
 	UseTFPluginCache: {
 		description:  "Enable the use of the Terraform plugin cache",
 		defaultValue: true,
 	},
+	WorkingDirKeepOnFailure: {
+		description: "Keep the working dirs of pull requests with a failed project when --" + WorkingDirKeepCountFlag +
+			" or --" + WorkingDirKeepHoursFlag + " would delete them.",
+		defaultValue: false,
+	},
 }
 var intFlags = map[string]intFlag{
+	ApplyTimeoutFlag: {
+		description: "Seconds each process run for an apply, ex. terraform apply, may run before it's interrupted and the apply fails." +
+			" Projects can override this with apply_timeout_seconds. Defaults to 0 which means unlimited.",
+		defaultValue: 0,
+	},
+	AutoplanDebounceSecondsFlag: {
+		description: "Seconds to wait after a pull request is pushed to before autoplanning it. Pushes within this window" +
+			" are coalesced so only the latest commit is planned and a running autoplan is canceled. Defaults to 0 which disables debouncing.",
+		defaultValue: 0,
+	},
 	CheckoutDepthFlag: {
-		description: fmt.Sprintf("Used only if --%s=%s.", CheckoutStrategyFlag, CheckoutStrategyMerge) +
+		description: fmt.Sprintf("Used only if --%s=%s or when planning a previous commit.", CheckoutStrategyFlag, CheckoutStrategyMerge) +
 			" How many commits to include in each of base and feature branches when cloning repository." +
-			" If merge base is further behind than this number of commits from any of branches heads, full fetch will be performed.",
+			" If merge base or the previous commit is further behind than this number of commits from any of branches heads, full fetch will be performed.",
 		defaultValue: DefaultCheckoutDepth,
 	},
+	GHDeploymentTimeoutFlag: {
+		description: "Seconds to wait for a GitHub deployment to a project's deployment_environment to be approved before" +
+			" aborting the apply.",
+		defaultValue: DefaultGHDeploymentTimeout,
+	},
+	GHPlanGistThresholdFlag: {
+		description: "Length in characters above which plan output is uploaded to a secret GitHub gist that the comment links to" +
+			" instead of being commented. Requires a GitHub token with the gist scope. Defaults to 0 which disables this.",
+		defaultValue: 0,
+	},
+	GitCloneRetriesFlag: {
+		description: "Number of times to retry a git clone that failed with a transient error, ex. the git server timing out." +
+			" Authentication errors aren't retried.",
+		defaultValue: 0,
+	},
+	GitCloneRetryDelayFlag: {
+		description:  "Seconds to wait before the first git clone retry. The delay doubles after each retry.",
+		defaultValue: DefaultGitCloneRetryDelay,
+	},
+	MaxConcurrentClonesFlag: {
+		description: "Max number of git clones that can run at the same time across all pull requests, ex. to avoid exhausting IO" +
+			" when many pull requests are updated at once. Further clones wait for a slot. Defaults to 0 which means unlimited.",
+		defaultValue: 0,
+	},
+	MaxConcurrentPreWorkflowHooks: {
+		description: "Max number of pre workflow hook runs that can execute at the same time across all pull requests." +
+			" Further runs wait for a slot. Defaults to 0 which means unlimited.",
+		defaultValue: 0,
+	},
 	ParallelPoolSize: {
 		description:  "Max size of the wait group that runs parallel plans and applies (if enabled).",
 		defaultValue: DefaultParallelPoolSize,
 	},
+	PlanRateLimitFlag: {
+		description: "Max number of plans per minute for each repo. Plans over the limit are rejected with a comment" +
+			" saying when to plan again. Defaults to 0 which means unlimited.",
+		defaultValue: 0,
+	},
+	PlanTimeoutFlag: {
+		description: "Seconds each process run for a plan, ex. terraform plan, may run before it's interrupted and the plan fails." +
+			" Projects can override this with plan_timeout_seconds. Defaults to 0 which means unlimited.",
+		defaultValue: 0,
+	},
+	PreWorkflowHookMaxOutputFlag: {
+		description: "Max number of bytes of output captured from each pre workflow hook run. Further output is discarded" +
+			" and the hook's commit status notes it was truncated. Defaults to 0 which means unlimited.",
+		defaultValue: 0,
+	},
+	PreWorkflowHookRetriesFlag: {
+		description:  "Number of times to retry a failed pre workflow hook commit status update before failing the hook.",
+		defaultValue: 0,
+	},
+	PreWorkflowHookRetryDelayFlag: {
+		description:  "Seconds to wait between pre workflow hook commit status update retries.",
+		defaultValue: DefaultPreWorkflowHookRetryDelay,
+	},
 	PortFlag: {
 		description:  "Port to bind to.",
 		defaultValue: DefaultPort,
@@ -600,6 +932,25 @@ var intFlags = map[string]intFlag{
 		description:  "The Redis Port for when using a Locking DB type of 'redis'.",
 		defaultValue: DefaultRedisPort,
 	},
+	TFParallelismFlag: {
+		description: "Value of -parallelism passed to terraform plan and apply. Projects can override this with terraform_parallelism." +
+			" Defaults to 0 which means Terraform's default.",
+		defaultValue: 0,
+	},
+	WebhookCaptureMaxCountFlag: {
+		description:  fmt.Sprintf("Number of the most recent webhooks to keep in --%s. Older ones are deleted.", WebhookCaptureDirFlag),
+		defaultValue: DefaultWebhookCaptureMaxCount,
+	},
+	WorkingDirKeepCountFlag: {
+		description: "Number of the most recently used pull request working dirs to keep. Older ones are deleted periodically" +
+			" unless their pull request holds locks. Defaults to 0 which means unlimited.",
+		defaultValue: 0,
+	},
+	WorkingDirKeepHoursFlag: {
+		description: "Hours to keep a pull request's working dir after it was last used. Older ones are deleted periodically" +
+			" unless their pull request holds locks. Defaults to 0 which means forever.",
+		defaultValue: 0,
+	},
 }
 
 var int64Flags = map[string]int64Flag{
@@ -841,18 +1192,33 @@ func (s *ServerCmd) setDefaults(c *server.UserConfig) {
 	if c.LockingDBType == "" {
 		c.LockingDBType = DefaultLockingDBType
 	}
+	if c.RepoConfigFile == "" {
+		c.RepoConfigFile = DefaultRepoConfigFile
+	}
 	if c.LogLevel == "" {
 		c.LogLevel = DefaultLogLevel
 	}
 	if c.MarkdownTemplateOverridesDir == "" {
 		c.MarkdownTemplateOverridesDir = DefaultMarkdownTemplateOverridesDir
 	}
+	if c.GithubDeploymentTimeout == 0 {
+		c.GithubDeploymentTimeout = DefaultGHDeploymentTimeout
+	}
 	if c.ParallelPoolSize == 0 {
 		c.ParallelPoolSize = DefaultParallelPoolSize
 	}
 	if c.StatsNamespace == "" {
 		c.StatsNamespace = DefaultStatsNamespace
 	}
+	if c.GitCloneRetryDelay == 0 {
+		c.GitCloneRetryDelay = DefaultGitCloneRetryDelay
+	}
+	if c.PreWorkflowHookStatusRetryDelay == 0 {
+		c.PreWorkflowHookStatusRetryDelay = DefaultPreWorkflowHookRetryDelay
+	}
+	if c.PolicyCheckCommentOrder == "" {
+		c.PolicyCheckCommentOrder = DefaultPolicyCheckCommentOrder
+	}
 	if c.Port == 0 {
 		c.Port = DefaultPort
 	}
@@ -877,6 +1243,12 @@ func (s *ServerCmd) setDefaults(c *server.UserConfig) {
 	if c.WebPassword == "" {
 		c.WebPassword = DefaultWebPassword
 	}
+	if c.WorkingDirLockScope == "" {
+		c.WorkingDirLockScope = DefaultWorkingDirLockScope
+	}
+	if c.WebhookCaptureMaxCount == 0 {
+		c.WebhookCaptureMaxCount = DefaultWebhookCaptureMaxCount
+	}
 }
 
 func (s *ServerCmd) validate(userConfig server.UserConfig) error {
@@ -891,6 +1263,34 @@ func (s *ServerCmd) validate(userConfig server.UserConfig) error {
 			CheckoutStrategyBranch, CheckoutStrategyMerge)
 	}
 
+	switch events.CodeOwnersComments(userConfig.CodeOwnersPlanComments) {
+	case "", events.GroupCodeOwnersComments, events.SplitCodeOwnersComments:
+	default:
+		return fmt.Errorf("invalid --%s %q: not one of %s or %s", CodeOwnersPlanCommentsFlag, userConfig.CodeOwnersPlanComments,
+			events.GroupCodeOwnersComments, events.SplitCodeOwnersComments)
+	}
+
+	switch events.ApplySummaryComments(userConfig.ApplySummaryComment) {
+	case "", events.AppendApplySummaryComments, events.OnlyApplySummaryComments:
+	default:
+		return fmt.Errorf("invalid --%s %q: not one of %s or %s", ApplySummaryCommentFlag, userConfig.ApplySummaryComment,
+			events.AppendApplySummaryComments, events.OnlyApplySummaryComments)
+	}
+
+	switch events.PolicyCheckCommentOrder(userConfig.PolicyCheckCommentOrder) {
+	case events.AfterPlanPolicyCheckCommentOrder, events.BeforePlanPolicyCheckCommentOrder, events.MergedPolicyCheckCommentOrder:
+	default:
+		return fmt.Errorf("invalid --%s %q: not one of %s, %s or %s", PolicyCheckCommentOrderFlag, userConfig.PolicyCheckCommentOrder,
+			events.AfterPlanPolicyCheckCommentOrder, events.BeforePlanPolicyCheckCommentOrder, events.MergedPolicyCheckCommentOrder)
+	}
+
+	switch events.WorkingDirLockScope(userConfig.WorkingDirLockScope) {
+	case events.RepoWorkingDirLockScope, events.WorkspaceWorkingDirLockScope, events.PathWorkingDirLockScope:
+	default:
+		return fmt.Errorf("invalid --%s %q: not one of %s, %s or %s", WorkingDirLockScopeFlag, userConfig.WorkingDirLockScope,
+			events.RepoWorkingDirLockScope, events.WorkspaceWorkingDirLockScope, events.PathWorkingDirLockScope)
+	}
+
 	if (userConfig.SSLKeyFile == "") != (userConfig.SSLCertFile == "") {
 		return fmt.Errorf("--%s and --%s are both required for ssl", SSLKeyFileFlag, SSLCertFileFlag)
 	}
@@ -944,15 +1344,43 @@ func (s *ServerCmd) validate(userConfig server.UserConfig) error {
 		return fmt.Errorf("--%s must have http:// or https://, got %q", BitbucketBaseURLFlag, userConfig.BitbucketBaseURL)
 	}
 
+	if userConfig.AuditLogURL != "" {
+		parsed, err := url.Parse(userConfig.AuditLogURL)
+		if err != nil {
+			return fmt.Errorf("error parsing --%s flag value %q: %s", AuditLogURLFlag, userConfig.AuditLogURL, err)
+		}
+		if parsed.Scheme != "http" && parsed.Scheme != "https" {
+			return fmt.Errorf("--%s must have http:// or https://, got %q", AuditLogURLFlag, userConfig.AuditLogURL)
+		}
+	}
+
+	for flag, proxyURL := range map[string]string{
+		ADHTTPProxyFlag:        userConfig.AzureDevopsHTTPProxy,
+		BitbucketHTTPProxyFlag: userConfig.BitbucketHTTPProxy,
+		GHHTTPProxyFlag:        userConfig.GithubHTTPProxy,
+		GitlabHTTPProxyFlag:    userConfig.GitlabHTTPProxy,
+	} {
+		if _, err := (vcs.ProxyConfig{URL: proxyURL}).Transport(); err != nil {
+			return errors.Wrapf(err, "invalid --%s", flag)
+		}
+	}
+
 	if userConfig.RepoConfig != "" && userConfig.RepoConfigJSON != "" {
 		return fmt.Errorf("cannot use --%s and --%s at the same time", RepoConfigFlag, RepoConfigJSONFlag)
 	}
 
+	// The file is read from inside each cloned repo so it can't escape it.
+	if filepath.IsAbs(userConfig.RepoConfigFile) || strings.Contains(userConfig.RepoConfigFile, "../") || strings.Contains(userConfig.RepoConfigFile, "..\\") {
+		return fmt.Errorf("--%s must be a path relative to the root of the repo, got %q", RepoConfigFileFlag, userConfig.RepoConfigFile)
+	}
+
 	// Warn if any tokens have newlines.
 	for name, token := range map[string]string{
 		GHTokenFlag:                userConfig.GithubToken,
+		GHStatusTokenFlag:          userConfig.GithubStatusToken,
 		GHWebhookSecretFlag:        userConfig.GithubWebhookSecret,
 		GitlabTokenFlag:            userConfig.GitlabToken,
+		GitlabStatusTokenFlag:      userConfig.GitlabStatusToken,
 		GitlabWebhookSecretFlag:    userConfig.GitlabWebhookSecret,
 		BitbucketTokenFlag:         userConfig.BitbucketToken,
 		BitbucketWebhookSecretFlag: userConfig.BitbucketWebhookSecret,
@@ -966,6 +1394,95 @@ func (s *ServerCmd) validate(userConfig server.UserConfig) error {
 		return fmt.Errorf("if setting --%s, must set --%s", TFEHostnameFlag, TFETokenFlag)
 	}
 
+	if userConfig.GithubAlternateBaseURLs != "" {
+		if userConfig.GithubUser == "" {
+			return fmt.Errorf("if setting --%s, must set --%s", GHAlternateBaseURLsFlag, GHUserFlag)
+		}
+		for _, baseURL := range strings.Split(userConfig.GithubAlternateBaseURLs, ",") {
+			parsed, err := url.Parse(strings.TrimSpace(baseURL))
+			if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+				return fmt.Errorf("--%s must contain https:// URLs, got %q", GHAlternateBaseURLsFlag, baseURL)
+			}
+		}
+	}
+
+	if userConfig.GithubStatusToken != "" && userConfig.GithubUser == "" && userConfig.GithubAppID == 0 {
+		return fmt.Errorf("if setting --%s, must set --%s or --%s", GHStatusTokenFlag, GHUserFlag, GHAppIDFlag)
+	}
+	if userConfig.GitlabStatusToken != "" && userConfig.GitlabUser == "" {
+		return fmt.Errorf("if setting --%s, must set --%s", GitlabStatusTokenFlag, GitlabUserFlag)
+	}
+
+	if userConfig.TFEAPIRuns && userConfig.TFEToken == "" {
+		return fmt.Errorf("if setting --%s, must set --%s", TFEAPIRunsFlag, TFETokenFlag)
+	}
+	if userConfig.TFEAPIRuns && userConfig.TFELocalExecutionMode {
+		return fmt.Errorf("--%s can't be used with --%s", TFEAPIRunsFlag, TFELocalExecutionModeFlag)
+	}
+
+	if userConfig.ApplyTimeoutSeconds < 0 {
+		return fmt.Errorf("--%s must not be negative", ApplyTimeoutFlag)
+	}
+
+	if userConfig.WorkingDirKeepCount < 0 {
+		return fmt.Errorf("--%s must not be negative", WorkingDirKeepCountFlag)
+	}
+
+	if userConfig.WorkingDirKeepHours < 0 {
+		return fmt.Errorf("--%s must not be negative", WorkingDirKeepHoursFlag)
+	}
+
+	if userConfig.PlanTimeoutSeconds < 0 {
+		return fmt.Errorf("--%s must not be negative", PlanTimeoutFlag)
+	}
+
+	if userConfig.PlanRateLimit < 0 {
+		return fmt.Errorf("--%s must not be negative", PlanRateLimitFlag)
+	}
+
+	if userConfig.TFParallelism < 0 {
+		return fmt.Errorf("--%s must not be negative", TFParallelismFlag)
+	}
+
+	if userConfig.AutoplanDebounceSeconds < 0 {
+		return fmt.Errorf("--%s must not be negative", AutoplanDebounceSecondsFlag)
+	}
+
+	if userConfig.GithubDeploymentTimeout < 0 {
+		return fmt.Errorf("--%s must not be negative", GHDeploymentTimeoutFlag)
+	}
+
+	if userConfig.GithubPlanGistThreshold < 0 {
+		return fmt.Errorf("--%s must not be negative", GHPlanGistThresholdFlag)
+	}
+
+	if userConfig.MaxConcurrentClones < 0 {
+		return fmt.Errorf("--%s must not be negative", MaxConcurrentClonesFlag)
+	}
+	if userConfig.MaxConcurrentPreWorkflowHooks < 0 {
+		return fmt.Errorf("--%s must not be negative", MaxConcurrentPreWorkflowHooks)
+	}
+
+	if userConfig.PreWorkflowHookMaxOutputBytes < 0 {
+		return fmt.Errorf("--%s must not be negative", PreWorkflowHookMaxOutputFlag)
+	}
+
+	if userConfig.GitCloneRetries < 0 {
+		return fmt.Errorf("--%s must not be negative", GitCloneRetriesFlag)
+	}
+
+	if userConfig.GitCloneRetryDelay < 0 {
+		return fmt.Errorf("--%s must not be negative", GitCloneRetryDelayFlag)
+	}
+
+	if userConfig.PreWorkflowHookStatusRetries < 0 {
+		return fmt.Errorf("--%s must not be negative", PreWorkflowHookRetriesFlag)
+	}
+
+	if userConfig.PreWorkflowHookStatusRetryDelay < 0 {
+		return fmt.Errorf("--%s must not be negative", PreWorkflowHookRetryDelayFlag)
+	}
+
 	_, patternErr := patternmatcher.New(strings.Split(userConfig.AutoplanFileList, ","))
 	if patternErr != nil {
 		return errors.Wrapf(patternErr, "invalid pattern in --%s, %s", AutoplanFileListFlag, userConfig.AutoplanFileList)
@@ -975,6 +1492,26 @@ func (s *ServerCmd) validate(userConfig server.UserConfig) error {
 		return errors.Wrapf(err, "invalid --%s", AllowCommandsFlag)
 	}
 
+	if _, err := userConfig.ToInitBackendArgs(); err != nil {
+		return errors.Wrapf(err, "invalid --%s", InitBackendArgsFlag)
+	}
+
+	if _, err := userConfig.ToMaxPlanAge(); err != nil {
+		return errors.Wrapf(err, "invalid --%s", MaxPlanAgeFlag)
+	}
+
+	if _, err := userConfig.ToScheduledReplanCron(); err != nil {
+		return errors.Wrapf(err, "invalid --%s", ScheduledReplanCronFlag)
+	}
+
+	if _, err := userConfig.ToTFEnvVars(); err != nil {
+		return errors.Wrapf(err, "invalid --%s", TFEnvVarsFlag)
+	}
+
+	if _, err := userConfig.ToLogModuleLevels(); err != nil {
+		return errors.Wrapf(err, "invalid --%s", LogModuleLevelsFlag)
+	}
+
 	return nil
 }
 
@@ -54,19 +54,33 @@ func (s *ServerStarterMock) Start() error {
 var testFlags = map[string]interface{}{
 	ADTokenFlag:                      "ad-token",
 	ADUserFlag:                       "ad-user",
+	ADHTTPProxyFlag:                  "http://ad-proxy:3128",
 	ADWebhookPasswordFlag:            "ad-wh-pass",
 	ADWebhookUserFlag:                "ad-wh-user",
 	AtlantisURLFlag:                  "url",
+	AuditLogFileFlag:                 "/var/log/atlantis/audit.log",
+	AuditLogURLFlag:                  "https://audit.example.com/atlantis",
 	AllowCommandsFlag:                "version,plan,unlock,import,approve_policies", // apply is disabled by DisableApply
 	AllowForkPRsFlag:                 true,
 	AllowRepoConfigFlag:              true,
+	AllowSkipStateLockFlag:           true,
+	ApplyAllowlistFlag:               "alice,team:platform",
+	ApplyFromPlanSnapshotFlag:        true,
+	ApplySummaryCommentFlag:          "append",
+	ApplyTimeoutFlag:                 3600,
 	AutomergeFlag:                    true,
 	AutoplanFileListFlag:             "**/*.tf,**/*.yml",
+	AutoplanCommentNoProjectsFlag:    true,
+	AutoplanDebounceSecondsFlag:      30,
+	AutoplanLabelPrefixFlag:          "atlantis/plan:",
 	BitbucketBaseURLFlag:             "https://bitbucket-base-url.com",
+	BitbucketHTTPProxyFlag:           "http://bitbucket-proxy:3128",
 	BitbucketTokenFlag:               "bitbucket-token",
 	BitbucketUserFlag:                "bitbucket-user",
 	BitbucketWebhookSecretFlag:       "bitbucket-secret",
 	CheckoutStrategyFlag:             CheckoutStrategyMerge,
+	CodeOwnersApprovedApplyFlag:      true,
+	CodeOwnersPlanCommentsFlag:       "group",
 	DataDirFlag:                      "/path",
 	DefaultTFVersionFlag:             "v0.11.0",
 	DisableApplyAllFlag:              true,
@@ -74,7 +88,11 @@ var testFlags = map[string]interface{}{
 	DisableMarkdownFoldingFlag:       true,
 	DisableRepoLockingFlag:           true,
 	DiscardApprovalOnPlanFlag:        true,
+	EditPlanCommentsFlag:             true,
+	GHAlternateBaseURLsFlag:          "https://ghe2.example.com",
 	GHHostnameFlag:                   "ghhostname",
+	GHHTTPProxyFlag:                  "http://gh-proxy:3128",
+	GHStatusTokenFlag:                "status-token",
 	GHTokenFlag:                      "token",
 	GHUserFlag:                       "user",
 	GHAppIDFlag:                      int64(0),
@@ -82,21 +100,46 @@ var testFlags = map[string]interface{}{
 	GHAppKeyFileFlag:                 "",
 	GHAppSlugFlag:                    "atlantis",
 	GHOrganizationFlag:               "",
+	GHDeploymentTimeoutFlag:          600,
+	GHPlanGistThresholdFlag:          50000,
+	GHPlanReviewsFlag:                true,
 	GHWebhookSecretFlag:              "secret",
+	GitCloneRetriesFlag:              3,
+	GitCloneRetryDelayFlag:           2,
 	GitlabHostnameFlag:               "gitlab-hostname",
+	GitlabHTTPProxyFlag:              "http://gitlab-proxy:3128",
+	GitlabStatusTokenFlag:            "gitlab-status-token",
 	GitlabTokenFlag:                  "gitlab-token",
 	GitlabUserFlag:                   "gitlab-user",
 	GitlabWebhookSecretFlag:          "gitlab-secret",
+	LockCommentsFlag:                 true,
 	LockingDBType:                    "boltdb",
+	InitBackendArgsFlag:              `{"s3": ["-reconfigure"]}`,
 	LogLevelFlag:                     "debug",
+	LogModuleLevelsFlag:              "events=debug",
 	MarkdownTemplateOverridesDirFlag: "/path2",
+	MaxConcurrentClonesFlag:          4,
+	MinimizePlanHeadersFlag:          true,
+	MaxConcurrentPreWorkflowHooks:    5,
+	MaxPlanAgeFlag:                   "24h",
 	StatsNamespace:                   "atlantis",
 	AllowDraftPRs:                    true,
 	PortFlag:                         8181,
 	ParallelPoolSize:                 100,
+	PreWorkflowHookMaxOutputFlag:     1024,
+	PreWorkflowHookRetriesFlag:       3,
+	PreWorkflowHookRetryDelayFlag:    2,
 	ParallelPlanFlag:                 true,
 	ParallelApplyFlag:                true,
+	PlanEncryptionKeyFlag:            "plan-key",
+	PlanTimeoutFlag:                  1800,
+	PlanEncryptionOldKeysFlag:        "old-key1,old-key2",
+	PlanCommentFooterFlag:            true,
+	PlanJSONFlag:                     true,
+	PlanRateLimitFlag:                10,
+	PolicyCheckCommentOrderFlag:      "merged",
 	RepoAllowlistFlag:                "github.com/runatlantis/atlantis",
+	RepoConfigFileFlag:               "infra/atlantis.yaml",
 	RequireApprovalFlag:              true,
 	RequireMergeableFlag:             true,
 	SilenceNoProjectsFlag:            false,
@@ -108,18 +151,38 @@ var testFlags = map[string]interface{}{
 	SSLCertFileFlag:                  "cert-file",
 	SSLKeyFileFlag:                   "key-file",
 	RestrictFileList:                 false,
+	RetryStalePlansFlag:              true,
+	ScheduledReplanCronFlag:          "0 2 * * 1-5",
+	ScheduledReplanLabelFlag:         "nightly-replan",
 	TFDownloadURLFlag:                "https://my-hostname.com",
+	TFEnvVarsFlag:                    `{"TF_IN_AUTOMATION": "1"}`,
+	TFParallelismFlag:                5,
+	TFPluginCacheDirFlag:             "/path/to/plugin-cache",
+	TFEAPIRunsFlag:                   false,
 	TFEHostnameFlag:                  "my-hostname",
 	TFELocalExecutionModeFlag:        true,
 	TFETokenFlag:                     "my-token",
+	UnauthorizedCommentContactFlag:   "@org/platform-team",
+	VCSNoProxyFlag:                   "internal.example.com,10.0.0.0/8",
 	VCSStatusName:                    "my-status",
+	WebhookCaptureDirFlag:            "/tmp/webhooks",
+	WebhookCaptureMaxCountFlag:       50,
 	WriteGitCredsFlag:                true,
+	WorkingDirLockScopeFlag:          "workspace",
+	WorkingDirKeepCountFlag:          20,
+	WorkingDirKeepHoursFlag:          168,
+	WorkingDirKeepOnFailure:          true,
+	WorkflowHookShellArgsFlag:        "-ec",
+	UseTFInitCache:                   true,
 	DisableAutoplanFlag:              true,
 	DisableAutoplanLabelFlag:         "no-auto-plan",
 	DisableUnlockLabelFlag:           "do-not-unlock",
+	EnableAdhocWorkspacesFlag:        true,
+	EnableFmtCheckFlag:               true,
 	EnablePolicyChecksFlag:           false,
 	EnableRegExpCmdFlag:              false,
 	EnableDiffMarkdownFormat:         false,
+	FailOnCommentErrorFlag:           true,
 }
 
 func TestExecute_Defaults(t *testing.T) {
@@ -311,6 +374,22 @@ func TestExecute_ValidateLogLevel(t *testing.T) {
 	}
 }
 
+func TestExecute_ValidateLogModuleLevels(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		LogModuleLevelsFlag: "events=verbose",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, `invalid --log-module-levels: invalid log level "verbose" for module "events": must be one of debug, info, warn or error`, err)
+}
+
+func TestExecute_ValidateLogModuleLevelsModule(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		LogModuleLevelsFlag: "vcs=debug,github=debug",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, `invalid --log-module-levels: unknown module "github": must be one of events or vcs`, err)
+}
+
 func TestExecute_ValidateCheckoutStrategy(t *testing.T) {
 	c := setupWithDefaults(map[string]interface{}{
 		CheckoutStrategyFlag: "invalid",
@@ -319,6 +398,239 @@ func TestExecute_ValidateCheckoutStrategy(t *testing.T) {
 	ErrEquals(t, "invalid checkout strategy: not one of branch or merge", err)
 }
 
+func TestExecute_ValidateWorkingDirKeep(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		WorkingDirKeepCountFlag: -1,
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--working-dir-keep-count must not be negative", err)
+
+	c = setupWithDefaults(map[string]interface{}{
+		WorkingDirKeepHoursFlag: -1,
+	}, t)
+	err = c.Execute()
+	ErrEquals(t, "--working-dir-keep-hours must not be negative", err)
+}
+
+func TestExecute_ValidateTimeouts(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		ApplyTimeoutFlag: -1,
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--apply-timeout-seconds must not be negative", err)
+
+	c = setupWithDefaults(map[string]interface{}{
+		PlanTimeoutFlag: -1,
+	}, t)
+	err = c.Execute()
+	ErrEquals(t, "--plan-timeout-seconds must not be negative", err)
+}
+
+func TestExecute_ValidatePlanRateLimit(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		PlanRateLimitFlag: -1,
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--plan-rate-limit must not be negative", err)
+}
+
+func TestExecute_ValidateTFParallelism(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		TFParallelismFlag: -1,
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--tf-parallelism must not be negative", err)
+}
+
+func TestExecute_ValidateAutoplanDebounceSeconds(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		AutoplanDebounceSecondsFlag: -1,
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--autoplan-debounce-seconds must not be negative", err)
+}
+
+func TestExecute_ValidateGHDeploymentTimeout(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		GHDeploymentTimeoutFlag: -1,
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--gh-deployment-timeout-seconds must not be negative", err)
+}
+
+func TestExecute_ValidateInitBackendArgs(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		InitBackendArgsFlag: `["-reconfigure"]`,
+	}, t)
+	err := c.Execute()
+	ErrContains(t, "invalid --init-backend-args: must be a JSON object of backend types to lists of args", err)
+}
+
+func TestExecute_ValidateMaxPlanAge(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		MaxPlanAgeFlag: "1 day",
+	}, t)
+	err := c.Execute()
+	ErrContains(t, "invalid --max-plan-age: must be a duration, ex. '24h'", err)
+}
+
+func TestExecute_ValidateScheduledReplanCron(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		ScheduledReplanCronFlag: "0 25 * * *",
+	}, t)
+	err := c.Execute()
+	ErrContains(t, `invalid --scheduled-replan-cron: hour: "25" is out of the range 0-23`, err)
+}
+
+func TestExecute_ValidateTFEnvVars(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		TFEnvVarsFlag: `{"TF_LOG": 1}`,
+	}, t)
+	err := c.Execute()
+	ErrContains(t, "invalid --tf-env-vars: must be a JSON object of environment variable names to string values", err)
+}
+
+func TestExecute_ValidateGHPlanGistThreshold(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		GHPlanGistThresholdFlag: -1,
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--gh-plan-gist-threshold must not be negative", err)
+}
+
+func TestExecute_ValidateWorkingDirLockScope(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		WorkingDirLockScopeFlag: "project",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, `invalid --working-dir-lock-scope "project": not one of repo, workspace or path`, err)
+}
+
+func TestExecute_ValidatePolicyCheckCommentOrder(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		PolicyCheckCommentOrderFlag: "first",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, `invalid --policy-check-comment-order "first": not one of after, before or merged`, err)
+}
+
+func TestExecute_ValidateCodeOwnersPlanComments(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		CodeOwnersPlanCommentsFlag: "teams",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, `invalid --codeowners-plan-comments "teams": not one of group or split`, err)
+}
+
+func TestExecute_ValidateApplySummaryComment(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		ApplySummaryCommentFlag: "always",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, `invalid --apply-summary-comment "always": not one of append or only`, err)
+}
+
+func TestExecute_ValidateMaxConcurrentClones(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		MaxConcurrentClonesFlag: -1,
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--max-concurrent-clones must not be negative", err)
+}
+
+func TestExecute_ValidateMaxConcurrentPreWorkflowHooks(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		MaxConcurrentPreWorkflowHooks: -1,
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--max-concurrent-pre-workflow-hooks must not be negative", err)
+}
+
+func TestExecute_ValidatePreWorkflowHookMaxOutputBytes(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		PreWorkflowHookMaxOutputFlag: -1,
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--pre-workflow-hook-max-output-bytes must not be negative", err)
+}
+
+func TestExecute_ValidateGitCloneRetries(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		GitCloneRetriesFlag: -1,
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--git-clone-retries must not be negative", err)
+
+	c = setupWithDefaults(map[string]interface{}{
+		GitCloneRetryDelayFlag: -1,
+	}, t)
+	err = c.Execute()
+	ErrEquals(t, "--git-clone-retry-delay-seconds must not be negative", err)
+}
+
+func TestExecute_ValidatePreWorkflowHookStatusRetries(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		PreWorkflowHookRetriesFlag: -1,
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "--pre-workflow-hook-status-retries must not be negative", err)
+
+	c = setupWithDefaults(map[string]interface{}{
+		PreWorkflowHookRetryDelayFlag: -1,
+	}, t)
+	err = c.Execute()
+	ErrEquals(t, "--pre-workflow-hook-status-retry-delay-seconds must not be negative", err)
+}
+
+func TestExecute_ValidateTFEAPIRuns(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		TFEAPIRunsFlag: true,
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "if setting --tfe-api-runs, must set --tfe-token", err)
+
+	c = setupWithDefaults(map[string]interface{}{
+		TFEAPIRunsFlag:            true,
+		TFETokenFlag:              "token",
+		TFELocalExecutionModeFlag: true,
+	}, t)
+	err = c.Execute()
+	ErrEquals(t, "--tfe-api-runs can't be used with --tfe-local-execution-mode", err)
+}
+
+func TestExecute_ValidateStatusTokens(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		GitlabStatusTokenFlag: "status-token",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, "if setting --gitlab-status-token, must set --gitlab-user", err)
+
+	c = setupWithDefaults(map[string]interface{}{
+		GHStatusTokenFlag: "status-token",
+	}, t)
+	Ok(t, c.Execute())
+}
+
+func TestExecute_ValidateVCSHTTPProxy(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		GitlabHTTPProxyFlag: "proxy.example.com:3128",
+	}, t)
+	err := c.Execute()
+	ErrEquals(t, `invalid --gitlab-http-proxy: proxy URL "proxy.example.com:3128" must start with http://, https:// or socks5://`, err)
+}
+
+func TestExecute_ValidateRepoConfigFile(t *testing.T) {
+	for _, path := range []string{"/etc/atlantis.yaml", "../atlantis.yaml", "infra/../../atlantis.yaml"} {
+		t.Run(path, func(t *testing.T) {
+			c := setupWithDefaults(map[string]interface{}{
+				RepoConfigFileFlag: path,
+			}, t)
+			err := c.Execute()
+			ErrEquals(t, fmt.Sprintf("--repo-config-file must be a path relative to the root of the repo, got %q", path), err)
+		})
+	}
+}
+
 func TestExecute_ValidateSSLConfig(t *testing.T) {
 	expErr := "--ssl-key-file and --ssl-cert-file are both required for ssl"
 	cases := []struct {
@@ -715,6 +1027,28 @@ func TestExecute_BitbucketServerBaseURLScheme(t *testing.T) {
 	ErrEquals(t, "error parsing --bitbucket-webhook-secret flag value \"://mydomain.com\": parse \"://mydomain.com\": missing protocol scheme", c.Execute())
 }
 
+func TestExecute_AuditLogURLScheme(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		AuditLogURLFlag: "audit.example.com",
+	}, t)
+	ErrEquals(t, "--audit-log-url must have http:// or https://, got \"audit.example.com\"", c.Execute())
+}
+
+func TestExecute_GHAlternateBaseURLs(t *testing.T) {
+	c := setup(map[string]interface{}{
+		GHAppIDFlag:             1,
+		GHAppKeyFileFlag:        "key.pem",
+		RepoAllowlistFlag:       "*",
+		GHAlternateBaseURLsFlag: "https://ghe2.example.com",
+	}, t)
+	ErrEquals(t, "if setting --gh-alternate-base-urls, must set --gh-user", c.Execute())
+
+	c = setupWithDefaults(map[string]interface{}{
+		GHAlternateBaseURLsFlag: "https://ghe2.example.com,ghe3.example.com",
+	}, t)
+	ErrEquals(t, "--gh-alternate-base-urls must contain https:// URLs, got \"ghe3.example.com\"", c.Execute())
+}
+
 // Port should be retained on base url.
 func TestExecute_BitbucketServerBaseURLPort(t *testing.T) {
 	c := setup(map[string]interface{}{
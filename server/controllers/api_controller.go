@@ -21,6 +21,7 @@ const atlantisTokenHeader = "X-Atlantis-Token"
 
 type APIController struct {
 	APISecret                 []byte
+	ApplyLocker               locking.ApplyLocker
 	Locker                    locking.Locker
 	Logger                    logging.SimpleLogging
 	Parser                    events.EventParsing
@@ -30,6 +31,12 @@ type APIController struct {
 	RepoAllowlistChecker      *events.RepoAllowlistChecker
 	Scope                     tally.Scope
 	VCSClient                 vcs.Client
+	// CodeOwnersApplyFilter only applies the projects whose owners in
+	// CODEOWNERS approved the pull request. It's nil if that's disabled.
+	CodeOwnersApplyFilter *events.CodeOwnersApplyFilter
+	// AuditLogger records each plan and apply in an audit trail. It's nil if
+	// that's disabled.
+	AuditLogger *events.AuditLogger
 }
 
 type APIRequest struct {
@@ -44,6 +51,12 @@ type APIRequest struct {
 	}
 }
 
+// APIApplyLockRequest is the optional body of a request to pause applies.
+type APIApplyLockRequest struct {
+	// Message is shown to users whose applies are rejected while paused.
+	Message string
+}
+
 func (a *APIRequest) getCommands(ctx *command.Context, cmdBuilder func(*command.Context, *events.CommentCommand) ([]command.ProjectContext, error)) ([]command.ProjectContext, error) {
 	cc := make([]*events.CommentCommand, 0)
 
@@ -89,10 +102,12 @@ func (a *APIController) Plan(w http.ResponseWriter, r *http.Request) {
 
 	result, err := a.apiPlan(request, ctx)
 	if err != nil {
+		a.audit(ctx, command.Plan, command.Result{Error: err})
 		a.apiReportError(w, http.StatusInternalServerError, err)
 		return
 	}
 	defer a.Locker.UnlockByPull(ctx.HeadRepo.FullName, 0) // nolint: errcheck
+	a.audit(ctx, command.Plan, *result)
 	if result.HasErrors() {
 		code = http.StatusInternalServerError
 	}
@@ -115,9 +130,27 @@ func (a *APIController) Apply(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if a.ApplyLocker != nil {
+		lock, err := a.ApplyLocker.CheckApplyLock()
+		if err != nil {
+			a.apiReportError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if lock.Locked {
+			err = fmt.Errorf("applies are disabled globally")
+			if lock.Message != "" {
+				err = fmt.Errorf("%s: %s", err, lock.Message)
+			}
+			a.audit(ctx, command.Apply, command.Result{Failure: err.Error()})
+			a.apiReportError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+	}
+
 	// We must first make the plan for all projects
 	_, err = a.apiPlan(request, ctx)
 	if err != nil {
+		a.audit(ctx, command.Apply, command.Result{Error: err})
 		a.apiReportError(w, http.StatusInternalServerError, err)
 		return
 	}
@@ -126,9 +159,11 @@ func (a *APIController) Apply(w http.ResponseWriter, r *http.Request) {
 	// We can now prepare and run the apply step
 	result, err := a.apiApply(request, ctx)
 	if err != nil {
+		a.audit(ctx, command.Apply, command.Result{Error: err})
 		a.apiReportError(w, http.StatusInternalServerError, err)
 		return
 	}
+	a.audit(ctx, command.Apply, *result)
 	if result.HasErrors() {
 		code = http.StatusInternalServerError
 	}
@@ -141,6 +176,72 @@ func (a *APIController) Apply(w http.ResponseWriter, r *http.Request) {
 	a.respond(w, logging.Debug, code, string(response))
 }
 
+// LockApply pauses all applies until UnlockApply is called. Plans still work.
+func (a *APIController) LockApply(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if code, err := a.apiValidateSecret(r); err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	if a.ApplyLocker == nil {
+		a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("apply locking is not configured"))
+		return
+	}
+
+	var request APIApplyLockRequest
+	bytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("failed to read request"))
+		return
+	}
+	if len(bytes) > 0 {
+		if err = json.Unmarshal(bytes, &request); err != nil {
+			a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("failed to parse request: %v", err.Error()))
+			return
+		}
+	}
+
+	lock, err := a.ApplyLocker.LockApply(request.Message)
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, fmt.Errorf("failed to lock apply: %v", err))
+		return
+	}
+
+	response, err := json.Marshal(lock)
+	if err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, err)
+		return
+	}
+	a.respond(w, logging.Info, http.StatusOK, string(response))
+}
+
+// UnlockApply resumes applies paused by LockApply.
+func (a *APIController) UnlockApply(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if code, err := a.apiValidateSecret(r); err != nil {
+		a.apiReportError(w, code, err)
+		return
+	}
+	if a.ApplyLocker == nil {
+		a.apiReportError(w, http.StatusBadRequest, fmt.Errorf("apply locking is not configured"))
+		return
+	}
+
+	if err := a.ApplyLocker.UnlockApply(); err != nil {
+		a.apiReportError(w, http.StatusInternalServerError, fmt.Errorf("failed to unlock apply: %v", err))
+		return
+	}
+	a.respond(w, logging.Info, http.StatusOK, "{}")
+}
+
+// audit records the API command cmdName, whose result is res, in the audit
+// trail.
+func (a *APIController) audit(ctx *command.Context, cmdName command.Name, res command.Result) {
+	a.AuditLogger.Record(ctx, &events.CommentCommand{Name: cmdName}, res)
+}
+
 func (a *APIController) apiPlan(request *APIRequest, ctx *command.Context) (*command.Result, error) {
 	cmds, err := request.getCommands(ctx, a.ProjectCommandBuilder.BuildPlanCommands)
 	if err != nil {
@@ -160,24 +261,33 @@ func (a *APIController) apiApply(request *APIRequest, ctx *command.Context) (*co
 	if err != nil {
 		return nil, err
 	}
+	cmds, notApproved := a.CodeOwnersApplyFilter.FilterAPI(ctx, cmds)
 
 	var projectResults []command.ProjectResult
 	for _, cmd := range cmds {
 		res := a.ProjectApplyCommandRunner.Apply(cmd)
 		projectResults = append(projectResults, res)
 	}
+	projectResults = append(projectResults, notApproved...)
 	return &command.Result{ProjectResults: projectResults}, nil
 }
 
-func (a *APIController) apiParseAndValidate(r *http.Request) (*APIRequest, *command.Context, int, error) {
+func (a *APIController) apiValidateSecret(r *http.Request) (int, error) {
 	if len(a.APISecret) == 0 {
-		return nil, nil, http.StatusBadRequest, fmt.Errorf("ignoring request since API is disabled")
+		return http.StatusBadRequest, fmt.Errorf("ignoring request since API is disabled")
 	}
 
 	// Validate the secret token
 	secret := r.Header.Get(atlantisTokenHeader)
 	if secret != string(a.APISecret) {
-		return nil, nil, http.StatusUnauthorized, fmt.Errorf("header %s did not match expected secret", atlantisTokenHeader)
+		return http.StatusUnauthorized, fmt.Errorf("header %s did not match expected secret", atlantisTokenHeader)
+	}
+	return http.StatusOK, nil
+}
+
+func (a *APIController) apiParseAndValidate(r *http.Request) (*APIRequest, *command.Context, int, error) {
+	if code, err := a.apiValidateSecret(r); err != nil {
+		return nil, nil, code, err
 	}
 
 	// Parse the JSON payload
@@ -221,8 +331,9 @@ func (a *APIController) apiParseAndValidate(r *http.Request) (*APIRequest, *comm
 			HeadCommit: request.Ref,
 			BaseRepo:   baseRepo,
 		},
-		Scope: a.Scope,
-		Log:   a.Logger,
+		Scope:   a.Scope,
+		Log:     a.Logger,
+		Trigger: command.APITrigger,
 	}, http.StatusOK, nil
 }
 
@@ -3,12 +3,14 @@ package controllers_test
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	. "github.com/petergtz/pegomock/v4"
 	"github.com/runatlantis/atlantis/server/controllers"
+	"github.com/runatlantis/atlantis/server/core/locking"
 	. "github.com/runatlantis/atlantis/server/core/locking/mocks"
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/command"
@@ -58,6 +60,198 @@ func TestAPIController_Apply(t *testing.T) {
 	projectCommandRunner.VerifyWasCalledOnce().Apply(Any[command.ProjectContext]())
 }
 
+func TestAPIController_ApplyCodeOwners(t *testing.T) {
+	ac, _, projectCommandRunner := setup(t)
+	ac.CodeOwnersApplyFilter = &events.CodeOwnersApplyFilter{}
+	When(ac.Parser.ParseAPIPlanRequest(Any[models.VCSHostType](), Any[string](), Any[string]())).
+		ThenReturn(models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Type: models.Github}}, nil)
+	body, _ := json.Marshal(controllers.APIRequest{
+		Repository: "Repo",
+		Ref:        "main",
+		Type:       "Github",
+		Projects:   []string{"default"},
+	})
+	req, _ := http.NewRequest("POST", "", bytes.NewBuffer(body))
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.Apply(w, req)
+	// Without a pull request, no one can approve the apply.
+	ResponseContains(t, w, http.StatusInternalServerError, "Applies must be for a pull request so that the project's owners in CODEOWNERS can approve them")
+	projectCommandRunner.VerifyWasCalled(Never()).Apply(Any[command.ProjectContext]())
+}
+
+func TestAPIController_ApplyLocked(t *testing.T) {
+	ac, _, projectCommandRunner := setup(t)
+	applyLocker := NewMockApplyLocker()
+	When(applyLocker.CheckApplyLock()).ThenReturn(locking.ApplyCommandLock{
+		Locked:  true,
+		Message: "maintenance window",
+	}, nil)
+	ac.ApplyLocker = applyLocker
+	body, _ := json.Marshal(controllers.APIRequest{
+		Repository: "Repo",
+		Ref:        "main",
+		Type:       "Gitlab",
+		Projects:   []string{"default"},
+	})
+	req, _ := http.NewRequest("POST", "", bytes.NewBuffer(body))
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	w := httptest.NewRecorder()
+	ac.Apply(w, req)
+	ResponseContains(t, w, http.StatusServiceUnavailable, "applies are disabled globally: maintenance window")
+	projectCommandRunner.VerifyWasCalled(Never()).Plan(Any[command.ProjectContext]())
+	projectCommandRunner.VerifyWasCalled(Never()).Apply(Any[command.ProjectContext]())
+}
+
+type fakeAuditSink struct {
+	records []events.AuditRecord
+}
+
+func (f *fakeAuditSink) Write(record events.AuditRecord) error {
+	f.records = append(f.records, record)
+	return nil
+}
+
+// API commands are recorded in the audit trail, including rejected ones.
+func TestAPIController_Audit(t *testing.T) {
+	ac, _, _ := setup(t)
+	sink := &fakeAuditSink{}
+	ac.AuditLogger = &events.AuditLogger{Sinks: []events.AuditSink{sink}}
+	applyLocker := NewMockApplyLocker()
+	When(applyLocker.CheckApplyLock()).ThenReturn(locking.ApplyCommandLock{Locked: true}, nil)
+	ac.ApplyLocker = applyLocker
+	body, _ := json.Marshal(controllers.APIRequest{
+		Repository: "Repo",
+		Ref:        "main",
+		Type:       "Gitlab",
+		PR:         2,
+		Projects:   []string{"default"},
+	})
+
+	req, _ := http.NewRequest("POST", "", bytes.NewBuffer(body))
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	ac.Plan(httptest.NewRecorder(), req)
+	req, _ = http.NewRequest("POST", "", bytes.NewBuffer(body))
+	req.Header.Set(atlantisTokenHeader, atlantisToken)
+	ac.Apply(httptest.NewRecorder(), req)
+
+	Equals(t, 2, len(sink.records))
+	Equals(t, "plan", sink.records[0].Command)
+	Equals(t, "api", sink.records[0].Trigger)
+	Equals(t, 2, sink.records[0].PullNum)
+	Equals(t, events.AuditSuccessOutcome, sink.records[0].Outcome)
+	Equals(t, "apply", sink.records[1].Command)
+	Equals(t, "api", sink.records[1].Trigger)
+	Equals(t, events.AuditFailureOutcome, sink.records[1].Outcome)
+	Equals(t, "applies are disabled globally", sink.records[1].Error)
+}
+
+func TestAPIController_LockApply(t *testing.T) {
+	t.Run("no token", func(t *testing.T) {
+		ac, _, _ := setup(t)
+		applyLocker := NewMockApplyLocker()
+		ac.ApplyLocker = applyLocker
+		req, _ := http.NewRequest("POST", "", bytes.NewBuffer(nil))
+		w := httptest.NewRecorder()
+		ac.LockApply(w, req)
+		ResponseContains(t, w, http.StatusUnauthorized, "did not match expected secret")
+		applyLocker.VerifyWasCalled(Never()).LockApply(Any[string]())
+	})
+
+	t.Run("api disabled", func(t *testing.T) {
+		ac, _, _ := setup(t)
+		ac.APISecret = nil
+		applyLocker := NewMockApplyLocker()
+		ac.ApplyLocker = applyLocker
+		req, _ := http.NewRequest("POST", "", bytes.NewBuffer(nil))
+		req.Header.Set(atlantisTokenHeader, atlantisToken)
+		w := httptest.NewRecorder()
+		ac.LockApply(w, req)
+		ResponseContains(t, w, http.StatusBadRequest, "API is disabled")
+		applyLocker.VerifyWasCalled(Never()).LockApply(Any[string]())
+	})
+
+	t.Run("without message", func(t *testing.T) {
+		ac, _, _ := setup(t)
+		applyLocker := NewMockApplyLocker()
+		When(applyLocker.LockApply("")).ThenReturn(locking.ApplyCommandLock{Locked: true}, nil)
+		ac.ApplyLocker = applyLocker
+		req, _ := http.NewRequest("POST", "", bytes.NewBuffer(nil))
+		req.Header.Set(atlantisTokenHeader, atlantisToken)
+		w := httptest.NewRecorder()
+		ac.LockApply(w, req)
+		ResponseContains(t, w, http.StatusOK, `"Locked":true`)
+		applyLocker.VerifyWasCalledOnce().LockApply("")
+	})
+
+	t.Run("with message", func(t *testing.T) {
+		ac, _, _ := setup(t)
+		applyLocker := NewMockApplyLocker()
+		When(applyLocker.LockApply("maintenance window")).ThenReturn(locking.ApplyCommandLock{
+			Locked:  true,
+			Message: "maintenance window",
+		}, nil)
+		ac.ApplyLocker = applyLocker
+		body, _ := json.Marshal(controllers.APIApplyLockRequest{Message: "maintenance window"})
+		req, _ := http.NewRequest("POST", "", bytes.NewBuffer(body))
+		req.Header.Set(atlantisTokenHeader, atlantisToken)
+		w := httptest.NewRecorder()
+		ac.LockApply(w, req)
+		ResponseContains(t, w, http.StatusOK, `"Message":"maintenance window"`)
+		applyLocker.VerifyWasCalledOnce().LockApply("maintenance window")
+	})
+
+	t.Run("lock error", func(t *testing.T) {
+		ac, _, _ := setup(t)
+		applyLocker := NewMockApplyLocker()
+		When(applyLocker.LockApply("")).ThenReturn(locking.ApplyCommandLock{}, errors.New("err"))
+		ac.ApplyLocker = applyLocker
+		req, _ := http.NewRequest("POST", "", bytes.NewBuffer(nil))
+		req.Header.Set(atlantisTokenHeader, atlantisToken)
+		w := httptest.NewRecorder()
+		ac.LockApply(w, req)
+		ResponseContains(t, w, http.StatusInternalServerError, "failed to lock apply: err")
+	})
+}
+
+func TestAPIController_UnlockApply(t *testing.T) {
+	t.Run("no token", func(t *testing.T) {
+		ac, _, _ := setup(t)
+		applyLocker := NewMockApplyLocker()
+		ac.ApplyLocker = applyLocker
+		req, _ := http.NewRequest("DELETE", "", bytes.NewBuffer(nil))
+		w := httptest.NewRecorder()
+		ac.UnlockApply(w, req)
+		ResponseContains(t, w, http.StatusUnauthorized, "did not match expected secret")
+		applyLocker.VerifyWasCalled(Never()).UnlockApply()
+	})
+
+	t.Run("success", func(t *testing.T) {
+		ac, _, _ := setup(t)
+		applyLocker := NewMockApplyLocker()
+		When(applyLocker.UnlockApply()).ThenReturn(nil)
+		ac.ApplyLocker = applyLocker
+		req, _ := http.NewRequest("DELETE", "", bytes.NewBuffer(nil))
+		req.Header.Set(atlantisTokenHeader, atlantisToken)
+		w := httptest.NewRecorder()
+		ac.UnlockApply(w, req)
+		ResponseContains(t, w, http.StatusOK, "")
+		applyLocker.VerifyWasCalledOnce().UnlockApply()
+	})
+
+	t.Run("unlock error", func(t *testing.T) {
+		ac, _, _ := setup(t)
+		applyLocker := NewMockApplyLocker()
+		When(applyLocker.UnlockApply()).ThenReturn(errors.New("err"))
+		ac.ApplyLocker = applyLocker
+		req, _ := http.NewRequest("DELETE", "", bytes.NewBuffer(nil))
+		req.Header.Set(atlantisTokenHeader, atlantisToken)
+		w := httptest.NewRecorder()
+		ac.UnlockApply(w, req)
+		ResponseContains(t, w, http.StatusInternalServerError, "failed to unlock apply: err")
+	})
+}
+
 func setup(t *testing.T) (controllers.APIController, *MockProjectCommandBuilder, *MockProjectCommandRunner) {
 	RegisterMockTestingT(t)
 	locker := NewMockLocker()
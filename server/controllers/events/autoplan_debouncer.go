@@ -0,0 +1,96 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// AutoplanDebouncer delays autoplans so that when a pull request is pushed to
+// several times within the window only its latest head is planned.
+type AutoplanDebouncer struct {
+	window time.Duration
+	// cancelRunning is called before a debounced autoplan runs if an earlier
+	// autoplan for the same pull request is still running so that it's
+	// superseded instead of both running.
+	cancelRunning func(baseRepo models.Repo, pullNum int)
+
+	mu    sync.Mutex
+	pulls map[string]*debouncedPull
+}
+
+type debouncedPull struct {
+	timer *time.Timer
+	// gen is incremented every time an autoplan is scheduled so timers that
+	// fired after they were replaced can be ignored.
+	gen int
+	// run is the latest autoplan that was scheduled.
+	run func()
+	// done is closed when the autoplan that's running, if any, finishes.
+	done chan struct{}
+}
+
+// NewAutoplanDebouncer returns an AutoplanDebouncer that waits for window
+// after the last push before autoplanning. cancelRunning may be nil.
+func NewAutoplanDebouncer(window time.Duration, cancelRunning func(baseRepo models.Repo, pullNum int)) *AutoplanDebouncer {
+	return &AutoplanDebouncer{
+		window:        window,
+		cancelRunning: cancelRunning,
+		pulls:         make(map[string]*debouncedPull),
+	}
+}
+
+// Debounce schedules run to autoplan the pull request once the window has
+// passed without another call for the same pull request. Earlier autoplans
+// that haven't started yet are dropped.
+func (d *AutoplanDebouncer) Debounce(baseRepo models.Repo, pullNum int, run func()) {
+	key := fmt.Sprintf("%s/%d", baseRepo.FullName, pullNum)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p, ok := d.pulls[key]
+	if !ok {
+		p = &debouncedPull{}
+		d.pulls[key] = p
+	}
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	p.gen++
+	gen := p.gen
+	p.run = run
+	p.timer = time.AfterFunc(d.window, func() { d.fire(key, p, gen, baseRepo, pullNum) })
+}
+
+func (d *AutoplanDebouncer) fire(key string, p *debouncedPull, gen int, baseRepo models.Repo, pullNum int) {
+	d.mu.Lock()
+	if d.pulls[key] != p || p.gen != gen {
+		d.mu.Unlock()
+		return
+	}
+	run, prevDone := p.run, p.done
+	done := make(chan struct{})
+	p.timer, p.run, p.done = nil, nil, done
+	d.mu.Unlock()
+
+	if prevDone != nil {
+		select {
+		case <-prevDone:
+		default:
+			if d.cancelRunning != nil {
+				d.cancelRunning(baseRepo, pullNum)
+			}
+			<-prevDone
+		}
+	}
+	run()
+	close(done)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if p.done == done && p.timer == nil {
+		delete(d.pulls, key)
+	}
+}
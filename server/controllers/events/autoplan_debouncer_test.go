@@ -0,0 +1,103 @@
+package events_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	events_controllers "github.com/runatlantis/atlantis/server/controllers/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestAutoplanDebouncer_CoalescesPushes(t *testing.T) {
+	d := events_controllers.NewAutoplanDebouncer(50*time.Millisecond, nil)
+	repo := models.Repo{FullName: "owner/repo"}
+
+	var mu sync.Mutex
+	var planned []string
+	done := make(chan struct{})
+	for _, sha := range []string{"sha1", "sha2", "sha3"} {
+		sha := sha
+		d.Debounce(repo, 1, func() {
+			mu.Lock()
+			planned = append(planned, sha)
+			mu.Unlock()
+			close(done)
+		})
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for autoplan")
+	}
+	// Give any other scheduled autoplans a chance to run.
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	Equals(t, []string{"sha3"}, planned)
+}
+
+func TestAutoplanDebouncer_PullsAreIndependent(t *testing.T) {
+	d := events_controllers.NewAutoplanDebouncer(10*time.Millisecond, nil)
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	d.Debounce(models.Repo{FullName: "owner/repo"}, 1, wg.Done)
+	d.Debounce(models.Repo{FullName: "owner/repo"}, 2, wg.Done)
+	d.Debounce(models.Repo{FullName: "owner/other"}, 1, wg.Done)
+	waitTimeout(t, &wg)
+}
+
+func TestAutoplanDebouncer_SupersedesRunningAutoplan(t *testing.T) {
+	canceled := make(chan struct{})
+	var canceledPulls []int
+	d := events_controllers.NewAutoplanDebouncer(10*time.Millisecond, func(baseRepo models.Repo, pullNum int) {
+		canceledPulls = append(canceledPulls, pullNum)
+		close(canceled)
+	})
+	repo := models.Repo{FullName: "owner/repo"}
+
+	started := make(chan struct{})
+	var order []string
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(2)
+	d.Debounce(repo, 1, func() {
+		defer wg.Done()
+		close(started)
+		// Simulate a plan that runs until it's canceled.
+		<-canceled
+		mu.Lock()
+		order = append(order, "sha1")
+		mu.Unlock()
+	})
+	<-started
+
+	d.Debounce(repo, 1, func() {
+		defer wg.Done()
+		mu.Lock()
+		order = append(order, "sha2")
+		mu.Unlock()
+	})
+	waitTimeout(t, &wg)
+
+	Equals(t, []int{1}, canceledPulls)
+	// The new autoplan only starts once the superseded one has finished.
+	Equals(t, []string{"sha1", "sha2"}, order)
+}
+
+func waitTimeout(t *testing.T, wg *sync.WaitGroup) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for autoplans")
+	}
+}
@@ -0,0 +1,63 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultDeliveryDedupTTL is how long a webhook delivery ID is remembered.
+// VCS hosts that deliver a webhook more than once do so within seconds.
+const DefaultDeliveryDedupTTL = 5 * time.Minute
+
+// DeliveryDeduplicator remembers the IDs of recently received webhook
+// deliveries so duplicate deliveries of the same event can be ignored.
+// A nil *DeliveryDeduplicator doesn't deduplicate anything.
+type DeliveryDeduplicator struct {
+	ttl time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewDeliveryDeduplicator returns a DeliveryDeduplicator that ignores repeats
+// of a delivery ID for ttl after it was first seen.
+func NewDeliveryDeduplicator(ttl time.Duration) *DeliveryDeduplicator {
+	return &DeliveryDeduplicator{
+		ttl:  ttl,
+		seen: make(map[string]time.Time),
+	}
+}
+
+// Seen records deliveryID and returns true if it was already recorded within
+// the TTL. Empty IDs are never considered duplicates since some VCS hosts
+// don't send one.
+func (d *DeliveryDeduplicator) Seen(deliveryID string) bool {
+	if d == nil || deliveryID == "" {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	for id, seenAt := range d.seen {
+		if now.Sub(seenAt) >= d.ttl {
+			delete(d.seen, id)
+		}
+	}
+	if _, ok := d.seen[deliveryID]; ok {
+		return true
+	}
+	d.seen[deliveryID] = now
+	return false
+}
+
+// Forget removes deliveryID so a redelivery of it will be handled, ex. after
+// handling the first delivery failed.
+func (d *DeliveryDeduplicator) Forget(deliveryID string) {
+	if d == nil || deliveryID == "" {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.seen, deliveryID)
+}
@@ -0,0 +1,46 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	events_controllers "github.com/runatlantis/atlantis/server/controllers/events"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestDeliveryDeduplicator_Seen(t *testing.T) {
+	d := events_controllers.NewDeliveryDeduplicator(time.Minute)
+
+	Assert(t, !d.Seen("github:1"), "expected first delivery to not be seen")
+	Assert(t, d.Seen("github:1"), "expected repeated delivery to be seen")
+	Assert(t, !d.Seen("github:2"), "expected distinct delivery to not be seen")
+	Assert(t, !d.Seen("gitlab:1"), "expected delivery from another host to not be seen")
+
+	// Deliveries without an ID can't be deduplicated.
+	Assert(t, !d.Seen(""), "expected empty delivery ID to not be seen")
+	Assert(t, !d.Seen(""), "expected empty delivery ID to not be seen")
+}
+
+func TestDeliveryDeduplicator_Expires(t *testing.T) {
+	d := events_controllers.NewDeliveryDeduplicator(10 * time.Millisecond)
+
+	Assert(t, !d.Seen("github:1"), "expected first delivery to not be seen")
+	time.Sleep(20 * time.Millisecond)
+	Assert(t, !d.Seen("github:1"), "expected delivery to be forgotten after the TTL")
+}
+
+func TestDeliveryDeduplicator_Forget(t *testing.T) {
+	d := events_controllers.NewDeliveryDeduplicator(time.Minute)
+
+	Assert(t, !d.Seen("github:1"), "expected first delivery to not be seen")
+	d.Forget("github:1")
+	Assert(t, !d.Seen("github:1"), "expected forgotten delivery to not be seen")
+}
+
+func TestDeliveryDeduplicator_Nil(t *testing.T) {
+	var d *events_controllers.DeliveryDeduplicator
+
+	Assert(t, !d.Seen("github:1"), "expected nil deduplicator to not dedupe")
+	Assert(t, !d.Seen("github:1"), "expected nil deduplicator to not dedupe")
+	d.Forget("github:1")
+}
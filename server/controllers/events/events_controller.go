@@ -14,9 +14,11 @@
 package events
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strings"
 
 	"github.com/google/go-github/v54/github"
@@ -34,8 +36,11 @@ import (
 )
 
 const githubHeader = "X-Github-Event"
+const githubDeliveryHeader = "X-Github-Delivery"
 const gitlabHeader = "X-Gitlab-Event"
+const gitlabEventUUIDHeader = "X-Gitlab-Event-UUID"
 const azuredevopsHeader = "Request-Id"
+const atlantisTokenHeader = "X-Atlantis-Token"
 
 // bitbucketEventTypeHeader is the same in both cloud and server.
 const bitbucketEventTypeHeader = "X-Event-Key"
@@ -91,10 +96,109 @@ type VCSEventsController struct {
 	// Azure DevOps Team Project. If empty, no request validation is done.
 	AzureDevopsWebhookBasicPassword []byte
 	AzureDevopsRequestValidator     AzureDevopsRequestValidator
+	// DeliveryDeduplicator is used to ignore duplicate deliveries of the same
+	// webhook. If nil, every delivery is handled.
+	DeliveryDeduplicator *DeliveryDeduplicator
+	// AutoplanDebouncer delays autoplans so rapid pushes to a pull request
+	// only autoplan its latest head. If nil, every push is autoplanned.
+	AutoplanDebouncer *AutoplanDebouncer
+	// WebhookRecorder records webhooks so they can be replayed. If nil,
+	// webhooks aren't recorded.
+	WebhookRecorder *WebhookRecorder
+	// APISecret authenticates requests to replay recorded webhooks. If empty,
+	// webhooks can't be replayed.
+	APISecret []byte
 }
 
 // Post handles POST webhook requests.
 func (e *VCSEventsController) Post(w http.ResponseWriter, r *http.Request) {
+	if e.WebhookRecorder != nil {
+		var err error
+		if r, err = e.WebhookRecorder.Capture(r); err != nil {
+			e.Logger.Warn("unable to record webhook: %s", err)
+		}
+	}
+
+	deliveryID := webhookDeliveryID(r)
+	if e.DeliveryDeduplicator.Seen(deliveryID) {
+		e.respond(w, logging.Info, http.StatusOK, "Ignoring duplicate webhook delivery %s", deliveryID)
+		return
+	}
+	// If the delivery couldn't be handled we forget it so the VCS host can
+	// redeliver it.
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	defer func() {
+		if recorder.status >= http.StatusMultipleChoices {
+			e.DeliveryDeduplicator.Forget(deliveryID)
+		}
+	}()
+	w = recorder
+	e.handlePost(w, r)
+}
+
+// Replay handles POST requests to replay a webhook recorded by the
+// WebhookRecorder, identified by the id query parameter. Secrets are scrubbed
+// from recorded webhooks so replays skip webhook validation. They aren't
+// deduplicated either. Replays are dry runs: they're handled up to the point
+// commands would run, pull requests would be cleaned up or commented on, and
+// respond with what would have been done instead.
+func (e *VCSEventsController) Replay(w http.ResponseWriter, r *http.Request) {
+	if e.WebhookRecorder == nil || len(e.APISecret) == 0 {
+		e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since replaying webhooks requires --webhook-capture-dir and --api-secret")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(atlantisTokenHeader)), e.APISecret) != 1 {
+		e.respond(w, logging.Warn, http.StatusUnauthorized, "header %s did not match expected secret", atlantisTokenHeader)
+		return
+	}
+	id := r.URL.Query().Get("id")
+	webhook, err := e.WebhookRecorder.Load(id)
+	if os.IsNotExist(err) {
+		e.respond(w, logging.Info, http.StatusNotFound, "No recorded webhook %s", id)
+		return
+	}
+	if err != nil {
+		e.respond(w, logging.Warn, http.StatusBadRequest, "Unable to load recorded webhook: %s", err)
+		return
+	}
+
+	dryRun := &dryRunReplay{Client: e.VCSClient}
+	replayer := *e
+	replayer.Logger = e.Logger.With("webhook-replay", webhook.ID)
+	replayer.GithubWebhookSecret = nil
+	replayer.GitlabWebhookSecret = nil
+	replayer.BitbucketWebhookSecret = nil
+	replayer.AzureDevopsWebhookBasicUser = nil
+	replayer.AzureDevopsWebhookBasicPassword = nil
+	replayer.CommandRunner = dryRun
+	replayer.PullCleaner = dryRun
+	replayer.VCSClient = dryRun
+	replayer.AutoplanDebouncer = nil
+	// Commands are "run" synchronously so they're in the response.
+	replayer.TestingMode = true
+	replayer.Logger.Info("replaying webhook %s received at %s as a dry run", webhook.ID, webhook.ReceivedAt)
+	w.Header().Set(replayHeader, webhook.ID)
+	replayer.handlePost(w, webhook.Request())
+	for _, action := range dryRun.Actions() {
+		replayer.Logger.Info("dry run: would %s", action)
+		fmt.Fprintf(w, "Dry run: would %s\n", action)
+	}
+}
+
+// recordWebhook records the webhook r once it's validated.
+func (e *VCSEventsController) recordWebhook(r *http.Request) {
+	id, err := e.WebhookRecorder.Record(r)
+	if err != nil {
+		e.Logger.Warn("unable to record webhook: %s", err)
+		return
+	}
+	if id != "" {
+		e.Logger.Debug("recorded webhook %s", id)
+	}
+}
+
+// handlePost handles a webhook request according to the VCS host it's from.
+func (e *VCSEventsController) handlePost(w http.ResponseWriter, r *http.Request) {
 	if r.Header.Get(githubHeader) != "" {
 		if !e.supportsHost(models.Github) {
 			e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request since not configured to support GitHub")
@@ -143,6 +247,40 @@ func (e *VCSEventsController) Post(w http.ResponseWriter, r *http.Request) {
 	e.respond(w, logging.Debug, http.StatusBadRequest, "Ignoring request")
 }
 
+// webhookDeliveryID returns the ID the VCS host gave this webhook delivery,
+// prefixed by the host so IDs can't collide across hosts. It returns an empty
+// string if the host didn't send one.
+func webhookDeliveryID(r *http.Request) string {
+	var host, id string
+	switch {
+	case r.Header.Get(githubHeader) != "":
+		host, id = "github", r.Header.Get(githubDeliveryHeader)
+	case r.Header.Get(gitlabHeader) != "":
+		host, id = "gitlab", r.Header.Get(gitlabEventUUIDHeader)
+	case r.Header.Get(bitbucketEventTypeHeader) != "" && r.Header.Get(bitbucketCloudRequestIDHeader) != "":
+		host, id = "bitbucketcloud", r.Header.Get(bitbucketCloudRequestIDHeader)
+	case r.Header.Get(bitbucketEventTypeHeader) != "" && r.Header.Get(bitbucketServerRequestIDHeader) != "":
+		host, id = "bitbucketserver", r.Header.Get(bitbucketServerRequestIDHeader)
+	case r.Header.Get(azuredevopsHeader) != "":
+		host, id = "azuredevops", r.Header.Get(azuredevopsHeader)
+	}
+	if id == "" {
+		return ""
+	}
+	return host + ":" + id
+}
+
+// statusRecorder records the status code written to a http.ResponseWriter.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
 type HTTPError struct {
 	err        error
 	code       int
@@ -161,8 +299,9 @@ func (e *VCSEventsController) handleGithubPost(w http.ResponseWriter, r *http.Re
 		e.respond(w, logging.Warn, http.StatusBadRequest, err.Error())
 		return
 	}
+	e.recordWebhook(r)
 
-	githubReqID := "X-Github-Delivery=" + r.Header.Get("X-Github-Delivery")
+	githubReqID := "X-Github-Delivery=" + r.Header.Get(githubDeliveryHeader)
 	logger := e.Logger.With("gh-request-id", githubReqID)
 	scope := e.Scope.SubScope("github_event")
 
@@ -211,6 +350,8 @@ func (e *VCSEventsController) handleBitbucketCloudPost(w http.ResponseWriter, r
 		e.respond(w, logging.Error, http.StatusBadRequest, "Unable to read body: %s %s=%s", err, bitbucketCloudRequestIDHeader, reqID)
 		return
 	}
+	// Bitbucket Cloud webhooks can't be validated.
+	e.recordWebhook(r)
 	switch eventType {
 	case bitbucketcloud.PullCreatedHeader, bitbucketcloud.PullUpdatedHeader, bitbucketcloud.PullFulfilledHeader, bitbucketcloud.PullRejectedHeader:
 		e.Logger.Debug("handling as pull request state changed event")
@@ -247,6 +388,7 @@ func (e *VCSEventsController) handleBitbucketServerPost(w http.ResponseWriter, r
 			return
 		}
 	}
+	e.recordWebhook(r)
 	switch eventType {
 	case bitbucketserver.PullCreatedHeader, bitbucketserver.PullFromRefUpdatedHeader, bitbucketserver.PullMergedHeader, bitbucketserver.PullDeclinedHeader, bitbucketserver.PullDeletedHeader:
 		e.Logger.Debug("handling as pull request state changed event")
@@ -268,6 +410,7 @@ func (e *VCSEventsController) handleAzureDevopsPost(w http.ResponseWriter, r *ht
 		e.respond(w, logging.Warn, http.StatusUnauthorized, err.Error())
 		return
 	}
+	e.recordWebhook(r)
 	e.Logger.Debug("request valid")
 
 	azuredevopsReqID := "Request-Id=" + r.Header.Get("Request-Id")
@@ -454,7 +597,11 @@ func (e *VCSEventsController) handlePullRequestEvent(logger logging.SimpleLoggin
 		// Respond with success and then actually execute the command asynchronously.
 		// We use a goroutine so that this function returns and the connection is
 		// closed.
-		if !e.TestingMode {
+		if e.AutoplanDebouncer != nil {
+			e.AutoplanDebouncer.Debounce(baseRepo, pull.Num, func() {
+				e.CommandRunner.RunAutoplanCommand(baseRepo, headRepo, pull, user)
+			})
+		} else if !e.TestingMode {
 			go e.CommandRunner.RunAutoplanCommand(baseRepo, headRepo, pull, user)
 		} else {
 			// When testing we want to wait for everything to complete.
@@ -494,6 +641,7 @@ func (e *VCSEventsController) handleGitlabPost(w http.ResponseWriter, r *http.Re
 		e.respond(w, logging.Warn, http.StatusBadRequest, err.Error())
 		return
 	}
+	e.recordWebhook(r)
 	e.Logger.Debug("request valid")
 
 	switch event := event.(type) {
@@ -608,7 +608,7 @@ func TestGitHubWorkflow(t *testing.T) {
 
 			// Create global apply lock if required
 			if c.ApplyLock {
-				_, _ = applyLocker.LockApply()
+				_, _ = applyLocker.LockApply("")
 			}
 
 			// Now send any other comments.
@@ -1248,7 +1248,7 @@ func setupE2E(t *testing.T, repoDir string, opt setupOption) (events_controllers
 		ExecutableName: "atlantis",
 		AllowCommands:  allowCommands,
 	}
-	terraformClient, err := terraform.NewClient(logger, binDir, cacheDir, "", "", "", "default-tf-version", "https://releases.hashicorp.com", &NoopTFDownloader{}, true, false, projectCmdOutputHandler)
+	terraformClient, err := terraform.NewClient(logger, binDir, cacheDir, "", "", "", "default-tf-version", "https://releases.hashicorp.com", &NoopTFDownloader{}, true, false, false, projectCmdOutputHandler)
 	Ok(t, err)
 	boltdb, err := db.New(dataDir)
 	Ok(t, err)
@@ -1352,6 +1352,7 @@ func setupE2E(t *testing.T, repoDir string, opt setupOption) (events_controllers
 		false,
 		false,
 		false,
+		false,
 		statsScope,
 		logger,
 		terraformClient,
@@ -1415,7 +1416,7 @@ func setupE2E(t *testing.T, repoDir string, opt setupOption) (events_controllers
 	pullUpdater := &events.PullUpdater{
 		HidePrevPlanComments: false,
 		VCSClient:            e2eVCSClient,
-		MarkdownRenderer:     events.NewMarkdownRenderer(false, false, false, false, false, false, "", "atlantis", false),
+		MarkdownRenderer:     events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"}),
 	}
 
 	autoMerger := &events.AutoMerger{
@@ -1454,6 +1455,7 @@ func setupE2E(t *testing.T, repoDir string, opt setupOption) (events_controllers
 		lockingClient,
 		discardApprovalOnPlan,
 		e2ePullReqStatusFetcher,
+		false,
 	)
 
 	applyCommandRunner := events.NewApplyCommandRunner(
@@ -1471,6 +1473,7 @@ func setupE2E(t *testing.T, repoDir string, opt setupOption) (events_controllers
 		silenceNoProjects,
 		false,
 		e2ePullReqStatusFetcher,
+		nil,
 	)
 
 	approvePoliciesCommandRunner := events.NewApprovePoliciesCommandRunner(
@@ -24,6 +24,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-github/v54/github"
 	"github.com/mcdafydd/go-azuredevops/azuredevops"
@@ -380,6 +381,153 @@ func TestPost_GithubCommentSuccess(t *testing.T) {
 	cr.VerifyWasCalledOnce().RunCommentCommand(baseRepo, nil, nil, user, 1, &cmd)
 }
 
+func TestPost_GithubCommentDuplicateDelivery(t *testing.T) {
+	t.Log("when the same github delivery is received twice we only call the command handler once")
+	e, v, _, _, p, cr, _, _, cp := setup(t)
+	e.DeliveryDeduplicator = events_controllers.NewDeliveryDeduplicator(time.Minute)
+	event := `{"action": "created"}`
+	baseRepo := models.Repo{}
+	user := models.User{}
+	cmd := events.CommentCommand{}
+	When(v.Validate(Any[*http.Request](), Eq(secret))).ThenReturn([]byte(event), nil)
+	When(p.ParseGithubIssueCommentEvent(Any[*github.IssueCommentEvent]())).ThenReturn(baseRepo, user, 1, nil)
+	When(cp.Parse("", models.Github)).ThenReturn(events.CommentParseResult{Command: &cmd})
+
+	newRequest := func(deliveryID string) *http.Request {
+		req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+		req.Header.Set(githubHeader, "issue_comment")
+		req.Header.Set("X-Github-Delivery", deliveryID)
+		return req
+	}
+
+	w := httptest.NewRecorder()
+	e.Post(w, newRequest("delivery-1"))
+	ResponseContains(t, w, http.StatusOK, "Processing...")
+
+	w = httptest.NewRecorder()
+	e.Post(w, newRequest("delivery-1"))
+	ResponseContains(t, w, http.StatusOK, "Ignoring duplicate webhook delivery github:delivery-1")
+	cr.VerifyWasCalledOnce().RunCommentCommand(baseRepo, nil, nil, user, 1, &cmd)
+
+	// Distinct deliveries must still be handled.
+	w = httptest.NewRecorder()
+	e.Post(w, newRequest("delivery-2"))
+	ResponseContains(t, w, http.StatusOK, "Processing...")
+	cr.VerifyWasCalled(Times(2)).RunCommentCommand(baseRepo, nil, nil, user, 1, &cmd)
+}
+
+func TestPost_GithubFailedDeliveryIsNotDeduplicated(t *testing.T) {
+	t.Log("when handling a github delivery fails a redelivery of it is handled")
+	e, v, _, _, _, _, _, _, _ := setup(t)
+	e.DeliveryDeduplicator = events_controllers.NewDeliveryDeduplicator(time.Minute)
+	When(v.Validate(Any[*http.Request](), Eq(secret))).ThenReturn(nil, errors.New("err"))
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+		req.Header.Set(githubHeader, "issue_comment")
+		req.Header.Set("X-Github-Delivery", "delivery-1")
+		w := httptest.NewRecorder()
+		e.Post(w, req)
+		ResponseContains(t, w, http.StatusBadRequest, "err")
+	}
+	v.VerifyWasCalled(Times(2)).Validate(Any[*http.Request](), Eq(secret))
+}
+
+func TestReplay_GithubComment(t *testing.T) {
+	t.Log("when a recorded github comment is replayed we report the command it would run without validating the secret or running it")
+	e, v, _, _, p, cr, _, vcsClient, cp := setup(t)
+	e.DeliveryDeduplicator = events_controllers.NewDeliveryDeduplicator(time.Minute)
+	e.APISecret = []byte("api-secret")
+	recorder, err := events_controllers.NewWebhookRecorder(t.TempDir(), 10)
+	Ok(t, err)
+	e.WebhookRecorder = recorder
+	event := `{"action": "created"}`
+	baseRepo := models.Repo{FullName: "owner/repo"}
+	user := models.User{Username: "user"}
+	cmd := events.CommentCommand{Name: command.Plan}
+	When(v.Validate(Any[*http.Request](), Any[[]byte]())).ThenReturn([]byte(event), nil)
+	When(p.ParseGithubIssueCommentEvent(Any[*github.IssueCommentEvent]())).ThenReturn(baseRepo, user, 1, nil)
+	When(cp.Parse("", models.Github)).ThenReturn(events.CommentParseResult{Command: &cmd})
+
+	req, _ := http.NewRequest("POST", "/events", bytes.NewBufferString(event))
+	req.Header.Set(githubHeader, "issue_comment")
+	req.Header.Set("X-Github-Delivery", "delivery-1")
+	w := httptest.NewRecorder()
+	e.Post(w, req)
+	ResponseContains(t, w, http.StatusOK, "Processing...")
+	v.VerifyWasCalledOnce().Validate(Any[*http.Request](), Eq(secret))
+
+	files, err := os.ReadDir(recorder.Dir)
+	Ok(t, err)
+	Equals(t, 1, len(files))
+	id := strings.TrimSuffix(files[0].Name(), ".json")
+
+	// Replays aren't deduplicated or validated, and aren't recorded again.
+	// They're dry runs so the command isn't run and the comment isn't
+	// reacted to again.
+	replayReq, _ := http.NewRequest("POST", "/events/replay?id="+id, nil)
+	replayReq.Header.Set("X-Atlantis-Token", "api-secret")
+	w = httptest.NewRecorder()
+	e.Replay(w, replayReq)
+	ResponseContains(t, w, http.StatusOK, "Processing...\nDry run: would react with eyes to comment 0 on owner/repo#1\nDry run: would run plan for owner/repo#1 by user")
+	Equals(t, id, w.Result().Header.Get("X-Atlantis-Replay"))
+	v.VerifyWasCalledOnce().Validate(Any[*http.Request](), Eq([]byte(nil)))
+	cr.VerifyWasCalledOnce().RunCommentCommand(baseRepo, nil, nil, user, 1, &cmd)
+	vcsClient.VerifyWasCalledOnce().ReactToComment(Any[models.Repo](), Any[int](), Any[int64](), Any[string]())
+	files, err = os.ReadDir(recorder.Dir)
+	Ok(t, err)
+	Equals(t, 1, len(files))
+}
+
+func TestPost_GithubInvalidWebhookIsNotRecorded(t *testing.T) {
+	t.Log("when a github webhook doesn't pass validation it isn't recorded")
+	e, v, _, _, _, _, _, _, _ := setup(t)
+	recorder, err := events_controllers.NewWebhookRecorder(t.TempDir(), 10)
+	Ok(t, err)
+	e.WebhookRecorder = recorder
+	When(v.Validate(Any[*http.Request](), Any[[]byte]())).ThenReturn(nil, errors.New("err"))
+
+	req, _ := http.NewRequest("POST", "/events", bytes.NewBufferString(`{"action": "created"}`))
+	req.Header.Set(githubHeader, "issue_comment")
+	w := httptest.NewRecorder()
+	e.Post(w, req)
+	ResponseContains(t, w, http.StatusBadRequest, "err")
+
+	files, err := os.ReadDir(recorder.Dir)
+	Ok(t, err)
+	Equals(t, 0, len(files))
+}
+
+func TestReplay_Errors(t *testing.T) {
+	e, _, _, _, _, _, _, _, _ := setup(t)
+	newRequest := func(id string, token string) *http.Request {
+		req, _ := http.NewRequest("POST", "/events/replay?id="+id, nil)
+		req.Header.Set("X-Atlantis-Token", token)
+		return req
+	}
+
+	w := httptest.NewRecorder()
+	e.Replay(w, newRequest("missing", "api-secret"))
+	ResponseContains(t, w, http.StatusBadRequest, "Ignoring request since replaying webhooks requires --webhook-capture-dir and --api-secret")
+
+	recorder, err := events_controllers.NewWebhookRecorder(t.TempDir(), 10)
+	Ok(t, err)
+	e.WebhookRecorder = recorder
+	e.APISecret = []byte("api-secret")
+
+	w = httptest.NewRecorder()
+	e.Replay(w, newRequest("missing", "wrong"))
+	ResponseContains(t, w, http.StatusUnauthorized, "header X-Atlantis-Token did not match expected secret")
+
+	w = httptest.NewRecorder()
+	e.Replay(w, newRequest("missing", "api-secret"))
+	ResponseContains(t, w, http.StatusNotFound, "No recorded webhook missing")
+
+	w = httptest.NewRecorder()
+	e.Replay(w, newRequest("..%2Fmissing", "api-secret"))
+	ResponseContains(t, w, http.StatusBadRequest, `invalid webhook ID "../missing"`)
+}
+
 func TestPost_GithubCommentReaction(t *testing.T) {
 	t.Log("when the event is a github comment with a valid command we call the ReactToComment handler")
 	e, v, _, _, p, _, _, vcsClient, cp := setup(t)
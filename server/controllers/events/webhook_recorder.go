@@ -0,0 +1,284 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+)
+
+// maxRecordedWebhookSize is the largest webhook body, in bytes, that's
+// recorded. Larger webhooks are still handled.
+const maxRecordedWebhookSize = 1 << 20
+
+// replayHeader marks replayed webhooks. Its value is the ID of the recorded
+// webhook.
+const replayHeader = "X-Atlantis-Replay"
+
+// scrubbedValue replaces secrets in recorded webhooks.
+const scrubbedValue = "[scrubbed]"
+
+// secretHeaders are the headers that authenticate webhooks. They're dropped
+// from recorded webhooks.
+var secretHeaders = []string{
+	"Authorization",
+	"X-Atlantis-Token",
+	"X-Gitlab-Token",
+	"X-Hub-Signature",
+	"X-Hub-Signature-256",
+}
+
+// secretKeyRegex matches the keys of JSON values in webhook bodies that are
+// scrubbed from recorded webhooks.
+var secretKeyRegex = regexp.MustCompile(`(?i)secret|token|password`)
+
+// recordedWebhookIDRegex matches the IDs of recorded webhooks so they can't
+// be used to load files outside of the recorder's directory.
+var recordedWebhookIDRegex = regexp.MustCompile(`^[0-9A-Za-z.-]+$`)
+
+// capturedWebhookKey is the context key of the webhook captured from a
+// request.
+type capturedWebhookKey struct{}
+
+// WebhookRecorder stores the webhooks Atlantis receives, with secrets
+// scrubbed, so they can be replayed to debug how they were handled.
+// A nil *WebhookRecorder doesn't record anything.
+type WebhookRecorder struct {
+	// Dir is the directory the webhooks are stored in, one JSON file each.
+	Dir string
+	// MaxWebhooks is the number of webhooks kept in Dir. Older ones are
+	// deleted when a webhook is recorded.
+	MaxWebhooks int
+}
+
+// RecordedWebhook is a webhook stored by a WebhookRecorder.
+type RecordedWebhook struct {
+	ID         string      `json:"id"`
+	ReceivedAt time.Time   `json:"received_at"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// NewWebhookRecorder returns a WebhookRecorder that keeps the last
+// maxWebhooks webhooks in dir, creating it if it doesn't exist.
+func NewWebhookRecorder(dir string, maxWebhooks int) (*WebhookRecorder, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating webhook capture dir: %w", err)
+	}
+	return &WebhookRecorder{Dir: dir, MaxWebhooks: maxWebhooks}, nil
+}
+
+// Capture reads the webhook r so it can be recorded with Record once it's
+// validated. It returns r with the webhook in its context and its body
+// replaced so it can still be handled.
+func (w *WebhookRecorder) Capture(r *http.Request) (*http.Request, error) {
+	if w == nil {
+		return r, nil
+	}
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close() // nolint: errcheck
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return r, fmt.Errorf("reading body: %w", err)
+	}
+	if len(body) > maxRecordedWebhookSize {
+		return r, fmt.Errorf("body is larger than %d bytes", maxRecordedWebhookSize)
+	}
+
+	random := make([]byte, 4)
+	if _, err := rand.Read(random); err != nil {
+		return r, err
+	}
+	receivedAt := time.Now().UTC()
+	webhook := &RecordedWebhook{
+		ID:         receivedAt.Format("20060102T150405.000000000Z") + "-" + hex.EncodeToString(random),
+		ReceivedAt: receivedAt,
+		Header:     r.Header.Clone(),
+		Body:       scrubWebhookBody(body, r.Header.Get("Content-Type")),
+	}
+	for _, h := range secretHeaders {
+		webhook.Header.Del(h)
+	}
+	return r.WithContext(context.WithValue(r.Context(), capturedWebhookKey{}, webhook)), nil
+}
+
+// Record stores the webhook captured from r and returns its ID. It should
+// only be called once r is validated so unauthenticated requests aren't
+// stored. The oldest webhooks are deleted so at most MaxWebhooks are kept.
+// It returns an empty ID if no webhook was captured from r.
+func (w *WebhookRecorder) Record(r *http.Request) (string, error) {
+	if w == nil {
+		return "", nil
+	}
+	webhook, ok := r.Context().Value(capturedWebhookKey{}).(*RecordedWebhook)
+	if !ok {
+		return "", nil
+	}
+
+	content, err := json.MarshalIndent(webhook, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(w.Dir, webhook.ID+".json"), content, 0600); err != nil {
+		return "", fmt.Errorf("writing webhook: %w", err)
+	}
+	return webhook.ID, w.prune()
+}
+
+// prune deletes the oldest recorded webhooks so at most MaxWebhooks are kept.
+// IDs start with the time the webhook was received so sorting the file names
+// sorts them from oldest to newest.
+func (w *WebhookRecorder) prune() error {
+	if w.MaxWebhooks <= 0 {
+		return nil
+	}
+	files, err := filepath.Glob(filepath.Join(w.Dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	if len(files) <= w.MaxWebhooks {
+		return nil
+	}
+	sort.Strings(files)
+	for _, file := range files[:len(files)-w.MaxWebhooks] {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("deleting old webhook: %w", err)
+		}
+	}
+	return nil
+}
+
+// Load returns the webhook recorded with id.
+func (w *WebhookRecorder) Load(id string) (RecordedWebhook, error) {
+	var webhook RecordedWebhook
+	if !recordedWebhookIDRegex.MatchString(id) {
+		return webhook, fmt.Errorf("invalid webhook ID %q", id)
+	}
+	content, err := os.ReadFile(filepath.Join(w.Dir, id+".json")) // nolint: gosec
+	if err != nil {
+		return webhook, err
+	}
+	if err := json.Unmarshal(content, &webhook); err != nil {
+		return webhook, fmt.Errorf("parsing webhook %s: %w", id, err)
+	}
+	return webhook, nil
+}
+
+// Request returns a request for the recorded webhook, marked as a replay.
+func (h RecordedWebhook) Request() *http.Request {
+	r, _ := http.NewRequest(http.MethodPost, "/events", strings.NewReader(h.Body))
+	r.Header = h.Header.Clone()
+	if r.Header == nil {
+		r.Header = make(http.Header)
+	}
+	r.Header.Set(replayHeader, h.ID)
+	return r
+}
+
+// scrubWebhookBody returns body with the values of secret looking keys
+// scrubbed. GitHub sends form encoded bodies if configured to, with the JSON
+// payload in the payload field.
+func scrubWebhookBody(body []byte, contentType string) string {
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return string(body)
+		}
+		if payload := form.Get("payload"); payload != "" {
+			form.Set("payload", scrubWebhookBody([]byte(payload), "application/json"))
+		}
+		return form.Encode()
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// Numbers are kept as is since IDs can be too large for float64.
+	decoder.UseNumber()
+	var payload interface{}
+	if err := decoder.Decode(&payload); err != nil {
+		return string(body)
+	}
+	scrubbed, err := json.Marshal(scrubJSON(payload))
+	if err != nil {
+		return string(body)
+	}
+	return string(scrubbed)
+}
+
+func scrubJSON(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for k, v := range value {
+			if _, isString := v.(string); isString && secretKeyRegex.MatchString(k) {
+				value[k] = scrubbedValue
+				continue
+			}
+			value[k] = scrubJSON(v)
+		}
+	case []interface{}:
+		for i, v := range value {
+			value[i] = scrubJSON(v)
+		}
+	}
+	return value
+}
+
+// dryRunReplay stands in for the command runner, pull cleaner and VCS client
+// of replayed webhooks so replays only report what they would have done. It
+// never runs commands, cleans up pull requests or comments on them.
+type dryRunReplay struct {
+	vcs.Client
+	mu      sync.Mutex
+	actions []string
+}
+
+func (d *dryRunReplay) record(format string, a ...interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.actions = append(d.actions, fmt.Sprintf(format, a...))
+}
+
+// Actions returns what the replayed webhook would have done, in order.
+func (d *dryRunReplay) Actions() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string{}, d.actions...)
+}
+
+func (d *dryRunReplay) RunCommentCommand(baseRepo models.Repo, _ *models.Repo, _ *models.PullRequest, user models.User, pullNum int, cmd *events.CommentCommand) {
+	d.record("run %s for %s#%d by %s", cmd.CommandName(), baseRepo.FullName, pullNum, user.Username)
+}
+
+func (d *dryRunReplay) RunAutoplanCommand(baseRepo models.Repo, _ models.Repo, pull models.PullRequest, user models.User) {
+	d.record("autoplan %s#%d at %s by %s", baseRepo.FullName, pull.Num, pull.HeadCommit, user.Username)
+}
+
+func (d *dryRunReplay) CleanUpPull(repo models.Repo, pull models.PullRequest) error {
+	d.record("clean up %s#%d", repo.FullName, pull.Num)
+	return nil
+}
+
+func (d *dryRunReplay) CreateComment(repo models.Repo, pullNum int, comment string, _ string) error {
+	d.record("comment on %s#%d: %s", repo.FullName, pullNum, comment)
+	return nil
+}
+
+func (d *dryRunReplay) ReactToComment(repo models.Repo, pullNum int, commentID int64, reaction string) error {
+	d.record("react with %s to comment %d on %s#%d", reaction, commentID, repo.FullName, pullNum)
+	return nil
+}
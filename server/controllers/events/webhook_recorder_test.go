@@ -0,0 +1,141 @@
+package events_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	events_controllers "github.com/runatlantis/atlantis/server/controllers/events"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestWebhookRecorder_RecordAndLoad(t *testing.T) {
+	recorder, err := events_controllers.NewWebhookRecorder(filepath.Join(t.TempDir(), "webhooks"), 10)
+	Ok(t, err)
+
+	body := `{"action":"created","installation":{"id":12345678901234567890,"access_token":"ghs_abc"},"hook":{"config":{"secret":"s3cret","url":"https://atlantis.example.com/events"}},"comments":[{"token":"t0ken"}]}`
+	req, _ := http.NewRequest("POST", "/events", bytes.NewBufferString(body))
+	req.Header.Set(githubHeader, "issue_comment")
+	req.Header.Set("X-Hub-Signature-256", "sha256=abc")
+	req.Header.Set("Content-Type", "application/json")
+
+	req, err = recorder.Capture(req)
+	Ok(t, err)
+	id, err := recorder.Record(req)
+	Ok(t, err)
+
+	// The request can still be handled.
+	handledBody, err := io.ReadAll(req.Body)
+	Ok(t, err)
+	Equals(t, body, string(handledBody))
+
+	webhook, err := recorder.Load(id)
+	Ok(t, err)
+	Equals(t, id, webhook.ID)
+	Equals(t, "issue_comment", webhook.Header.Get(githubHeader))
+	Equals(t, "", webhook.Header.Get("X-Hub-Signature-256"))
+	Equals(t, `{"action":"created","comments":[{"token":"[scrubbed]"}],"hook":{"config":{"secret":"[scrubbed]","url":"https://atlantis.example.com/events"}},"installation":{"access_token":"[scrubbed]","id":12345678901234567890}}`, webhook.Body)
+
+	replay := webhook.Request()
+	Equals(t, id, replay.Header.Get("X-Atlantis-Replay"))
+	Equals(t, "issue_comment", replay.Header.Get(githubHeader))
+	replayBody, err := io.ReadAll(replay.Body)
+	Ok(t, err)
+	Equals(t, webhook.Body, string(replayBody))
+}
+
+func TestWebhookRecorder_RecordFormEncoded(t *testing.T) {
+	recorder, err := events_controllers.NewWebhookRecorder(t.TempDir(), 10)
+	Ok(t, err)
+
+	form := url.Values{"payload": {`{"action":"opened","token":"abc"}`}}
+	req, _ := http.NewRequest("POST", "/events", bytes.NewBufferString(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req, err = recorder.Capture(req)
+	Ok(t, err)
+	id, err := recorder.Record(req)
+	Ok(t, err)
+
+	webhook, err := recorder.Load(id)
+	Ok(t, err)
+	recorded, err := url.ParseQuery(webhook.Body)
+	Ok(t, err)
+	Equals(t, `{"action":"opened","token":"[scrubbed]"}`, recorded.Get("payload"))
+}
+
+func TestWebhookRecorder_Load(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := events_controllers.NewWebhookRecorder(filepath.Join(dir, "webhooks"), 10)
+	Ok(t, err)
+	Ok(t, os.WriteFile(filepath.Join(dir, "outside.json"), []byte(`{}`), 0600))
+
+	_, err = recorder.Load("../outside")
+	ErrEquals(t, `invalid webhook ID "../outside"`, err)
+	_, err = recorder.Load("missing")
+	Assert(t, os.IsNotExist(err), "exp not exist error, got %v", err)
+}
+
+func TestWebhookRecorder_RecordUncaptured(t *testing.T) {
+	recorder, err := events_controllers.NewWebhookRecorder(t.TempDir(), 10)
+	Ok(t, err)
+	req, _ := http.NewRequest("POST", "/events", bytes.NewBufferString("body"))
+	id, err := recorder.Record(req)
+	Ok(t, err)
+	Equals(t, "", id)
+	files, err := os.ReadDir(recorder.Dir)
+	Ok(t, err)
+	Equals(t, 0, len(files))
+}
+
+func TestWebhookRecorder_TooLarge(t *testing.T) {
+	recorder, err := events_controllers.NewWebhookRecorder(t.TempDir(), 10)
+	Ok(t, err)
+	body := strings.Repeat("a", 1<<20+1)
+	req, _ := http.NewRequest("POST", "/events", bytes.NewBufferString(body))
+	req, err = recorder.Capture(req)
+	ErrEquals(t, "body is larger than 1048576 bytes", err)
+
+	// The request can still be handled but isn't recorded.
+	handledBody, err := io.ReadAll(req.Body)
+	Ok(t, err)
+	Equals(t, body, string(handledBody))
+	id, err := recorder.Record(req)
+	Ok(t, err)
+	Equals(t, "", id)
+}
+
+func TestWebhookRecorder_KeepsMaxWebhooks(t *testing.T) {
+	recorder, err := events_controllers.NewWebhookRecorder(t.TempDir(), 2)
+	Ok(t, err)
+	var ids []string
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("POST", "/events", bytes.NewBufferString("body"))
+		req, err = recorder.Capture(req)
+		Ok(t, err)
+		id, err := recorder.Record(req)
+		Ok(t, err)
+		ids = append(ids, id)
+	}
+
+	_, err = recorder.Load(ids[0])
+	Assert(t, os.IsNotExist(err), "exp oldest webhook to be deleted, got %v", err)
+	for _, id := range ids[1:] {
+		_, err = recorder.Load(id)
+		Ok(t, err)
+	}
+}
+
+func TestWebhookRecorder_Nil(t *testing.T) {
+	var recorder *events_controllers.WebhookRecorder
+	req, _ := http.NewRequest("POST", "/events", bytes.NewBufferString("body"))
+	req, err := recorder.Capture(req)
+	Ok(t, err)
+	id, err := recorder.Record(req)
+	Ok(t, err)
+	Equals(t, "", id)
+}
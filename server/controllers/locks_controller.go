@@ -33,7 +33,7 @@ type LocksController struct {
 // LockApply handles creating a global apply lock.
 // If Lock already exists it will be a no-op
 func (l *LocksController) LockApply(w http.ResponseWriter, r *http.Request) {
-	lock, err := l.ApplyLocker.LockApply()
+	lock, err := l.ApplyLocker.LockApply("")
 	if err != nil {
 		l.respond(w, logging.Error, http.StatusInternalServerError, "creating apply lock failed with: %s", err)
 		return
@@ -40,7 +40,7 @@ func TestCreateApplyLock(t *testing.T) {
 		lockTime, _ := time.Parse(layout, strLockTime)
 
 		l := mocks.NewMockApplyLocker()
-		When(l.LockApply()).ThenReturn(locking.ApplyCommandLock{
+		When(l.LockApply("")).ThenReturn(locking.ApplyCommandLock{
 			Locked: true,
 			Time:   lockTime,
 		}, nil)
@@ -59,7 +59,7 @@ func TestCreateApplyLock(t *testing.T) {
 		w := httptest.NewRecorder()
 
 		l := mocks.NewMockApplyLocker()
-		When(l.LockApply()).ThenReturn(locking.ApplyCommandLock{
+		When(l.LockApply("")).ThenReturn(locking.ApplyCommandLock{
 			Locked: false,
 		}, errors.New("failed to acquire lock"))
 
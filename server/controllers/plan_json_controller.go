@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// PlanJSONController serves the JSON of plans that's linked from plan
+// comments. Since plans contain sensitive values, requests must be
+// authenticated with web basic auth or the API secret.
+type PlanJSONController struct {
+	Logger logging.SimpleLogging
+	Store  events.PlanJSONStore
+	// APISecret is accepted in the X-Atlantis-Token header.
+	APISecret []byte
+	// WebAuthentication is true if web basic auth is enabled, in which case
+	// the request was already authenticated by the middleware.
+	WebAuthentication bool
+}
+
+// Get serves the plan JSON with the id in the route.
+func (p *PlanJSONController) Get(w http.ResponseWriter, r *http.Request) {
+	if !p.WebAuthentication {
+		if len(p.APISecret) == 0 {
+			p.respond(w, logging.Warn, http.StatusForbidden, "Plan json requires web basic auth or an API secret to be configured")
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(atlantisTokenHeader)), p.APISecret) != 1 {
+			p.respond(w, logging.Warn, http.StatusUnauthorized, "header %s did not match expected secret", atlantisTokenHeader)
+			return
+		}
+	}
+	id, ok := mux.Vars(r)["id"]
+	if !ok {
+		p.respond(w, logging.Warn, http.StatusBadRequest, "No plan json id in request")
+		return
+	}
+	planJSON, err := p.Store.Get(id)
+	if errors.Is(err, os.ErrNotExist) {
+		p.respond(w, logging.Info, http.StatusNotFound, "No plan json found for id %q", id)
+		return
+	}
+	if err != nil {
+		p.respond(w, logging.Error, http.StatusInternalServerError, "Failed getting plan json: %s", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(planJSON) // nolint: errcheck
+}
+
+func (p *PlanJSONController) respond(w http.ResponseWriter, lvl logging.LogLevel, responseCode int, format string, args ...interface{}) {
+	response := fmt.Sprintf(format, args...)
+	p.Logger.Log(lvl, response)
+	w.WriteHeader(responseCode)
+	fmt.Fprintln(w, response)
+}
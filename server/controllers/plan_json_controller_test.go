@@ -0,0 +1,82 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server/controllers"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestPlanJSONController_Get(t *testing.T) {
+	store := &events.FilePlanJSONStore{Dir: t.TempDir()}
+	id, err := store.Save(models.PullRequest{Num: 1}, "./default/", []byte(`{"format_version":"1.2"}`))
+	Ok(t, err)
+	pc := controllers.PlanJSONController{
+		Logger:    logging.NewNoopLogger(t),
+		Store:     store,
+		APISecret: []byte("secret"),
+	}
+
+	t.Run("found", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/plan-json/"+id, nil)
+		req.Header.Set("X-Atlantis-Token", "secret")
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		w := httptest.NewRecorder()
+		pc.Get(w, req)
+		ResponseContains(t, w, http.StatusOK, `{"format_version":"1.2"}`)
+		Equals(t, "application/json", w.Result().Header.Get("Content-Type"))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/plan-json/unknown", nil)
+		req.Header.Set("X-Atlantis-Token", "secret")
+		req = mux.SetURLVars(req, map[string]string{"id": "unknown"})
+		w := httptest.NewRecorder()
+		pc.Get(w, req)
+		ResponseContains(t, w, http.StatusNotFound, `No plan json found for id "unknown"`)
+	})
+
+	t.Run("no id", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/plan-json/", nil)
+		req.Header.Set("X-Atlantis-Token", "secret")
+		w := httptest.NewRecorder()
+		pc.Get(w, req)
+		ResponseContains(t, w, http.StatusBadRequest, "No plan json id in request")
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/plan-json/"+id, nil)
+		req.Header.Set("X-Atlantis-Token", "wrong")
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		w := httptest.NewRecorder()
+		pc.Get(w, req)
+		ResponseContains(t, w, http.StatusUnauthorized, "header X-Atlantis-Token did not match expected secret")
+	})
+
+	t.Run("web basic auth", func(t *testing.T) {
+		pc := pc
+		pc.APISecret = nil
+		pc.WebAuthentication = true
+		req, _ := http.NewRequest("GET", "/plan-json/"+id, nil)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		w := httptest.NewRecorder()
+		pc.Get(w, req)
+		ResponseContains(t, w, http.StatusOK, `{"format_version":"1.2"}`)
+	})
+
+	t.Run("no authentication configured", func(t *testing.T) {
+		pc := pc
+		pc.APISecret = nil
+		req, _ := http.NewRequest("GET", "/plan-json/"+id, nil)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		w := httptest.NewRecorder()
+		pc.Get(w, req)
+		ResponseContains(t, w, http.StatusForbidden, "Plan json requires web basic auth or an API secret to be configured")
+	})
+}
@@ -75,7 +75,7 @@ var IndexTemplate = template.Must(template.New("index.html.tmpl").Parse(`
         $("p.js-discard-success").show();
         setTimeout(function() {
           $("p.js-discard-success").fadeOut('slow',function(){
-            window.location.href = "/";
+            window.location.href = "{{ .CleanedBasePath }}/";
           })
         }, 5000); // <-- time in milliseconds
       }
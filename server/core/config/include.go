@@ -0,0 +1,93 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// includeTag is the YAML tag that replaces a value in the global config with
+// the contents of another file, ex. `pre_workflow_hooks: !include hooks.yaml`.
+const includeTag = "!include"
+
+// resolveIncludes replaces every value tagged with includeTag in the YAML
+// file at path, whose contents are data, with the contents of the file it
+// names. Relative paths are relative to the directory of the file that
+// includes them. Included files can include other files, but not a file
+// that's already being included.
+func resolveIncludes(path string, data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte(includeTag)) {
+		return data, nil
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	resolved, err := includeFiles(&doc, []string{absPath})
+	if err != nil {
+		return nil, err
+	}
+	if !resolved {
+		return data, nil
+	}
+	return yaml.Marshal(&doc)
+}
+
+// includeFiles resolves the includes in node and its children. stack holds
+// the files being included, the last one being the file node is in. It
+// returns whether any include was resolved.
+func includeFiles(node *yaml.Node, stack []string) (bool, error) {
+	if node.Tag != includeTag {
+		resolved := false
+		for _, child := range node.Content {
+			childResolved, err := includeFiles(child, stack)
+			if err != nil {
+				return false, err
+			}
+			resolved = resolved || childResolved
+		}
+		return resolved, nil
+	}
+
+	if node.Kind != yaml.ScalarNode || node.Value == "" {
+		return false, fmt.Errorf("%s line %d: %s must be followed by a file path", stack[len(stack)-1], node.Line, includeTag)
+	}
+	path := node.Value
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(stack[len(stack)-1]), path)
+	}
+	for _, including := range stack {
+		if including == path {
+			return false, fmt.Errorf("include cycle: %s", strings.Join(append(stack, path), " -> "))
+		}
+	}
+
+	data, err := os.ReadFile(path) // nolint: gosec
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to read included file %s", path)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return false, errors.Wrapf(err, "parsing included file %s", path)
+	}
+	if len(doc.Content) == 0 {
+		return false, fmt.Errorf("included file %s was empty", path)
+	}
+	included := doc.Content[0]
+	// Copy the stack so includes in sibling nodes don't share its backing array.
+	includedStack := append(append([]string{}, stack...), path)
+	if _, err := includeFiles(included, includedStack); err != nil {
+		return false, err
+	}
+	*node = *included
+	return true, nil
+}
@@ -69,21 +69,7 @@ func (p *ParserValidator) ParseRepoCfgData(repoCfgData []byte, globalCfg valid.G
 	}
 
 	validConfig := rawConfig.ToValid()
-
-	// Filter the repo config's projects based on pull request's branch. Only
-	// keep projects that either:
-	//
-	//   - Have no branch regex defined at all (i.e. match all branches), or
-	//   - Those that have branch regex matching the PR's base branch.
-	//
-	i := 0
-	for _, p := range validConfig.Projects {
-		if branch == "" || p.BranchRegex == nil || p.BranchRegex.Match([]byte(branch)) {
-			validConfig.Projects[i] = p
-			i++
-		}
-	}
-	validConfig.Projects = validConfig.Projects[:i]
+	validConfig.Projects = filterProjectsByBranch(validConfig.Projects, branch)
 
 	// We do the project name validation after we get the valid config because
 	// we need the defaults of dir and workspace to be populated.
@@ -102,6 +88,56 @@ func (p *ParserValidator) ParseRepoCfgData(repoCfgData []byte, globalCfg valid.G
 	return validConfig, err
 }
 
+// AddGeneratedProjects adds the projects in projectsData, a JSON array of
+// projects in the same format as the projects of atlantis.yaml, to repoCfg
+// and validates the result. repoCfg is empty if the repo has no atlantis.yaml.
+func (p *ParserValidator) AddGeneratedProjects(repoCfg valid.RepoCfg, projectsData []byte, globalCfg valid.GlobalCfg, repoID string, branch string) (valid.RepoCfg, error) {
+	// JSON is valid YAML so this also rejects unknown keys.
+	var rawProjects []raw.Project
+	if err := yaml.UnmarshalStrict(projectsData, &rawProjects); err != nil {
+		return repoCfg, err
+	}
+
+	validation.ErrorTag = "yaml"
+	if err := validation.Validate(rawProjects); err != nil {
+		return repoCfg, err
+	}
+
+	var projects []valid.Project
+	for _, rawProject := range rawProjects {
+		projects = append(projects, rawProject.ToValid())
+	}
+	if repoCfg.Version == 0 {
+		// Without an atlantis.yaml, generated projects use the current version.
+		repoCfg.Version = 3
+	}
+	repoCfg.Projects = append(repoCfg.Projects, filterProjectsByBranch(projects, branch)...)
+
+	if err := p.validateProjectNames(repoCfg); err != nil {
+		return repoCfg, err
+	}
+	err := globalCfg.ValidateRepoCfg(repoCfg, repoID)
+	return repoCfg, err
+}
+
+// filterProjectsByBranch filters projects based on pull request's branch.
+// Only projects that either:
+//
+//   - Have no branch regex defined at all (i.e. match all branches), or
+//   - Those that have branch regex matching the PR's base branch
+//
+// are kept.
+func filterProjectsByBranch(projects []valid.Project, branch string) []valid.Project {
+	i := 0
+	for _, p := range projects {
+		if branch == "" || p.BranchRegex == nil || p.BranchRegex.Match([]byte(branch)) {
+			projects[i] = p
+			i++
+		}
+	}
+	return projects[:i]
+}
+
 // ParseGlobalCfg returns the parsed and validated global repo config file at
 // configFile. defaultCfg will be merged into the parsed config.
 // If there is no file at configFile it will return an error.
@@ -113,6 +149,10 @@ func (p *ParserValidator) ParseGlobalCfg(configFile string, defaultCfg valid.Glo
 	if len(configData) == 0 {
 		return valid.GlobalCfg{}, fmt.Errorf("file %s was empty", configFile)
 	}
+	configData, err = resolveIncludes(configFile, configData)
+	if err != nil {
+		return valid.GlobalCfg{}, err
+	}
 
 	var rawCfg raw.GlobalCfg
 	if err := yaml.UnmarshalStrict(configData, &rawCfg); err != nil {
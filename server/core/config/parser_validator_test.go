@@ -73,6 +73,62 @@ func TestParseRepoCfg_BadPermissions(t *testing.T) {
 	ErrContains(t, "unable to read atlantis.yaml file: ", err)
 }
 
+func TestParseRepoCfg_RepoConfigFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	Ok(t, os.MkdirAll(filepath.Join(tmpDir, "infra"), 0700))
+	Ok(t, os.WriteFile(filepath.Join(tmpDir, "infra", "atlantis-config.yaml"), []byte(`
+version: 3
+projects:
+- dir: mydir
+`), 0600))
+	// A config at the default path must be ignored.
+	Ok(t, os.WriteFile(filepath.Join(tmpDir, "atlantis.yaml"), []byte("version: 3\nprojects:\n- dir: ignored\n"), 0600))
+
+	cfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{RepoConfigFile: "infra/atlantis-config.yaml"})
+	r := config.ParserValidator{}
+	repoCfg, err := r.ParseRepoCfg(tmpDir, cfg, "github.com/owner/repo", "main")
+	Ok(t, err)
+	Equals(t, 1, len(repoCfg.Projects))
+	Equals(t, "mydir", repoCfg.Projects[0].Dir)
+
+	// A server-side repo config can still override the file per repo.
+	cfg.Repos = append(cfg.Repos, valid.Repo{ID: "github.com/owner/repo", RepoConfigFile: "atlantis.yaml"})
+	repoCfg, err = r.ParseRepoCfg(tmpDir, cfg, "github.com/owner/repo", "main")
+	Ok(t, err)
+	Equals(t, "ignored", repoCfg.Projects[0].Dir)
+
+	// Repos that match but don't set a file use the default from the args.
+	cfg.Repos = append(cfg.Repos, valid.Repo{ID: "github.com/owner/other"})
+	Equals(t, "infra/atlantis-config.yaml", cfg.RepoConfigFile("github.com/owner/other"))
+}
+
+// Test that repo_config_file in a server-side repo config wins over the
+// default set by --repo-config-file, even for repos matching every repo.
+func TestParseGlobalCfg_RepoConfigFileOverridesArgs(t *testing.T) {
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "repos.yaml")
+	Ok(t, os.WriteFile(path, []byte(`repos:
+- id: /.*/
+  repo_config_file: all/atlantis.yaml
+- id: github.com/owner/repo
+  repo_config_file: repo/atlantis.yaml
+- id: github.com/owner/unset
+`), 0600))
+
+	r := config.ParserValidator{}
+	cfg, err := r.ParseGlobalCfg(path, valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{RepoConfigFile: "infra/atlantis.yaml"}))
+	Ok(t, err)
+	Equals(t, "repo/atlantis.yaml", cfg.RepoConfigFile("github.com/owner/repo"))
+	Equals(t, "all/atlantis.yaml", cfg.RepoConfigFile("github.com/owner/other"))
+	Equals(t, "all/atlantis.yaml", cfg.RepoConfigFile("github.com/owner/unset"))
+
+	// Without a repo_config_file the flag's default is used.
+	Ok(t, os.WriteFile(path, []byte("repos:\n- id: /.*/\n"), 0600))
+	cfg, err = r.ParseGlobalCfg(path, valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{RepoConfigFile: "infra/atlantis.yaml"}))
+	Ok(t, err)
+	Equals(t, "infra/atlantis.yaml", cfg.RepoConfigFile("github.com/owner/other"))
+}
+
 // Test both ParseRepoCfg and ParseGlobalCfg when given in valid YAML.
 // We only have a few cases here because we assume the YAML library to be
 // well tested. See https://github.com/go-yaml/yaml/blob/v2/decode_test.go#L810.
@@ -1287,18 +1343,80 @@ func TestParseGlobalCfg(t *testing.T) {
   branch: /?/`,
 			expErr: "repos: (0: (branch: parsing: /?/: error parsing regexp: missing argument to repetition operator: `?`.).).",
 		},
+		"invalid delete_pr_workspaces without slashes": {
+			input: `repos:
+- id: /.*/
+  delete_pr_workspaces: pr-.*`,
+			expErr: "repos: (0: (delete_pr_workspaces: regex must begin and end with a slash '/'.).).",
+		},
+		"invalid delete_pr_workspaces regex": {
+			input: `repos:
+- id: /.*/
+  delete_pr_workspaces: /?/`,
+			expErr: "repos: (0: (delete_pr_workspaces: parsing: /?/: error parsing regexp: missing argument to repetition operator: `?`.).).",
+		},
+		"invalid plan_warnings_as_errors without slashes": {
+			input: `repos:
+- id: /.*/
+  plan_warnings_as_errors: [/deprecated/, Deprecated]`,
+			expErr: "repos: (0: (plan_warnings_as_errors: regex \"Deprecated\" must begin and end with a slash '/'.).).",
+		},
+		"invalid plan_warnings_as_errors regex": {
+			input: `repos:
+- id: /.*/
+  plan_warnings_as_errors: ["/?/"]`,
+			expErr: "repos: (0: (plan_warnings_as_errors: parsing: /?/: error parsing regexp: missing argument to repetition operator: `?`.).).",
+		},
+		"invalid working_dir_permissions": {
+			input: `repos:
+- id: /.*/
+  working_dir_permissions:
+    dir_mode: "0600"`,
+			expErr: "repos: (0: (working_dir_permissions: (dir_mode: must give the owner at least 0700 permissions.).).).",
+		},
+		"invalid clean_env_allowed_vars": {
+			input: `repos:
+- id: /.*/
+  clean_env: true
+  clean_env_allowed_vars: [PATH, "HOME=/root"]`,
+			expErr: "repos: (0: (clean_env_allowed_vars: \"HOME=/root\" is not a valid environment variable name.).).",
+		},
 		"invalid repo_config_file which starts with a slash": {
 			input: `repos:
 - id: /.*/
   repo_config_file: /etc/passwd`,
 			expErr: "repos: (0: (repo_config_file: must not starts with a slash '/'.).).",
 		},
+		"invalid relative terraform_binary": {
+			input: `repos:
+- id: /.*/
+  terraform_binary: bin/terraform`,
+			expErr: "repos: (0: (terraform_binary: must be an absolute path.).).",
+		},
+		"invalid allowed_terraform_versions": {
+			input: `repos:
+- id: /.*/
+  allowed_terraform_versions: newer than 1.3`,
+			expErr: "repos: (0: (allowed_terraform_versions: parsing: newer than 1.3: Malformed constraint: newer than 1.3.).).",
+		},
+		"invalid vcs_base_url": {
+			input: `repos:
+- id: /.*/
+  vcs_base_url: ghe2.example.com`,
+			expErr: "repos: (0: (vcs_base_url: \"ghe2.example.com\" must be an http:// or https:// URL.).).",
+		},
 		"invalid repo_config_file which contains parent directory path": {
 			input: `repos:
 - id: /.*/
   repo_config_file: ../../etc/passwd`,
 			expErr: "repos: (0: (repo_config_file: must not contains parent directory path like '../'.).).",
 		},
+		"invalid project_name_template": {
+			input: `repos:
+- id: /.*/
+  project_name_template: "{{ .Path"`,
+			expErr: "repos: (0: (project_name_template: template: project_name_template:1: unclosed action.).).",
+		},
 		"workflow doesn't exist": {
 			input: `repos:
 - id: /.*/
@@ -1309,7 +1427,7 @@ func TestParseGlobalCfg(t *testing.T) {
 			input: `repos:
 - id: /.*/
   allowed_overrides: [invalid]`,
-			expErr: "repos: (0: (allowed_overrides: \"invalid\" is not a valid override, only \"plan_requirements\", \"apply_requirements\", \"import_requirements\", \"workflow\", \"delete_source_branch_on_merge\", \"repo_locking\", \"policy_check\", and \"custom_policy_check\" are supported.).).",
+			expErr: "repos: (0: (allowed_overrides: \"invalid\" is not a valid override, only \"plan_requirements\", \"apply_requirements\", \"import_requirements\", \"workflow\", \"delete_source_branch_on_merge\", \"repo_locking\", \"policy_check\", \"custom_policy_check\", \"deployment_environment\", and \"plan_presets\" are supported.).).",
 		},
 		"invalid plan_requirement": {
 			input: `repos:
@@ -1321,7 +1439,7 @@ func TestParseGlobalCfg(t *testing.T) {
 			input: `repos:
 - id: /.*/
   apply_requirements: [invalid]`,
-			expErr: "repos: (0: (apply_requirements: \"invalid\" is not a valid apply_requirement, only \"approved\", \"mergeable\" and \"undiverged\" are supported.).).",
+			expErr: "repos: (0: (apply_requirements: \"invalid\" is not a valid apply_requirement, only \"approved\", \"mergeable\", \"undiverged\", \"no_destroy\", \"signed_commits\", \"clean_working_tree\", \"approval_rule:<rule name>\", \"commit_status:<context>\" and \"plan_reaction:<reaction>\" are supported.).).",
 		},
 		"invalid import_requirement": {
 			input: `repos:
@@ -1329,6 +1447,80 @@ func TestParseGlobalCfg(t *testing.T) {
   import_requirements: [invalid]`,
 			expErr: "repos: (0: (import_requirements: \"invalid\" is not a valid import_requirement, only \"approved\", \"mergeable\" and \"undiverged\" are supported.).).",
 		},
+		"invalid pre_workflow_hooks successCodes": {
+			input: `repos:
+- id: /.*/
+  pre_workflow_hooks:
+    - run: echo hi
+      successCodes: 2, two`,
+			expErr: "repos: (0: (pre_workflow_hooks: \"two\" is not a valid exit code in successCodes \"2, two\".).).",
+		},
+		"invalid pre_workflow_hooks priority": {
+			input: `repos:
+- id: /.*/
+  pre_workflow_hooks:
+    - run: echo hi
+      priority: high`,
+			expErr: "repos: (0: (pre_workflow_hooks: \"high\" is not a valid priority, must be an integer.).).",
+		},
+		"invalid pre_workflow_hooks events": {
+			input: `repos:
+- id: /.*/
+  pre_workflow_hooks:
+    - run: echo hi
+      events: autoplan, push`,
+			expErr: "repos: (0: (pre_workflow_hooks: \"push\" is not a valid event in events \"autoplan, push\", must be autoplan or comment.).).",
+		},
+		"post_workflow_hooks events": {
+			input: `repos:
+- id: /.*/
+  post_workflow_hooks:
+    - run: echo hi
+      events: comment`,
+			expErr: "repos: (0: (post_workflow_hooks: events is only supported by pre workflow hooks.).).",
+		},
+		"invalid post_workflow_hooks successCodes": {
+			input: `repos:
+- id: /.*/
+  post_workflow_hooks:
+    - run: echo hi
+      successCodes: 256`,
+			expErr: "repos: (0: (post_workflow_hooks: \"256\" is not a valid exit code in successCodes \"256\".).).",
+		},
+		"invalid post_workflow_hooks per_project": {
+			input: `repos:
+- id: /.*/
+  post_workflow_hooks:
+    - run: echo hi
+      commands: plan
+      per_project: yes please`,
+			expErr: "repos: (0: (post_workflow_hooks: \"yes please\" is not a valid per_project, must be true or false.).).",
+		},
+		"post_workflow_hooks per_project without commands": {
+			input: `repos:
+- id: /.*/
+  post_workflow_hooks:
+    - run: echo hi
+      per_project: true`,
+			expErr: "repos: (0: (post_workflow_hooks: per_project requires commands to be set to plan and/or apply.).).",
+		},
+		"post_workflow_hooks per_project for other commands": {
+			input: `repos:
+- id: /.*/
+  post_workflow_hooks:
+    - run: echo hi
+      commands: plan, unlock
+      per_project: true`,
+			expErr: "repos: (0: (post_workflow_hooks: \"unlock\" is not supported with per_project, commands must be plan and/or apply.).).",
+		},
+		"pre_workflow_hooks per_project": {
+			input: `repos:
+- id: /.*/
+  pre_workflow_hooks:
+    - run: echo hi
+      per_project: true`,
+			expErr: "repos: (0: (pre_workflow_hooks: per_project is only supported by post workflow hooks.).).",
+		},
 		"no workflows key": {
 			input: `repos: []`,
 			exp:   defaultCfg,
@@ -1337,6 +1529,193 @@ func TestParseGlobalCfg(t *testing.T) {
 			input: `workflows:`,
 			exp:   defaultCfg,
 		},
+		"plan_only": {
+			input: `
+repos:
+- id: github.com/owner/repo
+  plan_only: true`,
+			exp: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					defaultCfg.Repos[0],
+					{
+						ID:       "github.com/owner/repo",
+						PlanOnly: Bool(true),
+					},
+				},
+				Workflows: defaultCfg.Workflows,
+			},
+		},
+		"terraform_binary": {
+			input: `
+repos:
+- id: github.com/owner/repo
+  terraform_binary: /opt/terraform/1.5.7/terraform`,
+			exp: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					defaultCfg.Repos[0],
+					{
+						ID:              "github.com/owner/repo",
+						TerraformBinary: "/opt/terraform/1.5.7/terraform",
+					},
+				},
+				Workflows: defaultCfg.Workflows,
+			},
+		},
+		"allowed_terraform_versions": {
+			input: `
+repos:
+- id: github.com/owner/repo
+  allowed_terraform_versions: ">= 1.3.0, < 2.0.0"`,
+			exp: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					defaultCfg.Repos[0],
+					{
+						ID:                       "github.com/owner/repo",
+						AllowedTerraformVersions: version.MustConstraints(version.NewConstraint(">= 1.3.0, < 2.0.0")),
+					},
+				},
+				Workflows: defaultCfg.Workflows,
+			},
+		},
+		"vcs_base_url": {
+			input: `
+repos:
+- id: github.com/owner/repo
+  vcs_base_url: https://ghe2.example.com/`,
+			exp: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					defaultCfg.Repos[0],
+					{
+						ID:         "github.com/owner/repo",
+						VCSBaseURL: "https://ghe2.example.com",
+					},
+				},
+				Workflows: defaultCfg.Workflows,
+			},
+		},
+		"pr_description_vars": {
+			input: `
+repos:
+- id: github.com/owner/repo
+  pr_description_vars: true`,
+			exp: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					defaultCfg.Repos[0],
+					{
+						ID:                "github.com/owner/repo",
+						PRDescriptionVars: Bool(true),
+					},
+				},
+				Workflows: defaultCfg.Workflows,
+			},
+		},
+		"delete_pr_workspaces": {
+			input: `
+repos:
+- id: github.com/owner/repo
+  delete_pr_workspaces: /^pr-\d+$/`,
+			exp: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					defaultCfg.Repos[0],
+					{
+						ID:                 "github.com/owner/repo",
+						DeletePRWorkspaces: regexp.MustCompile(`^pr-\d+$`),
+					},
+				},
+				Workflows: defaultCfg.Workflows,
+			},
+		},
+		"plan_warnings_as_errors": {
+			input: `
+repos:
+- id: github.com/owner/repo
+  plan_warnings_as_errors:
+  - /(?i)deprecated/
+  - "/^Warning: Value for undeclared variable$/"`,
+			exp: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					defaultCfg.Repos[0],
+					{
+						ID: "github.com/owner/repo",
+						PlanWarningsAsErrors: []*regexp.Regexp{
+							regexp.MustCompile(`(?i)deprecated`),
+							regexp.MustCompile(`^Warning: Value for undeclared variable$`),
+						},
+					},
+				},
+				Workflows: defaultCfg.Workflows,
+			},
+		},
+		"working_dir_permissions": {
+			input: `
+repos:
+- id: github.com/owner/repo
+  working_dir_permissions:
+    dir_mode: "0750"
+    file_mode: "0640"`,
+			exp: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					defaultCfg.Repos[0],
+					{
+						ID:                    "github.com/owner/repo",
+						WorkingDirPermissions: &valid.WorkingDirPermissions{DirMode: 0750, FileMode: 0640},
+					},
+				},
+				Workflows: defaultCfg.Workflows,
+			},
+		},
+		"confirm_apply": {
+			input: `
+repos:
+- id: github.com/owner/repo
+  confirm_apply: true`,
+			exp: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					defaultCfg.Repos[0],
+					{
+						ID:           "github.com/owner/repo",
+						ConfirmApply: Bool(true),
+					},
+				},
+				Workflows: defaultCfg.Workflows,
+			},
+		},
+		"deployment_environment": {
+			input: `
+repos:
+- id: github.com/owner/repo
+  deployment_environment: production
+  allowed_overrides: [deployment_environment]`,
+			exp: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					defaultCfg.Repos[0],
+					{
+						ID:                    "github.com/owner/repo",
+						DeploymentEnvironment: "production",
+						AllowedOverrides:      []string{"deployment_environment"},
+					},
+				},
+				Workflows: defaultCfg.Workflows,
+			},
+		},
+		"clean_env": {
+			input: `
+repos:
+- id: github.com/owner/repo
+  clean_env: true
+  clean_env_allowed_vars: [PATH, HOME]`,
+			exp: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					defaultCfg.Repos[0],
+					{
+						ID:                  "github.com/owner/repo",
+						CleanEnv:            Bool(true),
+						CleanEnvAllowedVars: []string{"PATH", "HOME"},
+					},
+				},
+				Workflows: defaultCfg.Workflows,
+			},
+		},
 		"workflow name but the rest is empty": {
 			input: `
 workflows:
@@ -1641,6 +2020,112 @@ workflows:
 	}
 }
 
+func TestParseGlobalCfg_Includes(t *testing.T) {
+	cases := map[string]struct {
+		files  map[string]string
+		expErr string
+	}{
+		"multiple files": {
+			files: map[string]string{
+				"repos.yaml": `
+- id: github.com/owner/repo
+  pre_workflow_hooks: !include hooks/hooks.yaml
+  workflow: custom
+- id: /.*/
+  pre_workflow_hooks: !include hooks/hooks.yaml
+`,
+				"hooks/hooks.yaml": `
+- run: echo hook
+`,
+				"workflows.yaml": `
+custom:
+  plan:
+    steps: !include hooks/steps.yaml
+`,
+				"hooks/steps.yaml": `
+- init
+- plan
+`,
+			},
+		},
+		"anchors": {
+			files: map[string]string{
+				"repos.yaml": `
+- id: github.com/owner/repo
+  pre_workflow_hooks: &hooks
+  - run: echo hook
+  workflow: custom
+- id: /.*/
+  pre_workflow_hooks: *hooks
+`,
+				"workflows.yaml": `
+custom:
+  plan:
+    steps: [init, plan]
+`,
+			},
+		},
+		"missing file": {
+			files: map[string]string{
+				"repos.yaml":     "!include hooks/missing.yaml",
+				"workflows.yaml": "{}",
+			},
+			expErr: "unable to read included file <tmp>/hooks/missing.yaml: open <tmp>/hooks/missing.yaml: no such file or directory",
+		},
+		"cycle": {
+			files: map[string]string{
+				"repos.yaml":     "!include repos2.yaml",
+				"repos2.yaml":    "!include repos.yaml",
+				"workflows.yaml": "{}",
+			},
+			expErr: "include cycle: <tmp>/conf.yaml -> <tmp>/repos.yaml -> <tmp>/repos2.yaml -> <tmp>/repos.yaml",
+		},
+		"including the main file": {
+			files: map[string]string{
+				"repos.yaml":     "!include conf.yaml",
+				"workflows.yaml": "{}",
+			},
+			expErr: "include cycle: <tmp>/conf.yaml -> <tmp>/repos.yaml -> <tmp>/conf.yaml",
+		},
+		"no path": {
+			files: map[string]string{
+				"repos.yaml":     "!include [a.yaml]",
+				"workflows.yaml": "{}",
+			},
+			expErr: "<tmp>/repos.yaml line 1: !include must be followed by a file path",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			tmp := t.TempDir()
+			path := filepath.Join(tmp, "conf.yaml")
+			Ok(t, os.WriteFile(path, []byte("repos: !include repos.yaml\nworkflows: !include workflows.yaml\n"), 0600))
+			for name, contents := range c.files {
+				Ok(t, os.MkdirAll(filepath.Dir(filepath.Join(tmp, name)), 0700))
+				Ok(t, os.WriteFile(filepath.Join(tmp, name), []byte(contents), 0600))
+			}
+
+			r := config.ParserValidator{}
+			act, err := r.ParseGlobalCfg(path, valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{}))
+			if c.expErr != "" {
+				ErrEquals(t, strings.Replace(c.expErr, "<tmp>", tmp, -1), err)
+				return
+			}
+			Ok(t, err)
+
+			hooks := []*valid.WorkflowHook{{StepName: "run", RunCommand: "echo hook"}}
+			// The default repo config is first.
+			Equals(t, 3, len(act.Repos))
+			Equals(t, "github.com/owner/repo", act.Repos[1].ID)
+			Equals(t, hooks, act.Repos[1].PreWorkflowHooks)
+			Equals(t, "custom", act.Repos[1].Workflow.Name)
+			Equals(t, []valid.Step{{StepName: "init"}, {StepName: "plan"}}, act.Repos[1].Workflow.Plan.Steps)
+			Equals(t, hooks, act.Repos[2].PreWorkflowHooks)
+		})
+	}
+}
+
 // Test that if we pass in JSON strings everything should parse fine.
 func TestParserValidator_ParseGlobalCfgJSON(t *testing.T) {
 	customWorkflow := valid.Workflow{
@@ -1851,6 +2336,84 @@ func TestParserValidator_ParseGlobalCfgJSON(t *testing.T) {
 }
 
 // Test legacy shell parsing vs v3 parsing.
+func TestParserValidator_AddGeneratedProjects(t *testing.T) {
+	r := config.ParserValidator{}
+	repoID := "github.com/owner/repo"
+	prodName := "prod"
+	appName := "app"
+
+	cases := map[string]struct {
+		repoCfg  valid.RepoCfg
+		projects string
+		exp      valid.RepoCfg
+		expErr   string
+	}{
+		"no atlantis.yaml": {
+			projects: `[{"name": "prod", "dir": "envs/prod"}, {"dir": "envs/staging", "branch": "/^staging$/"}]`,
+			exp: valid.RepoCfg{
+				Version: 3,
+				Projects: []valid.Project{
+					{
+						Name:      &prodName,
+						Dir:       "envs/prod",
+						Workspace: "default",
+						Autoplan: valid.Autoplan{
+							WhenModified: raw.DefaultAutoPlanWhenModified,
+							Enabled:      true,
+						},
+					},
+				},
+			},
+		},
+		"added to atlantis.yaml projects": {
+			repoCfg: valid.RepoCfg{
+				Version:  3,
+				Projects: []valid.Project{{Name: &appName, Dir: "app", Workspace: "default"}},
+			},
+			projects: `[{"name": "prod", "dir": "envs/prod", "autoplan": {"enabled": false}}]`,
+			exp: valid.RepoCfg{
+				Version: 3,
+				Projects: []valid.Project{
+					{Name: &appName, Dir: "app", Workspace: "default"},
+					{
+						Name:      &prodName,
+						Dir:       "envs/prod",
+						Workspace: "default",
+						Autoplan: valid.Autoplan{
+							WhenModified: raw.DefaultAutoPlanWhenModified,
+							Enabled:      false,
+						},
+					},
+				},
+			},
+		},
+		"not an array": {
+			projects: `{"dir": "envs/prod"}`,
+			expErr:   "yaml: unmarshal errors:\n  line 1: cannot unmarshal !!map into []raw.Project",
+		},
+		"invalid project": {
+			projects: `[{"dir": "../envs/prod"}]`,
+			expErr:   "0: (dir: cannot contain '..'.).",
+		},
+		"unnamed projects in the same dir": {
+			projects: `[{"dir": "envs/prod"}, {"dir": "envs/prod"}]`,
+			expErr:   "there are two or more projects with dir: \"envs/prod\" workspace: \"default\" that are not all named; they must have a 'name' key so they can be targeted for apply's separately",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			act, err := r.AddGeneratedProjects(c.repoCfg, []byte(c.projects), globalCfg, repoID, "main")
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.exp, act)
+		})
+	}
+}
+
 func TestParseRepoCfg_V2ShellParsing(t *testing.T) {
 	cases := []struct {
 		in       string
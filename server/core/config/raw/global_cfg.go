@@ -2,10 +2,13 @@ package raw
 
 import (
 	"fmt"
+	"net/url"
+	"path/filepath"
 	"regexp"
 	"strings"
 
 	validation "github.com/go-ozzo/ozzo-validation"
+	version "github.com/hashicorp/go-version"
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/core/config/valid"
 )
@@ -23,6 +26,7 @@ type Repo struct {
 	ID                        string         `yaml:"id" json:"id"`
 	Branch                    string         `yaml:"branch" json:"branch"`
 	RepoConfigFile            string         `yaml:"repo_config_file" json:"repo_config_file"`
+	ProjectNameTemplate       string         `yaml:"project_name_template,omitempty" json:"project_name_template,omitempty"`
 	PlanRequirements          []string       `yaml:"plan_requirements" json:"plan_requirements"`
 	ApplyRequirements         []string       `yaml:"apply_requirements" json:"apply_requirements"`
 	ImportRequirements        []string       `yaml:"import_requirements" json:"import_requirements"`
@@ -36,6 +40,20 @@ type Repo struct {
 	RepoLocking               *bool          `yaml:"repo_locking,omitempty" json:"repo_locking,omitempty"`
 	PolicyCheck               *bool          `yaml:"policy_check,omitempty" json:"policy_check,omitempty"`
 	CustomPolicyCheck         *bool          `yaml:"custom_policy_check,omitempty" json:"custom_policy_check,omitempty"`
+	PlanOnly                  *bool          `yaml:"plan_only,omitempty" json:"plan_only,omitempty"`
+	PRDescriptionVars         *bool          `yaml:"pr_description_vars,omitempty" json:"pr_description_vars,omitempty"`
+	PlanWarningsAsErrors      []string       `yaml:"plan_warnings_as_errors,omitempty" json:"plan_warnings_as_errors,omitempty"`
+	DeletePRWorkspaces        string         `yaml:"delete_pr_workspaces,omitempty" json:"delete_pr_workspaces,omitempty"`
+	ProjectGenerator          string         `yaml:"project_generator,omitempty" json:"project_generator,omitempty"`
+	TerraformBinary           string         `yaml:"terraform_binary,omitempty" json:"terraform_binary,omitempty"`
+	VCSBaseURL                string         `yaml:"vcs_base_url,omitempty" json:"vcs_base_url,omitempty"`
+	ConfirmApply              *bool          `yaml:"confirm_apply,omitempty" json:"confirm_apply,omitempty"`
+	DeploymentEnvironment     string         `yaml:"deployment_environment,omitempty" json:"deployment_environment,omitempty"`
+	CleanEnv                  *bool          `yaml:"clean_env,omitempty" json:"clean_env,omitempty"`
+	CleanEnvAllowedVars       []string       `yaml:"clean_env_allowed_vars,omitempty" json:"clean_env_allowed_vars,omitempty"`
+	AllowedTerraformVersions  string         `yaml:"allowed_terraform_versions,omitempty" json:"allowed_terraform_versions,omitempty"`
+
+	WorkingDirPermissions *WorkingDirPermissions `yaml:"working_dir_permissions,omitempty" json:"working_dir_permissions,omitempty"`
 }
 
 func (g GlobalCfg) Validate() error {
@@ -176,6 +194,39 @@ func (r Repo) Validate() error {
 		return errors.Wrapf(err, "parsing: %s", branch)
 	}
 
+	deletePRWorkspacesValid := func(value interface{}) error {
+		workspaces := value.(string)
+		if workspaces == "" {
+			return nil
+		}
+		if !strings.HasPrefix(workspaces, "/") || !strings.HasSuffix(workspaces, "/") || len(workspaces) < 2 {
+			return errors.New("regex must begin and end with a slash '/'")
+		}
+		_, err := regexp.Compile(workspaces[1 : len(workspaces)-1])
+		return errors.Wrapf(err, "parsing: %s", workspaces)
+	}
+
+	cleanEnvAllowedVarsValid := func(value interface{}) error {
+		for _, name := range value.([]string) {
+			if name == "" || strings.Contains(name, "=") {
+				return fmt.Errorf("%q is not a valid environment variable name", name)
+			}
+		}
+		return nil
+	}
+
+	planWarningsAsErrorsValid := func(value interface{}) error {
+		for _, pattern := range value.([]string) {
+			if !strings.HasPrefix(pattern, "/") || !strings.HasSuffix(pattern, "/") || len(pattern) < 2 {
+				return fmt.Errorf("regex %q must begin and end with a slash '/'", pattern)
+			}
+			if _, err := regexp.Compile(pattern[1 : len(pattern)-1]); err != nil {
+				return errors.Wrapf(err, "parsing: %s", pattern)
+			}
+		}
+		return nil
+	}
+
 	repoConfigFileValid := func(value interface{}) error {
 		repoConfigFile := value.(string)
 		if repoConfigFile == "" {
@@ -190,11 +241,49 @@ func (r Repo) Validate() error {
 		return nil
 	}
 
+	projectNameTemplateValid := func(value interface{}) error {
+		projectNameTemplate := value.(string)
+		if projectNameTemplate == "" {
+			return nil
+		}
+		_, err := valid.ParseProjectNameTemplate(projectNameTemplate)
+		return err
+	}
+
+	terraformBinaryValid := func(value interface{}) error {
+		terraformBinary := value.(string)
+		if terraformBinary != "" && !filepath.IsAbs(terraformBinary) {
+			return errors.New("must be an absolute path")
+		}
+		return nil
+	}
+
+	allowedTerraformVersionsValid := func(value interface{}) error {
+		allowedTerraformVersions := value.(string)
+		if allowedTerraformVersions == "" {
+			return nil
+		}
+		_, err := version.NewConstraint(allowedTerraformVersions)
+		return errors.Wrapf(err, "parsing: %s", allowedTerraformVersions)
+	}
+
+	vcsBaseURLValid := func(value interface{}) error {
+		vcsBaseURL := value.(string)
+		if vcsBaseURL == "" {
+			return nil
+		}
+		parsed, err := url.Parse(vcsBaseURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("%q must be an http:// or https:// URL", vcsBaseURL)
+		}
+		return nil
+	}
+
 	overridesValid := func(value interface{}) error {
 		overrides := value.([]string)
 		for _, o := range overrides {
-			if o != valid.PlanRequirementsKey && o != valid.ApplyRequirementsKey && o != valid.ImportRequirementsKey && o != valid.WorkflowKey && o != valid.DeleteSourceBranchOnMergeKey && o != valid.RepoLockingKey && o != valid.PolicyCheckKey && o != valid.CustomPolicyCheckKey {
-				return fmt.Errorf("%q is not a valid override, only %q, %q, %q, %q, %q, %q, %q, and %q are supported", o, valid.PlanRequirementsKey, valid.ApplyRequirementsKey, valid.ImportRequirementsKey, valid.WorkflowKey, valid.DeleteSourceBranchOnMergeKey, valid.RepoLockingKey, valid.PolicyCheckKey, valid.CustomPolicyCheckKey)
+			if o != valid.PlanRequirementsKey && o != valid.ApplyRequirementsKey && o != valid.ImportRequirementsKey && o != valid.WorkflowKey && o != valid.DeleteSourceBranchOnMergeKey && o != valid.RepoLockingKey && o != valid.PolicyCheckKey && o != valid.CustomPolicyCheckKey && o != valid.DeploymentEnvironmentKey && o != valid.PlanPresetsKey {
+				return fmt.Errorf("%q is not a valid override, only %q, %q, %q, %q, %q, %q, %q, %q, %q, and %q are supported", o, valid.PlanRequirementsKey, valid.ApplyRequirementsKey, valid.ImportRequirementsKey, valid.WorkflowKey, valid.DeleteSourceBranchOnMergeKey, valid.RepoLockingKey, valid.PolicyCheckKey, valid.CustomPolicyCheckKey, valid.DeploymentEnvironmentKey, valid.PlanPresetsKey)
 			}
 		}
 		return nil
@@ -211,10 +300,18 @@ func (r Repo) Validate() error {
 		return nil
 	}
 
-	return validation.ValidateStruct(&r,
+	err := validation.ValidateStruct(&r,
 		validation.Field(&r.ID, validation.Required, validation.By(idValid)),
 		validation.Field(&r.Branch, validation.By(branchValid)),
 		validation.Field(&r.RepoConfigFile, validation.By(repoConfigFileValid)),
+		validation.Field(&r.ProjectNameTemplate, validation.By(projectNameTemplateValid)),
+		validation.Field(&r.TerraformBinary, validation.By(terraformBinaryValid)),
+		validation.Field(&r.AllowedTerraformVersions, validation.By(allowedTerraformVersionsValid)),
+		validation.Field(&r.VCSBaseURL, validation.By(vcsBaseURLValid)),
+		validation.Field(&r.DeletePRWorkspaces, validation.By(deletePRWorkspacesValid)),
+		validation.Field(&r.PlanWarningsAsErrors, validation.By(planWarningsAsErrorsValid)),
+		validation.Field(&r.CleanEnvAllowedVars, validation.By(cleanEnvAllowedVarsValid)),
+		validation.Field(&r.WorkingDirPermissions),
 		validation.Field(&r.AllowedOverrides, validation.By(overridesValid)),
 		validation.Field(&r.PlanRequirements, validation.By(validPlanReq)),
 		validation.Field(&r.ApplyRequirements, validation.By(validApplyReq)),
@@ -222,6 +319,38 @@ func (r Repo) Validate() error {
 		validation.Field(&r.Workflow, validation.By(workflowExists)),
 		validation.Field(&r.DeleteSourceBranchOnMerge, validation.By(deleteSourceBranchOnMergeValid)),
 	)
+	if err != nil {
+		return err
+	}
+
+	// Workflow hooks are validated individually since they're parsed as
+	// generic maps.
+	for _, hook := range r.PreWorkflowHooks {
+		if err := hook.ValidateSuccessCodes(); err != nil {
+			return validation.Errors{"pre_workflow_hooks": err}
+		}
+		if err := hook.ValidatePriority(); err != nil {
+			return validation.Errors{"pre_workflow_hooks": err}
+		}
+		if err := hook.ValidateEvents(); err != nil {
+			return validation.Errors{"pre_workflow_hooks": err}
+		}
+		if _, ok := hook.StringVal[PerProjectKey]; ok {
+			return validation.Errors{"pre_workflow_hooks": fmt.Errorf("%s is only supported by post workflow hooks", PerProjectKey)}
+		}
+	}
+	for _, hook := range r.PostWorkflowHooks {
+		if err := hook.ValidateSuccessCodes(); err != nil {
+			return validation.Errors{"post_workflow_hooks": err}
+		}
+		if _, ok := hook.StringVal[EventsKey]; ok {
+			return validation.Errors{"post_workflow_hooks": fmt.Errorf("%s is only supported by pre workflow hooks", EventsKey)}
+		}
+		if err := hook.ValidatePerProject(); err != nil {
+			return validation.Errors{"post_workflow_hooks": err}
+		}
+	}
+	return nil
 }
 
 func (r Repo) ToValid(workflows map[string]valid.Workflow, globalPlanReqs []string, globalApplyReqs []string, globalImportReqs []string) valid.Repo {
@@ -242,6 +371,32 @@ func (r Repo) ToValid(workflows map[string]valid.Workflow, globalPlanReqs []stri
 		branchRegex = regexp.MustCompile(withoutSlashes)
 	}
 
+	var deletePRWorkspacesRegex *regexp.Regexp
+	if r.DeletePRWorkspaces != "" {
+		// Safe to use MustCompile because we test it in Validate().
+		deletePRWorkspacesRegex = regexp.MustCompile(r.DeletePRWorkspaces[1 : len(r.DeletePRWorkspaces)-1])
+	}
+
+	var allowedTerraformVersions version.Constraints
+	if r.AllowedTerraformVersions != "" {
+		// Safe to ignore the error because we test it in Validate().
+		allowedTerraformVersions, _ = version.NewConstraint(r.AllowedTerraformVersions)
+	}
+
+	var planWarningsAsErrors []*regexp.Regexp
+	if r.PlanWarningsAsErrors != nil {
+		planWarningsAsErrors = []*regexp.Regexp{}
+		for _, pattern := range r.PlanWarningsAsErrors {
+			// Safe to use MustCompile because we test it in Validate().
+			planWarningsAsErrors = append(planWarningsAsErrors, regexp.MustCompile(pattern[1:len(pattern)-1]))
+		}
+	}
+
+	var workingDirPermissions *valid.WorkingDirPermissions
+	if r.WorkingDirPermissions != nil {
+		workingDirPermissions = r.WorkingDirPermissions.ToValid()
+	}
+
 	var workflow *valid.Workflow
 	if r.Workflow != nil {
 		// This key is guaranteed to exist because we test for it in
@@ -320,6 +475,7 @@ OuterGlobalImportReqs:
 		IDRegex:                   idRegex,
 		BranchRegex:               branchRegex,
 		RepoConfigFile:            r.RepoConfigFile,
+		ProjectNameTemplate:       r.ProjectNameTemplate,
 		PlanRequirements:          mergedPlanReqs,
 		ApplyRequirements:         mergedApplyReqs,
 		ImportRequirements:        mergedImportReqs,
@@ -333,5 +489,18 @@ OuterGlobalImportReqs:
 		RepoLocking:               r.RepoLocking,
 		PolicyCheck:               r.PolicyCheck,
 		CustomPolicyCheck:         r.CustomPolicyCheck,
+		PlanOnly:                  r.PlanOnly,
+		PRDescriptionVars:         r.PRDescriptionVars,
+		PlanWarningsAsErrors:      planWarningsAsErrors,
+		WorkingDirPermissions:     workingDirPermissions,
+		DeletePRWorkspaces:        deletePRWorkspacesRegex,
+		ProjectGenerator:          r.ProjectGenerator,
+		TerraformBinary:           r.TerraformBinary,
+		AllowedTerraformVersions:  allowedTerraformVersions,
+		VCSBaseURL:                strings.TrimSuffix(r.VCSBaseURL, "/"),
+		ConfirmApply:              r.ConfirmApply,
+		DeploymentEnvironment:     r.DeploymentEnvironment,
+		CleanEnv:                  r.CleanEnv,
+		CleanEnvAllowedVars:       r.CleanEnvAllowedVars,
 	}
 }
@@ -1,6 +1,10 @@
 package raw
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	validation "github.com/go-ozzo/ozzo-validation"
 	version "github.com/hashicorp/go-version"
 	"github.com/runatlantis/atlantis/server/core/config/valid"
@@ -12,6 +16,9 @@ type PolicySets struct {
 	Owners       PolicyOwners `yaml:"owners,omitempty" json:"owners,omitempty"`
 	PolicySets   []PolicySet  `yaml:"policy_sets" json:"policy_sets"`
 	ApproveCount int          `yaml:"approve_count,omitempty" json:"approve_count,omitempty"`
+	// ChangedResourcesOnly scopes policy checks to the resources that the
+	// plan changes.
+	ChangedResourcesOnly bool `yaml:"changed_resources_only,omitempty" json:"changed_resources_only,omitempty"`
 }
 
 func (p PolicySets) Validate() error {
@@ -36,6 +43,7 @@ func (p PolicySets) ToValid() valid.PolicySets {
 	}
 
 	policySets.Owners = p.Owners.ToValid()
+	policySets.ChangedResourcesOnly = p.ChangedResourcesOnly
 
 	validPolicySets := make([]valid.PolicySet, 0)
 	for _, rawPolicySet := range p.PolicySets {
@@ -75,15 +83,33 @@ type PolicySet struct {
 	Name         string       `yaml:"name" json:"name"`
 	Owners       PolicyOwners `yaml:"owners,omitempty" json:"owners,omitempty"`
 	ApproveCount int          `yaml:"approve_count,omitempty" json:"approve_count,omitempty"`
+	DataDirs     []string     `yaml:"data_dirs,omitempty" json:"data_dirs,omitempty"`
 }
 
 func (p PolicySet) Validate() error {
+	// Relative data dirs are relative to the policy set's path so they can't
+	// leave it.
+	noDotDot := func(value interface{}) error {
+		for _, dir := range value.([]string) {
+			if dir == "" {
+				return errors.New("cannot contain empty dirs")
+			}
+			for _, elem := range strings.Split(dir, "/") {
+				if elem == ".." {
+					return fmt.Errorf("%q cannot contain '..'", dir)
+				}
+			}
+		}
+		return nil
+	}
+
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.Name, validation.Required.Error("is required")),
 		validation.Field(&p.Owners),
 		validation.Field(&p.ApproveCount),
 		validation.Field(&p.Path, validation.Required.Error("is required")),
 		validation.Field(&p.Source, validation.In(valid.LocalPolicySet, valid.GithubPolicySet).Error("only 'local' and 'github' source types are supported")),
+		validation.Field(&p.DataDirs, validation.By(noDotDot)),
 	)
 }
 
@@ -95,6 +121,7 @@ func (p PolicySet) ToValid() valid.PolicySet {
 	policySet.Source = p.Source
 	policySet.ApproveCount = p.ApproveCount
 	policySet.Owners = p.Owners.ToValid()
+	policySet.DataDirs = p.DataDirs
 
 	return policySet
 }
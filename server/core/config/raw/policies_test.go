@@ -37,6 +37,48 @@ policy_sets:
 				},
 			},
 		},
+		{
+			description: "changed resources only",
+			input: `
+changed_resources_only: true
+policy_sets:
+- name: policy-name
+  source: "local"
+  path: "rel/path/to/policy-set"
+`,
+			exp: raw.PolicySets{
+				ChangedResourcesOnly: true,
+				PolicySets: []raw.PolicySet{
+					{
+						Name:   "policy-name",
+						Source: valid.LocalPolicySet,
+						Path:   "rel/path/to/policy-set",
+					},
+				},
+			},
+		},
+		{
+			description: "data dirs",
+			input: `
+policy_sets:
+- name: policy-name
+  source: "local"
+  path: "rel/path/to/policy-set"
+  data_dirs:
+  - /etc/policy-data
+  - rel/path/to/data
+`,
+			exp: raw.PolicySets{
+				PolicySets: []raw.PolicySet{
+					{
+						Name:     "policy-name",
+						Source:   valid.LocalPolicySet,
+						Path:     "rel/path/to/policy-set",
+						DataDirs: []string{"/etc/policy-data", "rel/path/to/data"},
+					},
+				},
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -123,6 +165,20 @@ func TestPolicySets_Validate(t *testing.T) {
 			},
 			expErr: "policy_sets: (0: (source: only 'local' and 'github' source types are supported.).).",
 		},
+		{
+			description: "data dir outside of the policy set",
+			input: raw.PolicySets{
+				PolicySets: []raw.PolicySet{
+					{
+						Name:     "good-policy",
+						Source:   valid.LocalPolicySet,
+						Path:     "rel/path/to/source",
+						DataDirs: []string{"data/../../other"},
+					},
+				},
+			},
+			expErr: "policy_sets: (0: (data_dirs: \"data/../../other\" cannot contain '..'.).).",
+		},
 		{
 			description: "empty string version",
 			input: raw.PolicySets{
@@ -258,6 +314,56 @@ func TestPolicySets_ToValid(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "changed resources only",
+			input: raw.PolicySets{
+				ChangedResourcesOnly: true,
+				PolicySets: []raw.PolicySet{
+					{
+						Name:   "good-policy",
+						Path:   "rel/path/to/source",
+						Source: valid.LocalPolicySet,
+					},
+				},
+			},
+			exp: valid.PolicySets{
+				ApproveCount:         1,
+				ChangedResourcesOnly: true,
+				PolicySets: []valid.PolicySet{
+					{
+						Name:         "good-policy",
+						Path:         "rel/path/to/source",
+						Source:       "local",
+						ApproveCount: 1,
+					},
+				},
+			},
+		},
+		{
+			description: "data dirs",
+			input: raw.PolicySets{
+				PolicySets: []raw.PolicySet{
+					{
+						Name:     "good-policy",
+						Path:     "rel/path/to/source",
+						Source:   valid.LocalPolicySet,
+						DataDirs: []string{"/etc/policy-data"},
+					},
+				},
+			},
+			exp: valid.PolicySets{
+				ApproveCount: 1,
+				PolicySets: []valid.PolicySet{
+					{
+						Name:         "good-policy",
+						Path:         "rel/path/to/source",
+						Source:       "local",
+						ApproveCount: 1,
+						DataDirs:     []string{"/etc/policy-data"},
+					},
+				},
+			},
+		},
 	}
 
 	for _, c := range cases {
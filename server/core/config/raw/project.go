@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 	version "github.com/hashicorp/go-version"
@@ -18,8 +19,49 @@ const (
 	ApprovedRequirement   = "approved"
 	MergeableRequirement  = "mergeable"
 	UnDivergedRequirement = "undiverged"
+	NoDestroyRequirement  = "no_destroy"
+	// SignedCommitsRequirement requires all of the pull request's commits to
+	// have signatures verified by the VCS host.
+	SignedCommitsRequirement = "signed_commits"
+	// CleanWorkingTreeRequirement requires the files of a project that aren't
+	// committed to be the same as when the project was planned.
+	CleanWorkingTreeRequirement = "clean_working_tree"
+	// ApprovalRuleRequirementPrefix prefixes the name of a GitLab approval
+	// rule that must be approved, ex. "approval_rule:Security".
+	ApprovalRuleRequirementPrefix = "approval_rule:"
+	// CommitStatusRequirementPrefix prefixes the context of a commit status
+	// that must be successful on the pull request's head commit, ex.
+	// "commit_status:ci/tests".
+	CommitStatusRequirementPrefix = "commit_status:"
+	// PlanReactionRequirementPrefix prefixes the reaction that someone other
+	// than Atlantis must have added to the latest plan comment, ex.
+	// "plan_reaction:+1".
+	PlanReactionRequirementPrefix = "plan_reaction:"
 )
 
+// ApprovalRuleName returns the name of the approval rule that the
+// requirement req requires to be approved, or false if req isn't an approval
+// rule requirement.
+func ApprovalRuleName(req string) (string, bool) {
+	name, ok := strings.CutPrefix(req, ApprovalRuleRequirementPrefix)
+	return name, ok && name != ""
+}
+
+// CommitStatusContext returns the context of the commit status that the
+// requirement req requires to be successful, or false if req isn't a commit
+// status requirement.
+func CommitStatusContext(req string) (string, bool) {
+	context, ok := strings.CutPrefix(req, CommitStatusRequirementPrefix)
+	return context, ok && context != ""
+}
+
+// PlanReaction returns the reaction that the requirement req requires on the
+// latest plan comment, or false if req isn't a plan reaction requirement.
+func PlanReaction(req string) (string, bool) {
+	reaction, ok := strings.CutPrefix(req, PlanReactionRequirementPrefix)
+	return reaction, ok && reaction != ""
+}
+
 type Project struct {
 	Name                      *string   `yaml:"name,omitempty"`
 	Branch                    *string   `yaml:"branch,omitempty"`
@@ -34,8 +76,17 @@ type Project struct {
 	DeleteSourceBranchOnMerge *bool     `yaml:"delete_source_branch_on_merge,omitempty"`
 	RepoLocking               *bool     `yaml:"repo_locking,omitempty"`
 	ExecutionOrderGroup       *int      `yaml:"execution_order_group,omitempty"`
+	ApplyOrder                *int      `yaml:"apply_order,omitempty"`
 	PolicyCheck               *bool     `yaml:"policy_check,omitempty"`
 	CustomPolicyCheck         *bool     `yaml:"custom_policy_check,omitempty"`
+	StatusContextSuffix       *string   `yaml:"status_context_suffix,omitempty"`
+	DeploymentEnvironment     *string   `yaml:"deployment_environment,omitempty"`
+	PlanTimeoutSeconds        *int      `yaml:"plan_timeout_seconds,omitempty"`
+	ApplyTimeoutSeconds       *int      `yaml:"apply_timeout_seconds,omitempty"`
+	TerraformParallelism      *int      `yaml:"terraform_parallelism,omitempty"`
+	// PlanPresets are named sets of extra plan args that can be selected
+	// with atlantis plan --preset <name>.
+	PlanPresets map[string][]string `yaml:"plan_presets,omitempty"`
 }
 
 func (p Project) Validate() error {
@@ -74,6 +125,26 @@ func (p Project) Validate() error {
 		return errors.Wrapf(err, "parsing: %s", branch)
 	}
 
+	positive := func(value interface{}) error {
+		intPtr := value.(*int)
+		if intPtr != nil && *intPtr <= 0 {
+			return errors.New("if set must be greater than 0")
+		}
+		return nil
+	}
+
+	planPresetsValid := func(value interface{}) error {
+		for name, args := range value.(map[string][]string) {
+			if !validProjectName(name) {
+				return fmt.Errorf("%q is not a valid preset name: must contain only URL safe characters", name)
+			}
+			if len(args) == 0 {
+				return fmt.Errorf("preset %q has no args", name)
+			}
+		}
+		return nil
+	}
+
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.Dir, validation.Required, validation.By(hasDotDot)),
 		validation.Field(&p.PlanRequirements, validation.By(validPlanReq)),
@@ -82,6 +153,12 @@ func (p Project) Validate() error {
 		validation.Field(&p.TerraformVersion, validation.By(VersionValidator)),
 		validation.Field(&p.Name, validation.By(validName)),
 		validation.Field(&p.Branch, validation.By(branchValid)),
+		validation.Field(&p.StatusContextSuffix, validation.By(validName)),
+		validation.Field(&p.DeploymentEnvironment, validation.NilOrNotEmpty),
+		validation.Field(&p.PlanTimeoutSeconds, validation.By(positive)),
+		validation.Field(&p.ApplyTimeoutSeconds, validation.By(positive)),
+		validation.Field(&p.TerraformParallelism, validation.By(positive)),
+		validation.Field(&p.PlanPresets, validation.By(planPresetsValid)),
 	)
 }
 
@@ -135,6 +212,8 @@ func (p Project) ToValid() valid.Project {
 		v.ExecutionOrderGroup = *p.ExecutionOrderGroup
 	}
 
+	v.ApplyOrder = p.ApplyOrder
+
 	if p.PolicyCheck != nil {
 		v.PolicyCheck = p.PolicyCheck
 	}
@@ -143,6 +222,28 @@ func (p Project) ToValid() valid.Project {
 		v.CustomPolicyCheck = p.CustomPolicyCheck
 	}
 
+	if p.StatusContextSuffix != nil {
+		v.StatusContextSuffix = *p.StatusContextSuffix
+	}
+
+	if p.DeploymentEnvironment != nil {
+		v.DeploymentEnvironment = *p.DeploymentEnvironment
+	}
+
+	if p.PlanTimeoutSeconds != nil {
+		v.PlanTimeout = time.Duration(*p.PlanTimeoutSeconds) * time.Second
+	}
+
+	v.PlanPresets = p.PlanPresets
+
+	if p.ApplyTimeoutSeconds != nil {
+		v.ApplyTimeout = time.Duration(*p.ApplyTimeoutSeconds) * time.Second
+	}
+
+	if p.TerraformParallelism != nil {
+		v.TerraformParallelism = *p.TerraformParallelism
+	}
+
 	return v
 }
 
@@ -168,8 +269,17 @@ func validPlanReq(value interface{}) error {
 func validApplyReq(value interface{}) error {
 	reqs := value.([]string)
 	for _, r := range reqs {
-		if r != ApprovedRequirement && r != MergeableRequirement && r != UnDivergedRequirement {
-			return fmt.Errorf("%q is not a valid apply_requirement, only %q, %q and %q are supported", r, ApprovedRequirement, MergeableRequirement, UnDivergedRequirement)
+		if _, ok := ApprovalRuleName(r); ok {
+			continue
+		}
+		if _, ok := CommitStatusContext(r); ok {
+			continue
+		}
+		if _, ok := PlanReaction(r); ok {
+			continue
+		}
+		if r != ApprovedRequirement && r != MergeableRequirement && r != UnDivergedRequirement && r != NoDestroyRequirement && r != SignedCommitsRequirement && r != CleanWorkingTreeRequirement {
+			return fmt.Errorf("%q is not a valid apply_requirement, only %q, %q, %q, %q, %q, %q, \"%s<rule name>\", \"%s<context>\" and \"%s<reaction>\" are supported", r, ApprovedRequirement, MergeableRequirement, UnDivergedRequirement, NoDestroyRequirement, SignedCommitsRequirement, CleanWorkingTreeRequirement, ApprovalRuleRequirementPrefix, CommitStatusRequirementPrefix, PlanReactionRequirementPrefix)
 		}
 	}
 	return nil
@@ -2,6 +2,7 @@ package raw_test
 
 import (
 	"testing"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 	version "github.com/hashicorp/go-version"
@@ -164,13 +165,77 @@ func TestProject_Validate(t *testing.T) {
 			},
 			expErr: "",
 		},
+		{
+			description: "apply reqs with signed_commits requirement",
+			input: raw.Project{
+				Dir:               String("."),
+				ApplyRequirements: []string{"signed_commits"},
+			},
+			expErr: "",
+		},
+		{
+			description: "apply reqs with clean_working_tree requirement",
+			input: raw.Project{
+				Dir:               String("."),
+				ApplyRequirements: []string{"clean_working_tree"},
+			},
+			expErr: "",
+		},
 		{
 			description: "apply reqs with unsupported",
 			input: raw.Project{
 				Dir:               String("."),
 				ApplyRequirements: []string{"unsupported"},
 			},
-			expErr: "apply_requirements: \"unsupported\" is not a valid apply_requirement, only \"approved\", \"mergeable\" and \"undiverged\" are supported.",
+			expErr: "apply_requirements: \"unsupported\" is not a valid apply_requirement, only \"approved\", \"mergeable\", \"undiverged\", \"no_destroy\", \"signed_commits\", \"clean_working_tree\", \"approval_rule:<rule name>\", \"commit_status:<context>\" and \"plan_reaction:<reaction>\" are supported.",
+		},
+		{
+			description: "apply reqs with approval rule requirement",
+			input: raw.Project{
+				Dir:               String("."),
+				ApplyRequirements: []string{"approval_rule:Security"},
+			},
+			expErr: "",
+		},
+		{
+			description: "apply reqs with unnamed approval rule requirement",
+			input: raw.Project{
+				Dir:               String("."),
+				ApplyRequirements: []string{"approval_rule:"},
+			},
+			expErr: "apply_requirements: \"approval_rule:\" is not a valid apply_requirement, only \"approved\", \"mergeable\", \"undiverged\", \"no_destroy\", \"signed_commits\", \"clean_working_tree\", \"approval_rule:<rule name>\", \"commit_status:<context>\" and \"plan_reaction:<reaction>\" are supported.",
+		},
+		{
+			description: "apply reqs with commit status requirement",
+			input: raw.Project{
+				Dir:               String("."),
+				ApplyRequirements: []string{"commit_status:ci/tests"},
+			},
+			expErr: "",
+		},
+		{
+			description: "apply reqs with empty commit status requirement",
+			input: raw.Project{
+				Dir:               String("."),
+				ApplyRequirements: []string{"commit_status:"},
+			},
+			expErr: "apply_requirements: \"commit_status:\" is not a valid apply_requirement, only \"approved\", \"mergeable\", \"undiverged\", \"no_destroy\", \"signed_commits\", \"clean_working_tree\", \"approval_rule:<rule name>\", \"commit_status:<context>\" and \"plan_reaction:<reaction>\" are supported.",
+		},
+		{
+			description: "apply reqs with plan reaction requirement",
+			input: raw.Project{
+				Dir:               String("."),
+				ApplyRequirements: []string{"plan_reaction:+1"},
+			},
+			expErr: "",
+		},
+		{
+			description: "apply reqs with empty plan reaction requirement",
+			input: raw.Project{
+				Dir:               String("."),
+				ApplyRequirements: []string{"plan_reaction:"},
+			},
+			expErr: "apply_requirements: \"plan_reaction:\" is not a valid apply_requirement, only \"approved\", \"mergeable\", \"undiverged\", \"no_destroy\", \"signed_commits\", \"clean_working_tree\", \"approval_rule:<rule name>\", \"commit_status:<context>\" and \"plan_reaction:<reaction>\" are supported.",
 		},
 		{
 			description: "apply reqs with approved requirement",
@@ -196,6 +261,14 @@ func TestProject_Validate(t *testing.T) {
 			},
 			expErr: "",
 		},
+		{
+			description: "apply reqs with no_destroy requirement",
+			input: raw.Project{
+				Dir:               String("."),
+				ApplyRequirements: []string{"no_destroy"},
+			},
+			expErr: "",
+		},
 		{
 			description: "apply reqs with mergeable and approved requirements",
 			input: raw.Project{
@@ -316,6 +389,100 @@ func TestProject_Validate(t *testing.T) {
 			},
 			expErr: `name: "namewith\\" is not allowed: must contain only URL safe characters.`,
 		},
+		{
+			description: "status context suffix",
+			input: raw.Project{
+				Dir:                 String("."),
+				StatusContextSuffix: String("team-a"),
+			},
+			expErr: "",
+		},
+		{
+			description: "empty status context suffix",
+			input: raw.Project{
+				Dir:                 String("."),
+				StatusContextSuffix: String(""),
+			},
+			expErr: "status_context_suffix: if set cannot be empty.",
+		},
+		{
+			description: "status context suffix with spaces",
+			input: raw.Project{
+				Dir:                 String("."),
+				StatusContextSuffix: String("team a"),
+			},
+			expErr: "status_context_suffix: \"team a\" is not allowed: must contain only URL safe characters.",
+		},
+		{
+			description: "empty deployment environment",
+			input: raw.Project{
+				Dir:                   String("."),
+				DeploymentEnvironment: String(""),
+			},
+			expErr: "deployment_environment: cannot be blank.",
+		},
+		{
+			description: "zero plan timeout",
+			input: raw.Project{
+				Dir:                String("."),
+				PlanTimeoutSeconds: Int(0),
+			},
+			expErr: "plan_timeout_seconds: if set must be greater than 0.",
+		},
+		{
+			description: "negative apply timeout",
+			input: raw.Project{
+				Dir:                 String("."),
+				ApplyTimeoutSeconds: Int(-1),
+			},
+			expErr: "apply_timeout_seconds: if set must be greater than 0.",
+		},
+		{
+			description: "zero terraform parallelism",
+			input: raw.Project{
+				Dir:                  String("."),
+				TerraformParallelism: Int(0),
+			},
+			expErr: "terraform_parallelism: if set must be greater than 0.",
+		},
+		{
+			description: "terraform parallelism",
+			input: raw.Project{
+				Dir:                  String("."),
+				TerraformParallelism: Int(20),
+			},
+		},
+		{
+			description: "timeouts",
+			input: raw.Project{
+				Dir:                 String("."),
+				PlanTimeoutSeconds:  Int(600),
+				ApplyTimeoutSeconds: Int(3600),
+			},
+		},
+		{
+			description: "plan presets",
+			input: raw.Project{
+				Dir:         String("."),
+				PlanPresets: map[string][]string{"emergency": {"-refresh=false"}},
+			},
+		},
+		{
+			description: "plan preset with invalid name",
+			input: raw.Project{
+				Dir:         String("."),
+				PlanPresets: map[string][]string{"emergency fix": {"-refresh=false"}},
+			},
+			expErr: "plan_presets: \"emergency fix\" is not a valid preset name: must contain only URL safe characters.",
+		},
+		{
+			description: "plan preset without args",
+			input: raw.Project{
+				Dir:         String("."),
+				PlanPresets: map[string][]string{"emergency": {}},
+			},
+			expErr: "plan_presets: preset \"emergency\" has no args.",
+		},
 	}
 	validation.ErrorTag = "yaml"
 	for _, c := range cases {
@@ -367,9 +534,16 @@ func TestProject_ToValid(t *testing.T) {
 					WhenModified: []string{"hi"},
 					Enabled:      Bool(false),
 				},
-				ApplyRequirements:   []string{"approved"},
-				Name:                String("myname"),
-				ExecutionOrderGroup: Int(10),
+				ApplyRequirements:     []string{"approved"},
+				Name:                  String("myname"),
+				ExecutionOrderGroup:   Int(10),
+				ApplyOrder:            Int(2),
+				StatusContextSuffix:   String("team-a"),
+				DeploymentEnvironment: String("production"),
+				PlanTimeoutSeconds:    Int(600),
+				ApplyTimeoutSeconds:   Int(3600),
+				TerraformParallelism:  Int(20),
+				PlanPresets:           map[string][]string{"emergency": {"-refresh=false"}},
 			},
 			exp: valid.Project{
 				Dir:              ".",
@@ -380,9 +554,16 @@ func TestProject_ToValid(t *testing.T) {
 					WhenModified: []string{"hi"},
 					Enabled:      false,
 				},
-				ApplyRequirements:   []string{"approved"},
-				Name:                String("myname"),
-				ExecutionOrderGroup: 10,
+				ApplyRequirements:     []string{"approved"},
+				Name:                  String("myname"),
+				ExecutionOrderGroup:   10,
+				ApplyOrder:            Int(2),
+				StatusContextSuffix:   "team-a",
+				DeploymentEnvironment: "production",
+				PlanTimeout:           10 * time.Minute,
+				ApplyTimeout:          time.Hour,
+				TerraformParallelism:  20,
+				PlanPresets:           map[string][]string{"emergency": {"-refresh=false"}},
 			},
 		},
 		{
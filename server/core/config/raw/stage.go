@@ -1,18 +1,55 @@
 package raw
 
 import (
+	"fmt"
+	"strconv"
+
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/runatlantis/atlantis/server/core/config/valid"
 )
 
 type Stage struct {
 	Steps []Step `yaml:"steps,omitempty" json:"steps,omitempty"`
+	// OnFailure are the steps run if one of Steps fails, ex. to roll back a
+	// failed apply. Only the apply stage supports them.
+	OnFailure []Step `yaml:"on_failure,omitempty" json:"on_failure,omitempty"`
 }
 
 func (s Stage) Validate() error {
-	return validation.ValidateStruct(&s,
+	if err := validation.ValidateStruct(&s,
 		validation.Field(&s.Steps),
-	)
+		validation.Field(&s.OnFailure),
+	); err != nil {
+		return err
+	}
+	return s.validateConditions()
+}
+
+// validateConditions checks that the steps with a condition come after a plan
+// step of the stage, since the condition is on the output of the last plan
+// step that ran. The on_failure steps run without a plan step so they can't
+// have conditions.
+func (s Stage) validateConditions() error {
+	planned := false
+	for i, step := range s.Steps {
+		validStep := step.ToValid()
+		if validStep.When != "" && !planned {
+			return validation.Errors{"steps": validation.Errors{
+				strconv.Itoa(i): fmt.Errorf("step with %q condition %q must come after a %q step", WhenArgKey, validStep.When, PlanStepName),
+			}}
+		}
+		if validStep.StepName == PlanStepName {
+			planned = true
+		}
+	}
+	for i, step := range s.OnFailure {
+		if step.ToValid().When != "" {
+			return validation.Errors{"on_failure": validation.Errors{
+				strconv.Itoa(i): fmt.Errorf("on_failure steps can't have a %q condition", WhenArgKey),
+			}}
+		}
+	}
+	return nil
 }
 
 func (s Stage) ToValid() valid.Stage {
@@ -20,7 +57,12 @@ func (s Stage) ToValid() valid.Stage {
 	for _, s := range s.Steps {
 		validSteps = append(validSteps, s.ToValid())
 	}
+	var onFailureSteps []valid.Step
+	for _, s := range s.OnFailure {
+		onFailureSteps = append(onFailureSteps, s.ToValid())
+	}
 	return valid.Stage{
-		Steps: validSteps,
+		Steps:     validSteps,
+		OnFailure: onFailureSteps,
 	}
 }
@@ -64,6 +64,49 @@ func TestStage_Validate(t *testing.T) {
 	Ok(t, (raw.Stage{}).Validate())
 }
 
+func TestStage_ValidateConditions(t *testing.T) {
+	conditional := raw.Step{
+		EnvOrRun: map[string]map[string]string{
+			"run": {"command": "echo changed", "when": "plan_has_changes"},
+		},
+	}
+	cases := []struct {
+		description string
+		stage       raw.Stage
+		expErr      string
+	}{
+		{
+			description: "after plan",
+			stage:       raw.Stage{Steps: []raw.Step{{Key: String("init")}, {Key: String("plan")}, conditional}},
+		},
+		{
+			description: "before plan",
+			stage:       raw.Stage{Steps: []raw.Step{{Key: String("init")}, conditional, {Key: String("plan")}}},
+			expErr:      "steps: (1: step with \"when\" condition \"plan_has_changes\" must come after a \"plan\" step.).",
+		},
+		{
+			description: "without plan",
+			stage:       raw.Stage{Steps: []raw.Step{{Key: String("apply")}, conditional}},
+			expErr:      "steps: (1: step with \"when\" condition \"plan_has_changes\" must come after a \"plan\" step.).",
+		},
+		{
+			description: "on failure",
+			stage:       raw.Stage{Steps: []raw.Step{{Key: String("plan")}}, OnFailure: []raw.Step{conditional}},
+			expErr:      "on_failure: (0: on_failure steps can't have a \"when\" condition.).",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.stage.Validate()
+			if c.expErr == "" {
+				Ok(t, err)
+				return
+			}
+			ErrEquals(t, c.expErr, err)
+		})
+	}
+}
+
 func TestStage_ToValid(t *testing.T) {
 	cases := []struct {
 		description string
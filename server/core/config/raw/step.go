@@ -17,6 +17,7 @@ const (
 	CommandArgKey       = "command"
 	ValueArgKey         = "value"
 	OutputArgKey        = "output"
+	WhenArgKey          = "when"
 	RunStepName         = "run"
 	PlanStepName        = "plan"
 	ShowStepName        = "show"
@@ -27,6 +28,7 @@ const (
 	MultiEnvStepName    = "multienv"
 	ImportStepName      = "import"
 	StateRmStepName     = "state_rm"
+	InfracostStepName   = "infracost"
 )
 
 // Step represents a single action/command to perform. In YAML, it can be set as
@@ -43,6 +45,7 @@ const (
 //   - run:
 //       command: my custom command
 //       output: hide
+//       when: plan_has_changes
 //
 // 3. A map for a built-in command and extra_args:
 //   - plan:
@@ -96,7 +99,8 @@ func (s Step) validStepName(stepName string) bool {
 		stepName == ShowStepName ||
 		stepName == PolicyCheckStepName ||
 		stepName == ImportStepName ||
-		stepName == StateRmStepName
+		stepName == StateRmStepName ||
+		stepName == InfracostStepName
 }
 
 func (s Step) Validate() error {
@@ -208,6 +212,12 @@ func (s Step) Validate() error {
 				}
 			}
 			delete(args, OutputArgKey)
+			if v, ok := args[WhenArgKey]; ok {
+				if !(v == valid.StepConditionPlanHasChanges || v == valid.StepConditionPlanHasNoChanges) {
+					return fmt.Errorf("run step %q option must be one of %q or %q", WhenArgKey, valid.StepConditionPlanHasChanges, valid.StepConditionPlanHasNoChanges)
+				}
+			}
+			delete(args, WhenArgKey)
 			if len(args) > 0 {
 				var argKeys []string
 				for k := range args {
@@ -215,7 +225,7 @@ func (s Step) Validate() error {
 				}
 				// Sort so tests can be deterministic.
 				sort.Strings(argKeys)
-				return fmt.Errorf("run steps only support keys %q, %q and %q, found extra keys %q", RunStepName, CommandArgKey, OutputArgKey, strings.Join(argKeys, ","))
+				return fmt.Errorf("run steps only support keys %q, %q, %q and %q, found extra keys %q", RunStepName, CommandArgKey, OutputArgKey, WhenArgKey, strings.Join(argKeys, ","))
 			}
 		default:
 			return fmt.Errorf("%q is not a valid step type", stepName)
@@ -279,6 +289,7 @@ func (s Step) ToValid() valid.Step {
 				RunCommand:  stepArgs[CommandArgKey],
 				EnvVarValue: stepArgs[ValueArgKey],
 				Output:      valid.PostProcessRunOutputOption(stepArgs[OutputArgKey]),
+				When:        valid.StepCondition(stepArgs[WhenArgKey]),
 			}
 			if step.StepName == RunStepName && step.Output == "" {
 				step.Output = valid.PostProcessRunOutputShow
@@ -386,6 +386,41 @@ func TestStep_Validate(t *testing.T) {
 			},
 			expErr: "env steps only support one of the \"value\" or \"command\" keys, found both",
 		},
+		{
+			description: "run step with condition",
+			input: raw.Step{
+				EnvOrRun: EnvOrRunType{
+					"run": {
+						"command": "notify",
+						"when":    "plan_has_changes",
+					},
+				},
+			},
+		},
+		{
+			description: "run step with unknown condition",
+			input: raw.Step{
+				EnvOrRun: EnvOrRunType{
+					"run": {
+						"command": "notify",
+						"when":    "plan_failed",
+					},
+				},
+			},
+			expErr: "run step \"when\" option must be one of \"plan_has_changes\" or \"plan_has_no_changes\"",
+		},
+		{
+			description: "run step with invalid key",
+			input: raw.Step{
+				EnvOrRun: EnvOrRunType{
+					"run": {
+						"command": "notify",
+						"if":      "plan_has_changes",
+					},
+				},
+			},
+			expErr: "run steps only support keys \"run\", \"command\", \"output\" and \"when\", found extra keys \"if\"",
+		},
 		{
 			// For atlantis.yaml v2, this wouldn't parse, but now there should
 			// be no error.
@@ -476,6 +511,15 @@ func TestStep_ToValid(t *testing.T) {
 				StepName: "import",
 			},
 		},
+		{
+			description: "infracost step",
+			input: raw.Step{
+				Key: String("infracost"),
+			},
+			exp: valid.Step{
+				StepName: "infracost",
+			},
+		},
 		{
 			description: "init extra_args",
 			input: raw.Step{
@@ -574,6 +618,23 @@ func TestStep_ToValid(t *testing.T) {
 				Output:     "hide",
 			},
 		},
+		{
+			description: "run step with condition",
+			input: raw.Step{
+				EnvOrRun: EnvOrRunType{
+					"run": {
+						"command": "notify",
+						"when":    "plan_has_no_changes",
+					},
+				},
+			},
+			exp: valid.Step{
+				StepName:   "run",
+				RunCommand: "notify",
+				Output:     "show",
+				When:       "plan_has_no_changes",
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.description, func(t *testing.T) {
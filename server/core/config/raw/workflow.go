@@ -1,6 +1,8 @@
 package raw
 
 import (
+	"errors"
+
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/runatlantis/atlantis/server/core/config/valid"
 )
@@ -11,29 +13,41 @@ type Workflow struct {
 	PolicyCheck *Stage `yaml:"policy_check,omitempty" json:"policy_check,omitempty"`
 	Import      *Stage `yaml:"import,omitempty" json:"import,omitempty"`
 	StateRm     *Stage `yaml:"state_rm,omitempty" json:"state_rm,omitempty"`
+	Terragrunt  bool   `yaml:"terragrunt,omitempty" json:"terragrunt,omitempty"`
 }
 
 func (w Workflow) Validate() error {
+	noOnFailure := func(value interface{}) error {
+		if stage, ok := value.(*Stage); ok && stage != nil && len(stage.OnFailure) > 0 {
+			return errors.New("on_failure is only supported in the apply stage")
+		}
+		return nil
+	}
 	return validation.ValidateStruct(&w,
 		validation.Field(&w.Apply),
-		validation.Field(&w.Plan),
-		validation.Field(&w.PolicyCheck),
-		validation.Field(&w.Import),
-		validation.Field(&w.StateRm),
+		validation.Field(&w.Plan, validation.By(noOnFailure)),
+		validation.Field(&w.PolicyCheck, validation.By(noOnFailure)),
+		validation.Field(&w.Import, validation.By(noOnFailure)),
+		validation.Field(&w.StateRm, validation.By(noOnFailure)),
 	)
 }
 
 func (w Workflow) toValidStage(stage *Stage, defaultStage valid.Stage) valid.Stage {
-	if stage == nil || stage.Steps == nil {
+	if stage == nil {
 		return defaultStage
 	}
 
-	return stage.ToValid()
+	v := stage.ToValid()
+	if stage.Steps == nil {
+		v.Steps = defaultStage.Steps
+	}
+	return v
 }
 
 func (w Workflow) ToValid(name string) valid.Workflow {
 	v := valid.Workflow{
-		Name: name,
+		Name:       name,
+		Terragrunt: w.Terragrunt,
 	}
 
 	v.Apply = w.toValidStage(w.Apply, valid.DefaultApplyStage)
@@ -5,12 +5,43 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/runatlantis/atlantis/server/core/config/valid"
 )
 
+// SuccessCodesKey is the workflow hook key listing non-zero exit codes that
+// should be treated as success.
+const SuccessCodesKey = "successCodes"
+
+// PriorityKey is the workflow hook key setting the order in which hooks run.
+// Hooks run in ascending order of priority.
+const PriorityKey = "priority"
+
+// AuthorsKey is the workflow hook key listing the users and teams, prefixed
+// with "team:", the hook runs for. If it's not set the hook runs for
+// everyone.
+const AuthorsKey = "authors"
+
+// SkipAuthorsKey is the workflow hook key listing the users and teams,
+// prefixed with "team:", the hook doesn't run for.
+const SkipAuthorsKey = "skip_authors"
+
+// ImageKey is the workflow hook key setting the container image the hook
+// runs in instead of the host.
+const ImageKey = "image"
+
+// EventsKey is the workflow hook key listing the events the hook runs for. If
+// it's not set the hook runs for all events.
+const EventsKey = "events"
+
+// PerProjectKey is the post workflow hook key that runs the hook once for
+// each project the command ran for instead of once per command. It's only
+// supported by hooks limited to the plan and apply commands.
+const PerProjectKey = "per_project"
+
 // WorkflowHook represents a single action/command to perform. In YAML,
 // it can be set as
 // A map for a custom run commands:
@@ -72,6 +103,10 @@ func (s WorkflowHook) Validate() error {
 func (s WorkflowHook) ToValid() *valid.WorkflowHook {
 	// This will trigger in case #4 (see WorkflowHook docs).
 	if len(s.StringVal) > 0 {
+		successCodes, _ := parseSuccessCodes(s.StringVal[SuccessCodesKey])
+		priority, _ := parsePriority(s.StringVal[PriorityKey])
+		events, _ := parseHookEvents(s.StringVal[EventsKey])
+		perProject, _ := parsePerProject(s.StringVal[PerProjectKey])
 		return &valid.WorkflowHook{
 			StepName:        RunStepName,
 			RunCommand:      s.StringVal["run"],
@@ -79,12 +114,120 @@ func (s WorkflowHook) ToValid() *valid.WorkflowHook {
 			Shell:           s.StringVal["shell"],
 			ShellArgs:       s.StringVal["shellArgs"],
 			Commands:        s.StringVal["commands"],
+			PerProject:      perProject,
+			SuccessCodes:    successCodes,
+			Priority:        priority,
+			Authors:         s.StringVal[AuthorsKey],
+			SkipAuthors:     s.StringVal[SkipAuthorsKey],
+			Image:           s.StringVal[ImageKey],
+			Events:          events,
 		}
 	}
 
 	panic("step was not valid. This is a bug!")
 }
 
+// ValidateSuccessCodes returns an error if the hook's successCodes aren't a
+// comma-separated list of exit codes.
+func (s WorkflowHook) ValidateSuccessCodes() error {
+	_, err := parseSuccessCodes(s.StringVal[SuccessCodesKey])
+	return err
+}
+
+// parseSuccessCodes parses a comma-separated list of exit codes, ex. "2, 3".
+func parseSuccessCodes(codes string) ([]int, error) {
+	var parsed []int
+	for _, code := range strings.Split(codes, ",") {
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+		c, err := strconv.Atoi(code)
+		if err != nil || c < 0 || c > 255 {
+			return nil, fmt.Errorf("%q is not a valid exit code in %s %q", code, SuccessCodesKey, codes)
+		}
+		parsed = append(parsed, c)
+	}
+	return parsed, nil
+}
+
+// ValidatePriority returns an error if the hook's priority isn't an integer.
+func (s WorkflowHook) ValidatePriority() error {
+	_, err := parsePriority(s.StringVal[PriorityKey])
+	return err
+}
+
+// parsePriority parses a hook's priority, which defaults to 0.
+func parsePriority(priority string) (int, error) {
+	priority = strings.TrimSpace(priority)
+	if priority == "" {
+		return 0, nil
+	}
+	p, err := strconv.Atoi(priority)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a valid %s, must be an integer", priority, PriorityKey)
+	}
+	return p, nil
+}
+
+// ValidateEvents returns an error if the hook's events aren't a
+// comma-separated list of hook events.
+func (s WorkflowHook) ValidateEvents() error {
+	_, err := parseHookEvents(s.StringVal[EventsKey])
+	return err
+}
+
+// parseHookEvents parses a comma-separated list of hook events, ex.
+// "autoplan, comment".
+func parseHookEvents(events string) ([]string, error) {
+	var parsed []string
+	for _, event := range strings.Split(events, ",") {
+		event = strings.TrimSpace(event)
+		if event == "" {
+			continue
+		}
+		if event != valid.AutoplanHookEvent && event != valid.CommentHookEvent {
+			return nil, fmt.Errorf("%q is not a valid event in %s %q, must be %s or %s", event, EventsKey, events,
+				valid.AutoplanHookEvent, valid.CommentHookEvent)
+		}
+		parsed = append(parsed, event)
+	}
+	return parsed, nil
+}
+
+// ValidatePerProject returns an error if the hook's per_project isn't a
+// boolean or if it's set for a hook that doesn't only run for the plan and
+// apply commands, since other commands don't report which projects they ran
+// for.
+func (s WorkflowHook) ValidatePerProject() error {
+	perProject, err := parsePerProject(s.StringVal[PerProjectKey])
+	if err != nil || !perProject {
+		return err
+	}
+	commands := s.StringVal["commands"]
+	if strings.TrimSpace(commands) == "" {
+		return fmt.Errorf("%s requires commands to be set to plan and/or apply", PerProjectKey)
+	}
+	for _, cmd := range strings.Split(commands, ",") {
+		cmd = strings.TrimSpace(cmd)
+		if cmd != "plan" && cmd != "apply" {
+			return fmt.Errorf("%q is not supported with %s, commands must be plan and/or apply", cmd, PerProjectKey)
+		}
+	}
+	return nil
+}
+
+// parsePerProject parses a hook's per_project, which defaults to false.
+func parsePerProject(perProject string) (bool, error) {
+	switch strings.TrimSpace(perProject) {
+	case "", "false":
+		return false, nil
+	case "true":
+		return true, nil
+	}
+	return false, fmt.Errorf("%q is not a valid %s, must be true or false", perProject, PerProjectKey)
+}
+
 // unmarshalGeneric is used by UnmarshalJSON and UnmarshalYAML to unmarshal
 // a step a custom run step: " - run: my custom command"
 // It takes a parameter unmarshal that is a function that tries to unmarshal
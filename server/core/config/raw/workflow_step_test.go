@@ -39,6 +39,18 @@ key: value`,
 				},
 			},
 		},
+		{
+			description: "run step per project",
+			input: `
+run: my command
+per_project: true`,
+			exp: raw.WorkflowHook{
+				StringVal: map[string]string{
+					"run":         "my command",
+					"per_project": "true",
+				},
+			},
+		},
 
 		// Errors
 		{
@@ -138,6 +150,92 @@ func TestWorkflowHook_ToValid(t *testing.T) {
 				RunCommand: "my 'run command'",
 			},
 		},
+		{
+			description: "run step per project",
+			input: raw.WorkflowHook{
+				StringVal: map[string]string{
+					"run":         "my 'run command'",
+					"per_project": "true",
+				},
+			},
+			exp: &valid.WorkflowHook{
+				StepName:   "run",
+				RunCommand: "my 'run command'",
+				PerProject: true,
+			},
+		},
+		{
+			description: "run step with success codes",
+			input: raw.WorkflowHook{
+				StringVal: map[string]string{
+					"run":          "my 'run command'",
+					"successCodes": "2, 3",
+				},
+			},
+			exp: &valid.WorkflowHook{
+				StepName:     "run",
+				RunCommand:   "my 'run command'",
+				SuccessCodes: []int{2, 3},
+			},
+		},
+		{
+			description: "run step with priority",
+			input: raw.WorkflowHook{
+				StringVal: map[string]string{
+					"run":      "my 'run command'",
+					"priority": "-10",
+				},
+			},
+			exp: &valid.WorkflowHook{
+				StepName:   "run",
+				RunCommand: "my 'run command'",
+				Priority:   -10,
+			},
+		},
+		{
+			description: "run step with events",
+			input: raw.WorkflowHook{
+				StringVal: map[string]string{
+					"run":    "my 'run command'",
+					"events": "autoplan, comment",
+				},
+			},
+			exp: &valid.WorkflowHook{
+				StepName:   "run",
+				RunCommand: "my 'run command'",
+				Events:     []string{"autoplan", "comment"},
+			},
+		},
+		{
+			description: "run step with authors",
+			input: raw.WorkflowHook{
+				StringVal: map[string]string{
+					"run":          "my 'run command'",
+					"authors":      "alice, team:contractors",
+					"skip_authors": "team:platform",
+				},
+			},
+			exp: &valid.WorkflowHook{
+				StepName:    "run",
+				RunCommand:  "my 'run command'",
+				Authors:     "alice, team:contractors",
+				SkipAuthors: "team:platform",
+			},
+		},
+		{
+			description: "run step with image",
+			input: raw.WorkflowHook{
+				StringVal: map[string]string{
+					"run":   "my 'run command'",
+					"image": "hashicorp/terraform:1.5",
+				},
+			},
+			exp: &valid.WorkflowHook{
+				StepName:   "run",
+				RunCommand: "my 'run command'",
+				Image:      "hashicorp/terraform:1.5",
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.description, func(t *testing.T) {
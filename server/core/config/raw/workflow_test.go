@@ -101,6 +101,14 @@ apply:
 				},
 			},
 		},
+		{
+			description: "terragrunt set",
+			input: `
+terragrunt: true`,
+			exp: raw.Workflow{
+				Terragrunt: true,
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -133,6 +141,11 @@ func TestWorkflow_Validate(t *testing.T) {
 
 	// Unset keys should validate.
 	Ok(t, (raw.Workflow{}).Validate())
+
+	// Only the apply stage can have on_failure steps.
+	rollback := []raw.Step{{StringVal: map[string]string{"run": "./rollback.sh"}}}
+	Ok(t, raw.Workflow{Apply: &raw.Stage{OnFailure: rollback}}.Validate())
+	ErrEquals(t, "plan: on_failure is only supported in the apply stage.", raw.Workflow{Plan: &raw.Stage{OnFailure: rollback}}.Validate())
 }
 
 func TestWorkflow_ToValid(t *testing.T) {
@@ -229,6 +242,47 @@ func TestWorkflow_ToValid(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "apply on_failure set without steps",
+			input: raw.Workflow{
+				Apply: &raw.Stage{
+					OnFailure: []raw.Step{
+						{
+							StringVal: map[string]string{"run": "./rollback.sh"},
+						},
+					},
+				},
+			},
+			exp: valid.Workflow{
+				Apply: valid.Stage{
+					Steps: valid.DefaultApplyStage.Steps,
+					OnFailure: []valid.Step{
+						{
+							StepName:   "run",
+							RunCommand: "./rollback.sh",
+						},
+					},
+				},
+				Plan:        valid.DefaultPlanStage,
+				PolicyCheck: valid.DefaultPolicyCheckStage,
+				Import:      valid.DefaultImportStage,
+				StateRm:     valid.DefaultStateRmStage,
+			},
+		},
+		{
+			description: "terragrunt set",
+			input: raw.Workflow{
+				Terragrunt: true,
+			},
+			exp: valid.Workflow{
+				Apply:       valid.DefaultApplyStage,
+				Plan:        valid.DefaultPlanStage,
+				PolicyCheck: valid.DefaultPolicyCheckStage,
+				Import:      valid.DefaultImportStage,
+				StateRm:     valid.DefaultStateRmStage,
+				Terragrunt:  true,
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.description, func(t *testing.T) {
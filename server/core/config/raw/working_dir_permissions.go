@@ -0,0 +1,69 @@
+package raw
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/core/config/valid"
+)
+
+// WorkingDirPermissions are the modes, as octal strings like "0750", that the
+// dirs and files of a repo's clones are set to. It's only a server-side repo
+// setting: clones are shared by all of a repo's projects and are set up
+// before the repo's config is read, so there's no project-level override.
+type WorkingDirPermissions struct {
+	DirMode  string `yaml:"dir_mode,omitempty" json:"dir_mode,omitempty"`
+	FileMode string `yaml:"file_mode,omitempty" json:"file_mode,omitempty"`
+}
+
+func (w WorkingDirPermissions) Validate() error {
+	// Atlantis must still be able to read, write and enter the working dir.
+	modeValid := func(ownerPerm os.FileMode) validation.RuleFunc {
+		return func(value interface{}) error {
+			mode := value.(string)
+			if mode == "" {
+				return nil
+			}
+			parsed, err := parseMode(mode)
+			if err != nil {
+				return err
+			}
+			if parsed&ownerPerm != ownerPerm {
+				return fmt.Errorf("must give the owner at least %#o permissions", ownerPerm)
+			}
+			return nil
+		}
+	}
+	return validation.ValidateStruct(&w,
+		validation.Field(&w.DirMode, validation.By(modeValid(0700))),
+		validation.Field(&w.FileMode, validation.By(modeValid(0600))),
+	)
+}
+
+func (w WorkingDirPermissions) ToValid() *valid.WorkingDirPermissions {
+	// Safe to ignore the errors because we test them in Validate().
+	dirMode, _ := parseMode(w.DirMode)
+	fileMode, _ := parseMode(w.FileMode)
+	return &valid.WorkingDirPermissions{
+		DirMode:  dirMode,
+		FileMode: fileMode,
+	}
+}
+
+// parseMode parses an octal permission mode. An empty mode is 0.
+func parseMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return 0, nil
+	}
+	parsed, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not an octal mode", mode)
+	}
+	if parsed > uint64(os.ModePerm) {
+		return 0, errors.New("must be at most 0777")
+	}
+	return os.FileMode(parsed), nil
+}
@@ -0,0 +1,67 @@
+package raw_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/core/config/raw"
+	"github.com/runatlantis/atlantis/server/core/config/valid"
+	. "github.com/runatlantis/atlantis/testing"
+	"gopkg.in/yaml.v2"
+)
+
+func TestWorkingDirPermissions_Unmarshal(t *testing.T) {
+	var result raw.WorkingDirPermissions
+	Ok(t, yaml.UnmarshalStrict([]byte("dir_mode: \"0750\"\nfile_mode: \"0640\"\n"), &result))
+	Equals(t, raw.WorkingDirPermissions{DirMode: "0750", FileMode: "0640"}, result)
+}
+
+func TestWorkingDirPermissions_Validate(t *testing.T) {
+	cases := []struct {
+		description string
+		subject     raw.WorkingDirPermissions
+		expErr      string
+	}{
+		{
+			description: "empty",
+		},
+		{
+			description: "valid modes",
+			subject:     raw.WorkingDirPermissions{DirMode: "0750", FileMode: "640"},
+		},
+		{
+			description: "not octal",
+			subject:     raw.WorkingDirPermissions{DirMode: "rwxr-x---"},
+			expErr:      "dir_mode: \"rwxr-x---\" is not an octal mode.",
+		},
+		{
+			description: "too large",
+			subject:     raw.WorkingDirPermissions{FileMode: "4755"},
+			expErr:      "file_mode: must be at most 0777.",
+		},
+		{
+			description: "dirs the owner can't enter",
+			subject:     raw.WorkingDirPermissions{DirMode: "0650"},
+			expErr:      "dir_mode: must give the owner at least 0700 permissions.",
+		},
+		{
+			description: "files the owner can't write",
+			subject:     raw.WorkingDirPermissions{FileMode: "0440"},
+			expErr:      "file_mode: must give the owner at least 0600 permissions.",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.subject.Validate()
+			if c.expErr == "" {
+				Ok(t, err)
+				return
+			}
+			ErrEquals(t, c.expErr, err)
+		})
+	}
+}
+
+func TestWorkingDirPermissions_ToValid(t *testing.T) {
+	Equals(t, &valid.WorkingDirPermissions{DirMode: 0750, FileMode: 0640}, raw.WorkingDirPermissions{DirMode: "0750", FileMode: "0640"}.ToValid())
+	Equals(t, &valid.WorkingDirPermissions{DirMode: 0700}, raw.WorkingDirPermissions{DirMode: "700"}.ToValid())
+}
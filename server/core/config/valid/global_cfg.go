@@ -1,11 +1,17 @@
 package valid
 
 import (
+	"bytes"
 	"fmt"
+	"os"
 	"regexp"
 	"strings"
+	"text/template"
+	"time"
 
+	"github.com/Masterminds/sprig/v3"
 	version "github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/logging"
 	"github.com/runatlantis/atlantis/server/utils"
 )
@@ -28,6 +34,19 @@ const DeleteSourceBranchOnMergeKey = "delete_source_branch_on_merge"
 const RepoLockingKey = "repo_locking"
 const PolicyCheckKey = "policy_check"
 const CustomPolicyCheckKey = "custom_policy_check"
+const ProjectNameTemplateKey = "project_name_template"
+const PlanOnlyKey = "plan_only"
+const PRDescriptionVarsKey = "pr_description_vars"
+const PlanWarningsAsErrorsKey = "plan_warnings_as_errors"
+const WorkingDirPermissionsKey = "working_dir_permissions"
+const DeletePRWorkspacesKey = "delete_pr_workspaces"
+const ProjectGeneratorKey = "project_generator"
+const TerraformBinaryKey = "terraform_binary"
+const AllowedTerraformVersionsKey = "allowed_terraform_versions"
+const VCSBaseURLKey = "vcs_base_url"
+const ConfirmApplyKey = "confirm_apply"
+const DeploymentEnvironmentKey = "deployment_environment"
+const PlanPresetsKey = "plan_presets"
 
 // DefaultAtlantisFile is the default name of the config file for each repo.
 const DefaultAtlantisFile = "atlantis.yaml"
@@ -71,6 +90,7 @@ type Repo struct {
 	IDRegex                   *regexp.Regexp
 	BranchRegex               *regexp.Regexp
 	RepoConfigFile            string
+	ProjectNameTemplate       string
 	PlanRequirements          []string
 	ApplyRequirements         []string
 	ImportRequirements        []string
@@ -84,6 +104,56 @@ type Repo struct {
 	RepoLocking               *bool
 	PolicyCheck               *bool
 	CustomPolicyCheck         *bool
+	// PlanOnly is true if the repo can only be planned. Plans don't lock
+	// projects and applies are rejected.
+	PlanOnly *bool
+	// PRDescriptionVars is true if the Terraform variables in the
+	// front-matter of pull request descriptions are passed to plans.
+	PRDescriptionVars *bool
+	// PlanWarningsAsErrors match the Terraform warnings that fail plans. If
+	// it's nil it isn't set for the repo.
+	PlanWarningsAsErrors []*regexp.Regexp
+	// DeletePRWorkspaces matches the names of the Terraform workspaces that
+	// are created for a single pull request. They're deleted when the pull
+	// request is closed. If it's nil no workspaces are deleted.
+	DeletePRWorkspaces *regexp.Regexp
+	// ProjectGenerator is a command that's run in the root of the cloned repo
+	// and prints projects to add to the repo config as a JSON array.
+	ProjectGenerator string
+	// TerraformBinary is the absolute path of the terraform binary that's
+	// run for the repo's projects instead of one picked by version.
+	TerraformBinary string
+	// AllowedTerraformVersions are the constraints the Terraform versions of
+	// the repo's projects must meet. If it's nil any version is allowed.
+	AllowedTerraformVersions version.Constraints
+	// VCSBaseURL is the base URL of the VCS instance, ex. a second GitHub
+	// Enterprise, that the repo's API calls go to instead of the instance
+	// of its VCS host type.
+	VCSBaseURL string
+	// ConfirmApply is true if applies must be confirmed with `atlantis
+	// confirm` before they run.
+	ConfirmApply *bool
+	// DeploymentEnvironment is the GitHub deployment environment that a
+	// deployment must be approved in before the repo's projects are applied.
+	// Projects can only override it with allowed_overrides.
+	DeploymentEnvironment string
+	// CleanEnv is true if workflow hooks run with only the env vars Atlantis
+	// sets and CleanEnvAllowedVars instead of the server's whole environment.
+	CleanEnv *bool
+	// CleanEnvAllowedVars are the names of the server's env vars that are
+	// passed to workflow hooks when CleanEnv is true.
+	CleanEnvAllowedVars []string
+	// WorkingDirPermissions are the permissions the repo's clones are set to
+	// before pre workflow hooks run. If it's nil they're left as cloned.
+	WorkingDirPermissions *WorkingDirPermissions
+}
+
+// WorkingDirPermissions are the modes the dirs and files of a clone are set
+// to. A mode of 0 leaves them as cloned. Executable files keep their execute
+// permissions where FileMode grants read permissions.
+type WorkingDirPermissions struct {
+	DirMode  os.FileMode
+	FileMode os.FileMode
 }
 
 type MergedProjectCfg struct {
@@ -102,9 +172,21 @@ type MergedProjectCfg struct {
 	PolicySets                PolicySets
 	DeleteSourceBranchOnMerge bool
 	ExecutionOrderGroup       int
+	ApplyOrder                *int
 	RepoLocking               bool
 	PolicyCheck               bool
 	CustomPolicyCheck         bool
+	StatusContextSuffix       string
+	DeploymentEnvironment     string
+	PlanTimeout               time.Duration
+	ApplyTimeout              time.Duration
+	TerraformParallelism      int
+	PlanOnly                  bool
+	PRDescriptionVars         bool
+	PlanWarningsAsErrors      []*regexp.Regexp
+	PlanPresets               map[string][]string
+	TerraformBinary           string
+	AllowedTerraformVersions  version.Constraints
 }
 
 // WorkflowHook is a map of custom run commands to run before or after workflows.
@@ -115,6 +197,55 @@ type WorkflowHook struct {
 	Shell           string
 	ShellArgs       string
 	Commands        string
+	// PerProject is true if the hook should run once for each project the
+	// command ran for instead of once for the pull request.
+	PerProject bool
+	// SuccessCodes are non-zero exit codes that should be treated as success.
+	SuccessCodes []int
+	// Priority orders pre workflow hooks, which run in ascending order of
+	// priority. Hooks with the same priority run in the order they're
+	// configured.
+	Priority int
+	// Authors is a comma-separated list of the users and teams, prefixed
+	// with "team:", the hook runs for. If it's empty the hook runs for
+	// everyone.
+	Authors string
+	// SkipAuthors is a comma-separated list of the users and teams, prefixed
+	// with "team:", the hook doesn't run for.
+	SkipAuthors string
+	// Image is the container image the hook runs in with docker or podman.
+	// If it's empty the hook runs on the host.
+	Image string
+	// Events are the events the hook runs for, AutoplanHookEvent and
+	// CommentHookEvent. If it's empty the hook runs for all events.
+	Events []string
+}
+
+// AutoplanHookEvent is the event of a pull request being opened or updated,
+// which autoplans it.
+const AutoplanHookEvent = "autoplan"
+
+// CommentHookEvent is the event of a command being commented on a pull
+// request.
+const CommentHookEvent = "comment"
+
+// RunsForEvent returns true if the hook runs for event, AutoplanHookEvent or
+// CommentHookEvent.
+func (h WorkflowHook) RunsForEvent(event string) bool {
+	return len(h.Events) == 0 || utils.SlicesContains(h.Events, event)
+}
+
+// IsSuccessCode returns true if a hook exiting with exitCode succeeded.
+func (h WorkflowHook) IsSuccessCode(exitCode int) bool {
+	if exitCode == 0 {
+		return true
+	}
+	for _, c := range h.SuccessCodes {
+		if c == exitCode {
+			return true
+		}
+	}
+	return false
 }
 
 // DefaultApplyStage is the Atlantis default apply stage.
@@ -246,7 +377,7 @@ func NewGlobalCfgFromArgs(args GlobalCfgArgs) GlobalCfg {
 	repoLockingKey := true
 	customPolicyCheck := false
 	if args.AllowRepoCfg {
-		allowedOverrides = []string{PlanRequirementsKey, ApplyRequirementsKey, ImportRequirementsKey, WorkflowKey, DeleteSourceBranchOnMergeKey, RepoLockingKey, PolicyCheckKey}
+		allowedOverrides = []string{PlanRequirementsKey, ApplyRequirementsKey, ImportRequirementsKey, WorkflowKey, DeleteSourceBranchOnMergeKey, RepoLockingKey, PolicyCheckKey, PlanPresetsKey}
 		allowCustomWorkflows = true
 	}
 
@@ -278,6 +409,8 @@ func NewGlobalCfgFromArgs(args GlobalCfgArgs) GlobalCfg {
 }
 
 // IDMatches returns true if the repo ID otherID matches this config.
+// Repo IDs can contain any number of path segments since GitLab repos can be
+// nested in subgroups, ex. gitlab.com/group/subgroup/subsubgroup/repo.
 func (r Repo) IDMatches(otherID string) bool {
 	if r.ID != "" {
 		return r.ID == otherID
@@ -306,6 +439,7 @@ func (r Repo) IDString() string {
 func (g GlobalCfg) MergeProjectCfg(log logging.SimpleLogging, repoID string, proj Project, rCfg RepoCfg) MergedProjectCfg {
 	log.Debug("MergeProjectCfg started")
 	planReqs, applyReqs, importReqs, workflow, allowedOverrides, allowCustomWorkflows, deleteSourceBranchOnMerge, repoLocking, policyCheck, customPolicyCheck := g.getMatchingCfg(log, repoID)
+	deploymentEnvironment := g.DeploymentEnvironment(repoID)
 
 	// If repos are allowed to override certain keys then override them.
 	for _, key := range allowedOverrides {
@@ -376,6 +510,11 @@ func (g GlobalCfg) MergeProjectCfg(log logging.SimpleLogging, repoID string, pro
 				log.Debug("overriding server-defined %s with repo settings: [%t]", CustomPolicyCheckKey, *proj.CustomPolicyCheck)
 				customPolicyCheck = *proj.CustomPolicyCheck
 			}
+		case DeploymentEnvironmentKey:
+			if proj.DeploymentEnvironment != "" {
+				log.Debug("overriding server-defined %s with repo settings: [%s]", DeploymentEnvironmentKey, proj.DeploymentEnvironment)
+				deploymentEnvironment = proj.DeploymentEnvironment
+			}
 		}
 		log.Debug("MergeProjectCfg completed")
 	}
@@ -397,9 +536,21 @@ func (g GlobalCfg) MergeProjectCfg(log logging.SimpleLogging, repoID string, pro
 		PolicySets:                g.PolicySets,
 		DeleteSourceBranchOnMerge: deleteSourceBranchOnMerge,
 		ExecutionOrderGroup:       proj.ExecutionOrderGroup,
+		ApplyOrder:                proj.ApplyOrder,
 		RepoLocking:               repoLocking,
 		PolicyCheck:               policyCheck,
 		CustomPolicyCheck:         customPolicyCheck,
+		StatusContextSuffix:       proj.StatusContextSuffix,
+		DeploymentEnvironment:     deploymentEnvironment,
+		PlanTimeout:               proj.PlanTimeout,
+		ApplyTimeout:              proj.ApplyTimeout,
+		TerraformParallelism:      proj.TerraformParallelism,
+		PlanOnly:                  g.PlanOnly(repoID),
+		PRDescriptionVars:         g.PRDescriptionVars(repoID),
+		PlanWarningsAsErrors:      g.PlanWarningsAsErrors(repoID),
+		PlanPresets:               proj.PlanPresets,
+		TerraformBinary:           g.TerraformBinary(repoID),
+		AllowedTerraformVersions:  g.AllowedTerraformVersions(repoID),
 	}
 }
 
@@ -423,6 +574,12 @@ func (g GlobalCfg) DefaultProjCfg(log logging.SimpleLogging, repoID string, repo
 		RepoLocking:               repoLocking,
 		PolicyCheck:               policyCheck,
 		CustomPolicyCheck:         customPolicyCheck,
+		DeploymentEnvironment:     g.DeploymentEnvironment(repoID),
+		PlanOnly:                  g.PlanOnly(repoID),
+		PRDescriptionVars:         g.PRDescriptionVars(repoID),
+		PlanWarningsAsErrors:      g.PlanWarningsAsErrors(repoID),
+		TerraformBinary:           g.TerraformBinary(repoID),
+		AllowedTerraformVersions:  g.AllowedTerraformVersions(repoID),
 	}
 }
 
@@ -470,6 +627,12 @@ func (g GlobalCfg) ValidateRepoCfg(rCfg RepoCfg, repoID string) error {
 		if p.CustomPolicyCheck != nil && !utils.SlicesContains(allowedOverrides, CustomPolicyCheckKey) {
 			return fmt.Errorf("repo config not allowed to set '%s' key: server-side config needs '%s: [%s]'", CustomPolicyCheckKey, AllowedOverridesKey, CustomPolicyCheckKey)
 		}
+		if p.DeploymentEnvironment != "" && !utils.SlicesContains(allowedOverrides, DeploymentEnvironmentKey) {
+			return fmt.Errorf("repo config not allowed to set '%s' key: server-side config needs '%s: [%s]'", DeploymentEnvironmentKey, AllowedOverridesKey, DeploymentEnvironmentKey)
+		}
+		if len(p.PlanPresets) > 0 && !utils.SlicesContains(allowedOverrides, PlanPresetsKey) {
+			return fmt.Errorf("repo config not allowed to set '%s' key: server-side config needs '%s: [%s]'", PlanPresetsKey, AllowedOverridesKey, PlanPresetsKey)
+		}
 	}
 
 	// Check custom workflows.
@@ -496,11 +659,11 @@ func (g GlobalCfg) ValidateRepoCfg(rCfg RepoCfg, repoID string) error {
 		}
 	}
 
-	// Check workflow is allowed
+	// Check workflow is allowed. Repos that don't set allowed_workflows can
+	// only select the default workflow or, if allowed, their own workflows.
 	var allowedWorkflows []string
 	for _, repo := range g.Repos {
 		if repo.IDMatches(repoID) {
-
 			if repo.AllowedWorkflows != nil {
 				allowedWorkflows = repo.AllowedWorkflows
 			}
@@ -508,19 +671,20 @@ func (g GlobalCfg) ValidateRepoCfg(rCfg RepoCfg, repoID string) error {
 	}
 
 	for _, p := range rCfg.Projects {
+		if p.WorkflowName == nil {
+			continue
+		}
+		name := *p.WorkflowName
 		// default is always allowed
-		if p.WorkflowName != nil && len(allowedWorkflows) != 0 {
-			name := *p.WorkflowName
-			if allowCustomWorkflows {
-				// If we allow CustomWorkflows we need to check that workflow name is defined inside repo and not global.
-				if mapContainsF(rCfg.Workflows, name) {
-					break
-				}
-			}
-
-			if !utils.SlicesContains(allowedWorkflows, name) {
-				return fmt.Errorf("workflow '%s' is not allowed for this repo", name)
-			}
+		if name == DefaultWorkflowName {
+			continue
+		}
+		// Workflows defined inside the repo are covered by allow_custom_workflows.
+		if allowCustomWorkflows && mapContainsF(rCfg.Workflows, name) {
+			continue
+		}
+		if !utils.SlicesContains(allowedWorkflows, name) {
+			return fmt.Errorf("workflow '%s' is not allowed for this repo: server-side config needs '%s: [%s]'", name, AllowedWorkflowsKey, name)
 		}
 	}
 
@@ -626,12 +790,216 @@ func (g GlobalCfg) MatchingRepo(repoID string) *Repo {
 	return nil
 }
 
-// RepoConfigFile returns a repository specific file path
+// RepoConfigFile returns a repository specific file path. Like other repo
+// settings, the last matching repo that sets it wins so the default set by
+// --repo-config-file applies unless a more specific repo overrides it.
 // If not defined, return atlantis.yaml as default
 func (g GlobalCfg) RepoConfigFile(repoID string) string {
-	repo := g.MatchingRepo(repoID)
-	if repo != nil && repo.RepoConfigFile != "" {
-		return repo.RepoConfigFile
+	for i := len(g.Repos) - 1; i >= 0; i-- {
+		repo := g.Repos[i]
+		if repo.IDMatches(repoID) && repo.RepoConfigFile != "" {
+			return repo.RepoConfigFile
+		}
 	}
 	return DefaultAtlantisFile
 }
+
+// PlanOnly returns true if the repo with id repoID is plan-only. Like other
+// repo settings, the last matching repo that sets it wins.
+func (g GlobalCfg) PlanOnly(repoID string) bool {
+	for i := len(g.Repos) - 1; i >= 0; i-- {
+		repo := g.Repos[i]
+		if repo.IDMatches(repoID) && repo.PlanOnly != nil {
+			return *repo.PlanOnly
+		}
+	}
+	return false
+}
+
+// PRDescriptionVars returns true if the Terraform variables in the
+// front-matter of the descriptions of the pull requests of the repo with id
+// repoID are passed to plans. Like other repo settings, the last matching repo
+// that sets it wins.
+func (g GlobalCfg) PRDescriptionVars(repoID string) bool {
+	for i := len(g.Repos) - 1; i >= 0; i-- {
+		repo := g.Repos[i]
+		if repo.IDMatches(repoID) && repo.PRDescriptionVars != nil {
+			return *repo.PRDescriptionVars
+		}
+	}
+	return false
+}
+
+// PlanWarningsAsErrors returns the regexes matching the Terraform warnings
+// that fail the plans of the repo with id repoID. Like other repo settings, the
+// last matching repo that sets it wins.
+func (g GlobalCfg) PlanWarningsAsErrors(repoID string) []*regexp.Regexp {
+	for i := len(g.Repos) - 1; i >= 0; i-- {
+		repo := g.Repos[i]
+		if repo.IDMatches(repoID) && repo.PlanWarningsAsErrors != nil {
+			return repo.PlanWarningsAsErrors
+		}
+	}
+	return nil
+}
+
+// WorkingDirPermissions returns the permissions that the clones of the repo with
+// id repoID are set to, or nil if they're left as cloned. Like other repo
+// settings, the last matching repo that sets it wins.
+func (g GlobalCfg) WorkingDirPermissions(repoID string) *WorkingDirPermissions {
+	for i := len(g.Repos) - 1; i >= 0; i-- {
+		repo := g.Repos[i]
+		if repo.IDMatches(repoID) && repo.WorkingDirPermissions != nil {
+			return repo.WorkingDirPermissions
+		}
+	}
+	return nil
+}
+
+// DeletePRWorkspaces returns the regex matching the names of the Terraform
+// workspaces of the repo with id repoID that are deleted when the pull request
+// that created them is closed, or nil if none are. Like other repo settings,
+// the last matching repo that sets it wins.
+func (g GlobalCfg) DeletePRWorkspaces(repoID string) *regexp.Regexp {
+	for i := len(g.Repos) - 1; i >= 0; i-- {
+		repo := g.Repos[i]
+		if repo.IDMatches(repoID) && repo.DeletePRWorkspaces != nil {
+			return repo.DeletePRWorkspaces
+		}
+	}
+	return nil
+}
+
+// ProjectGenerator returns the project_generator command of the repo with id
+// repoID, or an empty string if it has none. Like other repo settings, the
+// last matching repo that sets it wins.
+func (g GlobalCfg) ProjectGenerator(repoID string) string {
+	for i := len(g.Repos) - 1; i >= 0; i-- {
+		repo := g.Repos[i]
+		if repo.IDMatches(repoID) && repo.ProjectGenerator != "" {
+			return repo.ProjectGenerator
+		}
+	}
+	return ""
+}
+
+// TerraformBinary returns the terraform binary that the repo with id repoID
+// runs, or "" if it runs the binary for its projects' versions. Like other
+// repo settings, the last matching repo that sets it wins.
+func (g GlobalCfg) TerraformBinary(repoID string) string {
+	for i := len(g.Repos) - 1; i >= 0; i-- {
+		repo := g.Repos[i]
+		if repo.IDMatches(repoID) && repo.TerraformBinary != "" {
+			return repo.TerraformBinary
+		}
+	}
+	return ""
+}
+
+// AllowedTerraformVersions returns the constraints the Terraform versions of
+// the projects of the repo with id repoID must meet, or nil if any version is
+// allowed. Like other repo settings, the last matching repo that sets it wins.
+func (g GlobalCfg) AllowedTerraformVersions(repoID string) version.Constraints {
+	for i := len(g.Repos) - 1; i >= 0; i-- {
+		repo := g.Repos[i]
+		if repo.IDMatches(repoID) && repo.AllowedTerraformVersions != nil {
+			return repo.AllowedTerraformVersions
+		}
+	}
+	return nil
+}
+
+// VCSBaseURL returns the base URL of the VCS instance that the API calls for
+// the repo with id repoID go to, or "" if they go to the instance of its VCS
+// host type. Like other repo settings, the last matching repo that sets it
+// wins.
+func (g GlobalCfg) VCSBaseURL(repoID string) string {
+	for i := len(g.Repos) - 1; i >= 0; i-- {
+		repo := g.Repos[i]
+		if repo.IDMatches(repoID) && repo.VCSBaseURL != "" {
+			return repo.VCSBaseURL
+		}
+	}
+	return ""
+}
+
+// ConfirmApply returns true if the applies of the repo with id repoID must be
+// confirmed before they run. Like other repo settings, the last matching repo
+// that sets it wins.
+func (g GlobalCfg) ConfirmApply(repoID string) bool {
+	for i := len(g.Repos) - 1; i >= 0; i-- {
+		repo := g.Repos[i]
+		if repo.IDMatches(repoID) && repo.ConfirmApply != nil {
+			return *repo.ConfirmApply
+		}
+	}
+	return false
+}
+
+// DeploymentEnvironment returns the GitHub deployment environment that the
+// projects of the repo with id repoID are applied in, or "" if they aren't
+// gated by one. Like other repo settings, the last matching repo that sets it
+// wins.
+func (g GlobalCfg) DeploymentEnvironment(repoID string) string {
+	for i := len(g.Repos) - 1; i >= 0; i-- {
+		repo := g.Repos[i]
+		if repo.IDMatches(repoID) && repo.DeploymentEnvironment != "" {
+			return repo.DeploymentEnvironment
+		}
+	}
+	return ""
+}
+
+// CleanEnv returns true if the workflow hooks of the repo with id repoID run
+// with a scrubbed environment, and the names of the server's env vars that are
+// still passed to them. Like other repo settings, the last matching repo that
+// sets clean_env wins, along with its clean_env_allowed_vars.
+func (g GlobalCfg) CleanEnv(repoID string) (bool, []string) {
+	for i := len(g.Repos) - 1; i >= 0; i-- {
+		repo := g.Repos[i]
+		if repo.IDMatches(repoID) && repo.CleanEnv != nil {
+			return *repo.CleanEnv, repo.CleanEnvAllowedVars
+		}
+	}
+	return false, nil
+}
+
+// ProjectNameTemplateData is the data available to a repo's
+// project_name_template when naming autodiscovered projects.
+type ProjectNameTemplateData struct {
+	// RepoFullName is the owner and repo name, ex. "runatlantis/atlantis".
+	RepoFullName string
+	// Path is the project's directory relative to the repo root, ex. "envs/prod".
+	Path string
+}
+
+// ParseProjectNameTemplate parses a project_name_template. Sprig functions
+// are available so that paths can be transformed, ex.
+// {{ .Path | trimPrefix "envs/" | replace "/" "-" }}.
+func ParseProjectNameTemplate(text string) (*template.Template, error) {
+	return template.New(ProjectNameTemplateKey).Funcs(sprig.TxtFuncMap()).Option("missingkey=error").Parse(text)
+}
+
+// AutodiscoveredProjectName returns the name of the project at repoRelDir
+// that was found via autodiscovery, using the project_name_template of the repo
+// matching repoID. If no template is configured it returns an empty string
+// since autodiscovered projects are unnamed by default.
+func (g GlobalCfg) AutodiscoveredProjectName(repoID string, repoFullName string, repoRelDir string) (string, error) {
+	repo := g.MatchingRepo(repoID)
+	if repo == nil || repo.ProjectNameTemplate == "" {
+		return "", nil
+	}
+	tmpl, err := ParseProjectNameTemplate(repo.ProjectNameTemplate)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing %s", ProjectNameTemplateKey)
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, ProjectNameTemplateData{RepoFullName: repoFullName, Path: repoRelDir}); err != nil {
+		return "", errors.Wrapf(err, "rendering %s for dir %q", ProjectNameTemplateKey, repoRelDir)
+	}
+	name := strings.TrimSpace(buf.String())
+	if name == "" {
+		return "", fmt.Errorf("%s rendered an empty name for dir %q", ProjectNameTemplateKey, repoRelDir)
+	}
+	return name, nil
+}
@@ -209,7 +209,7 @@ func TestNewGlobalCfg(t *testing.T) {
 
 			if c.allowRepoCfg {
 				exp.Repos[0].AllowCustomWorkflows = Bool(true)
-				exp.Repos[0].AllowedOverrides = []string{"plan_requirements", "apply_requirements", "import_requirements", "workflow", "delete_source_branch_on_merge", "repo_locking", "policy_check"}
+				exp.Repos[0].AllowedOverrides = []string{"plan_requirements", "apply_requirements", "import_requirements", "workflow", "delete_source_branch_on_merge", "repo_locking", "policy_check", "plan_presets"}
 			}
 			if c.mergeableReq {
 				exp.Repos[0].PlanRequirements = append(exp.Repos[0].PlanRequirements, "mergeable")
@@ -289,7 +289,7 @@ func TestGlobalCfg_ValidateRepoCfg(t *testing.T) {
 				},
 			},
 			repoID: "github.com/owner/repo",
-			expErr: "workflow 'forbidden' is not allowed for this repo",
+			expErr: "workflow 'forbidden' is not allowed for this repo: server-side config needs 'allowed_workflows: [forbidden]'",
 		},
 		"repo uses workflow that is defined server side but not allowed (without custom workflows)": {
 			gCfg: valid.GlobalCfg{
@@ -322,7 +322,7 @@ func TestGlobalCfg_ValidateRepoCfg(t *testing.T) {
 				},
 			},
 			repoID: "github.com/owner/repo",
-			expErr: "workflow 'forbidden' is not allowed for this repo",
+			expErr: "workflow 'forbidden' is not allowed for this repo: server-side config needs 'allowed_workflows: [forbidden]'",
 		},
 		"repo uses workflow that is defined in both places with same name (without custom workflows)": {
 			gCfg: valid.GlobalCfg{
@@ -571,6 +571,71 @@ func TestGlobalCfg_ValidateRepoCfg(t *testing.T) {
 			repoID: "github.com/owner/repo",
 			expErr: "",
 		},
+		"repo without allowed workflows can only use default": {
+			gCfg: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{
+						AllowRepoCfg: true,
+					}).Repos[0],
+				},
+				Workflows: map[string]valid.Workflow{
+					"default": {},
+					"custom":  {},
+				},
+			},
+			rCfg: valid.RepoCfg{
+				Projects: []valid.Project{
+					{
+						Dir:          "a",
+						Workspace:    "default",
+						WorkflowName: String("default"),
+					},
+					{
+						Dir:          "b",
+						Workspace:    "default",
+						WorkflowName: String("custom"),
+					},
+				},
+			},
+			repoID: "github.com/owner/repo",
+			expErr: "workflow 'custom' is not allowed for this repo: server-side config needs 'allowed_workflows: [custom]'",
+		},
+		"repo defined workflow doesn't skip checks on other projects": {
+			gCfg: valid.GlobalCfg{
+				Repos: []valid.Repo{
+					valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{
+						AllowRepoCfg: true,
+					}).Repos[0],
+					{
+						ID:               "github.com/owner/repo",
+						AllowedWorkflows: []string{"allowed"},
+					},
+				},
+				Workflows: map[string]valid.Workflow{
+					"allowed":   {},
+					"forbidden": {},
+				},
+			},
+			rCfg: valid.RepoCfg{
+				Projects: []valid.Project{
+					{
+						Dir:          "a",
+						Workspace:    "default",
+						WorkflowName: String("repodefined"),
+					},
+					{
+						Dir:          "b",
+						Workspace:    "default",
+						WorkflowName: String("forbidden"),
+					},
+				},
+				Workflows: map[string]valid.Workflow{
+					"repodefined": {},
+				},
+			},
+			repoID: "github.com/owner/repo",
+			expErr: "workflow 'forbidden' is not allowed for this repo: server-side config needs 'allowed_workflows: [forbidden]'",
+		},
 		"plan_reqs not allowed": {
 			gCfg: valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{
 				AllowRepoCfg:  false,
@@ -1121,6 +1186,22 @@ func TestRepo_IDMatches(t *testing.T) {
 	Equals(t, true, (valid.Repo{IDRegex: regexp.MustCompile("github.com/owner.*")}).IDMatches("github.com/owner/repo"))
 }
 
+func TestRepo_IDMatches_GitlabSubgroups(t *testing.T) {
+	id := "gitlab.com/group/subgroup/subsubgroup/repo"
+
+	// Test exact matches.
+	Equals(t, true, (valid.Repo{ID: "gitlab.com/group/subgroup/subsubgroup/repo"}).IDMatches(id))
+	Equals(t, false, (valid.Repo{ID: "gitlab.com/group/subgroup/repo"}).IDMatches(id))
+	Equals(t, false, (valid.Repo{ID: "gitlab.com/group/subgroup/subsubgroup"}).IDMatches(id))
+
+	// Test regexes.
+	Equals(t, true, (valid.Repo{IDRegex: regexp.MustCompile("gitlab.com/group/.*")}).IDMatches(id))
+	Equals(t, true, (valid.Repo{IDRegex: regexp.MustCompile("^gitlab.com/group/subgroup/[^/]+/repo$")}).IDMatches(id))
+	Equals(t, true, (valid.Repo{IDRegex: regexp.MustCompile("^gitlab.com/group/(.+/)?repo$")}).IDMatches(id))
+	Equals(t, false, (valid.Repo{IDRegex: regexp.MustCompile("^gitlab.com/group/[^/]+/repo$")}).IDMatches(id))
+	Equals(t, false, (valid.Repo{IDRegex: regexp.MustCompile("gitlab.com/othergroup/.*")}).IDMatches(id))
+}
+
 func TestRepo_IDString(t *testing.T) {
 	Equals(t, "github.com/owner/repo", (valid.Repo{ID: "github.com/owner/repo"}).IDString())
 	Equals(t, "/regex.*/", (valid.Repo{IDRegex: regexp.MustCompile("regex.*")}).IDString())
@@ -1210,6 +1291,284 @@ func TestGlobalCfg_MatchingRepo(t *testing.T) {
 	}
 }
 
+func TestGlobalCfg_PlanOnly(t *testing.T) {
+	gCfg := valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{IDRegex: regexp.MustCompile(".*")},
+			{IDRegex: regexp.MustCompile("^github.com/owner/"), PlanOnly: Bool(true)},
+			{ID: "github.com/owner/applied", PlanOnly: Bool(false)},
+			{ID: "github.com/owner/unset"},
+		},
+	}
+	Equals(t, false, gCfg.PlanOnly("github.com/other/repo"))
+	Equals(t, true, gCfg.PlanOnly("github.com/owner/repo"))
+	Equals(t, false, gCfg.PlanOnly("github.com/owner/applied"))
+	// Repos that don't set plan_only inherit it from earlier matches.
+	Equals(t, true, gCfg.PlanOnly("github.com/owner/unset"))
+
+	mergedCfg := gCfg.DefaultProjCfg(logging.NewNoopLogger(t), "github.com/owner/repo", ".", "default")
+	Equals(t, true, mergedCfg.PlanOnly)
+}
+
+func TestGlobalCfg_TerraformBinary(t *testing.T) {
+	gCfg := valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{IDRegex: regexp.MustCompile(".*")},
+			{IDRegex: regexp.MustCompile("^github.com/owner/"), TerraformBinary: "/opt/terraform/1.5.7/terraform"},
+			{ID: "github.com/owner/pinned", TerraformBinary: "/opt/terraform/1.3.9/terraform"},
+			{ID: "github.com/owner/unset"},
+		},
+	}
+	Equals(t, "", gCfg.TerraformBinary("github.com/other/repo"))
+	Equals(t, "/opt/terraform/1.5.7/terraform", gCfg.TerraformBinary("github.com/owner/repo"))
+	Equals(t, "/opt/terraform/1.3.9/terraform", gCfg.TerraformBinary("github.com/owner/pinned"))
+	// Repos that don't set terraform_binary inherit it from earlier matches.
+	Equals(t, "/opt/terraform/1.5.7/terraform", gCfg.TerraformBinary("github.com/owner/unset"))
+
+	mergedCfg := gCfg.DefaultProjCfg(logging.NewNoopLogger(t), "github.com/owner/pinned", ".", "default")
+	Equals(t, "/opt/terraform/1.3.9/terraform", mergedCfg.TerraformBinary)
+	mergedCfg = gCfg.MergeProjectCfg(logging.NewNoopLogger(t), "github.com/owner/pinned", valid.Project{Dir: ".", Workspace: "default"}, valid.RepoCfg{})
+	Equals(t, "/opt/terraform/1.3.9/terraform", mergedCfg.TerraformBinary)
+}
+
+func TestGlobalCfg_AllowedTerraformVersions(t *testing.T) {
+	supported, err := version.NewConstraint(">= 1.3.0, < 2.0.0")
+	Ok(t, err)
+	legacy, err := version.NewConstraint("~> 0.15.0")
+	Ok(t, err)
+	gCfg := valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{IDRegex: regexp.MustCompile(".*")},
+			{IDRegex: regexp.MustCompile("^github.com/owner/"), AllowedTerraformVersions: supported},
+			{ID: "github.com/owner/legacy", AllowedTerraformVersions: legacy},
+			{ID: "github.com/owner/unset"},
+		},
+	}
+	Equals(t, version.Constraints(nil), gCfg.AllowedTerraformVersions("github.com/other/repo"))
+	Equals(t, supported, gCfg.AllowedTerraformVersions("github.com/owner/repo"))
+	Equals(t, legacy, gCfg.AllowedTerraformVersions("github.com/owner/legacy"))
+	// Repos that don't set allowed_terraform_versions inherit it from earlier
+	// matches.
+	Equals(t, supported, gCfg.AllowedTerraformVersions("github.com/owner/unset"))
+
+	mergedCfg := gCfg.DefaultProjCfg(logging.NewNoopLogger(t), "github.com/owner/legacy", ".", "default")
+	Equals(t, legacy, mergedCfg.AllowedTerraformVersions)
+	mergedCfg = gCfg.MergeProjectCfg(logging.NewNoopLogger(t), "github.com/owner/legacy", valid.Project{Dir: ".", Workspace: "default"}, valid.RepoCfg{})
+	Equals(t, legacy, mergedCfg.AllowedTerraformVersions)
+}
+
+func TestGlobalCfg_VCSBaseURL(t *testing.T) {
+	gCfg := valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{IDRegex: regexp.MustCompile(".*")},
+			{IDRegex: regexp.MustCompile("^github.com/mirrored/"), VCSBaseURL: "https://ghe2.example.com"},
+			{ID: "github.com/mirrored/unset"},
+		},
+	}
+	Equals(t, "", gCfg.VCSBaseURL("github.com/owner/repo"))
+	Equals(t, "https://ghe2.example.com", gCfg.VCSBaseURL("github.com/mirrored/repo"))
+	// Repos that don't set vcs_base_url inherit it from earlier matches.
+	Equals(t, "https://ghe2.example.com", gCfg.VCSBaseURL("github.com/mirrored/unset"))
+}
+
+func TestGlobalCfg_PRDescriptionVars(t *testing.T) {
+	gCfg := valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{IDRegex: regexp.MustCompile(".*")},
+			{IDRegex: regexp.MustCompile("^github.com/owner/"), PRDescriptionVars: Bool(true)},
+			{ID: "github.com/owner/disabled", PRDescriptionVars: Bool(false)},
+		},
+	}
+	Equals(t, false, gCfg.PRDescriptionVars("github.com/other/repo"))
+	Equals(t, true, gCfg.PRDescriptionVars("github.com/owner/repo"))
+	Equals(t, false, gCfg.PRDescriptionVars("github.com/owner/disabled"))
+
+	mergedCfg := gCfg.DefaultProjCfg(logging.NewNoopLogger(t), "github.com/owner/repo", ".", "default")
+	Equals(t, true, mergedCfg.PRDescriptionVars)
+}
+
+func TestGlobalCfg_DeletePRWorkspaces(t *testing.T) {
+	gCfg := valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{IDRegex: regexp.MustCompile(".*")},
+			{IDRegex: regexp.MustCompile("^github.com/owner/"), DeletePRWorkspaces: regexp.MustCompile("^pr-")},
+			{ID: "github.com/owner/repo", DeletePRWorkspaces: regexp.MustCompile("^review-")},
+		},
+	}
+	Assert(t, gCfg.DeletePRWorkspaces("github.com/other/repo") == nil, "expected no regex for other repos")
+	Equals(t, "^pr-", gCfg.DeletePRWorkspaces("github.com/owner/other").String())
+	Equals(t, "^review-", gCfg.DeletePRWorkspaces("github.com/owner/repo").String())
+}
+
+func TestGlobalCfg_PlanWarningsAsErrors(t *testing.T) {
+	deprecated := []*regexp.Regexp{regexp.MustCompile("deprecated")}
+	gCfg := valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{IDRegex: regexp.MustCompile(".*")},
+			{IDRegex: regexp.MustCompile("^github.com/owner/"), PlanWarningsAsErrors: deprecated},
+			{ID: "github.com/owner/lenient", PlanWarningsAsErrors: []*regexp.Regexp{}},
+			{ID: "github.com/owner/unset"},
+		},
+	}
+	Assert(t, gCfg.PlanWarningsAsErrors("github.com/other/repo") == nil, "expected no regexes for other repos")
+	Equals(t, deprecated, gCfg.PlanWarningsAsErrors("github.com/owner/repo"))
+	Equals(t, deprecated, gCfg.PlanWarningsAsErrors("github.com/owner/unset"))
+	Equals(t, 0, len(gCfg.PlanWarningsAsErrors("github.com/owner/lenient")))
+
+	mergedCfg := gCfg.DefaultProjCfg(logging.NewNoopLogger(t), "github.com/owner/repo", ".", "default")
+	Equals(t, deprecated, mergedCfg.PlanWarningsAsErrors)
+}
+
+func TestGlobalCfg_WorkingDirPermissions(t *testing.T) {
+	gCfg := valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{IDRegex: regexp.MustCompile(".*"), WorkingDirPermissions: &valid.WorkingDirPermissions{DirMode: 0750, FileMode: 0640}},
+			{ID: "github.com/owner/private", WorkingDirPermissions: &valid.WorkingDirPermissions{DirMode: 0700, FileMode: 0600}},
+			{ID: "github.com/owner/unset"},
+		},
+	}
+	Equals(t, &valid.WorkingDirPermissions{DirMode: 0750, FileMode: 0640}, gCfg.WorkingDirPermissions("github.com/owner/repo"))
+	Equals(t, &valid.WorkingDirPermissions{DirMode: 0750, FileMode: 0640}, gCfg.WorkingDirPermissions("github.com/owner/unset"))
+	Equals(t, &valid.WorkingDirPermissions{DirMode: 0700, FileMode: 0600}, gCfg.WorkingDirPermissions("github.com/owner/private"))
+	Assert(t, valid.GlobalCfg{}.WorkingDirPermissions("github.com/owner/repo") == nil, "expected no permissions by default")
+}
+
+func TestGlobalCfg_CleanEnv(t *testing.T) {
+	gCfg := valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{IDRegex: regexp.MustCompile(".*")},
+			{IDRegex: regexp.MustCompile("^github.com/owner/"), CleanEnv: Bool(true), CleanEnvAllowedVars: []string{"PATH"}},
+			{ID: "github.com/owner/dirty", CleanEnv: Bool(false)},
+			{ID: "github.com/owner/unset"},
+		},
+	}
+	cleanEnv, allowedVars := gCfg.CleanEnv("github.com/other/repo")
+	Equals(t, false, cleanEnv)
+	Equals(t, []string(nil), allowedVars)
+	cleanEnv, allowedVars = gCfg.CleanEnv("github.com/owner/repo")
+	Equals(t, true, cleanEnv)
+	Equals(t, []string{"PATH"}, allowedVars)
+	cleanEnv, _ = gCfg.CleanEnv("github.com/owner/dirty")
+	Equals(t, false, cleanEnv)
+	// Repos that don't set clean_env inherit it from earlier matches.
+	cleanEnv, allowedVars = gCfg.CleanEnv("github.com/owner/unset")
+	Equals(t, true, cleanEnv)
+	Equals(t, []string{"PATH"}, allowedVars)
+}
+
+func TestGlobalCfg_ConfirmApply(t *testing.T) {
+	gCfg := valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{IDRegex: regexp.MustCompile(".*")},
+			{IDRegex: regexp.MustCompile("^github.com/owner/"), ConfirmApply: Bool(true)},
+			{ID: "github.com/owner/unconfirmed", ConfirmApply: Bool(false)},
+			{ID: "github.com/owner/unset"},
+		},
+	}
+	Equals(t, false, gCfg.ConfirmApply("github.com/other/repo"))
+	Equals(t, true, gCfg.ConfirmApply("github.com/owner/repo"))
+	Equals(t, false, gCfg.ConfirmApply("github.com/owner/unconfirmed"))
+	// Repos that don't set confirm_apply inherit it from earlier matches.
+	Equals(t, true, gCfg.ConfirmApply("github.com/owner/unset"))
+}
+
+func TestGlobalCfg_DeploymentEnvironment(t *testing.T) {
+	gCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{AllowRepoCfg: true})
+	gCfg.Repos = append(gCfg.Repos,
+		valid.Repo{IDRegex: regexp.MustCompile("^github.com/owner/"), DeploymentEnvironment: "production"},
+		valid.Repo{ID: "github.com/owner/overridable", AllowedOverrides: []string{valid.DeploymentEnvironmentKey}},
+	)
+	Equals(t, "", gCfg.DeploymentEnvironment("github.com/other/repo"))
+	Equals(t, "production", gCfg.DeploymentEnvironment("github.com/owner/repo"))
+	// Repos that don't set deployment_environment inherit it from earlier
+	// matches.
+	Equals(t, "production", gCfg.DeploymentEnvironment("github.com/owner/overridable"))
+
+	log := logging.NewNoopLogger(t)
+	proj := valid.Project{Dir: ".", Workspace: "default", DeploymentEnvironment: "staging"}
+	rCfg := valid.RepoCfg{Projects: []valid.Project{proj}}
+
+	// Repo configs can't remove or change the server-side environment
+	// unless it's an allowed override.
+	ErrEquals(t, "repo config not allowed to set 'deployment_environment' key: server-side config needs 'allowed_overrides: [deployment_environment]'",
+		gCfg.ValidateRepoCfg(rCfg, "github.com/owner/repo"))
+	Equals(t, "production", gCfg.DefaultProjCfg(log, "github.com/owner/repo", ".", "default").DeploymentEnvironment)
+	Equals(t, "production", gCfg.MergeProjectCfg(log, "github.com/owner/repo", valid.Project{Dir: ".", Workspace: "default"}, valid.RepoCfg{}).DeploymentEnvironment)
+
+	Ok(t, gCfg.ValidateRepoCfg(rCfg, "github.com/owner/overridable"))
+	Equals(t, "staging", gCfg.MergeProjectCfg(log, "github.com/owner/overridable", proj, rCfg).DeploymentEnvironment)
+	Equals(t, "production", gCfg.MergeProjectCfg(log, "github.com/owner/overridable", valid.Project{Dir: ".", Workspace: "default"}, rCfg).DeploymentEnvironment)
+}
+
+func TestGlobalCfg_ValidateRepoCfg_PlanPresets(t *testing.T) {
+	gCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+	gCfg.Repos = append(gCfg.Repos,
+		valid.Repo{ID: "github.com/owner/overridable", AllowedOverrides: []string{valid.PlanPresetsKey}},
+	)
+	rCfg := valid.RepoCfg{Projects: []valid.Project{
+		{Dir: ".", Workspace: "default", PlanPresets: map[string][]string{"emergency": {"-refresh=false"}}},
+	}}
+
+	ErrEquals(t, "repo config not allowed to set 'plan_presets' key: server-side config needs 'allowed_overrides: [plan_presets]'",
+		gCfg.ValidateRepoCfg(rCfg, "github.com/owner/repo"))
+	Ok(t, gCfg.ValidateRepoCfg(rCfg, "github.com/owner/overridable"))
+}
+
+func TestGlobalCfg_AutodiscoveredProjectName(t *testing.T) {
+	cases := map[string]struct {
+		template string
+		path     string
+		exp      string
+		expErr   string
+	}{
+		"no template": {
+			template: "",
+			path:     "envs/prod",
+			exp:      "",
+		},
+		"path as is": {
+			template: "{{ .Path }}",
+			path:     "envs/prod",
+			exp:      "envs/prod",
+		},
+		"strip prefix and join with dashes": {
+			template: `{{ .Path | trimPrefix "envs/" | replace "/" "-" }}`,
+			path:     "envs/prod/us-east-1",
+			exp:      "prod-us-east-1",
+		},
+		"repo name": {
+			template: `{{ .RepoFullName | base }}-{{ .Path | base }}`,
+			path:     "envs/prod",
+			exp:      "repo-prod",
+		},
+		"empty name": {
+			template: `{{ .Path | trimPrefix "envs/prod" }}`,
+			path:     "envs/prod",
+			expErr:   "project_name_template rendered an empty name for dir \"envs/prod\"",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			gCfg := valid.GlobalCfg{
+				Repos: []valid.Repo{
+					{
+						IDRegex:             regexp.MustCompile(".*"),
+						ProjectNameTemplate: c.template,
+					},
+				},
+			}
+			act, err := gCfg.AutodiscoveredProjectName("github.com/owner/repo", "owner/repo", c.path)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.exp, act)
+		})
+	}
+}
+
 func TestGlobalCfg_PolicyCheckOverride(t *testing.T) {
 	var emptyPolicySets valid.PolicySets
 
@@ -19,6 +19,9 @@ type PolicySets struct {
 	Owners       PolicyOwners
 	ApproveCount int
 	PolicySets   []PolicySet
+	// ChangedResourcesOnly is true if policies are only evaluated against the
+	// resources that the plan changes.
+	ChangedResourcesOnly bool
 }
 
 type PolicyOwners struct {
@@ -32,6 +35,10 @@ type PolicySet struct {
 	Name         string
 	ApproveCount int
 	Owners       PolicyOwners
+	// DataDirs are passed to conftest with --data so the policies can use
+	// the data files in them. Relative dirs are relative to the policy set's
+	// path.
+	DataDirs []string
 }
 
 func (p *PolicySets) HasPolicies() bool {
@@ -7,6 +7,7 @@ import (
 	"log"
 	"regexp"
 	"strings"
+	"time"
 
 	version "github.com/hashicorp/go-version"
 )
@@ -135,8 +136,27 @@ type Project struct {
 	DeleteSourceBranchOnMerge *bool
 	RepoLocking               *bool
 	ExecutionOrderGroup       int
-	PolicyCheck               *bool
-	CustomPolicyCheck         *bool
+	// ApplyOrder overrides ExecutionOrderGroup when the project is applied.
+	// If nil, ExecutionOrderGroup is used for apply too.
+	ApplyOrder        *int
+	PolicyCheck       *bool
+	CustomPolicyCheck *bool
+	// StatusContextSuffix is appended to the context of the project's commit
+	// statuses, ex. to filter statuses by team.
+	StatusContextSuffix string
+	// DeploymentEnvironment overrides the server-side deployment_environment
+	// of the project's repo if it's an allowed override.
+	DeploymentEnvironment string
+	// PlanTimeout and ApplyTimeout limit how long each process run for a
+	// plan or apply may take. 0 uses the server's default.
+	PlanTimeout  time.Duration
+	ApplyTimeout time.Duration
+	// TerraformParallelism is the -parallelism of the project's plans and
+	// applies. 0 uses the server's default.
+	TerraformParallelism int
+	// PlanPresets are named sets of extra plan args, by name, that a plan
+	// comment can select with --preset.
+	PlanPresets map[string][]string
 }
 
 // GetName returns the name of the project or an empty string if there is no
@@ -162,8 +182,18 @@ const (
 	PostProcessRunOutputStripRefreshing = "strip_refreshing"
 )
 
+// StepCondition is an enum of conditions on the plan for a step to run
+type StepCondition string
+
+const (
+	StepConditionPlanHasChanges   = "plan_has_changes"
+	StepConditionPlanHasNoChanges = "plan_has_no_changes"
+)
+
 type Stage struct {
 	Steps []Step
+	// OnFailure are the steps run if one of Steps fails.
+	OnFailure []Step
 }
 
 type Step struct {
@@ -179,6 +209,9 @@ type Step struct {
 	EnvVarName string
 	// EnvVarValue is the value to set EnvVarName to.
 	EnvVarValue string
+	// When is the condition on the output of the last plan step of the stage
+	// for a RunCommand to run. If it's empty the step always runs.
+	When StepCondition
 }
 
 type Workflow struct {
@@ -188,4 +221,7 @@ type Workflow struct {
 	PolicyCheck Stage
 	Import      Stage
 	StateRm     Stage
+	// Terragrunt is true if the workflow's steps run terragrunt instead of
+	// terraform.
+	Terragrunt bool
 }
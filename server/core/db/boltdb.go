@@ -31,6 +31,10 @@ const (
 	pullKeySeparator      = "::"
 )
 
+// scheduledRunsBucketName stores the last time each scheduled job ran. It's
+// created when a job first runs.
+const scheduledRunsBucketName = "scheduledRuns"
+
 // New returns a valid locker. We need to be able to write to dataDir
 // since bolt stores its data as a file
 func New(dataDir string) (*BoltDB, error) {
@@ -174,11 +178,12 @@ func (b *BoltDB) List() ([]models.ProjectLock, error) {
 // LockCommand attempts to create a new lock for a CommandName.
 // If the lock doesn't exists, it will create a lock and return a pointer to it.
 // If the lock already exists, it will return an "lock already exists" error
-func (b *BoltDB) LockCommand(cmdName command.Name, lockTime time.Time) (*command.Lock, error) {
+func (b *BoltDB) LockCommand(cmdName command.Name, lockTime time.Time, message string) (*command.Lock, error) {
 	lock := command.Lock{
 		CommandName: cmdName,
 		LockMetadata: command.LockMetadata{
 			UnixTime: lockTime.Unix(),
+			Message:  message,
 		},
 	}
 
@@ -223,6 +228,36 @@ func (b *BoltDB) UnlockCommand(cmdName command.Name) error {
 	return nil
 }
 
+// TryLockScheduledRun claims the run of the scheduled job name at
+// scheduledTime. It returns true if the job hasn't run at or after
+// scheduledTime yet.
+func (b *BoltDB) TryLockScheduledRun(name string, scheduledTime time.Time) (bool, error) {
+	var lockAcquired bool
+	transactionErr := b.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(scheduledRunsBucketName))
+		if err != nil {
+			return err
+		}
+		scheduledUnix := scheduledTime.Unix()
+		if lastRun := bucket.Get([]byte(name)); lastRun != nil {
+			var lastRunUnix int64
+			if err := json.Unmarshal(lastRun, &lastRunUnix); err != nil {
+				return errors.Wrap(err, "failed to deserialize last scheduled run")
+			}
+			if lastRunUnix >= scheduledUnix {
+				return nil
+			}
+		}
+		scheduledUnixSerialized, _ := json.Marshal(scheduledUnix)
+		lockAcquired = true
+		return bucket.Put([]byte(name), scheduledUnixSerialized)
+	})
+	if transactionErr != nil {
+		return false, errors.Wrap(transactionErr, "DB transaction failed")
+	}
+	return lockAcquired, nil
+}
+
 // CheckCommandLock checks if CommandName lock was set.
 // If the lock exists return the pointer to the lock object, otherwise return nil
 func (b *BoltDB) CheckCommandLock(cmdName command.Name) (*command.Lock, error) {
@@ -357,6 +392,11 @@ func (b *BoltDB) UpdatePullWithResults(pull models.PullRequest, newResults []com
 						res.ProjectName == proj.ProjectName {
 
 						proj.Status = res.PlanStatus()
+						if res.Command == command.Plan {
+							proj.PlanDestroys = res.PlanDestroys()
+							proj.NoDestroy = res.NoDestroy
+							proj.PlannedAt = res.PlannedAt()
+						}
 
 						// Updating only policy sets which are included in results; keeping the rest.
 						if len(proj.PolicyStatus) > 0 {
@@ -407,6 +447,23 @@ func (b *BoltDB) GetPullStatus(pull models.PullRequest) (*models.PullStatus, err
 	return s, errors.Wrap(err, "DB transaction failed")
 }
 
+// GetPullStatuses returns the statuses of all pulls.
+func (b *BoltDB) GetPullStatuses() ([]models.PullStatus, error) {
+	var statuses []models.PullStatus
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.pullsBucketName)
+		return bucket.ForEach(func(k, _ []byte) error {
+			s, err := b.getPullFromBucket(bucket, k)
+			if err != nil {
+				return err
+			}
+			statuses = append(statuses, *s)
+			return nil
+		})
+	})
+	return statuses, errors.Wrap(err, "DB transaction failed")
+}
+
 // DeletePullStatus deletes the status for pull.
 func (b *BoltDB) DeletePullStatus(pull models.PullRequest) error {
 	key, err := b.pullKey(pull)
@@ -471,7 +528,7 @@ func (b *BoltDB) commandLockKey(cmdName command.Name) string {
 }
 
 func (b *BoltDB) lockKey(p models.Project, workspace string) string {
-	return fmt.Sprintf("%s/%s/%s", p.RepoFullName, p.Path, workspace)
+	return models.GenerateLockKey(p, workspace)
 }
 
 func (b *BoltDB) getPullFromBucket(bucket *bolt.Bucket, key []byte) (*models.PullStatus, error) {
@@ -502,5 +559,8 @@ func (b *BoltDB) projectResultToProject(p command.ProjectResult) models.ProjectS
 		ProjectName:  p.ProjectName,
 		PolicyStatus: p.PolicyStatus(),
 		Status:       p.PlanStatus(),
+		PlanDestroys: p.PlanDestroys(),
+		NoDestroy:    p.NoDestroy,
+		PlannedAt:    p.PlannedAt(),
 	}
 }
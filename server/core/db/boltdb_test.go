@@ -15,6 +15,7 @@ package db_test
 
 import (
 	"os"
+	"sort"
 	"testing"
 	"time"
 
@@ -58,7 +59,7 @@ func TestLockCommandEnabled(t *testing.T) {
 	db, b := newTestDB()
 	defer cleanupDB(db)
 	timeNow := time.Now()
-	_, err := b.LockCommand(command.Apply, timeNow)
+	_, err := b.LockCommand(command.Apply, timeNow, "")
 	Ok(t, err)
 
 	config, err := b.CheckCommandLock(command.Apply)
@@ -66,24 +67,59 @@ func TestLockCommandEnabled(t *testing.T) {
 	Equals(t, true, config.IsLocked())
 }
 
+func TestLockCommandMessage(t *testing.T) {
+	t.Log("setting the apply lock with a message")
+	db, b := newTestDB()
+	defer cleanupDB(db)
+	timeNow := time.Now()
+	_, err := b.LockCommand(command.Apply, timeNow, "incident in progress")
+	Ok(t, err)
+
+	config, err := b.CheckCommandLock(command.Apply)
+	Ok(t, err)
+	Equals(t, true, config.IsLocked())
+	Equals(t, "incident in progress", config.LockMetadata.Message)
+}
+
 func TestLockCommandFail(t *testing.T) {
 	t.Log("setting the apply lock")
 	db, b := newTestDB()
 	defer cleanupDB(db)
 	timeNow := time.Now()
-	_, err := b.LockCommand(command.Apply, timeNow)
+	_, err := b.LockCommand(command.Apply, timeNow, "")
 	Ok(t, err)
 
-	_, err = b.LockCommand(command.Apply, timeNow)
+	_, err = b.LockCommand(command.Apply, timeNow, "")
 	ErrEquals(t, "db transaction failed: lock already exists", err)
 }
 
+func TestTryLockScheduledRun(t *testing.T) {
+	t.Log("a scheduled run should only be claimed once")
+	db, b := newTestDB()
+	defer cleanupDB(db)
+	minute := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	claimed, err := b.TryLockScheduledRun("job", minute)
+	Ok(t, err)
+	Equals(t, true, claimed)
+	claimed, err = b.TryLockScheduledRun("job", minute)
+	Ok(t, err)
+	Equals(t, false, claimed)
+
+	claimed, err = b.TryLockScheduledRun("other-job", minute)
+	Ok(t, err)
+	Equals(t, true, claimed)
+	claimed, err = b.TryLockScheduledRun("job", minute.Add(time.Minute))
+	Ok(t, err)
+	Equals(t, true, claimed)
+}
+
 func TestUnlockCommandDisabled(t *testing.T) {
 	t.Log("unsetting the apply lock")
 	db, b := newTestDB()
 	defer cleanupDB(db)
 	timeNow := time.Now()
-	_, err := b.LockCommand(command.Apply, timeNow)
+	_, err := b.LockCommand(command.Apply, timeNow, "")
 	Ok(t, err)
 
 	config, err := b.CheckCommandLock(command.Apply)
@@ -110,7 +146,7 @@ func TestMixedLocksPresent(t *testing.T) {
 	db, b := newTestDB()
 	defer cleanupDB(db)
 	timeNow := time.Now()
-	_, err := b.LockCommand(command.Apply, timeNow)
+	_, err := b.LockCommand(command.Apply, timeNow, "")
 	Ok(t, err)
 
 	_, _, err = b.TryLock(lock)
@@ -523,6 +559,65 @@ func TestPullStatus_UpdateDeleteGet(t *testing.T) {
 	Assert(t, maybeStatus == nil, "exp nil")
 }
 
+func TestPullStatus_GetPullStatuses(t *testing.T) {
+	b := newTestDB2(t)
+
+	statuses, err := b.GetPullStatuses()
+	Ok(t, err)
+	Equals(t, 0, len(statuses))
+
+	pulls := []models.PullRequest{
+		{
+			Num:        1,
+			HeadCommit: "sha",
+			BaseRepo: models.Repo{
+				FullName: "runatlantis/atlantis",
+				VCSHost: models.VCSHost{
+					Hostname: "github.com",
+					Type:     models.Github,
+				},
+			},
+		},
+		{
+			Num:        2,
+			HeadCommit: "sha2",
+			BaseRepo: models.Repo{
+				FullName: "runatlantis/atlantis",
+				VCSHost: models.VCSHost{
+					Hostname: "github.com",
+					Type:     models.Github,
+				},
+			},
+		},
+	}
+	for _, pull := range pulls {
+		_, err := b.UpdatePullWithResults(
+			pull,
+			[]command.ProjectResult{
+				{
+					RepoRelDir: ".",
+					Workspace:  "default",
+					PlanSuccess: &models.PlanSuccess{
+						TerraformOutput: "tf-output",
+						LockURL:         "lock-url",
+					},
+				},
+			})
+		Ok(t, err)
+	}
+
+	statuses, err = b.GetPullStatuses()
+	Ok(t, err)
+	Equals(t, 2, len(statuses))
+	var nums []int
+	for _, s := range statuses {
+		nums = append(nums, s.Pull.Num)
+		Equals(t, 1, len(s.Projects))
+	}
+	sort.Ints(nums)
+	Equals(t, []int{1, 2}, nums)
+}
+
 // Test we can create a status, update a specific project's status within that
 // pull status, and when we getCommandLock all the project statuses, that specific project
 // should be updated.
@@ -653,6 +748,7 @@ func TestPullStatus_UpdateNewCommit(t *testing.T) {
 // the same commit, that we merge the statuses.
 func TestPullStatus_UpdateMerge_Apply(t *testing.T) {
 	b := newTestDB2(t)
+	plannedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
 
 	pull := models.PullRequest{
 		Num:        1,
@@ -699,6 +795,7 @@ func TestPullStatus_UpdateMerge_Apply(t *testing.T) {
 					LockURL:         "lock-url",
 					RePlanCmd:       "plan command",
 					ApplyCmd:        "apply command",
+					PlannedAt:       plannedAt,
 				},
 			},
 		})
@@ -751,6 +848,7 @@ func TestPullStatus_UpdateMerge_Apply(t *testing.T) {
 				RepoRelDir: "staythesame",
 				Workspace:  "default",
 				Status:     models.PlannedPlanStatus,
+				PlannedAt:  plannedAt,
 			},
 			{
 				RepoRelDir: "newresult",
@@ -20,8 +20,9 @@ type ApplyLockChecker interface {
 // ApplyLocker interface that manages locks for apply command runner
 type ApplyLocker interface {
 	// LockApply creates a lock for ApplyCommand if lock already exists it will
-	// return existing lock without any changes
-	LockApply() (ApplyCommandLock, error)
+	// return existing lock without any changes. message is why applies are
+	// locked, it's shown when an apply is rejected.
+	LockApply(message string) (ApplyCommandLock, error)
 	// UnlockApply deletes apply lock created by LockApply if present, otherwise
 	// it is a no-op
 	UnlockApply() error
@@ -36,6 +37,9 @@ type ApplyCommandLock struct {
 	Locked  bool
 	Time    time.Time
 	Failure string
+	// Message is why applies are locked. It's empty if no message was given
+	// or applies are disabled by DisableApply.
+	Message string
 }
 
 type ApplyClient struct {
@@ -53,14 +57,14 @@ func NewApplyClient(backend Backend, disableApplyFlag bool) ApplyLocker {
 // LockApply acquires global apply lock.
 // DisableApplyFlag takes presedence to any existing locks, if it is set to true
 // this function returns an error
-func (c *ApplyClient) LockApply() (ApplyCommandLock, error) {
+func (c *ApplyClient) LockApply(message string) (ApplyCommandLock, error) {
 	response := ApplyCommandLock{}
 
 	if c.disableApplyFlag {
 		return response, errors.New("DisableApplyFlag is set; Apply commands are locked globally until flag is unset")
 	}
 
-	applyCmdLock, err := c.backend.LockCommand(command.Apply, time.Now())
+	applyCmdLock, err := c.backend.LockCommand(command.Apply, time.Now(), message)
 	if err != nil {
 		return response, err
 	}
@@ -68,6 +72,7 @@ func (c *ApplyClient) LockApply() (ApplyCommandLock, error) {
 	if applyCmdLock != nil {
 		response.Locked = true
 		response.Time = applyCmdLock.LockTime()
+		response.Message = applyCmdLock.LockMetadata.Message
 	}
 	return response, nil
 }
@@ -107,6 +112,7 @@ func (c *ApplyClient) CheckApplyLock() (ApplyCommandLock, error) {
 	if applyCmdLock != nil {
 		response.Locked = true
 		response.Time = applyCmdLock.LockTime()
+		response.Message = applyCmdLock.LockMetadata.Message
 	}
 
 	return response, nil
@@ -18,7 +18,6 @@ package locking
 
 import (
 	"errors"
-	"fmt"
 	"regexp"
 	"time"
 
@@ -37,12 +36,18 @@ type Backend interface {
 	UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error)
 	UpdateProjectStatus(pull models.PullRequest, workspace string, repoRelDir string, newStatus models.ProjectPlanStatus) error
 	GetPullStatus(pull models.PullRequest) (*models.PullStatus, error)
+	GetPullStatuses() ([]models.PullStatus, error)
 	DeletePullStatus(pull models.PullRequest) error
 	UpdatePullWithResults(pull models.PullRequest, newResults []command.ProjectResult) (models.PullStatus, error)
 
-	LockCommand(cmdName command.Name, lockTime time.Time) (*command.Lock, error)
+	LockCommand(cmdName command.Name, lockTime time.Time, message string) (*command.Lock, error)
 	UnlockCommand(cmdName command.Name) error
 	CheckCommandLock(cmdName command.Name) (*command.Lock, error)
+
+	// TryLockScheduledRun claims the run of the scheduled job name at
+	// scheduledTime. Only the first caller for a job and time gets true so a
+	// job scheduled on every Atlantis server runs once.
+	TryLockScheduledRun(name string, scheduledTime time.Time) (bool, error)
 }
 
 // TryLockResponse results from an attempted lock.
@@ -146,7 +151,7 @@ func (c *Client) GetLock(key string) (*models.ProjectLock, error) {
 }
 
 func (c *Client) key(p models.Project, workspace string) string {
-	return fmt.Sprintf("%s/%s/%s", p.RepoFullName, p.Path, workspace)
+	return models.GenerateLockKey(p, workspace)
 }
 
 func (c *Client) lockKeyToProjectWorkspace(key string) (models.Project, string, error) {
@@ -199,5 +204,5 @@ func (c *NoOpLocker) GetLock(key string) (*models.ProjectLock, error) {
 }
 
 func (c *NoOpLocker) key(p models.Project, workspace string) string {
-	return fmt.Sprintf("%s/%s/%s", p.RepoFullName, p.Path, workspace)
+	return models.GenerateLockKey(p, workspace)
 }
@@ -195,9 +195,9 @@ func TestApplyLocker(t *testing.T) {
 		t.Run("backend errors", func(t *testing.T) {
 			backend := mocks.NewMockBackend()
 
-			When(backend.LockCommand(Any[command.Name](), Any[time.Time]())).ThenReturn(nil, errExpected)
+			When(backend.LockCommand(Any[command.Name](), Any[time.Time](), Any[string]())).ThenReturn(nil, errExpected)
 			l := locking.NewApplyClient(backend, false)
-			lock, err := l.LockApply()
+			lock, err := l.LockApply("")
 			Equals(t, errExpected, err)
 			Assert(t, !lock.Locked, "exp false")
 		})
@@ -206,20 +206,43 @@ func TestApplyLocker(t *testing.T) {
 			backend := mocks.NewMockBackend()
 
 			l := locking.NewApplyClient(backend, true)
-			_, err := l.LockApply()
+			_, err := l.LockApply("")
 			ErrEquals(t, "DisableApplyFlag is set; Apply commands are locked globally until flag is unset", err)
 
-			backend.VerifyWasCalled(Never()).LockCommand(Any[command.Name](), Any[time.Time]())
+			backend.VerifyWasCalled(Never()).LockCommand(Any[command.Name](), Any[time.Time](), Any[string]())
 		})
 
 		t.Run("succeeds", func(t *testing.T) {
 			backend := mocks.NewMockBackend()
 
-			When(backend.LockCommand(Any[command.Name](), Any[time.Time]())).ThenReturn(applyLock, nil)
+			When(backend.LockCommand(Any[command.Name](), Any[time.Time](), Any[string]())).ThenReturn(applyLock, nil)
 			l := locking.NewApplyClient(backend, false)
-			lock, _ := l.LockApply()
+			lock, _ := l.LockApply("")
 			Assert(t, lock.Locked, "exp lock present")
 		})
+
+		t.Run("with message", func(t *testing.T) {
+			backend := mocks.NewMockBackend()
+			messageLock := &command.Lock{
+				CommandName: command.Apply,
+				LockMetadata: command.LockMetadata{
+					UnixTime: time.Now().Unix(),
+					Message:  "incident in progress",
+				},
+			}
+
+			When(backend.LockCommand(Any[command.Name](), Any[time.Time](), Eq("incident in progress"))).ThenReturn(messageLock, nil)
+			l := locking.NewApplyClient(backend, false)
+			lock, err := l.LockApply("incident in progress")
+			Ok(t, err)
+			Assert(t, lock.Locked, "exp lock present")
+			Equals(t, "incident in progress", lock.Message)
+
+			When(backend.CheckCommandLock(Any[command.Name]())).ThenReturn(messageLock, nil)
+			lock, err = l.CheckApplyLock()
+			Ok(t, err)
+			Equals(t, "incident in progress", lock.Message)
+		})
 	})
 
 	t.Run("UnlockApply", func(t *testing.T) {
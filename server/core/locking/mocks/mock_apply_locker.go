@@ -44,11 +44,11 @@ func (mock *MockApplyLocker) CheckApplyLock() (locking.ApplyCommandLock, error)
 	return ret0, ret1
 }
 
-func (mock *MockApplyLocker) LockApply() (locking.ApplyCommandLock, error) {
+func (mock *MockApplyLocker) LockApply(message string) (locking.ApplyCommandLock, error) {
 	if mock == nil {
 		panic("mock must not be nil. Use myMock := NewMockApplyLocker().")
 	}
-	params := []pegomock.Param{}
+	params := []pegomock.Param{message}
 	result := pegomock.GetGenericMockFrom(mock).Invoke("LockApply", params, []reflect.Type{reflect.TypeOf((*locking.ApplyCommandLock)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
 	var ret0 locking.ApplyCommandLock
 	var ret1 error
@@ -132,8 +132,8 @@ func (c *MockApplyLocker_CheckApplyLock_OngoingVerification) GetCapturedArgument
 func (c *MockApplyLocker_CheckApplyLock_OngoingVerification) GetAllCapturedArguments() {
 }
 
-func (verifier *VerifierMockApplyLocker) LockApply() *MockApplyLocker_LockApply_OngoingVerification {
-	params := []pegomock.Param{}
+func (verifier *VerifierMockApplyLocker) LockApply(message string) *MockApplyLocker_LockApply_OngoingVerification {
+	params := []pegomock.Param{message}
 	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "LockApply", params, verifier.timeout)
 	return &MockApplyLocker_LockApply_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
 }
@@ -143,10 +143,20 @@ type MockApplyLocker_LockApply_OngoingVerification struct {
 	methodInvocations []pegomock.MethodInvocation
 }
 
-func (c *MockApplyLocker_LockApply_OngoingVerification) GetCapturedArguments() {
+func (c *MockApplyLocker_LockApply_OngoingVerification) GetCapturedArguments() string {
+	message := c.GetAllCapturedArguments()
+	return message[len(message)-1]
 }
 
-func (c *MockApplyLocker_LockApply_OngoingVerification) GetAllCapturedArguments() {
+func (c *MockApplyLocker_LockApply_OngoingVerification) GetAllCapturedArguments() (_param0 []string) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]string, len(c.methodInvocations))
+		for u, param := range params[0] {
+			_param0[u] = param.(string)
+		}
+	}
+	return
 }
 
 func (verifier *VerifierMockApplyLocker) UnlockApply() *MockApplyLocker_UnlockApply_OngoingVerification {
@@ -98,6 +98,25 @@ func (mock *MockBackend) GetPullStatus(pull models.PullRequest) (*models.PullSta
 	return ret0, ret1
 }
 
+func (mock *MockBackend) GetPullStatuses() ([]models.PullStatus, error) {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockBackend().")
+	}
+	params := []pegomock.Param{}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("GetPullStatuses", params, []reflect.Type{reflect.TypeOf((*[]models.PullStatus)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 []models.PullStatus
+	var ret1 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].([]models.PullStatus)
+		}
+		if result[1] != nil {
+			ret1 = result[1].(error)
+		}
+	}
+	return ret0, ret1
+}
+
 func (mock *MockBackend) List() ([]models.ProjectLock, error) {
 	if mock == nil {
 		panic("mock must not be nil. Use myMock := NewMockBackend().")
@@ -117,11 +136,11 @@ func (mock *MockBackend) List() ([]models.ProjectLock, error) {
 	return ret0, ret1
 }
 
-func (mock *MockBackend) LockCommand(cmdName command.Name, lockTime time.Time) (*command.Lock, error) {
+func (mock *MockBackend) LockCommand(cmdName command.Name, lockTime time.Time, message string) (*command.Lock, error) {
 	if mock == nil {
 		panic("mock must not be nil. Use myMock := NewMockBackend().")
 	}
-	params := []pegomock.Param{cmdName, lockTime}
+	params := []pegomock.Param{cmdName, lockTime, message}
 	result := pegomock.GetGenericMockFrom(mock).Invoke("LockCommand", params, []reflect.Type{reflect.TypeOf((**command.Lock)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
 	var ret0 *command.Lock
 	var ret1 error
@@ -159,6 +178,25 @@ func (mock *MockBackend) TryLock(lock models.ProjectLock) (bool, models.ProjectL
 	return ret0, ret1, ret2
 }
 
+func (mock *MockBackend) TryLockScheduledRun(name string, scheduledTime time.Time) (bool, error) {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockBackend().")
+	}
+	params := []pegomock.Param{name, scheduledTime}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("TryLockScheduledRun", params, []reflect.Type{reflect.TypeOf((*bool)(nil)).Elem(), reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 bool
+	var ret1 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(bool)
+		}
+		if result[1] != nil {
+			ret1 = result[1].(error)
+		}
+	}
+	return ret0, ret1
+}
+
 func (mock *MockBackend) Unlock(project models.Project, workspace string) (*models.ProjectLock, error) {
 	if mock == nil {
 		panic("mock must not be nil. Use myMock := NewMockBackend().")
@@ -395,6 +433,23 @@ func (c *MockBackend_GetPullStatus_OngoingVerification) GetAllCapturedArguments(
 	return
 }
 
+func (verifier *VerifierMockBackend) GetPullStatuses() *MockBackend_GetPullStatuses_OngoingVerification {
+	params := []pegomock.Param{}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "GetPullStatuses", params, verifier.timeout)
+	return &MockBackend_GetPullStatuses_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type MockBackend_GetPullStatuses_OngoingVerification struct {
+	mock              *MockBackend
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *MockBackend_GetPullStatuses_OngoingVerification) GetCapturedArguments() {
+}
+
+func (c *MockBackend_GetPullStatuses_OngoingVerification) GetAllCapturedArguments() {
+}
+
 func (verifier *VerifierMockBackend) List() *MockBackend_List_OngoingVerification {
 	params := []pegomock.Param{}
 	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "List", params, verifier.timeout)
@@ -412,8 +467,8 @@ func (c *MockBackend_List_OngoingVerification) GetCapturedArguments() {
 func (c *MockBackend_List_OngoingVerification) GetAllCapturedArguments() {
 }
 
-func (verifier *VerifierMockBackend) LockCommand(cmdName command.Name, lockTime time.Time) *MockBackend_LockCommand_OngoingVerification {
-	params := []pegomock.Param{cmdName, lockTime}
+func (verifier *VerifierMockBackend) LockCommand(cmdName command.Name, lockTime time.Time, message string) *MockBackend_LockCommand_OngoingVerification {
+	params := []pegomock.Param{cmdName, lockTime, message}
 	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "LockCommand", params, verifier.timeout)
 	return &MockBackend_LockCommand_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
 }
@@ -423,12 +478,12 @@ type MockBackend_LockCommand_OngoingVerification struct {
 	methodInvocations []pegomock.MethodInvocation
 }
 
-func (c *MockBackend_LockCommand_OngoingVerification) GetCapturedArguments() (command.Name, time.Time) {
-	cmdName, lockTime := c.GetAllCapturedArguments()
-	return cmdName[len(cmdName)-1], lockTime[len(lockTime)-1]
+func (c *MockBackend_LockCommand_OngoingVerification) GetCapturedArguments() (command.Name, time.Time, string) {
+	cmdName, lockTime, message := c.GetAllCapturedArguments()
+	return cmdName[len(cmdName)-1], lockTime[len(lockTime)-1], message[len(message)-1]
 }
 
-func (c *MockBackend_LockCommand_OngoingVerification) GetAllCapturedArguments() (_param0 []command.Name, _param1 []time.Time) {
+func (c *MockBackend_LockCommand_OngoingVerification) GetAllCapturedArguments() (_param0 []command.Name, _param1 []time.Time, _param2 []string) {
 	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
 	if len(params) > 0 {
 		_param0 = make([]command.Name, len(c.methodInvocations))
@@ -439,6 +494,10 @@ func (c *MockBackend_LockCommand_OngoingVerification) GetAllCapturedArguments()
 		for u, param := range params[1] {
 			_param1[u] = param.(time.Time)
 		}
+		_param2 = make([]string, len(c.methodInvocations))
+		for u, param := range params[2] {
+			_param2[u] = param.(string)
+		}
 	}
 	return
 }
@@ -470,6 +529,37 @@ func (c *MockBackend_TryLock_OngoingVerification) GetAllCapturedArguments() (_pa
 	return
 }
 
+func (verifier *VerifierMockBackend) TryLockScheduledRun(name string, scheduledTime time.Time) *MockBackend_TryLockScheduledRun_OngoingVerification {
+	params := []pegomock.Param{name, scheduledTime}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "TryLockScheduledRun", params, verifier.timeout)
+	return &MockBackend_TryLockScheduledRun_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type MockBackend_TryLockScheduledRun_OngoingVerification struct {
+	mock              *MockBackend
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *MockBackend_TryLockScheduledRun_OngoingVerification) GetCapturedArguments() (string, time.Time) {
+	name, scheduledTime := c.GetAllCapturedArguments()
+	return name[len(name)-1], scheduledTime[len(scheduledTime)-1]
+}
+
+func (c *MockBackend_TryLockScheduledRun_OngoingVerification) GetAllCapturedArguments() (_param0 []string, _param1 []time.Time) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]string, len(c.methodInvocations))
+		for u, param := range params[0] {
+			_param0[u] = param.(string)
+		}
+		_param1 = make([]time.Time, len(c.methodInvocations))
+		for u, param := range params[1] {
+			_param1[u] = param.(time.Time)
+		}
+	}
+	return
+}
+
 func (verifier *VerifierMockBackend) Unlock(project models.Project, workspace string) *MockBackend_Unlock_OngoingVerification {
 	params := []pegomock.Param{project, workspace}
 	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "Unlock", params, verifier.timeout)
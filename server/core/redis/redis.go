@@ -24,6 +24,9 @@ type RedisDB struct { // nolint: revive
 
 const (
 	pullKeySeparator = "::"
+	// scheduledRunLockTTL is how long the key claiming a scheduled run is
+	// kept. It only needs to outlive the other servers' attempts at the run.
+	scheduledRunLockTTL = time.Hour
 )
 
 func New(hostname string, port int, password string, tlsEnabled bool, insecureSkipVerify bool, db int) (*RedisDB, error) {
@@ -184,12 +187,13 @@ func (r *RedisDB) UnlockByPull(repoFullName string, pullNum int) ([]models.Proje
 	return locks, nil
 }
 
-func (r *RedisDB) LockCommand(cmdName command.Name, lockTime time.Time) (*command.Lock, error) {
+func (r *RedisDB) LockCommand(cmdName command.Name, lockTime time.Time, message string) (*command.Lock, error) {
 
 	lock := command.Lock{
 		CommandName: cmdName,
 		LockMetadata: command.LockMetadata{
 			UnixTime: lockTime.Unix(),
+			Message:  message,
 		},
 	}
 
@@ -237,6 +241,18 @@ func (r *RedisDB) CheckCommandLock(cmdName command.Name) (*command.Lock, error)
 	}
 }
 
+// TryLockScheduledRun claims the run of the scheduled job name at
+// scheduledTime. The key is set only if it doesn't exist yet so only one
+// server gets true.
+func (r *RedisDB) TryLockScheduledRun(name string, scheduledTime time.Time) (bool, error) {
+	key := fmt.Sprintf("scheduled/%s/%d", name, scheduledTime.Unix())
+	acquired, err := r.client.SetNX(ctx, key, scheduledTime.Unix(), scheduledRunLockTTL).Result()
+	if err != nil {
+		return false, errors.Wrap(err, "db transaction failed")
+	}
+	return acquired, nil
+}
+
 // UpdatePullWithResults updates pull's status with the latest project results.
 // It returns the new PullStatus object.
 func (r *RedisDB) UpdateProjectStatus(pull models.PullRequest, workspace string, repoRelDir string, newStatus models.ProjectPlanStatus) error {
@@ -280,6 +296,25 @@ func (r *RedisDB) GetPullStatus(pull models.PullRequest) (*models.PullStatus, er
 	return pullStatus, errors.Wrap(err, "db transaction failed")
 }
 
+// GetPullStatuses returns the statuses of all pulls.
+func (r *RedisDB) GetPullStatuses() ([]models.PullStatus, error) {
+	var statuses []models.PullStatus
+	iter := r.client.Scan(ctx, 0, fmt.Sprintf("*%s*%s*", pullKeySeparator, pullKeySeparator), 0).Iterator()
+	for iter.Next(ctx) {
+		pullStatus, err := r.getPull(iter.Val())
+		if err != nil {
+			return nil, err
+		}
+		if pullStatus != nil {
+			statuses = append(statuses, *pullStatus)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, errors.Wrap(err, "db transaction failed")
+	}
+	return statuses, nil
+}
+
 func (r *RedisDB) DeletePullStatus(pull models.PullRequest) error {
 	key, err := r.pullKey(pull)
 	if err != nil {
@@ -330,6 +365,11 @@ func (r *RedisDB) UpdatePullWithResults(pull models.PullRequest, newResults []co
 					res.ProjectName == proj.ProjectName {
 
 					proj.Status = res.PlanStatus()
+					if res.Command == command.Plan {
+						proj.PlanDestroys = res.PlanDestroys()
+						proj.NoDestroy = res.NoDestroy
+						proj.PlannedAt = res.PlannedAt()
+					}
 
 					// Updating only policy sets which are included in results; keeping the rest.
 					if len(proj.PolicyStatus) > 0 {
@@ -418,5 +458,8 @@ func (r *RedisDB) projectResultToProject(p command.ProjectResult) models.Project
 		ProjectName:  p.ProjectName,
 		PolicyStatus: p.PolicyStatus(),
 		Status:       p.PlanStatus(),
+		PlanDestroys: p.PlanDestroys(),
+		NoDestroy:    p.NoDestroy,
+		PlannedAt:    p.PlannedAt(),
 	}
 }
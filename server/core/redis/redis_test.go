@@ -11,6 +11,7 @@ import (
 	"math/big"
 	"net"
 	"os"
+	"sort"
 	"testing"
 	"time"
 
@@ -93,7 +94,7 @@ func TestLockCommandEnabled(t *testing.T) {
 	s := miniredis.RunT(t)
 	r := newTestRedis(s)
 	timeNow := time.Now()
-	_, err := r.LockCommand(command.Apply, timeNow)
+	_, err := r.LockCommand(command.Apply, timeNow, "")
 	Ok(t, err)
 
 	config, err := r.CheckCommandLock(command.Apply)
@@ -101,24 +102,59 @@ func TestLockCommandEnabled(t *testing.T) {
 	Equals(t, true, config.IsLocked())
 }
 
+func TestLockCommandMessage(t *testing.T) {
+	t.Log("setting the apply lock with a message")
+	s := miniredis.RunT(t)
+	r := newTestRedis(s)
+	timeNow := time.Now()
+	_, err := r.LockCommand(command.Apply, timeNow, "incident in progress")
+	Ok(t, err)
+
+	config, err := r.CheckCommandLock(command.Apply)
+	Ok(t, err)
+	Equals(t, true, config.IsLocked())
+	Equals(t, "incident in progress", config.LockMetadata.Message)
+}
+
 func TestLockCommandFail(t *testing.T) {
 	t.Log("setting the apply lock")
 	s := miniredis.RunT(t)
 	r := newTestRedis(s)
 	timeNow := time.Now()
-	_, err := r.LockCommand(command.Apply, timeNow)
+	_, err := r.LockCommand(command.Apply, timeNow, "")
 	Ok(t, err)
 
-	_, err = r.LockCommand(command.Apply, timeNow)
+	_, err = r.LockCommand(command.Apply, timeNow, "")
 	ErrEquals(t, "db transaction failed: lock already exists", err)
 }
 
+func TestTryLockScheduledRun(t *testing.T) {
+	t.Log("a scheduled run should only be claimed once")
+	s := miniredis.RunT(t)
+	r := newTestRedis(s)
+	minute := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	claimed, err := r.TryLockScheduledRun("job", minute)
+	Ok(t, err)
+	Equals(t, true, claimed)
+	claimed, err = r.TryLockScheduledRun("job", minute)
+	Ok(t, err)
+	Equals(t, false, claimed)
+
+	claimed, err = r.TryLockScheduledRun("other-job", minute)
+	Ok(t, err)
+	Equals(t, true, claimed)
+	claimed, err = r.TryLockScheduledRun("job", minute.Add(time.Minute))
+	Ok(t, err)
+	Equals(t, true, claimed)
+}
+
 func TestUnlockCommandDisabled(t *testing.T) {
 	t.Log("unsetting the apply lock")
 	s := miniredis.RunT(t)
 	r := newTestRedis(s)
 	timeNow := time.Now()
-	_, err := r.LockCommand(command.Apply, timeNow)
+	_, err := r.LockCommand(command.Apply, timeNow, "")
 	Ok(t, err)
 
 	config, err := r.CheckCommandLock(command.Apply)
@@ -145,7 +181,7 @@ func TestMixedLocksPresent(t *testing.T) {
 	s := miniredis.RunT(t)
 	r := newTestRedis(s)
 	timeNow := time.Now()
-	_, err := r.LockCommand(command.Apply, timeNow)
+	_, err := r.LockCommand(command.Apply, timeNow, "")
 	Ok(t, err)
 
 	_, _, err = r.TryLock(lock)
@@ -561,6 +597,70 @@ func TestPullStatus_UpdateDeleteGet(t *testing.T) {
 	Assert(t, maybeStatus == nil, "exp nil")
 }
 
+func TestPullStatus_GetPullStatuses(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := newTestRedis(s)
+
+	// Locks share the keyspace with pull statuses.
+	_, _, err := rdb.TryLock(lock)
+	Ok(t, err)
+
+	statuses, err := rdb.GetPullStatuses()
+	Ok(t, err)
+	Equals(t, 0, len(statuses))
+
+	pulls := []models.PullRequest{
+		{
+			Num:        1,
+			HeadCommit: "sha",
+			BaseRepo: models.Repo{
+				FullName: "runatlantis/atlantis",
+				VCSHost: models.VCSHost{
+					Hostname: "github.com",
+					Type:     models.Github,
+				},
+			},
+		},
+		{
+			Num:        2,
+			HeadCommit: "sha2",
+			BaseRepo: models.Repo{
+				FullName: "runatlantis/atlantis",
+				VCSHost: models.VCSHost{
+					Hostname: "github.com",
+					Type:     models.Github,
+				},
+			},
+		},
+	}
+	for _, pull := range pulls {
+		_, err := rdb.UpdatePullWithResults(
+			pull,
+			[]command.ProjectResult{
+				{
+					RepoRelDir: ".",
+					Workspace:  "default",
+					PlanSuccess: &models.PlanSuccess{
+						TerraformOutput: "tf-output",
+						LockURL:         "lock-url",
+					},
+				},
+			})
+		Ok(t, err)
+	}
+
+	statuses, err = rdb.GetPullStatuses()
+	Ok(t, err)
+	Equals(t, 2, len(statuses))
+	var nums []int
+	for _, s := range statuses {
+		nums = append(nums, s.Pull.Num)
+		Equals(t, 1, len(s.Projects))
+	}
+	sort.Ints(nums)
+	Equals(t, []int{1, 2}, nums)
+}
+
 // Test we can create a status, update a specific project's status within that
 // pull status, and when we getCommandLock all the project statuses, that specific project
 // should be updated.
@@ -74,6 +74,103 @@ func TestRun_Success(t *testing.T) {
 	Assert(t, os.IsNotExist(err), "planfile should be deleted")
 }
 
+func TestRun_ApplyParallelism(t *testing.T) {
+	cases := []struct {
+		description string
+		extraArgs   []string
+		expArgs     []string
+	}{
+		{
+			description: "project parallelism",
+			expArgs:     []string{"apply", "-input=false", "-parallelism=20"},
+		},
+		{
+			description: "overridden by extra args",
+			extraArgs:   []string{"-parallelism=5"},
+			expArgs:     []string{"apply", "-input=false", "-parallelism=5"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			planPath := filepath.Join(tmpDir, "default.tfplan")
+			Ok(t, os.WriteFile(planPath, nil, 0600))
+			ctx := command.ProjectContext{
+				Log:                  logging.NewNoopLogger(t),
+				Workspace:            "default",
+				RepoRelDir:           ".",
+				TerraformParallelism: 20,
+			}
+
+			RegisterMockTestingT(t)
+			terraform := mocks.NewMockClient()
+			o := runtime.ApplyStepRunner{
+				TerraformExecutor: terraform,
+			}
+			When(terraform.RunCommandWithVersion(Any[command.ProjectContext](), Any[string](), Any[[]string](), Any[map[string]string](), Any[*version.Version](), Any[string]())).
+				ThenReturn("output", nil)
+			_, err := o.Run(ctx, c.extraArgs, tmpDir, map[string]string(nil))
+			Ok(t, err)
+			terraform.VerifyWasCalledOnce().RunCommandWithVersion(ctx, tmpDir, append(c.expArgs, fmt.Sprintf("%q", planPath)), map[string]string(nil), nil, "default")
+		})
+	}
+}
+
+func TestRun_ApplyStateLock(t *testing.T) {
+	cases := []struct {
+		description   string
+		lockTimeout   string
+		skipStateLock bool
+		expArgs       []string
+	}{
+		{
+			description: "no lock options",
+			expArgs:     []string{"apply", "-input=false"},
+		},
+		{
+			description: "lock timeout",
+			lockTimeout: "0s",
+			expArgs:     []string{"apply", "-input=false", "-lock-timeout=0s"},
+		},
+		{
+			description:   "skip state lock",
+			skipStateLock: true,
+			expArgs:       []string{"apply", "-input=false", "-lock=false"},
+		},
+		{
+			description:   "both",
+			lockTimeout:   "5m",
+			skipStateLock: true,
+			expArgs:       []string{"apply", "-input=false", "-lock=false", "-lock-timeout=5m"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			planPath := filepath.Join(tmpDir, "default.tfplan")
+			Ok(t, os.WriteFile(planPath, nil, 0600))
+			ctx := command.ProjectContext{
+				Log:              logging.NewNoopLogger(t),
+				Workspace:        "default",
+				RepoRelDir:       ".",
+				StateLockTimeout: c.lockTimeout,
+				SkipStateLock:    c.skipStateLock,
+			}
+
+			RegisterMockTestingT(t)
+			terraform := mocks.NewMockClient()
+			o := runtime.ApplyStepRunner{
+				TerraformExecutor: terraform,
+			}
+			When(terraform.RunCommandWithVersion(Any[command.ProjectContext](), Any[string](), Any[[]string](), Any[map[string]string](), Any[*version.Version](), Any[string]())).
+				ThenReturn("output", nil)
+			_, err := o.Run(ctx, nil, tmpDir, map[string]string(nil))
+			Ok(t, err)
+			terraform.VerifyWasCalledOnce().RunCommandWithVersion(ctx, tmpDir, append(c.expArgs, fmt.Sprintf("%q", planPath)), map[string]string(nil), nil, "default")
+		})
+	}
+}
+
 func TestRun_AppliesCorrectProjectPlan(t *testing.T) {
 	// When running for a project, the planfile has a different name.
 	tmpDir := t.TempDir()
@@ -0,0 +1,246 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+var terraformSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{
+			Type: "terraform",
+		},
+	},
+}
+
+var backendSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{
+			Type:       "backend",
+			LabelNames: []string{"type"},
+		},
+	},
+}
+
+// BackendType returns the type of the backend configured in the Terraform
+// files in dir, ex. "s3", or an empty string if no backend is configured.
+// Files that can't be parsed are skipped since terraform will report the
+// errors itself.
+func BackendType(dir string) (string, error) {
+	blocks, err := terraformBlocks(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, block := range blocks {
+		backends, _, _ := block.Body.PartialContent(backendSchema)
+		for _, backend := range backends.Blocks {
+			return backend.Labels[0], nil
+		}
+	}
+	return "", nil
+}
+
+// RemoteBackend is the Terraform Cloud or Enterprise configuration of a
+// project, from its cloud block or remote backend.
+type RemoteBackend struct {
+	// Hostname is the hostname of Terraform Cloud or Enterprise. It's empty
+	// if the configuration doesn't set it, ex. to use app.terraform.io.
+	Hostname     string
+	Organization string
+	// Workspace is the name of the Terraform Cloud workspace if the
+	// configuration sets one.
+	Workspace string
+	// WorkspacePrefix prefixes the names of Terraform workspaces to get
+	// the names of remote backend workspaces.
+	WorkspacePrefix string
+}
+
+var remoteBackendSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{
+			Type: "cloud",
+		},
+		{
+			Type:       "backend",
+			LabelNames: []string{"type"},
+		},
+	},
+}
+
+var remoteBackendBodySchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "hostname"},
+		{Name: "organization"},
+	},
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "workspaces"},
+	},
+}
+
+var remoteBackendWorkspacesSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "name"},
+		{Name: "prefix"},
+	},
+}
+
+// FindRemoteBackend returns the cloud block or remote backend configured in
+// the Terraform files in dir, or nil if the project uses neither. Only
+// literal strings are read from the configuration so values set with
+// environment variables or -backend-config are missing.
+func FindRemoteBackend(dir string) (*RemoteBackend, error) {
+	blocks, err := terraformBlocks(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, block := range blocks {
+		content, _, _ := block.Body.PartialContent(remoteBackendSchema)
+		for _, backend := range content.Blocks {
+			if backend.Type == "backend" && backend.Labels[0] != "remote" {
+				continue
+			}
+			body, _, _ := backend.Body.PartialContent(remoteBackendBodySchema)
+			remoteBackend := &RemoteBackend{
+				Hostname:     stringAttribute(body.Attributes["hostname"]),
+				Organization: stringAttribute(body.Attributes["organization"]),
+			}
+			for _, workspaces := range body.Blocks {
+				workspacesBody, _, _ := workspaces.Body.PartialContent(remoteBackendWorkspacesSchema)
+				remoteBackend.Workspace = stringAttribute(workspacesBody.Attributes["name"])
+				remoteBackend.WorkspacePrefix = stringAttribute(workspacesBody.Attributes["prefix"])
+			}
+			return remoteBackend, nil
+		}
+	}
+	return nil, nil
+}
+
+// stringAttribute returns the value of attr if it's a literal string.
+func stringAttribute(attr *hcl.Attribute) string {
+	if attr == nil {
+		return ""
+	}
+	var value string
+	if diags := gohcl.DecodeExpression(attr.Expr, nil, &value); diags.HasErrors() {
+		return ""
+	}
+	return value
+}
+
+var moduleSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{
+			Type:       "module",
+			LabelNames: []string{"name"},
+		},
+	},
+}
+
+var moduleBodySchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "source"},
+	},
+}
+
+// LocalModuleDirs returns the dirs of the local modules called by the module
+// blocks of the Terraform files in dir, the ones whose source is a relative
+// path. Sources that aren't literal strings are skipped.
+func LocalModuleDirs(dir string) ([]string, error) {
+	blocks, err := topLevelBlocks(dir, moduleSchema)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, block := range blocks {
+		body, _, _ := block.Body.PartialContent(moduleBodySchema)
+		source := stringAttribute(body.Attributes["source"])
+		if strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") {
+			dirs = append(dirs, filepath.Join(dir, source))
+		}
+	}
+	return dirs, nil
+}
+
+// terraformBlocks returns the terraform blocks of the Terraform files in dir.
+func terraformBlocks(dir string) ([]*hcl.Block, error) {
+	return topLevelBlocks(dir, terraformSchema)
+}
+
+// topLevelBlocks returns the blocks in schema of the Terraform files in dir.
+func topLevelBlocks(dir string, schema *hcl.BodySchema) ([]*hcl.Block, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	parser := hclparse.NewParser()
+	var blocks []*hcl.Block
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		var file *hcl.File
+		switch {
+		case strings.HasSuffix(name, ".tf"):
+			file, _ = parser.ParseHCLFile(filepath.Join(dir, name))
+		case strings.HasSuffix(name, ".tf.json"):
+			file, _ = parser.ParseJSONFile(filepath.Join(dir, name))
+		}
+		if file == nil {
+			continue
+		}
+		content, _, _ := file.Body.PartialContent(schema)
+		blocks = append(blocks, content.Blocks...)
+	}
+	return blocks, nil
+}
+
+// InitConfig returns the parts of the Terraform files in dir that terraform
+// init depends on: the terraform blocks, which configure the backend and
+// required providers, and the module blocks. Files that can't be parsed and
+// JSON files are returned whole.
+func InitConfig(dir string) ([]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var config []byte
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json")) {
+			continue
+		}
+		src, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		var file *hcl.File
+		var diags hcl.Diagnostics
+		if !strings.HasSuffix(name, ".tf.json") {
+			file, diags = hclsyntax.ParseConfig(src, name, hcl.InitialPos)
+		}
+		if file == nil || diags.HasErrors() {
+			config = append(config, name...)
+			config = append(config, '\n')
+			config = append(config, src...)
+			continue
+		}
+		for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+			if block.Type != "terraform" && block.Type != "module" {
+				continue
+			}
+			rng := block.Range()
+			config = append(config, name...)
+			config = append(config, '\n')
+			config = append(config, src[rng.Start.Byte:rng.End.Byte]...)
+			config = append(config, '\n')
+		}
+	}
+	return config, nil
+}
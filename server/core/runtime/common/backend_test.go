@@ -0,0 +1,145 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestBackendType(t *testing.T) {
+	cases := []struct {
+		description string
+		files       map[string]string
+		exp         string
+	}{
+		{
+			description: "hcl backend",
+			files: map[string]string{
+				"main.tf":    `resource "null_resource" "a" {}`,
+				"backend.tf": "terraform {\n  backend \"s3\" { bucket = \"b\" }\n}",
+			},
+			exp: "s3",
+		},
+		{
+			description: "json backend",
+			files: map[string]string{
+				"backend.tf.json": `{"terraform": {"backend": {"gcs": {"bucket": "b"}}}}`,
+			},
+			exp: "gcs",
+		},
+		{
+			description: "no backend",
+			files: map[string]string{
+				"main.tf": `terraform { required_version = ">= 1.0" }`,
+			},
+		},
+		{
+			description: "invalid files are skipped",
+			files: map[string]string{
+				"a.tf":       `terraform {`,
+				"backend.tf": "terraform {\n  backend \"azurerm\" {}\n}",
+				"notes.txt":  "terraform {\n  backend \"local\" {}\n}",
+			},
+			exp: "azurerm",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range c.files {
+				Ok(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+			}
+			backendType, err := BackendType(dir)
+			Ok(t, err)
+			Equals(t, c.exp, backendType)
+		})
+	}
+}
+
+func TestFindRemoteBackend(t *testing.T) {
+	cases := []struct {
+		description string
+		files       map[string]string
+		exp         *RemoteBackend
+	}{
+		{
+			description: "cloud block",
+			files: map[string]string{
+				"main.tf": `terraform {
+  cloud {
+    organization = "acme"
+    workspaces {
+      name = "networking"
+    }
+  }
+}`,
+			},
+			exp: &RemoteBackend{Organization: "acme", Workspace: "networking"},
+		},
+		{
+			description: "cloud block with tags",
+			files: map[string]string{
+				"main.tf": `terraform {
+  cloud {
+    hostname     = "tfe.example.com"
+    organization = "acme"
+    workspaces {
+      tags = ["networking"]
+    }
+  }
+}`,
+			},
+			exp: &RemoteBackend{Hostname: "tfe.example.com", Organization: "acme"},
+		},
+		{
+			description: "remote backend with prefix",
+			files: map[string]string{
+				"backend.tf.json": `{"terraform": {"backend": {"remote": {"organization": "acme", "workspaces": {"prefix": "app-"}}}}}`,
+			},
+			exp: &RemoteBackend{Organization: "acme", WorkspacePrefix: "app-"},
+		},
+		{
+			description: "other backend",
+			files: map[string]string{
+				"backend.tf": "terraform {\n  backend \"s3\" { bucket = \"b\" }\n}",
+			},
+		},
+		{
+			description: "organization from the environment",
+			files: map[string]string{
+				"main.tf": "terraform {\n  cloud {}\n}",
+			},
+			exp: &RemoteBackend{},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range c.files {
+				Ok(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+			}
+			backend, err := FindRemoteBackend(dir)
+			Ok(t, err)
+			Equals(t, c.exp, backend)
+		})
+	}
+}
+
+func TestLocalModuleDirs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.tf": "module \"vpc\" {\n  source = \"./vpc\"\n}\n\n" +
+			"module \"shared\" {\n  source = \"../shared\"\n}\n\n" +
+			"module \"registry\" {\n  source = \"hashicorp/subnets/cidr\"\n}\n\n" +
+			"module \"git\" {\n  source = \"git::https://example.com/network.git\"\n}\n",
+		"dns.tf.json": `{"module": {"dns": {"source": "./dns"}}}`,
+	}
+	for name, content := range files {
+		Ok(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	dirs, err := LocalModuleDirs(dir)
+	Ok(t, err)
+	Equals(t, []string{filepath.Join(dir, "dns"), filepath.Join(dir, "vpc"), filepath.Join(filepath.Dir(dir), "shared")}, dirs)
+}
@@ -0,0 +1,64 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/events/command"
+)
+
+// fmtCheckUnformattedExitCode is the exit code of terraform fmt -check if
+// files aren't formatted.
+const fmtCheckUnformattedExitCode = 3
+
+// UnformattedFilesError is returned by FmtCheckStepRunner if files aren't
+// formatted.
+type UnformattedFilesError struct {
+	// Files are the unformatted files, relative to the checked directory.
+	Files []string
+}
+
+func (e UnformattedFilesError) Error() string {
+	return fmt.Sprintf("%d file(s) aren't formatted: %s", len(e.Files), strings.Join(e.Files, ", "))
+}
+
+// FmtCheckStepRunner runs terraform fmt -check to check that the Terraform
+// files of a project are formatted.
+type FmtCheckStepRunner struct {
+	TerraformExecutor TerraformExec
+	DefaultTFVersion  *version.Version
+}
+
+// Run runs terraform fmt -check in path. It returns an UnformattedFilesError
+// if any files aren't formatted.
+func (f *FmtCheckStepRunner) Run(ctx command.ProjectContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	tfVersion := f.DefaultTFVersion
+	if ctx.TerraformVersion != nil {
+		tfVersion = ctx.TerraformVersion
+	}
+
+	// Comment args are meant for plan so they're not passed to fmt.
+	fmtCmd := append([]string{"fmt", "-check", "-list=true", "-no-color"}, extraArgs...)
+	out, err := f.TerraformExecutor.RunCommandWithVersion(ctx, filepath.Clean(path), fmtCmd, envs, tfVersion, ctx.Workspace)
+	var exitErr *exec.ExitError
+	if err != nil && errors.As(err, &exitErr) && exitErr.ExitCode() == fmtCheckUnformattedExitCode {
+		return out, UnformattedFilesError{Files: parseFmtCheckOutput(out)}
+	}
+	return out, err
+}
+
+// parseFmtCheckOutput returns the files listed by terraform fmt -check, one
+// per line.
+func parseFmtCheckOutput(out string) []string {
+	var files []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files
+}
@@ -0,0 +1,77 @@
+package runtime
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock/v4"
+	"github.com/runatlantis/atlantis/server/core/terraform/mocks"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestFmtCheckStepRunner_Run(t *testing.T) {
+	// terraform fmt -check exits with 3 if files aren't formatted.
+	unformattedErr := exec.Command("sh", "-c", "exit 3").Run()
+	otherErr := exec.Command("sh", "-c", "exit 2").Run()
+
+	cases := []struct {
+		description string
+		tfOut       string
+		tfErr       error
+		expErr      error
+	}{
+		{
+			description: "formatted",
+		},
+		{
+			description: "unformatted",
+			tfOut:       "main.tf\nvariables.tf\n",
+			tfErr:       unformattedErr,
+			expErr:      UnformattedFilesError{Files: []string{"main.tf", "variables.tf"}},
+		},
+		{
+			description: "syntax error",
+			tfOut:       "Error: Invalid character\n\n  on main.tf line 1",
+			tfErr:       otherErr,
+			expErr:      otherErr,
+		},
+		{
+			description: "terraform can't run",
+			tfErr:       errors.New("terraform not found"),
+			expErr:      errors.New("terraform not found"),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			terraform := mocks.NewMockClient()
+			tfVersion, _ := version.NewVersion("1.5.0")
+			tmpDir := t.TempDir()
+			ctx := command.ProjectContext{
+				Log:                logging.NewNoopLogger(t),
+				EscapedCommentArgs: []string{"-var", "a=b"},
+				Workspace:          "default",
+			}
+			s := &FmtCheckStepRunner{
+				TerraformExecutor: terraform,
+				DefaultTFVersion:  tfVersion,
+			}
+
+			When(terraform.RunCommandWithVersion(Any[command.ProjectContext](), Any[string](), Any[[]string](), Any[map[string]string](), Any[*version.Version](), Any[string]())).
+				ThenReturn(c.tfOut, c.tfErr)
+			out, err := s.Run(ctx, nil, tmpDir, map[string]string(nil))
+			Equals(t, c.tfOut, out)
+			Equals(t, c.expErr, err)
+			terraform.VerifyWasCalledOnce().RunCommandWithVersion(ctx, tmpDir, []string{"fmt", "-check", "-list=true", "-no-color"}, map[string]string(nil), tfVersion, "default")
+		})
+	}
+}
+
+func TestUnformattedFilesError(t *testing.T) {
+	ErrEquals(t, "2 file(s) aren't formatted: main.tf, variables.tf", UnformattedFilesError{Files: []string{"main.tf", "variables.tf"}})
+}
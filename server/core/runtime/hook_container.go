@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+)
+
+// hookContainerRuntimes are the container runtimes that can run workflow
+// hooks with an image, in order of preference.
+var hookContainerRuntimes = []string{"docker", "podman"}
+
+// containerHookCommand returns the command that runs shell with args inside
+// image instead of on the host. path is mounted at the same path in the
+// container and used as its working directory so the hook's env vars that
+// point into it stay valid. The env vars named envVarNames are passed through
+// from the host.
+func containerHookCommand(image string, path string, envVarNames []string, shell string, args []string) (*exec.Cmd, error) {
+	var containerRuntime string
+	for _, r := range hookContainerRuntimes {
+		if p, err := exec.LookPath(r); err == nil {
+			containerRuntime = p
+			break
+		}
+	}
+	if containerRuntime == "" {
+		return nil, fmt.Errorf("running hook in image %q: neither docker nor podman was found in $PATH", image)
+	}
+
+	runArgs := []string{"run", "--rm", "--volume", path + ":" + path, "--workdir", path}
+	// Files the hook writes to the repo must be owned by the Atlantis user so
+	// they can be cleaned up.
+	if filepath.Base(containerRuntime) == "podman" {
+		runArgs = append(runArgs, "--userns=keep-id")
+	} else {
+		runArgs = append(runArgs, "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()))
+	}
+	names := append([]string(nil), envVarNames...)
+	sort.Strings(names)
+	for _, name := range names {
+		runArgs = append(runArgs, "--env", name)
+	}
+	runArgs = append(runArgs, image, shell)
+	runArgs = append(runArgs, args...)
+	return exec.Command(containerRuntime, runArgs...), nil // #nosec
+}
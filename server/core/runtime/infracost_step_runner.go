@@ -0,0 +1,129 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/command"
+)
+
+const defaultInfracostExecutable = "infracost"
+
+// InfracostStepRunner runs infracost against the JSON plan written by the show
+// step and saves a summary of the cost difference so it can be added to the
+// plan comment.
+type InfracostStepRunner struct {
+	// Executable is the name or path of the infracost binary. Defaults to
+	// infracost which is looked up in $PATH.
+	Executable string
+}
+
+// infracostOutput is the part of the output of
+// `infracost diff --format json` that we use. Costs are decimal strings and
+// are null when they can't be estimated.
+type infracostOutput struct {
+	Currency             string  `json:"currency"`
+	TotalMonthlyCost     *string `json:"totalMonthlyCost"`
+	PastTotalMonthlyCost *string `json:"pastTotalMonthlyCost"`
+	DiffTotalMonthlyCost *string `json:"diffTotalMonthlyCost"`
+}
+
+func (r *InfracostStepRunner) Run(ctx command.ProjectContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	executable := r.Executable
+	if executable == "" {
+		executable = defaultInfracostExecutable
+	}
+	// The cost estimate is informational so we don't fail the plan if
+	// infracost isn't installed.
+	binPath, err := exec.LookPath(executable)
+	if err != nil {
+		ctx.Log.Warn("skipping cost estimate: %s", err)
+		return "", nil
+	}
+
+	showResultFile := filepath.Join(path, ctx.GetShowResultFileName())
+	if _, err := os.Stat(showResultFile); err != nil {
+		return "", errors.Wrap(err, "reading plan JSON, the show step must run before the infracost step")
+	}
+
+	args := append([]string{"diff", "--path", showResultFile, "--format", "json", "--no-color"}, extraArgs...)
+	cmd := exec.Command(binPath, args...) // #nosec
+	cmd.Dir = path
+	cmd.Env = os.Environ()
+	for key, val := range envs {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, val))
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	ctx.Log.Debug("running %s %v", binPath, args)
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running infracost: %w: %s", err, stderr.String())
+	}
+
+	summary, err := InfracostSummary(stdout.Bytes())
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(path, ctx.GetCostSummaryFileName()), []byte(summary), 0600); err != nil {
+		return "", errors.Wrap(err, "writing cost summary")
+	}
+	// The summary is added to the comment separately so there's nothing to
+	// add to the plan output.
+	return "", nil
+}
+
+// InfracostSummary parses the JSON output of `infracost diff` into a one line
+// summary of how the monthly cost changes.
+func InfracostSummary(output []byte) (string, error) {
+	var out infracostOutput
+	if err := json.Unmarshal(output, &out); err != nil {
+		return "", errors.Wrap(err, "parsing infracost output")
+	}
+	total, err := parseCost(out.TotalMonthlyCost)
+	if err != nil {
+		return "", err
+	}
+	past, err := parseCost(out.PastTotalMonthlyCost)
+	if err != nil {
+		return "", err
+	}
+	diff := total - past
+	if out.DiffTotalMonthlyCost != nil {
+		if diff, err = parseCost(out.DiffTotalMonthlyCost); err != nil {
+			return "", err
+		}
+	}
+
+	currency := out.Currency
+	if currency == "" {
+		currency = "USD"
+	}
+	switch {
+	case diff > 0:
+		return fmt.Sprintf("**Cost estimate:** monthly cost will increase by %.2f %s (%.2f %s → %.2f %s).", diff, currency, past, currency, total, currency), nil
+	case diff < 0:
+		return fmt.Sprintf("**Cost estimate:** monthly cost will decrease by %.2f %s (%.2f %s → %.2f %s).", -diff, currency, past, currency, total, currency), nil
+	default:
+		return fmt.Sprintf("**Cost estimate:** monthly cost will not change (%.2f %s).", total, currency), nil
+	}
+}
+
+// parseCost parses a cost from the infracost output. Costs that couldn't be
+// estimated are null and count as zero.
+func parseCost(cost *string) (float64, error) {
+	if cost == nil || *cost == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(*cost, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parsing infracost cost %q", *cost)
+	}
+	return f, nil
+}
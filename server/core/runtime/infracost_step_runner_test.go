@@ -0,0 +1,113 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestInfracostSummary(t *testing.T) {
+	cases := []struct {
+		description string
+		output      string
+		exp         string
+		expErr      string
+	}{
+		{
+			description: "increase",
+			output:      `{"currency":"USD","totalMonthlyCost":"123.45","pastTotalMonthlyCost":"100","diffTotalMonthlyCost":"23.45"}`,
+			exp:         "**Cost estimate:** monthly cost will increase by 23.45 USD (100.00 USD → 123.45 USD).",
+		},
+		{
+			description: "decrease",
+			output:      `{"currency":"EUR","totalMonthlyCost":"50.5","pastTotalMonthlyCost":"75","diffTotalMonthlyCost":"-24.5"}`,
+			exp:         "**Cost estimate:** monthly cost will decrease by 24.50 EUR (75.00 EUR → 50.50 EUR).",
+		},
+		{
+			description: "no change",
+			output:      `{"currency":"USD","totalMonthlyCost":"42","pastTotalMonthlyCost":"42","diffTotalMonthlyCost":"0"}`,
+			exp:         "**Cost estimate:** monthly cost will not change (42.00 USD).",
+		},
+		{
+			description: "new resources without past cost",
+			output:      `{"currency":"USD","totalMonthlyCost":"10","pastTotalMonthlyCost":null,"diffTotalMonthlyCost":null}`,
+			exp:         "**Cost estimate:** monthly cost will increase by 10.00 USD (0.00 USD → 10.00 USD).",
+		},
+		{
+			description: "missing currency",
+			output:      `{"totalMonthlyCost":"0","pastTotalMonthlyCost":"0"}`,
+			exp:         "**Cost estimate:** monthly cost will not change (0.00 USD).",
+		},
+		{
+			description: "invalid cost",
+			output:      `{"currency":"USD","totalMonthlyCost":"lots"}`,
+			expErr:      `parsing infracost cost "lots": strconv.ParseFloat: parsing "lots": invalid syntax`,
+		},
+		{
+			description: "invalid json",
+			output:      `Error: no such flag`,
+			expErr:      "parsing infracost output: invalid character 'E' looking for beginning of value",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			summary, err := InfracostSummary([]byte(c.output))
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.exp, summary)
+		})
+	}
+}
+
+func TestInfracostStepRunner_Run(t *testing.T) {
+	ctx := command.ProjectContext{
+		Workspace:   "default",
+		ProjectName: "test",
+		Log:         logging.NewNoopLogger(t),
+	}
+
+	t.Run("missing binary is skipped", func(t *testing.T) {
+		path := t.TempDir()
+		subject := InfracostStepRunner{Executable: "infracost-does-not-exist"}
+		out, err := subject.Run(ctx, nil, path, map[string]string{})
+		Ok(t, err)
+		Equals(t, "", out)
+		_, err = os.Stat(filepath.Join(path, ctx.GetCostSummaryFileName()))
+		Assert(t, os.IsNotExist(err), "expected no cost summary")
+	})
+
+	t.Run("missing plan json", func(t *testing.T) {
+		path := t.TempDir()
+		subject := InfracostStepRunner{Executable: fakeInfracost(t, `{}`)}
+		_, err := subject.Run(ctx, nil, path, map[string]string{})
+		Assert(t, err != nil, "expected error")
+		Assert(t, os.IsNotExist(errors.Cause(err)), "expected not exist error, got %s", err)
+	})
+
+	t.Run("writes summary", func(t *testing.T) {
+		path := t.TempDir()
+		Ok(t, os.WriteFile(filepath.Join(path, ctx.GetShowResultFileName()), []byte(`{}`), 0600))
+		subject := InfracostStepRunner{Executable: fakeInfracost(t, `{"currency":"USD","totalMonthlyCost":"20","pastTotalMonthlyCost":"10","diffTotalMonthlyCost":"10"}`)}
+		out, err := subject.Run(ctx, nil, path, map[string]string{})
+		Ok(t, err)
+		Equals(t, "", out)
+		summary, err := os.ReadFile(filepath.Join(path, "test-default-cost.md"))
+		Ok(t, err)
+		Equals(t, "**Cost estimate:** monthly cost will increase by 10.00 USD (10.00 USD → 20.00 USD).", string(summary))
+	})
+}
+
+// fakeInfracost writes a script that prints output and returns its path.
+func fakeInfracost(t *testing.T, output string) string {
+	bin := filepath.Join(t.TempDir(), "infracost")
+	Ok(t, os.WriteFile(bin, []byte("#!/bin/sh\ncat <<'EOF'\n"+output+"\nEOF\n"), 0700)) // nolint: gosec
+	return bin
+}
@@ -0,0 +1,138 @@
+package runtime
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/core/runtime/common"
+)
+
+// initCacheKeyFile is the file in the .terraform dir of a project that holds
+// the key of the init that created it.
+const initCacheKeyFile = "atlantis-init-cache-key"
+
+// initCacheKeyPath returns the path of the init cache key of the project in
+// path.
+func initCacheKeyPath(path string) string {
+	return filepath.Join(path, ".terraform", initCacheKeyFile)
+}
+
+// initCacheKey returns the key of running initCmd with tfVersion in path, a
+// hash of the version, the args, the backend and module configuration of the
+// project and the local modules it calls, the -backend-config files and the
+// lock file. It's empty if the init can't be cached because it upgrades
+// providers, the project has no lock file or a -backend-config file is
+// missing.
+func initCacheKey(path string, tfVersion *version.Version, initCmd []string) (string, error) {
+	if slices.Contains(initCmd, "-upgrade") {
+		return "", nil
+	}
+	lockFile, err := os.ReadFile(filepath.Join(path, lockFileName))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n", tfVersion, strings.Join(initCmd, "\x00"))
+	if err := hashModuleTree(hash, path); err != nil {
+		return "", err
+	}
+	for _, file := range backendConfigFiles(path, initCmd) {
+		contents, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s\n", file)
+		hash.Write(contents)
+		hash.Write([]byte{0})
+	}
+	hash.Write(lockFile)
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// hashModuleTree writes the init configuration of the project in path and of
+// the local modules it calls, directly or not, to w. Modules that don't exist
+// are skipped since init fails without them.
+func hashModuleTree(w io.Writer, path string) error {
+	dirs := []string{filepath.Clean(path)}
+	seen := map[string]bool{dirs[0]: true}
+	for i := 0; i < len(dirs); i++ {
+		config, err := common.InitConfig(dirs[i])
+		if os.IsNotExist(err) && i > 0 {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(path, dirs[i])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\n", rel)
+		w.Write(config)    // nolint: errcheck
+		w.Write([]byte{0}) // nolint: errcheck
+		modules, err := common.LocalModuleDirs(dirs[i])
+		if err != nil {
+			return err
+		}
+		for _, module := range modules {
+			if !seen[module] {
+				seen[module] = true
+				dirs = append(dirs, module)
+			}
+		}
+	}
+	return nil
+}
+
+// backendConfigFiles returns the paths of the files initCmd, run in path,
+// passes with -backend-config. Values with an "=" are key/value pairs, not
+// files.
+func backendConfigFiles(path string, initCmd []string) []string {
+	var files []string
+	for i, arg := range initCmd {
+		var value string
+		switch arg = "-" + strings.TrimLeft(arg, "-"); {
+		case strings.HasPrefix(arg, "-backend-config="):
+			value = strings.TrimPrefix(arg, "-backend-config=")
+		case arg == "-backend-config" && i+1 < len(initCmd):
+			value = initCmd[i+1]
+		default:
+			continue
+		}
+		if value == "" || strings.Contains(value, "=") {
+			continue
+		}
+		if !filepath.IsAbs(value) {
+			value = filepath.Join(path, value)
+		}
+		files = append(files, value)
+	}
+	return files
+}
+
+// initCached returns true if the project in path was already initialized with
+// key.
+func initCached(path string, key string) bool {
+	cached, err := os.ReadFile(initCacheKeyPath(path))
+	return err == nil && string(cached) == key
+}
+
+// saveInitCacheKey records that the project in path was initialized with key.
+func saveInitCacheKey(path string, key string) error {
+	if err := os.MkdirAll(filepath.Dir(initCacheKeyPath(path)), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(initCacheKeyPath(path), []byte(key), 0600)
+}
@@ -13,10 +13,19 @@ import (
 type InitStepRunner struct {
 	TerraformExecutor TerraformExec
 	DefaultTFVersion  *version.Version
+	// BackendArgs maps a backend type, ex. "s3", to the args to add to init
+	// for projects using that backend. Args from the workflow override them.
+	BackendArgs map[string][]string
+	// UseInitCache skips the init of projects that were already initialized
+	// with the same Terraform version, args, backend and module configuration
+	// and committed lock file.
+	UseInitCache bool
 }
 
+// lockFileName is the name of Terraform's dependency lock file.
+const lockFileName = ".terraform.lock.hcl"
+
 func (i *InitStepRunner) Run(ctx command.ProjectContext, extraArgs []string, path string, envs map[string]string) (string, error) {
-	lockFileName := ".terraform.lock.hcl"
 	terraformLockfilePath := filepath.Join(path, lockFileName)
 	terraformLockFileTracked, err := common.IsFileTracked(path, lockFileName)
 	if err != nil {
@@ -53,15 +62,55 @@ func (i *InitStepRunner) Run(ctx command.ProjectContext, extraArgs []string, pat
 		terraformInitArgs = append(terraformInitArgs, "-upgrade")
 	}
 
+	if len(i.BackendArgs) > 0 && terraformInitVerb[0] == "init" {
+		backendType, err := common.BackendType(path)
+		if err != nil {
+			ctx.Log.Warn("unable to detect backend type in %s: %s", path, err)
+		}
+		if args, ok := i.BackendArgs[backendType]; ok && backendType != "" {
+			ctx.Log.Debug("adding init args for %q backend: %v", backendType, args)
+			terraformInitArgs = append(terraformInitArgs, args...)
+		}
+	}
+
 	finalArgs := common.DeDuplicateExtraArgs(terraformInitArgs, extraArgs)
 
 	terraformInitCmd := append(terraformInitVerb, finalArgs...)
 
+	// Uncommitted lock files are deleted above so those inits are never
+	// cached.
+	useInitCache := i.UseInitCache && terraformLockFileTracked && terraformInitVerb[0] == "init"
+	if useInitCache {
+		key, err := initCacheKey(path, tfVersion, terraformInitCmd)
+		if err != nil {
+			ctx.Log.Warn("unable to get init cache key of %s: %s", path, err)
+		}
+		if key != "" && initCached(path, key) {
+			ctx.Log.Info("skipping init since %s was already initialized with the same configuration and lock file", path)
+			return "", nil
+		}
+		// Remove the key so a failed init isn't cached.
+		if err := os.Remove(initCacheKeyPath(path)); err != nil && !os.IsNotExist(err) {
+			ctx.Log.Warn("unable to remove init cache key of %s: %s", path, err)
+		}
+	}
+
 	out, err := i.TerraformExecutor.RunCommandWithVersion(ctx, path, terraformInitCmd, envs, tfVersion, ctx.Workspace)
 	// Only include the init output if there was an error. Otherwise it's
 	// unnecessary and lengthens the comment.
 	if err != nil {
 		return out, err
 	}
+
+	// The key is computed again since init may have updated the lock file.
+	if useInitCache {
+		key, err := initCacheKey(path, tfVersion, terraformInitCmd)
+		if err == nil && key != "" {
+			err = saveInitCacheKey(path, key)
+		}
+		if err != nil {
+			ctx.Log.Warn("unable to save init cache key of %s: %s", path, err)
+		}
+	}
 	return "", nil
 }
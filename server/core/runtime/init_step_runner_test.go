@@ -324,3 +324,199 @@ func initRepo(t *testing.T) string {
 	runCmd(t, repoDir, "git", "branch", "branch")
 	return repoDir
 }
+
+func TestRun_InitBackendArgs(t *testing.T) {
+	backendArgs := map[string][]string{
+		"s3":  {"-reconfigure", "-backend-config=backend/s3.hcl"},
+		"gcs": {"-backend-config=backend/gcs.hcl"},
+	}
+	cases := []struct {
+		description string
+		config      string
+		extraArgs   []string
+		expArgs     []string
+	}{
+		{
+			description: "s3 backend",
+			config:      "terraform {\n  backend \"s3\" {}\n}",
+			expArgs:     []string{"init", "-input=false", "-upgrade", "-reconfigure", "-backend-config=backend/s3.hcl"},
+		},
+		{
+			description: "gcs backend",
+			config:      "terraform {\n  backend \"gcs\" {}\n}",
+			expArgs:     []string{"init", "-input=false", "-upgrade", "-backend-config=backend/gcs.hcl"},
+		},
+		{
+			description: "backend without args",
+			config:      "terraform {\n  backend \"local\" {}\n}",
+			expArgs:     []string{"init", "-input=false", "-upgrade"},
+		},
+		{
+			description: "no backend",
+			config:      `resource "null_resource" "a" {}`,
+			expArgs:     []string{"init", "-input=false", "-upgrade"},
+		},
+		{
+			description: "workflow args override backend args",
+			config:      "terraform {\n  backend \"s3\" {}\n}",
+			extraArgs:   []string{"-backend-config=prod.hcl"},
+			expArgs:     []string{"init", "-input=false", "-upgrade", "-reconfigure", "-backend-config=prod.hcl"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			terraform := mocks.NewMockClient()
+			path := t.TempDir()
+			Ok(t, os.WriteFile(filepath.Join(path, "main.tf"), []byte(c.config), 0600))
+
+			ctx := command.ProjectContext{
+				Workspace:  "workspace",
+				RepoRelDir: ".",
+				Log:        logging.NewNoopLogger(t),
+			}
+			tfVersion, _ := version.NewVersion("0.14.0")
+			iso := runtime.InitStepRunner{
+				TerraformExecutor: terraform,
+				DefaultTFVersion:  tfVersion,
+				BackendArgs:       backendArgs,
+			}
+			When(terraform.RunCommandWithVersion(Any[command.ProjectContext](), Any[string](), Any[[]string](), Any[map[string]string](), Any[*version.Version](), Any[string]())).
+				ThenReturn("output", nil)
+
+			_, err := iso.Run(ctx, c.extraArgs, path, map[string]string(nil))
+			Ok(t, err)
+			terraform.VerifyWasCalledOnce().RunCommandWithVersion(ctx, path, c.expArgs, map[string]string(nil), tfVersion, "workspace")
+		})
+	}
+}
+
+func TestRun_InitCache(t *testing.T) {
+	repoDir := initRepo(t)
+	mainTF := "terraform {\n  backend \"s3\" {}\n}\n\nmodule \"vpc\" {\n  source = \"./vpc\"\n}\n"
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "main.tf"), []byte(mainTF), 0600))
+	Ok(t, os.WriteFile(filepath.Join(repoDir, ".terraform.lock.hcl"), []byte("provider \"aws\" {}\n"), 0600))
+	Ok(t, os.Mkdir(filepath.Join(repoDir, "vpc"), 0700))
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "vpc", "main.tf"), []byte("module \"subnets\" {\n  source = \"hashicorp/subnets/cidr\"\n}\n"), 0600))
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "backend.hcl"), []byte("bucket = \"a\"\n"), 0600))
+	runCmd(t, repoDir, "git", "add", ".")
+	runCmd(t, repoDir, "git", "commit", "-m", "add project")
+
+	ctx := command.ProjectContext{
+		Workspace:  "default",
+		RepoRelDir: ".",
+		Log:        logging.NewNoopLogger(t),
+	}
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	tfVersion, _ := version.NewVersion("1.5.0")
+	iso := runtime.InitStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+		UseInitCache:      true,
+	}
+	When(terraform.RunCommandWithVersion(Any[command.ProjectContext](), Any[string](), Any[[]string](), Any[map[string]string](), Any[*version.Version](), Any[string]())).
+		ThenReturn("output", nil)
+	expInits := 0
+	runInit := func(description string, expInit bool) {
+		t.Helper()
+		_, err := iso.Run(ctx, nil, repoDir, map[string]string(nil))
+		Ok(t, err)
+		if expInit {
+			expInits++
+		}
+		terraform.VerifyWasCalled(Times(expInits)).RunCommandWithVersion(ctx, repoDir, []string{"init", "-input=false"}, map[string]string(nil), tfVersion, "default")
+		Assert(t, !t.Failed(), "unexpected number of inits after %s", description)
+	}
+
+	runInit("first init", true)
+	runInit("init without changes", false)
+
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "resources.tf"), []byte("resource \"null_resource\" \"a\" {}\n"), 0600))
+	runInit("adding a resource", false)
+
+	Ok(t, os.WriteFile(filepath.Join(repoDir, ".terraform.lock.hcl"), []byte("provider \"aws\" {\n  version = \"5.0.0\"\n}\n"), 0600))
+	runInit("changing the lock file", true)
+	runInit("init after changing the lock file", false)
+
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "vpc", "resources.tf"), []byte("resource \"null_resource\" \"a\" {}\n"), 0600))
+	runInit("adding a resource to a local module", false)
+
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "vpc", "main.tf"), []byte("module \"subnets\" {\n  source  = \"hashicorp/subnets/cidr\"\n  version = \"1.0.0\"\n}\n"), 0600))
+	runInit("changing a module of a local module", true)
+	runInit("init after changing a module of a local module", false)
+
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "main.tf"), []byte(strings.Replace(mainTF, "./vpc", "./network", 1)), 0600))
+	runInit("changing a module source", true)
+
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "main.tf"), []byte(strings.Replace(mainTF, "s3", "gcs", 1)), 0600))
+	runInit("changing the backend", true)
+
+	_, err := iso.Run(ctx, []string{"-reconfigure"}, repoDir, map[string]string(nil))
+	Ok(t, err)
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(ctx, repoDir, []string{"init", "-input=false", "-reconfigure"}, map[string]string(nil), tfVersion, "default")
+
+	backendConfigInit := []string{"init", "-input=false", "-backend-config=backend.hcl"}
+	for i, exp := range []int{1, 1, 2} {
+		if i == 2 {
+			Ok(t, os.WriteFile(filepath.Join(repoDir, "backend.hcl"), []byte("bucket = \"b\"\n"), 0600))
+		}
+		_, err = iso.Run(ctx, []string{"-backend-config=backend.hcl"}, repoDir, map[string]string(nil))
+		Ok(t, err)
+		terraform.VerifyWasCalled(Times(exp)).RunCommandWithVersion(ctx, repoDir, backendConfigInit, map[string]string(nil), tfVersion, "default")
+	}
+}
+
+func TestRun_InitCacheNotUsed(t *testing.T) {
+	cases := []struct {
+		description  string
+		useInitCache bool
+		commitLock   bool
+	}{
+		{
+			description:  "disabled",
+			useInitCache: false,
+			commitLock:   true,
+		},
+		{
+			description:  "lock file not committed",
+			useInitCache: true,
+			commitLock:   false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			repoDir := initRepo(t)
+			Ok(t, os.WriteFile(filepath.Join(repoDir, "main.tf"), []byte("terraform {\n  backend \"s3\" {}\n}\n"), 0600))
+			Ok(t, os.WriteFile(filepath.Join(repoDir, ".terraform.lock.hcl"), nil, 0600))
+			if c.commitLock {
+				runCmd(t, repoDir, "git", "add", ".terraform.lock.hcl")
+				runCmd(t, repoDir, "git", "commit", "-m", "add .terraform.lock.hcl")
+			}
+
+			ctx := command.ProjectContext{
+				Workspace:  "default",
+				RepoRelDir: ".",
+				Log:        logging.NewNoopLogger(t),
+			}
+			RegisterMockTestingT(t)
+			terraform := mocks.NewMockClient()
+			tfVersion, _ := version.NewVersion("1.5.0")
+			iso := runtime.InitStepRunner{
+				TerraformExecutor: terraform,
+				DefaultTFVersion:  tfVersion,
+				UseInitCache:      c.useInitCache,
+			}
+			When(terraform.RunCommandWithVersion(Any[command.ProjectContext](), Any[string](), Any[[]string](), Any[map[string]string](), Any[*version.Version](), Any[string]())).
+				ThenReturn("output", nil)
+
+			for i := 0; i < 2; i++ {
+				_, err := iso.Run(ctx, nil, repoDir, map[string]string(nil))
+				Ok(t, err)
+			}
+			terraform.VerifyWasCalled(Times(2)).RunCommandWithVersion(Any[command.ProjectContext](), Any[string](), Any[[]string](), Any[map[string]string](), Any[*version.Version](), Any[string]())
+			_, err := os.Stat(filepath.Join(repoDir, ".terraform"))
+			Assert(t, os.IsNotExist(err), "exp no init cache key to be saved")
+		})
+	}
+}
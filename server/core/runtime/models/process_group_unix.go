@@ -0,0 +1,34 @@
+//go:build !windows
+
+package models
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup makes cmd start in a new process group so the processes it
+// starts, ex. terraform started by `sh -c`, can be signalled along with it.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// interruptProcessGroup interrupts the started cmd and the processes it
+// started. If cmd doesn't lead its own process group only it is interrupted.
+func interruptProcessGroup(cmd *exec.Cmd) error {
+	return signalProcessGroup(cmd, syscall.SIGINT)
+}
+
+// killProcessGroup kills the started cmd and the processes it started. If cmd
+// doesn't lead its own process group only it is killed.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return signalProcessGroup(cmd, syscall.SIGKILL)
+}
+
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	pid := cmd.Process.Pid
+	if pgid, err := syscall.Getpgid(pid); err == nil && pgid == pid {
+		return syscall.Kill(-pid, sig)
+	}
+	return cmd.Process.Signal(sig)
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on Windows, which has no process groups that can
+// be signalled.
+func setProcessGroup(_ *exec.Cmd) {}
+
+// interruptProcessGroup interrupts the started cmd. Interrupting processes
+// isn't supported on Windows so it always returns an error.
+func interruptProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Signal(os.Interrupt)
+}
+
+// killProcessGroup kills the started cmd.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/command"
+)
+
+// ErrCommandCanceled is returned by a ShellCommandRunner when its process was
+// stopped because a user canceled it.
+var ErrCommandCanceled = errors.New("command was canceled")
+
+// DefaultProcessTracker tracks every process started by a ShellCommandRunner.
+var DefaultProcessTracker = NewProcessTracker()
+
+// CancelKillDelay is how long a canceled process has to exit after it's
+// interrupted before it's killed.
+var CancelKillDelay = 30 * time.Second
+
+// TrackedProcess describes the project a running process was started for.
+type TrackedProcess struct {
+	RepoFullName string
+	PullNum      int
+	ProjectName  string
+	RepoRelDir   string
+	Workspace    string
+	CommandName  command.Name
+	// Autoplan is true if the process was started by an autoplan.
+	Autoplan bool
+}
+
+// ProcessFilter selects tracked processes to cancel. RepoFullName and PullNum
+// must match, the other fields only have to match if they're set.
+type ProcessFilter struct {
+	RepoFullName string
+	PullNum      int
+	ProjectName  string
+	RepoRelDir   string
+	Workspace    string
+	// CommandNames limits the filter to processes run for one of these
+	// commands, ex. to only cancel plans.
+	CommandNames []command.Name
+	// Autoplan limits the filter to processes started by autoplans.
+	Autoplan bool
+}
+
+func (f ProcessFilter) matches(p TrackedProcess) bool {
+	if f.RepoFullName != p.RepoFullName || f.PullNum != p.PullNum {
+		return false
+	}
+	if f.ProjectName != "" && f.ProjectName != p.ProjectName {
+		return false
+	}
+	if f.RepoRelDir != "" && filepath.Clean(f.RepoRelDir) != filepath.Clean(p.RepoRelDir) {
+		return false
+	}
+	if f.Workspace != "" && f.Workspace != p.Workspace {
+		return false
+	}
+	if f.Autoplan && !p.Autoplan {
+		return false
+	}
+	if len(f.CommandNames) > 0 {
+		for _, name := range f.CommandNames {
+			if name == p.CommandName {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+type trackedCmd struct {
+	TrackedProcess
+	canceled bool
+	// exited is closed when the process is untracked after it has exited.
+	exited chan struct{}
+}
+
+// ProcessTracker keeps track of running processes by project so they can be
+// canceled from another goroutine, ex. by an `atlantis cancel` comment.
+type ProcessTracker struct {
+	mu    sync.Mutex
+	procs map[*exec.Cmd]*trackedCmd
+}
+
+// NewProcessTracker returns an empty ProcessTracker.
+func NewProcessTracker() *ProcessTracker {
+	return &ProcessTracker{
+		procs: make(map[*exec.Cmd]*trackedCmd),
+	}
+}
+
+// Track registers cmd, which must already be started, as running for the
+// project described by ctx.
+func (t *ProcessTracker) Track(ctx command.ProjectContext, cmd *exec.Cmd) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.procs[cmd] = &trackedCmd{
+		TrackedProcess: TrackedProcess{
+			RepoFullName: ctx.BaseRepo.FullName,
+			PullNum:      ctx.Pull.Num,
+			ProjectName:  ctx.ProjectName,
+			RepoRelDir:   ctx.RepoRelDir,
+			Workspace:    ctx.Workspace,
+			CommandName:  ctx.CommandName,
+			Autoplan:     ctx.Autoplan,
+		},
+		exited: make(chan struct{}),
+	}
+}
+
+// Untrack removes cmd once it has exited. It returns true if cmd was
+// canceled while it was running.
+func (t *ProcessTracker) Untrack(cmd *exec.Cmd) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p, ok := t.procs[cmd]
+	if !ok {
+		return false
+	}
+	delete(t.procs, cmd)
+	close(p.exited)
+	return p.canceled
+}
+
+// Cancel interrupts every tracked process matching filter, along with the
+// processes it started, and returns them once they have exited so that their
+// locks can be released. Processes that can't be interrupted, ex. on Windows,
+// or that haven't exited CancelKillDelay after they were interrupted are
+// killed.
+func (t *ProcessTracker) Cancel(filter ProcessFilter) ([]TrackedProcess, error) {
+	t.mu.Lock()
+	var canceled []TrackedProcess
+	var cancelErr error
+	exited := make(map[*exec.Cmd]chan struct{})
+	for cmd, p := range t.procs {
+		if p.canceled || !filter.matches(p.TrackedProcess) || cmd.Process == nil {
+			continue
+		}
+		// Terraform stops gracefully and releases any state lock on an
+		// interrupt so we prefer that over killing the process.
+		if err := interruptProcessGroup(cmd); err != nil {
+			if killErr := killProcessGroup(cmd); killErr != nil {
+				cancelErr = errors.Wrapf(killErr, "canceling process %d", cmd.Process.Pid)
+				continue
+			}
+		}
+		p.canceled = true
+		canceled = append(canceled, p.TrackedProcess)
+		exited[cmd] = p.exited
+	}
+	// Processes are untracked once they have exited, which needs the lock.
+	t.mu.Unlock()
+
+	for cmd, ch := range exited {
+		if err := waitForExit(cmd, ch); err != nil {
+			cancelErr = err
+		}
+	}
+	return canceled, cancelErr
+}
+
+// waitForExit waits until exited is closed, killing cmd if it takes longer
+// than CancelKillDelay.
+func waitForExit(cmd *exec.Cmd, exited chan struct{}) error {
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(CancelKillDelay):
+	}
+	if err := killProcessGroup(cmd); err != nil {
+		return errors.Wrapf(err, "killing process %d", cmd.Process.Pid)
+	}
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(CancelKillDelay):
+		return fmt.Errorf("process %d didn't exit after it was killed", cmd.Process.Pid)
+	}
+}
@@ -0,0 +1,93 @@
+package models_test
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock/v4"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/runtime/models"
+	"github.com/runatlantis/atlantis/server/events/command"
+	eventmodels "github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/jobs/mocks"
+	logmocks "github.com/runatlantis/atlantis/server/logging/mocks"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestProcessTracker_Cancel(t *testing.T) {
+	RegisterMockTestingT(t)
+	log := logmocks.NewMockSimpleLogging()
+	When(log.With(Any[string](), Any[interface{}]())).ThenReturn(log)
+	ctx := command.ProjectContext{
+		Log:         log,
+		BaseRepo:    eventmodels.Repo{FullName: "owner/repo"},
+		Pull:        eventmodels.PullRequest{Num: 1},
+		ProjectName: "foo",
+		Workspace:   "default",
+		RepoRelDir:  "dir",
+		CommandName: command.Plan,
+	}
+	cwd, err := os.Getwd()
+	Ok(t, err)
+
+	// sleep isn't exec'd so it's a child of the shell and has to be
+	// canceled along with it.
+	runner := models.NewShellCommandRunner("sleep 30; echo done", os.Environ(), cwd, false, mocks.NewMockProjectCommandOutputHandler())
+	_, outCh := runner.RunCommandAsync(ctx)
+
+	// Processes for other projects or pulls must not be touched.
+	for _, filter := range []models.ProcessFilter{
+		{RepoFullName: "owner/repo", PullNum: 2},
+		{RepoFullName: "owner/other", PullNum: 1},
+		{RepoFullName: "owner/repo", PullNum: 1, ProjectName: "bar"},
+		{RepoFullName: "owner/repo", PullNum: 1, Workspace: "staging"},
+		{RepoFullName: "owner/repo", PullNum: 1, CommandNames: []command.Name{command.Apply}},
+		// The plan was run by a comment so it isn't a superseded autoplan.
+		{RepoFullName: "owner/repo", PullNum: 1, Autoplan: true},
+	} {
+		canceled, err := models.DefaultProcessTracker.Cancel(filter)
+		Ok(t, err)
+		Equals(t, 0, len(canceled))
+	}
+
+	// The process is tracked once it has started so retry until it shows up.
+	filter := models.ProcessFilter{RepoFullName: "owner/repo", PullNum: 1, ProjectName: "foo", CommandNames: []command.Name{command.Plan}}
+	var canceled []models.TrackedProcess
+	start := time.Now()
+	deadline := start.Add(10 * time.Second)
+	for len(canceled) == 0 && time.Now().Before(deadline) {
+		canceled, err = models.DefaultProcessTracker.Cancel(filter)
+		Ok(t, err)
+		time.Sleep(10 * time.Millisecond)
+	}
+	Equals(t, []models.TrackedProcess{
+		{
+			RepoFullName: "owner/repo",
+			PullNum:      1,
+			ProjectName:  "foo",
+			RepoRelDir:   "dir",
+			Workspace:    "default",
+			CommandName:  command.Plan,
+		},
+	}, canceled)
+
+	var lineErr error
+	var lines []string
+	for line := range outCh {
+		if line.Err != nil {
+			lineErr = line.Err
+		}
+		lines = append(lines, line.Line)
+	}
+	Assert(t, errors.Is(lineErr, models.ErrCommandCanceled), "expected canceled error, got %v", lineErr)
+	Assert(t, !slices.Contains(lines, "done"), "expected the shell to be canceled, got %v", lines)
+	// The output only ends once sleep exits too.
+	Assert(t, time.Since(start) < 10*time.Second, "expected sleep to be canceled along with the shell")
+
+	// Once the process exited there's nothing left to cancel.
+	canceled, err = models.DefaultProcessTracker.Cancel(filter)
+	Ok(t, err)
+	Equals(t, 0, len(canceled))
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"bufio"
+	"context"
 	"io"
 	"os/exec"
 	"strings"
@@ -17,6 +18,14 @@ import (
 // Setting the buffer size to 10mb
 const BufioScannerBufferSize = 10 * 1024 * 1024
 
+// ErrCommandTimedOut is returned by a ShellCommandRunner when its process was
+// stopped because it ran for longer than the project's command timeout.
+var ErrCommandTimedOut = errors.New("command timed out")
+
+// TimeoutKillDelay is how long a process that timed out has to exit after
+// it's interrupted before it's killed.
+var TimeoutKillDelay = 30 * time.Second
+
 // Line represents a line that was output from a shell command.
 type Line struct {
 	// Line is the contents of the line (without the newline).
@@ -33,12 +42,14 @@ type ShellCommandRunner struct {
 	outputHandler jobs.ProjectCommandOutputHandler
 	streamOutput  bool
 	cmd           *exec.Cmd
+	tracker       *ProcessTracker
 }
 
 func NewShellCommandRunner(command string, environ []string, workingDir string, streamOutput bool, outputHandler jobs.ProjectCommandOutputHandler) *ShellCommandRunner {
 	cmd := exec.Command("sh", "-c", command) // #nosec
 	cmd.Env = environ
 	cmd.Dir = workingDir
+	setProcessGroup(cmd)
 
 	return &ShellCommandRunner{
 		command:       command,
@@ -46,6 +57,7 @@ func NewShellCommandRunner(command string, environ []string, workingDir string,
 		outputHandler: outputHandler,
 		streamOutput:  streamOutput,
 		cmd:           cmd,
+		tracker:       DefaultProcessTracker,
 	}
 }
 
@@ -100,6 +112,8 @@ func (s *ShellCommandRunner) RunCommandAsync(ctx command.ProjectContext) (chan<-
 			outCh <- Line{Err: err}
 			return
 		}
+		s.tracker.Track(ctx, s.cmd)
+		stopTimeout := s.interruptOnTimeout(ctx.CommandTimeout)
 
 		// If we get anything on inCh, write it to stdin.
 		// This function will exit when inCh is closed which we do in our defer.
@@ -148,12 +162,22 @@ func (s *ShellCommandRunner) RunCommandAsync(ctx command.ProjectContext) (chan<-
 
 		// Wait for the command to complete.
 		err = s.cmd.Wait()
+		canceled := s.tracker.Untrack(s.cmd)
+		timedOut := stopTimeout()
 
 		dur := time.Since(start)
 		log := ctx.Log.With("duration", dur)
 
 		// We're done now. Send an error if there was one.
-		if err != nil {
+		if canceled {
+			err = errors.Wrapf(ErrCommandCanceled, "running %q in %q", s.command, s.workingDir)
+			log.Info(err.Error())
+			outCh <- Line{Err: err}
+		} else if timedOut && err != nil {
+			err = errors.Wrapf(ErrCommandTimedOut, "running %q in %q for longer than %s", s.command, s.workingDir, ctx.CommandTimeout)
+			log.Err(err.Error())
+			outCh <- Line{Err: err}
+		} else if err != nil {
 			err = errors.Wrapf(err, "running %q in %q", s.command, s.workingDir)
 			log.Err(err.Error())
 			outCh <- Line{Err: err}
@@ -164,3 +188,45 @@ func (s *ShellCommandRunner) RunCommandAsync(ctx command.ProjectContext) (chan<-
 
 	return inCh, outCh
 }
+
+// interruptOnTimeout runs the started command under a deadline context. If the
+// command is still running once timeout has passed it's interrupted, and it's
+// killed if it hasn't exited TimeoutKillDelay later. The returned func must be
+// called after the command has exited and reports whether it timed out.
+// A timeout of 0 means no limit.
+func (s *ShellCommandRunner) interruptOnTimeout(timeout time.Duration) (stop func() bool) {
+	if timeout <= 0 {
+		return func() bool { return false }
+	}
+	deadlineCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	exited := make(chan struct{})
+	done := make(chan struct{})
+	timedOut := false
+	go func() {
+		defer close(done)
+		select {
+		case <-exited:
+			return
+		case <-deadlineCtx.Done():
+		}
+		timedOut = true
+		// Terraform stops gracefully and releases any state lock on an
+		// interrupt so we prefer that over killing the process. The whole
+		// process group is signalled so commands the shell started stop too.
+		if err := interruptProcessGroup(s.cmd); err != nil {
+			killProcessGroup(s.cmd) // nolint: errcheck
+			return
+		}
+		select {
+		case <-exited:
+		case <-time.After(TimeoutKillDelay):
+			killProcessGroup(s.cmd) // nolint: errcheck
+		}
+	}()
+	return func() bool {
+		close(exited)
+		<-done
+		cancel()
+		return timedOut
+	}
+}
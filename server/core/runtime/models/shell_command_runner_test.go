@@ -5,8 +5,10 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	. "github.com/petergtz/pegomock/v4"
+	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/core/runtime/models"
 	"github.com/runatlantis/atlantis/server/events/command"
 	"github.com/runatlantis/atlantis/server/jobs/mocks"
@@ -78,3 +80,82 @@ func TestShellCommandRunner_Run(t *testing.T) {
 		})
 	}
 }
+
+func TestShellCommandRunner_Timeout(t *testing.T) {
+	killDelay := models.TimeoutKillDelay
+	models.TimeoutKillDelay = 100 * time.Millisecond
+	defer func() { models.TimeoutKillDelay = killDelay }()
+
+	cases := []struct {
+		description string
+		command     string
+		timeout     time.Duration
+		expTimeout  bool
+		expOutput   string
+	}{
+		{
+			description: "finishes before the timeout",
+			command:     "echo done",
+			timeout:     10 * time.Second,
+			expOutput:   "done\n",
+		},
+		{
+			description: "no timeout",
+			command:     "sleep 0.2 && echo done",
+			expOutput:   "done\n",
+		},
+		{
+			description: "interrupted",
+			command:     "echo started && exec sleep 10",
+			timeout:     100 * time.Millisecond,
+			expTimeout:  true,
+			expOutput:   "started\n",
+		},
+		{
+			description: "interrupted with a child process",
+			command:     "echo started && sleep 10 && echo done",
+			timeout:     100 * time.Millisecond,
+			expTimeout:  true,
+			expOutput:   "started\n",
+		},
+		{
+			description: "killed when interrupt is ignored",
+			command:     "trap '' INT; echo started; exec sleep 10",
+			timeout:     100 * time.Millisecond,
+			expTimeout:  true,
+			expOutput:   "started\n",
+		},
+		{
+			description: "killed with a child process when interrupt is ignored",
+			command:     "trap '' INT; echo started; sleep 10; echo done",
+			timeout:     100 * time.Millisecond,
+			expTimeout:  true,
+			expOutput:   "started\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			log := logmocks.NewMockSimpleLogging()
+			When(log.With(Any[string](), Any[interface{}]())).ThenReturn(log)
+			ctx := command.ProjectContext{
+				Log:            log,
+				Workspace:      "default",
+				RepoRelDir:     ".",
+				CommandTimeout: c.timeout,
+			}
+			runner := models.NewShellCommandRunner(c.command, os.Environ(), t.TempDir(), false, mocks.NewMockProjectCommandOutputHandler())
+
+			start := time.Now()
+			output, err := runner.Run(ctx)
+			Assert(t, time.Since(start) < 5*time.Second, "expected command to be stopped, took %s", time.Since(start))
+			Equals(t, c.expOutput, output)
+			if c.expTimeout {
+				Assert(t, errors.Is(err, models.ErrCommandTimedOut), "expected timeout error, got %v", err)
+				ErrContains(t, "for longer than 100ms: command timed out", err)
+			} else {
+				Ok(t, err)
+			}
+		})
+	}
+}
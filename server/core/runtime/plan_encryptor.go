@@ -0,0 +1,192 @@
+package runtime
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// encryptedPlanHeader prefixes planfiles encrypted by a PlanEncryptor. It's
+// followed by the tag of the key used and a newline.
+const encryptedPlanHeader = "ATLANTIS-ENCRYPTED-PLAN:v1:"
+
+// PlanEncryptor encrypts planfiles at rest with AES-GCM. Encrypted planfiles
+// are tagged with the key they were encrypted with so that planfiles written
+// before a key rotation can still be decrypted with the old key.
+// A nil *PlanEncryptor leaves planfiles untouched.
+type PlanEncryptor struct {
+	tag  string
+	aead cipher.AEAD
+	// oldKeys maps the tag of each old key to its cipher.
+	oldKeys map[string]cipher.AEAD
+}
+
+// NewPlanEncryptor returns a PlanEncryptor that encrypts with key and can
+// also decrypt planfiles that were encrypted with any of oldKeys.
+func NewPlanEncryptor(key string, oldKeys []string) (*PlanEncryptor, error) {
+	if key == "" {
+		return nil, errors.New("plan encryption key must not be empty")
+	}
+	tag, aead, err := planCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	e := &PlanEncryptor{
+		tag:     tag,
+		aead:    aead,
+		oldKeys: make(map[string]cipher.AEAD),
+	}
+	for _, oldKey := range oldKeys {
+		if oldKey == "" {
+			continue
+		}
+		oldTag, oldAEAD, err := planCipher(oldKey)
+		if err != nil {
+			return nil, err
+		}
+		e.oldKeys[oldTag] = oldAEAD
+	}
+	return e, nil
+}
+
+// planCipher derives an AES-256 key from key and returns it along with a tag
+// that identifies the key without revealing it.
+func planCipher(key string) (string, cipher.AEAD, error) {
+	derived := sha256.Sum256([]byte(key))
+	tagSum := sha256.Sum256(derived[:])
+	block, err := aes.NewCipher(derived[:])
+	if err != nil {
+		return "", nil, errors.Wrap(err, "creating plan encryption cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "creating plan encryption cipher")
+	}
+	return hex.EncodeToString(tagSum[:6]), aead, nil
+}
+
+// Encrypt returns plaintext encrypted with the current key. A nil
+// PlanEncryptor returns plaintext unchanged.
+func (e *PlanEncryptor) Encrypt(plaintext []byte) ([]byte, error) {
+	if e == nil {
+		return plaintext, nil
+	}
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, "generating nonce")
+	}
+	out := []byte(fmt.Sprintf("%s%s\n", encryptedPlanHeader, e.tag))
+	out = append(out, nonce...)
+	return e.aead.Seal(out, nonce, plaintext, nil), nil
+}
+
+// Decrypt returns the plaintext of data. Data that wasn't encrypted by a
+// PlanEncryptor is returned unchanged.
+func (e *PlanEncryptor) Decrypt(data []byte) ([]byte, error) {
+	tag, body, encrypted := splitEncryptedPlan(data)
+	if !encrypted {
+		return data, nil
+	}
+	if e == nil {
+		return nil, errors.New("planfile is encrypted but no plan encryption key is configured")
+	}
+	aead := e.aead
+	if tag != e.tag {
+		var ok bool
+		if aead, ok = e.oldKeys[tag]; !ok {
+			return nil, fmt.Errorf("planfile was encrypted with key %q which isn't configured", tag)
+		}
+	}
+	if len(body) < aead.NonceSize() {
+		return nil, errors.New("encrypted planfile is truncated")
+	}
+	nonce, ciphertext := body[:aead.NonceSize()], body[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "decrypting planfile")
+	}
+	return plaintext, nil
+}
+
+// EncryptFile encrypts the planfile at path in place. Planfiles encrypted with
+// an old key are re-encrypted with the current key. It's a no-op if the file
+// doesn't exist or is already encrypted with the current key.
+func (e *PlanEncryptor) EncryptFile(path string) error {
+	if e == nil {
+		return nil
+	}
+	data, info, err := readPlanfile(path)
+	if err != nil || data == nil {
+		return err
+	}
+	if tag, _, encrypted := splitEncryptedPlan(data); encrypted {
+		if tag == e.tag {
+			return nil
+		}
+		if data, err = e.Decrypt(data); err != nil {
+			return err
+		}
+	}
+	encrypted, err := e.Encrypt(data)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(os.WriteFile(path, encrypted, info.Mode().Perm()), "writing encrypted planfile")
+}
+
+// DecryptFile decrypts the planfile at path in place so Terraform can read
+// it. It's a no-op if the file doesn't exist or isn't encrypted.
+func (e *PlanEncryptor) DecryptFile(path string) error {
+	if e == nil {
+		return nil
+	}
+	data, info, err := readPlanfile(path)
+	if err != nil || data == nil {
+		return err
+	}
+	if _, _, encrypted := splitEncryptedPlan(data); !encrypted {
+		return nil
+	}
+	plaintext, err := e.Decrypt(data)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(os.WriteFile(path, plaintext, info.Mode().Perm()), "writing decrypted planfile")
+}
+
+func readPlanfile(path string) ([]byte, os.FileInfo, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "reading planfile")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "reading planfile")
+	}
+	return data, info, nil
+}
+
+// splitEncryptedPlan returns the key tag and encrypted body of data if it's
+// an encrypted planfile.
+func splitEncryptedPlan(data []byte) (string, []byte, bool) {
+	if !bytes.HasPrefix(data, []byte(encryptedPlanHeader)) {
+		return "", nil, false
+	}
+	rest := data[len(encryptedPlanHeader):]
+	i := bytes.IndexByte(rest, '\n')
+	if i < 0 {
+		return "", nil, false
+	}
+	return string(rest[:i]), rest[i+1:], true
+}
@@ -0,0 +1,137 @@
+package runtime_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/core/runtime"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestPlanEncryptor_RoundTrip(t *testing.T) {
+	e, err := runtime.NewPlanEncryptor("key", nil)
+	Ok(t, err)
+
+	plaintext := []byte("planfile contents with a secret")
+	encrypted, err := e.Encrypt(plaintext)
+	Ok(t, err)
+	Assert(t, !bytes.Contains(encrypted, plaintext), "expected plaintext to not be in encrypted output")
+
+	decrypted, err := e.Decrypt(encrypted)
+	Ok(t, err)
+	Equals(t, plaintext, decrypted)
+
+	// Unencrypted planfiles are passed through so existing plans still work.
+	decrypted, err = e.Decrypt(plaintext)
+	Ok(t, err)
+	Equals(t, plaintext, decrypted)
+}
+
+func TestPlanEncryptor_EmptyKey(t *testing.T) {
+	_, err := runtime.NewPlanEncryptor("", nil)
+	ErrEquals(t, "plan encryption key must not be empty", err)
+}
+
+func TestPlanEncryptor_WrongKey(t *testing.T) {
+	e, err := runtime.NewPlanEncryptor("key", nil)
+	Ok(t, err)
+	encrypted, err := e.Encrypt([]byte("plan"))
+	Ok(t, err)
+
+	other, err := runtime.NewPlanEncryptor("other", nil)
+	Ok(t, err)
+	_, err = other.Decrypt(encrypted)
+	ErrContains(t, "which isn't configured", err)
+}
+
+func TestPlanEncryptor_Tampered(t *testing.T) {
+	e, err := runtime.NewPlanEncryptor("key", nil)
+	Ok(t, err)
+	encrypted, err := e.Encrypt([]byte("plan"))
+	Ok(t, err)
+
+	encrypted[len(encrypted)-1] ^= 0xff
+	_, err = e.Decrypt(encrypted)
+	ErrContains(t, "decrypting planfile", err)
+}
+
+func TestPlanEncryptor_Files(t *testing.T) {
+	tmp := t.TempDir()
+	planPath := filepath.Join(tmp, "default.tfplan")
+	plaintext := []byte("plan")
+	Ok(t, os.WriteFile(planPath, plaintext, 0600))
+
+	e, err := runtime.NewPlanEncryptor("key", nil)
+	Ok(t, err)
+
+	Ok(t, e.EncryptFile(planPath))
+	onDisk, err := os.ReadFile(planPath)
+	Ok(t, err)
+	Assert(t, !bytes.Equal(plaintext, onDisk), "expected planfile to be encrypted on disk")
+
+	// Encrypting twice must not double encrypt.
+	Ok(t, e.EncryptFile(planPath))
+	onDiskAgain, err := os.ReadFile(planPath)
+	Ok(t, err)
+	Equals(t, onDisk, onDiskAgain)
+
+	Ok(t, e.DecryptFile(planPath))
+	onDisk, err = os.ReadFile(planPath)
+	Ok(t, err)
+	Equals(t, plaintext, onDisk)
+
+	// Missing planfiles are ignored.
+	Ok(t, e.EncryptFile(filepath.Join(tmp, "missing.tfplan")))
+	Ok(t, e.DecryptFile(filepath.Join(tmp, "missing.tfplan")))
+}
+
+func TestPlanEncryptor_KeyRotation(t *testing.T) {
+	tmp := t.TempDir()
+	planPath := filepath.Join(tmp, "default.tfplan")
+	plaintext := []byte("plan")
+	Ok(t, os.WriteFile(planPath, plaintext, 0600))
+
+	oldEncryptor, err := runtime.NewPlanEncryptor("old", nil)
+	Ok(t, err)
+	Ok(t, oldEncryptor.EncryptFile(planPath))
+
+	e, err := runtime.NewPlanEncryptor("new", []string{"", "old"})
+	Ok(t, err)
+
+	// Re-encrypting moves the planfile to the new key.
+	Ok(t, e.EncryptFile(planPath))
+	_, err = oldEncryptor.Decrypt(mustReadFile(t, planPath))
+	ErrContains(t, "which isn't configured", err)
+
+	newOnly, err := runtime.NewPlanEncryptor("new", nil)
+	Ok(t, err)
+	decrypted, err := newOnly.Decrypt(mustReadFile(t, planPath))
+	Ok(t, err)
+	Equals(t, plaintext, decrypted)
+
+	// Planfiles still encrypted with the old key are decrypted with it.
+	Ok(t, os.WriteFile(planPath, plaintext, 0600))
+	Ok(t, oldEncryptor.EncryptFile(planPath))
+	Ok(t, e.DecryptFile(planPath))
+	Equals(t, plaintext, mustReadFile(t, planPath))
+}
+
+func TestPlanEncryptor_Nil(t *testing.T) {
+	tmp := t.TempDir()
+	planPath := filepath.Join(tmp, "default.tfplan")
+	Ok(t, os.WriteFile(planPath, []byte("plan"), 0600))
+
+	var e *runtime.PlanEncryptor
+	Ok(t, e.EncryptFile(planPath))
+	Ok(t, e.DecryptFile(planPath))
+	Equals(t, []byte("plan"), mustReadFile(t, planPath))
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	t.Helper()
+	contents, err := os.ReadFile(path)
+	Ok(t, err)
+	return contents
+}
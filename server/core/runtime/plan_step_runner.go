@@ -53,12 +53,18 @@ func (p *planStepRunner) Run(ctx command.ProjectContext, extraArgs []string, pat
 	output, err := p.TerraformExecutor.RunCommandWithVersion(ctx, filepath.Clean(path), planCmd, envs, tfVersion, ctx.Workspace)
 	if p.isRemoteOpsErr(output, err) {
 		ctx.Log.Debug("detected that this project is using TFE remote ops")
-		return p.remotePlan(ctx, extraArgs, path, tfVersion, planFile, envs)
+		remoteOutput, err := p.remotePlan(ctx, extraArgs, path, tfVersion, planFile, envs)
+		if err != nil {
+			return remoteOutput, err
+		}
+		return remoteOutput, failOnPlanWarnings(ctx.PlanWarningsAsErrors, planFile, remoteOutput)
 	}
 	if err != nil {
 		return output, err
 	}
-	return p.fmtPlanOutput(output, tfVersion), nil
+	// Warnings are looked for in the unformatted output since Terraform may
+	// print them before the refresh output that's stripped.
+	return p.fmtPlanOutput(output, tfVersion), failOnPlanWarnings(ctx.PlanWarningsAsErrors, planFile, output)
 }
 
 // isRemoteOpsErr returns true if there was an error caused due to this
@@ -76,6 +82,7 @@ func (p *planStepRunner) remotePlan(ctx command.ProjectContext, extraArgs []stri
 	argList := [][]string{
 		{"plan", "-input=false", "-refresh", "-no-color"},
 		extraArgs,
+		ctx.PlanPresetArgs,
 		ctx.EscapedCommentArgs,
 	}
 	args := p.flatten(argList)
@@ -121,8 +128,11 @@ func (p *planStepRunner) buildPlanCmd(ctx command.ProjectContext, extraArgs []st
 		// NOTE: we need to quote the plan filename because Bitbucket Server can
 		// have spaces in its repo owner names.
 		{"plan", "-input=false", "-refresh", "-out", fmt.Sprintf("%q", planFile)},
+		ParallelismArgs(ctx, extraArgs, ctx.PlanPresetArgs, ctx.EscapedCommentArgs),
 		tfVars,
+		ctx.PRDescriptionVarArgs,
 		extraArgs,
+		ctx.PlanPresetArgs,
 		ctx.EscapedCommentArgs,
 		envFileArgs,
 	}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -89,6 +90,161 @@ func TestRun_AddsEnvVarFile(t *testing.T) {
 	Equals(t, "output", output)
 }
 
+func TestRun_PlanPresetArgs(t *testing.T) {
+	// Test that the args of the selected plan preset come before the
+	// comment's args.
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	commitStatusUpdater := runtimemocks.NewMockStatusUpdater()
+	asyncTfExec := runtimemocks.NewMockAsyncTFExec()
+
+	tmpDir := t.TempDir()
+	tfVersion, _ := version.NewVersion("0.12.0")
+	s := runtime.NewPlanStepRunner(terraform, tfVersion, commitStatusUpdater, asyncTfExec)
+
+	expPlanArgs := []string{"plan",
+		"-input=false",
+		"-refresh",
+		"-out",
+		fmt.Sprintf("%q", filepath.Join(tmpDir, "default.tfplan")),
+		"extra",
+		"-refresh=false",
+		"-lock-timeout=10m",
+		"comment",
+	}
+	ctx := command.ProjectContext{
+		Log:                logging.NewNoopLogger(t),
+		Workspace:          "default",
+		RepoRelDir:         ".",
+		PlanPresetArgs:     []string{"-refresh=false", "-lock-timeout=10m"},
+		EscapedCommentArgs: []string{"comment"},
+	}
+	When(terraform.RunCommandWithVersion(ctx, tmpDir, expPlanArgs, map[string]string(nil), tfVersion, "default")).ThenReturn("output", nil)
+
+	output, err := s.Run(ctx, []string{"extra"}, tmpDir, map[string]string(nil))
+	Ok(t, err)
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(ctx, tmpDir, expPlanArgs, map[string]string(nil), tfVersion, "default")
+	Equals(t, "output", output)
+}
+
+func TestRun_PlanParallelism(t *testing.T) {
+	cases := []struct {
+		description string
+		extraArgs   []string
+		commentArgs []string
+		expArgs     []string
+	}{
+		{
+			description: "project parallelism",
+			expArgs:     []string{"-parallelism=20"},
+		},
+		{
+			description: "overridden by extra args",
+			extraArgs:   []string{"-parallelism=5"},
+			expArgs:     []string{"-parallelism=5"},
+		},
+		{
+			description: "overridden by comment args",
+			commentArgs: []string{"--parallelism=2"},
+			expArgs:     []string{"--parallelism=2"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			terraform := mocks.NewMockClient()
+			tmpDir := t.TempDir()
+			tfVersion, _ := version.NewVersion("0.12.0")
+			s := runtime.NewPlanStepRunner(terraform, tfVersion, runtimemocks.NewMockStatusUpdater(), runtimemocks.NewMockAsyncTFExec())
+
+			expPlanArgs := append([]string{"plan",
+				"-input=false",
+				"-refresh",
+				"-out",
+				fmt.Sprintf("%q", filepath.Join(tmpDir, "default.tfplan")),
+			}, c.expArgs...)
+			ctx := command.ProjectContext{
+				Log:                  logging.NewNoopLogger(t),
+				Workspace:            "default",
+				RepoRelDir:           ".",
+				EscapedCommentArgs:   c.commentArgs,
+				TerraformParallelism: 20,
+			}
+			When(terraform.RunCommandWithVersion(ctx, tmpDir, expPlanArgs, map[string]string(nil), tfVersion, "default")).ThenReturn("output", nil)
+
+			_, err := s.Run(ctx, c.extraArgs, tmpDir, map[string]string(nil))
+			Ok(t, err)
+			terraform.VerifyWasCalledOnce().RunCommandWithVersion(ctx, tmpDir, expPlanArgs, map[string]string(nil), tfVersion, "default")
+		})
+	}
+}
+
+func TestRun_PlanPRDescriptionVars(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	tmpDir := t.TempDir()
+	tfVersion, _ := version.NewVersion("0.12.0")
+	s := runtime.NewPlanStepRunner(terraform, tfVersion, runtimemocks.NewMockStatusUpdater(), runtimemocks.NewMockAsyncTFExec())
+
+	// The workflow's extra args come after the description's vars so they
+	// take precedence.
+	expPlanArgs := []string{"plan",
+		"-input=false",
+		"-refresh",
+		"-out",
+		fmt.Sprintf("%q", filepath.Join(tmpDir, "default.tfplan")),
+		"-var",
+		`\s\i\z\e\=\3`,
+		"-var",
+		"size=2",
+	}
+	ctx := command.ProjectContext{
+		Log:                  logging.NewNoopLogger(t),
+		Workspace:            "default",
+		RepoRelDir:           ".",
+		PRDescriptionVarArgs: []string{"-var", `\s\i\z\e\=\3`},
+	}
+	When(terraform.RunCommandWithVersion(ctx, tmpDir, expPlanArgs, map[string]string(nil), tfVersion, "default")).ThenReturn("output", nil)
+
+	_, err := s.Run(ctx, []string{"-var", "size=2"}, tmpDir, map[string]string(nil))
+	Ok(t, err)
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(ctx, tmpDir, expPlanArgs, map[string]string(nil), tfVersion, "default")
+}
+
+func TestRun_PlanWarningsAsErrors(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	tmpDir := t.TempDir()
+	planFile := filepath.Join(tmpDir, "default.tfplan")
+	tfVersion, _ := version.NewVersion("1.5.0")
+	s := runtime.NewPlanStepRunner(terraform, tfVersion, runtimemocks.NewMockStatusUpdater(), runtimemocks.NewMockAsyncTFExec())
+	ctx := command.ProjectContext{
+		Log:                  logging.NewNoopLogger(t),
+		Workspace:            "default",
+		RepoRelDir:           ".",
+		PlanWarningsAsErrors: []*regexp.Regexp{regexp.MustCompile("deprecated")},
+	}
+	output := "Plan: 1 to add, 0 to change, 0 to destroy.\n╷\n│ Warning: Argument is deprecated\n│ \n│ Use the aws_s3_bucket_acl resource instead\n╵\n"
+	When(terraform.RunCommandWithVersion(Any[command.ProjectContext](), Any[string](), Any[[]string](), Any[map[string]string](), Any[*version.Version](), Any[string]())).
+		Then(func(params []Param) ReturnValues {
+			Ok(t, os.WriteFile(planFile, nil, 0600))
+			return []ReturnValue{output, nil}
+		})
+
+	actOutput, err := s.Run(ctx, nil, tmpDir, map[string]string(nil))
+	ErrEquals(t, "plan has warnings that are treated as errors by plan_warnings_as_errors:\n\nWarning: Argument is deprecated\n\nUse the aws_s3_bucket_acl resource instead", err)
+	Equals(t, output, actOutput)
+	_, err = os.Stat(planFile)
+	Assert(t, os.IsNotExist(err), "exp planfile of failed plan to be deleted")
+
+	// Warnings that don't match are left alone.
+	ctx.PlanWarningsAsErrors = []*regexp.Regexp{regexp.MustCompile("Interpolation-only")}
+	_, err = s.Run(ctx, nil, tmpDir, map[string]string(nil))
+	Ok(t, err)
+	_, err = os.Stat(planFile)
+	Ok(t, err)
+}
+
 func TestRun_UsesDiffPathForProject(t *testing.T) {
 	// Test that if running for a project, uses a different path for the plan
 	// file.
@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// warningPrefix starts the summary line of each warning Terraform prints.
+const warningPrefix = "Warning: "
+
+// warningBoxLine starts each line of the box Terraform >= 0.15 draws around
+// warnings and errors, even with -no-color.
+const warningBoxLine = "│"
+
+// planWarnings returns the warnings in the output of terraform plan. Boxed
+// warnings include their details, ex. the deprecated attribute and where it's
+// used, while older unboxed warnings only include their summary line.
+func planWarnings(output string) []string {
+	var warnings []string
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		boxed := strings.HasPrefix(line, warningBoxLine)
+		summary := strings.TrimSpace(strings.TrimPrefix(line, warningBoxLine))
+		if !strings.HasPrefix(summary, warningPrefix) {
+			continue
+		}
+		warning := []string{summary}
+		for _, detail := range lines[i+1:] {
+			if !boxed || !strings.HasPrefix(detail, warningBoxLine) {
+				break
+			}
+			warning = append(warning, strings.TrimSpace(strings.TrimPrefix(detail, warningBoxLine)))
+		}
+		warnings = append(warnings, strings.TrimSpace(strings.Join(warning, "\n")))
+	}
+	return warnings
+}
+
+// matchingPlanWarnings returns the warnings in output matched by any of
+// patterns.
+func matchingPlanWarnings(output string, patterns []*regexp.Regexp) []string {
+	var matching []string
+	for _, warning := range planWarnings(output) {
+		for _, pattern := range patterns {
+			if pattern.MatchString(warning) {
+				matching = append(matching, warning)
+				break
+			}
+		}
+	}
+	return matching
+}
+
+// failOnPlanWarnings fails the plan that wrote planFile with output if any of
+// its warnings match the project's plan_warnings_as_errors. The planfile is
+// deleted so the plan can't be applied.
+func failOnPlanWarnings(patterns []*regexp.Regexp, planFile string, output string) error {
+	warnings := matchingPlanWarnings(output, patterns)
+	if len(warnings) == 0 {
+		return nil
+	}
+	if err := os.Remove(planFile); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "deleting planfile of plan with warnings treated as errors")
+	}
+	return fmt.Errorf("plan has warnings that are treated as errors by plan_warnings_as_errors:\n\n%s", strings.Join(warnings, "\n\n"))
+}
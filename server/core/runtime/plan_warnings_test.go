@@ -0,0 +1,102 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+const boxedWarningsOutput = `
+No changes. Your infrastructure matches the configuration.
+
+╷
+│ Warning: Argument is deprecated
+│ 
+│   with aws_s3_bucket.logs,
+│   on main.tf line 3, in resource "aws_s3_bucket" "logs":
+│    3:   acl    = "private"
+│ 
+│ Use the aws_s3_bucket_acl resource instead
+╵
+╷
+│ Warning: Version constraints inside provider configuration blocks are deprecated
+│ 
+│   on providers.tf line 2, in provider "aws":
+╵
+`
+
+func TestPlanWarnings(t *testing.T) {
+	cases := []struct {
+		description string
+		output      string
+		exp         []string
+	}{
+		{
+			description: "no warnings",
+			output:      "No changes. Your infrastructure matches the configuration.",
+		},
+		{
+			description: "boxed warnings",
+			output:      boxedWarningsOutput,
+			exp: []string{
+				"Warning: Argument is deprecated\n\nwith aws_s3_bucket.logs,\non main.tf line 3, in resource \"aws_s3_bucket\" \"logs\":\n3:   acl    = \"private\"\n\nUse the aws_s3_bucket_acl resource instead",
+				"Warning: Version constraints inside provider configuration blocks are deprecated\n\non providers.tf line 2, in provider \"aws\":",
+			},
+		},
+		{
+			description: "unboxed warnings",
+			output:      "Plan: 1 to add, 0 to change, 0 to destroy.\n\nWarning: Interpolation-only expressions are deprecated\n\n  on main.tf line 4\n",
+			exp:         []string{"Warning: Interpolation-only expressions are deprecated"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			Equals(t, c.exp, planWarnings(c.output))
+		})
+	}
+}
+
+func TestFailOnPlanWarnings(t *testing.T) {
+	cases := []struct {
+		description string
+		patterns    []*regexp.Regexp
+		expErr      string
+	}{
+		{
+			description: "no patterns",
+		},
+		{
+			description: "no matching warnings",
+			patterns:    []*regexp.Regexp{regexp.MustCompile("Interpolation-only")},
+		},
+		{
+			description: "matches summary",
+			patterns:    []*regexp.Regexp{regexp.MustCompile("(?i)provider configuration blocks are deprecated")},
+			expErr:      "plan has warnings that are treated as errors by plan_warnings_as_errors:\n\nWarning: Version constraints inside provider configuration blocks are deprecated\n\non providers.tf line 2, in provider \"aws\":",
+		},
+		{
+			description: "matches details",
+			patterns:    []*regexp.Regexp{regexp.MustCompile("Interpolation-only"), regexp.MustCompile("aws_s3_bucket_acl")},
+			expErr:      "plan has warnings that are treated as errors by plan_warnings_as_errors:\n\nWarning: Argument is deprecated\n\nwith aws_s3_bucket.logs,\non main.tf line 3, in resource \"aws_s3_bucket\" \"logs\":\n3:   acl    = \"private\"\n\nUse the aws_s3_bucket_acl resource instead",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			planFile := filepath.Join(t.TempDir(), "default.tfplan")
+			Ok(t, os.WriteFile(planFile, nil, 0600))
+
+			err := failOnPlanWarnings(c.patterns, planFile, boxedWarningsOutput)
+			_, statErr := os.Stat(planFile)
+			if c.expErr == "" {
+				Ok(t, err)
+				Ok(t, statErr)
+				return
+			}
+			ErrEquals(t, c.expErr, err)
+			Assert(t, os.IsNotExist(statErr), "exp planfile to be deleted")
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// noOpAction is the only action in the change of a resource that the plan
+// doesn't change.
+const noOpAction = "no-op"
+
+// writeChangedResourcesPlan writes the plan JSON in showFile to outFile
+// without the resources the plan doesn't change, so policies are only
+// evaluated against changed resources.
+func writeChangedResourcesPlan(showFile string, outFile string) error {
+	planJSON, err := os.ReadFile(showFile)
+	if err != nil {
+		return errors.Wrap(err, "reading plan")
+	}
+	filtered, err := filterChangedResources(planJSON)
+	if err != nil {
+		return errors.Wrap(err, "filtering plan")
+	}
+	return errors.Wrap(os.WriteFile(outFile, filtered, 0600), "writing filtered plan")
+}
+
+// filterChangedResources removes the resources whose change is a no-op from
+// the resource_changes and planned_values of planJSON, the output of
+// terraform show -json. The rest of the plan is left as is.
+func filterChangedResources(planJSON []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(planJSON))
+	// Keep numbers as they are instead of converting them to floats.
+	decoder.UseNumber()
+	var plan map[string]interface{}
+	if err := decoder.Decode(&plan); err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]bool)
+	var resourceChanges []interface{}
+	rawChanges, _ := plan["resource_changes"].([]interface{})
+	for _, rawChange := range rawChanges {
+		resourceChange, _ := rawChange.(map[string]interface{})
+		if isNoOp(resourceChange) {
+			continue
+		}
+		address, _ := resourceChange["address"].(string)
+		changed[address] = true
+		resourceChanges = append(resourceChanges, rawChange)
+	}
+	if _, ok := plan["resource_changes"]; ok {
+		if resourceChanges == nil {
+			resourceChanges = []interface{}{}
+		}
+		plan["resource_changes"] = resourceChanges
+	}
+
+	if plannedValues, ok := plan["planned_values"].(map[string]interface{}); ok {
+		if rootModule, ok := plannedValues["root_module"].(map[string]interface{}); ok {
+			filterModuleResources(rootModule, changed)
+		}
+	}
+
+	return json.Marshal(plan)
+}
+
+// isNoOp returns true if resourceChange doesn't change its resource.
+func isNoOp(resourceChange map[string]interface{}) bool {
+	change, _ := resourceChange["change"].(map[string]interface{})
+	actions, _ := change["actions"].([]interface{})
+	return len(actions) == 1 && actions[0] == noOpAction
+}
+
+// filterModuleResources removes the resources of module and its child modules
+// whose addresses aren't in changed.
+func filterModuleResources(module map[string]interface{}, changed map[string]bool) {
+	if resources, ok := module["resources"].([]interface{}); ok {
+		kept := []interface{}{}
+		for _, rawResource := range resources {
+			resource, _ := rawResource.(map[string]interface{})
+			address, _ := resource["address"].(string)
+			if changed[address] {
+				kept = append(kept, rawResource)
+			}
+		}
+		module["resources"] = kept
+	}
+	childModules, _ := module["child_modules"].([]interface{})
+	for _, rawChild := range childModules {
+		if child, ok := rawChild.(map[string]interface{}); ok {
+			filterModuleResources(child, changed)
+		}
+	}
+}
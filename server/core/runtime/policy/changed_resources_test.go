@@ -0,0 +1,69 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+const planWithNoOps = `{
+  "format_version": "1.2",
+  "planned_values": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_s3_bucket.changed", "values": {"bucket": "changed"}},
+        {"address": "aws_s3_bucket.unchanged", "values": {"bucket": "unchanged"}}
+      ],
+      "child_modules": [
+        {
+          "address": "module.child",
+          "resources": [
+            {"address": "module.child.aws_instance.unchanged", "values": {"count": 10000000000}}
+          ]
+        }
+      ]
+    }
+  },
+  "resource_changes": [
+    {"address": "aws_s3_bucket.changed", "change": {"actions": ["delete", "create"]}},
+    {"address": "aws_s3_bucket.unchanged", "change": {"actions": ["no-op"]}},
+    {"address": "module.child.aws_instance.unchanged", "change": {"actions": ["no-op"]}}
+  ]
+}`
+
+func TestFilterChangedResources(t *testing.T) {
+	filtered, err := filterChangedResources([]byte(planWithNoOps))
+	Ok(t, err)
+	exp := `{"format_version":"1.2",` +
+		`"planned_values":{"root_module":{"child_modules":[{"address":"module.child","resources":[]}],` +
+		`"resources":[{"address":"aws_s3_bucket.changed","values":{"bucket":"changed"}}]}},` +
+		`"resource_changes":[{"address":"aws_s3_bucket.changed","change":{"actions":["delete","create"]}}]}`
+	Equals(t, exp, string(filtered))
+}
+
+func TestFilterChangedResources_NoChanges(t *testing.T) {
+	filtered, err := filterChangedResources([]byte(`{"resource_changes":[{"address":"a.b","change":{"actions":["no-op"]}}]}`))
+	Ok(t, err)
+	Equals(t, `{"resource_changes":[]}`, string(filtered))
+}
+
+func TestFilterChangedResources_InvalidJSON(t *testing.T) {
+	_, err := filterChangedResources([]byte("not json"))
+	Assert(t, err != nil, "expected an error")
+}
+
+func TestWriteChangedResourcesPlan(t *testing.T) {
+	dir := t.TempDir()
+	showFile := filepath.Join(dir, "default.json")
+	outFile := filepath.Join(dir, "default-changed.json")
+	Ok(t, os.WriteFile(showFile, []byte(`{"resource_changes":[]}`), 0600))
+
+	Ok(t, writeChangedResourcesPlan(showFile, outFile))
+	out, err := os.ReadFile(outFile)
+	Ok(t, err)
+	Equals(t, `{"resource_changes":[]}`, string(out))
+
+	ErrContains(t, "reading plan", writeChangedResourcesPlan(filepath.Join(dir, "missing.json"), outFile))
+}
@@ -47,8 +47,16 @@ func NewPolicyArg(parameter string) Arg {
 	}
 }
 
+func NewDataArg(parameter string) Arg {
+	return Arg{
+		Param:  parameter,
+		Option: "--data",
+	}
+}
+
 type ConftestTestCommandArgs struct {
 	PolicyArgs []Arg
+	DataArgs   []Arg
 	ExtraArgs  []string
 	InputFile  string
 	Command    string
@@ -67,6 +75,10 @@ func (c ConftestTestCommandArgs) build() ([]string, error) {
 		commandArgs = append(commandArgs, a.build()...)
 	}
 
+	for _, a := range c.DataArgs {
+		commandArgs = append(commandArgs, a.build()...)
+	}
+
 	// add hardcoded options
 	commandArgs = append(commandArgs, c.InputFile, "--no-color")
 
@@ -171,6 +183,13 @@ func (c *ConfTestExecutorWorkflow) Run(ctx command.ProjectContext, executablePat
 	ctx.Log.Debug("policy sets, %s ", ctx.PolicySets)
 
 	inputFile := filepath.Join(workdir, ctx.GetShowResultFileName())
+	if ctx.PolicySets.ChangedResourcesOnly {
+		changedResourcesFile := filepath.Join(workdir, ctx.GetChangedResourcesShowFileName())
+		if err := writeChangedResourcesPlan(inputFile, changedResourcesFile); err != nil {
+			return "", errors.Wrap(err, "scoping policy check to changed resources")
+		}
+		inputFile = changedResourcesFile
+	}
 	var policySetResults []models.PolicySetResult
 	var combinedErr error
 
@@ -183,8 +202,37 @@ func (c *ConfTestExecutorWorkflow) Run(ctx command.ProjectContext, executablePat
 			continue
 		}
 
+		// A missing data dir fails the policy set since its policies can't
+		// be evaluated correctly without their data.
+		var dataArgs []Arg
+		var missingDataDir string
+		for _, dir := range policySet.DataDirs {
+			// Relative dirs are resolved against the policy set rather
+			// than the project so pull requests can't change the data.
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(path, dir)
+			}
+			if _, err := os.Stat(dir); err != nil {
+				missingDataDir = dir
+				break
+			}
+			dataArgs = append(dataArgs, NewDataArg(dir))
+		}
+		if missingDataDir != "" {
+			failure := fmt.Sprintf("data dir %q does not exist", missingDataDir)
+			combinedErr = multierror.Append(combinedErr, fmt.Errorf("policy_set: %s: %s", policySet.Name, failure))
+			policySetResults = append(policySetResults, models.PolicySetResult{
+				PolicySetName: policySet.Name,
+				PolicyOutput:  failure,
+				Passed:        false,
+				ReqApprovals:  policySet.ApproveCount,
+			})
+			continue
+		}
+
 		args := ConftestTestCommandArgs{
 			PolicyArgs: []Arg{NewPolicyArg(path)},
+			DataArgs:   dataArgs,
 			ExtraArgs:  extraArgs,
 			InputFile:  inputFile,
 			Command:    executablePath,
@@ -3,6 +3,7 @@ package policy
 import (
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
@@ -323,4 +324,97 @@ func TestRun(t *testing.T) {
 		Assert(t, err != nil, "error is expected")
 
 	})
+
+	t.Run("data dirs", func(t *testing.T) {
+		var extraArgs []string
+		absDataDir := t.TempDir()
+		resolvedPath := t.TempDir()
+		Ok(t, os.MkdirAll(filepath.Join(resolvedPath, "data"), 0700))
+		// Data dirs in the project's dir aren't used.
+		Ok(t, os.MkdirAll(filepath.Join(workdir, "data"), 0700))
+		policySet := valid.PolicySet{
+			Source:   valid.LocalPolicySet,
+			Path:     policySetPath1,
+			Name:     "data-policy",
+			DataDirs: []string{absDataDir, "data"},
+		}
+		dataCtx := ctx
+		dataCtx.PolicySets = valid.PolicySets{PolicySets: []valid.PolicySet{policySet}}
+
+		expectedResult := `[{"PolicySetName":"data-policy","PolicyOutput":"Success","Passed":true,"ReqApprovals":0,"CurApprovals":0}]`
+		expectedArgs := []string{executablePath, "test", "-p", resolvedPath, "--data", absDataDir, "--data", filepath.Join(resolvedPath, "data"), filepath.Join(workdir, "testproj-default.json"), "--no-color"}
+
+		When(mockResolver.Resolve(policySet)).ThenReturn(resolvedPath, nil)
+		When(mockExec.CombinedOutput(expectedArgs, envs, workdir)).ThenReturn("Success", nil)
+
+		result, err := subject.Run(dataCtx, executablePath, envs, workdir, extraArgs)
+
+		Ok(t, err)
+		Equals(t, expectedResult, result)
+		mockExec.VerifyWasCalledOnce().CombinedOutput(expectedArgs, envs, workdir)
+	})
+
+	t.Run("missing data dir", func(t *testing.T) {
+		var extraArgs []string
+		missingDir := filepath.Join(localPolicySetPath1, "missing")
+		policySet := valid.PolicySet{
+			Source:   valid.LocalPolicySet,
+			Path:     policySetPath1,
+			Name:     "missing-data-policy",
+			DataDirs: []string{"missing"},
+		}
+		dataCtx := ctx
+		dataCtx.PolicySets = valid.PolicySets{PolicySets: []valid.PolicySet{policySet}}
+
+		expectedResult := fmt.Sprintf(`[{"PolicySetName":"missing-data-policy","PolicyOutput":"data dir \"%s\" does not exist","Passed":false,"ReqApprovals":0,"CurApprovals":0}]`, missingDir)
+
+		When(mockResolver.Resolve(policySet)).ThenReturn(localPolicySetPath1, nil)
+
+		result, err := subject.Run(dataCtx, executablePath, envs, workdir, extraArgs)
+
+		Equals(t, expectedResult, result)
+		ErrContains(t, fmt.Sprintf("policy_set: missing-data-policy: data dir %q does not exist", missingDir), err)
+	})
+
+	t.Run("changed resources only", func(t *testing.T) {
+		var extraArgs []string
+
+		scopedCtx := ctx
+		scopedCtx.PolicySets.ChangedResourcesOnly = true
+		Ok(t, os.WriteFile(filepath.Join(workdir, "testproj-default.json"), []byte(planWithNoOps), 0600))
+
+		changedFile := filepath.Join(workdir, "testproj-default-changed.json")
+		expectedOutput := fmt.Sprintf("FAIL - %s - failure\n1 tests, 0 passed, 0 warnings, 1 failure, 0 exceptions", changedFile)
+		expectedResult := `[{"PolicySetName":"policy1","PolicyOutput":"FAIL - <redacted plan file> - failure\n1 tests, 0 passed, 0 warnings, 1 failure, 0 exceptions","Passed":false,"ReqApprovals":0,"CurApprovals":0},{"PolicySetName":"policy2","PolicyOutput":"Success","Passed":true,"ReqApprovals":0,"CurApprovals":0}]`
+
+		expectedArgsPolicy1 := []string{executablePath, "test", "-p", localPolicySetPath1, changedFile, "--no-color"}
+		expectedArgsPolicy2 := []string{executablePath, "test", "-p", localPolicySetPath2, changedFile, "--no-color"}
+
+		When(mockResolver.Resolve(policySet1)).ThenReturn(localPolicySetPath1, nil)
+		When(mockResolver.Resolve(policySet2)).ThenReturn(localPolicySetPath2, nil)
+
+		When(mockExec.CombinedOutput(expectedArgsPolicy1, envs, workdir)).ThenReturn(expectedOutput, errors.New("exit status code 1"))
+		When(mockExec.CombinedOutput(expectedArgsPolicy2, envs, workdir)).ThenReturn("Success", nil)
+
+		result, err := subject.Run(scopedCtx, executablePath, envs, workdir, extraArgs)
+
+		Equals(t, expectedResult, result)
+		Assert(t, err != nil, "error is expected")
+
+		// Policies are evaluated against the plan without the unchanged resources.
+		changed, err := os.ReadFile(changedFile)
+		Ok(t, err)
+		filtered, err := filterChangedResources([]byte(planWithNoOps))
+		Ok(t, err)
+		Equals(t, string(filtered), string(changed))
+	})
+
+	t.Run("changed resources only without a plan", func(t *testing.T) {
+		scopedCtx := ctx
+		scopedCtx.PolicySets.ChangedResourcesOnly = true
+		scopedCtx.Workspace = "missing"
+
+		_, err := subject.Run(scopedCtx, executablePath, envs, workdir, nil)
+		ErrContains(t, "scoping policy check to changed resources: reading plan", err)
+	})
 }
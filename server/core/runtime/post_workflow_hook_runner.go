@@ -23,11 +23,7 @@ type DefaultPostWorkflowHookRunner struct {
 func (wh DefaultPostWorkflowHookRunner) Run(ctx models.WorkflowHookCommandContext, command string, shell string, shellArgs string, path string) (string, string, error) {
 	outputFilePath := filepath.Join(path, "OUTPUT_STATUS_FILE")
 
-	shellArgsSlice := append(strings.Split(shellArgs, " "), command)
-	cmd := exec.Command(shell, shellArgsSlice...) // #nosec
-	cmd.Dir = path
-
-	baseEnvVars := os.Environ()
+	baseEnvVars := hookBaseEnv(ctx)
 	customEnvVars := map[string]string{
 		"BASE_BRANCH_NAME":   ctx.Pull.BaseBranch,
 		"BASE_REPO_NAME":     ctx.BaseRepo.Name,
@@ -45,6 +41,28 @@ func (wh DefaultPostWorkflowHookRunner) Run(ctx models.WorkflowHookCommandContex
 		"OUTPUT_STATUS_FILE": outputFilePath,
 		"COMMAND_NAME":       ctx.CommandName,
 	}
+	addHeadCommitEnvVars(customEnvVars, ctx.HeadCommit)
+	// Project-scoped hooks also get the project they're running for.
+	if ctx.RepoRelDir != "" {
+		customEnvVars["PROJECT_NAME"] = ctx.ProjectName
+		customEnvVars["WORKSPACE"] = ctx.Workspace
+		customEnvVars["REPO_REL_DIR"] = ctx.RepoRelDir
+	}
+
+	shellArgsSlice := append(strings.Split(shellArgs, " "), command)
+	cmd := exec.Command(shell, shellArgsSlice...) // #nosec
+	if ctx.Image != "" {
+		var envVarNames []string
+		for key := range customEnvVars {
+			envVarNames = append(envVarNames, key)
+		}
+		var err error
+		if cmd, err = containerHookCommand(ctx.Image, path, envVarNames, shell, shellArgsSlice); err != nil {
+			ctx.Log.Debug("error: %s", err)
+			return "", "", err
+		}
+	}
+	cmd.Dir = path
 
 	finalEnvVars := baseEnvVars
 	for key, val := range customEnvVars {
@@ -59,7 +77,7 @@ func (wh DefaultPostWorkflowHookRunner) Run(ctx models.WorkflowHookCommandContex
 	wh.OutputHandler.SendWorkflowHook(ctx, "\n", true)
 
 	if err != nil {
-		err = fmt.Errorf("%s: running %q in %q: \n%s", err, shell+" "+shellArgs+" "+command, path, out)
+		err = fmt.Errorf("%w: running %q in %q: \n%s", err, shell+" "+shellArgs+" "+command, path, out)
 		ctx.Log.Debug("error: %s", err)
 		return string(out), "", err
 	}
@@ -1,6 +1,7 @@
 package runtime
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"os/exec"
@@ -18,16 +19,45 @@ type PreWorkflowHookRunner interface {
 
 type DefaultPreWorkflowHookRunner struct {
 	OutputHandler jobs.ProjectCommandOutputHandler
+	// MaxOutputBytes is the most output of a hook that's kept. Further output
+	// is discarded so that a noisy hook can't use up all the memory.
+	// If 0, all output is kept.
+	MaxOutputBytes int
+}
+
+// hookOutputTruncatedMarker is appended to hook output that was truncated.
+const hookOutputTruncatedMarker = "\n... output truncated after %d bytes ...\n"
+
+// limitedBuffer is a buffer that keeps at most max bytes and discards the
+// rest. If max is 0 it keeps everything.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+// Write always reports writing all of p so the command isn't interrupted.
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.max > 0 && b.buf.Len()+len(p) > b.max {
+		b.truncated = true
+		b.buf.Write(p[:b.max-b.buf.Len()])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// Bytes returns the output, with a marker if it was truncated.
+func (b *limitedBuffer) Bytes() []byte {
+	if b.truncated {
+		return append(b.buf.Bytes(), fmt.Sprintf(hookOutputTruncatedMarker, b.max)...)
+	}
+	return b.buf.Bytes()
 }
 
 func (wh DefaultPreWorkflowHookRunner) Run(ctx models.WorkflowHookCommandContext, command string, shell string, shellArgs string, path string) (string, string, error) {
 	outputFilePath := filepath.Join(path, "OUTPUT_STATUS_FILE")
 
-	shellArgsSlice := append(strings.Split(shellArgs, " "), command)
-	cmd := exec.Command(shell, shellArgsSlice...) // #nosec
-	cmd.Dir = path
-
-	baseEnvVars := os.Environ()
+	baseEnvVars := hookBaseEnv(ctx)
 	customEnvVars := map[string]string{
 		"BASE_BRANCH_NAME":   ctx.Pull.BaseBranch,
 		"BASE_REPO_NAME":     ctx.BaseRepo.Name,
@@ -44,7 +74,24 @@ func (wh DefaultPreWorkflowHookRunner) Run(ctx models.WorkflowHookCommandContext
 		"USER_NAME":          ctx.User.Username,
 		"OUTPUT_STATUS_FILE": outputFilePath,
 		"COMMAND_NAME":       ctx.CommandName,
+		"PLANNED_PROJECTS":   strings.Join(ctx.PlannedProjects, ","),
+	}
+	addHeadCommitEnvVars(customEnvVars, ctx.HeadCommit)
+
+	shellArgsSlice := append(strings.Split(shellArgs, " "), command)
+	cmd := exec.Command(shell, shellArgsSlice...) // #nosec
+	if ctx.Image != "" {
+		var envVarNames []string
+		for key := range customEnvVars {
+			envVarNames = append(envVarNames, key)
+		}
+		var err error
+		if cmd, err = containerHookCommand(ctx.Image, path, envVarNames, shell, shellArgsSlice); err != nil {
+			ctx.Log.Debug("error: %s", err)
+			return "", "", err
+		}
 	}
+	cmd.Dir = path
 
 	finalEnvVars := baseEnvVars
 	for key, val := range customEnvVars {
@@ -52,15 +99,25 @@ func (wh DefaultPreWorkflowHookRunner) Run(ctx models.WorkflowHookCommandContext
 	}
 
 	cmd.Env = finalEnvVars
-	out, err := cmd.CombinedOutput()
+	output := &limitedBuffer{max: wh.MaxOutputBytes}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	err := cmd.Run()
+	out := output.Bytes()
+	if output.truncated {
+		ctx.Log.Warn("pre workflow hook output exceeded %d bytes, the rest was discarded", wh.MaxOutputBytes)
+	}
 
 	outString := strings.ReplaceAll(string(out), "\n", "\r\n")
 	wh.OutputHandler.SendWorkflowHook(ctx, outString, false)
 	wh.OutputHandler.SendWorkflowHook(ctx, "\n", true)
 
 	if err != nil {
-		err = fmt.Errorf("%s: running %q in %q: \n%s", err, shell+" "+shellArgs+" "+command, path, out)
+		err = fmt.Errorf("%w: running %q in %q: \n%s", err, shell+" "+shellArgs+" "+command, path, out)
 		ctx.Log.Debug("error: %s", err)
+		if output.truncated {
+			return string(out), "output truncated.", err
+		}
 		return string(out), "", err
 	}
 
@@ -78,5 +135,36 @@ func (wh DefaultPreWorkflowHookRunner) Run(ctx models.WorkflowHookCommandContext
 	}
 
 	ctx.Log.Info("successfully ran %q in %q", shell+" "+shellArgs+" "+command, path)
-	return string(out), strings.Trim(string(customStatusOut), "\n"), nil
+	description := strings.Trim(string(customStatusOut), "\n")
+	if output.truncated {
+		if description == "" {
+			description = "succeeded, output truncated."
+		} else {
+			description += " (output truncated)"
+		}
+	}
+	return string(out), description, nil
+}
+
+// hookBaseEnv returns the server's env vars that a workflow hook inherits. If
+// ctx.CleanEnv is set, only those named in ctx.AllowedEnvVars are inherited.
+func hookBaseEnv(ctx models.WorkflowHookCommandContext) []string {
+	if !ctx.CleanEnv {
+		return os.Environ()
+	}
+	var env []string
+	for _, name := range ctx.AllowedEnvVars {
+		if val, ok := os.LookupEnv(name); ok {
+			env = append(env, fmt.Sprintf("%s=%s", name, val))
+		}
+	}
+	return env
+}
+
+// addHeadCommitEnvVars adds the metadata of the pull request's head commit to
+// the env vars of a workflow hook.
+func addHeadCommitEnvVars(envVars map[string]string, headCommit models.CommitMetadata) {
+	envVars["HEAD_COMMIT_AUTHOR_EMAIL"] = headCommit.AuthorEmail
+	envVars["HEAD_COMMIT_AUTHOR_NAME"] = headCommit.AuthorName
+	envVars["HEAD_COMMIT_MESSAGE"] = headCommit.Message
 }
@@ -2,6 +2,8 @@ package runtime_test
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	goruntime "runtime"
 	"strings"
 	"testing"
@@ -122,6 +124,22 @@ func TestPreWorkflowHookRunner_Run(t *testing.T) {
 			ExpErr:         "",
 			ExpDescription: "",
 		},
+		{
+			Command:        "echo author=\"$HEAD_COMMIT_AUTHOR_NAME <$HEAD_COMMIT_AUTHOR_EMAIL>\" message=\"$HEAD_COMMIT_MESSAGE\"",
+			Shell:          defaultShell,
+			ShellArgs:      defaultShellArgs,
+			ExpOut:         "author=Acme <acme@example.com> message=Add feature\r\n",
+			ExpErr:         "",
+			ExpDescription: "",
+		},
+		{
+			Command:        "echo planned_projects=$PLANNED_PROJECTS",
+			Shell:          defaultShell,
+			ShellArgs:      defaultShellArgs,
+			ExpOut:         "planned_projects=staging,modules/vpc\r\n",
+			ExpErr:         "",
+			ExpDescription: "",
+		},
 		{
 			Command:        "echo something > $OUTPUT_STATUS_FILE",
 			Shell:          defaultShell,
@@ -194,8 +212,15 @@ func TestPreWorkflowHookRunner_Run(t *testing.T) {
 				User: models.User{
 					Username: "acme-user",
 				},
-				Log:         logger,
-				CommandName: "plan",
+				Log:             logger,
+				CommandName:     "plan",
+				PlannedProjects: []string{"staging", "modules/vpc"},
+				HeadCommit: models.CommitMetadata{
+					SHA:         "12345abcdef",
+					AuthorName:  "Acme",
+					AuthorEmail: "acme@example.com",
+					Message:     "Add feature",
+				},
 			}
 			_, desc, err := r.Run(ctx, c.Command, c.Shell, c.ShellArgs, tmpDir)
 			if c.ExpErr != "" {
@@ -213,3 +238,184 @@ func TestPreWorkflowHookRunner_Run(t *testing.T) {
 		})
 	}
 }
+
+func TestPreWorkflowHookRunner_MaxOutputBytes(t *testing.T) {
+	cases := []struct {
+		description    string
+		command        string
+		expOut         string
+		expErr         string
+		expDescription string
+	}{
+		{
+			description: "output below the limit",
+			command:     "printf 0123456789",
+			expOut:      "0123456789",
+		},
+		{
+			description:    "output above the limit",
+			command:        "printf 0123456789abcdef",
+			expOut:         "0123456789\r\n... output truncated after 10 bytes ...\r\n",
+			expDescription: "succeeded, output truncated.",
+		},
+		{
+			description:    "stderr counts towards the limit",
+			command:        "printf 01234 && printf 56789abcdef >&2",
+			expOut:         "0123456789\r\n... output truncated after 10 bytes ...\r\n",
+			expDescription: "succeeded, output truncated.",
+		},
+		{
+			description:    "output above the limit with custom status",
+			command:        "echo checked > $OUTPUT_STATUS_FILE && printf 0123456789abcdef",
+			expOut:         "0123456789\r\n... output truncated after 10 bytes ...\r\n",
+			expDescription: "checked (output truncated)",
+		},
+		{
+			description:    "output above the limit and failing",
+			command:        "printf 0123456789abcdef && exit 3",
+			expOut:         "0123456789\r\n... output truncated after 10 bytes ...\r\n",
+			expErr:         "exit status 3",
+			expDescription: "output truncated.",
+		},
+		{
+			description:    "very large output",
+			command:        "yes | head -c 10000000",
+			expOut:         "y\r\ny\r\ny\r\ny\r\ny\r\n\r\n... output truncated after 10 bytes ...\r\n",
+			expDescription: "succeeded, output truncated.",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			projectCmdOutputHandler := jobmocks.NewMockProjectCommandOutputHandler()
+			r := runtime.DefaultPreWorkflowHookRunner{
+				OutputHandler:  projectCmdOutputHandler,
+				MaxOutputBytes: 10,
+			}
+			ctx := models.WorkflowHookCommandContext{
+				Log:         logging.NewNoopLogger(t),
+				CommandName: "plan",
+			}
+			out, desc, err := r.Run(ctx, c.command, "sh", "-c", t.TempDir())
+			if c.expErr != "" {
+				ErrContains(t, c.expErr, err)
+			} else {
+				Ok(t, err)
+			}
+			Equals(t, c.expDescription, desc)
+			Equals(t, strings.ReplaceAll(c.expOut, "\r\n", "\n"), out)
+			projectCmdOutputHandler.VerifyWasCalledOnce().SendWorkflowHook(
+				Any[models.WorkflowHookCommandContext](), Eq(c.expOut), Eq(false))
+		})
+	}
+}
+
+func TestPreWorkflowHookRunner_CleanEnv(t *testing.T) {
+	t.Setenv("ATLANTIS_TEST_SECRET", "secret")
+	t.Setenv("ATLANTIS_TEST_ALLOWED", "allowed")
+	command := "echo secret=$ATLANTIS_TEST_SECRET allowed=$ATLANTIS_TEST_ALLOWED pull_num=$PULL_NUM"
+
+	cases := []struct {
+		description    string
+		cleanEnv       bool
+		allowedEnvVars []string
+		expOut         string
+	}{
+		{
+			description: "inherits the server's env",
+			expOut:      "secret=secret allowed=allowed pull_num=2\r\n",
+		},
+		{
+			description: "scrubbed env",
+			cleanEnv:    true,
+			expOut:      "secret= allowed= pull_num=2\r\n",
+		},
+		{
+			description:    "scrubbed env with allowed vars",
+			cleanEnv:       true,
+			allowedEnvVars: []string{"ATLANTIS_TEST_ALLOWED", "ATLANTIS_TEST_UNSET"},
+			expOut:         "secret= allowed=allowed pull_num=2\r\n",
+		},
+		{
+			description:    "allowed vars are ignored without clean env",
+			allowedEnvVars: []string{"ATLANTIS_TEST_ALLOWED"},
+			expOut:         "secret=secret allowed=allowed pull_num=2\r\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			projectCmdOutputHandler := jobmocks.NewMockProjectCommandOutputHandler()
+			r := runtime.DefaultPreWorkflowHookRunner{
+				OutputHandler: projectCmdOutputHandler,
+			}
+			ctx := models.WorkflowHookCommandContext{
+				Log:            logging.NewNoopLogger(t),
+				Pull:           models.PullRequest{Num: 2},
+				CommandName:    "plan",
+				CleanEnv:       c.cleanEnv,
+				AllowedEnvVars: c.allowedEnvVars,
+			}
+			_, _, err := r.Run(ctx, command, "sh", "-c", t.TempDir())
+			Ok(t, err)
+			projectCmdOutputHandler.VerifyWasCalledOnce().SendWorkflowHook(
+				Any[models.WorkflowHookCommandContext](), Eq(c.expOut), Eq(false))
+		})
+	}
+}
+
+func TestPreWorkflowHookRunner_Image(t *testing.T) {
+	envArgs := "--env\nBASE_BRANCH_NAME\n--env\nBASE_REPO_NAME\n--env\nBASE_REPO_OWNER\n--env\nCOMMAND_NAME\n--env\nCOMMENT_ARGS\n--env\nDIR\n--env\nHEAD_BRANCH_NAME\n--env\nHEAD_COMMIT\n--env\nHEAD_COMMIT_AUTHOR_EMAIL\n--env\nHEAD_COMMIT_AUTHOR_NAME\n--env\nHEAD_COMMIT_MESSAGE\n--env\nHEAD_REPO_NAME\n--env\nHEAD_REPO_OWNER\n--env\nOUTPUT_STATUS_FILE\n--env\nPLANNED_PROJECTS\n--env\nPULL_AUTHOR\n--env\nPULL_NUM\n--env\nPULL_URL\n--env\nUSER_NAME\n"
+	cases := []struct {
+		description string
+		runtimes    []string
+		expUserArgs string
+		expErr      string
+	}{
+		{
+			description: "docker",
+			runtimes:    []string{"docker", "podman"},
+			expUserArgs: fmt.Sprintf("--user\n%d:%d\n", os.Getuid(), os.Getgid()),
+		},
+		{
+			description: "podman",
+			runtimes:    []string{"podman"},
+			expUserArgs: "--userns=keep-id\n",
+		},
+		{
+			description: "no container runtime",
+			expErr:      `running hook in image "alpine:3": neither docker nor podman was found in $PATH`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			// The container runtimes print the arguments they were called with
+			// and an env var they were passed.
+			binDir := t.TempDir()
+			for _, r := range c.runtimes {
+				Ok(t, os.WriteFile(filepath.Join(binDir, r), []byte("#!/bin/sh\nprintf '%s\\n' \"$@\"\necho \"PULL_NUM=$PULL_NUM\"\n"), 0700)) // nolint: gosec
+			}
+			t.Setenv("PATH", binDir)
+
+			projectCmdOutputHandler := jobmocks.NewMockProjectCommandOutputHandler()
+			r := runtime.DefaultPreWorkflowHookRunner{
+				OutputHandler: projectCmdOutputHandler,
+			}
+			ctx := models.WorkflowHookCommandContext{
+				Log:         logging.NewNoopLogger(t),
+				CommandName: "plan",
+				Pull:        models.PullRequest{Num: 2},
+				Image:       "alpine:3",
+			}
+			dir := t.TempDir()
+			out, _, err := r.Run(ctx, "echo hi", "sh", "-c", dir)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, "run\n--rm\n--volume\n"+dir+":"+dir+"\n--workdir\n"+dir+"\n"+c.expUserArgs+envArgs+"alpine:3\nsh\n-c\necho hi\nPULL_NUM=2\n", out)
+		})
+	}
+}
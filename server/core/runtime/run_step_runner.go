@@ -28,16 +28,21 @@ func (r *RunStepRunner) Run(ctx command.ProjectContext, command string, path str
 		tfVersion = ctx.TerraformVersion
 	}
 
-	err := r.TerraformExecutor.EnsureVersion(ctx.Log, tfVersion)
-	if err != nil {
-		err = fmt.Errorf("%s: Downloading terraform Version %s", err, tfVersion.String())
-		ctx.Log.Debug("error: %s", err)
-		return "", err
+	// A repo's terraform binary is used instead of the one for the version,
+	// so the version doesn't need to be downloaded.
+	if ctx.TerraformBinary == "" {
+		err := r.TerraformExecutor.EnsureVersion(ctx.Log, tfVersion)
+		if err != nil {
+			err = fmt.Errorf("%s: Downloading terraform Version %s", err, tfVersion.String())
+			ctx.Log.Debug("error: %s", err)
+			return "", err
+		}
 	}
 
 	baseEnvVars := os.Environ()
 	customEnvVars := map[string]string{
 		"ATLANTIS_TERRAFORM_VERSION": tfVersion.String(),
+		"ATLANTIS_TERRAFORM_BINARY":  ctx.TerraformBinary,
 		"BASE_BRANCH_NAME":           ctx.Pull.BaseBranch,
 		"BASE_REPO_NAME":             ctx.BaseRepo.Name,
 		"BASE_REPO_OWNER":            ctx.BaseRepo.Owner,
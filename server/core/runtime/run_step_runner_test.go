@@ -163,3 +163,26 @@ func TestRunStepRunner_Run(t *testing.T) {
 		})
 	}
 }
+
+func TestRunStepRunner_RunTerraformBinary(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	defaultVersion, _ := version.NewVersion("0.8")
+	r := runtime.RunStepRunner{
+		TerraformExecutor:       terraform,
+		DefaultTFVersion:        defaultVersion,
+		TerraformBinDir:         "/bin/dir",
+		ProjectCmdOutputHandler: jobmocks.NewMockProjectCommandOutputHandler(),
+	}
+	ctx := command.ProjectContext{
+		Log:             logging.NewNoopLogger(t),
+		Workspace:       "default",
+		RepoRelDir:      ".",
+		TerraformBinary: "/opt/terraform/1.5.7/terraform",
+	}
+	out, err := r.Run(ctx, "echo $ATLANTIS_TERRAFORM_BINARY", t.TempDir(), nil, true, valid.PostProcessRunOutputShow)
+	Ok(t, err)
+	Equals(t, "/opt/terraform/1.5.7/terraform\n", out)
+	// The repo's binary is run so no version is downloaded.
+	terraform.VerifyWasCalled(Never()).EnsureVersion(Any[logging.SimpleLogging](), Any[*version.Version]())
+}
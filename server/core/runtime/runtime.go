@@ -96,6 +96,36 @@ func GetPlanFilename(workspace string, projName string) string {
 	return fmt.Sprintf("%s-%s.tfplan", projName, workspace)
 }
 
+// ParallelismArgs returns the -parallelism arg for ctx's project, unless it
+// doesn't set a parallelism or one of args, ex. the workflow's extra args,
+// already sets it.
+func ParallelismArgs(ctx command.ProjectContext, args ...[]string) []string {
+	if ctx.TerraformParallelism == 0 {
+		return nil
+	}
+	for _, a := range args {
+		for _, arg := range a {
+			if strings.HasPrefix(strings.TrimLeft(arg, "-"), "parallelism") {
+				return nil
+			}
+		}
+	}
+	return []string{fmt.Sprintf("-parallelism=%d", ctx.TerraformParallelism)}
+}
+
+// StateLockArgs returns the -lock and -lock-timeout args for the state lock
+// options the user applied ctx's project with.
+func StateLockArgs(ctx command.ProjectContext) []string {
+	var args []string
+	if ctx.SkipStateLock {
+		args = append(args, "-lock=false")
+	}
+	if ctx.StateLockTimeout != "" {
+		args = append(args, fmt.Sprintf("-lock-timeout=%s", ctx.StateLockTimeout))
+	}
+	return args
+}
+
 // isRemotePlan returns true if planContents are from a plan that was generated
 // using TFE remote operations.
 func IsRemotePlan(planContents []byte) bool {
@@ -49,7 +49,8 @@ func (p *showStepRunner) Run(ctx command.ProjectContext, extraArgs []string, pat
 		return "", errors.Wrap(err, "running terraform show")
 	}
 
-	if err := os.WriteFile(showResultFile, []byte(output), os.ModePerm); err != nil {
+	// The plan's JSON contains the same sensitive values as the planfile.
+	if err := os.WriteFile(showResultFile, []byte(output), 0600); err != nil {
 		return "", errors.Wrap(err, "writing terraform show result")
 	}
 
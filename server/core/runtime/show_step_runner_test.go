@@ -52,6 +52,9 @@ func TestShowStepRunnner(t *testing.T) {
 		Assert(t, actualStr == "success", fmt.Sprintf("expected '%s' to be success", actualStr))
 		Assert(t, r == "success", fmt.Sprintf("expected '%s' to be success", r))
 
+		info, err := os.Stat(resultPath)
+		Ok(t, err)
+		Equals(t, os.FileMode(0600), info.Mode().Perm())
 	})
 
 	t.Run("success w/ version override", func(t *testing.T) {
@@ -0,0 +1,34 @@
+package runtime
+
+import (
+	"fmt"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/events/command"
+)
+
+// TerraformVersionChecker checks that projects run a Terraform version that
+// their repo's allowed_terraform_versions allows.
+type TerraformVersionChecker struct {
+	// DefaultTFVersion is the version that projects that don't pin one run.
+	DefaultTFVersion *version.Version
+}
+
+// Check returns a failure explaining why the project of ctx can't run if its
+// Terraform version doesn't meet ctx.AllowedTerraformVersions, or "" if it
+// does. The version is resolved like the steps resolve it: the project's
+// pinned or detected version, or else the default version.
+func (c *TerraformVersionChecker) Check(ctx command.ProjectContext) string {
+	if c == nil || ctx.AllowedTerraformVersions == nil {
+		return ""
+	}
+	tfVersion := c.DefaultTFVersion
+	if ctx.TerraformVersion != nil {
+		tfVersion = ctx.TerraformVersion
+	}
+	if tfVersion == nil || ctx.AllowedTerraformVersions.Check(tfVersion) {
+		return ""
+	}
+	return fmt.Sprintf("Terraform version %s isn't allowed for this repo, which requires %q. Set terraform_version in the repo config"+
+		" or the required_version of the project's terraform block to an allowed version.", tfVersion, ctx.AllowedTerraformVersions)
+}
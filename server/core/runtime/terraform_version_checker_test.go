@@ -0,0 +1,71 @@
+package runtime_test
+
+import (
+	"strings"
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/core/runtime"
+	"github.com/runatlantis/atlantis/server/events/command"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestTerraformVersionChecker_Check(t *testing.T) {
+	allowed, err := version.NewConstraint(">= 1.3.0, < 2.0.0")
+	Ok(t, err)
+	checker := &runtime.TerraformVersionChecker{DefaultTFVersion: version.Must(version.NewVersion("1.5.7"))}
+
+	cases := map[string]struct {
+		tfVersion  string
+		allowed    version.Constraints
+		expFailure bool
+	}{
+		"in range": {
+			tfVersion: "1.3.0",
+			allowed:   allowed,
+		},
+		"out of range": {
+			tfVersion:  "1.2.9",
+			allowed:    allowed,
+			expFailure: true,
+		},
+		"above range": {
+			tfVersion:  "2.0.0",
+			allowed:    allowed,
+			expFailure: true,
+		},
+		"default version in range": {
+			allowed: allowed,
+		},
+		"no allowed versions": {
+			tfVersion: "0.12.31",
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := command.ProjectContext{AllowedTerraformVersions: c.allowed}
+			if c.tfVersion != "" {
+				ctx.TerraformVersion = version.Must(version.NewVersion(c.tfVersion))
+			}
+			failure := checker.Check(ctx)
+			if !c.expFailure {
+				Equals(t, "", failure)
+				return
+			}
+			Assert(t, strings.Contains(failure, "Terraform version "+c.tfVersion+" isn't allowed"), "exp the version in %q", failure)
+			Assert(t, strings.Contains(failure, `">= 1.3.0, < 2.0.0"`), "exp the allowed versions in %q", failure)
+		})
+	}
+}
+
+func TestTerraformVersionChecker_CheckDefaultVersion(t *testing.T) {
+	allowed, err := version.NewConstraint("~> 1.6")
+	Ok(t, err)
+	checker := &runtime.TerraformVersionChecker{DefaultTFVersion: version.Must(version.NewVersion("1.5.7"))}
+	failure := checker.Check(command.ProjectContext{AllowedTerraformVersions: allowed})
+	Assert(t, strings.Contains(failure, "Terraform version 1.5.7 isn't allowed"), "exp the default version to be rejected, got %q", failure)
+
+	// A nil checker allows any version.
+	var nilChecker *runtime.TerraformVersionChecker
+	Equals(t, "", nilChecker.Check(command.ProjectContext{AllowedTerraformVersions: allowed}))
+}
@@ -0,0 +1,302 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/core/runtime/common"
+	runtimemodels "github.com/runatlantis/atlantis/server/core/runtime/models"
+	"github.com/runatlantis/atlantis/server/core/terraform/tfc"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/jobs"
+)
+
+// DefaultTFCPollInterval is how often Terraform Cloud runs are polled.
+const DefaultTFCPollInterval = 2 * time.Second
+
+// tfcRunLinePrefix prefixes the line of planfiles created by Terraform Cloud
+// runs that has the URL of the run. It follows the remote ops header so
+// runners that don't support remote plans skip them.
+const tfcRunLinePrefix = "Atlantis: this plan was created by Terraform Cloud run "
+
+// TFCRuns plans and applies projects that use a cloud block or remote backend
+// with runs driven through the Terraform Cloud API, rather than by running
+// the terraform CLI.
+type TFCRuns struct {
+	Client              *tfc.Client
+	CommitStatusUpdater StatusUpdater
+	// OutputHandler streams the logs of runs to the project's job output.
+	OutputHandler jobs.ProjectCommandOutputHandler
+	// PollInterval is how often runs are polled.
+	PollInterval time.Duration
+}
+
+// PlanStepRunner returns a Runner that plans projects that use t's Terraform
+// Cloud instance with runs and other projects with runner.
+func (t *TFCRuns) PlanStepRunner(runner Runner) Runner {
+	return &tfcPlanStepRunner{TFCRuns: t, runner: runner}
+}
+
+// ApplyStepRunner returns a Runner that applies plans created by Terraform
+// Cloud runs and other plans with runner.
+func (t *TFCRuns) ApplyStepRunner(runner Runner) Runner {
+	return &tfcApplyStepRunner{TFCRuns: t, runner: runner}
+}
+
+type tfcPlanStepRunner struct {
+	*TFCRuns
+	runner Runner
+}
+
+func (p *tfcPlanStepRunner) Run(ctx command.ProjectContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	backend, err := common.FindRemoteBackend(path)
+	if err != nil {
+		return "", err
+	}
+	// Configurations that get the organization from the environment or use
+	// another instance are planned by the terraform CLI.
+	if backend == nil || backend.Organization == "" || (backend.Hostname != "" && backend.Hostname != p.Client.Hostname()) {
+		return p.runner.Run(ctx, extraArgs, path, envs)
+	}
+	if err := tfcUnsupportedArgs(ctx, extraArgs); err != nil {
+		return "", err
+	}
+
+	workspaceName := backend.Workspace
+	if workspaceName == "" {
+		workspaceName = backend.WorkspacePrefix + ctx.Workspace
+	}
+	workspace, err := p.Client.ReadWorkspace(backend.Organization, workspaceName)
+	if err != nil {
+		return "", fmt.Errorf("reading Terraform Cloud workspace %s/%s: %w", backend.Organization, workspaceName, err)
+	}
+
+	// A planned run waits to be applied and blocks the workspace's queue so
+	// the run of the previous plan is discarded.
+	planFile := filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+	if previousRunURL := tfcRunURL(planFile); previousRunURL != "" {
+		if err := p.Client.DiscardRun(tfcRunID(previousRunURL), "Superseded by a new Atlantis plan."); err != nil {
+			ctx.Log.Debug("unable to discard previous Terraform Cloud run %s: %s", previousRunURL, err)
+		}
+	}
+
+	// Terraform Cloud runs in the workspace's working directory, relative to
+	// the uploaded configuration, so the configuration is uploaded from the
+	// directory it's relative to.
+	configDir := path
+	if workingDir := strings.Trim(workspace.WorkingDirectory, "/"); workingDir != "" {
+		if root, ok := strings.CutSuffix(filepath.ToSlash(filepath.Clean(path)), "/"+workingDir); ok {
+			configDir = filepath.FromSlash(root)
+		}
+	}
+	configurationVersionID, err := p.Client.UploadConfiguration(workspace.ID, configDir, p.PollInterval)
+	if err != nil {
+		return "", fmt.Errorf("uploading configuration to Terraform Cloud: %w", err)
+	}
+	run, err := p.Client.CreateRun(workspace.ID, configurationVersionID, fmt.Sprintf("Atlantis plan of %s#%d", ctx.BaseRepo.FullName, ctx.Pull.Num))
+	if err != nil {
+		return "", fmt.Errorf("creating Terraform Cloud run: %w", err)
+	}
+	runURL := p.Client.RunURL(backend.Organization, workspaceName, run.ID)
+	ctx.Log.Info("planning with Terraform Cloud run %s", runURL)
+	p.updateStatus(ctx, command.Plan, models.PendingCommitStatus, runURL)
+
+	run, runLog, err := p.waitForRun(ctx, run, tfcPlanDone, func(run tfc.Run) (string, error) {
+		if run.PlanID == "" {
+			return "", nil
+		}
+		return p.Client.PlanLog(run.PlanID)
+	})
+	output := tfcOutput(runURL, runLog)
+	if err != nil {
+		p.updateStatus(ctx, command.Plan, models.FailedCommitStatus, runURL)
+		return output, err
+	}
+	if !run.IsConfirmable && run.Status != tfc.RunPlannedAndFinished {
+		p.updateStatus(ctx, command.Plan, models.FailedCommitStatus, runURL)
+		return output, fmt.Errorf("run %s is %s", runURL, run.Status)
+	}
+
+	// As with remote ops, the planfile is the text output of the plan so
+	// Atlantis knows the project has a plan, with the run to apply.
+	if err := os.WriteFile(planFile, []byte(remoteOpsHeader+tfcRunLinePrefix+runURL+"\n"+runLog), 0600); err != nil {
+		return output, fmt.Errorf("unable to create planfile for Terraform Cloud run: %w", err)
+	}
+	p.updateStatus(ctx, command.Plan, models.SuccessCommitStatus, runURL)
+	return output, nil
+}
+
+type tfcApplyStepRunner struct {
+	*TFCRuns
+	runner Runner
+}
+
+func (a *tfcApplyStepRunner) Run(ctx command.ProjectContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	planFile := filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+	runURL := tfcRunURL(planFile)
+	if runURL == "" {
+		return a.runner.Run(ctx, extraArgs, path, envs)
+	}
+	if err := tfcUnsupportedArgs(ctx, extraArgs); err != nil {
+		return "", err
+	}
+
+	run, err := a.Client.ReadRun(tfcRunID(runURL))
+	if err != nil {
+		return "", fmt.Errorf("reading Terraform Cloud run: %w", err)
+	}
+	if run.Status == tfc.RunPlannedAndFinished {
+		a.removePlanFile(ctx, planFile)
+		return tfcOutput(runURL, "No changes to apply."), nil
+	}
+	if !run.IsConfirmable {
+		return "", fmt.Errorf("run %s can't be applied since it's %s, plan again", runURL, run.Status)
+	}
+
+	ctx.Log.Info("applying Terraform Cloud run %s", runURL)
+	a.updateStatus(ctx, command.Apply, models.PendingCommitStatus, runURL)
+	if err := a.Client.ApplyRun(run.ID, fmt.Sprintf("Applied by Atlantis for %s#%d.", ctx.BaseRepo.FullName, ctx.Pull.Num)); err != nil {
+		a.updateStatus(ctx, command.Apply, models.FailedCommitStatus, runURL)
+		return "", fmt.Errorf("applying Terraform Cloud run: %w", err)
+	}
+
+	run, runLog, err := a.waitForRun(ctx, run, tfcApplyDone, func(run tfc.Run) (string, error) {
+		if run.ApplyID == "" || run.IsConfirmable {
+			return "", nil
+		}
+		return a.Client.ApplyLog(run.ApplyID)
+	})
+	output := tfcOutput(runURL, runLog)
+	if err == nil && run.Status != tfc.RunApplied {
+		err = fmt.Errorf("run %s is %s", runURL, run.Status)
+	}
+	if err != nil {
+		a.updateStatus(ctx, command.Apply, models.FailedCommitStatus, runURL)
+		return output, err
+	}
+	a.removePlanFile(ctx, planFile)
+	a.updateStatus(ctx, command.Apply, models.SuccessCommitStatus, runURL)
+	return output, nil
+}
+
+func (a *tfcApplyStepRunner) removePlanFile(ctx command.ProjectContext, planFile string) {
+	if err := os.Remove(planFile); err != nil {
+		ctx.Log.Warn("failed to delete planfile after successful apply: %s", err)
+	}
+}
+
+// waitForRun polls run until done returns true and returns it with its log,
+// read by readLog. The log is streamed to the job output as it grows. Like
+// processes run for the command, the run is canceled if it isn't done within
+// the project's command timeout.
+func (t *TFCRuns) waitForRun(ctx command.ProjectContext, run tfc.Run, done func(tfc.Run) bool, readLog func(tfc.Run) (string, error)) (tfc.Run, string, error) {
+	var runLog string
+	streamedLines := 0
+	start := time.Now()
+	for {
+		isDone := done(run)
+		if l, err := readLog(run); err != nil {
+			ctx.Log.Debug("unable to read log of Terraform Cloud run %s: %s", run.ID, err)
+		} else {
+			runLog = l
+		}
+
+		// The last line is only streamed once the run is done since it may
+		// not be complete.
+		lines := strings.Split(runLog, "\n")
+		if !isDone {
+			lines = lines[:len(lines)-1]
+		}
+		for ; streamedLines < len(lines); streamedLines++ {
+			if t.OutputHandler != nil {
+				t.OutputHandler.Send(ctx, lines[streamedLines], false)
+			}
+		}
+		if isDone {
+			return run, runLog, nil
+		}
+		if ctx.CommandTimeout > 0 && time.Since(start) > ctx.CommandTimeout {
+			if err := t.Client.CancelRun(run.ID, fmt.Sprintf("Ran for longer than the Atlantis timeout of %s.", ctx.CommandTimeout)); err != nil {
+				ctx.Log.Warn("unable to cancel Terraform Cloud run %s: %s", run.ID, err)
+			}
+			return run, runLog, fmt.Errorf("%w: Terraform Cloud run %s ran for longer than %s", runtimemodels.ErrCommandTimedOut, run.ID, ctx.CommandTimeout)
+		}
+
+		time.Sleep(t.PollInterval)
+		var err error
+		if run, err = t.Client.ReadRun(run.ID); err != nil {
+			return run, runLog, fmt.Errorf("reading Terraform Cloud run: %w", err)
+		}
+	}
+}
+
+func (t *TFCRuns) updateStatus(ctx command.ProjectContext, cmdName command.Name, status models.CommitStatus, runURL string) {
+	if err := t.CommitStatusUpdater.UpdateProject(ctx, cmdName, status, runURL, nil); err != nil {
+		ctx.Log.Err("unable to update status: %s", err)
+	}
+}
+
+// tfcPlanDone returns true once run is planned or can't continue.
+func tfcPlanDone(run tfc.Run) bool {
+	return run.IsConfirmable || tfcRunFinished(run)
+}
+
+// tfcApplyDone returns true once run is applied or can't continue.
+func tfcApplyDone(run tfc.Run) bool {
+	return !run.IsConfirmable && tfcRunFinished(run)
+}
+
+func tfcRunFinished(run tfc.Run) bool {
+	switch run.Status {
+	case tfc.RunApplied, tfc.RunPlannedAndFinished, tfc.RunErrored, tfc.RunDiscarded, tfc.RunCanceled, tfc.RunForceCanceled, tfc.RunPolicySoftFailed:
+		return true
+	}
+	return false
+}
+
+// tfcUnsupportedArgs returns an error if terraform args are set since they
+// can't be passed to Terraform Cloud runs.
+func tfcUnsupportedArgs(ctx command.ProjectContext, extraArgs []string) error {
+	if args := append(append(append([]string{}, extraArgs...), ctx.PlanPresetArgs...), ctx.EscapedCommentArgs...); len(args) > 0 {
+		return fmt.Errorf("extra args aren't supported by Terraform Cloud runs, got %q", args)
+	}
+	return nil
+}
+
+// tfcOutput is the output of a step run by Terraform Cloud. It starts with
+// the URL of the run so it's in the pull request comment.
+func tfcOutput(runURL string, runLog string) string {
+	runLog = plusDiffRegex.ReplaceAllString(runLog, "+")
+	runLog = tildeDiffRegex.ReplaceAllString(runLog, "~")
+	runLog = minusDiffRegex.ReplaceAllString(runLog, "-")
+	return fmt.Sprintf("Terraform Cloud run: %s\n\n%s", runURL, runLog)
+}
+
+// tfcRunURL returns the URL of the Terraform Cloud run that created
+// planFile, or an empty string if it wasn't created by one.
+func tfcRunURL(planFile string) string {
+	contents, err := os.ReadFile(planFile) // nolint: gosec
+	if err != nil {
+		return ""
+	}
+	header, rest, _ := strings.Cut(string(contents), "\n")
+	if header+"\n" != remoteOpsHeader {
+		return ""
+	}
+	line, _, _ := strings.Cut(rest, "\n")
+	runURL, ok := strings.CutPrefix(line, tfcRunLinePrefix)
+	if !ok {
+		return ""
+	}
+	return runURL
+}
+
+// tfcRunID returns the ID of the run with runURL.
+func tfcRunID(runURL string) string {
+	return path.Base(runURL)
+}
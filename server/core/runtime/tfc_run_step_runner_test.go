@@ -0,0 +1,238 @@
+package runtime_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock/v4"
+	"github.com/runatlantis/atlantis/server/core/runtime"
+	runtimemocks "github.com/runatlantis/atlantis/server/core/runtime/mocks"
+	runtimemodels "github.com/runatlantis/atlantis/server/core/runtime/models"
+	"github.com/runatlantis/atlantis/server/core/terraform/tfc"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+	jobmocks "github.com/runatlantis/atlantis/server/jobs/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// fakeTFC is a Terraform Cloud API with the networking workspace of the acme
+// organization and a single run.
+type fakeTFC struct {
+	t      *testing.T
+	server *httptest.Server
+	// runStatuses are the statuses of successive reads of the run. The last
+	// one repeats.
+	runStatuses []string
+	runReads    int
+	planLog     string
+	applyLog    string
+	// actions are the run actions called, ex. "run-1/apply".
+	actions []string
+}
+
+func newFakeTFC(t *testing.T) *fakeTFC {
+	f := &fakeTFC{t: t}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeTFC) handle(w http.ResponseWriter, r *http.Request) {
+	switch r.Method + " " + r.URL.Path {
+	case "GET /api/v2/organizations/acme/workspaces/networking":
+		fmt.Fprint(w, `{"data":{"id":"ws-1","type":"workspaces","attributes":{"working-directory":""}}}`)
+	case "POST /api/v2/workspaces/ws-1/configuration-versions":
+		fmt.Fprintf(w, `{"data":{"id":"cv-1","type":"configuration-versions","attributes":{"upload-url":"%s/upload/cv-1"}}}`, f.server.URL)
+	case "PUT /upload/cv-1":
+	case "GET /api/v2/configuration-versions/cv-1":
+		fmt.Fprint(w, `{"data":{"id":"cv-1","type":"configuration-versions","attributes":{"status":"uploaded"}}}`)
+	case "POST /api/v2/runs":
+		fmt.Fprint(w, f.run("pending"))
+	case "GET /api/v2/runs/run-1":
+		status := f.runStatuses[len(f.runStatuses)-1]
+		if f.runReads < len(f.runStatuses) {
+			status = f.runStatuses[f.runReads]
+		}
+		f.runReads++
+		fmt.Fprint(w, f.run(status))
+	case "GET /api/v2/plans/plan-1":
+		fmt.Fprintf(w, `{"data":{"id":"plan-1","type":"plans","attributes":{"log-read-url":"%s/logs/plan-1"}}}`, f.server.URL)
+	case "GET /logs/plan-1":
+		fmt.Fprint(w, f.planLog)
+	case "GET /api/v2/applies/apply-1":
+		fmt.Fprintf(w, `{"data":{"id":"apply-1","type":"applies","attributes":{"log-read-url":"%s/logs/apply-1"}}}`, f.server.URL)
+	case "GET /logs/apply-1":
+		fmt.Fprint(w, f.applyLog)
+	case "POST /api/v2/runs/run-0/actions/discard", "POST /api/v2/runs/run-1/actions/discard", "POST /api/v2/runs/run-1/actions/apply", "POST /api/v2/runs/run-1/actions/cancel":
+		f.actions = append(f.actions, filepath.Base(filepath.Dir(filepath.Dir(r.URL.Path)))+"/"+filepath.Base(r.URL.Path))
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeTFC) run(status string) string {
+	return fmt.Sprintf(`{"data":{"id":"run-1","type":"runs","attributes":{"status":%q,"actions":{"is-confirmable":%t}},"relationships":{"plan":{"data":{"id":"plan-1","type":"plans"}},"apply":{"data":{"id":"apply-1","type":"applies"}}}}}`, status, status == "planned")
+}
+
+func tfcTestContext(t *testing.T) command.ProjectContext {
+	return command.ProjectContext{
+		Log:        logging.NewNoopLogger(t),
+		Workspace:  "default",
+		RepoRelDir: ".",
+		BaseRepo:   models.Repo{FullName: "owner/repo"},
+		Pull:       models.PullRequest{Num: 2},
+	}
+}
+
+func tfcTestDir(t *testing.T, backend string) string {
+	dir := t.TempDir()
+	Ok(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(backend), 0600))
+	return dir
+}
+
+const tfcCloudBlock = `terraform {
+  cloud {
+    organization = "acme"
+    workspaces {
+      name = "networking"
+    }
+  }
+}`
+
+func TestTFCRuns_PlanAndApply(t *testing.T) {
+	RegisterMockTestingT(t)
+	fake := newFakeTFC(t)
+	statusUpdater := runtimemocks.NewMockStatusUpdater()
+	outputHandler := jobmocks.NewMockProjectCommandOutputHandler()
+	tfcRuns := &runtime.TFCRuns{
+		Client:              tfc.NewClient(fake.server.URL, "token"),
+		CommitStatusUpdater: statusUpdater,
+		OutputHandler:       outputHandler,
+	}
+	defaultRunner := runtimemocks.NewMockRunner()
+	ctx := tfcTestContext(t)
+	dir := tfcTestDir(t, tfcCloudBlock)
+	runURL := fake.server.URL + "/app/acme/workspaces/networking/runs/run-1"
+
+	// A previous plan's run is discarded.
+	planFile := filepath.Join(dir, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+	Ok(t, os.WriteFile(planFile, []byte("Atlantis: this plan was created by remote ops\nAtlantis: this plan was created by Terraform Cloud run "+fake.server.URL+"/app/acme/workspaces/networking/runs/run-0\n"), 0600))
+
+	fake.runStatuses = []string{"planning", "planned"}
+	fake.planLog = "Terraform will perform the following actions:\n\n  + null_resource.a\n\nPlan: 1 to add, 0 to change, 0 to destroy."
+	output, err := tfcRuns.PlanStepRunner(defaultRunner).Run(ctx, nil, dir, nil)
+	Ok(t, err)
+	Equals(t, "Terraform Cloud run: "+runURL+"\n\nTerraform will perform the following actions:\n\n+ null_resource.a\n\nPlan: 1 to add, 0 to change, 0 to destroy.", output)
+	Equals(t, []string{"run-0/discard"}, fake.actions)
+	planFileContents, err := os.ReadFile(planFile)
+	Ok(t, err)
+	Assert(t, runtime.IsRemotePlan(planFileContents), "exp a remote plan")
+	outputHandler.VerifyWasCalledOnce().Send(ctx, "Plan: 1 to add, 0 to change, 0 to destroy.", false)
+	statusUpdater.VerifyWasCalledOnce().UpdateProject(ctx, command.Plan, models.PendingCommitStatus, runURL, nil)
+	statusUpdater.VerifyWasCalledOnce().UpdateProject(ctx, command.Plan, models.SuccessCommitStatus, runURL, nil)
+
+	fake.runReads = 0
+	fake.runStatuses = []string{"planned", "planned", "applying", "applied"}
+	fake.applyLog = "null_resource.a: Creating...\nApply complete! Resources: 1 added, 0 changed, 0 destroyed."
+	output, err = tfcRuns.ApplyStepRunner(defaultRunner).Run(ctx, nil, dir, nil)
+	Ok(t, err)
+	Equals(t, "Terraform Cloud run: "+runURL+"\n\nnull_resource.a: Creating...\nApply complete! Resources: 1 added, 0 changed, 0 destroyed.", output)
+	Equals(t, []string{"run-0/discard", "run-1/apply"}, fake.actions)
+	_, err = os.Stat(planFile)
+	Assert(t, os.IsNotExist(err), "exp planfile to be deleted")
+	statusUpdater.VerifyWasCalledOnce().UpdateProject(ctx, command.Apply, models.SuccessCommitStatus, runURL, nil)
+	defaultRunner.VerifyWasCalled(Never()).Run(Any[command.ProjectContext](), Any[[]string](), Any[string](), Any[map[string]string]())
+}
+
+func TestTFCRuns_PlanErrored(t *testing.T) {
+	RegisterMockTestingT(t)
+	fake := newFakeTFC(t)
+	statusUpdater := runtimemocks.NewMockStatusUpdater()
+	tfcRuns := &runtime.TFCRuns{
+		Client:              tfc.NewClient(fake.server.URL, "token"),
+		CommitStatusUpdater: statusUpdater,
+	}
+	ctx := tfcTestContext(t)
+	dir := tfcTestDir(t, tfcCloudBlock)
+	runURL := fake.server.URL + "/app/acme/workspaces/networking/runs/run-1"
+
+	fake.runStatuses = []string{"errored"}
+	fake.planLog = "Error: Unsupported argument"
+	output, err := tfcRuns.PlanStepRunner(runtimemocks.NewMockRunner()).Run(ctx, nil, dir, nil)
+	ErrEquals(t, "run "+runURL+" is errored", err)
+	Equals(t, "Terraform Cloud run: "+runURL+"\n\nError: Unsupported argument", output)
+	_, err = os.Stat(filepath.Join(dir, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName)))
+	Assert(t, os.IsNotExist(err), "exp no planfile")
+	statusUpdater.VerifyWasCalledOnce().UpdateProject(ctx, command.Plan, models.FailedCommitStatus, runURL, nil)
+}
+
+func TestTFCRuns_PlanTimedOut(t *testing.T) {
+	RegisterMockTestingT(t)
+	fake := newFakeTFC(t)
+	statusUpdater := runtimemocks.NewMockStatusUpdater()
+	tfcRuns := &runtime.TFCRuns{
+		Client:              tfc.NewClient(fake.server.URL, "token"),
+		CommitStatusUpdater: statusUpdater,
+		PollInterval:        time.Millisecond,
+	}
+	ctx := tfcTestContext(t)
+	ctx.CommandTimeout = 10 * time.Millisecond
+	dir := tfcTestDir(t, tfcCloudBlock)
+	runURL := fake.server.URL + "/app/acme/workspaces/networking/runs/run-1"
+
+	fake.runStatuses = []string{"planning"}
+	_, err := tfcRuns.PlanStepRunner(runtimemocks.NewMockRunner()).Run(ctx, nil, dir, nil)
+	Assert(t, errors.Is(err, runtimemodels.ErrCommandTimedOut), "expected timeout error, got %v", err)
+	Equals(t, []string{"run-1/cancel"}, fake.actions)
+	_, err = os.Stat(filepath.Join(dir, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName)))
+	Assert(t, os.IsNotExist(err), "exp no planfile")
+	statusUpdater.VerifyWasCalledOnce().UpdateProject(ctx, command.Plan, models.FailedCommitStatus, runURL, nil)
+}
+
+func TestTFCRuns_ExtraArgs(t *testing.T) {
+	RegisterMockTestingT(t)
+	fake := newFakeTFC(t)
+	tfcRuns := &runtime.TFCRuns{Client: tfc.NewClient(fake.server.URL, "token")}
+	ctx := tfcTestContext(t)
+	ctx.EscapedCommentArgs = []string{"-target=null_resource.a"}
+
+	_, err := tfcRuns.PlanStepRunner(runtimemocks.NewMockRunner()).Run(ctx, nil, tfcTestDir(t, tfcCloudBlock), nil)
+	ErrEquals(t, `extra args aren't supported by Terraform Cloud runs, got ["-target=null_resource.a"]`, err)
+}
+
+func TestTFCRuns_OtherProjects(t *testing.T) {
+	cases := map[string]string{
+		"other backend":  "terraform {\n  backend \"s3\" {}\n}",
+		"other instance": "terraform {\n  cloud {\n    hostname = \"tfe.example.com\"\n    organization = \"acme\"\n  }\n}",
+		"no backend":     `resource "null_resource" "a" {}`,
+	}
+	for description, backend := range cases {
+		t.Run(description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			fake := newFakeTFC(t)
+			tfcRuns := &runtime.TFCRuns{Client: tfc.NewClient(fake.server.URL, "token")}
+			defaultRunner := runtimemocks.NewMockRunner()
+			ctx := tfcTestContext(t)
+			dir := tfcTestDir(t, backend)
+			When(defaultRunner.Run(ctx, nil, dir, map[string]string(nil))).ThenReturn("output", nil)
+
+			output, err := tfcRuns.PlanStepRunner(defaultRunner).Run(ctx, nil, dir, nil)
+			Ok(t, err)
+			Equals(t, "output", output)
+
+			output, err = tfcRuns.ApplyStepRunner(defaultRunner).Run(ctx, nil, dir, nil)
+			Ok(t, err)
+			Equals(t, "output", output)
+			defaultRunner.VerifyWasCalled(Times(2)).Run(ctx, nil, dir, map[string]string(nil))
+		})
+	}
+}
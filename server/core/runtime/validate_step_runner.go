@@ -0,0 +1,106 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/command"
+)
+
+// ValidateStepRunner runs terraform validate and renders its diagnostics.
+type ValidateStepRunner struct {
+	TerraformExecutor TerraformExec
+	DefaultTFVersion  *version.Version
+}
+
+// validateOutput is the output of terraform validate -json.
+type validateOutput struct {
+	Valid        bool                 `json:"valid"`
+	ErrorCount   int                  `json:"error_count"`
+	WarningCount int                  `json:"warning_count"`
+	Diagnostics  []validateDiagnostic `json:"diagnostics"`
+}
+
+type validateDiagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail"`
+	Range    *struct {
+		Filename string `json:"filename"`
+		Start    struct {
+			Line int `json:"line"`
+		} `json:"start"`
+	} `json:"range"`
+}
+
+// Run runs terraform validate in path. It returns an error if the
+// configuration is invalid.
+func (v *ValidateStepRunner) Run(ctx command.ProjectContext, extraArgs []string, path string, envs map[string]string) (string, error) {
+	tfVersion := v.DefaultTFVersion
+	if ctx.TerraformVersion != nil {
+		tfVersion = ctx.TerraformVersion
+	}
+
+	validateCmd := append([]string{"validate", "-json"}, extraArgs...)
+	validateCmd = append(validateCmd, ctx.EscapedCommentArgs...)
+	out, err := v.TerraformExecutor.RunCommandWithVersion(ctx, filepath.Clean(path), validateCmd, envs, tfVersion, ctx.Workspace)
+
+	result, parseErr := parseValidateOutput(out)
+	if parseErr != nil {
+		// Terraform failed before it could output its diagnostics so there's
+		// nothing better to show than its raw output.
+		ctx.Log.Debug("unable to parse validate output: %s", parseErr)
+		return out, err
+	}
+	rendered := result.render()
+	if !result.Valid {
+		return rendered, fmt.Errorf("configuration is invalid: %d error(s), %d warning(s)", result.ErrorCount, result.WarningCount)
+	}
+	return rendered, nil
+}
+
+// parseValidateOutput parses the output of terraform validate -json. The
+// output may be preceded by other lines, ex. from a wrapper script, so
+// parsing starts at the first line that opens a JSON object.
+func parseValidateOutput(out string) (validateOutput, error) {
+	var result validateOutput
+	start := strings.Index(out, "{")
+	if start < 0 {
+		return result, errors.New("no JSON object found")
+	}
+	if err := json.NewDecoder(strings.NewReader(out[start:])).Decode(&result); err != nil {
+		return result, errors.Wrap(err, "decoding validate output")
+	}
+	return result, nil
+}
+
+// render formats the diagnostics similarly to how terraform validate does
+// without -json.
+func (r validateOutput) render() string {
+	var sb strings.Builder
+	if r.Valid {
+		sb.WriteString("Success! The configuration is valid")
+		if r.WarningCount > 0 {
+			fmt.Fprintf(&sb, ", but there were %d warning(s)", r.WarningCount)
+		}
+		sb.WriteString(".\n")
+	}
+	for _, d := range r.Diagnostics {
+		severity := "Error"
+		if d.Severity == "warning" {
+			severity = "Warning"
+		}
+		fmt.Fprintf(&sb, "\n%s: %s\n", severity, d.Summary)
+		if d.Range != nil && d.Range.Filename != "" {
+			fmt.Fprintf(&sb, "\n  on %s line %d\n", d.Range.Filename, d.Range.Start.Line)
+		}
+		if d.Detail != "" {
+			fmt.Fprintf(&sb, "\n%s\n", d.Detail)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,87 @@
+package runtime
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock/v4"
+	"github.com/runatlantis/atlantis/server/core/terraform/mocks"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestValidateStepRunner_Run(t *testing.T) {
+	cases := []struct {
+		description string
+		tfOut       string
+		tfErr       error
+		expOut      string
+		expErr      string
+	}{
+		{
+			description: "valid",
+			tfOut:       `{"format_version":"1.0","valid":true,"error_count":0,"warning_count":0,"diagnostics":[]}`,
+			expOut:      "Success! The configuration is valid.",
+		},
+		{
+			description: "valid with warnings",
+			tfOut: `{"valid":true,"error_count":0,"warning_count":1,"diagnostics":[
+				{"severity":"warning","summary":"Deprecated attribute","detail":"Use name_prefix instead.","range":{"filename":"main.tf","start":{"line":4}}}
+			]}`,
+			expOut: "Success! The configuration is valid, but there were 1 warning(s).\n\nWarning: Deprecated attribute\n\n  on main.tf line 4\n\nUse name_prefix instead.",
+		},
+		{
+			description: "invalid",
+			tfOut: `{"valid":false,"error_count":2,"warning_count":0,"diagnostics":[
+				{"severity":"error","summary":"Missing required argument","detail":"The argument \"ami\" is required.","range":{"filename":"main.tf","start":{"line":1}}},
+				{"severity":"error","summary":"Unsupported block type"}
+			]}`,
+			tfErr:  errors.New("exit status 1"),
+			expOut: "Error: Missing required argument\n\n  on main.tf line 1\n\nThe argument \"ami\" is required.\n\nError: Unsupported block type",
+			expErr: "configuration is invalid: 2 error(s), 0 warning(s)",
+		},
+		{
+			description: "output before json",
+			tfOut:       "wrapper: running terraform\n" + `{"valid":true,"error_count":0,"warning_count":0,"diagnostics":[]}`,
+			expOut:      "Success! The configuration is valid.",
+		},
+		{
+			description: "unparseable output",
+			tfOut:       "Error: Could not load plugin",
+			tfErr:       errors.New("exit status 1"),
+			expOut:      "Error: Could not load plugin",
+			expErr:      "exit status 1",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			terraform := mocks.NewMockClient()
+			tfVersion, _ := version.NewVersion("1.5.0")
+			tmpDir := t.TempDir()
+			ctx := command.ProjectContext{
+				Log:                logging.NewNoopLogger(t),
+				EscapedCommentArgs: []string{"-no-color"},
+				Workspace:          "default",
+			}
+			s := &ValidateStepRunner{
+				TerraformExecutor: terraform,
+				DefaultTFVersion:  tfVersion,
+			}
+
+			When(terraform.RunCommandWithVersion(Any[command.ProjectContext](), Any[string](), Any[[]string](), Any[map[string]string](), Any[*version.Version](), Any[string]())).
+				ThenReturn(c.tfOut, c.tfErr)
+			out, err := s.Run(ctx, nil, tmpDir, map[string]string(nil))
+			Equals(t, c.expOut, out)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+			} else {
+				Ok(t, err)
+			}
+			terraform.VerifyWasCalledOnce().RunCommandWithVersion(ctx, tmpDir, []string{"validate", "-json", "-no-color"}, map[string]string(nil), tfVersion, "default")
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package runtime
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/events/command"
+)
+
+// WorkspaceDeleter deletes the Terraform workspaces of projects.
+type WorkspaceDeleter struct {
+	TerraformExecutor TerraformExec
+	DefaultTFVersion  *version.Version
+}
+
+// Delete deletes the workspace ctx.Workspace of the project in path, which
+// must be initialized, after switching it to the default workspace. Terraform
+// refuses to delete workspaces whose state still manages resources.
+func (d *WorkspaceDeleter) Delete(ctx command.ProjectContext, path string, envs map[string]string) error {
+	if ctx.Workspace == defaultWorkspace {
+		return fmt.Errorf("refusing to delete the %s workspace", defaultWorkspace)
+	}
+	tfVersion := d.DefaultTFVersion
+	if ctx.TerraformVersion != nil {
+		tfVersion = ctx.TerraformVersion
+	}
+	if MustConstraint("<0.9").Check(tfVersion) {
+		return fmt.Errorf("terraform version %s does not support workspaces", tfVersion)
+	}
+	// In version 0.9.* the workspace command was called env.
+	workspaceCmd := "workspace"
+	if MustConstraint(">=0.9,<0.10").Check(tfVersion) {
+		workspaceCmd = "env"
+	}
+
+	if out, err := d.TerraformExecutor.RunCommandWithVersion(ctx, path, []string{workspaceCmd, "select", defaultWorkspace}, envs, tfVersion, defaultWorkspace); err != nil {
+		return fmt.Errorf("%s: %s", err, out)
+	}
+	if out, err := d.TerraformExecutor.RunCommandWithVersion(ctx, path, []string{workspaceCmd, "delete", ctx.Workspace}, envs, tfVersion, defaultWorkspace); err != nil {
+		return fmt.Errorf("%s: %s", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package runtime_test
+
+import (
+	"errors"
+	"testing"
+
+	version "github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock/v4"
+
+	"github.com/runatlantis/atlantis/server/core/runtime"
+	"github.com/runatlantis/atlantis/server/core/terraform/mocks"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestWorkspaceDeleter_Delete(t *testing.T) {
+	cases := []struct {
+		version       string
+		expSubcommand string
+	}{
+		{"0.9.11", "env"},
+		{"0.10.0", "workspace"},
+		{"1.5.0", "workspace"},
+	}
+	for _, c := range cases {
+		t.Run(c.version, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			terraform := mocks.NewMockClient()
+			tfVersion, _ := version.NewVersion(c.version)
+			d := runtime.WorkspaceDeleter{
+				TerraformExecutor: terraform,
+				DefaultTFVersion:  tfVersion,
+			}
+			ctx := command.ProjectContext{
+				Log:       logging.NewNoopLogger(t),
+				Workspace: "pr-1",
+			}
+			When(terraform.RunCommandWithVersion(Any[command.ProjectContext](), Any[string](), Any[[]string](), Any[map[string]string](), Any[*version.Version](), Any[string]())).
+				ThenReturn("", nil)
+
+			envs := map[string]string{"TF_TOKEN": "token"}
+			Ok(t, d.Delete(ctx, "/path", envs))
+			terraform.VerifyWasCalledOnce().RunCommandWithVersion(ctx, "/path", []string{c.expSubcommand, "select", "default"}, envs, tfVersion, "default")
+			terraform.VerifyWasCalledOnce().RunCommandWithVersion(ctx, "/path", []string{c.expSubcommand, "delete", "pr-1"}, envs, tfVersion, "default")
+		})
+	}
+}
+
+func TestWorkspaceDeleter_DeleteErrs(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	tfVersion, _ := version.NewVersion("1.5.0")
+	d := runtime.WorkspaceDeleter{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+	}
+	logger := logging.NewNoopLogger(t)
+
+	t.Log("the default workspace should never be deleted")
+	ErrEquals(t, "refusing to delete the default workspace", d.Delete(command.ProjectContext{Log: logger, Workspace: "default"}, "/path", nil))
+
+	t.Log("terraform's output should be returned when it fails")
+	When(terraform.RunCommandWithVersion(Any[command.ProjectContext](), Any[string](), Any[[]string](), Any[map[string]string](), Any[*version.Version](), Any[string]())).
+		ThenReturn("Workspace \"pr-1\" is not empty.", errors.New("exit status 1"))
+	ErrEquals(t, "exit status 1: Workspace \"pr-1\" is not empty.", d.Delete(command.ProjectContext{Log: logger, Workspace: "pr-1"}, "/path", nil))
+}
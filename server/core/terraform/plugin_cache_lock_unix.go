@@ -0,0 +1,29 @@
+//go:build !windows
+
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// lockPluginCache blocks until it holds an exclusive lock on the plugin cache
+// in cacheDir. The lock is a file lock so it's also respected by other
+// Atlantis servers sharing the dir. The returned func releases the lock.
+func lockPluginCache(cacheDir string) (func(), error) {
+	f, err := os.OpenFile(filepath.Join(cacheDir, pluginCacheLockFile), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening plugin cache lock file")
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close() // nolint: errcheck
+		return nil, errors.Wrap(err, "locking plugin cache")
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN) // nolint: errcheck
+		f.Close()                                   // nolint: errcheck
+	}, nil
+}
@@ -0,0 +1,18 @@
+package terraform
+
+import (
+	"sync"
+)
+
+// pluginCacheLocks maps plugin cache dirs to the mutex guarding them.
+var pluginCacheLocks sync.Map
+
+// lockPluginCache blocks until it holds an exclusive lock on the plugin cache
+// in cacheDir. File locks aren't supported on Windows so the lock only applies
+// to this Atlantis server. The returned func releases the lock.
+func lockPluginCache(cacheDir string) (func(), error) {
+	l, _ := pluginCacheLocks.LoadOrStore(cacheDir, &sync.Mutex{})
+	mu := l.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock, nil
+}
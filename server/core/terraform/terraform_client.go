@@ -89,6 +89,10 @@ type DefaultClient struct {
 
 	// usePluginCache determines whether or not to set the TF_PLUGIN_CACHE_DIR env var
 	usePluginCache bool
+	// sharedPluginCache is set when the plugin cache dir can be shared with
+	// other Atlantis servers. Terraform doesn't lock the cache itself and
+	// concurrent inits can corrupt it, so inits then hold a file lock on it.
+	sharedPluginCache bool
 
 	projectCmdOutputHandler jobs.ProjectCommandOutputHandler
 }
@@ -110,6 +114,10 @@ type Downloader interface {
 //		   => 0.11.10
 var versionRegex = regexp.MustCompile("Terraform v(.*?)(\\s.*)?\n")
 
+// pluginCacheLockFile is the name of the file inside a shared plugin cache dir
+// that inits lock.
+const pluginCacheLockFile = ".atlantis.lock"
+
 // NewClientWithDefaultVersion creates a new terraform client and pre-fetches the default version
 func NewClientWithDefaultVersion(
 	log logging.SimpleLogging,
@@ -123,6 +131,7 @@ func NewClientWithDefaultVersion(
 	tfDownloader Downloader,
 	tfDownloadAllowed bool,
 	usePluginCache bool,
+	sharedPluginCache bool,
 	fetchAsync bool,
 	projectCmdOutputHandler jobs.ProjectCommandOutputHandler,
 ) (*DefaultClient, error) {
@@ -192,6 +201,7 @@ func NewClientWithDefaultVersion(
 		versionsLock:            &versionsLock,
 		versions:                versions,
 		usePluginCache:          usePluginCache,
+		sharedPluginCache:       sharedPluginCache,
 		projectCmdOutputHandler: projectCmdOutputHandler,
 	}, nil
 
@@ -209,6 +219,7 @@ func NewTestClient(
 	tfDownloader Downloader,
 	tfDownloadAllowed bool,
 	usePluginCache bool,
+	sharedPluginCache bool,
 	projectCmdOutputHandler jobs.ProjectCommandOutputHandler,
 ) (*DefaultClient, error) {
 	return NewClientWithDefaultVersion(
@@ -223,6 +234,7 @@ func NewTestClient(
 		tfDownloader,
 		tfDownloadAllowed,
 		usePluginCache,
+		sharedPluginCache,
 		false,
 		projectCmdOutputHandler,
 	)
@@ -248,6 +260,7 @@ func NewClient(
 	tfDownloader Downloader,
 	tfDownloadAllowed bool,
 	usePluginCache bool,
+	sharedPluginCache bool,
 	projectCmdOutputHandler jobs.ProjectCommandOutputHandler,
 ) (*DefaultClient, error) {
 	return NewClientWithDefaultVersion(
@@ -262,6 +275,7 @@ func NewClient(
 		tfDownloader,
 		tfDownloadAllowed,
 		usePluginCache,
+		sharedPluginCache,
 		true,
 		projectCmdOutputHandler,
 	)
@@ -387,6 +401,14 @@ func (c *DefaultClient) EnsureVersion(log logging.SimpleLogging, v *version.Vers
 
 // See Client.RunCommandWithVersion.
 func (c *DefaultClient) RunCommandWithVersion(ctx command.ProjectContext, path string, args []string, customEnvVars map[string]string, v *version.Version, workspace string) (string, error) {
+	if c.usePluginCache && c.sharedPluginCache && len(args) > 0 && args[0] == "init" {
+		ctx.Log.Debug("waiting for other terraform inits using the plugin cache to finish")
+		unlock, err := lockPluginCache(c.terraformPluginCacheDir)
+		if err != nil {
+			return "", err
+		}
+		defer unlock()
+	}
 	if isAsyncEligibleCommand(args[0]) {
 		_, outCh := c.RunCommandAsync(ctx, path, args, customEnvVars, v, workspace)
 
@@ -403,9 +425,12 @@ func (c *DefaultClient) RunCommandWithVersion(ctx command.ProjectContext, path s
 
 		// sanitize output by stripping out any ansi characters.
 		output = ansi.Strip(output)
+		if ctx.Terragrunt {
+			output = StripTerragruntLogs(output)
+		}
 		return fmt.Sprintf("%s\n", output), err
 	}
-	tfCmd, cmd, err := c.prepExecCmd(ctx.Log, v, workspace, path, args)
+	tfCmd, cmd, err := c.prepExecCmd(ctx.Log, v, ctx.TerraformBinary, ctx.Terragrunt, workspace, path, args)
 	if err != nil {
 		return "", err
 	}
@@ -418,21 +443,25 @@ func (c *DefaultClient) RunCommandWithVersion(ctx command.ProjectContext, path s
 	out, err := cmd.CombinedOutput()
 	dur := time.Since(start)
 	log := ctx.Log.With("duration", dur)
+	output := ansi.Strip(string(out))
+	if ctx.Terragrunt {
+		output = StripTerragruntLogs(output)
+	}
 	if err != nil {
 		err = errors.Wrapf(err, "running %q in %q", tfCmd, path)
 		log.Err(err.Error())
-		return ansi.Strip(string(out)), err
+		return output, err
 	}
 	log.Info("successfully ran %q in %q", tfCmd, path)
 
-	return ansi.Strip(string(out)), nil
+	return output, nil
 }
 
 // prepExecCmd builds a ready to execute command based on the version of terraform
 // v, and args. It returns a printable representation of the command that will
 // be run and the actual command.
-func (c *DefaultClient) prepExecCmd(log logging.SimpleLogging, v *version.Version, workspace string, path string, args []string) (string, *exec.Cmd, error) {
-	tfCmd, envVars, err := c.prepCmd(log, v, workspace, path, args)
+func (c *DefaultClient) prepExecCmd(log logging.SimpleLogging, v *version.Version, tfBinary string, terragrunt bool, workspace string, path string, args []string) (string, *exec.Cmd, error) {
+	tfCmd, envVars, err := c.prepCmd(log, v, tfBinary, terragrunt, workspace, path, args)
 	if err != nil {
 		return "", nil, err
 	}
@@ -443,8 +472,10 @@ func (c *DefaultClient) prepExecCmd(log logging.SimpleLogging, v *version.Versio
 }
 
 // prepCmd prepares a shell command (to be interpreted with `sh -c <cmd>`) and set of environment
-// variables for running terraform.
-func (c *DefaultClient) prepCmd(log logging.SimpleLogging, v *version.Version, workspace string, path string, args []string) (string, []string, error) {
+// variables for running terraform. If tfBinary is set, it's run instead of
+// the binary for v. If terragrunt is true, terragrunt is run instead and wraps
+// the terraform binary.
+func (c *DefaultClient) prepCmd(log logging.SimpleLogging, v *version.Version, tfBinary string, terragrunt bool, workspace string, path string, args []string) (string, []string, error) {
 	if v == nil {
 		v = c.defaultVersion
 	}
@@ -453,6 +484,11 @@ func (c *DefaultClient) prepCmd(log logging.SimpleLogging, v *version.Version, w
 	if c.overrideTF != "" {
 		// This is only set during testing.
 		binPath = c.overrideTF
+	} else if tfBinary != "" {
+		if err := CheckBinary(tfBinary); err != nil {
+			return "", nil, err
+		}
+		binPath = tfBinary
 	} else {
 		var err error
 		c.versionsLock.Lock()
@@ -476,6 +512,22 @@ func (c *DefaultClient) prepCmd(log logging.SimpleLogging, v *version.Version, w
 	if c.usePluginCache {
 		envVars = append(envVars, fmt.Sprintf("TF_PLUGIN_CACHE_DIR=%s", c.terraformPluginCacheDir))
 	}
+	if terragrunt {
+		tgPath, err := exec.LookPath(TerragruntBinaryName)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "terragrunt workflow")
+		}
+		// Terragrunt runs the terraform binary Atlantis picked for the
+		// project's version and must never prompt. Newer terragrunt versions
+		// renamed these variables.
+		envVars = append(envVars,
+			fmt.Sprintf("TERRAGRUNT_TFPATH=%s", binPath),
+			fmt.Sprintf("TG_TF_PATH=%s", binPath),
+			"TERRAGRUNT_NON_INTERACTIVE=true",
+			"TG_NON_INTERACTIVE=true",
+		)
+		binPath = tgPath
+	}
 	// Append current Atlantis process's environment variables, ex.
 	// AWS_ACCESS_KEY.
 	envVars = append(envVars, os.Environ()...)
@@ -490,7 +542,7 @@ func (c *DefaultClient) prepCmd(log logging.SimpleLogging, v *version.Version, w
 // If any error is passed on the out channel, there will be no
 // further output (so callers are free to exit).
 func (c *DefaultClient) RunCommandAsync(ctx command.ProjectContext, path string, args []string, customEnvVars map[string]string, v *version.Version, workspace string) (chan<- string, <-chan models.Line) {
-	cmd, envVars, err := c.prepCmd(ctx.Log, v, workspace, path, args)
+	cmd, envVars, err := c.prepCmd(ctx.Log, v, ctx.TerraformBinary, ctx.Terragrunt, workspace, path, args)
 	if err != nil {
 		// The signature of `RunCommandAsync` doesn't provide for returning an immediate error, only one
 		// once reading the output. Since we won't be spawning a process, simulate that by sending the
@@ -514,6 +566,20 @@ func (c *DefaultClient) RunCommandAsync(ctx command.ProjectContext, path string,
 	return inCh, outCh
 }
 
+// CheckBinary returns an error if there's no executable terraform binary at
+// path. It's used to check a repo's terraform_binary before running it so a
+// missing binary fails clearly instead of with a shell error.
+func CheckBinary(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.Wrapf(err, "terraform_binary %q", path)
+	}
+	if info.IsDir() || info.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("terraform_binary %q isn't an executable file", path)
+	}
+	return nil
+}
+
 // MustConstraint will parse one or more constraints from the given
 // constraint string. The string must be a comma-separated list of
 // constraints. It panics if there is an error.
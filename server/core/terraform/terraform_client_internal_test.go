@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	version "github.com/hashicorp/go-version"
@@ -126,6 +127,198 @@ func TestDefaultClient_RunCommandWithVersion_EnvVars(t *testing.T) {
 	Equals(t, exp, out)
 }
 
+// Test that inits using a shared plugin cache don't run concurrently.
+func TestDefaultClient_RunCommandWithVersion_PluginCacheInitsDontOverlap(t *testing.T) {
+	v, err := version.NewVersion("0.11.11")
+	Ok(t, err)
+	tmp := t.TempDir()
+	Ok(t, os.Mkdir(filepath.Join(tmp, "plugin-cache"), 0700))
+	// The fake terraform fails if another one is running in the same dir.
+	fakeTF := filepath.Join(tmp, "terraform")
+	Ok(t, os.WriteFile(fakeTF, []byte("#!/bin/sh\nmkdir \"$DIR/running\" || exit 1\nsleep 0.1\nrmdir \"$DIR/running\"\necho \"$TF_PLUGIN_CACHE_DIR\"\n"), 0700)) // nolint: gosec
+	client := &DefaultClient{
+		defaultVersion:          v,
+		terraformPluginCacheDir: filepath.Join(tmp, "plugin-cache"),
+		overrideTF:              fakeTF,
+		usePluginCache:          true,
+		sharedPluginCache:       true,
+		projectCmdOutputHandler: jobmocks.NewMockProjectCommandOutputHandler(),
+	}
+	ctx := command.ProjectContext{
+		Log:        logging.NewNoopLogger(t),
+		Workspace:  "default",
+		RepoRelDir: ".",
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	outs := make([]string, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			outs[i], errs[i] = client.RunCommandWithVersion(ctx, tmp, []string{"init", "-input=false"}, map[string]string{}, nil, "default")
+		}(i)
+	}
+	wg.Wait()
+	for i := range errs {
+		Ok(t, errs[i])
+		Equals(t, filepath.Join(tmp, "plugin-cache")+"\n", outs[i])
+	}
+}
+
+// Test that inits don't lock a plugin cache that isn't shared.
+func TestDefaultClient_RunCommandWithVersion_PluginCacheNotShared(t *testing.T) {
+	v, err := version.NewVersion("0.11.11")
+	Ok(t, err)
+	tmp := t.TempDir()
+	cacheDir := filepath.Join(tmp, "plugin-cache")
+	Ok(t, os.Mkdir(cacheDir, 0700))
+	client := &DefaultClient{
+		defaultVersion:          v,
+		terraformPluginCacheDir: cacheDir,
+		overrideTF:              "echo",
+		usePluginCache:          true,
+		projectCmdOutputHandler: jobmocks.NewMockProjectCommandOutputHandler(),
+	}
+	ctx := command.ProjectContext{
+		Log:        logging.NewNoopLogger(t),
+		Workspace:  "default",
+		RepoRelDir: ".",
+	}
+
+	_, err = client.RunCommandWithVersion(ctx, tmp, []string{"init"}, map[string]string{}, nil, "default")
+	Ok(t, err)
+	_, err = os.Stat(filepath.Join(cacheDir, pluginCacheLockFile))
+	Assert(t, os.IsNotExist(err), "exp no lock file, got %v", err)
+}
+
+// Test that a project's terraform binary is run instead of the one for its
+// version, and that a bad binary fails before running anything.
+func TestDefaultClient_RunCommandWithVersion_TerraformBinary(t *testing.T) {
+	v, err := version.NewVersion("0.11.11")
+	Ok(t, err)
+	tmp := t.TempDir()
+	customTF := filepath.Join(tmp, "terraform-custom")
+	Ok(t, os.WriteFile(customTF, []byte("#!/bin/sh\necho \"custom $@\"\n"), 0700)) // nolint: gosec
+	notExecutable := filepath.Join(tmp, "terraform-not-executable")
+	Ok(t, os.WriteFile(notExecutable, nil, 0600))
+	client := &DefaultClient{
+		defaultVersion:          v,
+		binDir:                  tmp,
+		versions:                map[string]string{},
+		projectCmdOutputHandler: jobmocks.NewMockProjectCommandOutputHandler(),
+	}
+
+	cases := []struct {
+		description string
+		binary      string
+		expOut      string
+		expErr      string
+	}{
+		{
+			description: "custom binary",
+			binary:      customTF,
+			expOut:      "custom output -json\n",
+		},
+		{
+			description: "missing binary",
+			binary:      filepath.Join(tmp, "missing"),
+			expErr:      fmt.Sprintf("terraform_binary %q: stat %s: no such file or directory", filepath.Join(tmp, "missing"), filepath.Join(tmp, "missing")),
+		},
+		{
+			description: "not executable",
+			binary:      notExecutable,
+			expErr:      fmt.Sprintf("terraform_binary %q isn't an executable file", notExecutable),
+		},
+		{
+			description: "directory",
+			binary:      tmp,
+			expErr:      fmt.Sprintf("terraform_binary %q isn't an executable file", tmp),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			ctx := command.ProjectContext{
+				Log:             logging.NewNoopLogger(t),
+				Workspace:       "default",
+				RepoRelDir:      ".",
+				TerraformBinary: c.binary,
+			}
+			out, err := client.RunCommandWithVersion(ctx, tmp, []string{"output", "-json"}, map[string]string{}, nil, "default")
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.expOut, out)
+		})
+	}
+}
+
+// Test that terragrunt is run instead of terraform for terragrunt workflows,
+// wrapping the terraform binary for the project's version, and that its logs
+// are stripped from the output.
+func TestDefaultClient_RunCommandWithVersion_Terragrunt(t *testing.T) {
+	v, err := version.NewVersion("1.5.7")
+	Ok(t, err)
+	tmp := t.TempDir()
+	binDir := filepath.Join(tmp, "bin")
+	Ok(t, os.Mkdir(binDir, 0700))
+	fakeTG := "#!/bin/sh\n" +
+		"echo 'time=2023-08-05T12:00:00Z level=info msg=Running terraform'\n" +
+		"echo \"12:00:00.000 STDOUT terraform: $TERRAGRUNT_TFPATH $TERRAGRUNT_NON_INTERACTIVE $@\"\n"
+	Ok(t, os.WriteFile(filepath.Join(binDir, TerragruntBinaryName), []byte(fakeTG), 0700)) // nolint: gosec
+	client := &DefaultClient{
+		defaultVersion:          v,
+		overrideTF:              "/usr/bin/terraform1.5.7",
+		projectCmdOutputHandler: jobmocks.NewMockProjectCommandOutputHandler(),
+	}
+	ctx := command.ProjectContext{
+		Log:        logging.NewNoopLogger(t),
+		Workspace:  "default",
+		RepoRelDir: ".",
+		Terragrunt: true,
+	}
+
+	t.Run("plan", func(t *testing.T) {
+		t.Setenv("PATH", binDir+":"+os.Getenv("PATH"))
+		out, err := client.RunCommandWithVersion(ctx, tmp, []string{"plan", "-input=false"}, map[string]string{}, nil, "default")
+		Ok(t, err)
+		Equals(t, "/usr/bin/terraform1.5.7 true plan -input=false\n", out)
+	})
+
+	t.Run("synchronous command", func(t *testing.T) {
+		t.Setenv("PATH", binDir+":"+os.Getenv("PATH"))
+		out, err := client.RunCommandWithVersion(ctx, tmp, []string{"show", "-json"}, map[string]string{}, nil, "default")
+		Ok(t, err)
+		Equals(t, "/usr/bin/terraform1.5.7 true show -json\n", out)
+	})
+
+	t.Run("terragrunt not installed", func(t *testing.T) {
+		t.Setenv("PATH", tmp)
+		_, err := client.RunCommandWithVersion(ctx, tmp, []string{"show", "-json"}, map[string]string{}, nil, "default")
+		ErrContains(t, "terragrunt workflow: exec: \"terragrunt\": executable file not found in $PATH", err)
+	})
+}
+
+func TestStripTerragruntLogs(t *testing.T) {
+	output := strings.Join([]string{
+		"[terragrunt] 2019/08/05 12:00:00 Running command: terraform plan",
+		"INFO[0000] Downloading Terraform configurations",
+		`time="2023-08-05T12:00:00Z" level=warning msg="No double-slash (//) found"`,
+		"12:00:00.000 INFO   Downloading Terraform configurations",
+		"12:00:00.000 STDOUT terraform: Plan: 1 to add, 0 to change, 0 to destroy.",
+		"12:00:00.000 STDOUT [vpc] terraform:   + resource \"null_resource\" \"a\" {",
+		"No changes. Your infrastructure matches the configuration.",
+	}, "\n")
+	Equals(t, strings.Join([]string{
+		"Plan: 1 to add, 0 to change, 0 to destroy.",
+		"  + resource \"null_resource\" \"a\" {",
+		"No changes. Your infrastructure matches the configuration.",
+	}, "\n"), StripTerragruntLogs(output))
+}
+
 // Test that it returns an error on error.
 func TestDefaultClient_RunCommandWithVersion_Error(t *testing.T) {
 	v, err := version.NewVersion("0.11.11")
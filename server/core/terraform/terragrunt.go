@@ -0,0 +1,39 @@
+package terraform
+
+import (
+	"regexp"
+	"strings"
+)
+
+// TerragruntBinaryName is the name of the terragrunt binary that's looked up
+// in the PATH for workflows that run terragrunt.
+const TerragruntBinaryName = "terragrunt"
+
+var (
+	// terragruntLogLine matches the lines that terragrunt logs itself in its
+	// different log formats, ex.
+	//   [terragrunt] 2019/08/05 12:00:00 Running command: terraform plan
+	//   INFO[0000] Downloading Terraform configurations
+	//   time=2023-08-05T12:00:00Z level=info msg=Downloading Terraform configurations
+	//   12:00:00.000 INFO   Downloading Terraform configurations
+	terragruntLogLine = regexp.MustCompile(`^(\[terragrunt\]|(TRAC|DEBU|INFO|WARN|ERRO)\[\d+\]|time=\S+ level=\w+ |\d{2}:\d{2}:\d{2}\.\d{3} +(TRACE|DEBUG|INFO|WARN|ERROR) )`)
+	// terragruntOutputLine matches the lines of terraform's output that newer
+	// terragrunt versions prefix, ex.
+	//   12:00:00.000 STDOUT terraform: Plan: 1 to add, 0 to change, 0 to destroy.
+	terragruntOutputLine = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}\.\d{3} +(STDOUT|STDERR) +(\[[^\]]*\] +)?\S+: ?`)
+)
+
+// StripTerragruntLogs removes the lines that terragrunt logs itself from
+// output and the prefixes it adds to terraform's output, so the output can be
+// parsed like terraform's.
+func StripTerragruntLogs(output string) string {
+	lines := strings.Split(output, "\n")
+	stripped := lines[:0]
+	for _, line := range lines {
+		if terragruntLogLine.MatchString(line) {
+			continue
+		}
+		stripped = append(stripped, terragruntOutputLine.ReplaceAllString(line, ""))
+	}
+	return strings.Join(stripped, "\n")
+}
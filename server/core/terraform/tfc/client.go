@@ -0,0 +1,409 @@
+// Package tfc is a client for the parts of the Terraform Cloud and Terraform
+// Enterprise API that drive runs.
+package tfc
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const jsonAPIContentType = "application/vnd.api+json"
+
+// Run statuses, see
+// https://developer.hashicorp.com/terraform/cloud-docs/api-docs/run#run-states.
+const (
+	RunApplied            = "applied"
+	RunCanceled           = "canceled"
+	RunDiscarded          = "discarded"
+	RunErrored            = "errored"
+	RunForceCanceled      = "force_canceled"
+	RunPlannedAndFinished = "planned_and_finished"
+	RunPolicySoftFailed   = "policy_soft_failed"
+)
+
+// ansiRegex matches the color codes in run logs.
+var ansiRegex = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// Client calls the Terraform Cloud API.
+type Client struct {
+	address    string
+	token      string
+	httpClient *http.Client
+}
+
+// Workspace is a Terraform Cloud workspace.
+type Workspace struct {
+	ID string
+	// WorkingDirectory is the directory Terraform runs in, relative to the
+	// root of the uploaded configuration.
+	WorkingDirectory string
+}
+
+// Run is a Terraform Cloud run.
+type Run struct {
+	ID     string
+	Status string
+	// IsConfirmable is true if the run is planned and waiting to be applied.
+	IsConfirmable bool
+	PlanID        string
+	ApplyID       string
+}
+
+// resource is a JSON:API resource.
+type resource struct {
+	ID            string                  `json:"id,omitempty"`
+	Type          string                  `json:"type"`
+	Attributes    json.RawMessage         `json:"attributes,omitempty"`
+	Relationships map[string]relationship `json:"relationships,omitempty"`
+}
+
+type relationship struct {
+	Data *resource `json:"data"`
+}
+
+type document struct {
+	Data resource `json:"data"`
+}
+
+// NewClient returns a client for the Terraform Cloud or Enterprise instance at
+// address, ex. https://app.terraform.io, authenticated with token.
+func NewClient(address string, token string) *Client {
+	return &Client{
+		address:    strings.TrimSuffix(address, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: time.Minute},
+	}
+}
+
+// Hostname is the hostname of the Terraform Cloud instance.
+func (c *Client) Hostname() string {
+	u, err := url.Parse(c.address)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// RunURL is the URL of run in the Terraform Cloud UI.
+func (c *Client) RunURL(organization string, workspace string, runID string) string {
+	return fmt.Sprintf("%s/app/%s/workspaces/%s/runs/%s", c.address, url.PathEscape(organization), url.PathEscape(workspace), runID)
+}
+
+// ReadWorkspace returns the workspace called name in organization.
+func (c *Client) ReadWorkspace(organization string, name string) (Workspace, error) {
+	var res resource
+	if err := c.do(http.MethodGet, fmt.Sprintf("/organizations/%s/workspaces/%s", url.PathEscape(organization), url.PathEscape(name)), nil, &res); err != nil {
+		return Workspace{}, err
+	}
+	var attrs struct {
+		WorkingDirectory string `json:"working-directory"`
+	}
+	if err := json.Unmarshal(res.Attributes, &attrs); err != nil {
+		return Workspace{}, fmt.Errorf("parsing workspace: %w", err)
+	}
+	return Workspace{ID: res.ID, WorkingDirectory: attrs.WorkingDirectory}, nil
+}
+
+// UploadConfiguration uploads the Terraform configuration in dir to a new
+// configuration version of the workspace and returns its ID once it's
+// uploaded. The .git and .terraform directories aren't uploaded.
+func (c *Client) UploadConfiguration(workspaceID string, dir string, pollInterval time.Duration) (string, error) {
+	var res resource
+	req := resource{
+		Type:       "configuration-versions",
+		Attributes: json.RawMessage(`{"auto-queue-runs":false}`),
+	}
+	if err := c.do(http.MethodPost, fmt.Sprintf("/workspaces/%s/configuration-versions", workspaceID), req, &res); err != nil {
+		return "", err
+	}
+	var attrs struct {
+		UploadURL string `json:"upload-url"`
+	}
+	if err := json.Unmarshal(res.Attributes, &attrs); err != nil {
+		return "", fmt.Errorf("parsing configuration version: %w", err)
+	}
+
+	archive, err := archiveDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("archiving configuration: %w", err)
+	}
+	upload, err := http.NewRequest(http.MethodPut, attrs.UploadURL, bytes.NewReader(archive))
+	if err != nil {
+		return "", err
+	}
+	upload.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := c.httpClient.Do(upload)
+	if err != nil {
+		return "", fmt.Errorf("uploading configuration: %w", err)
+	}
+	resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("uploading configuration returned %d", resp.StatusCode)
+	}
+
+	// The configuration version is processed asynchronously.
+	for {
+		if err := c.do(http.MethodGet, fmt.Sprintf("/configuration-versions/%s", res.ID), nil, &res); err != nil {
+			return "", err
+		}
+		var state struct {
+			Status       string `json:"status"`
+			ErrorMessage string `json:"error-message"`
+		}
+		if err := json.Unmarshal(res.Attributes, &state); err != nil {
+			return "", fmt.Errorf("parsing configuration version: %w", err)
+		}
+		switch state.Status {
+		case "uploaded":
+			return res.ID, nil
+		case "errored":
+			return "", fmt.Errorf("configuration version %s errored: %s", res.ID, state.ErrorMessage)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// CreateRun queues a run of the configuration version in the workspace. The
+// run waits to be applied once it's planned.
+func (c *Client) CreateRun(workspaceID string, configurationVersionID string, message string) (Run, error) {
+	attrs, err := json.Marshal(map[string]interface{}{
+		"message":    message,
+		"auto-apply": false,
+	})
+	if err != nil {
+		return Run{}, err
+	}
+	req := resource{
+		Type:       "runs",
+		Attributes: attrs,
+		Relationships: map[string]relationship{
+			"workspace":             {Data: &resource{Type: "workspaces", ID: workspaceID}},
+			"configuration-version": {Data: &resource{Type: "configuration-versions", ID: configurationVersionID}},
+		},
+	}
+	var res resource
+	if err := c.do(http.MethodPost, "/runs", req, &res); err != nil {
+		return Run{}, err
+	}
+	return parseRun(res)
+}
+
+// ReadRun returns the run with runID.
+func (c *Client) ReadRun(runID string) (Run, error) {
+	var res resource
+	if err := c.do(http.MethodGet, fmt.Sprintf("/runs/%s", runID), nil, &res); err != nil {
+		return Run{}, err
+	}
+	return parseRun(res)
+}
+
+// ApplyRun applies the planned run with runID.
+func (c *Client) ApplyRun(runID string, comment string) error {
+	return c.runAction(runID, "apply", comment)
+}
+
+// DiscardRun discards the run with runID so it doesn't block the workspace.
+func (c *Client) DiscardRun(runID string, comment string) error {
+	return c.runAction(runID, "discard", comment)
+}
+
+// CancelRun cancels the run with runID while it's planning or applying.
+func (c *Client) CancelRun(runID string, comment string) error {
+	return c.runAction(runID, "cancel", comment)
+}
+
+// PlanLog returns the log of the plan with planID so far.
+func (c *Client) PlanLog(planID string) (string, error) {
+	return c.log("plans", planID)
+}
+
+// ApplyLog returns the log of the apply with applyID so far.
+func (c *Client) ApplyLog(applyID string) (string, error) {
+	return c.log("applies", applyID)
+}
+
+func (c *Client) runAction(runID string, action string, comment string) error {
+	body, err := json.Marshal(map[string]string{"comment": comment})
+	if err != nil {
+		return err
+	}
+	return c.doRaw(http.MethodPost, fmt.Sprintf("/runs/%s/actions/%s", runID, action), body, nil)
+}
+
+// log returns the log of the plan or apply with id as plain text. Structured
+// run output is converted to its messages.
+func (c *Client) log(kind string, id string) (string, error) {
+	var res resource
+	if err := c.do(http.MethodGet, fmt.Sprintf("/%s/%s", kind, id), nil, &res); err != nil {
+		return "", err
+	}
+	var attrs struct {
+		LogReadURL string `json:"log-read-url"`
+	}
+	if err := json.Unmarshal(res.Attributes, &attrs); err != nil {
+		return "", fmt.Errorf("parsing %s: %w", kind, err)
+	}
+	resp, err := c.httpClient.Get(attrs.LogReadURL)
+	if err != nil {
+		return "", fmt.Errorf("reading log: %w", err)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("reading log returned %d", resp.StatusCode)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		var structured struct {
+			Message *string `json:"@message"`
+		}
+		if strings.HasPrefix(line, "{") && json.Unmarshal([]byte(line), &structured) == nil && structured.Message != nil {
+			line = *structured.Message
+		}
+		lines = append(lines, ansiRegex.ReplaceAllString(line, ""))
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("reading log: %w", err)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// do calls the API at path, relative to /api/v2, with req as the data of the
+// request body if it's not nil, and unmarshals the data of the response into
+// res if it's not nil.
+func (c *Client) do(method string, path string, req interface{}, res *resource) error {
+	var body []byte
+	if req != nil {
+		var err error
+		if body, err = json.Marshal(map[string]interface{}{"data": req}); err != nil {
+			return err
+		}
+	}
+	return c.doRaw(method, path, body, res)
+}
+
+func (c *Client) doRaw(method string, path string, body []byte, res *resource) error {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, c.address+"/api/v2"+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", jsonAPIContentType)
+	req.Header.Set("Accept", jsonAPIContentType)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s %s: reading response: %w", method, path, err)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s returned %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	if res == nil {
+		return nil
+	}
+	var doc document
+	if err := json.Unmarshal(respBody, &doc); err != nil {
+		return fmt.Errorf("%s %s: parsing response: %w", method, path, err)
+	}
+	*res = doc.Data
+	return nil
+}
+
+func parseRun(res resource) (Run, error) {
+	var attrs struct {
+		Status  string `json:"status"`
+		Actions struct {
+			IsConfirmable bool `json:"is-confirmable"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal(res.Attributes, &attrs); err != nil {
+		return Run{}, fmt.Errorf("parsing run: %w", err)
+	}
+	run := Run{
+		ID:            res.ID,
+		Status:        attrs.Status,
+		IsConfirmable: attrs.Actions.IsConfirmable,
+	}
+	if plan := res.Relationships["plan"].Data; plan != nil {
+		run.PlanID = plan.ID
+	}
+	if apply := res.Relationships["apply"].Data; apply != nil {
+		run.ApplyID = apply.ID
+	}
+	return run, nil
+}
+
+// archiveDir returns a gzipped tarball of the files in dir.
+func archiveDir(dir string) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (d.Name() == ".git" || d.Name() == ".terraform") {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    filepath.ToSlash(rel),
+			Mode:    int64(info.Mode().Perm()),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		}); err != nil {
+			return err
+		}
+		f, err := os.Open(path) // nolint: gosec
+		if err != nil {
+			return err
+		}
+		defer f.Close() // nolint: errcheck
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,144 @@
+package tfc_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/core/terraform/tfc"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestClient_ReadWorkspace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "GET /api/v2/organizations/acme/workspaces/networking", r.Method+" "+r.URL.Path)
+		Equals(t, "Bearer token", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"data":{"id":"ws-1","type":"workspaces","attributes":{"name":"networking","working-directory":"envs/prod"}}}`)) // nolint: errcheck
+	}))
+	defer server.Close()
+
+	workspace, err := tfc.NewClient(server.URL, "token").ReadWorkspace("acme", "networking")
+	Ok(t, err)
+	Equals(t, tfc.Workspace{ID: "ws-1", WorkingDirectory: "envs/prod"}, workspace)
+}
+
+func TestClient_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors":[{"status":"404","title":"not found"}]}`)) // nolint: errcheck
+	}))
+	defer server.Close()
+
+	_, err := tfc.NewClient(server.URL, "token").ReadRun("run-1")
+	ErrEquals(t, `GET /runs/run-1 returned 404: {"errors":[{"status":"404","title":"not found"}]}`, err)
+}
+
+func TestClient_UploadConfiguration(t *testing.T) {
+	dir := t.TempDir()
+	Ok(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`resource "null_resource" "a" {}`), 0600))
+	Ok(t, os.MkdirAll(filepath.Join(dir, "modules", "vpc"), 0700))
+	Ok(t, os.WriteFile(filepath.Join(dir, "modules", "vpc", "main.tf"), []byte(""), 0600))
+	Ok(t, os.MkdirAll(filepath.Join(dir, ".terraform", "providers"), 0700))
+	Ok(t, os.WriteFile(filepath.Join(dir, ".terraform", "providers", "provider"), []byte("binary"), 0600))
+
+	var uploaded []string
+	statusReads := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v2/workspaces/ws-1/configuration-versions":
+			body, _ := io.ReadAll(r.Body)
+			Equals(t, `{"data":{"type":"configuration-versions","attributes":{"auto-queue-runs":false}}}`, string(body))
+			w.Write([]byte(`{"data":{"id":"cv-1","type":"configuration-versions","attributes":{"status":"pending","upload-url":"` + server.URL + `/upload/cv-1"}}}`)) // nolint: errcheck
+		case "PUT /upload/cv-1":
+			gz, err := gzip.NewReader(r.Body)
+			Ok(t, err)
+			tr := tar.NewReader(gz)
+			for {
+				header, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				Ok(t, err)
+				uploaded = append(uploaded, header.Name)
+			}
+		case "GET /api/v2/configuration-versions/cv-1":
+			// The configuration version is processed after a poll.
+			status := "pending"
+			if statusReads++; statusReads > 1 {
+				status = "uploaded"
+			}
+			w.Write([]byte(`{"data":{"id":"cv-1","type":"configuration-versions","attributes":{"status":"` + status + `"}}}`)) // nolint: errcheck
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	id, err := tfc.NewClient(server.URL, "token").UploadConfiguration("ws-1", dir, 0)
+	Ok(t, err)
+	Equals(t, "cv-1", id)
+	Equals(t, 2, statusReads)
+	sort.Strings(uploaded)
+	Equals(t, []string{"main.tf", "modules/vpc/main.tf"}, uploaded)
+}
+
+func TestClient_CreateRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "POST /api/v2/runs", r.Method+" "+r.URL.Path)
+		var req map[string]interface{}
+		Ok(t, json.NewDecoder(r.Body).Decode(&req))
+		Equals(t, map[string]interface{}{
+			"data": map[string]interface{}{
+				"type":       "runs",
+				"attributes": map[string]interface{}{"message": "plan", "auto-apply": false},
+				"relationships": map[string]interface{}{
+					"workspace":             map[string]interface{}{"data": map[string]interface{}{"id": "ws-1", "type": "workspaces"}},
+					"configuration-version": map[string]interface{}{"data": map[string]interface{}{"id": "cv-1", "type": "configuration-versions"}},
+				},
+			},
+		}, req)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"data":{"id":"run-1","type":"runs","attributes":{"status":"pending","actions":{"is-confirmable":false}},"relationships":{"plan":{"data":{"id":"plan-1","type":"plans"}},"apply":{"data":{"id":"apply-1","type":"applies"}}}}}`)) // nolint: errcheck
+	}))
+	defer server.Close()
+
+	run, err := tfc.NewClient(server.URL, "token").CreateRun("ws-1", "cv-1", "plan")
+	Ok(t, err)
+	Equals(t, tfc.Run{ID: "run-1", Status: "pending", PlanID: "plan-1", ApplyID: "apply-1"}, run)
+}
+
+func TestClient_PlanLog(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/plans/plan-1":
+			w.Write([]byte(`{"data":{"id":"plan-1","type":"plans","attributes":{"log-read-url":"` + server.URL + `/logs/plan-1"}}}`)) // nolint: errcheck
+		case "/logs/plan-1":
+			// Logs can be structured run output or colored text.
+			w.Write([]byte("{\"@level\":\"info\",\"@message\":\"Terraform v1.5.0\",\"type\":\"version\"}\n\x1b[1mPlan:\x1b[0m 1 to add, 0 to change, 0 to destroy.")) // nolint: errcheck
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	log, err := tfc.NewClient(server.URL, "token").PlanLog("plan-1")
+	Ok(t, err)
+	Equals(t, "Terraform v1.5.0\nPlan: 1 to add, 0 to change, 0 to destroy.", log)
+}
+
+func TestClient_RunURL(t *testing.T) {
+	client := tfc.NewClient("https://tfe.example.com/", "token")
+	Equals(t, "tfe.example.com", client.Hostname())
+	Equals(t, "https://tfe.example.com/app/acme/workspaces/networking/runs/run-1", client.RunURL("acme", "networking", "run-1"))
+}
@@ -1,6 +1,10 @@
 package events
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/core/config/valid"
 	"github.com/runatlantis/atlantis/server/core/locking"
 	"github.com/runatlantis/atlantis/server/events/command"
 	"github.com/runatlantis/atlantis/server/events/models"
@@ -22,6 +26,7 @@ func NewApplyCommandRunner(
 	SilenceNoProjects bool,
 	silenceVCSStatusNoProjects bool,
 	pullReqStatusFetcher vcs.PullReqStatusFetcher,
+	applyAllowlist *UserAllowlistChecker,
 ) *ApplyCommandRunner {
 	return &ApplyCommandRunner{
 		vcsClient:                  vcsClient,
@@ -38,6 +43,7 @@ func NewApplyCommandRunner(
 		SilenceNoProjects:          SilenceNoProjects,
 		silenceVCSStatusNoProjects: silenceVCSStatusNoProjects,
 		pullReqStatusFetcher:       pullReqStatusFetcher,
+		applyAllowlist:             applyAllowlist,
 	}
 }
 
@@ -60,6 +66,21 @@ type ApplyCommandRunner struct {
 	// SilenceVCSStatusNoPlans is whether any plan should set commit status if no projects
 	// are found
 	silenceVCSStatusNoProjects bool
+	// applyAllowlist restricts which users and teams can run apply. If it has
+	// no rules anyone can apply.
+	applyAllowlist *UserAllowlistChecker
+	// GlobalCfg is the server-side repo config, used to reject applies on
+	// plan-only repos.
+	GlobalCfg valid.GlobalCfg
+	// StalePlanRetrier plans and applies again projects whose plans were
+	// stale. If nil, they're left failed.
+	StalePlanRetrier *StalePlanRetrier
+	// PendingApplies holds the applies waiting for `atlantis confirm` on
+	// repos with confirm_apply set.
+	PendingApplies *PendingApplies
+	// CodeOwnersApplyFilter only applies the projects owned by a user who
+	// approved the pull request. If nil, all projects are applied.
+	CodeOwnersApplyFilter *CodeOwnersApplyFilter
 }
 
 func (a *ApplyCommandRunner) Run(ctx *command.Context, cmd *CommentCommand) {
@@ -67,7 +88,7 @@ func (a *ApplyCommandRunner) Run(ctx *command.Context, cmd *CommentCommand) {
 	baseRepo := ctx.Pull.BaseRepo
 	pull := ctx.Pull
 
-	locked, err := a.IsLocked()
+	lock, err := a.locker.CheckApplyLock()
 	// CheckApplyLock falls back to DisableApply flag if fetching the lock
 	// raises an error
 	// We will log failure as warning
@@ -75,11 +96,43 @@ func (a *ApplyCommandRunner) Run(ctx *command.Context, cmd *CommentCommand) {
 		ctx.Log.Warn("checking global apply lock: %s", err)
 	}
 
-	if locked {
+	if lock.Locked {
 		ctx.Log.Info("ignoring apply command since apply disabled globally")
-		if err := a.vcsClient.CreateComment(baseRepo, pull.Num, applyDisabledComment, command.Apply.String()); err != nil {
+		comment := applyDisabledComment
+		if lock.Message != "" {
+			comment += "\n\n" + lock.Message
+		}
+		if err := a.vcsClient.CreateComment(baseRepo, pull.Num, comment, command.Apply.String()); err != nil {
+			ctx.Log.Err("unable to comment on pull request: %s", err)
+		}
+		addAuditResult(ctx, command.Result{Failure: comment})
+
+		return
+	}
+
+	if a.GlobalCfg.PlanOnly(baseRepo.ID()) {
+		ctx.Log.Info("ignoring apply command since repo is plan-only")
+		if err := a.vcsClient.CreateComment(baseRepo, pull.Num, applyPlanOnlyComment, command.Apply.String()); err != nil {
 			ctx.Log.Err("unable to comment on pull request: %s", err)
 		}
+		addAuditResult(ctx, command.Result{Failure: applyPlanOnlyComment})
+
+		return
+	}
+
+	allowed, err := a.applyAllowlist.IsAllowed(ctx.User.Username, func() ([]string, error) {
+		return a.vcsClient.GetTeamNamesForUser(baseRepo, ctx.User)
+	})
+	if err != nil {
+		ctx.Log.Err("unable to check if user %s is allowed to apply: %s", ctx.User.Username, err)
+	}
+	if !allowed {
+		ctx.Log.Info("ignoring apply command since user %s is not in the apply allowlist", ctx.User.Username)
+		comment := a.pullUpdater.MarkdownRenderer.RenderUnauthorized(ctx.User.Username, command.Apply.String(), false)
+		if err := a.vcsClient.CreateComment(baseRepo, pull.Num, comment, command.Apply.String()); err != nil {
+			ctx.Log.Err("unable to comment on pull request: %s", err)
+		}
+		addAuditResult(ctx, command.Result{Failure: fmt.Sprintf("User @%s is not in the apply allowlist", ctx.User.Username)})
 
 		return
 	}
@@ -89,7 +142,12 @@ func (a *ApplyCommandRunner) Run(ctx *command.Context, cmd *CommentCommand) {
 		if err := a.vcsClient.CreateComment(baseRepo, pull.Num, applyAllDisabledComment, command.Apply.String()); err != nil {
 			ctx.Log.Err("unable to comment on pull request: %s", err)
 		}
+		addAuditResult(ctx, command.Result{Failure: applyAllDisabledComment})
+
+		return
+	}
 
+	if a.GlobalCfg.ConfirmApply(baseRepo.ID()) && !cmd.Confirmed && a.requestConfirmation(ctx, cmd) {
 		return
 	}
 
@@ -157,6 +215,8 @@ func (a *ApplyCommandRunner) Run(ctx *command.Context, cmd *CommentCommand) {
 		return
 	}
 
+	projectCmds, notApproved := a.CodeOwnersApplyFilter.Filter(ctx, projectCmds)
+
 	// Only run commands in parallel if enabled
 	var result command.Result
 	if a.isParallelEnabled(projectCmds) {
@@ -165,6 +225,9 @@ func (a *ApplyCommandRunner) Run(ctx *command.Context, cmd *CommentCommand) {
 	} else {
 		result = runProjectCmds(projectCmds, a.prjCmdRunner.Apply)
 	}
+	result = a.StalePlanRetrier.Retry(ctx, cmd, projectCmds, result)
+	result.ProjectResults = append(result.ProjectResults, notApproved...)
+	ctx.ProjectResults = result.ProjectResults
 
 	a.pullUpdater.updatePull(
 		ctx,
@@ -184,6 +247,44 @@ func (a *ApplyCommandRunner) Run(ctx *command.Context, cmd *CommentCommand) {
 	}
 }
 
+// requestConfirmation records cmd as waiting for `atlantis confirm` and
+// comments with the projects it would apply. It returns false if there's
+// nothing to apply, in which case the apply should run as usual to report it.
+// If the projects can't be found it comments the error and returns true so
+// the apply never runs unconfirmed.
+func (a *ApplyCommandRunner) requestConfirmation(ctx *command.Context, cmd *CommentCommand) bool {
+	projectCmds, err := a.prjCmdBuilder.BuildApplyCommands(ctx, cmd)
+	if err != nil {
+		ctx.Log.Err("finding projects to confirm: %s", err)
+		if err := a.vcsClient.CreateComment(ctx.Pull.BaseRepo, ctx.Pull.Num, fmt.Sprintf(applyConfirmationErrComment, err), command.Apply.String()); err != nil {
+			ctx.Log.Err("unable to comment on pull request: %s", err)
+		}
+		addAuditResult(ctx, command.Result{Error: err})
+		return true
+	}
+	if len(projectCmds) == 0 {
+		return false
+	}
+
+	a.PendingApplies.Add(ctx.Pull, ctx.PullStatus, cmd)
+	ctx.Log.Info("waiting for confirmation to apply %d project(s)", len(projectCmds))
+
+	var projects []string
+	for _, p := range projectCmds {
+		if p.ProjectName != "" {
+			projects = append(projects, fmt.Sprintf("* project: `%s` dir: `%s` workspace: `%s`", p.ProjectName, p.RepoRelDir, p.Workspace))
+		} else {
+			projects = append(projects, fmt.Sprintf("* dir: `%s` workspace: `%s`", p.RepoRelDir, p.Workspace))
+		}
+	}
+	comment := fmt.Sprintf(applyConfirmationComment, strings.Join(projects, "\n"))
+	if err := a.vcsClient.CreateComment(ctx.Pull.BaseRepo, ctx.Pull.Num, comment, command.Apply.String()); err != nil {
+		ctx.Log.Err("unable to comment on pull request: %s", err)
+	}
+	addAuditResult(ctx, command.Result{Failure: fmt.Sprintf("Waiting for `atlantis confirm` to apply %d project(s)", len(projectCmds))})
+	return true
+}
+
 func (a *ApplyCommandRunner) IsLocked() (bool, error) {
 	lock, err := a.locker.CheckApplyLock()
 
@@ -200,7 +301,7 @@ func (a *ApplyCommandRunner) updateCommitStatus(ctx *command.Context, pullStatus
 	status := models.SuccessCommitStatus
 
 	numSuccess = pullStatus.StatusCount(models.AppliedPlanStatus) + pullStatus.StatusCount(models.PlannedNoChangesPlanStatus)
-	numErrored = pullStatus.StatusCount(models.ErroredApplyStatus)
+	numErrored = pullStatus.StatusCount(models.ErroredApplyStatus) + pullStatus.StatusCount(models.CanceledPlanStatus)
 
 	if numErrored > 0 {
 		status = models.FailedCommitStatus
@@ -227,5 +328,18 @@ func (a *ApplyCommandRunner) updateCommitStatus(ctx *command.Context, pullStatus
 var applyAllDisabledComment = "**Error:** Running `atlantis apply` without flags is disabled." +
 	" You must specify which project to apply via the `-d <dir>`, `-w <workspace>` or `-p <project name>` flags."
 
+// applyPlanOnlyComment is posted when an apply command is issued on a repo
+// that's configured as plan-only.
+var applyPlanOnlyComment = "**Error:** This repo is plan-only, `atlantis apply` can't be run on it."
+
+// applyConfirmationComment is posted when an apply command is issued on a repo
+// whose applies must be confirmed.
+var applyConfirmationComment = "**Confirmation required:** Applies on this repo must be confirmed. This apply will apply the plans of:\n\n%s\n\n" +
+	"To apply them, comment `atlantis confirm`. Pushing new commits discards this apply."
+
+// applyConfirmationErrComment is posted when the projects an apply that must
+// be confirmed would apply can't be found.
+var applyConfirmationErrComment = "**Error:** Unable to find the plans this apply would apply, so it wasn't requested: %s\n\nRun `atlantis apply` again."
+
 // applyDisabledComment is posted when apply commands are disabled globally and an apply command is issued.
 var applyDisabledComment = "**Error:** Running `atlantis apply` is disabled."
@@ -2,16 +2,20 @@ package events_test
 
 import (
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-github/v54/github"
 	. "github.com/petergtz/pegomock/v4"
+	"github.com/runatlantis/atlantis/server/core/config/valid"
 	"github.com/runatlantis/atlantis/server/core/db"
 	"github.com/runatlantis/atlantis/server/core/locking"
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/command"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/models/testdata"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
 	"github.com/runatlantis/atlantis/server/logging"
 	"github.com/runatlantis/atlantis/server/metrics"
 	. "github.com/runatlantis/atlantis/testing"
@@ -24,6 +28,7 @@ func TestApplyCommandRunner_IsLocked(t *testing.T) {
 	cases := []struct {
 		Description    string
 		ApplyLocked    bool
+		ApplyLockMsg   string
 		ApplyLockError error
 		ExpComment     string
 	}{
@@ -33,6 +38,12 @@ func TestApplyCommandRunner_IsLocked(t *testing.T) {
 			ApplyLockError: nil,
 			ExpComment:     "**Error:** Running `atlantis apply` is disabled.",
 		},
+		{
+			Description:  "When global apply lock has a message it's commented",
+			ApplyLocked:  true,
+			ApplyLockMsg: "Applies are paused during INC-42.",
+			ExpComment:   "**Error:** Running `atlantis apply` is disabled.\n\nApplies are paused during INC-42.",
+		},
 		{
 			Description:    "When no global apply lock is present and DisableApply flag is false IsDisabled returns false",
 			ApplyLocked:    false,
@@ -69,7 +80,7 @@ func TestApplyCommandRunner_IsLocked(t *testing.T) {
 				Trigger:  command.CommentTrigger,
 			}
 
-			When(applyLockChecker.CheckApplyLock()).ThenReturn(locking.ApplyCommandLock{Locked: c.ApplyLocked}, c.ApplyLockError)
+			When(applyLockChecker.CheckApplyLock()).ThenReturn(locking.ApplyCommandLock{Locked: c.ApplyLocked, Message: c.ApplyLockMsg}, c.ApplyLockError)
 			applyCommandRunner.Run(ctx, &events.CommentCommand{Name: command.Apply})
 
 			vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, modelPull.Num, c.ExpComment, "apply")
@@ -77,6 +88,150 @@ func TestApplyCommandRunner_IsLocked(t *testing.T) {
 	}
 }
 
+func TestApplyCommandRunner_PlanOnly(t *testing.T) {
+	vcsClient := setup(t)
+	planOnly := true
+	applyCommandRunner.GlobalCfg = valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{ID: testdata.GithubRepo.ID(), PlanOnly: &planOnly},
+		},
+	}
+
+	scopeNull, _, _ := metrics.NewLoggingScope(logging.NewNoopLogger(t), "atlantis")
+	modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+	ctx := &command.Context{
+		User:     testdata.User,
+		Log:      logging.NewNoopLogger(t),
+		Scope:    scopeNull,
+		Pull:     modelPull,
+		HeadRepo: testdata.GithubRepo,
+		Trigger:  command.CommentTrigger,
+	}
+
+	applyCommandRunner.Run(ctx, &events.CommentCommand{Name: command.Apply})
+
+	vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, modelPull.Num, "**Error:** This repo is plan-only, `atlantis apply` can't be run on it.", "apply")
+	projectCommandBuilder.VerifyWasCalled(Never()).BuildApplyCommands(Any[*command.Context](), Any[*events.CommentCommand]())
+}
+
+func TestApplyCommandRunner_ConfirmApply(t *testing.T) {
+	confirmApply := true
+	projectCtx := command.ProjectContext{
+		CommandName: command.Apply,
+		ProjectName: "proj",
+		RepoRelDir:  ".",
+		Workspace:   "default",
+	}
+	confirmationComment := "**Confirmation required:** Applies on this repo must be confirmed. This apply will apply the plans of:\n\n" +
+		"* project: `proj` dir: `.` workspace: `default`\n\n" +
+		"To apply them, comment `atlantis confirm`. Pushing new commits discards this apply."
+
+	setupConfirm := func(t *testing.T) (*vcsmocks.MockClient, *events.ConfirmCommandRunner, *command.Context) {
+		vcsClient := setup(t)
+		applyCommandRunner.GlobalCfg = valid.GlobalCfg{
+			Repos: []valid.Repo{
+				{ID: testdata.GithubRepo.ID(), ConfirmApply: &confirmApply},
+			},
+		}
+		applyCommandRunner.PendingApplies = events.NewPendingApplies()
+		confirmCommandRunner := events.NewConfirmCommandRunner(applyCommandRunner.PendingApplies, applyCommandRunner, vcsClient)
+
+		scopeNull, _, _ := metrics.NewLoggingScope(logging.NewNoopLogger(t), "atlantis")
+		ctx := &command.Context{
+			User:     testdata.User,
+			Log:      logging.NewNoopLogger(t),
+			Scope:    scopeNull,
+			Pull:     models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num, HeadCommit: "abc123"},
+			HeadRepo: testdata.GithubRepo,
+			Trigger:  command.CommentTrigger,
+		}
+		When(projectCommandBuilder.BuildApplyCommands(Any[*command.Context](), Any[*events.CommentCommand]())).ThenReturn([]command.ProjectContext{projectCtx}, nil)
+		When(projectCommandRunner.Apply(Any[command.ProjectContext]())).ThenReturn(command.ProjectResult{Command: command.Apply, ApplySuccess: "success"})
+		return vcsClient, confirmCommandRunner, ctx
+	}
+
+	t.Run("apply waits for confirmation", func(t *testing.T) {
+		vcsClient, confirmCommandRunner, ctx := setupConfirm(t)
+
+		applyCommandRunner.Run(ctx, &events.CommentCommand{Name: command.Apply, ProjectName: "proj"})
+
+		vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, ctx.Pull.Num, confirmationComment, "apply")
+		projectCommandRunner.VerifyWasCalled(Never()).Apply(Any[command.ProjectContext]())
+		commitUpdater.VerifyWasCalled(Never()).UpdateCombined(Any[models.Repo](), Any[models.PullRequest](), Any[models.CommitStatus](), Any[command.Name]())
+
+		confirmCommandRunner.Run(ctx, &events.CommentCommand{Name: command.Confirm})
+
+		projectCommandRunner.VerifyWasCalledOnce().Apply(projectCtx)
+		_, cmd := projectCommandBuilder.VerifyWasCalled(Times(2)).BuildApplyCommands(Any[*command.Context](), Any[*events.CommentCommand]()).GetCapturedArguments()
+		Equals(t, &events.CommentCommand{Name: command.Apply, ProjectName: "proj", Confirmed: true}, cmd)
+	})
+
+	t.Run("confirm without apply", func(t *testing.T) {
+		vcsClient, confirmCommandRunner, ctx := setupConfirm(t)
+
+		confirmCommandRunner.Run(ctx, &events.CommentCommand{Name: command.Confirm})
+
+		vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, ctx.Pull.Num, "**Error:** There's no apply waiting for confirmation. Run `atlantis apply` first.", "confirm")
+		projectCommandRunner.VerifyWasCalled(Never()).Apply(Any[command.ProjectContext]())
+	})
+
+	t.Run("confirm twice", func(t *testing.T) {
+		vcsClient, confirmCommandRunner, ctx := setupConfirm(t)
+
+		applyCommandRunner.Run(ctx, &events.CommentCommand{Name: command.Apply})
+		confirmCommandRunner.Run(ctx, &events.CommentCommand{Name: command.Confirm})
+		confirmCommandRunner.Run(ctx, &events.CommentCommand{Name: command.Confirm})
+
+		projectCommandRunner.VerifyWasCalledOnce().Apply(projectCtx)
+		vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, ctx.Pull.Num, "**Error:** There's no apply waiting for confirmation. Run `atlantis apply` first.", "confirm")
+	})
+
+	t.Run("pull request changed since apply", func(t *testing.T) {
+		vcsClient, confirmCommandRunner, ctx := setupConfirm(t)
+
+		applyCommandRunner.Run(ctx, &events.CommentCommand{Name: command.Apply})
+		ctx.Pull.HeadCommit = "def456"
+		confirmCommandRunner.Run(ctx, &events.CommentCommand{Name: command.Confirm})
+
+		vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, ctx.Pull.Num, "**Error:** The pull request changed since `atlantis apply` was run, so the apply was discarded. Run `atlantis apply` again.", "confirm")
+		projectCommandRunner.VerifyWasCalled(Never()).Apply(Any[command.ProjectContext]())
+	})
+
+	t.Run("pull request planned again since apply", func(t *testing.T) {
+		vcsClient, confirmCommandRunner, ctx := setupConfirm(t)
+		plannedAt := time.Now()
+		ctx.PullStatus = &models.PullStatus{
+			Projects: []models.ProjectStatus{
+				{ProjectName: "proj", RepoRelDir: ".", Workspace: "default", Status: models.PlannedPlanStatus, PlannedAt: plannedAt},
+			},
+		}
+
+		applyCommandRunner.Run(ctx, &events.CommentCommand{Name: command.Apply})
+		ctx.PullStatus = &models.PullStatus{
+			Projects: []models.ProjectStatus{
+				{ProjectName: "proj", RepoRelDir: ".", Workspace: "default", Status: models.PlannedPlanStatus, PlannedAt: plannedAt.Add(time.Minute)},
+			},
+		}
+		confirmCommandRunner.Run(ctx, &events.CommentCommand{Name: command.Confirm})
+
+		vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, ctx.Pull.Num, "**Error:** The pull request was planned again since `atlantis apply` was run, so the apply was discarded. Run `atlantis apply` again to apply the new plans.", "confirm")
+		projectCommandRunner.VerifyWasCalled(Never()).Apply(Any[command.ProjectContext]())
+	})
+
+	t.Run("projects can't be found", func(t *testing.T) {
+		vcsClient, confirmCommandRunner, ctx := setupConfirm(t)
+		When(projectCommandBuilder.BuildApplyCommands(Any[*command.Context](), Any[*events.CommentCommand]())).ThenReturn(nil, errors.New("clone failed"))
+
+		applyCommandRunner.Run(ctx, &events.CommentCommand{Name: command.Apply})
+
+		vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, ctx.Pull.Num, "**Error:** Unable to find the plans this apply would apply, so it wasn't requested: clone failed\n\nRun `atlantis apply` again.", "apply")
+		projectCommandRunner.VerifyWasCalled(Never()).Apply(Any[command.ProjectContext]())
+
+		confirmCommandRunner.Run(ctx, &events.CommentCommand{Name: command.Confirm})
+		projectCommandRunner.VerifyWasCalled(Never()).Apply(Any[command.ProjectContext]())
+	})
+}
+
 func TestApplyCommandRunner_IsSilenced(t *testing.T) {
 	logger := logging.NewNoopLogger(t)
 	RegisterMockTestingT(t)
@@ -507,3 +662,146 @@ func TestApplyCommandRunner_ExecutionOrder(t *testing.T) {
 		})
 	}
 }
+
+// fakeApproversGetter returns the approvers of every pull request.
+type fakeApproversGetter struct {
+	approvers []string
+	err       error
+}
+
+func (f fakeApproversGetter) GetPullApprovers(models.Repo, models.PullRequest) ([]string, error) {
+	return f.approvers, f.err
+}
+
+func TestApplyCommandRunner_CodeOwnersApproved(t *testing.T) {
+	vcsClient := setup(t)
+	applyCommandRunner.CodeOwnersApplyFilter = &events.CodeOwnersApplyFilter{
+		VCSClient:       vcsClient,
+		ApproversGetter: fakeApproversGetter{approvers: []string{"alice", "bob"}},
+	}
+	When(vcsClient.GetFileContent(Any[models.PullRequest](), Eq(".github/CODEOWNERS"))).
+		ThenReturn(true, []byte("app/ @alice\nnetwork/ @runatlantis/network\ndocs/ @carol\n"), nil)
+	When(vcsClient.GetTeamNamesForUser(testdata.GithubRepo, models.User{Username: "alice"})).ThenReturn(nil, nil)
+	When(vcsClient.GetTeamNamesForUser(testdata.GithubRepo, models.User{Username: "bob"})).ThenReturn([]string{"Network", "network"}, nil)
+
+	scopeNull, _, _ := metrics.NewLoggingScope(logging.NewNoopLogger(t), "atlantis")
+	modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num, BaseBranch: "main"}
+	cmd := &events.CommentCommand{Name: command.Apply}
+	ctx := &command.Context{
+		User:     testdata.User,
+		Log:      logging.NewNoopLogger(t),
+		Scope:    scopeNull,
+		Pull:     modelPull,
+		HeadRepo: testdata.GithubRepo,
+		Trigger:  command.CommentTrigger,
+	}
+	projectContexts := []command.ProjectContext{
+		{RepoRelDir: "app", Workspace: "default"},
+		{RepoRelDir: "network", Workspace: "default"},
+		{RepoRelDir: "docs", Workspace: "default"},
+		{RepoRelDir: "unowned", Workspace: "default"},
+	}
+	When(projectCommandBuilder.BuildApplyCommands(ctx, cmd)).ThenReturn(projectContexts, nil)
+	for _, p := range projectContexts {
+		When(projectCommandRunner.Apply(p)).ThenReturn(command.ProjectResult{Command: command.Apply, RepoRelDir: p.RepoRelDir, Workspace: p.Workspace, ApplySuccess: "success"})
+	}
+
+	applyCommandRunner.Run(ctx, cmd)
+
+	// The CODEOWNERS file is read from the base branch.
+	pull, _ := vcsClient.VerifyWasCalled(AtLeast(1)).GetFileContent(Any[models.PullRequest](), Eq(".github/CODEOWNERS")).GetCapturedArguments()
+	Equals(t, "main", pull.HeadBranch)
+	projectCommandRunner.VerifyWasCalledOnce().Apply(projectContexts[0])
+	projectCommandRunner.VerifyWasCalledOnce().Apply(projectContexts[1])
+	projectCommandRunner.VerifyWasCalled(Never()).Apply(projectContexts[2])
+	projectCommandRunner.VerifyWasCalledOnce().Apply(projectContexts[3])
+	_, _, comment, _ := vcsClient.VerifyWasCalledOnce().CreateComment(Any[models.Repo](), Any[int](), Any[string](), Any[string]()).GetCapturedArguments()
+	Assert(t, strings.Contains(comment, "Pull request must be approved by one of the project's owners in CODEOWNERS: `@carol`"), "exp failure in comment: %s", comment)
+}
+
+func TestApplyCommandRunner_ApplySummary(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	RegisterMockTestingT(t)
+
+	projectContexts := []command.ProjectContext{
+		{ProjectName: "app", RepoRelDir: "app", Workspace: "default"},
+		{ProjectName: "network", RepoRelDir: "network", Workspace: "default"},
+	}
+	projectResults := []command.ProjectResult{
+		{
+			Command:      command.Apply,
+			ProjectName:  "app",
+			RepoRelDir:   "app",
+			Workspace:    "default",
+			ApplySuccess: "Apply complete! Resources: 1 added, 2 changed, 0 destroyed.",
+		},
+		{
+			Command:     command.Apply,
+			ProjectName: "network",
+			RepoRelDir:  "network",
+			Workspace:   "default",
+			Error:       errors.New("Shabang!"),
+		},
+	}
+	expSummary := "Ran Apply for 2 projects: 1 succeeded, 1 failed.\n\n" +
+		"| Project | Result | Changes |\n|---------|--------|---------|\n" +
+		"| project: `app` dir: `app` workspace: `default` | :heavy_check_mark: Applied | 1 added, 2 changed, 0 destroyed. |\n" +
+		"| project: `network` dir: `network` workspace: `default` | :x: Failed |  |"
+	expOutput := "Ran Apply for 2 projects:\n\n" +
+		"1. project: `app` dir: `app` workspace: `default`\n1. project: `network` dir: `network` workspace: `default`\n\n" +
+		"### 1. project: `app` dir: `app` workspace: `default`\n```diff\nApply complete! Resources: 1 added, 2 changed, 0 destroyed.\n```\n\n---\n" +
+		"### 2. project: `network` dir: `network` workspace: `default`\n**Apply Error**\n```\nShabang!\n```\n\n---"
+
+	expFailedOutput := "Ran Apply for project: `network` dir: `network` workspace: `default`\n\n**Apply Error**\n```\nShabang!\n```"
+
+	cases := []struct {
+		Description          string
+		ApplySummaryComments events.ApplySummaryComments
+		ExpOutputComment     bool
+	}{
+		{
+			Description:          "summary after output",
+			ApplySummaryComments: events.AppendApplySummaryComments,
+			ExpOutputComment:     true,
+		},
+		{
+			Description:          "summary only, after the output of failed applies",
+			ApplySummaryComments: events.OnlyApplySummaryComments,
+			ExpOutputComment:     false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Description, func(t *testing.T) {
+			vcsClient := setup(t)
+			pullUpdater.ApplySummaryComments = c.ApplySummaryComments
+
+			scopeNull, _, _ := metrics.NewLoggingScope(logger, "atlantis")
+			modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+			cmd := &events.CommentCommand{Name: command.Apply}
+			ctx := &command.Context{
+				User:     testdata.User,
+				Log:      logging.NewNoopLogger(t),
+				Scope:    scopeNull,
+				Pull:     modelPull,
+				HeadRepo: testdata.GithubRepo,
+				Trigger:  command.CommentTrigger,
+			}
+
+			When(projectCommandBuilder.BuildApplyCommands(ctx, cmd)).ThenReturn(projectContexts, nil)
+			for i := range projectContexts {
+				When(projectCommandRunner.Apply(projectContexts[i])).ThenReturn(projectResults[i])
+			}
+
+			applyCommandRunner.Run(ctx, cmd)
+
+			if c.ExpOutputComment {
+				vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, modelPull.Num, expOutput, "apply")
+			} else {
+				vcsClient.VerifyWasCalled(Never()).CreateComment(testdata.GithubRepo, modelPull.Num, expOutput, "apply")
+				vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, modelPull.Num, expFailedOutput, "apply")
+			}
+			vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, modelPull.Num, expSummary, "apply")
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package events
+
+import (
+	"github.com/runatlantis/atlantis/server/events/command"
+)
+
+// ApplySummaryComments is how the applies of more than one project are
+// summarized in a comment after they all finish.
+type ApplySummaryComments string
+
+const (
+	// AppendApplySummaryComments comments the summary after the comment
+	// with the output of each project's apply.
+	AppendApplySummaryComments ApplySummaryComments = "append"
+	// OnlyApplySummaryComments comments the summary instead of the output
+	// of each project's apply. The output of failed applies is still
+	// commented before the summary.
+	OnlyApplySummaryComments ApplySummaryComments = "only"
+)
+
+// hasApplySummary returns true if the results of cmd are summarized in their
+// own comment.
+func (c *PullUpdater) hasApplySummary(cmd PullCommand, res command.Result) bool {
+	return c.ApplySummaryComments != "" && cmd.CommandName() == command.Apply &&
+		res.Error == nil && res.Failure == "" && len(res.ProjectResults) > 1
+}
+
+// updatePullWithApplySummary comments the summary of the applies in res,
+// after their output if ApplySummaryComments is AppendApplySummaryComments or
+// else after the output of the failed ones.
+func (c *PullUpdater) updatePullWithApplySummary(ctx *command.Context, cmd PullCommand, res command.Result) {
+	var comments []string
+	if c.ApplySummaryComments == AppendApplySummaryComments {
+		comments = append(comments,
+			c.MarkdownRenderer.Render(res, cmd.CommandName(), cmd.SubCommandName(), ctx.Log.GetHistory(), cmd.IsVerbose(), ctx.Pull.BaseRepo.VCSHost.Type),
+			c.MarkdownRenderer.RenderApplySummary(res, "", false))
+	} else if failed := failedProjectResults(res); len(failed.ProjectResults) > 0 {
+		comments = append(comments,
+			c.MarkdownRenderer.Render(failed, cmd.CommandName(), cmd.SubCommandName(), ctx.Log.GetHistory(), cmd.IsVerbose(), ctx.Pull.BaseRepo.VCSHost.Type),
+			c.MarkdownRenderer.RenderApplySummary(res, "", false))
+	} else {
+		comments = append(comments, c.MarkdownRenderer.RenderApplySummary(res, ctx.Log.GetHistory(), cmd.IsVerbose()))
+	}
+
+	if c.HidePrevPlanComments {
+		if err := c.VCSClient.HidePrevCommandComments(ctx.Pull.BaseRepo, ctx.Pull.Num, cmd.CommandName().TitleString()); err != nil {
+			ctx.Log.Err("unable to hide old comments: %s", err)
+		}
+	}
+	for _, comment := range comments {
+		c.createComment(ctx, cmd, comment)
+	}
+}
+
+// failedProjectResults returns res with only the results of the projects
+// whose apply failed.
+func failedProjectResults(res command.Result) command.Result {
+	failed := res
+	failed.ProjectResults = nil
+	for _, result := range res.ProjectResults {
+		if !result.IsSuccessful() {
+			failed.ProjectResults = append(failed.ProjectResults, result)
+		}
+	}
+	return failed
+}
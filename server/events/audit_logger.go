@@ -0,0 +1,247 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// Outcomes of the commands and projects in audit records.
+const (
+	AuditSuccessOutcome = "success"
+	AuditFailureOutcome = "failure"
+	AuditErrorOutcome   = "error"
+)
+
+// AuditRecord is the record of a command that was run, for an audit trail of
+// who ran what, when and with which outcome.
+type AuditRecord struct {
+	Time       time.Time `json:"time"`
+	User       string    `json:"user"`
+	Command    string    `json:"command"`
+	SubCommand string    `json:"sub_command,omitempty"`
+	// Trigger is "auto" for autoplans, "comment" for commands that were
+	// commented and "api" for commands run through the API.
+	Trigger    string `json:"trigger"`
+	Repo       string `json:"repo"`
+	PullNum    int    `json:"pull_num"`
+	HeadCommit string `json:"head_commit"`
+	// Outcome is the outcome of the whole command, which is an error or
+	// failure if any of its projects errored or failed.
+	Outcome string `json:"outcome"`
+	// Error is the error or failure message of the whole command.
+	Error    string               `json:"error,omitempty"`
+	Projects []AuditProjectRecord `json:"projects,omitempty"`
+}
+
+// AuditProjectRecord is the record of a project a command was run for.
+type AuditProjectRecord struct {
+	// Command is the command run for the project, which can differ from the
+	// record's command, ex. policy checks run by a plan.
+	Command   string `json:"command"`
+	Name      string `json:"name,omitempty"`
+	Dir       string `json:"dir"`
+	Workspace string `json:"workspace"`
+	Outcome   string `json:"outcome"`
+	Error     string `json:"error,omitempty"`
+}
+
+// AuditSink keeps audit records.
+type AuditSink interface {
+	// Write keeps record.
+	Write(record AuditRecord) error
+}
+
+// FileAuditSink appends audit records to a file, one JSON object per line.
+type FileAuditSink struct {
+	Path  string
+	mutex sync.Mutex
+}
+
+// Write appends record to the file, creating it if it doesn't exist.
+func (f *FileAuditSink) Write(record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close() // nolint: errcheck
+		return err
+	}
+	return file.Close()
+}
+
+// httpAuditQueueSize is how many audit records can wait to be posted before
+// new ones are dropped.
+const httpAuditQueueSize = 1000
+
+// HTTPAuditSink posts audit records as JSON to a URL. Records are posted in
+// the background so a slow or unavailable URL doesn't hold up commands.
+type HTTPAuditSink struct {
+	URL    string
+	Client *http.Client
+	Logger logging.SimpleLogging
+	queue  chan AuditRecord
+}
+
+// NewHTTPAuditSink returns an HTTPAuditSink posting to url and starts posting
+// the records written to it.
+func NewHTTPAuditSink(url string, client *http.Client, logger logging.SimpleLogging) *HTTPAuditSink {
+	h := &HTTPAuditSink{
+		URL:    url,
+		Client: client,
+		Logger: logger,
+		queue:  make(chan AuditRecord, httpAuditQueueSize),
+	}
+	go h.run()
+	return h
+}
+
+// Write queues record to be posted. It returns an error if too many records
+// are already waiting, in which case record is dropped.
+func (h *HTTPAuditSink) Write(record AuditRecord) error {
+	select {
+	case h.queue <- record:
+		return nil
+	default:
+		return fmt.Errorf("dropping audit record since %d records are waiting to be posted to %s", httpAuditQueueSize, h.URL)
+	}
+}
+
+// run posts the queued records one at a time.
+func (h *HTTPAuditSink) run() {
+	for record := range h.queue {
+		if err := h.post(record); err != nil {
+			h.Logger.Err("unable to post audit record: %s", err)
+		}
+	}
+}
+
+// post posts record to the URL. Any response status other than 2xx is an
+// error.
+func (h *HTTPAuditSink) post(record AuditRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	resp, err := h.Client.Post(h.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("posting audit record to %s returned status %d", h.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+// AuditLogger writes an audit record of each command to its sinks once the
+// command finishes. Sinks that fail are logged and don't fail the command.
+// A nil *AuditLogger records nothing.
+type AuditLogger struct {
+	Sinks []AuditSink
+}
+
+// addAuditResult adds res to the result of ctx's command that's recorded in
+// the audit trail. Commands can add more than one result, ex. a plan and the
+// policy checks it runs, and are recorded with all of their projects.
+func addAuditResult(ctx *command.Context, res command.Result) {
+	if ctx.AuditResult == nil {
+		ctx.AuditResult = &command.Result{}
+	}
+	if ctx.AuditResult.Error == nil {
+		ctx.AuditResult.Error = res.Error
+	}
+	if ctx.AuditResult.Failure == "" {
+		ctx.AuditResult.Failure = res.Failure
+	}
+	ctx.AuditResult.ProjectResults = append(ctx.AuditResult.ProjectResults, res.ProjectResults...)
+}
+
+// RecordCommand writes the audit record of cmd with the result added to ctx.
+// It's called by the entry points of commands once they finish.
+func (a *AuditLogger) RecordCommand(ctx *command.Context, cmd PullCommand) {
+	var res command.Result
+	if ctx.AuditResult != nil {
+		res = *ctx.AuditResult
+	}
+	a.Record(ctx, cmd, res)
+}
+
+// Record writes the audit record of cmd, whose result is res, to the sinks.
+func (a *AuditLogger) Record(ctx *command.Context, cmd PullCommand, res command.Result) {
+	if a == nil {
+		return
+	}
+	record := newAuditRecord(ctx, cmd, res)
+	for _, sink := range a.Sinks {
+		if err := sink.Write(record); err != nil {
+			ctx.Log.Err("unable to write audit record: %s", err)
+		}
+	}
+}
+
+// newAuditRecord returns the audit record of cmd, whose result is res.
+func newAuditRecord(ctx *command.Context, cmd PullCommand, res command.Result) AuditRecord {
+	trigger := "comment"
+	switch ctx.Trigger {
+	case command.AutoTrigger:
+		trigger = "auto"
+	case command.APITrigger:
+		trigger = "api"
+	}
+	record := AuditRecord{
+		Time:       time.Now(),
+		User:       ctx.User.Username,
+		Command:    cmd.CommandName().String(),
+		SubCommand: cmd.SubCommandName(),
+		Trigger:    trigger,
+		Repo:       ctx.Pull.BaseRepo.FullName,
+		PullNum:    ctx.Pull.Num,
+		HeadCommit: ctx.Pull.HeadCommit,
+	}
+	record.Outcome, record.Error = auditOutcome(res.Error, res.Failure)
+	for _, p := range res.ProjectResults {
+		project := AuditProjectRecord{
+			Command:   p.Command.String(),
+			Name:      p.ProjectName,
+			Dir:       p.RepoRelDir,
+			Workspace: p.Workspace,
+		}
+		project.Outcome, project.Error = auditOutcome(p.Error, p.Failure)
+		// An error outweighs a failure in the command's outcome.
+		switch {
+		case project.Outcome == AuditErrorOutcome:
+			record.Outcome = AuditErrorOutcome
+		case project.Outcome == AuditFailureOutcome && record.Outcome == AuditSuccessOutcome:
+			record.Outcome = AuditFailureOutcome
+		}
+		record.Projects = append(record.Projects, project)
+	}
+	return record
+}
+
+// auditOutcome returns the outcome and error message of a command or project
+// that returned err and failure.
+func auditOutcome(err error, failure string) (string, string) {
+	if err != nil {
+		return AuditErrorOutcome, err.Error()
+	}
+	if failure != "" {
+		return AuditFailureOutcome, failure
+	}
+	return AuditSuccessOutcome, ""
+}
@@ -0,0 +1,204 @@
+package events_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+type fakeAuditSink struct {
+	records []events.AuditRecord
+	err     error
+}
+
+func (f *fakeAuditSink) Write(record events.AuditRecord) error {
+	f.records = append(f.records, record)
+	return f.err
+}
+
+func auditTestCtx(t *testing.T) *command.Context {
+	return &command.Context{
+		User: models.User{Username: "alice"},
+		Log:  logging.NewNoopLogger(t),
+		Pull: models.PullRequest{
+			Num:        2,
+			HeadCommit: "abc123",
+			BaseRepo:   models.Repo{FullName: "owner/repo"},
+		},
+		Trigger: command.CommentTrigger,
+	}
+}
+
+func TestAuditLogger_Record(t *testing.T) {
+	cases := []struct {
+		description string
+		res         command.Result
+		expOutcome  string
+		expError    string
+		expProjects []events.AuditProjectRecord
+	}{
+		{
+			description: "success",
+			res: command.Result{ProjectResults: []command.ProjectResult{
+				{Command: command.Apply, RepoRelDir: "app", Workspace: "default", ProjectName: "app", ApplySuccess: "applied"},
+			}},
+			expOutcome: events.AuditSuccessOutcome,
+			expProjects: []events.AuditProjectRecord{
+				{Command: "apply", Name: "app", Dir: "app", Workspace: "default", Outcome: events.AuditSuccessOutcome},
+			},
+		},
+		{
+			description: "project failure and error",
+			res: command.Result{ProjectResults: []command.ProjectResult{
+				{Command: command.Apply, RepoRelDir: "app", Workspace: "default", Failure: "Pull request must be approved"},
+				{Command: command.Apply, RepoRelDir: "network", Workspace: "staging", Error: errors.New("exit status 1")},
+			}},
+			expOutcome: events.AuditErrorOutcome,
+			expProjects: []events.AuditProjectRecord{
+				{Command: "apply", Dir: "app", Workspace: "default", Outcome: events.AuditFailureOutcome, Error: "Pull request must be approved"},
+				{Command: "apply", Dir: "network", Workspace: "staging", Outcome: events.AuditErrorOutcome, Error: "exit status 1"},
+			},
+		},
+		{
+			description: "command failure",
+			res:         command.Result{Failure: "Locks are held by another pull request"},
+			expOutcome:  events.AuditFailureOutcome,
+			expError:    "Locks are held by another pull request",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			sink := &fakeAuditSink{}
+			logger := &events.AuditLogger{Sinks: []events.AuditSink{sink}}
+			logger.Record(auditTestCtx(t), &events.CommentCommand{Name: command.Apply}, c.res)
+
+			Equals(t, 1, len(sink.records))
+			record := sink.records[0]
+			Assert(t, !record.Time.IsZero(), "exp time to be set")
+			Equals(t, "alice", record.User)
+			Equals(t, "apply", record.Command)
+			Equals(t, "comment", record.Trigger)
+			Equals(t, "owner/repo", record.Repo)
+			Equals(t, 2, record.PullNum)
+			Equals(t, "abc123", record.HeadCommit)
+			Equals(t, c.expOutcome, record.Outcome)
+			Equals(t, c.expError, record.Error)
+			Equals(t, c.expProjects, record.Projects)
+		})
+	}
+}
+
+// A failing sink doesn't stop the record from being written to the others.
+func TestAuditLogger_RecordSinkError(t *testing.T) {
+	failing := &fakeAuditSink{err: errors.New("disk full")}
+	working := &fakeAuditSink{}
+	logger := &events.AuditLogger{Sinks: []events.AuditSink{failing, working}}
+	ctx := auditTestCtx(t)
+	ctx.Trigger = command.AutoTrigger
+	logger.Record(ctx, events.AutoplanCommand{}, command.Result{})
+	Equals(t, 1, len(working.records))
+	Equals(t, "plan", working.records[0].Command)
+	Equals(t, "auto", working.records[0].Trigger)
+}
+
+// The command's record has the results added to its context.
+func TestAuditLogger_RecordCommand(t *testing.T) {
+	sink := &fakeAuditSink{}
+	logger := &events.AuditLogger{Sinks: []events.AuditSink{sink}}
+	ctx := auditTestCtx(t)
+	ctx.Trigger = command.APITrigger
+	ctx.AuditResult = &command.Result{
+		Failure: "Apply requires `atlantis confirm`",
+		ProjectResults: []command.ProjectResult{
+			{Command: command.Plan, RepoRelDir: "app", Workspace: "default", PlanSuccess: &models.PlanSuccess{}},
+		},
+	}
+	logger.RecordCommand(ctx, &events.CommentCommand{Name: command.Plan})
+
+	Equals(t, 1, len(sink.records))
+	Equals(t, "api", sink.records[0].Trigger)
+	Equals(t, events.AuditFailureOutcome, sink.records[0].Outcome)
+	Equals(t, "Apply requires `atlantis confirm`", sink.records[0].Error)
+	Equals(t, []events.AuditProjectRecord{
+		{Command: "plan", Dir: "app", Workspace: "default", Outcome: events.AuditSuccessOutcome},
+	}, sink.records[0].Projects)
+}
+
+func TestAuditLogger_Nil(t *testing.T) {
+	var logger *events.AuditLogger
+	logger.Record(auditTestCtx(t), &events.CommentCommand{Name: command.Plan}, command.Result{})
+}
+
+func TestFileAuditSink_Write(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink := &events.FileAuditSink{Path: path}
+	Ok(t, sink.Write(events.AuditRecord{User: "alice", Command: "plan", Outcome: events.AuditSuccessOutcome}))
+	Ok(t, sink.Write(events.AuditRecord{User: "bob", Command: "apply", Outcome: events.AuditErrorOutcome}))
+
+	contents, err := os.ReadFile(path)
+	Ok(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n")
+	Equals(t, 2, len(lines))
+	var record events.AuditRecord
+	Ok(t, json.Unmarshal([]byte(lines[1]), &record))
+	Equals(t, "bob", record.User)
+	Equals(t, "apply", record.Command)
+	Equals(t, events.AuditErrorOutcome, record.Outcome)
+}
+
+func TestHTTPAuditSink_Write(t *testing.T) {
+	bodies := make(chan []byte, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "application/json", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	sink := events.NewHTTPAuditSink(server.URL, server.Client(), logging.NewNoopLogger(t))
+
+	// Records are posted in the background and a failed post doesn't stop
+	// the next one.
+	Ok(t, sink.Write(events.AuditRecord{User: "alice", Command: "plan"}))
+	Ok(t, sink.Write(events.AuditRecord{User: "bob", Command: "apply"}))
+	for _, expUser := range []string{"alice", "bob"} {
+		select {
+		case body := <-bodies:
+			var record events.AuditRecord
+			Ok(t, json.Unmarshal(body, &record))
+			Equals(t, expUser, record.User)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the record of %s to be posted", expUser)
+		}
+	}
+}
+
+// Records are dropped instead of blocking commands when the URL can't keep up.
+func TestHTTPAuditSink_WriteQueueFull(t *testing.T) {
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer server.Close()
+	defer close(block)
+	sink := events.NewHTTPAuditSink(server.URL, server.Client(), logging.NewNoopLogger(t))
+
+	var err error
+	for i := 0; i < 2000 && err == nil; i++ {
+		err = sink.Write(events.AuditRecord{User: "alice", Command: "plan"})
+	}
+	ErrContains(t, "dropping audit record", err)
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+
+	runtimemodels "github.com/runatlantis/atlantis/server/core/runtime/models"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+)
+
+func NewCancelCommandRunner(
+	processTracker *runtimemodels.ProcessTracker,
+	deleteLockCommand DeleteLockCommand,
+	vcsClient vcs.Client,
+	SilenceNoProjects bool,
+) *CancelCommandRunner {
+	return &CancelCommandRunner{
+		processTracker:    processTracker,
+		deleteLockCommand: deleteLockCommand,
+		vcsClient:         vcsClient,
+		SilenceNoProjects: SilenceNoProjects,
+	}
+}
+
+// CancelCommandRunner stops running plans and applies for a pull request and
+// releases the locks they held.
+type CancelCommandRunner struct {
+	processTracker    *runtimemodels.ProcessTracker
+	deleteLockCommand DeleteLockCommand
+	vcsClient         vcs.Client
+	// SilenceNoProjects is whether Atlantis should respond to PRs if no projects
+	// are found
+	SilenceNoProjects bool
+}
+
+func (c *CancelCommandRunner) Run(
+	ctx *command.Context,
+	cmd *CommentCommand,
+) {
+	baseRepo := ctx.Pull.BaseRepo
+	pullNum := ctx.Pull.Num
+
+	canceled, err := c.processTracker.Cancel(runtimemodels.ProcessFilter{
+		RepoFullName: baseRepo.FullName,
+		PullNum:      pullNum,
+		ProjectName:  cmd.ProjectName,
+		RepoRelDir:   cmd.RepoRelDir,
+		Workspace:    cmd.Workspace,
+	})
+	if err != nil {
+		ctx.Log.Err("failed to cancel running commands: %s", err)
+		addAuditResult(ctx, command.Result{Error: err})
+	}
+
+	if len(canceled) == 0 && err == nil {
+		ctx.Log.Info("no running commands to cancel")
+		if c.SilenceNoProjects {
+			return
+		}
+		if commentErr := c.vcsClient.CreateComment(baseRepo, pullNum, "No running plan or apply found to cancel.", command.Cancel.String()); commentErr != nil {
+			ctx.Log.Err("unable to comment: %s", commentErr)
+		}
+		return
+	}
+
+	var projects []string
+	for _, p := range canceled {
+		// Cancel only returns once the processes have exited so their locks
+		// are no longer in use.
+		lockKey := models.GenerateLockKey(models.NewProject(p.RepoFullName, p.RepoRelDir), p.Workspace)
+		if _, unlockErr := c.deleteLockCommand.DeleteLock(lockKey); unlockErr != nil {
+			ctx.Log.Err("failed to release lock %q: %s", lockKey, unlockErr)
+		}
+		if p.ProjectName != "" {
+			projects = append(projects, fmt.Sprintf("* project: `%s` dir: `%s` workspace: `%s`", p.ProjectName, p.RepoRelDir, p.Workspace))
+		} else {
+			projects = append(projects, fmt.Sprintf("* dir: `%s` workspace: `%s`", p.RepoRelDir, p.Workspace))
+		}
+	}
+
+	vcsMessage := fmt.Sprintf("Canceled %d running command(s) and released their locks:\n\n%s", len(canceled), strings.Join(projects, "\n"))
+	if err != nil {
+		vcsMessage = fmt.Sprintf("%s\n\nFailed to cancel some commands: %s", vcsMessage, err)
+	}
+	if commentErr := c.vcsClient.CreateComment(baseRepo, pullNum, vcsMessage, command.Cancel.String()); commentErr != nil {
+		ctx.Log.Err("unable to comment: %s", commentErr)
+	}
+}
@@ -0,0 +1,392 @@
+package events
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+)
+
+// codeOwnersPaths are where CODEOWNERS files are looked for, relative to the
+// root of the repo, in order.
+var codeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// CodeOwnersComments is how plan comments use the owners of projects in the
+// repo's CODEOWNERS file.
+type CodeOwnersComments string
+
+const (
+	// GroupCodeOwnersComments groups the projects in the plan comment by
+	// their owners.
+	GroupCodeOwnersComments CodeOwnersComments = "group"
+	// SplitCodeOwnersComments comments the plans of each owners' projects
+	// separately.
+	SplitCodeOwnersComments CodeOwnersComments = "split"
+)
+
+// CodeOwners are the rules of a CODEOWNERS file.
+type CodeOwners struct {
+	rules []codeOwnersRule
+}
+
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// ProjectResultGroup are the results of projects with the same owners.
+type ProjectResultGroup struct {
+	// Owners are the owners of the projects. They're empty if the projects
+	// don't have owners.
+	Owners         []string
+	ProjectResults []command.ProjectResult
+}
+
+// LoadCodeOwners parses the first CODEOWNERS file found in repoDir, the root
+// of a cloned repo. It returns nil if there's none.
+func LoadCodeOwners(repoDir string) (*CodeOwners, error) {
+	for _, p := range codeOwnersPaths {
+		content, err := os.ReadFile(filepath.Join(repoDir, p)) // nolint: gosec
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", p, err)
+		}
+		return ParseCodeOwners(string(content)), nil
+	}
+	return nil, nil
+}
+
+// ParseCodeOwners parses the content of a CODEOWNERS file. Each line is a
+// gitignore style pattern followed by its owners. GitLab section headers are
+// ignored.
+func ParseCodeOwners(content string) *CodeOwners {
+	codeOwners := &CodeOwners{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		fields := strings.Fields(line)
+		rule := codeOwnersRule{pattern: codeOwnersPattern(fields[0])}
+		// A rule without owners removes the owners of earlier rules.
+		if len(fields) > 1 {
+			rule.owners = fields[1:]
+		}
+		codeOwners.rules = append(codeOwners.rules, rule)
+	}
+	return codeOwners
+}
+
+// codeOwnersPattern converts the gitignore style pattern of a CODEOWNERS rule
+// to a regexp that matches the paths it applies to, including the contents
+// of matching directories.
+func codeOwnersPattern(pattern string) *regexp.Regexp {
+	// Patterns with a slash other than a trailing one are relative to the
+	// root of the repo, others match at any level.
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.Trim(pattern, "/")
+
+	var expr strings.Builder
+	if anchored {
+		expr.WriteString("^")
+	} else {
+		expr.WriteString("^(.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		}
+	}
+	// As on GitHub, a trailing /* only matches the direct children of a
+	// directory.
+	if strings.HasSuffix(pattern, "/*") {
+		expr.WriteString("$")
+	} else {
+		expr.WriteString("(/.*)?$")
+	}
+	return regexp.MustCompile(expr.String())
+}
+
+// Owners returns the owners of path, relative to the root of the repo. The
+// last matching rule wins so it returns nothing if no rule matches or the
+// last matching rule has no owners.
+func (c *CodeOwners) Owners(repoRelPath string) []string {
+	repoRelPath = strings.TrimPrefix(path.Clean(filepath.ToSlash(repoRelPath)), "/")
+	if repoRelPath == "." {
+		repoRelPath = ""
+	}
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		if c.rules[i].pattern.MatchString(repoRelPath) {
+			return c.rules[i].owners
+		}
+	}
+	return nil
+}
+
+// Group groups results by the owners of their project's directory. Groups
+// are sorted by owners with the projects without owners last. The results in
+// each group keep their order.
+func (c *CodeOwners) Group(results []command.ProjectResult) []ProjectResultGroup {
+	var groups []ProjectResultGroup
+	groupIndexes := make(map[string]int)
+	for _, result := range results {
+		owners := c.Owners(result.RepoRelDir)
+		key := strings.Join(owners, " ")
+		i, ok := groupIndexes[key]
+		if !ok {
+			i = len(groups)
+			groupIndexes[key] = i
+			groups = append(groups, ProjectResultGroup{Owners: owners})
+		}
+		groups[i].ProjectResults = append(groups[i].ProjectResults, result)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if len(groups[i].Owners) == 0 || len(groups[j].Owners) == 0 {
+			return len(groups[j].Owners) == 0 && len(groups[i].Owners) != 0
+		}
+		return strings.Join(groups[i].Owners, " ") < strings.Join(groups[j].Owners, " ")
+	})
+	return groups
+}
+
+// CodeOwnersGrouper groups the projects in plan comments by their owners in
+// the repo's CODEOWNERS file. A nil *CodeOwnersGrouper doesn't group.
+type CodeOwnersGrouper struct {
+	WorkingDir WorkingDir
+	// Comments is how the groups are commented.
+	Comments CodeOwnersComments
+}
+
+// CodeOwners returns the CODEOWNERS of the pull request in ctx, or nil if
+// g is nil or the repo doesn't have a CODEOWNERS file.
+func (g *CodeOwnersGrouper) CodeOwners(ctx *command.Context) *CodeOwners {
+	if g == nil {
+		return nil
+	}
+	repoDir, err := g.WorkingDir.GetWorkingDir(ctx.Pull.BaseRepo, ctx.Pull, DefaultWorkspace)
+	if err != nil {
+		ctx.Log.Warn("unable to find CODEOWNERS, not grouping plans by owners: %s", err)
+		return nil
+	}
+	codeOwners, err := LoadCodeOwners(repoDir)
+	if err != nil {
+		ctx.Log.Warn("unable to load CODEOWNERS, not grouping plans by owners: %s", err)
+		return nil
+	}
+	return codeOwners
+}
+
+// codeOwnersHeading is the heading of the projects owned by owners in plan
+// comments. Owners are quoted so they aren't mentioned on every plan.
+func codeOwnersHeading(owners []string) string {
+	if len(owners) == 0 {
+		return "Projects without owners"
+	}
+	return fmt.Sprintf("Projects owned by `%s`", strings.Join(owners, "`, `"))
+}
+
+// PullApproversGetter gets the users who approved a pull request.
+type PullApproversGetter interface {
+	// GetPullApprovers returns the usernames of the users who approved pull.
+	GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error)
+}
+
+// CodeOwnersApplyFilter only applies the projects owned, in the CODEOWNERS
+// file of the base branch, by a user who approved the pull request. Like
+// GitHub's code owner reviews, each file the pull request modified in the
+// project's dir must be approved by one of its owners, so rules on files
+// apply too. If it didn't modify any, the dir's owners must approve. Owners
+// are users, or teams of the repo's organization, and projects without owners
+// aren't filtered. It's only used for GitHub pull requests.
+// A nil *CodeOwnersApplyFilter doesn't filter.
+type CodeOwnersApplyFilter struct {
+	VCSClient       vcs.Client
+	ApproversGetter PullApproversGetter
+	// PullGetter gets the pull requests of API applies, whose base branch
+	// isn't known.
+	PullGetter GithubPullGetter
+}
+
+// FilterAPI is like Filter for applies through the API. Their ref isn't the
+// base branch of the pull request, so it's looked up to read CODEOWNERS.
+// Applies that aren't for a pull request have no approvals so none of them
+// are applied.
+func (f *CodeOwnersApplyFilter) FilterAPI(ctx *command.Context, projectCmds []command.ProjectContext) ([]command.ProjectContext, []command.ProjectResult) {
+	if f == nil || ctx.Pull.BaseRepo.VCSHost.Type != models.Github || len(projectCmds) == 0 {
+		return projectCmds, nil
+	}
+	if ctx.Pull.Num == 0 {
+		return nil, codeOwnersApplyFailures(projectCmds, "Applies must be for a pull request so that the project's owners in CODEOWNERS can approve them")
+	}
+	pull, err := f.PullGetter.GetPullRequest(ctx.Pull.BaseRepo, ctx.Pull.Num)
+	if err != nil {
+		return nil, codeOwnersApplyFailures(projectCmds, fmt.Sprintf("Unable to get the pull request to check that the project's owners approved: %s", err))
+	}
+	pullCtx := *ctx
+	pullCtx.Pull.BaseBranch = pull.GetBase().GetRef()
+	return f.Filter(&pullCtx, projectCmds)
+}
+
+// Filter returns the projects of projectCmds that can be applied, and the
+// failed results of the others.
+func (f *CodeOwnersApplyFilter) Filter(ctx *command.Context, projectCmds []command.ProjectContext) ([]command.ProjectContext, []command.ProjectResult) {
+	if f == nil || ctx.Pull.BaseRepo.VCSHost.Type != models.Github || len(projectCmds) == 0 {
+		return projectCmds, nil
+	}
+	codeOwners, err := f.baseCodeOwners(ctx.Pull)
+	if err != nil {
+		return nil, codeOwnersApplyFailures(projectCmds, fmt.Sprintf("Unable to load CODEOWNERS to check that the project's owners approved: %s", err))
+	}
+	if codeOwners == nil {
+		return projectCmds, nil
+	}
+	approvers, err := f.ApproversGetter.GetPullApprovers(ctx.Pull.BaseRepo, ctx.Pull)
+	if err != nil {
+		return nil, codeOwnersApplyFailures(projectCmds, fmt.Sprintf("Unable to check that the project's owners approved: %s", err))
+	}
+	modifiedFiles, err := f.VCSClient.GetModifiedFiles(ctx.Pull.BaseRepo, ctx.Pull)
+	if err != nil {
+		return nil, codeOwnersApplyFailures(projectCmds, fmt.Sprintf("Unable to list the modified files to check that their owners approved: %s", err))
+	}
+
+	// Teams are only looked up if a project is owned by a team.
+	approverTeams := make(map[string][]string)
+	ownerApproved := func(owner string) bool {
+		owner = strings.TrimPrefix(owner, "@")
+		org, team, isTeam := strings.Cut(owner, "/")
+		for _, approver := range approvers {
+			if !isTeam {
+				if strings.EqualFold(owner, approver) {
+					return true
+				}
+				continue
+			}
+			if !strings.EqualFold(org, ctx.Pull.BaseRepo.Owner) {
+				return false
+			}
+			teams, ok := approverTeams[approver]
+			if !ok {
+				var teamsErr error
+				if teams, teamsErr = f.VCSClient.GetTeamNamesForUser(ctx.Pull.BaseRepo, models.User{Username: approver}); teamsErr != nil {
+					ctx.Log.Warn("unable to get the teams of %s: %s", approver, teamsErr)
+				}
+				approverTeams[approver] = teams
+			}
+			for _, t := range teams {
+				if strings.EqualFold(t, team) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	var allowed []command.ProjectContext
+	var failures []command.ProjectResult
+	for _, p := range projectCmds {
+		var unapproved []string
+		for _, owners := range projectOwners(codeOwners, p.RepoRelDir, modifiedFiles) {
+			approved := false
+			for _, owner := range owners {
+				if ownerApproved(owner) {
+					approved = true
+					break
+				}
+			}
+			if !approved {
+				unapproved = owners
+				break
+			}
+		}
+		if unapproved == nil {
+			allowed = append(allowed, p)
+			continue
+		}
+		ctx.Log.Info("not applying project at dir %q, workspace %q since none of its owners approved", p.RepoRelDir, p.Workspace)
+		failures = append(failures, codeOwnersApplyFailures([]command.ProjectContext{p},
+			fmt.Sprintf("Pull request must be approved by one of the project's owners in CODEOWNERS: `%s`", strings.Join(unapproved, "`, `")))...)
+	}
+	return allowed, failures
+}
+
+// projectOwners returns the owners of each of modifiedFiles in the project at
+// repoRelDir, or of repoRelDir if none of them are in it. Files and dirs
+// without owners are left out.
+func projectOwners(codeOwners *CodeOwners, repoRelDir string, modifiedFiles []string) [][]string {
+	dir := strings.TrimPrefix(path.Clean(filepath.ToSlash(repoRelDir)), "/")
+	var owners [][]string
+	inProject := false
+	for _, file := range modifiedFiles {
+		if dir != "." && !strings.HasPrefix(path.Clean(file), dir+"/") {
+			continue
+		}
+		inProject = true
+		if fileOwners := codeOwners.Owners(file); len(fileOwners) > 0 {
+			owners = append(owners, fileOwners)
+		}
+	}
+	if !inProject {
+		if dirOwners := codeOwners.Owners(repoRelDir); len(dirOwners) > 0 {
+			owners = append(owners, dirOwners)
+		}
+	}
+	return owners
+}
+
+// baseCodeOwners parses the first CODEOWNERS file found on the base branch of
+// pull, so a pull request can't change its own owners. It returns nil if
+// there's none.
+func (f *CodeOwnersApplyFilter) baseCodeOwners(pull models.PullRequest) (*CodeOwners, error) {
+	// GetFileContent reads files at the head branch.
+	base := pull
+	base.HeadBranch = pull.BaseBranch
+	for _, p := range codeOwnersPaths {
+		found, content, err := f.VCSClient.GetFileContent(base, p)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", p, err)
+		}
+		if found {
+			return ParseCodeOwners(string(content)), nil
+		}
+	}
+	return nil, nil
+}
+
+func codeOwnersApplyFailures(projectCmds []command.ProjectContext, failure string) []command.ProjectResult {
+	var results []command.ProjectResult
+	for _, p := range projectCmds {
+		results = append(results, command.ProjectResult{
+			Command:     command.Apply,
+			RepoRelDir:  p.RepoRelDir,
+			Workspace:   p.Workspace,
+			ProjectName: p.ProjectName,
+			Failure:     failure,
+		})
+	}
+	return results
+}
@@ -0,0 +1,243 @@
+package events_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v54/github"
+	. "github.com/petergtz/pegomock/v4"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/testdata"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestCodeOwners_Owners(t *testing.T) {
+	codeOwners := events.ParseCodeOwners(`# Default owners.
+* @org/platform
+
+/modules/ @org/modules # Shared modules.
+envs/*/network @org/network @alice
+docs/* @org/docs
+**/sandbox @org/sandbox
+/envs/prod/legacy
+
+[GitLab section]
+/gitlab/ @gitlab-team
+`)
+
+	cases := []struct {
+		path      string
+		expOwners []string
+	}{
+		{".", []string{"@org/platform"}},
+		{"app", []string{"@org/platform"}},
+		{"modules/vpc", []string{"@org/modules"}},
+		{"/modules", []string{"@org/modules"}},
+		{"other/modules", []string{"@org/platform"}},
+		{"envs/staging/network", []string{"@org/network", "@alice"}},
+		{"envs/staging/network/vpc", []string{"@org/network", "@alice"}},
+		{"envs/staging/app", []string{"@org/platform"}},
+		{"docs/examples", []string{"@org/docs"}},
+		{"docs/examples/basic", []string{"@org/platform"}},
+		{"sandbox", []string{"@org/sandbox"}},
+		{"envs/dev/sandbox/app", []string{"@org/sandbox"}},
+		{"envs/prod/legacy", nil},
+		{"gitlab/app", []string{"@gitlab-team"}},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			Equals(t, c.expOwners, codeOwners.Owners(c.path))
+		})
+	}
+}
+
+func TestCodeOwners_Group(t *testing.T) {
+	codeOwners := events.ParseCodeOwners(`/network/ @org/network
+/app/ @org/app
+/shared/ @org/app
+`)
+	results := []command.ProjectResult{
+		{RepoRelDir: "misc"},
+		{RepoRelDir: "network"},
+		{RepoRelDir: "shared"},
+		{RepoRelDir: "app", Workspace: "staging"},
+		{RepoRelDir: "app", Workspace: "production"},
+	}
+	Equals(t, []events.ProjectResultGroup{
+		{
+			Owners: []string{"@org/app"},
+			ProjectResults: []command.ProjectResult{
+				{RepoRelDir: "shared"},
+				{RepoRelDir: "app", Workspace: "staging"},
+				{RepoRelDir: "app", Workspace: "production"},
+			},
+		},
+		{
+			Owners:         []string{"@org/network"},
+			ProjectResults: []command.ProjectResult{{RepoRelDir: "network"}},
+		},
+		{
+			ProjectResults: []command.ProjectResult{{RepoRelDir: "misc"}},
+		},
+	}, codeOwners.Group(results))
+}
+
+func TestLoadCodeOwners(t *testing.T) {
+	repoDir := t.TempDir()
+	codeOwners, err := events.LoadCodeOwners(repoDir)
+	Ok(t, err)
+	Assert(t, codeOwners == nil, "exp no CODEOWNERS, got %v", codeOwners)
+
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "CODEOWNERS"), []byte("* @root"), 0600))
+	Ok(t, os.Mkdir(filepath.Join(repoDir, ".github"), 0700))
+	Ok(t, os.WriteFile(filepath.Join(repoDir, ".github", "CODEOWNERS"), []byte("* @github"), 0600))
+	codeOwners, err = events.LoadCodeOwners(repoDir)
+	Ok(t, err)
+	Equals(t, []string{"@github"}, codeOwners.Owners("app"))
+}
+
+func TestCodeOwnersApplyFilter_Filter(t *testing.T) {
+	projectCmds := []command.ProjectContext{
+		{RepoRelDir: "app", Workspace: "default"},
+		{RepoRelDir: "docs", Workspace: "default"},
+	}
+	newCtx := func(repo models.Repo) *command.Context {
+		return &command.Context{
+			Log:  logging.NewNoopLogger(t),
+			Pull: models.PullRequest{BaseRepo: repo, Num: 1, BaseBranch: "main"},
+		}
+	}
+
+	t.Run("nil filter", func(t *testing.T) {
+		var filter *events.CodeOwnersApplyFilter
+		allowed, failures := filter.Filter(newCtx(testdata.GithubRepo), projectCmds)
+		Equals(t, projectCmds, allowed)
+		Equals(t, 0, len(failures))
+	})
+
+	t.Run("not GitHub", func(t *testing.T) {
+		RegisterMockTestingT(t)
+		vcsClient := vcsmocks.NewMockClient()
+		filter := &events.CodeOwnersApplyFilter{VCSClient: vcsClient, ApproversGetter: fakeApproversGetter{}}
+		allowed, failures := filter.Filter(newCtx(testdata.GitlabRepo), projectCmds)
+		Equals(t, projectCmds, allowed)
+		Equals(t, 0, len(failures))
+		vcsClient.VerifyWasCalled(Never()).GetFileContent(Any[models.PullRequest](), Any[string]())
+	})
+
+	t.Run("no CODEOWNERS", func(t *testing.T) {
+		RegisterMockTestingT(t)
+		vcsClient := vcsmocks.NewMockClient()
+		When(vcsClient.GetFileContent(Any[models.PullRequest](), Any[string]())).ThenReturn(false, nil, nil)
+		filter := &events.CodeOwnersApplyFilter{VCSClient: vcsClient, ApproversGetter: fakeApproversGetter{}}
+		allowed, failures := filter.Filter(newCtx(testdata.GithubRepo), projectCmds)
+		Equals(t, projectCmds, allowed)
+		Equals(t, 0, len(failures))
+	})
+
+	t.Run("CODEOWNERS in later path", func(t *testing.T) {
+		RegisterMockTestingT(t)
+		vcsClient := vcsmocks.NewMockClient()
+		When(vcsClient.GetFileContent(Any[models.PullRequest](), Any[string]())).ThenReturn(false, nil, nil)
+		When(vcsClient.GetFileContent(Any[models.PullRequest](), Eq("docs/CODEOWNERS"))).ThenReturn(true, []byte("* @alice\ndocs/ @bob"), nil)
+		filter := &events.CodeOwnersApplyFilter{VCSClient: vcsClient, ApproversGetter: fakeApproversGetter{approvers: []string{"Alice"}}}
+		allowed, failures := filter.Filter(newCtx(testdata.GithubRepo), projectCmds)
+		Equals(t, projectCmds[:1], allowed)
+		Equals(t, []command.ProjectResult{{
+			Command:    command.Apply,
+			RepoRelDir: "docs",
+			Workspace:  "default",
+			Failure:    "Pull request must be approved by one of the project's owners in CODEOWNERS: `@bob`",
+		}}, failures)
+	})
+
+	t.Run("teams of other orgs don't match", func(t *testing.T) {
+		RegisterMockTestingT(t)
+		vcsClient := vcsmocks.NewMockClient()
+		When(vcsClient.GetFileContent(Any[models.PullRequest](), Eq(".github/CODEOWNERS"))).ThenReturn(true, []byte("* @other/app"), nil)
+		When(vcsClient.GetTeamNamesForUser(Any[models.Repo](), Any[models.User]())).ThenReturn([]string{"app"}, nil)
+		filter := &events.CodeOwnersApplyFilter{VCSClient: vcsClient, ApproversGetter: fakeApproversGetter{approvers: []string{"alice"}}}
+		allowed, failures := filter.Filter(newCtx(testdata.GithubRepo), projectCmds)
+		Equals(t, 0, len(allowed))
+		Equals(t, 2, len(failures))
+	})
+
+	t.Run("file rules", func(t *testing.T) {
+		RegisterMockTestingT(t)
+		vcsClient := vcsmocks.NewMockClient()
+		When(vcsClient.GetFileContent(Any[models.PullRequest](), Eq(".github/CODEOWNERS"))).ThenReturn(true, []byte("*.tf @alice\n/app/secrets.tf @bob\n"), nil)
+		When(vcsClient.GetModifiedFiles(Any[models.Repo](), Any[models.PullRequest]())).ThenReturn([]string{"app/main.tf", "app/secrets.tf", "docs/README.md"}, nil)
+		filter := &events.CodeOwnersApplyFilter{VCSClient: vcsClient, ApproversGetter: fakeApproversGetter{approvers: []string{"alice"}}}
+		allowed, failures := filter.Filter(newCtx(testdata.GithubRepo), projectCmds)
+		// Every modified file of the project must be approved by its owners,
+		// and files without owners aren't filtered.
+		Equals(t, projectCmds[1:], allowed)
+		Equals(t, []command.ProjectResult{{
+			Command:    command.Apply,
+			RepoRelDir: "app",
+			Workspace:  "default",
+			Failure:    "Pull request must be approved by one of the project's owners in CODEOWNERS: `@bob`",
+		}}, failures)
+	})
+
+	t.Run("modified files error", func(t *testing.T) {
+		RegisterMockTestingT(t)
+		vcsClient := vcsmocks.NewMockClient()
+		When(vcsClient.GetFileContent(Any[models.PullRequest](), Eq(".github/CODEOWNERS"))).ThenReturn(true, []byte("app/ @alice"), nil)
+		When(vcsClient.GetModifiedFiles(Any[models.Repo](), Any[models.PullRequest]())).ThenReturn(nil, errors.New("api error"))
+		filter := &events.CodeOwnersApplyFilter{VCSClient: vcsClient, ApproversGetter: fakeApproversGetter{approvers: []string{"alice"}}}
+		allowed, failures := filter.Filter(newCtx(testdata.GithubRepo), projectCmds)
+		Equals(t, 0, len(allowed))
+		Equals(t, 2, len(failures))
+		Equals(t, "Unable to list the modified files to check that their owners approved: api error", failures[0].Failure)
+	})
+
+	t.Run("approvers error", func(t *testing.T) {
+		RegisterMockTestingT(t)
+		vcsClient := vcsmocks.NewMockClient()
+		When(vcsClient.GetFileContent(Any[models.PullRequest](), Eq(".github/CODEOWNERS"))).ThenReturn(true, []byte("app/ @alice"), nil)
+		filter := &events.CodeOwnersApplyFilter{VCSClient: vcsClient, ApproversGetter: fakeApproversGetter{err: errors.New("api error")}}
+		allowed, failures := filter.Filter(newCtx(testdata.GithubRepo), projectCmds)
+		Equals(t, 0, len(allowed))
+		Equals(t, 2, len(failures))
+		Equals(t, "Unable to check that the project's owners approved: api error", failures[0].Failure)
+	})
+}
+
+func TestCodeOwnersApplyFilter_FilterAPI(t *testing.T) {
+	RegisterMockTestingT(t)
+	vcsClient := vcsmocks.NewMockClient()
+	pullGetter := mocks.NewMockGithubPullGetter()
+	When(pullGetter.GetPullRequest(testdata.GithubRepo, 1)).ThenReturn(&github.PullRequest{Base: &github.PullRequestBranch{Ref: github.String("main")}}, nil)
+	When(vcsClient.GetFileContent(Any[models.PullRequest](), Eq(".github/CODEOWNERS"))).ThenReturn(true, []byte("docs/ @bob"), nil)
+	filter := &events.CodeOwnersApplyFilter{VCSClient: vcsClient, ApproversGetter: fakeApproversGetter{approvers: []string{"alice"}}, PullGetter: pullGetter}
+	projectCmds := []command.ProjectContext{
+		{RepoRelDir: "app", Workspace: "default"},
+		{RepoRelDir: "docs", Workspace: "default"},
+	}
+	// The API's ref is the pull request's head branch.
+	ctx := &command.Context{
+		Log:  logging.NewNoopLogger(t),
+		Pull: models.PullRequest{BaseRepo: testdata.GithubRepo, Num: 1, BaseBranch: "feature", HeadBranch: "feature"},
+	}
+
+	allowed, failures := filter.FilterAPI(ctx, projectCmds)
+	Equals(t, projectCmds[:1], allowed)
+	Equals(t, 1, len(failures))
+	// CODEOWNERS is read from the pull request's base branch.
+	pull, _ := vcsClient.VerifyWasCalledOnce().GetFileContent(Any[models.PullRequest](), Eq(".github/CODEOWNERS")).GetCapturedArguments()
+	Equals(t, "main", pull.HeadBranch)
+	Equals(t, "feature", ctx.Pull.BaseBranch)
+
+	ctx.Pull.Num = 0
+	allowed, failures = filter.FilterAPI(ctx, projectCmds)
+	Equals(t, 0, len(allowed))
+	Equals(t, 2, len(failures))
+}
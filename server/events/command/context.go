@@ -15,6 +15,9 @@ const (
 
 	// Commands that are triggered by comments (ie. atlantis plan)
 	CommentTrigger
+
+	// Commands that are triggered through the API (ie. POST /api/plan)
+	APITrigger
 )
 
 // Context represents the context of a command that should be executed
@@ -43,4 +46,18 @@ type Context struct {
 	ClearPolicyApproval bool
 
 	Trigger Trigger
+
+	// CommentFailed is true if a comment with the command's results couldn't
+	// be created.
+	CommentFailed bool
+
+	// ProjectResults are the results of the projects the command ran for.
+	// They're set once the command finishes so that project-scoped post
+	// workflow hooks can run for each project.
+	ProjectResults []ProjectResult
+
+	// AuditResult is the result of the command so far, including commands
+	// that were rejected. It's recorded in the audit trail once the command
+	// finishes.
+	AuditResult *Result
 }
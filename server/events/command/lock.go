@@ -7,6 +7,9 @@ import (
 // LockMetadata contains additional data provided to the lock
 type LockMetadata struct {
 	UnixTime int64
+	// Message is why the command is locked, ex. for an incident. It's shown
+	// when the command is rejected.
+	Message string `json:",omitempty"`
 }
 
 // Lock represents a global lock for an atlantis command (plan, apply, policy_check).
@@ -30,6 +30,13 @@ const (
 	Import
 	// State is a command to run terraform state rm
 	State
+	// Cancel is a command to stop a running plan or apply.
+	Cancel
+	// Validate is a command to run terraform validate.
+	Validate
+	// Confirm is a command to confirm an apply on a repo whose applies
+	// require confirmation.
+	Confirm
 	// Adding more? Don't forget to update String() below
 )
 
@@ -47,6 +54,9 @@ var AllCommentCommands = []Name{
 	ApprovePolicies,
 	Import,
 	State,
+	Cancel,
+	Validate,
+	Confirm,
 }
 
 // TitleString returns the string representation in title form.
@@ -74,6 +84,12 @@ func (c Name) String() string {
 		return "import"
 	case State:
 		return "state"
+	case Cancel:
+		return "cancel"
+	case Validate:
+		return "validate"
+	case Confirm:
+		return "confirm"
 	}
 	return ""
 }
@@ -149,6 +165,12 @@ func ParseCommandName(name string) (Name, error) {
 		return Import, nil
 	case "state":
 		return State, nil
+	case "cancel":
+		return Cancel, nil
+	case "validate":
+		return Validate, nil
+	case "confirm":
+		return Confirm, nil
 	}
 	return -1, fmt.Errorf("unknown command name: %s", name)
 }
@@ -40,6 +40,9 @@ func TestName_String(t *testing.T) {
 		{command.Version, "version"},
 		{command.Import, "import"},
 		{command.State, "state"},
+		{command.Cancel, "cancel"},
+		{command.Validate, "validate"},
+		{command.Confirm, "confirm"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.want, func(t *testing.T) {
@@ -63,6 +66,8 @@ func TestName_DefaultUsage(t *testing.T) {
 		{command.Version, "version"},
 		{command.Import, "import ADDRESS ID"},
 		{command.State, "state [rm ADDRESS...]"},
+		{command.Validate, "validate"},
+		{command.Confirm, "confirm"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.c.String(), func(t *testing.T) {
@@ -86,6 +91,7 @@ func TestName_SubCommands(t *testing.T) {
 		{c: command.Version},
 		{c: command.Import},
 		{c: command.State, want: []string{"rm"}},
+		{c: command.Validate},
 	}
 	for _, tt := range tests {
 		t.Run(tt.c.String(), func(t *testing.T) {
@@ -112,6 +118,7 @@ func TestName_CommandArgCount(t *testing.T) {
 		{c: command.Import, want: &command.ArgCount{Min: 2, Max: 2}},
 		{c: command.State, subCommand: "rm", want: &command.ArgCount{Min: 1, Max: -1}},
 		{c: command.State, subCommand: "unknown", wantErr: true},
+		{c: command.Validate, want: &command.ArgCount{}},
 	}
 	for _, tt := range tests {
 		t.Run(fmt.Sprintf("%s %s", tt.c, tt.subCommand), func(t *testing.T) {
@@ -178,6 +185,9 @@ func TestParseCommandName(t *testing.T) {
 		{command.Version, "version"},
 		{command.Import, "import"},
 		{command.State, "state"},
+		{command.Cancel, "cancel"},
+		{command.Validate, "validate"},
+		{command.Confirm, "confirm"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -2,8 +2,10 @@ package command
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-version"
 	"github.com/runatlantis/atlantis/server/core/config/valid"
@@ -46,6 +48,9 @@ type ProjectContext struct {
 	ParallelPolicyCheckEnabled bool
 	// AutoplanEnabled is true if autoplanning is enabled for this project.
 	AutoplanEnabled bool
+	// Autoplan is true if the command is run by an autoplan rather than a
+	// comment or the API. It's only set by BuildAutoplanCommands.
+	Autoplan bool
 	// BaseRepo is the repository that the pull request will be merged into.
 	BaseRepo models.Repo
 	// EscapedCommentArgs are the extra arguments that were added to the atlantis
@@ -67,6 +72,24 @@ type ProjectContext struct {
 	ProjectPlanStatus models.ProjectPlanStatus
 	// ProjectPolicyStatus is the status of policy sets of the current project prior to this command.
 	ProjectPolicyStatus []models.PolicySetStatus
+	// ProjectPlanDestroys is the number of resources the current project's
+	// latest plan destroys.
+	ProjectPlanDestroys int
+	// ProjectPlannedAt is when the current project's latest successful plan
+	// was created. It's zero if that isn't known.
+	ProjectPlannedAt time.Time
+	// AllowDestroy is true if the user overrode the no_destroy apply
+	// requirement with --allow-destroy.
+	AllowDestroy bool
+	// SkipFmtCheck is true if the user skipped checking that the Terraform
+	// files are formatted before planning with --skip-fmt-check.
+	SkipFmtCheck bool
+	// StateLockTimeout is how long Terraform retries acquiring the state lock
+	// on apply, set with --lock-timeout. If empty Terraform's default is used.
+	StateLockTimeout string
+	// SkipStateLock is true if the user applies without locking the
+	// Terraform state with --skip-state-lock.
+	SkipStateLock bool
 	// Pull is the pull request we're responding to.
 	Pull models.PullRequest
 	// ProjectName is the name of the project set in atlantis.yaml. If there was
@@ -83,10 +106,24 @@ type ProjectContext struct {
 	// Steps are the sequence of commands we need to run for this project and this
 	// stage.
 	Steps []valid.Step
+	// OnFailureSteps are run if one of Steps fails, ex. to roll back a failed
+	// apply.
+	OnFailureSteps []valid.Step
 	// TerraformVersion is the version of terraform we should use when executing
 	// commands for this project. This can be set to nil in which case we will
 	// use the default Atlantis terraform version.
 	TerraformVersion *version.Version
+	// TerraformBinary is the path of the terraform binary to run for this
+	// project instead of the one for TerraformVersion. It's set by the repo's
+	// terraform_binary setting.
+	TerraformBinary string
+	// AllowedTerraformVersions are the constraints the project's Terraform
+	// version must meet, set by the repo's allowed_terraform_versions setting.
+	// If it's nil any version is allowed.
+	AllowedTerraformVersions version.Constraints
+	// Terragrunt is true if the project's workflow runs terragrunt, which
+	// wraps the terraform binary, instead of terraform.
+	Terragrunt bool
 	// Configuration metadata for a given project.
 	User models.User
 	// Verbose is true when the user would like verbose output.
@@ -105,6 +142,9 @@ type ProjectContext struct {
 	DeleteSourceBranchOnMerge bool
 	// RepoLocking will get a lock when plan
 	RepoLocking bool
+	// PlanOnly is true if the project's repo can only be planned. Plans don't
+	// get a lock and applies are rejected.
+	PlanOnly bool
 	// RepoConfigFile
 	RepoConfigFile string
 	// UUID for atlantis logs
@@ -115,6 +155,32 @@ type ProjectContext struct {
 	AbortOnExcecutionOrderFail bool
 	// Allows custom policy check tools outside of Conftest to run in checks
 	CustomPolicyCheck bool
+	// StatusContextSuffix is appended to the context of this project's commit
+	// statuses.
+	StatusContextSuffix string
+	// DeploymentEnvironment is the GitHub deployment environment that a
+	// deployment must be approved in before this project is applied.
+	DeploymentEnvironment string
+	// CommandTimeout is how long each process run for this command, ex.
+	// terraform plan, may run before it's interrupted. 0 means no limit.
+	CommandTimeout time.Duration
+	// TerraformParallelism is the -parallelism of terraform plan and apply
+	// for this project. 0 uses Terraform's default.
+	TerraformParallelism int
+	// PlanPresets are the project's named sets of extra plan args.
+	PlanPresets map[string][]string
+	// PlanPresetArgs are the extra plan args of the preset selected with
+	// atlantis plan --preset. They're passed to terraform plan before the
+	// comment's args.
+	PlanPresetArgs []string
+	// PRDescriptionVarArgs are the "-var" args for the Terraform variables in
+	// the front-matter of the pull request description, escaped like
+	// EscapedCommentArgs. They're only set for plans of repos with
+	// pr_description_vars enabled.
+	PRDescriptionVarArgs []string
+	// PlanWarningsAsErrors match the Terraform warnings that fail the plan of
+	// this project, from the repo's plan_warnings_as_errors setting.
+	PlanWarningsAsErrors []*regexp.Regexp
 }
 
 // SetProjectScopeTags adds ProjectContext tags to a new returned scope.
@@ -145,6 +211,16 @@ func (p ProjectContext) GetShowResultFileName() string {
 	return fmt.Sprintf("%s-%s.json", projName, p.Workspace)
 }
 
+// GetChangedResourcesShowFileName returns the filename (not the path) to store
+// the tf show result without the resources the plan doesn't change.
+func (p ProjectContext) GetChangedResourcesShowFileName() string {
+	if p.ProjectName == "" {
+		return fmt.Sprintf("%s-changed.json", p.Workspace)
+	}
+	projName := strings.Replace(p.ProjectName, "/", planfileSlashReplace, -1)
+	return fmt.Sprintf("%s-%s-changed.json", projName, p.Workspace)
+}
+
 // GetPolicyCheckResultFileName returns the filename (not the path) to store the result from conftest_client.
 func (p ProjectContext) GetPolicyCheckResultFileName() string {
 	if p.ProjectName == "" {
@@ -154,6 +230,16 @@ func (p ProjectContext) GetPolicyCheckResultFileName() string {
 	return fmt.Sprintf("%s-%s-policyout.json", projName, p.Workspace)
 }
 
+// GetCostSummaryFileName returns the filename (not the path) to store the cost
+// estimate summary from the infracost step.
+func (p ProjectContext) GetCostSummaryFileName() string {
+	if p.ProjectName == "" {
+		return fmt.Sprintf("%s-cost.md", p.Workspace)
+	}
+	projName := strings.Replace(p.ProjectName, "/", planfileSlashReplace, -1)
+	return fmt.Sprintf("%s-%s-cost.md", projName, p.Workspace)
+}
+
 // Gets a unique identifier for the current pull request as a single string
 func (p ProjectContext) PullInfo() string {
 	normalizedOwner := strings.ReplaceAll(p.BaseRepo.Owner, "/", "-")
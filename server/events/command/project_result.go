@@ -1,24 +1,47 @@
 package command
 
 import (
+	"time"
+
 	"github.com/runatlantis/atlantis/server/events/models"
 )
 
 // ProjectResult is the result of executing a plan/policy_check/apply for a specific project.
 type ProjectResult struct {
-	Command            Name
-	SubCommand         string
-	RepoRelDir         string
-	Workspace          string
-	Error              error
-	Failure            string
+	Command    Name
+	SubCommand string
+	RepoRelDir string
+	Workspace  string
+	Error      error
+	Failure    string
+	// Canceled is true if the command was stopped by `atlantis cancel`.
+	Canceled           bool
 	PlanSuccess        *models.PlanSuccess
 	PolicyCheckResults *models.PolicyCheckResults
 	ApplySuccess       string
 	VersionSuccess     string
+	ValidateSuccess    string
 	ImportSuccess      *models.ImportSuccess
 	StateRmSuccess     *models.StateRmSuccess
-	ProjectName        string
+	// OnFailureStepsFailed is true if the project's apply failed and so did
+	// the on_failure steps run after it.
+	OnFailureStepsFailed bool
+	// NoDestroy is true if the project of a plan has the no_destroy apply
+	// requirement, so the plan's destroys fail the destroy check.
+	NoDestroy bool
+	// PlanComparison is set by `atlantis plan --compare`.
+	PlanComparison *models.PlanComparison
+	// FmtCheck is the result of checking that the Terraform files are
+	// formatted before planning. It's nil if they weren't checked.
+	FmtCheck *models.FmtCheckResult
+	// MergedPolicyCheck is the result of checking the project's plan against
+	// its policies when it's commented together with the plan. It's nil if
+	// it isn't.
+	MergedPolicyCheck *ProjectResult
+	// ReplanSuccess is the plan that was applied after the project's plan
+	// turned out to be stale and it was planned again. It's nil if it wasn't.
+	ReplanSuccess *models.PlanSuccess
+	ProjectName   string
 }
 
 // CommitStatus returns the vcs commit status of this project result.
@@ -48,12 +71,30 @@ func (p ProjectResult) PolicyStatus() []models.PolicySetStatus {
 	return policyStatuses
 }
 
+// PlanDestroys returns the number of resources a successful plan destroys.
+func (p ProjectResult) PlanDestroys() int {
+	if p.PlanSuccess == nil {
+		return 0
+	}
+	return p.PlanSuccess.Stats().Destroy
+}
+
+// PlannedAt returns when a successful plan was created.
+func (p ProjectResult) PlannedAt() time.Time {
+	if p.PlanSuccess == nil {
+		return time.Time{}
+	}
+	return p.PlanSuccess.PlannedAt
+}
+
 // PlanStatus returns the plan status.
 func (p ProjectResult) PlanStatus() models.ProjectPlanStatus {
 	switch p.Command {
 
 	case Plan:
-		if p.Error != nil {
+		if p.Canceled {
+			return models.CanceledPlanStatus
+		} else if p.Error != nil {
 			return models.ErroredPlanStatus
 		} else if p.Failure != "" {
 			return models.ErroredPlanStatus
@@ -69,7 +110,9 @@ func (p ProjectResult) PlanStatus() models.ProjectPlanStatus {
 		}
 		return models.PassedPolicyCheckStatus
 	case Apply:
-		if p.Error != nil {
+		if p.Canceled {
+			return models.CanceledPlanStatus
+		} else if p.Error != nil {
 			return models.ErroredApplyStatus
 		} else if p.Failure != "" {
 			return models.ErroredApplyStatus
@@ -109,6 +109,14 @@ func TestProjectResult_PlanStatus(t *testing.T) {
 			},
 			expStatus: models.AppliedPlanStatus,
 		},
+		{
+			p: command.ProjectResult{
+				Command:  command.Apply,
+				Error:    errors.New("err"),
+				Canceled: true,
+			},
+			expStatus: models.CanceledPlanStatus,
+		},
 		{
 			p: command.ProjectResult{
 				Command:            command.PolicyCheck,
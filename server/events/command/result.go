@@ -1,5 +1,7 @@
 package command
 
+import "time"
+
 // Result is the result of running a Command.
 type Result struct {
 	Error          error
@@ -9,6 +11,18 @@ type Result struct {
 	// deleted. This happens if automerging is enabled and one project has an
 	// error since automerging requires all plans to succeed.
 	PlansDeleted bool
+	// HistoricalSHA is set if the plans were made for a previous commit of
+	// the pull request instead of its head. Those plans can't be applied.
+	HistoricalSHA string
+	// ComparePull is set if the results compare the plans of the pull
+	// request with the plans of this pull request number.
+	ComparePull int
+	// PlanOnly is true if the results are for a plan-only repo whose plans
+	// can't be applied.
+	PlanOnly bool
+	// Duration is how long it took to run the command for all projects. It's
+	// only set for plans.
+	Duration time.Duration
 }
 
 // HasErrors returns true if there were any errors during the execution,
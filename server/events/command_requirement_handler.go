@@ -1,9 +1,17 @@
 package events
 
 import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/core/config/raw"
 	"github.com/runatlantis/atlantis/server/core/config/valid"
 	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
 )
 
 //go:generate pegomock generate --package mocks -o mocks/mock_command_requirement_handler.go CommandRequirementHandler
@@ -13,8 +21,49 @@ type CommandRequirementHandler interface {
 	ValidateImportProject(repoDir string, ctx command.ProjectContext) (string, error)
 }
 
+// CommitStatusGetter gets the statuses of a pull request's head commit.
+type CommitStatusGetter interface {
+	// GetCommitStatuses returns the state of each commit status and check of
+	// pull's head commit, by context.
+	GetCommitStatuses(repo models.Repo, pull models.PullRequest) (map[string]models.CommitStatus, error)
+}
+
+// CommitSignatureGetter gets the commits of a pull request that aren't signed.
+type CommitSignatureGetter interface {
+	// GetUnsignedCommits returns the SHAs of pull's commits whose signatures
+	// aren't verified, including commits that aren't signed.
+	GetUnsignedCommits(repo models.Repo, pull models.PullRequest) ([]string, error)
+}
+
+// PlanReactionGetter gets the reactions on a pull request's plan comment.
+type PlanReactionGetter interface {
+	// GetPlanCommentReactions returns the reactions that users who can push to
+	// repo, other than Atlantis and pull's author, added to the latest plan
+	// comment on pull since plannedAt, the time the project was planned. It
+	// returns no reactions if there's no plan comment.
+	GetPlanCommentReactions(repo models.Repo, pull models.PullRequest, plannedAt time.Time) ([]string, error)
+}
+
 type DefaultCommandRequirementHandler struct {
 	WorkingDir WorkingDir
+	// CommitStatusGetters are the clients that can get commit statuses by
+	// VCS host type. Commit status requirements fail on other hosts.
+	CommitStatusGetters map[models.VCSHostType]CommitStatusGetter
+	// CommitSignatureGetters are the clients that can get unsigned commits by
+	// VCS host type. The signed commits requirement fails on other hosts.
+	CommitSignatureGetters map[models.VCSHostType]CommitSignatureGetter
+	// PlanReactionGetters are the clients that can get the reactions on plan
+	// comments by VCS host type. Plan reaction requirements fail on other
+	// hosts.
+	PlanReactionGetters map[models.VCSHostType]PlanReactionGetter
+	// GithubPlanReviews is true if plans are posted as reviews on GitHub.
+	// Reviews can't be reacted to, so plan reaction requirements fail on
+	// GitHub rather than be approved by the reactions on an older plan
+	// comment.
+	GithubPlanReviews bool
+	// MaxPlanAge is the max age of a plan that can be applied. If 0, plans
+	// can be applied no matter how old they are.
+	MaxPlanAge time.Duration
 }
 
 func (a *DefaultCommandRequirementHandler) ValidatePlanProject(repoDir string, ctx command.ProjectContext) (failure string, err error) {
@@ -39,6 +88,15 @@ func (a *DefaultCommandRequirementHandler) ValidatePlanProject(repoDir string, c
 }
 
 func (a *DefaultCommandRequirementHandler) ValidateApplyProject(repoDir string, ctx command.ProjectContext) (failure string, err error) {
+	// statuses and reactions are fetched once, the first time a commit status
+	// or plan reaction requirement is checked.
+	var statuses map[string]models.CommitStatus
+	var reactions []string
+	// Plans whose creation time isn't known, ex. because they were created
+	// before it was recorded, aren't rejected.
+	if a.MaxPlanAge > 0 && !ctx.ProjectPlannedAt.IsZero() && time.Since(ctx.ProjectPlannedAt) > a.MaxPlanAge {
+		return fmt.Sprintf("Plan is older than the max plan age of %s. To plan again, comment `%s`.", a.MaxPlanAge, ctx.RePlanCmd), nil
+	}
 	for _, req := range ctx.ApplyRequirements {
 		switch req {
 		case raw.ApprovedRequirement:
@@ -59,6 +117,70 @@ func (a *DefaultCommandRequirementHandler) ValidateApplyProject(repoDir string,
 			if a.WorkingDir.HasDiverged(repoDir) {
 				return "Default branch must be rebased onto pull request before running apply.", nil
 			}
+		case raw.NoDestroyRequirement:
+			if ctx.ProjectPlanDestroys > 0 && !ctx.AllowDestroy {
+				return fmt.Sprintf("Plan destroys %d resource(s). To apply it anyway, comment `%s --%s`.", ctx.ProjectPlanDestroys, ctx.ApplyCmd, allowDestroyFlagLong), nil
+			}
+		case raw.SignedCommitsRequirement:
+			getter, ok := a.CommitSignatureGetters[ctx.BaseRepo.VCSHost.Type]
+			if !ok {
+				return fmt.Sprintf("The signed commits requirement isn't supported on %s.", ctx.BaseRepo.VCSHost.Type.String()), nil
+			}
+			unsigned, err := getter.GetUnsignedCommits(ctx.BaseRepo, ctx.Pull)
+			if err != nil {
+				return "", errors.Wrap(err, "getting unsigned commits")
+			}
+			if len(unsigned) > 0 {
+				return fmt.Sprintf("All commits must be signed before running apply, these aren't: %s.", strings.Join(unsigned, ", ")), nil
+			}
+		case raw.CleanWorkingTreeRequirement:
+			changed, err := changedSinceSnapshot(a.WorkingDir, repoDir, ctx)
+			if os.IsNotExist(err) {
+				return fmt.Sprintf("The project's files weren't recorded when it was planned. To plan again, comment `%s`.", ctx.RePlanCmd), nil
+			}
+			if err != nil {
+				return "", errors.Wrap(err, "comparing working tree with plan")
+			}
+			if len(changed) > 0 {
+				return fmt.Sprintf("Files changed since the project was planned: %s. To plan again, comment `%s`.", strings.Join(changed, ", "), ctx.RePlanCmd), nil
+			}
+		default:
+			if rule, ok := raw.ApprovalRuleName(req); ok && !ctx.PullReqStatus.ApprovalStatus.ApprovalRules[rule] {
+				return fmt.Sprintf("Pull request must be approved according to the %q approval rule before running apply.", rule), nil
+			}
+			if context, ok := raw.CommitStatusContext(req); ok {
+				if statuses == nil {
+					getter, ok := a.CommitStatusGetters[ctx.BaseRepo.VCSHost.Type]
+					if !ok {
+						return fmt.Sprintf("Commit status requirements aren't supported on %s.", ctx.BaseRepo.VCSHost.Type.String()), nil
+					}
+					if statuses, err = getter.GetCommitStatuses(ctx.BaseRepo, ctx.Pull); err != nil {
+						return "", errors.Wrap(err, "getting commit statuses")
+					}
+				}
+				if status, ok := statuses[context]; !ok {
+					return fmt.Sprintf("Commit status %q must be successful before running apply, it's missing.", context), nil
+				} else if status != models.SuccessCommitStatus {
+					return fmt.Sprintf("Commit status %q must be successful before running apply, it's %s.", context, status.String()), nil
+				}
+			}
+			if reaction, ok := raw.PlanReaction(req); ok {
+				if a.GithubPlanReviews && ctx.BaseRepo.VCSHost.Type == models.Github {
+					return "Plan reaction requirements aren't supported when plans are posted as reviews.", nil
+				}
+				if reactions == nil {
+					getter, ok := a.PlanReactionGetters[ctx.BaseRepo.VCSHost.Type]
+					if !ok {
+						return fmt.Sprintf("Plan reaction requirements aren't supported on %s.", ctx.BaseRepo.VCSHost.Type.String()), nil
+					}
+					if reactions, err = getter.GetPlanCommentReactions(ctx.BaseRepo, ctx.Pull, ctx.ProjectPlannedAt); err != nil {
+						return "", errors.Wrap(err, "getting plan comment reactions")
+					}
+				}
+				if !slices.Contains(reactions, reaction) {
+					return fmt.Sprintf("The plan comment must have a %q reaction before running apply.", reaction), nil
+				}
+			}
 		}
 	}
 	// Passed all apply requirements configured.
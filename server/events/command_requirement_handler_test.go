@@ -3,6 +3,7 @@ package events_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	. "github.com/petergtz/pegomock/v4"
 	"github.com/runatlantis/atlantis/server/core/config/raw"
@@ -145,6 +146,38 @@ func TestAggregateApplyRequirements_ValidateApplyProject(t *testing.T) {
 			wantFailure: "Pull request must be approved according to the project's approval rules before running apply.",
 			wantErr:     assert.NoError,
 		},
+		{
+			name: "pass by approval rule approved",
+			ctx: command.ProjectContext{
+				ApplyRequirements: []string{raw.ApprovalRuleRequirementPrefix + "Security"},
+				PullReqStatus: models.PullReqStatus{
+					ApprovalStatus: models.ApprovalStatus{ApprovalRules: map[string]bool{"Security": true, "Platform": false}},
+				},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "fail by approval rule not approved",
+			ctx: command.ProjectContext{
+				ApplyRequirements: []string{raw.ApprovalRuleRequirementPrefix + "Security"},
+				PullReqStatus: models.PullReqStatus{
+					ApprovalStatus: models.ApprovalStatus{IsApproved: true, ApprovalRules: map[string]bool{"Security": false, "Platform": true}},
+				},
+			},
+			wantFailure: "Pull request must be approved according to the \"Security\" approval rule before running apply.",
+			wantErr:     assert.NoError,
+		},
+		{
+			name: "fail by approval rule missing",
+			ctx: command.ProjectContext{
+				ApplyRequirements: []string{raw.ApprovalRuleRequirementPrefix + "Security"},
+				PullReqStatus: models.PullReqStatus{
+					ApprovalStatus: models.ApprovalStatus{IsApproved: true},
+				},
+			},
+			wantFailure: "Pull request must be approved according to the \"Security\" approval rule before running apply.",
+			wantErr:     assert.NoError,
+		},
 		{
 			name: "fail by no policy passed",
 			ctx: command.ProjectContext{
@@ -178,6 +211,32 @@ func TestAggregateApplyRequirements_ValidateApplyProject(t *testing.T) {
 			wantFailure: "Pull request must be mergeable before running apply.",
 			wantErr:     assert.NoError,
 		},
+		{
+			name: "fail by plan destroying resources",
+			ctx: command.ProjectContext{
+				ApplyRequirements:   []string{raw.NoDestroyRequirement},
+				ApplyCmd:            "atlantis apply -d .",
+				ProjectPlanDestroys: 2,
+			},
+			wantFailure: "Plan destroys 2 resource(s). To apply it anyway, comment `atlantis apply -d . --allow-destroy`.",
+			wantErr:     assert.NoError,
+		},
+		{
+			name: "pass plan destroying resources with allow destroy",
+			ctx: command.ProjectContext{
+				ApplyRequirements:   []string{raw.NoDestroyRequirement},
+				ProjectPlanDestroys: 2,
+				AllowDestroy:        true,
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "pass plan without destroys",
+			ctx: command.ProjectContext{
+				ApplyRequirements: []string{raw.NoDestroyRequirement},
+			},
+			wantErr: assert.NoError,
+		},
 		{
 			name: "fail by diverged",
 			ctx: command.ProjectContext{
@@ -207,6 +266,298 @@ func TestAggregateApplyRequirements_ValidateApplyProject(t *testing.T) {
 	}
 }
 
+type fakeCommitStatusGetter struct {
+	statuses map[string]models.CommitStatus
+	err      error
+	calls    int
+}
+
+func (f *fakeCommitStatusGetter) GetCommitStatuses(_ models.Repo, _ models.PullRequest) (map[string]models.CommitStatus, error) {
+	f.calls++
+	return f.statuses, f.err
+}
+
+func TestAggregateApplyRequirements_ValidateApplyProject_CommitStatus(t *testing.T) {
+	github := models.Repo{VCSHost: models.VCSHost{Type: models.Github}}
+	statuses := map[string]models.CommitStatus{
+		"ci/tests": models.SuccessCommitStatus,
+		"ci/lint":  models.FailedCommitStatus,
+		"ci/e2e":   models.PendingCommitStatus,
+	}
+	tests := []struct {
+		name         string
+		repo         models.Repo
+		requirements []string
+		err          error
+		wantFailure  string
+		wantErr      string
+	}{
+		{
+			name:         "pass by successful status",
+			repo:         github,
+			requirements: []string{raw.CommitStatusRequirementPrefix + "ci/tests"},
+		},
+		{
+			name:         "fail by failed status",
+			repo:         github,
+			requirements: []string{raw.CommitStatusRequirementPrefix + "ci/tests", raw.CommitStatusRequirementPrefix + "ci/lint"},
+			wantFailure:  "Commit status \"ci/lint\" must be successful before running apply, it's failed.",
+		},
+		{
+			name:         "fail by pending status",
+			repo:         github,
+			requirements: []string{raw.CommitStatusRequirementPrefix + "ci/e2e"},
+			wantFailure:  "Commit status \"ci/e2e\" must be successful before running apply, it's pending.",
+		},
+		{
+			name:         "fail by missing status",
+			repo:         github,
+			requirements: []string{raw.CommitStatusRequirementPrefix + "ci/other"},
+			wantFailure:  "Commit status \"ci/other\" must be successful before running apply, it's missing.",
+		},
+		{
+			name:         "fail by unsupported host",
+			repo:         models.Repo{VCSHost: models.VCSHost{Type: models.BitbucketCloud}},
+			requirements: []string{raw.CommitStatusRequirementPrefix + "ci/tests"},
+			wantFailure:  "Commit status requirements aren't supported on BitbucketCloud.",
+		},
+		{
+			name:         "error getting statuses",
+			repo:         github,
+			requirements: []string{raw.CommitStatusRequirementPrefix + "ci/tests"},
+			err:          fmt.Errorf("403 forbidden"),
+			wantErr:      "getting commit statuses: 403 forbidden",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getter := &fakeCommitStatusGetter{statuses: statuses, err: tt.err}
+			a := &events.DefaultCommandRequirementHandler{
+				CommitStatusGetters: map[models.VCSHostType]events.CommitStatusGetter{models.Github: getter},
+			}
+			gotFailure, err := a.ValidateApplyProject("repoDir", command.ProjectContext{
+				ApplyRequirements: tt.requirements,
+				BaseRepo:          tt.repo,
+				Pull:              models.PullRequest{Num: 1, HeadCommit: "abc123"},
+			})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantFailure, gotFailure)
+			// Statuses are only fetched once for all of the requirements.
+			if tt.repo.VCSHost.Type == models.Github {
+				assert.Equal(t, 1, getter.calls)
+			}
+		})
+	}
+}
+
+type fakeCommitSignatureGetter struct {
+	unsigned []string
+	err      error
+}
+
+func (f *fakeCommitSignatureGetter) GetUnsignedCommits(_ models.Repo, _ models.PullRequest) ([]string, error) {
+	return f.unsigned, f.err
+}
+
+func TestAggregateApplyRequirements_ValidateApplyProject_SignedCommits(t *testing.T) {
+	github := models.Repo{VCSHost: models.VCSHost{Type: models.Github}}
+	tests := []struct {
+		name        string
+		repo        models.Repo
+		unsigned    []string
+		err         error
+		wantFailure string
+		wantErr     string
+	}{
+		{
+			name: "pass by signed commits",
+			repo: github,
+		},
+		{
+			name:        "fail by unsigned commits",
+			repo:        github,
+			unsigned:    []string{"abc123", "def456"},
+			wantFailure: "All commits must be signed before running apply, these aren't: abc123, def456.",
+		},
+		{
+			name:        "fail by unsupported host",
+			repo:        models.Repo{VCSHost: models.VCSHost{Type: models.BitbucketCloud}},
+			wantFailure: "The signed commits requirement isn't supported on BitbucketCloud.",
+		},
+		{
+			name:    "error getting commits",
+			repo:    github,
+			err:     fmt.Errorf("403 forbidden"),
+			wantErr: "getting unsigned commits: 403 forbidden",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &events.DefaultCommandRequirementHandler{
+				CommitSignatureGetters: map[models.VCSHostType]events.CommitSignatureGetter{
+					models.Github: &fakeCommitSignatureGetter{unsigned: tt.unsigned, err: tt.err},
+				},
+			}
+			gotFailure, err := a.ValidateApplyProject("repoDir", command.ProjectContext{
+				ApplyRequirements: []string{raw.SignedCommitsRequirement},
+				BaseRepo:          tt.repo,
+				Pull:              models.PullRequest{Num: 1},
+			})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantFailure, gotFailure)
+		})
+	}
+}
+
+type fakePlanReactionGetter struct {
+	reactions []string
+	err       error
+	plannedAt time.Time
+}
+
+func (f *fakePlanReactionGetter) GetPlanCommentReactions(_ models.Repo, _ models.PullRequest, plannedAt time.Time) ([]string, error) {
+	f.plannedAt = plannedAt
+	return f.reactions, f.err
+}
+
+func TestAggregateApplyRequirements_ValidateApplyProject_PlanReaction(t *testing.T) {
+	github := models.Repo{VCSHost: models.VCSHost{Type: models.Github}}
+	tests := []struct {
+		name        string
+		repo        models.Repo
+		reqs        []string
+		reactions   []string
+		err         error
+		planReviews bool
+		wantFailure string
+		wantErr     string
+	}{
+		{
+			name:      "pass by reaction present",
+			repo:      github,
+			reqs:      []string{"plan_reaction:+1"},
+			reactions: []string{"eyes", "+1"},
+		},
+		{
+			name:        "fail by reaction absent",
+			repo:        github,
+			reqs:        []string{"plan_reaction:+1"},
+			reactions:   []string{"eyes"},
+			wantFailure: "The plan comment must have a \"+1\" reaction before running apply.",
+		},
+		{
+			name:        "fail by no plan comment",
+			repo:        github,
+			reqs:        []string{"plan_reaction:+1"},
+			wantFailure: "The plan comment must have a \"+1\" reaction before running apply.",
+		},
+		{
+			name:        "fail by second reaction absent",
+			repo:        github,
+			reqs:        []string{"plan_reaction:+1", "plan_reaction:rocket"},
+			reactions:   []string{"+1"},
+			wantFailure: "The plan comment must have a \"rocket\" reaction before running apply.",
+		},
+		{
+			name:        "fail by unsupported host",
+			repo:        models.Repo{VCSHost: models.VCSHost{Type: models.BitbucketCloud}},
+			reqs:        []string{"plan_reaction:+1"},
+			wantFailure: "Plan reaction requirements aren't supported on BitbucketCloud.",
+		},
+		{
+			name:        "fail by plan reviews",
+			repo:        github,
+			reqs:        []string{"plan_reaction:+1"},
+			reactions:   []string{"+1"},
+			planReviews: true,
+			wantFailure: "Plan reaction requirements aren't supported when plans are posted as reviews.",
+		},
+		{
+			name:    "error getting reactions",
+			repo:    github,
+			reqs:    []string{"plan_reaction:+1"},
+			err:     fmt.Errorf("403 forbidden"),
+			wantErr: "getting plan comment reactions: 403 forbidden",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getter := &fakePlanReactionGetter{reactions: tt.reactions, err: tt.err}
+			a := &events.DefaultCommandRequirementHandler{
+				PlanReactionGetters: map[models.VCSHostType]events.PlanReactionGetter{
+					models.Github: getter,
+				},
+				GithubPlanReviews: tt.planReviews,
+			}
+			plannedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			gotFailure, err := a.ValidateApplyProject("repoDir", command.ProjectContext{
+				ApplyRequirements: tt.reqs,
+				BaseRepo:          tt.repo,
+				Pull:              models.PullRequest{Num: 1},
+				ProjectPlannedAt:  plannedAt,
+			})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantFailure, gotFailure)
+			if len(tt.reactions) > 0 && !tt.planReviews {
+				// Only reactions since the project was planned count.
+				assert.Equal(t, plannedAt, getter.plannedAt)
+			}
+		})
+	}
+}
+
+func TestAggregateApplyRequirements_ValidateApplyProject_MaxPlanAge(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxPlanAge  time.Duration
+		plannedAt   time.Time
+		wantFailure string
+	}{
+		{
+			name:      "pass by no max plan age",
+			plannedAt: time.Now().Add(-48 * time.Hour),
+		},
+		{
+			name:       "pass by recent plan",
+			maxPlanAge: 24 * time.Hour,
+			plannedAt:  time.Now().Add(-time.Hour),
+		},
+		{
+			name:       "pass by unknown plan time",
+			maxPlanAge: 24 * time.Hour,
+		},
+		{
+			name:        "fail by old plan",
+			maxPlanAge:  24 * time.Hour,
+			plannedAt:   time.Now().Add(-48 * time.Hour),
+			wantFailure: "Plan is older than the max plan age of 24h0m0s. To plan again, comment `atlantis plan -d .`.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &events.DefaultCommandRequirementHandler{MaxPlanAge: tt.maxPlanAge}
+			gotFailure, err := a.ValidateApplyProject("repoDir", command.ProjectContext{
+				ProjectPlannedAt: tt.plannedAt,
+				RePlanCmd:        "atlantis plan -d .",
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantFailure, gotFailure)
+		})
+	}
+}
+
 func TestAggregateApplyRequirements_ValidateImportProject(t *testing.T) {
 	repoDir := "repoDir"
 	fullRequirements := []string{
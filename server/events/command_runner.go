@@ -129,6 +129,14 @@ type DefaultCommandRunner struct {
 	TeamAllowlistChecker           *TeamAllowlistChecker
 	VarFileAllowlistChecker        *VarFileAllowlistChecker
 	CommitStatusUpdater            CommitStatusUpdater
+	// User config option: Fail the commit status of a command if its results couldn't be commented.
+	FailOnCommentError bool
+	// MarkdownRenderer renders the comment posted when a user isn't allowed
+	// to run a command. If it's nil a plain error is commented instead.
+	MarkdownRenderer *MarkdownRenderer
+	// AuditLogger records each command, including rejected ones, in an audit
+	// trail. It's nil if this is disabled.
+	AuditLogger *AuditLogger
 }
 
 // RunAutoplanCommand runs plan and policy_checks when a pull request is opened or updated.
@@ -162,6 +170,13 @@ func (c *DefaultCommandRunner) RunAutoplanCommand(baseRepo models.Repo, headRepo
 		PullStatus: status,
 		Trigger:    command.AutoTrigger,
 	}
+	// Autoplans are only recorded if they planned or were rejected with a
+	// comment, not every time a pull request is updated.
+	defer func() {
+		if ctx.AuditResult != nil {
+			c.AuditLogger.RecordCommand(ctx, AutoplanCommand{})
+		}
+	}()
 	if !c.validateCtxAndComment(ctx, command.Autoplan) {
 		return
 	}
@@ -187,6 +202,7 @@ func (c *DefaultCommandRunner) RunAutoplanCommand(baseRepo models.Repo, headRepo
 
 		if c.FailOnPreWorkflowHookError {
 			ctx.Log.Err("'fail-on-pre-workflow-hook-error' set, so not running %s command.", command.Plan)
+			addAuditResult(ctx, command.Result{Error: err})
 
 			// Update the plan or apply commit status to pending whilst the pre workflow hook is running so that the PR can't be merged.
 			switch cmd.Name {
@@ -209,6 +225,7 @@ func (c *DefaultCommandRunner) RunAutoplanCommand(baseRepo models.Repo, headRepo
 	autoPlanRunner := buildCommentCommandRunner(c, command.Plan)
 
 	autoPlanRunner.Run(ctx, nil)
+	c.failOnCommentError(ctx, command.Plan)
 
 	err = c.PostWorkflowHooksCommandRunner.RunPostHooks(ctx, cmd)
 
@@ -217,16 +234,44 @@ func (c *DefaultCommandRunner) RunAutoplanCommand(baseRepo models.Repo, headRepo
 	}
 }
 
+// failOnCommentError fails the commit status of cmdName if FailOnCommentError
+// is set and the command's results couldn't be commented, so the failure isn't
+// hidden behind a successful status. Commands without a commit status only
+// log it.
+func (c *DefaultCommandRunner) failOnCommentError(ctx *command.Context, cmdName command.Name) {
+	if !c.FailOnCommentError || !ctx.CommentFailed {
+		return
+	}
+	statusName := cmdName
+	if cmdName == command.ApprovePolicies {
+		statusName = command.PolicyCheck
+	}
+	switch statusName {
+	case command.Plan, command.Apply, command.PolicyCheck:
+		ctx.Log.Err("'fail-on-comment-error' set and the %s results couldn't be commented, so failing the %s commit status", cmdName.String(), statusName.String())
+		if err := c.CommitStatusUpdater.UpdateCombined(ctx.Pull.BaseRepo, ctx.Pull, models.FailedCommitStatus, statusName); err != nil {
+			ctx.Log.Warn("unable to update %s commit status: %s", statusName.String(), err)
+		}
+	default:
+		ctx.Log.Err("'fail-on-comment-error' set but the %s command has no commit status to fail", cmdName.String())
+	}
+}
+
 // commentUserDoesNotHavePermissions comments on the pull request that the user
 // is not allowed to execute the command.
 func (c *DefaultCommandRunner) commentUserDoesNotHavePermissions(baseRepo models.Repo, pullNum int, user models.User, cmd *CommentCommand) {
-	errMsg := fmt.Sprintf("```\nError: User @%s does not have permissions to execute '%s' command.\n```", user.Username, cmd.Name.String())
-	if err := c.VCSClient.CreateComment(baseRepo, pullNum, errMsg, ""); err != nil {
+	comment := fmt.Sprintf("```\nError: User @%s does not have permissions to execute '%s' command.\n```", user.Username, cmd.Name.String())
+	if c.MarkdownRenderer != nil {
+		comment = c.MarkdownRenderer.RenderUnauthorized(user.Username, cmd.Name.String(), true)
+	}
+	if err := c.VCSClient.CreateComment(baseRepo, pullNum, comment, ""); err != nil {
 		c.Logger.Err("unable to comment on pull request: %s", err)
 	}
 }
 
-// checkUserPermissions checks if the user has permissions to execute the command
+// checkUserPermissions checks if the user has permissions to execute the command.
+// Confirming runs a pending apply, possibly requested by another user, so it
+// also requires permission to apply.
 func (c *DefaultCommandRunner) checkUserPermissions(repo models.Repo, user models.User, cmd *CommentCommand) (bool, error) {
 	if c.TeamAllowlistChecker == nil || !c.TeamAllowlistChecker.HasRules() {
 		// allowlist restriction is not enabled
@@ -237,6 +282,9 @@ func (c *DefaultCommandRunner) checkUserPermissions(repo models.Repo, user model
 		return false, err
 	}
 	ok := c.TeamAllowlistChecker.IsCommandAllowedForAnyTeam(teams, cmd.Name.String())
+	if ok && cmd.Name == command.Confirm {
+		ok = c.TeamAllowlistChecker.IsCommandAllowedForAnyTeam(teams, command.Apply.String())
+	}
 	if !ok {
 		return false, nil
 	}
@@ -277,14 +325,32 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 	timer := scope.Timer(metrics.ExecutionTimeMetric).Start()
 	defer timer.Stop()
 
+	// ctx is replaced once the pull request is fetched. The command is
+	// recorded in the audit trail with the latest ctx, even if it's rejected
+	// before then.
+	ctx := &command.Context{
+		User:    user,
+		Log:     log,
+		Pull:    models.PullRequest{Num: pullNum, BaseRepo: baseRepo},
+		Scope:   scope,
+		Trigger: command.CommentTrigger,
+	}
+	defer func() {
+		if cmd != nil {
+			c.AuditLogger.RecordCommand(ctx, cmd)
+		}
+	}()
+
 	// Check if the user who commented has the permissions to execute the 'plan' or 'apply' commands
 	ok, err := c.checkUserPermissions(baseRepo, user, cmd)
 	if err != nil {
 		c.Logger.Err("Unable to check user permissions: %s", err)
+		addAuditResult(ctx, command.Result{Error: errors.Wrap(err, "checking user permissions")})
 		return
 	}
 	if !ok {
 		c.commentUserDoesNotHavePermissions(baseRepo, pullNum, user, cmd)
+		addAuditResult(ctx, command.Result{Failure: fmt.Sprintf("User @%s does not have permissions to execute '%s' command.", user.Username, cmd.Name.String())})
 		return
 	}
 
@@ -294,11 +360,13 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 		if commentErr := c.VCSClient.CreateComment(baseRepo, pullNum, errMsg, ""); commentErr != nil {
 			c.Logger.Err("unable to comment on pull request: %s", commentErr)
 		}
+		addAuditResult(ctx, command.Result{Failure: err.Error()})
 		return
 	}
 
 	headRepo, pull, err := c.ensureValidRepoMetadata(baseRepo, maybeHeadRepo, maybePull, user, pullNum, log)
 	if err != nil {
+		addAuditResult(ctx, command.Result{Error: err})
 		return
 	}
 
@@ -308,7 +376,7 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 		log.Err("Unable to fetch pull status, this is likely a bug.", err)
 	}
 
-	ctx := &command.Context{
+	ctx = &command.Context{
 		User:                user,
 		Log:                 log,
 		Pull:                pull,
@@ -324,6 +392,13 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 		return
 	}
 
+	// Cancel must run while another command still holds the working dir
+	// lock, so it skips the workflow hooks which need that lock.
+	if cmd.Name == command.Cancel {
+		buildCommentCommandRunner(c, cmd.CommandName()).Run(ctx, cmd)
+		return
+	}
+
 	err = c.PreWorkflowHooksCommandRunner.RunPreHooks(ctx, cmd)
 
 	if err != nil {
@@ -331,6 +406,7 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 
 		if c.FailOnPreWorkflowHookError {
 			ctx.Log.Err("'fail-on-pre-workflow-hook-error' set, so not running %s command.", cmd.Name.String())
+			addAuditResult(ctx, command.Result{Error: err})
 
 			// Update the plan or apply commit status to pending whilst the pre workflow hook is running so that the PR can't be merged.
 			switch cmd.Name {
@@ -353,6 +429,7 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 	cmdRunner := buildCommentCommandRunner(c, cmd.CommandName())
 
 	cmdRunner.Run(ctx, cmd)
+	c.failOnCommentError(ctx, cmd.CommandName())
 
 	err = c.PostWorkflowHooksCommandRunner.RunPostHooks(ctx, cmd)
 
@@ -459,6 +536,7 @@ func (c *DefaultCommandRunner) validateCtxAndComment(ctx *command.Context, comma
 		if err := c.VCSClient.CreateComment(ctx.Pull.BaseRepo, ctx.Pull.Num, fmt.Sprintf("Atlantis commands can't be run on fork pull requests. To enable, set --%s  or, to disable this message, set --%s", c.AllowForkPRsFlag, c.SilenceForkPRErrorsFlag), ""); err != nil {
 			ctx.Log.Err("unable to comment: %s", err)
 		}
+		addAuditResult(ctx, command.Result{Failure: "Atlantis commands can't be run on fork pull requests"})
 		return false
 	}
 
@@ -467,6 +545,7 @@ func (c *DefaultCommandRunner) validateCtxAndComment(ctx *command.Context, comma
 		if err := c.VCSClient.CreateComment(ctx.Pull.BaseRepo, ctx.Pull.Num, "Atlantis commands can't be run on closed pull requests", ""); err != nil {
 			ctx.Log.Err("unable to comment: %s", err)
 		}
+		addAuditResult(ctx, command.Result{Failure: "Atlantis commands can't be run on closed pull requests"})
 		return false
 	}
 
@@ -288,6 +288,14 @@ func (m *MockCSU) UpdateCombined(repo models.Repo, pull models.PullRequest, stat
 	return nil
 }
 
+func (m *MockCSU) UpdateDestroyCheck(repo models.Repo, pull models.PullRequest, status models.CommitStatus, numDestroying int) error {
+	return nil
+}
+
+func (m *MockCSU) UpdateFmtCheck(repo models.Repo, pull models.PullRequest, status models.CommitStatus, numUnformatted int) error {
+	return nil
+}
+
 func (m *MockCSU) UpdateProject(ctx command.ProjectContext, cmdName command.Name, status models.CommitStatus, url string, result *command.ProjectResult) error {
 	return nil
 }
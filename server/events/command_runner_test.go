@@ -16,10 +16,12 @@ package events_test
 import (
 	"errors"
 	"fmt"
+	"os/exec"
 	"regexp"
 	"strings"
 	"testing"
 
+	"github.com/runatlantis/atlantis/server/core/config/raw"
 	"github.com/runatlantis/atlantis/server/core/config/valid"
 	"github.com/runatlantis/atlantis/server/core/db"
 	"github.com/runatlantis/atlantis/server/core/locking"
@@ -30,6 +32,7 @@ import (
 	"github.com/google/go-github/v54/github"
 	. "github.com/petergtz/pegomock/v4"
 	lockingmocks "github.com/runatlantis/atlantis/server/core/locking/mocks"
+	runtimemodels "github.com/runatlantis/atlantis/server/core/runtime/models"
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/mocks"
 	"github.com/runatlantis/atlantis/server/events/models"
@@ -65,6 +68,8 @@ var applyLockChecker *lockingmocks.MockApplyLockChecker
 var lockingLocker *lockingmocks.MockLocker
 var applyCommandRunner *events.ApplyCommandRunner
 var unlockCommandRunner *events.UnlockCommandRunner
+var cancelCommandRunner *events.CancelCommandRunner
+var processTracker *runtimemodels.ProcessTracker
 var importCommandRunner *events.ImportCommandRunner
 var preWorkflowHooksCommandRunner events.PreWorkflowHooksCommandRunner
 var postWorkflowHooksCommandRunner events.PostWorkflowHooksCommandRunner
@@ -78,6 +83,8 @@ type TestConfig struct {
 	discardApprovalOnPlan      bool
 	backend                    locking.Backend
 	DisableUnlockLabel         string
+	autoplanCommentNoProjects  bool
+	applyAllowlist             string
 }
 
 func setup(t *testing.T, options ...func(testConfig *TestConfig)) *vcsmocks.MockClient {
@@ -126,7 +133,7 @@ func setup(t *testing.T, options ...func(testConfig *TestConfig)) *vcsmocks.Mock
 	pullUpdater = &events.PullUpdater{
 		HidePrevPlanComments: false,
 		VCSClient:            vcsClient,
-		MarkdownRenderer:     events.NewMarkdownRenderer(false, false, false, false, false, false, "", "atlantis", false),
+		MarkdownRenderer:     events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"}),
 	}
 
 	autoMerger = &events.AutoMerger{
@@ -163,6 +170,7 @@ func setup(t *testing.T, options ...func(testConfig *TestConfig)) *vcsmocks.Mock
 		lockingLocker,
 		testConfig.discardApprovalOnPlan,
 		pullReqStatusFetcher,
+		testConfig.autoplanCommentNoProjects,
 	)
 
 	applyCommandRunner = events.NewApplyCommandRunner(
@@ -180,6 +188,7 @@ func setup(t *testing.T, options ...func(testConfig *TestConfig)) *vcsmocks.Mock
 		testConfig.SilenceNoProjects,
 		testConfig.silenceVCSStatusNoProjects,
 		pullReqStatusFetcher,
+		events.NewUserAllowlistChecker(testConfig.applyAllowlist),
 	)
 
 	approvePoliciesCommandRunner = events.NewApprovePoliciesCommandRunner(
@@ -200,6 +209,14 @@ func setup(t *testing.T, options ...func(testConfig *TestConfig)) *vcsmocks.Mock
 		testConfig.DisableUnlockLabel,
 	)
 
+	processTracker = runtimemodels.NewProcessTracker()
+	cancelCommandRunner = events.NewCancelCommandRunner(
+		processTracker,
+		deleteLockCommand,
+		vcsClient,
+		testConfig.SilenceNoProjects,
+	)
+
 	versionCommandRunner := events.NewVersionCommandRunner(
 		pullUpdater,
 		projectCommandBuilder,
@@ -208,6 +225,13 @@ func setup(t *testing.T, options ...func(testConfig *TestConfig)) *vcsmocks.Mock
 		testConfig.SilenceNoProjects,
 	)
 
+	validateCommandRunner := events.NewValidateCommandRunner(
+		pullUpdater,
+		projectCommandBuilder,
+		projectCommandRunner,
+		testConfig.parallelPoolSize,
+	)
+
 	importCommandRunner = events.NewImportCommandRunner(
 		pullUpdater,
 		pullReqStatusFetcher,
@@ -223,6 +247,8 @@ func setup(t *testing.T, options ...func(testConfig *TestConfig)) *vcsmocks.Mock
 		command.Unlock:          unlockCommandRunner,
 		command.Version:         versionCommandRunner,
 		command.Import:          importCommandRunner,
+		command.Cancel:          cancelCommandRunner,
+		command.Validate:        validateCommandRunner,
 	}
 
 	preWorkflowHooksCommandRunner = mocks.NewMockPreWorkflowHooksCommandRunner()
@@ -253,6 +279,7 @@ func setup(t *testing.T, options ...func(testConfig *TestConfig)) *vcsmocks.Mock
 		PreWorkflowHooksCommandRunner:  preWorkflowHooksCommandRunner,
 		PostWorkflowHooksCommandRunner: postWorkflowHooksCommandRunner,
 		PullStatusFetcher:              testConfig.backend,
+		MarkdownRenderer:               pullUpdater.MarkdownRenderer,
 	}
 
 	return vcsClient
@@ -328,6 +355,101 @@ func TestRunCommentCommand_TeamAllowListChecker(t *testing.T) {
 		vcsClient.VerifyWasCalled(Never()).GetTeamNamesForUser(testdata.GithubRepo, testdata.User)
 		vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, modelPull.Num, "Ran Plan for 0 projects:", "plan")
 	})
+
+	t.Run("not allowed", func(t *testing.T) {
+		vcsClient := setup(t)
+		checker, err := events.NewTeamAllowlistChecker("platform:plan")
+		Ok(t, err)
+		ch.TeamAllowlistChecker = checker
+		ch.MarkdownRenderer = events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis", UnauthorizedContact: "#infra-help"})
+		When(vcsClient.GetTeamNamesForUser(testdata.GithubRepo, testdata.User)).ThenReturn([]string{"dev"}, nil)
+
+		ch.RunCommentCommand(testdata.GithubRepo, nil, nil, testdata.User, testdata.Pull.Num, &events.CommentCommand{Name: command.Plan})
+		vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, testdata.Pull.Num,
+			"```\nError: User @lkysow does not have permissions to execute 'plan' command.\n```\n\nTo get access, contact #infra-help.", "")
+		githubGetter.VerifyWasCalled(Never()).GetPullRequest(Any[models.Repo](), Any[int]())
+	})
+
+	t.Run("confirm not allowed without apply", func(t *testing.T) {
+		vcsClient := setup(t)
+		checker, err := events.NewTeamAllowlistChecker("dev:plan, dev:confirm, platform:apply")
+		Ok(t, err)
+		ch.TeamAllowlistChecker = checker
+		ch.MarkdownRenderer = events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
+		When(vcsClient.GetTeamNamesForUser(testdata.GithubRepo, testdata.User)).ThenReturn([]string{"dev"}, nil)
+
+		ch.RunCommentCommand(testdata.GithubRepo, nil, nil, testdata.User, testdata.Pull.Num, &events.CommentCommand{Name: command.Confirm})
+		vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, testdata.Pull.Num,
+			"```\nError: User @lkysow does not have permissions to execute 'confirm' command.\n```", "")
+		githubGetter.VerifyWasCalled(Never()).GetPullRequest(Any[models.Repo](), Any[int]())
+	})
+
+	t.Run("not allowed without a renderer", func(t *testing.T) {
+		vcsClient := setup(t)
+		checker, err := events.NewTeamAllowlistChecker("platform:plan")
+		Ok(t, err)
+		ch.TeamAllowlistChecker = checker
+		ch.MarkdownRenderer = nil
+		When(vcsClient.GetTeamNamesForUser(testdata.GithubRepo, testdata.User)).ThenReturn([]string{"dev"}, nil)
+
+		ch.RunCommentCommand(testdata.GithubRepo, nil, nil, testdata.User, testdata.Pull.Num, &events.CommentCommand{Name: command.Plan})
+		vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, testdata.Pull.Num,
+			"```\nError: User @lkysow does not have permissions to execute 'plan' command.\n```", "")
+	})
+}
+
+// Commands are recorded in the audit trail even if they're rejected before
+// they run.
+func TestRunCommentCommand_Audit(t *testing.T) {
+	t.Run("not allowed", func(t *testing.T) {
+		vcsClient := setup(t)
+		sink := &fakeAuditSink{}
+		ch.AuditLogger = &events.AuditLogger{Sinks: []events.AuditSink{sink}}
+		checker, err := events.NewTeamAllowlistChecker("platform:plan")
+		Ok(t, err)
+		ch.TeamAllowlistChecker = checker
+		When(vcsClient.GetTeamNamesForUser(testdata.GithubRepo, testdata.User)).ThenReturn([]string{"dev"}, nil)
+
+		ch.RunCommentCommand(testdata.GithubRepo, nil, nil, testdata.User, testdata.Pull.Num, &events.CommentCommand{Name: command.Plan})
+		Equals(t, 1, len(sink.records))
+		Equals(t, "plan", sink.records[0].Command)
+		Equals(t, "comment", sink.records[0].Trigger)
+		Equals(t, testdata.User.Username, sink.records[0].User)
+		Equals(t, testdata.Pull.Num, sink.records[0].PullNum)
+		Equals(t, events.AuditFailureOutcome, sink.records[0].Outcome)
+		Equals(t, "User @lkysow does not have permissions to execute 'plan' command.", sink.records[0].Error)
+	})
+
+	t.Run("closed pull request", func(t *testing.T) {
+		setup(t)
+		sink := &fakeAuditSink{}
+		ch.AuditLogger = &events.AuditLogger{Sinks: []events.AuditSink{sink}}
+		var pull github.PullRequest
+		modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.ClosedPullState, Num: testdata.Pull.Num}
+		When(githubGetter.GetPullRequest(testdata.GithubRepo, testdata.Pull.Num)).ThenReturn(&pull, nil)
+		When(eventParsing.ParseGithubPull(&pull)).ThenReturn(modelPull, modelPull.BaseRepo, testdata.GithubRepo, nil)
+
+		ch.RunCommentCommand(testdata.GithubRepo, nil, nil, testdata.User, testdata.Pull.Num, &events.CommentCommand{Name: command.Apply})
+		Equals(t, 1, len(sink.records))
+		Equals(t, "apply", sink.records[0].Command)
+		Equals(t, events.AuditFailureOutcome, sink.records[0].Outcome)
+		Equals(t, "Atlantis commands can't be run on closed pull requests", sink.records[0].Error)
+	})
+
+	t.Run("plan", func(t *testing.T) {
+		setup(t)
+		sink := &fakeAuditSink{}
+		ch.AuditLogger = &events.AuditLogger{Sinks: []events.AuditSink{sink}}
+		var pull github.PullRequest
+		modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+		When(githubGetter.GetPullRequest(testdata.GithubRepo, testdata.Pull.Num)).ThenReturn(&pull, nil)
+		When(eventParsing.ParseGithubPull(&pull)).ThenReturn(modelPull, modelPull.BaseRepo, testdata.GithubRepo, nil)
+
+		ch.RunCommentCommand(testdata.GithubRepo, nil, nil, testdata.User, testdata.Pull.Num, &events.CommentCommand{Name: command.Plan})
+		Equals(t, 1, len(sink.records))
+		Equals(t, "plan", sink.records[0].Command)
+		Equals(t, events.AuditSuccessOutcome, sink.records[0].Outcome)
+	})
 }
 
 func TestRunCommentCommand_ForkPRDisabled(t *testing.T) {
@@ -499,6 +621,42 @@ func TestRunCommentCommand_DisableApplyAllDisabled(t *testing.T) {
 	vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, modelPull.Num, "**Error:** Running `atlantis apply` without flags is disabled. You must specify which project to apply via the `-d <dir>`, `-w <workspace>` or `-p <project name>` flags.", "apply")
 }
 
+func TestRunCommentCommand_ApplyAllowlistDenied(t *testing.T) {
+	t.Log("if \"atlantis apply\" is run by a user who isn't in the apply allowlist atlantis should" +
+		" comment saying that this is not allowed")
+	vcsClient := setup(t, func(tc *TestConfig) {
+		tc.applyAllowlist = "alice,team:platform"
+	})
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+	When(githubGetter.GetPullRequest(testdata.GithubRepo, testdata.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, testdata.GithubRepo, nil)
+	When(vcsClient.GetTeamNamesForUser(testdata.GithubRepo, testdata.User)).ThenReturn([]string{"developers"}, nil)
+
+	ch.RunCommentCommand(testdata.GithubRepo, nil, nil, testdata.User, modelPull.Num, &events.CommentCommand{Name: command.Apply})
+	vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, modelPull.Num, "**Error:** User @lkysow is not allowed to run `atlantis apply`.", "apply")
+	projectCommandBuilder.VerifyWasCalled(Never()).BuildApplyCommands(Any[*command.Context](), Any[*events.CommentCommand]())
+}
+
+func TestRunCommentCommand_ApplyAllowlistTeam(t *testing.T) {
+	t.Log("if \"atlantis apply\" is run by a member of a team in the apply allowlist atlantis should apply")
+	vcsClient := setup(t, func(tc *TestConfig) {
+		tc.applyAllowlist = "alice,team:platform"
+	})
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+	When(githubGetter.GetPullRequest(testdata.GithubRepo, testdata.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, testdata.GithubRepo, nil)
+	When(vcsClient.GetTeamNamesForUser(testdata.GithubRepo, testdata.User)).ThenReturn([]string{"Platform"}, nil)
+
+	ch.RunCommentCommand(testdata.GithubRepo, nil, nil, testdata.User, modelPull.Num, &events.CommentCommand{Name: command.Apply})
+	projectCommandBuilder.VerifyWasCalledOnce().BuildApplyCommands(Any[*command.Context](), Any[*events.CommentCommand]())
+}
+
 func TestRunCommentCommand_DisableAutoplan(t *testing.T) {
 	t.Log("if \"DisableAutoplan\" is true, auto plans are disabled and we are silencing return and do not comment with error")
 	setup(t)
@@ -655,6 +813,301 @@ func TestRunUnlockCommand_VCSComment(t *testing.T) {
 	}
 }
 
+func TestRunCancelCommand_NothingRunning(t *testing.T) {
+	t.Log("if cancel is run when nothing is running, atlantis should say so" +
+		" and not touch any locks")
+	vcsClient := setup(t)
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+	When(githubGetter.GetPullRequest(testdata.GithubRepo, testdata.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, testdata.GithubRepo, nil)
+
+	ch.RunCommentCommand(testdata.GithubRepo, &testdata.GithubRepo, nil, testdata.User, testdata.Pull.Num, &events.CommentCommand{Name: command.Cancel})
+
+	deleteLockCommand.VerifyWasCalled(Never()).DeleteLock(Any[string]())
+	vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, testdata.Pull.Num, "No running plan or apply found to cancel.", "cancel")
+}
+
+func TestRunCancelCommand_SignalsProcess(t *testing.T) {
+	t.Log("if cancel is run for a project that's running, atlantis should" +
+		" interrupt its process, release its lock and skip workflow hooks")
+	vcsClient := setup(t)
+	pull := &github.PullRequest{
+		State: github.String("open"),
+	}
+	modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+	When(githubGetter.GetPullRequest(testdata.GithubRepo, testdata.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, testdata.GithubRepo, nil)
+
+	projCtx := command.ProjectContext{
+		BaseRepo:    testdata.GithubRepo,
+		Pull:        modelPull,
+		ProjectName: "foo",
+		RepoRelDir:  "dir",
+		Workspace:   "default",
+	}
+	otherCmd := exec.Command("sleep", "30")
+	Ok(t, otherCmd.Start())
+	defer otherCmd.Process.Kill() // nolint: errcheck
+	processTracker.Track(command.ProjectContext{BaseRepo: testdata.GithubRepo, Pull: modelPull, ProjectName: "bar", RepoRelDir: "other", Workspace: "default"}, otherCmd)
+	cmd := exec.Command("sleep", "30")
+	Ok(t, cmd.Start())
+	processTracker.Track(projCtx, cmd)
+	// Cancel waits for the process to exit and be untracked, like the
+	// ShellCommandRunner does, before the lock is released.
+	waitErr := make(chan error, 1)
+	untracked := make(chan bool, 1)
+	go func() {
+		waitErr <- cmd.Wait()
+		untracked <- processTracker.Untrack(cmd)
+	}()
+
+	ch.RunCommentCommand(testdata.GithubRepo, &testdata.GithubRepo, nil, testdata.User, testdata.Pull.Num, &events.CommentCommand{Name: command.Cancel, ProjectName: "foo"})
+
+	Assert(t, <-waitErr != nil, "expected process to be interrupted")
+	Equals(t, true, <-untracked)
+	Equals(t, false, processTracker.Untrack(otherCmd))
+	deleteLockCommand.VerifyWasCalledOnce().DeleteLock(fmt.Sprintf("%s/dir/default", testdata.GithubRepo.FullName))
+	vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, testdata.Pull.Num, "Canceled 1 running command(s) and released their locks:\n\n* project: `foo` dir: `dir` workspace: `default`", "cancel")
+	preWorkflowHooksCommandRunner.(*mocks.MockPreWorkflowHooksCommandRunner).VerifyWasCalled(Never()).RunPreHooks(Any[*command.Context](), Any[*events.CommentCommand]())
+}
+
+func TestRunValidateCommand(t *testing.T) {
+	vcsClient := setup(t)
+	pull := &github.PullRequest{State: github.String("open")}
+	modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+	When(githubGetter.GetPullRequest(testdata.GithubRepo, testdata.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, testdata.GithubRepo, nil)
+	When(projectCommandBuilder.BuildValidateCommands(Any[*command.Context](), Any[*events.CommentCommand]())).
+		ThenReturn([]command.ProjectContext{{CommandName: command.Validate, RepoRelDir: ".", Workspace: "default"}}, nil)
+	When(projectCommandRunner.Validate(Any[command.ProjectContext]())).
+		ThenReturn(command.ProjectResult{Command: command.Validate, RepoRelDir: ".", Workspace: "default", ValidateSuccess: "Success! The configuration is valid."})
+
+	ch.RunCommentCommand(testdata.GithubRepo, &testdata.GithubRepo, nil, testdata.User, testdata.Pull.Num, &events.CommentCommand{Name: command.Validate})
+
+	projectCommandRunner.VerifyWasCalledOnce().Validate(Any[command.ProjectContext]())
+	projectCommandRunner.VerifyWasCalled(Never()).Plan(Any[command.ProjectContext]())
+	_, _, comment, cmdName := vcsClient.VerifyWasCalledOnce().CreateComment(
+		Any[models.Repo](), Any[int](), Any[string](), Any[string]()).GetCapturedArguments()
+	Equals(t, "validate", cmdName)
+	Assert(t, strings.Contains(comment, "Success! The configuration is valid."), "unexpected comment: %s", comment)
+}
+
+func TestRunPlanCommand_DestroyCheck(t *testing.T) {
+	cases := []struct {
+		description   string
+		applyReqs     []string
+		tfOutput      string
+		expStatus     models.CommitStatus
+		expDestroying int
+		expUpdate     bool
+	}{
+		{
+			description:   "destroys with no_destroy",
+			applyReqs:     []string{raw.NoDestroyRequirement},
+			tfOutput:      "Plan: 1 to add, 0 to change, 2 to destroy.",
+			expStatus:     models.FailedCommitStatus,
+			expDestroying: 1,
+			expUpdate:     true,
+		},
+		{
+			description: "no destroys with no_destroy",
+			applyReqs:   []string{raw.NoDestroyRequirement},
+			tfOutput:    "Plan: 1 to add, 0 to change, 0 to destroy.",
+			expStatus:   models.SuccessCommitStatus,
+			expUpdate:   true,
+		},
+		{
+			description: "destroys without no_destroy",
+			tfOutput:    "Plan: 1 to add, 0 to change, 2 to destroy.",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			setup(t)
+			tmp := t.TempDir()
+			boltDB, err := db.New(tmp)
+			Ok(t, err)
+			dbUpdater.Backend = boltDB
+			pull := &github.PullRequest{State: github.String("open")}
+			modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+			When(githubGetter.GetPullRequest(testdata.GithubRepo, testdata.Pull.Num)).ThenReturn(pull, nil)
+			When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, testdata.GithubRepo, nil)
+			When(projectCommandBuilder.BuildPlanCommands(Any[*command.Context](), Any[*events.CommentCommand]())).
+				ThenReturn([]command.ProjectContext{{CommandName: command.Plan, RepoRelDir: ".", Workspace: "default", ApplyRequirements: c.applyReqs}}, nil)
+			When(projectCommandRunner.Plan(Any[command.ProjectContext]())).ThenReturn(command.ProjectResult{
+				Command:     command.Plan,
+				RepoRelDir:  ".",
+				Workspace:   "default",
+				PlanSuccess: &models.PlanSuccess{TerraformOutput: c.tfOutput},
+			})
+
+			ch.RunCommentCommand(testdata.GithubRepo, &testdata.GithubRepo, nil, testdata.User, testdata.Pull.Num, &events.CommentCommand{Name: command.Plan, RepoRelDir: "."})
+
+			if c.expUpdate {
+				commitUpdater.VerifyWasCalledOnce().UpdateDestroyCheck(modelPull.BaseRepo, modelPull, c.expStatus, c.expDestroying)
+			} else {
+				commitUpdater.VerifyWasCalled(Never()).UpdateDestroyCheck(Any[models.Repo](), Any[models.PullRequest](), Any[models.CommitStatus](), Any[int]())
+			}
+		})
+	}
+}
+
+// Test that planning one project again keeps the destroy check failed if the
+// latest plan of another project destroys resources.
+func TestRunPlanCommand_DestroyCheckOtherProjects(t *testing.T) {
+	setup(t)
+	boltDB, err := db.New(t.TempDir())
+	Ok(t, err)
+	dbUpdater.Backend = boltDB
+	pull := &github.PullRequest{State: github.String("open")}
+	modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+	When(githubGetter.GetPullRequest(testdata.GithubRepo, testdata.Pull.Num)).ThenReturn(pull, nil)
+	When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, testdata.GithubRepo, nil)
+	destroying := command.ProjectContext{CommandName: command.Plan, RepoRelDir: "destroying", Workspace: "default", ApplyRequirements: []string{raw.NoDestroyRequirement}}
+	safe := command.ProjectContext{CommandName: command.Plan, RepoRelDir: "safe", Workspace: "default", ApplyRequirements: []string{raw.NoDestroyRequirement}}
+	for _, projCtx := range []command.ProjectContext{destroying, safe} {
+		tfOutput := "Plan: 1 to add, 0 to change, 0 to destroy."
+		if projCtx.RepoRelDir == "destroying" {
+			tfOutput = "Plan: 1 to add, 0 to change, 2 to destroy."
+		}
+		When(projectCommandRunner.Plan(projCtx)).ThenReturn(command.ProjectResult{
+			Command:     command.Plan,
+			RepoRelDir:  projCtx.RepoRelDir,
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{TerraformOutput: tfOutput},
+		})
+	}
+	allCmd := &events.CommentCommand{Name: command.Plan}
+	safeCmd := &events.CommentCommand{Name: command.Plan, RepoRelDir: "safe"}
+	When(projectCommandBuilder.BuildPlanCommands(Any[*command.Context](), Eq(allCmd))).ThenReturn([]command.ProjectContext{destroying, safe}, nil)
+	When(projectCommandBuilder.BuildPlanCommands(Any[*command.Context](), Eq(safeCmd))).ThenReturn([]command.ProjectContext{safe}, nil)
+
+	ch.RunCommentCommand(testdata.GithubRepo, &testdata.GithubRepo, nil, testdata.User, testdata.Pull.Num, allCmd)
+	ch.RunCommentCommand(testdata.GithubRepo, &testdata.GithubRepo, nil, testdata.User, testdata.Pull.Num, safeCmd)
+	commitUpdater.VerifyWasCalled(Times(2)).UpdateDestroyCheck(modelPull.BaseRepo, modelPull, models.FailedCommitStatus, 1)
+}
+
+func TestRunPlanCommand_FmtCheck(t *testing.T) {
+	cases := []struct {
+		description       string
+		fmtCheck          *models.FmtCheckResult
+		expStatus         models.CommitStatus
+		expNumUnformatted int
+		expUpdate         bool
+	}{
+		{
+			description:       "unformatted",
+			fmtCheck:          &models.FmtCheckResult{UnformattedFiles: []string{"main.tf"}},
+			expStatus:         models.FailedCommitStatus,
+			expNumUnformatted: 1,
+			expUpdate:         true,
+		},
+		{
+			description: "formatted",
+			fmtCheck:    &models.FmtCheckResult{},
+			expStatus:   models.SuccessCommitStatus,
+			expUpdate:   true,
+		},
+		{
+			description: "not checked",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			setup(t)
+			tmp := t.TempDir()
+			boltDB, err := db.New(tmp)
+			Ok(t, err)
+			dbUpdater.Backend = boltDB
+			pull := &github.PullRequest{State: github.String("open")}
+			modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+			When(githubGetter.GetPullRequest(testdata.GithubRepo, testdata.Pull.Num)).ThenReturn(pull, nil)
+			When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, testdata.GithubRepo, nil)
+			When(projectCommandBuilder.BuildPlanCommands(Any[*command.Context](), Any[*events.CommentCommand]())).
+				ThenReturn([]command.ProjectContext{{CommandName: command.Plan, RepoRelDir: ".", Workspace: "default"}}, nil)
+			result := command.ProjectResult{
+				Command:     command.Plan,
+				RepoRelDir:  ".",
+				Workspace:   "default",
+				FmtCheck:    c.fmtCheck,
+				PlanSuccess: &models.PlanSuccess{TerraformOutput: "Plan: 1 to add, 0 to change, 0 to destroy."},
+			}
+			if c.fmtCheck != nil && len(c.fmtCheck.UnformattedFiles) > 0 {
+				result.PlanSuccess = nil
+				result.Failure = c.fmtCheck.Failure()
+			}
+			When(projectCommandRunner.Plan(Any[command.ProjectContext]())).ThenReturn(result)
+
+			ch.RunCommentCommand(testdata.GithubRepo, &testdata.GithubRepo, nil, testdata.User, testdata.Pull.Num, &events.CommentCommand{Name: command.Plan, RepoRelDir: "."})
+
+			if c.expUpdate {
+				commitUpdater.VerifyWasCalledOnce().UpdateFmtCheck(modelPull.BaseRepo, modelPull, c.expStatus, c.expNumUnformatted)
+			} else {
+				commitUpdater.VerifyWasCalled(Never()).UpdateFmtCheck(Any[models.Repo](), Any[models.PullRequest](), Any[models.CommitStatus](), Any[int]())
+			}
+		})
+	}
+}
+
+func TestRunPlanCommand_FailOnCommentError(t *testing.T) {
+	cases := []struct {
+		description        string
+		failOnCommentError bool
+		commentErr         error
+		expFailedStatus    bool
+	}{
+		{
+			description:        "comment fails and fail-on-comment-error set",
+			failOnCommentError: true,
+			commentErr:         errors.New("comment err"),
+			expFailedStatus:    true,
+		},
+		{
+			description: "comment fails and best-effort",
+			commentErr:  errors.New("comment err"),
+		},
+		{
+			description:        "comment succeeds and fail-on-comment-error set",
+			failOnCommentError: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			vcsClient := setup(t)
+			tmp := t.TempDir()
+			boltDB, err := db.New(tmp)
+			Ok(t, err)
+			dbUpdater.Backend = boltDB
+			ch.CommitStatusUpdater = commitUpdater
+			ch.FailOnCommentError = c.failOnCommentError
+			pull := &github.PullRequest{State: github.String("open")}
+			modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+			When(githubGetter.GetPullRequest(testdata.GithubRepo, testdata.Pull.Num)).ThenReturn(pull, nil)
+			When(eventParsing.ParseGithubPull(pull)).ThenReturn(modelPull, modelPull.BaseRepo, testdata.GithubRepo, nil)
+			When(projectCommandBuilder.BuildPlanCommands(Any[*command.Context](), Any[*events.CommentCommand]())).
+				ThenReturn([]command.ProjectContext{{CommandName: command.Plan, RepoRelDir: ".", Workspace: "default"}}, nil)
+			When(projectCommandRunner.Plan(Any[command.ProjectContext]())).ThenReturn(command.ProjectResult{
+				Command:     command.Plan,
+				RepoRelDir:  ".",
+				Workspace:   "default",
+				PlanSuccess: &models.PlanSuccess{TerraformOutput: "Plan: 1 to add, 0 to change, 0 to destroy."},
+			})
+			When(vcsClient.CreateComment(Any[models.Repo](), Any[int](), Any[string](), Any[string]())).ThenReturn(c.commentErr)
+
+			ch.RunCommentCommand(testdata.GithubRepo, &testdata.GithubRepo, nil, testdata.User, testdata.Pull.Num, &events.CommentCommand{Name: command.Plan, RepoRelDir: "."})
+
+			if c.expFailedStatus {
+				commitUpdater.VerifyWasCalledOnce().UpdateCombined(modelPull.BaseRepo, modelPull, models.FailedCommitStatus, command.Plan)
+			} else {
+				commitUpdater.VerifyWasCalled(Never()).UpdateCombined(Any[models.Repo](), Any[models.PullRequest](), Eq(models.FailedCommitStatus), Any[command.Name]())
+			}
+		})
+	}
+}
+
 func TestRunUnlockCommandFail_VCSComment(t *testing.T) {
 	t.Log("if unlock PR command is run and delete fails, atlantis should" +
 		" invoke comment on PR with error message")
@@ -759,6 +1212,52 @@ func TestRunAutoplanCommand_DeletePlans(t *testing.T) {
 	lockingLocker.VerifyWasCalledOnce().UnlockByPull(testdata.Pull.BaseRepo.FullName, testdata.Pull.Num)
 }
 
+func TestRunAutoplanCommand_NoProjects(t *testing.T) {
+	cases := []struct {
+		description     string
+		commentEnabled  bool
+		expCommentCount int
+	}{
+		{
+			description:     "comment disabled",
+			commentEnabled:  false,
+			expCommentCount: 0,
+		},
+		{
+			description:     "comment enabled",
+			commentEnabled:  true,
+			expCommentCount: 1,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			vcsClient := setup(t, func(tc *TestConfig) {
+				tc.autoplanCommentNoProjects = c.commentEnabled
+			})
+			When(projectCommandBuilder.BuildAutoplanCommands(Any[*command.Context]())).
+				ThenReturn([]command.ProjectContext{}, nil)
+			testdata.Pull.BaseRepo = testdata.GithubRepo
+			ch.RunAutoplanCommand(testdata.GithubRepo, testdata.GithubRepo, testdata.Pull, testdata.User)
+
+			vcsClient.VerifyWasCalled(Times(c.expCommentCount)).CreateComment(
+				Eq(testdata.GithubRepo),
+				Eq(testdata.Pull.Num),
+				Eq("No Terraform projects were affected by this pull request so Atlantis didn't run plan."),
+				Eq("plan"),
+			)
+			commitUpdater.VerifyWasCalledOnce().UpdateCombinedCount(
+				Any[models.Repo](),
+				Any[models.PullRequest](),
+				Eq[models.CommitStatus](models.SuccessCommitStatus),
+				Eq[command.Name](command.Plan),
+				Eq(0),
+				Eq(0),
+			)
+			projectCommandRunner.VerifyWasCalled(Never()).Plan(Any[command.ProjectContext]())
+		})
+	}
+}
+
 func TestRunAutoplanCommand_FailedPreWorkflowHook_FailOnPreWorkflowHookError_False(t *testing.T) {
 	setup(t)
 	tmp := t.TempDir()
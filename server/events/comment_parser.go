@@ -20,8 +20,10 @@ import (
 	"net/url"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/google/shlex"
 	"github.com/runatlantis/atlantis/server/events/command"
@@ -33,6 +35,8 @@ import (
 const (
 	workspaceFlagLong            = "workspace"
 	workspaceFlagShort           = "w"
+	workspacesFlagLong           = "workspaces"
+	workspacesFlagShort          = ""
 	dirFlagLong                  = "dir"
 	dirFlagShort                 = "d"
 	projectFlagLong              = "project"
@@ -41,10 +45,24 @@ const (
 	policySetFlagShort           = ""
 	autoMergeDisabledFlagLong    = "auto-merge-disabled"
 	autoMergeDisabledFlagShort   = ""
+	allowDestroyFlagLong         = "allow-destroy"
+	allowDestroyFlagShort        = ""
 	verboseFlagLong              = "verbose"
 	verboseFlagShort             = ""
 	clearPolicyApprovalFlagLong  = "clear-policy-approval"
 	clearPolicyApprovalFlagShort = ""
+	shaFlagLong                  = "sha"
+	shaFlagShort                 = ""
+	compareFlagLong              = "compare"
+	compareFlagShort             = ""
+	skipFmtCheckFlagLong         = "skip-fmt-check"
+	skipFmtCheckFlagShort        = ""
+	presetFlagLong               = "preset"
+	presetFlagShort              = ""
+	lockTimeoutFlagLong          = "lock-timeout"
+	lockTimeoutFlagShort         = ""
+	skipStateLockFlagLong        = "skip-state-lock"
+	skipStateLockFlagShort       = ""
 )
 
 // multiLineRegex is used to ignore multi-line comments since those aren't valid
@@ -54,6 +72,9 @@ const (
 // and pasting GitHub comments.
 var multiLineRegex = regexp.MustCompile(`.*\r?\n[^\r\n]+`)
 
+// shaRegex matches full or abbreviated git commit SHAs.
+var shaRegex = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
 //go:generate pegomock generate --package mocks -o mocks/mock_comment_parsing.go CommentParsing
 
 // CommentParsing handles parsing pull request comments.
@@ -83,6 +104,9 @@ type CommentParser struct {
 	AzureDevopsUser string
 	ExecutableName  string
 	AllowCommands   []command.Name
+	// AllowSkipStateLock is true if apply can be run without locking the
+	// Terraform state, ex. to recover from a stale lock.
+	AllowSkipStateLock bool
 }
 
 // NewCommentParser returns a CommentParser
@@ -125,7 +149,7 @@ type CommentParseResult struct {
 //   - The initial "executable" name, 'run' or 'atlantis' or '@GithubUser'
 //     where GithubUser is the API user Atlantis is running as.
 //   - Then a command: 'plan', 'apply', 'unlock', 'version, 'approve_policies',
-//     or 'help'.
+//     'cancel', 'confirm' or 'help'.
 //   - Then optional flags, then an optional separator '--' followed by optional
 //     extra flags to be appended to the terraform plan/apply command.
 //
@@ -139,6 +163,8 @@ type CommentParseResult struct {
 // - atlantis version
 // - atlantis approve_policies
 // - atlantis import ADDRESS ID
+// - atlantis cancel -p project
+// - atlantis confirm
 func (e *CommentParser) Parse(rawComment string, vcsHost models.VCSHostType) CommentParseResult {
 	comment := strings.TrimSpace(rawComment)
 
@@ -218,11 +244,16 @@ func (e *CommentParser) Parse(rawComment string, vcsHost models.VCSHostType) Com
 	}
 
 	var workspace string
+	var workspaces []string
 	var dir string
 	var project string
 	var policySet string
 	var clearPolicyApproval bool
-	var verbose, autoMergeDisabled bool
+	var sha string
+	var comparePull int
+	var preset string
+	var lockTimeout string
+	var verbose, autoMergeDisabled, allowDestroy, skipFmtCheck, skipStateLock bool
 	var flagSet *pflag.FlagSet
 	var name command.Name
 
@@ -233,8 +264,13 @@ func (e *CommentParser) Parse(rawComment string, vcsHost models.VCSHostType) Com
 		flagSet = pflag.NewFlagSet(command.Plan.String(), pflag.ContinueOnError)
 		flagSet.SetOutput(io.Discard)
 		flagSet.StringVarP(&workspace, workspaceFlagLong, workspaceFlagShort, "", "Switch to this Terraform workspace before planning.")
+		flagSet.StringSliceVarP(&workspaces, workspacesFlagLong, workspacesFlagShort, nil, "Plan in each of these Terraform workspaces, ex. 'staging,prod'. Cannot be used at same time as workspace flag.")
 		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Which directory to run plan in relative to root of repo, ex. 'child/dir'.")
 		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", "Which project to run plan for. Refers to the name of the project configured in a repo config file. Cannot be used at same time as workspace or dir flags.")
+		flagSet.StringVarP(&sha, shaFlagLong, shaFlagShort, "", "Plan a previous commit of the pull request instead of its head. Historical plans can't be applied.")
+		flagSet.IntVarP(&comparePull, compareFlagLong, compareFlagShort, 0, "Instead of planning, show how the existing plans differ from the plans of another pull request, ex. 123.")
+		flagSet.BoolVarP(&skipFmtCheck, skipFmtCheckFlagLong, skipFmtCheckFlagShort, false, "Plan even if the Terraform files aren't formatted.")
+		flagSet.StringVarP(&preset, presetFlagLong, presetFlagShort, "", "Add the plan args of this preset, configured in the project's plan_presets.")
 		flagSet.BoolVarP(&verbose, verboseFlagLong, verboseFlagShort, false, "Append Atlantis log to comment.")
 	case command.Apply.String():
 		name = command.Apply
@@ -244,6 +280,9 @@ func (e *CommentParser) Parse(rawComment string, vcsHost models.VCSHostType) Com
 		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Apply the plan for this directory, relative to root of repo, ex. 'child/dir'.")
 		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", "Apply the plan for this project. Refers to the name of the project configured in a repo config file. Cannot be used at same time as workspace or dir flags.")
 		flagSet.BoolVarP(&autoMergeDisabled, autoMergeDisabledFlagLong, autoMergeDisabledFlagShort, false, "Disable automerge after apply.")
+		flagSet.BoolVarP(&allowDestroy, allowDestroyFlagLong, allowDestroyFlagShort, false, "Apply plans that destroy resources even if the project requires no_destroy.")
+		flagSet.StringVarP(&lockTimeout, lockTimeoutFlagLong, lockTimeoutFlagShort, "", "How long Terraform retries acquiring the state lock, ex. '0s' or '5m'.")
+		flagSet.BoolVarP(&skipStateLock, skipStateLockFlagLong, skipStateLockFlagShort, false, "Apply without locking the Terraform state. Must be allowed by the server.")
 		flagSet.BoolVarP(&verbose, verboseFlagLong, verboseFlagShort, false, "Append Atlantis log to comment.")
 	case command.ApprovePolicies.String():
 		name = command.ApprovePolicies
@@ -282,6 +321,25 @@ func (e *CommentParser) Parse(rawComment string, vcsHost models.VCSHostType) Com
 		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Which directory to run state command in relative to root of repo, ex. 'child/dir'.")
 		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", "Which project to run state command for. Refers to the name of the project configured in a repo config file. Cannot be used at same time as workspace or dir flags.")
 		flagSet.BoolVarP(&verbose, verboseFlagLong, verboseFlagShort, false, "Append Atlantis log to comment.")
+	case command.Cancel.String():
+		name = command.Cancel
+		flagSet = pflag.NewFlagSet(command.Cancel.String(), pflag.ContinueOnError)
+		flagSet.SetOutput(io.Discard)
+		flagSet.StringVarP(&workspace, workspaceFlagLong, workspaceFlagShort, "", "Cancel the running command for this Terraform workspace.")
+		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Cancel the running command for this directory, relative to root of repo, ex. 'child/dir'.")
+		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", "Cancel the running command for this project. Refers to the name of the project configured in a repo config file. Cannot be used at same time as workspace or dir flags.")
+	case command.Validate.String():
+		name = command.Validate
+		flagSet = pflag.NewFlagSet(command.Validate.String(), pflag.ContinueOnError)
+		flagSet.SetOutput(io.Discard)
+		flagSet.StringVarP(&workspace, workspaceFlagLong, workspaceFlagShort, "", "Switch to this Terraform workspace before validating.")
+		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Which directory to run validate in relative to root of repo, ex. 'child/dir'.")
+		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", "Which project to run validate for. Refers to the name of the project configured in a repo config file. Cannot be used at same time as workspace or dir flags.")
+		flagSet.BoolVarP(&verbose, verboseFlagLong, verboseFlagShort, false, "Append Atlantis log to comment.")
+	case command.Confirm.String():
+		name = command.Confirm
+		flagSet = pflag.NewFlagSet(command.Confirm.String(), pflag.ContinueOnError)
+		flagSet.SetOutput(io.Discard)
 	default:
 		return CommentParseResult{CommentResponse: fmt.Sprintf("Error: unknown command %q – this is a bug", cmd)}
 	}
@@ -302,6 +360,19 @@ func (e *CommentParser) Parse(rawComment string, vcsHost models.VCSHostType) Com
 	if workspace != url.PathEscape(workspace) || strings.Contains(workspace, "..") {
 		return CommentParseResult{CommentResponse: e.errMarkdown(fmt.Sprintf("invalid workspace: %q", workspace), cmd, flagSet)}
 	}
+	for _, w := range workspaces {
+		if w == "" || w != url.PathEscape(w) || strings.Contains(w, "..") {
+			return CommentParseResult{CommentResponse: e.errMarkdown(fmt.Sprintf("invalid workspace: %q", w), cmd, flagSet)}
+		}
+	}
+	if len(workspaces) > 0 && workspace != "" {
+		err := fmt.Sprintf("cannot use --%s at same time as -%s/--%s", workspacesFlagLong, workspaceFlagShort, workspaceFlagLong)
+		return CommentParseResult{CommentResponse: e.errMarkdown(err, cmd, flagSet)}
+	}
+	if len(workspaces) > 0 && comparePull != 0 {
+		err := fmt.Sprintf("cannot use --%s at same time as --%s", workspacesFlagLong, compareFlagLong)
+		return CommentParseResult{CommentResponse: e.errMarkdown(err, cmd, flagSet)}
+	}
 
 	// If project is specified, dir or workspace should not be set. Since we
 	// dir/workspace have defaults we can't detect if the user set the flag
@@ -313,11 +384,57 @@ func (e *CommentParser) Parse(rawComment string, vcsHost models.VCSHostType) Com
 		return CommentParseResult{CommentResponse: e.errMarkdown(err, cmd, flagSet)}
 	}
 
+	if sha != "" && !shaRegex.MatchString(sha) {
+		return CommentParseResult{CommentResponse: e.errMarkdown(fmt.Sprintf("invalid commit sha: %q", sha), cmd, flagSet)}
+	}
+
+	if comparePull < 0 {
+		return CommentParseResult{CommentResponse: e.errMarkdown(fmt.Sprintf("invalid pull request number: %d", comparePull), cmd, flagSet)}
+	}
+	if comparePull != 0 && sha != "" {
+		err := fmt.Sprintf("cannot use --%s at same time as --%s", compareFlagLong, shaFlagLong)
+		return CommentParseResult{CommentResponse: e.errMarkdown(err, cmd, flagSet)}
+	}
+
+	if lockTimeout != "" {
+		if _, err := time.ParseDuration(lockTimeout); err != nil {
+			return CommentParseResult{CommentResponse: e.errMarkdown(fmt.Sprintf("invalid lock timeout: %q", lockTimeout), cmd, flagSet)}
+		}
+	}
+	// Plans don't write the state so they may always skip locking it.
+	if name != command.Plan && (skipStateLock || disablesStateLock(extraArgs)) && !e.AllowSkipStateLock {
+		return CommentParseResult{CommentResponse: e.errMarkdown("running without locking the Terraform state isn't allowed, the server must be started with --allow-skip-state-lock", cmd, flagSet)}
+	}
+
+	commentCommand := NewCommentCommand(dir, extraArgs, name, subName, verbose, autoMergeDisabled, workspace, project, policySet, clearPolicyApproval)
+	commentCommand.SHA = strings.ToLower(sha)
+	commentCommand.ComparePull = comparePull
+	commentCommand.AllowDestroy = allowDestroy
+	commentCommand.SkipFmtCheck = skipFmtCheck
+	commentCommand.PlanPreset = preset
+	commentCommand.Workspaces = workspaces
+	commentCommand.StateLockTimeout = lockTimeout
+	commentCommand.SkipStateLock = skipStateLock
 	return CommentParseResult{
-		Command: NewCommentCommand(dir, extraArgs, name, subName, verbose, autoMergeDisabled, workspace, project, policySet, clearPolicyApproval),
+		Command: commentCommand,
 	}
 }
 
+// disablesStateLock returns true if the Terraform args turn off state
+// locking, ex. -lock=false.
+func disablesStateLock(args []string) bool {
+	for _, arg := range args {
+		name, value, found := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name != "lock" || !found {
+			continue
+		}
+		if lock, err := strconv.ParseBool(strings.Trim(value, `"'`)); err == nil && !lock {
+			return true
+		}
+	}
+	return false
+}
+
 func (e *CommentParser) parseArgs(name command.Name, args []string, flagSet *pflag.FlagSet) (string, []string, string) {
 	// Now parse the flags.
 	// It's safe to use [2:] because we know there's at least 2 elements in args.
@@ -492,6 +609,9 @@ func (e *CommentParser) HelpComment() string {
 		AllowApprovePolicies bool
 		AllowImport          bool
 		AllowState           bool
+		AllowCancel          bool
+		AllowValidate        bool
+		AllowConfirm         bool
 	}{
 		ExecutableName:       e.ExecutableName,
 		AllowVersion:         e.isAllowedCommand(command.Version.String()),
@@ -501,6 +621,9 @@ func (e *CommentParser) HelpComment() string {
 		AllowApprovePolicies: e.isAllowedCommand(command.ApprovePolicies.String()),
 		AllowImport:          e.isAllowedCommand(command.Import.String()),
 		AllowState:           e.isAllowedCommand(command.State.String()),
+		AllowCancel:          e.isAllowedCommand(command.Cancel.String()),
+		AllowValidate:        e.isAllowedCommand(command.Validate.String()),
+		AllowConfirm:         e.isAllowedCommand(command.Confirm.String()),
 	}); err != nil {
 		return fmt.Sprintf("Failed to render template, this is a bug: %v", err)
 	}
@@ -548,6 +671,10 @@ Commands:
   approve_policies
            Approves all current policy checking failures for the PR.
 {{- end }}
+{{- if .AllowValidate }}
+  validate Runs 'terraform validate' for the changes in this pull request.
+           To validate a specific project, use the -d, -w and -p flags.
+{{- end }}
 {{- if .AllowVersion }}
   version  Print the output of 'terraform version'
 {{- end }}
@@ -560,6 +687,15 @@ Commands:
   state rm ADDRESS...
            Runs 'terraform state rm' for the passed address resource.
            To remove a specific project resource, use the -d, -w and -p flags.
+{{- end }}
+{{- if .AllowCancel }}
+  cancel   Stops any running plan or apply for this pull request and
+           releases its locks. To cancel a specific project, use the
+           -d, -w and -p flags.
+{{- end }}
+{{- if .AllowConfirm }}
+  confirm  Applies the plans of the last 'apply' on repos whose applies
+           must be confirmed.
 {{- end }}
   help     View help.
 
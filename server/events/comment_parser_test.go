@@ -413,6 +413,204 @@ func TestParse_ValidCommand(t *testing.T) {
 	}
 }
 
+func TestParse_Cancel(t *testing.T) {
+	cases := []struct {
+		comment string
+		exp     *events.CommentCommand
+	}{
+		{
+			"atlantis cancel",
+			&events.CommentCommand{Name: command.Cancel},
+		},
+		{
+			"atlantis cancel -p project",
+			&events.CommentCommand{Name: command.Cancel, ProjectName: "project"},
+		},
+		{
+			"atlantis cancel -d dir -w staging",
+			&events.CommentCommand{Name: command.Cancel, RepoRelDir: "dir", Workspace: "staging"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.comment, func(t *testing.T) {
+			r := commentParser.Parse(c.comment, models.Github)
+			Equals(t, "", r.CommentResponse)
+			Equals(t, c.exp, r.Command)
+		})
+	}
+}
+
+func TestParse_Validate(t *testing.T) {
+	cases := []struct {
+		comment string
+		exp     *events.CommentCommand
+	}{
+		{
+			"atlantis validate",
+			&events.CommentCommand{Name: command.Validate},
+		},
+		{
+			"atlantis validate -p project --verbose",
+			&events.CommentCommand{Name: command.Validate, ProjectName: "project", Verbose: true},
+		},
+		{
+			"atlantis validate -d dir -w staging",
+			&events.CommentCommand{Name: command.Validate, RepoRelDir: "dir", Workspace: "staging"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.comment, func(t *testing.T) {
+			r := commentParser.Parse(c.comment, models.Github)
+			Equals(t, "", r.CommentResponse)
+			Equals(t, c.exp, r.Command)
+		})
+	}
+}
+
+func TestParse_Confirm(t *testing.T) {
+	r := commentParser.Parse("atlantis confirm", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, &events.CommentCommand{Name: command.Confirm}, r.Command)
+
+	r = commentParser.Parse("atlantis confirm -p project", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "unknown shorthand flag: 'p' in -p"), "unexpected response: %s", r.CommentResponse)
+
+	// The comment can't mark an apply as confirmed itself.
+	r = commentParser.Parse("atlantis apply --confirmed", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "unknown flag: --confirmed"), "unexpected response: %s", r.CommentResponse)
+}
+
+func TestParse_PlanSHA(t *testing.T) {
+	r := commentParser.Parse("atlantis plan --sha ABCDEF1 -p project", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, &events.CommentCommand{Name: command.Plan, ProjectName: "project", SHA: "abcdef1"}, r.Command)
+
+	r = commentParser.Parse("atlantis plan --sha abc", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, `invalid commit sha: "abc"`), "unexpected response: %s", r.CommentResponse)
+
+	r = commentParser.Parse("atlantis plan --sha -abcdef1", models.Github)
+	Assert(t, r.CommentResponse != "", "expected an error response")
+
+	// Historical plans can't be applied.
+	r = commentParser.Parse("atlantis apply --sha abcdef1", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "unknown flag: --sha"), "unexpected response: %s", r.CommentResponse)
+}
+
+func TestParse_AllowDestroy(t *testing.T) {
+	r := commentParser.Parse("atlantis apply -p project --allow-destroy", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, &events.CommentCommand{Name: command.Apply, ProjectName: "project", AllowDestroy: true}, r.Command)
+
+	r = commentParser.Parse("atlantis apply -p project", models.Github)
+	Equals(t, false, r.Command.AllowDestroy)
+
+	r = commentParser.Parse("atlantis plan --allow-destroy", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "unknown flag: --allow-destroy"), "unexpected response: %s", r.CommentResponse)
+}
+
+func TestParse_PlanWorkspaces(t *testing.T) {
+	r := commentParser.Parse("atlantis plan -p foo --workspaces staging,prod", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, &events.CommentCommand{Name: command.Plan, ProjectName: "foo", Workspaces: []string{"staging", "prod"}}, r.Command)
+	Assert(t, r.Command.IsForSpecificProject(), "exp command to be for a specific project")
+
+	r = commentParser.Parse("atlantis plan -d dir --workspaces staging --workspaces prod", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, &events.CommentCommand{Name: command.Plan, RepoRelDir: "dir", Workspaces: []string{"staging", "prod"}}, r.Command)
+
+	r = commentParser.Parse("atlantis plan --workspaces staging,../prod", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, `invalid workspace: "../prod"`), "unexpected response: %s", r.CommentResponse)
+
+	r = commentParser.Parse("atlantis plan --workspaces staging,", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, `invalid workspace: ""`), "unexpected response: %s", r.CommentResponse)
+
+	r = commentParser.Parse("atlantis plan -w staging --workspaces prod", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "cannot use --workspaces at same time as -w/--workspace"), "unexpected response: %s", r.CommentResponse)
+
+	r = commentParser.Parse("atlantis plan --compare 12 --workspaces prod", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "cannot use --workspaces at same time as --compare"), "unexpected response: %s", r.CommentResponse)
+
+	r = commentParser.Parse("atlantis apply --workspaces prod", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "unknown flag: --workspaces"), "unexpected response: %s", r.CommentResponse)
+}
+
+func TestParse_PlanCompare(t *testing.T) {
+	r := commentParser.Parse("atlantis plan --compare 12 -d dir", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, &events.CommentCommand{Name: command.Plan, RepoRelDir: "dir", ComparePull: 12}, r.Command)
+
+	r = commentParser.Parse("atlantis plan --compare -1", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "invalid pull request number: -1"), "unexpected response: %s", r.CommentResponse)
+
+	r = commentParser.Parse("atlantis plan --compare abc", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "invalid argument"), "unexpected response: %s", r.CommentResponse)
+
+	r = commentParser.Parse("atlantis plan --compare 12 --sha abcdef1", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "cannot use --compare at same time as --sha"), "unexpected response: %s", r.CommentResponse)
+
+	r = commentParser.Parse("atlantis apply --compare 12", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "unknown flag: --compare"), "unexpected response: %s", r.CommentResponse)
+}
+
+func TestParse_SkipFmtCheck(t *testing.T) {
+	r := commentParser.Parse("atlantis plan -d dir --skip-fmt-check", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, &events.CommentCommand{Name: command.Plan, RepoRelDir: "dir", SkipFmtCheck: true}, r.Command)
+
+	r = commentParser.Parse("atlantis plan -d dir", models.Github)
+	Equals(t, false, r.Command.SkipFmtCheck)
+
+	r = commentParser.Parse("atlantis apply --skip-fmt-check", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "unknown flag: --skip-fmt-check"), "unexpected response: %s", r.CommentResponse)
+}
+
+func TestParse_StateLock(t *testing.T) {
+	r := commentParser.Parse("atlantis apply -p project --lock-timeout 0s", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, &events.CommentCommand{Name: command.Apply, ProjectName: "project", StateLockTimeout: "0s"}, r.Command)
+
+	r = commentParser.Parse("atlantis apply --lock-timeout forever", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, `invalid lock timeout: "forever"`), "unexpected response: %s", r.CommentResponse)
+
+	r = commentParser.Parse("atlantis plan --lock-timeout 0s", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "unknown flag: --lock-timeout"), "unexpected response: %s", r.CommentResponse)
+
+	r = commentParser.Parse("atlantis apply --skip-state-lock", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "--allow-skip-state-lock"), "unexpected response: %s", r.CommentResponse)
+
+	r = commentParser.Parse("atlantis apply -- -lock=false", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "--allow-skip-state-lock"), "unexpected response: %s", r.CommentResponse)
+
+	r = commentParser.Parse("atlantis plan -- -lock=false", models.Github)
+	Equals(t, "", r.CommentResponse)
+
+	allowingParser := events.CommentParser{
+		GithubUser:         "github-user",
+		ExecutableName:     "atlantis",
+		AllowCommands:      command.AllCommentCommands,
+		AllowSkipStateLock: true,
+	}
+	r = allowingParser.Parse("atlantis apply --skip-state-lock --lock-timeout 5m", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, &events.CommentCommand{Name: command.Apply, SkipStateLock: true, StateLockTimeout: "5m"}, r.Command)
+
+	r = allowingParser.Parse("atlantis apply -- -lock=false", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, []string{"-lock=false"}, r.Command.Flags)
+}
+
+func TestParse_PlanPreset(t *testing.T) {
+	r := commentParser.Parse("atlantis plan -p foo --preset emergency", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, &events.CommentCommand{Name: command.Plan, ProjectName: "foo", PlanPreset: "emergency"}, r.Command)
+
+	r = commentParser.Parse("atlantis plan -p foo", models.Github)
+	Equals(t, "", r.Command.PlanPreset)
+
+	r = commentParser.Parse("atlantis apply -p foo --preset emergency", models.Github)
+	Assert(t, strings.Contains(r.CommentResponse, "unknown flag: --preset"), "unexpected response: %s", r.CommentResponse)
+}
+
 func TestParse_InvalidWorkspace(t *testing.T) {
 	t.Log("if -w is used with '..' or '/', should return an error")
 	comments := []string{
@@ -877,6 +1075,8 @@ Commands:
            To unlock a specific plan you can use the Atlantis UI.
   approve_policies
            Approves all current policy checking failures for the PR.
+  validate Runs 'terraform validate' for the changes in this pull request.
+           To validate a specific project, use the -d, -w and -p flags.
   version  Print the output of 'terraform version'
   import ADDRESS ID
            Runs 'terraform import' for the passed address resource.
@@ -884,6 +1084,11 @@ Commands:
   state rm ADDRESS...
            Runs 'terraform state rm' for the passed address resource.
            To remove a specific project resource, use the -d, -w and -p flags.
+  cancel   Stops any running plan or apply for this pull request and
+           releases its locks. To cancel a specific project, use the
+           -d, -w and -p flags.
+  confirm  Applies the plans of the last 'apply' on repos whose applies
+           must be confirmed.
   help     View help.
 
 Flags:
@@ -1007,22 +1212,38 @@ func TestParse_VCSUsername(t *testing.T) {
 }
 
 var PlanUsage = `Usage of plan:
-  -d, --dir string         Which directory to run plan in relative to root of repo,
-                           ex. 'child/dir'.
-  -p, --project string     Which project to run plan for. Refers to the name of the
-                           project configured in a repo config file. Cannot be used
-                           at same time as workspace or dir flags.
-      --verbose            Append Atlantis log to comment.
-  -w, --workspace string   Switch to this Terraform workspace before planning.
+      --compare int          Instead of planning, show how the existing plans differ
+                             from the plans of another pull request, ex. 123.
+  -d, --dir string           Which directory to run plan in relative to root of
+                             repo, ex. 'child/dir'.
+      --preset string        Add the plan args of this preset, configured in the
+                             project's plan_presets.
+  -p, --project string       Which project to run plan for. Refers to the name of
+                             the project configured in a repo config file. Cannot be
+                             used at same time as workspace or dir flags.
+      --sha string           Plan a previous commit of the pull request instead of
+                             its head. Historical plans can't be applied.
+      --skip-fmt-check       Plan even if the Terraform files aren't formatted.
+      --verbose              Append Atlantis log to comment.
+  -w, --workspace string     Switch to this Terraform workspace before planning.
+      --workspaces strings   Plan in each of these Terraform workspaces, ex.
+                             'staging,prod'. Cannot be used at same time as
+                             workspace flag.
 `
 
 var ApplyUsage = `Usage of apply:
+      --allow-destroy         Apply plans that destroy resources even if the project
+                              requires no_destroy.
       --auto-merge-disabled   Disable automerge after apply.
   -d, --dir string            Apply the plan for this directory, relative to root of
                               repo, ex. 'child/dir'.
+      --lock-timeout string   How long Terraform retries acquiring the state lock,
+                              ex. '0s' or '5m'.
   -p, --project string        Apply the plan for this project. Refers to the name of
                               the project configured in a repo config file. Cannot
                               be used at same time as workspace or dir flags.
+      --skip-state-lock       Apply without locking the Terraform state. Must be
+                              allowed by the server.
       --verbose               Append Atlantis log to comment.
   -w, --workspace string      Apply the plan for this Terraform workspace.
 `
@@ -0,0 +1,99 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// lockCommentTimeFormat is the format of the times in lock comments.
+const lockCommentTimeFormat = "2006-01-02 15:04:05 MST"
+
+// CommentingLocker implements locking.Locker.
+// It acts as a proxy to an instance of locking.Locker that comments on pull
+// requests when their project locks are acquired and released, so it's
+// visible how long a pull request held them.
+type CommentingLocker struct {
+	locking.Locker
+	VCSClient vcs.Client
+	Logger    logging.SimpleLogging
+}
+
+// NewCommentingLocker returns a CommentingLocker that comments when locker
+// acquires or releases locks.
+func NewCommentingLocker(locker locking.Locker, vcsClient vcs.Client, logger logging.SimpleLogging) *CommentingLocker {
+	return &CommentingLocker{
+		Locker:    locker,
+		VCSClient: vcsClient,
+		Logger:    logger,
+	}
+}
+
+// TryLock comments if the lock is newly acquired. Locks the pull request
+// already held aren't commented again.
+func (c *CommentingLocker) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User) (locking.TryLockResponse, error) {
+	res, err := c.Locker.TryLock(p, workspace, pull, user)
+	if err != nil || !res.LockAcquired {
+		return res, err
+	}
+	comment := fmt.Sprintf(":lock: Locked dir: `%s` workspace: `%s` for this pull request at %s.",
+		res.CurrLock.Project.Path, res.CurrLock.Workspace, c.formatTime(res.CurrLock.Time))
+	c.comment(pull, comment)
+	return res, nil
+}
+
+// Unlock comments on the pull request that held the lock at key if there was
+// one.
+func (c *CommentingLocker) Unlock(key string) (*models.ProjectLock, error) {
+	lock, err := c.Locker.Unlock(key)
+	if err != nil || lock == nil {
+		return lock, err
+	}
+	c.commentReleased([]models.ProjectLock{*lock})
+	return lock, nil
+}
+
+// UnlockByPull comments once on the pull request for all the locks it held.
+func (c *CommentingLocker) UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error) {
+	locks, err := c.Locker.UnlockByPull(repoFullName, pullNum)
+	if err != nil || len(locks) == 0 {
+		return locks, err
+	}
+	c.commentReleased(locks)
+	return locks, nil
+}
+
+// commentReleased comments that locks, which were all held by the same pull
+// request, were released.
+func (c *CommentingLocker) commentReleased(locks []models.ProjectLock) {
+	releasedAt := c.formatTime(time.Now())
+	var comment string
+	if len(locks) == 1 {
+		comment = fmt.Sprintf(":unlock: Unlocked dir: `%s` workspace: `%s` at %s. It was locked at %s.",
+			locks[0].Project.Path, locks[0].Workspace, releasedAt, c.formatTime(locks[0].Time))
+	} else {
+		var lines []string
+		for _, lock := range locks {
+			lines = append(lines, fmt.Sprintf("* dir: `%s` workspace: `%s`, locked at %s", lock.Project.Path, lock.Workspace, c.formatTime(lock.Time)))
+		}
+		comment = fmt.Sprintf(":unlock: Unlocked the projects of this pull request at %s:\n\n%s", releasedAt, strings.Join(lines, "\n"))
+	}
+	c.comment(locks[0].Pull, comment)
+}
+
+// comment comments on pull. Failures are logged since the lock itself was
+// acquired or released.
+func (c *CommentingLocker) comment(pull models.PullRequest, comment string) {
+	if err := c.VCSClient.CreateComment(pull.BaseRepo, pull.Num, comment, ""); err != nil {
+		c.Logger.Warn("unable to comment on lock of %s#%d: %s", pull.BaseRepo.FullName, pull.Num, err)
+	}
+}
+
+func (c *CommentingLocker) formatTime(t time.Time) string {
+	return t.UTC().Format(lockCommentTimeFormat)
+}
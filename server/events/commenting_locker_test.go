@@ -0,0 +1,100 @@
+package events_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock/v4"
+	"github.com/runatlantis/atlantis/server/core/locking"
+	lockingmocks "github.com/runatlantis/atlantis/server/core/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/testdata"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestCommentingLocker_LockLifecycle(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockLocker := lockingmocks.NewMockLocker()
+	vcsClient := vcsmocks.NewMockClient()
+	locker := events.NewCommentingLocker(mockLocker, vcsClient, logging.NewNoopLogger(t))
+
+	pull := testdata.Pull
+	pull.BaseRepo = testdata.GithubRepo
+	project := models.NewProject(pull.BaseRepo.FullName, "mydir")
+	lock := models.ProjectLock{
+		Project:   project,
+		Pull:      pull,
+		User:      testdata.User,
+		Workspace: "default",
+		Time:      time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC),
+	}
+	lockKey := "runatlantis/atlantis/mydir/default"
+
+	// A newly acquired lock is commented.
+	When(mockLocker.TryLock(project, "default", pull, testdata.User)).ThenReturn(locking.TryLockResponse{LockAcquired: true, CurrLock: lock, LockKey: lockKey}, nil)
+	res, err := locker.TryLock(project, "default", pull, testdata.User)
+	Ok(t, err)
+	Equals(t, true, res.LockAcquired)
+	vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, pull.Num,
+		":lock: Locked dir: `mydir` workspace: `default` for this pull request at 2026-10-15 09:30:00 UTC.", "")
+
+	// A lock the pull request already holds isn't commented again.
+	When(mockLocker.TryLock(project, "default", pull, testdata.User)).ThenReturn(locking.TryLockResponse{LockAcquired: false, CurrLock: lock, LockKey: lockKey}, nil)
+	_, err = locker.TryLock(project, "default", pull, testdata.User)
+	Ok(t, err)
+	vcsClient.VerifyWasCalledOnce().CreateComment(Any[models.Repo](), Any[int](), Any[string](), Any[string]())
+
+	// Releasing the lock is commented with when it was locked.
+	When(mockLocker.Unlock(lockKey)).ThenReturn(&lock, nil)
+	unlocked, err := locker.Unlock(lockKey)
+	Ok(t, err)
+	Equals(t, &lock, unlocked)
+	_, _, comments, _ := vcsClient.VerifyWasCalled(Times(2)).CreateComment(Any[models.Repo](), Eq(pull.Num), Any[string](), Any[string]()).GetAllCapturedArguments()
+	Assert(t, strings.HasPrefix(comments[1], ":unlock: Unlocked dir: `mydir` workspace: `default` at "), "exp unlock comment, got %q", comments[1])
+	Assert(t, strings.HasSuffix(comments[1], ". It was locked at 2026-10-15 09:30:00 UTC."), "exp the lock time in %q", comments[1])
+
+	// Unlocking when there's no lock isn't commented.
+	When(mockLocker.Unlock(lockKey)).ThenReturn(nil, nil)
+	_, err = locker.Unlock(lockKey)
+	Ok(t, err)
+	vcsClient.VerifyWasCalled(Times(2)).CreateComment(Any[models.Repo](), Any[int](), Any[string](), Any[string]())
+}
+
+func TestCommentingLocker_UnlockByPull(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockLocker := lockingmocks.NewMockLocker()
+	vcsClient := vcsmocks.NewMockClient()
+	locker := events.NewCommentingLocker(mockLocker, vcsClient, logging.NewNoopLogger(t))
+
+	pull := testdata.Pull
+	pull.BaseRepo = testdata.GithubRepo
+	var locks []models.ProjectLock
+	for _, dir := range []string{"app", "network"} {
+		locks = append(locks, models.ProjectLock{
+			Project:   models.NewProject(pull.BaseRepo.FullName, dir),
+			Pull:      pull,
+			Workspace: "default",
+			Time:      time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC),
+		})
+	}
+	When(mockLocker.UnlockByPull(pull.BaseRepo.FullName, pull.Num)).ThenReturn(locks, nil)
+
+	unlocked, err := locker.UnlockByPull(pull.BaseRepo.FullName, pull.Num)
+	Ok(t, err)
+	Equals(t, locks, unlocked)
+	_, _, comment, _ := vcsClient.VerifyWasCalledOnce().CreateComment(Any[models.Repo](), Eq(pull.Num), Any[string](), Eq("")).GetCapturedArguments()
+	Assert(t, strings.HasPrefix(comment, ":unlock: Unlocked the projects of this pull request at "), "exp unlock comment, got %q", comment)
+	Assert(t, strings.HasSuffix(comment, ":\n\n"+
+		"* dir: `app` workspace: `default`, locked at 2026-10-15 09:30:00 UTC\n"+
+		"* dir: `network` workspace: `default`, locked at 2026-10-15 09:30:00 UTC"), "exp each lock in %q", comment)
+
+	// Pull requests without locks aren't commented.
+	When(mockLocker.UnlockByPull(pull.BaseRepo.FullName, pull.Num)).ThenReturn(nil, nil)
+	_, err = locker.UnlockByPull(pull.BaseRepo.FullName, pull.Num)
+	Ok(t, err)
+	vcsClient.VerifyWasCalledOnce().CreateComment(Any[models.Repo](), Any[int](), Any[string](), Any[string]())
+}
@@ -0,0 +1,35 @@
+package events
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// headCommitMetadata reads the metadata of the pull request's head commit
+// from its clone in repoDir. With the merge checkout strategy HEAD is the
+// merge commit, so the head commit is looked up by its SHA.
+func headCommitMetadata(repoDir string, pull models.PullRequest) (models.CommitMetadata, error) {
+	rev := pull.HeadCommit
+	if rev == "" {
+		rev = "HEAD"
+	}
+	cmd := exec.Command("git", "log", "-1", "--format=%H%x00%an%x00%ae%x00%B", rev, "--") // #nosec
+	cmd.Dir = repoDir
+	out, err := cmd.Output()
+	if err != nil {
+		return models.CommitMetadata{}, fmt.Errorf("reading commit %s: %w", rev, err)
+	}
+	fields := strings.SplitN(string(out), "\x00", 4)
+	if len(fields) != 4 {
+		return models.CommitMetadata{}, fmt.Errorf("reading commit %s: unexpected output %q", rev, out)
+	}
+	return models.CommitMetadata{
+		SHA:         fields[0],
+		AuthorName:  fields[1],
+		AuthorEmail: fields[2],
+		Message:     strings.TrimSpace(fields[3]),
+	}, nil
+}
@@ -35,6 +35,12 @@ type CommitStatusUpdater interface {
 	// UpdateCombinedCount updates the combined status to reflect the
 	// numSuccess out of numTotal.
 	UpdateCombinedCount(repo models.Repo, pull models.PullRequest, status models.CommitStatus, cmdName command.Name, numSuccess int, numTotal int) error
+	// UpdateDestroyCheck updates the status that fails if numDestroying
+	// projects that require no_destroy have plans that destroy resources.
+	UpdateDestroyCheck(repo models.Repo, pull models.PullRequest, status models.CommitStatus, numDestroying int) error
+	// UpdateFmtCheck updates the status that fails if numUnformatted
+	// projects have Terraform files that aren't formatted.
+	UpdateFmtCheck(repo models.Repo, pull models.PullRequest, status models.CommitStatus, numUnformatted int) error
 
 	UpdatePreWorkflowHook(pull models.PullRequest, status models.CommitStatus, hookDescription string, runtimeDescription string, url string) error
 	UpdatePostWorkflowHook(pull models.PullRequest, status models.CommitStatus, hookDescription string, runtimeDescription string, url string) error
@@ -43,6 +49,9 @@ type CommitStatusUpdater interface {
 // DefaultCommitStatusUpdater implements CommitStatusUpdater.
 type DefaultCommitStatusUpdater struct {
 	Client vcs.Client
+	// StatusClient, if set, is used instead of Client to update statuses so
+	// they're rate limited separately from comments.
+	StatusClient vcs.Client
 	// StatusName is the name used to identify Atlantis when creating PR statuses.
 	StatusName string
 }
@@ -62,7 +71,7 @@ func (d *DefaultCommitStatusUpdater) UpdateCombined(repo models.Repo, pull model
 	case models.SuccessCommitStatus:
 		descripWords = genProjectStatusDescription(cmdName.String(), "succeeded.")
 	}
-	return d.Client.UpdateStatus(repo, pull, status, src, descripWords, "")
+	return d.statusClient().UpdateStatus(repo, pull, status, src, descripWords, "")
 }
 
 func (d *DefaultCommitStatusUpdater) UpdateCombinedCount(repo models.Repo, pull models.PullRequest, status models.CommitStatus, cmdName command.Name, numSuccess int, numTotal int) error {
@@ -78,7 +87,25 @@ func (d *DefaultCommitStatusUpdater) UpdateCombinedCount(repo models.Repo, pull
 		cmdVerb = "applied"
 	}
 
-	return d.Client.UpdateStatus(repo, pull, status, src, fmt.Sprintf("%d/%d projects %s successfully.", numSuccess, numTotal, cmdVerb), "")
+	return d.statusClient().UpdateStatus(repo, pull, status, src, fmt.Sprintf("%d/%d projects %s successfully.", numSuccess, numTotal, cmdVerb), "")
+}
+
+func (d *DefaultCommitStatusUpdater) UpdateDestroyCheck(repo models.Repo, pull models.PullRequest, status models.CommitStatus, numDestroying int) error {
+	src := fmt.Sprintf("%s/destroy-check", d.StatusName)
+	descripWords := "No plans destroy resources."
+	if numDestroying > 0 {
+		descripWords = fmt.Sprintf("%d project(s) plan to destroy resources, apply with --%s.", numDestroying, allowDestroyFlagLong)
+	}
+	return d.statusClient().UpdateStatus(repo, pull, status, src, descripWords, "")
+}
+
+func (d *DefaultCommitStatusUpdater) UpdateFmtCheck(repo models.Repo, pull models.PullRequest, status models.CommitStatus, numUnformatted int) error {
+	src := fmt.Sprintf("%s/fmt-check", d.StatusName)
+	descripWords := "Terraform files are formatted."
+	if numUnformatted > 0 {
+		descripWords = fmt.Sprintf("%d project(s) have unformatted Terraform files, run terraform fmt.", numUnformatted)
+	}
+	return d.statusClient().UpdateStatus(repo, pull, status, src, descripWords, "")
 }
 
 func (d *DefaultCommitStatusUpdater) UpdateProject(ctx command.ProjectContext, cmdName command.Name, status models.CommitStatus, url string, result *command.ProjectResult) error {
@@ -87,6 +114,11 @@ func (d *DefaultCommitStatusUpdater) UpdateProject(ctx command.ProjectContext, c
 		projectID = fmt.Sprintf("%s/%s", ctx.RepoRelDir, ctx.Workspace)
 	}
 	src := fmt.Sprintf("%s/%s: %s", d.StatusName, cmdName.String(), projectID)
+	if ctx.StatusContextSuffix != "" {
+		// Project names and suffixes can't contain spaces so contexts stay
+		// unique across projects.
+		src = fmt.Sprintf("%s (%s)", src, ctx.StatusContextSuffix)
+	}
 	var descripWords string
 	switch status {
 	case models.PendingCommitStatus:
@@ -100,7 +132,15 @@ func (d *DefaultCommitStatusUpdater) UpdateProject(ctx command.ProjectContext, c
 			descripWords = genProjectStatusDescription(cmdName.String(), "succeeded.")
 		}
 	}
-	return d.Client.UpdateStatus(ctx.BaseRepo, ctx.Pull, status, src, descripWords, url)
+	return d.statusClient().UpdateStatus(ctx.BaseRepo, ctx.Pull, status, src, descripWords, url)
+}
+
+// statusClient returns the client that updates statuses.
+func (d *DefaultCommitStatusUpdater) statusClient() vcs.Client {
+	if d.StatusClient != nil {
+		return d.StatusClient
+	}
+	return d.Client
 }
 
 func genProjectStatusDescription(cmdName, description string) string {
@@ -132,5 +172,5 @@ func (d *DefaultCommitStatusUpdater) updateWorkflowHook(pull models.PullRequest,
 		}
 	}
 
-	return d.Client.UpdateStatus(pull.BaseRepo, pull, status, src, descripWords, url)
+	return d.statusClient().UpdateStatus(pull.BaseRepo, pull, status, src, descripWords, url)
 }
@@ -148,10 +148,11 @@ func TestUpdateCombinedCount(t *testing.T) {
 func TestDefaultCommitStatusUpdater_UpdateProjectSrc(t *testing.T) {
 	RegisterMockTestingT(t)
 	cases := []struct {
-		projectName string
-		repoRelDir  string
-		workspace   string
-		expSrc      string
+		projectName  string
+		repoRelDir   string
+		workspace    string
+		statusSuffix string
+		expSrc       string
 	}{
 		{
 			projectName: "name",
@@ -165,6 +166,20 @@ func TestDefaultCommitStatusUpdater_UpdateProjectSrc(t *testing.T) {
 			workspace:   "workspace",
 			expSrc:      "atlantis/plan: dir1/dir2/workspace",
 		},
+		{
+			projectName:  "name",
+			repoRelDir:   ".",
+			workspace:    "default",
+			statusSuffix: "team-a",
+			expSrc:       "atlantis/plan: name (team-a)",
+		},
+		{
+			projectName:  "",
+			repoRelDir:   "dir1/dir2",
+			workspace:    "workspace",
+			statusSuffix: "team-a",
+			expSrc:       "atlantis/plan: dir1/dir2/workspace (team-a)",
+		},
 	}
 
 	for _, c := range cases {
@@ -172,9 +187,10 @@ func TestDefaultCommitStatusUpdater_UpdateProjectSrc(t *testing.T) {
 			client := mocks.NewMockClient()
 			s := events.DefaultCommitStatusUpdater{Client: client, StatusName: "atlantis"}
 			err := s.UpdateProject(command.ProjectContext{
-				ProjectName: c.projectName,
-				RepoRelDir:  c.repoRelDir,
-				Workspace:   c.workspace,
+				ProjectName:         c.projectName,
+				RepoRelDir:          c.repoRelDir,
+				Workspace:           c.workspace,
+				StatusContextSuffix: c.statusSuffix,
 			}, command.Plan, models.PendingCommitStatus, "url", nil)
 			Ok(t, err)
 			client.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, models.PullRequest{}, models.PendingCommitStatus, c.expSrc, "Plan in progress...", "url")
@@ -258,3 +274,21 @@ func TestDefaultCommitStatusUpdater_UpdateProjectCustomStatusName(t *testing.T)
 	client.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, models.PullRequest{},
 		models.SuccessCommitStatus, "custom/apply: ./default", "Apply succeeded.", "url")
 }
+
+// Test that statuses are updated with the status client when it's set.
+func TestDefaultCommitStatusUpdater_StatusClient(t *testing.T) {
+	RegisterMockTestingT(t)
+	client := mocks.NewMockClient()
+	statusClient := mocks.NewMockClient()
+	s := events.DefaultCommitStatusUpdater{Client: client, StatusClient: statusClient, StatusName: "atlantis"}
+	pull := models.PullRequest{Num: 1}
+
+	Ok(t, s.UpdateCombined(models.Repo{}, pull, models.PendingCommitStatus, command.Plan))
+	Ok(t, s.UpdateProject(command.ProjectContext{RepoRelDir: ".", Workspace: "default", Pull: pull}, command.Plan, models.PendingCommitStatus, "url", nil))
+	Ok(t, s.UpdatePreWorkflowHook(pull, models.PendingCommitStatus, "hook", "", "url"))
+
+	statusClient.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, pull, models.PendingCommitStatus, "atlantis/plan", "Plan in progress...", "")
+	statusClient.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, pull, models.PendingCommitStatus, "atlantis/plan: ./default", "Plan in progress...", "url")
+	statusClient.VerifyWasCalledOnce().UpdateStatus(models.Repo{}, pull, models.PendingCommitStatus, "atlantis/pre_workflow_hook: hook", "in progress...", "url")
+	client.VerifyWasCalled(Never()).UpdateStatus(Any[models.Repo](), Any[models.PullRequest](), Any[models.CommitStatus](), Any[string](), Any[string](), Any[string]())
+}
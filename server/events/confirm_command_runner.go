@@ -0,0 +1,154 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+)
+
+// PendingApply is an apply that's waiting for `atlantis confirm`.
+type PendingApply struct {
+	// Cmd is the apply command to run once it's confirmed.
+	Cmd *CommentCommand
+	// HeadCommit is the head commit of the pull request when the apply was
+	// requested. The apply can't be confirmed once the pull request changes.
+	HeadCommit string
+	// PlannedAt is when each of the pull request's plans was created, by
+	// project, when the apply was requested. The apply can't be confirmed
+	// once a project is planned again since it would apply the new plan.
+	PlannedAt map[string]time.Time
+}
+
+// PendingApplies holds the applies waiting for confirmation on repos with
+// confirm_apply set, one per pull request. They're kept in memory so they're
+// lost when Atlantis restarts.
+type PendingApplies struct {
+	mu      sync.Mutex
+	applies map[string]PendingApply
+}
+
+func NewPendingApplies() *PendingApplies {
+	return &PendingApplies{
+		applies: make(map[string]PendingApply),
+	}
+}
+
+// Add records cmd as the apply waiting for confirmation on pull, whose plans
+// are in pullStatus, replacing any earlier one.
+func (p *PendingApplies) Add(pull models.PullRequest, pullStatus *models.PullStatus, cmd *CommentCommand) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.applies[p.key(pull)] = PendingApply{Cmd: cmd, HeadCommit: pull.HeadCommit, PlannedAt: plannedAt(pullStatus)}
+}
+
+// Pop removes and returns the apply waiting for confirmation on pull. It
+// returns false if there's none.
+func (p *PendingApplies) Pop(pull models.PullRequest) (PendingApply, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pending, ok := p.applies[p.key(pull)]
+	delete(p.applies, p.key(pull))
+	return pending, ok
+}
+
+func (p *PendingApplies) key(pull models.PullRequest) string {
+	return fmt.Sprintf("%s/%d", pull.BaseRepo.FullName, pull.Num)
+}
+
+// plannedAt returns when each project's plan in pullStatus was created, by
+// project.
+func plannedAt(pullStatus *models.PullStatus) map[string]time.Time {
+	planned := make(map[string]time.Time)
+	if pullStatus == nil {
+		return planned
+	}
+	for _, project := range pullStatus.Projects {
+		if project.PlannedAt.IsZero() {
+			continue
+		}
+		planned[fmt.Sprintf("%s/%s/%s", project.RepoRelDir, project.Workspace, project.ProjectName)] = project.PlannedAt
+	}
+	return planned
+}
+
+// replanned returns true if any project was planned since the plans in
+// before were created.
+func replanned(before map[string]time.Time, after map[string]time.Time) bool {
+	if len(before) != len(after) {
+		return true
+	}
+	for project, plannedAt := range after {
+		if !plannedAt.Equal(before[project]) {
+			return true
+		}
+	}
+	return false
+}
+
+func NewConfirmCommandRunner(
+	pendingApplies *PendingApplies,
+	applyCommandRunner CommentCommandRunner,
+	vcsClient vcs.Client,
+) *ConfirmCommandRunner {
+	return &ConfirmCommandRunner{
+		pendingApplies:     pendingApplies,
+		applyCommandRunner: applyCommandRunner,
+		vcsClient:          vcsClient,
+	}
+}
+
+// ConfirmCommandRunner runs the apply waiting for confirmation on a pull
+// request of a repo with confirm_apply set.
+type ConfirmCommandRunner struct {
+	pendingApplies     *PendingApplies
+	applyCommandRunner CommentCommandRunner
+	vcsClient          vcs.Client
+}
+
+func (c *ConfirmCommandRunner) Run(ctx *command.Context, _ *CommentCommand) {
+	pull := ctx.Pull
+
+	pending, ok := c.pendingApplies.Pop(pull)
+	if !ok {
+		ctx.Log.Info("no apply waiting for confirmation")
+		c.comment(ctx, noPendingApplyComment)
+		return
+	}
+	if pending.HeadCommit != pull.HeadCommit {
+		ctx.Log.Info("not confirming apply requested at commit %s since the pull request is now at commit %s", pending.HeadCommit, pull.HeadCommit)
+		c.comment(ctx, pendingApplyOutdatedComment)
+		return
+	}
+	if replanned(pending.PlannedAt, plannedAt(ctx.PullStatus)) {
+		ctx.Log.Info("not confirming apply since the pull request was planned again after it was requested")
+		c.comment(ctx, pendingApplyReplannedComment)
+		return
+	}
+
+	applyCmd := *pending.Cmd
+	applyCmd.Confirmed = true
+	c.applyCommandRunner.Run(ctx, &applyCmd)
+}
+
+func (c *ConfirmCommandRunner) comment(ctx *command.Context, comment string) {
+	if err := c.vcsClient.CreateComment(ctx.Pull.BaseRepo, ctx.Pull.Num, comment, command.Confirm.String()); err != nil {
+		ctx.Log.Err("unable to comment on pull request: %s", err)
+	}
+	addAuditResult(ctx, command.Result{Failure: comment})
+}
+
+// noPendingApplyComment is posted when `atlantis confirm` is run but no apply
+// is waiting for confirmation.
+var noPendingApplyComment = "**Error:** There's no apply waiting for confirmation. Run `atlantis apply` first."
+
+// pendingApplyOutdatedComment is posted when `atlantis confirm` is run after
+// new commits were pushed to the pull request.
+var pendingApplyOutdatedComment = "**Error:** The pull request changed since `atlantis apply` was run, so the apply was discarded. Run `atlantis apply` again."
+
+// pendingApplyReplannedComment is posted when `atlantis confirm` is run after
+// the pull request was planned again.
+var pendingApplyReplannedComment = "**Error:** The pull request was planned again since `atlantis apply` was run, so the apply was discarded. Run `atlantis apply` again to apply the new plans."
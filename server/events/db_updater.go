@@ -8,6 +8,9 @@ import (
 
 type DBUpdater struct {
 	Backend locking.Backend
+	// WorkingDirCleaner, if set, is told which pull requests have failed
+	// projects so their working dirs can be kept.
+	WorkingDirCleaner *WorkingDirCleaner
 }
 
 func (c *DBUpdater) updateDB(ctx *command.Context, pull models.PullRequest, results []command.ProjectResult) (models.PullStatus, error) {
@@ -23,5 +26,9 @@ func (c *DBUpdater) updateDB(ctx *command.Context, pull models.PullRequest, resu
 		filtered = append(filtered, r)
 	}
 	ctx.Log.Debug("updating DB with pull results")
-	return c.Backend.UpdatePullWithResults(pull, filtered)
+	status, err := c.Backend.UpdatePullWithResults(pull, filtered)
+	if err == nil {
+		c.WorkingDirCleaner.RecordPullStatus(status)
+	}
+	return status, err
 }
@@ -111,11 +111,28 @@ type CommentCommand struct {
 	SubName string
 	// AutoMergeDisabled is true if the command should not automerge after apply.
 	AutoMergeDisabled bool
+	// AllowDestroy is true if apply should run even if the plan destroys
+	// resources and the project requires no_destroy.
+	AllowDestroy bool
+	// Confirmed is true if the apply was confirmed with atlantis confirm on
+	// a repo that requires it. It's never set from the comment itself.
+	Confirmed bool
+	// SkipFmtCheck is true if plan should run even if the Terraform files
+	// aren't formatted.
+	SkipFmtCheck bool
+	// PlanPreset is the name of the project's plan preset whose args should
+	// be added to the plan. If empty then the comment specified no preset.
+	PlanPreset string
 	// Verbose is true if the command should output verbosely.
 	Verbose bool
 	// Workspace is the name of the Terraform workspace to run the command in.
 	// If empty then the comment specified no workspace.
 	Workspace string
+	// Workspaces are the names of the Terraform workspaces to plan in, one
+	// after the other, ex. atlantis plan -p project --workspaces staging,prod.
+	// The plan is built for each of them with Workspace set to it.
+	// If empty then the comment specified no workspaces.
+	Workspaces []string
 	// ProjectName is the name of a project to run the command on. It refers to a
 	// project specified in an atlantis.yaml file.
 	// If empty then the comment specified no project.
@@ -124,13 +141,26 @@ type CommentCommand struct {
 	PolicySet string
 	// ClearPolicyApproval is true if approvals should be cleared out for specified policies.
 	ClearPolicyApproval bool
+	// SHA is the commit to plan instead of the pull request's head, ex. for
+	// auditing. If empty then the comment specified no commit.
+	SHA string
+	// ComparePull is the number of another pull request whose plans should
+	// be compared with this pull request's plans instead of planning.
+	// If 0 then the comment specified no pull request.
+	ComparePull int
+	// StateLockTimeout is how long Terraform retries acquiring the state lock
+	// on apply, ex. 5m. If empty then the comment specified no timeout.
+	StateLockTimeout string
+	// SkipStateLock is true if apply should run without locking the
+	// Terraform state.
+	SkipStateLock bool
 }
 
 // IsForSpecificProject returns true if the command is for a specific dir, workspace
 // or project name. Otherwise it's a command like "atlantis plan" or "atlantis
 // apply".
 func (c CommentCommand) IsForSpecificProject() bool {
-	return c.RepoRelDir != "" || c.Workspace != "" || c.ProjectName != ""
+	return c.RepoRelDir != "" || c.Workspace != "" || len(c.Workspaces) > 0 || c.ProjectName != ""
 }
 
 // CommandName returns the name of this command.
@@ -574,6 +604,7 @@ func (e *EventParser) ParseGithubPull(pull *github.PullRequest) (pullModel model
 
 	pullModel = models.PullRequest{
 		Author:     authorUsername,
+		Body:       pull.GetBody(),
 		HeadBranch: headBranch,
 		HeadCommit: commit,
 		URL:        url,
@@ -627,6 +658,7 @@ func (e *EventParser) ParseGitlabMergeRequestEvent(event gitlab.MergeEvent) (pul
 	pull = models.PullRequest{
 		URL:        event.ObjectAttributes.URL,
 		Author:     event.User.Username,
+		Body:       event.ObjectAttributes.Description,
 		Num:        event.ObjectAttributes.IID,
 		HeadCommit: event.ObjectAttributes.LastCommit.ID,
 		HeadBranch: event.ObjectAttributes.SourceBranch,
@@ -700,6 +732,7 @@ func (e *EventParser) ParseGitlabMergeRequest(mr *gitlab.MergeRequest, baseRepo
 	return models.PullRequest{
 		URL:        mr.WebURL,
 		Author:     mr.Author.Username,
+		Body:       mr.Description,
 		Num:        mr.IID,
 		HeadCommit: mr.SHA,
 		HeadBranch: mr.SourceBranch,
@@ -906,6 +939,7 @@ func (e *EventParser) ParseAzureDevopsPull(pull *azuredevops.GitPullRequest) (pu
 
 	pullModel = models.PullRequest{
 		Author: authorUsername,
+		Body:   pull.GetDescription(),
 		// Change webhook refs from "refs/heads/<branch>" to "<branch>"
 		HeadBranch: strings.Replace(headBranch, "refs/heads/", "", 1),
 		HeadCommit: commit,
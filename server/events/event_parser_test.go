@@ -329,6 +329,12 @@ func TestParseGithubPull(t *testing.T) {
 	}, pullRes)
 	Equals(t, expBaseRepo, actBaseRepo)
 	Equals(t, expBaseRepo, actHeadRepo)
+
+	testPull = deepcopy.Copy(Pull).(github.PullRequest)
+	testPull.Body = github.String("---\nsize: 3\n---")
+	pullRes, _, _, err = parser.ParseGithubPull(&testPull)
+	Ok(t, err)
+	Equals(t, "---\nsize: 3\n---", pullRes.Body)
 }
 
 func TestParseGitlabMergeEvent(t *testing.T) {
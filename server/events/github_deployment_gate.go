@@ -0,0 +1,99 @@
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// GithubDeploymentClient creates GitHub deployments and reads and updates
+// their statuses.
+type GithubDeploymentClient interface {
+	CreateDeployment(repo models.Repo, ref string, environment string, description string) (int64, error)
+	GetDeploymentState(repo models.Repo, deploymentID int64) (string, error)
+	UpdateDeploymentStatus(repo models.Repo, deploymentID int64, state string, description string) error
+}
+
+// GithubDeploymentGate creates a GitHub deployment to a project's
+// deployment environment before it's applied and waits for the deployment's
+// status to be set to in_progress or success. GitHub itself doesn't do that
+// for deployments created through the API, even if the environment requires
+// reviewers, so something else, ex. a workflow job that runs in the
+// environment, must set the status once the deployment is approved.
+// A nil *GithubDeploymentGate approves every apply.
+type GithubDeploymentGate struct {
+	Client GithubDeploymentClient
+	// Timeout is how long to wait for a deployment to be approved before
+	// the apply is aborted.
+	Timeout time.Duration
+	// PollInterval is how often the deployment's status is checked while
+	// waiting.
+	PollInterval time.Duration
+}
+
+// Wait creates a deployment of the pull request's head commit to the
+// project's deployment environment and blocks until it's approved, which is
+// when its latest status is set to in_progress or success. It returns the ID of the
+// deployment, or a failure if the deployment was rejected or wasn't approved
+// within the timeout. Projects without a deployment environment are approved
+// without creating a deployment.
+func (g *GithubDeploymentGate) Wait(ctx command.ProjectContext) (deploymentID int64, failure string, err error) {
+	if g == nil || ctx.DeploymentEnvironment == "" {
+		return 0, "", nil
+	}
+	repo := ctx.Pull.BaseRepo
+	if repo.VCSHost.Type != models.Github {
+		ctx.Log.Warn("ignoring deployment environment %q, deployments are only supported on GitHub", ctx.DeploymentEnvironment)
+		return 0, "", nil
+	}
+
+	description := fmt.Sprintf("atlantis apply for dir: %s workspace: %s", ctx.RepoRelDir, ctx.Workspace)
+	deploymentID, err = g.Client.CreateDeployment(repo, ctx.Pull.HeadCommit, ctx.DeploymentEnvironment, description)
+	if err != nil {
+		return 0, "", err
+	}
+	ctx.Log.Info("created deployment %d to environment %q, waiting up to %s for it to be approved", deploymentID, ctx.DeploymentEnvironment, g.Timeout)
+
+	deadline := time.Now().Add(g.Timeout)
+	for {
+		state, err := g.Client.GetDeploymentState(repo, deploymentID)
+		if err != nil {
+			return 0, "", err
+		}
+		switch state {
+		case "in_progress", "success":
+			ctx.Log.Info("deployment %d to environment %q was approved", deploymentID, ctx.DeploymentEnvironment)
+			return deploymentID, "", nil
+		case "failure", "error", "inactive":
+			return 0, fmt.Sprintf("Deployment to environment %q was not approved, its status is %q.", ctx.DeploymentEnvironment, state), nil
+		}
+
+		if !time.Now().Add(g.PollInterval).Before(deadline) {
+			break
+		}
+		time.Sleep(g.PollInterval)
+	}
+
+	// Mark the deployment as failed so it doesn't stay pending forever.
+	if err := g.Client.UpdateDeploymentStatus(repo, deploymentID, "error", "not approved in time"); err != nil {
+		ctx.Log.Warn("unable to update status of deployment %d: %s", deploymentID, err)
+	}
+	return 0, fmt.Sprintf("Deployment to environment %q was not approved within %s.", ctx.DeploymentEnvironment, g.Timeout), nil
+}
+
+// Finish sets the status of an approved deployment to success or failure
+// depending on the result of the apply.
+func (g *GithubDeploymentGate) Finish(ctx command.ProjectContext, deploymentID int64, applyErr error) {
+	if g == nil || deploymentID == 0 {
+		return
+	}
+	state, description := "success", "applied by atlantis"
+	if applyErr != nil {
+		state, description = "failure", "atlantis apply failed"
+	}
+	if err := g.Client.UpdateDeploymentStatus(ctx.Pull.BaseRepo, deploymentID, state, description); err != nil {
+		ctx.Log.Warn("unable to update status of deployment %d: %s", deploymentID, err)
+	}
+}
@@ -0,0 +1,171 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// fakeDeploymentClient returns states in order, repeating the last one.
+type fakeDeploymentClient struct {
+	createErr    error
+	states       []string
+	environments []string
+	refs         []string
+	statuses     []string
+}
+
+func (f *fakeDeploymentClient) CreateDeployment(_ models.Repo, ref string, environment string, _ string) (int64, error) {
+	f.refs = append(f.refs, ref)
+	f.environments = append(f.environments, environment)
+	return 42, f.createErr
+}
+
+func (f *fakeDeploymentClient) GetDeploymentState(_ models.Repo, deploymentID int64) (string, error) {
+	if deploymentID != 42 {
+		return "", errors.New("unknown deployment")
+	}
+	state := f.states[0]
+	if len(f.states) > 1 {
+		f.states = f.states[1:]
+	}
+	return state, nil
+}
+
+func (f *fakeDeploymentClient) UpdateDeploymentStatus(_ models.Repo, _ int64, state string, _ string) error {
+	f.statuses = append(f.statuses, state)
+	return nil
+}
+
+func deploymentGateCtx(t *testing.T, environment string, vcsHost models.VCSHostType) command.ProjectContext {
+	return command.ProjectContext{
+		Log:                   logging.NewNoopLogger(t),
+		RepoRelDir:            "path/to/project",
+		Workspace:             "default",
+		DeploymentEnvironment: environment,
+		Pull: models.PullRequest{
+			Num:        1,
+			HeadCommit: "abc123",
+			BaseRepo: models.Repo{
+				FullName: "owner/repo",
+				VCSHost:  models.VCSHost{Type: vcsHost},
+			},
+		},
+	}
+}
+
+func TestGithubDeploymentGate_Wait(t *testing.T) {
+	cases := []struct {
+		description    string
+		environment    string
+		vcsHost        models.VCSHostType
+		states         []string
+		createErr      error
+		expID          int64
+		expFailure     string
+		expErr         string
+		expDeployments int
+		expStatuses    []string
+	}{
+		{
+			description: "no deployment environment",
+			vcsHost:     models.Github,
+		},
+		{
+			description: "not github",
+			environment: "production",
+			vcsHost:     models.Gitlab,
+		},
+		{
+			description:    "approved after waiting",
+			environment:    "production",
+			vcsHost:        models.Github,
+			states:         []string{"", "queued", "waiting", "in_progress"},
+			expID:          42,
+			expDeployments: 1,
+		},
+		{
+			description:    "approved by success status",
+			environment:    "production",
+			vcsHost:        models.Github,
+			states:         []string{"success"},
+			expID:          42,
+			expDeployments: 1,
+		},
+		{
+			description:    "rejected",
+			environment:    "production",
+			vcsHost:        models.Github,
+			states:         []string{"queued", "failure"},
+			expFailure:     `Deployment to environment "production" was not approved, its status is "failure".`,
+			expDeployments: 1,
+		},
+		{
+			description:    "timed out",
+			environment:    "production",
+			vcsHost:        models.Github,
+			states:         []string{"waiting"},
+			expFailure:     `Deployment to environment "production" was not approved within 20ms.`,
+			expDeployments: 1,
+			expStatuses:    []string{"error"},
+		},
+		{
+			description:    "error creating deployment",
+			environment:    "production",
+			vcsHost:        models.Github,
+			createErr:      errors.New("creating deployment to environment \"production\": 404 Not Found"),
+			expErr:         "creating deployment to environment \"production\": 404 Not Found",
+			expDeployments: 1,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			client := &fakeDeploymentClient{states: c.states, createErr: c.createErr}
+			gate := &events.GithubDeploymentGate{
+				Client:       client,
+				Timeout:      20 * time.Millisecond,
+				PollInterval: time.Millisecond,
+			}
+			id, failure, err := gate.Wait(deploymentGateCtx(t, c.environment, c.vcsHost))
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+			} else {
+				Ok(t, err)
+			}
+			Equals(t, c.expID, id)
+			Equals(t, c.expFailure, failure)
+			Equals(t, c.expDeployments, len(client.environments))
+			if c.expDeployments > 0 {
+				Equals(t, []string{c.environment}, client.environments)
+				Equals(t, []string{"abc123"}, client.refs)
+			}
+			Equals(t, c.expStatuses, client.statuses)
+		})
+	}
+}
+
+func TestGithubDeploymentGate_Finish(t *testing.T) {
+	ctx := deploymentGateCtx(t, "production", models.Github)
+	client := &fakeDeploymentClient{}
+	gate := &events.GithubDeploymentGate{Client: client}
+
+	gate.Finish(ctx, 42, nil)
+	gate.Finish(ctx, 42, errors.New("apply failed"))
+	// No deployment was created.
+	gate.Finish(ctx, 0, nil)
+	Equals(t, []string{"success", "failure"}, client.statuses)
+
+	// A nil gate does nothing.
+	var nilGate *events.GithubDeploymentGate
+	nilGate.Finish(ctx, 42, nil)
+	id, failure, err := nilGate.Wait(ctx)
+	Ok(t, err)
+	Equals(t, int64(0), id)
+	Equals(t, "", failure)
+}
@@ -19,10 +19,12 @@ import (
 	"fmt"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/Masterminds/sprig/v3"
 	"github.com/runatlantis/atlantis/server/events/command"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/utils"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
@@ -35,6 +37,7 @@ var (
 	versionCommandTitle         = command.Version.TitleString()
 	importCommandTitle          = command.Import.TitleString()
 	stateCommandTitle           = command.State.TitleString()
+	validateCommandTitle        = command.Validate.TitleString()
 	// maxUnwrappedLines is the maximum number of lines the Terraform output
 	// can be before we wrap it in an expandable template.
 	maxUnwrappedLines = 12
@@ -43,6 +46,13 @@ var (
 	templatesFS embed.FS
 )
 
+// historicalPlanNote is prepended to comments for plans of previous commits.
+const historicalPlanNote = ":warning: This is a historical plan for commit `%s`, not the head of this pull request. It can't be applied.\n\n"
+
+// planComparisonNote is prepended to comments comparing the plans of two pull
+// requests.
+const planComparisonNote = ":mag: Compared the plans of this pull request with the plans of #%d. Resources that aren't listed are changed the same way by both.\n\n"
+
 // MarkdownRenderer renders responses as markdown.
 type MarkdownRenderer struct {
 	// gitlabSupportsCommonMark is true if the version of GitLab we're
@@ -57,6 +67,42 @@ type MarkdownRenderer struct {
 	markdownTemplates         *template.Template
 	executableName            string
 	hideUnchangedPlanComments bool
+	minimizePlanHeaders       bool
+	// planCommentFooter is true if plan comments end with a footer with
+	// the versions Atlantis and Terraform and how long the plans took.
+	planCommentFooter bool
+	atlantisVersion   string
+	defaultTFVersion  string
+	// unauthorizedContact is who users that aren't allowed to run a command
+	// are told to contact, ex. "@org/platform-team". Empty if not set.
+	unauthorizedContact string
+}
+
+// MarkdownRendererOptions configures a MarkdownRenderer.
+type MarkdownRendererOptions struct {
+	// GitlabSupportsCommonMark is true if the version of GitLab we're
+	// using supports the CommonMark markdown format.
+	GitlabSupportsCommonMark bool
+	DisableApplyAll          bool
+	DisableApply             bool
+	DisableMarkdownFolding   bool
+	DisableRepoLocking       bool
+	EnableDiffMarkdownFormat bool
+	// MarkdownTemplateOverridesDir is a directory of templates that override
+	// the default ones. It's ignored if it doesn't exist.
+	MarkdownTemplateOverridesDir string
+	ExecutableName               string
+	HideUnchangedPlanComments    bool
+	MinimizePlanHeaders          bool
+	// PlanCommentFooter ends plan comments with a footer with the versions of
+	// Atlantis, AtlantisVersion, and Terraform, DefaultTFVersion unless the
+	// project sets its own, and how long the plans took.
+	PlanCommentFooter bool
+	AtlantisVersion   string
+	DefaultTFVersion  string
+	// UnauthorizedContact is who users that aren't allowed to run a command
+	// are told to contact, ex. "@org/platform-team".
+	UnauthorizedContact string
 }
 
 // commonData is data that all responses have.
@@ -72,6 +118,9 @@ type commonData struct {
 	EnableDiffMarkdownFormat  bool
 	ExecutableName            string
 	HideUnchangedPlanComments bool
+	// MinimizePlanHeaders replaces the headers of plan comments with each
+	// project's name and change summary.
+	MinimizePlanHeaders bool
 }
 
 // errData is data about an error response.
@@ -88,6 +137,39 @@ type failureData struct {
 	commonData
 }
 
+// workflowHookFailureData is data about a failed workflow hook.
+type workflowHookFailureData struct {
+	HookType           string
+	HookDescription    string
+	RuntimeDescription string
+	URL                string
+	Command            string
+	ExecutableName     string
+}
+
+// lockFailureData is data about the lock held by another pull request that
+// stopped a project from being locked.
+type lockFailureData struct {
+	PullLink       string
+	PullURL        string
+	Path           string
+	Workspace      string
+	User           string
+	LockURL        string
+	ExecutableName string
+}
+
+// unauthorizedData is data about a command a user wasn't allowed to run.
+type unauthorizedData struct {
+	User           string
+	Command        string
+	Contact        string
+	ExecutableName string
+	// TeamAllowlist is true if the user was denied by --gh-team-allowlist
+	// rather than --apply-allowlist.
+	TeamAllowlist bool
+}
+
 type resultData struct {
 	Results []projectResultTmplData
 	commonData
@@ -119,42 +201,80 @@ type projectResultTmplData struct {
 	ProjectName string
 	Rendered    string
 	NoChanges   bool
+	// PlanSummary is the one line summary of the plan's changes without the
+	// "Plan: " prefix, ex. "1 to add, 0 to change, 0 to destroy.".
+	PlanSummary string
+	// OwnersHeading is the heading of the group of projects with the same
+	// owners the project is in. It's empty if projects aren't grouped.
+	OwnersHeading string
+}
+
+// planCommentFooterData is data about the run of a plan, for the footer of
+// plan comments.
+type planCommentFooterData struct {
+	AtlantisVersion string
+	// TerraformVersion is the versions of Terraform the projects were
+	// planned with, ex. "1.5.7, 1.6.0".
+	TerraformVersion string
+	// Duration is how long the plans took, rounded to the second. It's 0
+	// if the plans weren't timed.
+	Duration time.Duration
+}
+
+// applySummaryData is data about the applies of all projects, for the
+// summary comment.
+type applySummaryData struct {
+	Results      []applySummaryResultData
+	NumSucceeded int
+	NumFailed    int
+	commonData
+}
+
+type applySummaryResultData struct {
+	Workspace   string
+	RepoRelDir  string
+	ProjectName string
+	Succeeded   bool
+	// Changes is the one line summary of the changes the apply made, ex.
+	// "1 added, 0 changed, 0 destroyed.". It's empty if the apply failed.
+	Changes string
 }
 
 // Initialize templates
-func NewMarkdownRenderer(
-	gitlabSupportsCommonMark bool,
-	disableApplyAll bool,
-	disableApply bool,
-	disableMarkdownFolding bool,
-	disableRepoLocking bool,
-	enableDiffMarkdownFormat bool,
-	markdownTemplateOverridesDir string,
-	executableName string,
-	hideUnchangedPlanComments bool,
-) *MarkdownRenderer {
+func NewMarkdownRenderer(opts MarkdownRendererOptions) *MarkdownRenderer {
 	var templates *template.Template
 	templates, _ = template.New("").Funcs(sprig.TxtFuncMap()).ParseFS(templatesFS, "templates/*.tmpl")
-	if overrides, err := templates.ParseGlob(fmt.Sprintf("%s/*.tmpl", markdownTemplateOverridesDir)); err == nil {
+	if overrides, err := templates.ParseGlob(fmt.Sprintf("%s/*.tmpl", opts.MarkdownTemplateOverridesDir)); err == nil {
 		// doesn't override if templates directory doesn't exist
 		templates = overrides
 	}
 	return &MarkdownRenderer{
-		gitlabSupportsCommonMark:  gitlabSupportsCommonMark,
-		disableApplyAll:           disableApplyAll,
-		disableMarkdownFolding:    disableMarkdownFolding,
-		disableApply:              disableApply,
-		disableRepoLocking:        disableRepoLocking,
-		enableDiffMarkdownFormat:  enableDiffMarkdownFormat,
+		gitlabSupportsCommonMark:  opts.GitlabSupportsCommonMark,
+		disableApplyAll:           opts.DisableApplyAll,
+		disableMarkdownFolding:    opts.DisableMarkdownFolding,
+		disableApply:              opts.DisableApply,
+		disableRepoLocking:        opts.DisableRepoLocking,
+		enableDiffMarkdownFormat:  opts.EnableDiffMarkdownFormat,
 		markdownTemplates:         templates,
-		executableName:            executableName,
-		hideUnchangedPlanComments: hideUnchangedPlanComments,
+		executableName:            opts.ExecutableName,
+		hideUnchangedPlanComments: opts.HideUnchangedPlanComments,
+		minimizePlanHeaders:       opts.MinimizePlanHeaders,
+		planCommentFooter:         opts.PlanCommentFooter,
+		atlantisVersion:           opts.AtlantisVersion,
+		defaultTFVersion:          opts.DefaultTFVersion,
+		unauthorizedContact:       opts.UnauthorizedContact,
 	}
 }
 
 // Render formats the data into a markdown string.
 // nolint: interfacer
 func (m *MarkdownRenderer) Render(res command.Result, cmdName command.Name, subCmd, log string, verbose bool, vcsHost models.VCSHostType) string {
+	return m.RenderByOwners(res, nil, cmdName, subCmd, log, verbose, vcsHost)
+}
+
+// RenderByOwners is like Render but groups the project results by the owners
+// of their projects in codeOwners. If codeOwners is nil it doesn't group.
+func (m *MarkdownRenderer) RenderByOwners(res command.Result, codeOwners *CodeOwners, cmdName command.Name, subCmd, log string, verbose bool, vcsHost models.VCSHostType) string {
 	commandStr := cases.Title(language.English).String(strings.Replace(cmdName.String(), "_", " ", -1))
 	common := commonData{
 		Command:                   commandStr,
@@ -168,8 +288,81 @@ func (m *MarkdownRenderer) Render(res command.Result, cmdName command.Name, subC
 		EnableDiffMarkdownFormat:  m.enableDiffMarkdownFormat,
 		ExecutableName:            m.executableName,
 		HideUnchangedPlanComments: m.hideUnchangedPlanComments,
+		MinimizePlanHeaders:       m.minimizePlanHeaders && commandStr == planCommandTitle,
+	}
+	if res.PlanOnly {
+		common.DisableApplyAll = true
+		common.DisableApply = true
 	}
 
+	if res.HistoricalSHA != "" {
+		// Historical plans can't be applied so don't tell users how to.
+		common.DisableApplyAll = true
+		common.DisableApply = true
+		return fmt.Sprintf(historicalPlanNote, res.HistoricalSHA) + m.render(res, codeOwners, common, vcsHost)
+	}
+	if res.ComparePull != 0 {
+		// Nothing was planned so there's nothing to apply.
+		common.DisableApplyAll = true
+		common.DisableApply = true
+		return fmt.Sprintf(planComparisonNote, res.ComparePull) + m.render(res, codeOwners, common, vcsHost)
+	}
+	if m.planCommentFooter && commandStr == planCommandTitle {
+		return m.render(res, codeOwners, common, vcsHost) + "\n\n" + m.renderPlanCommentFooter(res)
+	}
+	return m.render(res, codeOwners, common, vcsHost)
+}
+
+// RenderApplySummary renders the summary of the applies in res with whether
+// each project's apply succeeded and the changes it made.
+func (m *MarkdownRenderer) RenderApplySummary(res command.Result, log string, verbose bool) string {
+	data := applySummaryData{
+		commonData: commonData{
+			Command:        applyCommandTitle,
+			Verbose:        verbose,
+			Log:            log,
+			ExecutableName: m.executableName,
+		},
+	}
+	for _, result := range res.ProjectResults {
+		resultData := applySummaryResultData{
+			Workspace:   result.Workspace,
+			RepoRelDir:  result.RepoRelDir,
+			ProjectName: result.ProjectName,
+			Succeeded:   result.IsSuccessful(),
+		}
+		if resultData.Succeeded {
+			resultData.Changes = models.ApplyDiffSummary(result.ApplySuccess)
+			data.NumSucceeded++
+		} else {
+			data.NumFailed++
+		}
+		data.Results = append(data.Results, resultData)
+	}
+	return m.renderTemplateTrimSpace(m.markdownTemplates.Lookup("applySummary"), data)
+}
+
+// renderPlanCommentFooter renders the footer of the comment for the plans in
+// res.
+func (m *MarkdownRenderer) renderPlanCommentFooter(res command.Result) string {
+	var tfVersions []string
+	for _, result := range res.ProjectResults {
+		tfVersion := m.defaultTFVersion
+		if result.PlanSuccess != nil && result.PlanSuccess.TerraformVersion != "" {
+			tfVersion = result.PlanSuccess.TerraformVersion
+		}
+		if tfVersion != "" && !utils.SlicesContains(tfVersions, tfVersion) {
+			tfVersions = append(tfVersions, tfVersion)
+		}
+	}
+	return m.renderTemplateTrimSpace(m.markdownTemplates.Lookup("planCommentFooter"), planCommentFooterData{
+		AtlantisVersion:  m.atlantisVersion,
+		TerraformVersion: strings.Join(tfVersions, ", "),
+		Duration:         res.Duration.Round(time.Second),
+	})
+}
+
+func (m *MarkdownRenderer) render(res command.Result, codeOwners *CodeOwners, common commonData, vcsHost models.VCSHostType) string {
 	templates := m.markdownTemplates
 
 	if res.Error != nil {
@@ -178,24 +371,41 @@ func (m *MarkdownRenderer) Render(res command.Result, cmdName command.Name, subC
 	if res.Failure != "" {
 		return m.renderTemplateTrimSpace(templates.Lookup("failureWithLog"), failureData{res.Failure, "", common})
 	}
-	return m.renderProjectResults(res.ProjectResults, common, vcsHost)
+	if codeOwners != nil {
+		var results []command.ProjectResult
+		var ownersHeadings []string
+		for _, group := range codeOwners.Group(res.ProjectResults) {
+			for _, result := range group.ProjectResults {
+				results = append(results, result)
+				ownersHeadings = append(ownersHeadings, codeOwnersHeading(group.Owners))
+			}
+		}
+		return m.renderProjectResults(results, ownersHeadings, common, vcsHost)
+	}
+	return m.renderProjectResults(res.ProjectResults, nil, common, vcsHost)
 }
 
-func (m *MarkdownRenderer) renderProjectResults(results []command.ProjectResult, common commonData, vcsHost models.VCSHostType) string {
+// renderProjectResults renders results. If ownersHeadings isn't nil, it's the
+// heading of the group of each result.
+func (m *MarkdownRenderer) renderProjectResults(results []command.ProjectResult, ownersHeadings []string, common commonData, vcsHost models.VCSHostType) string {
 	var resultsTmplData []projectResultTmplData
 	numPlanSuccesses := 0
 	numPolicyCheckSuccesses := 0
 	numPolicyApprovalSuccesses := 0
 	numVersionSuccesses := 0
+	numValidateSuccesses := 0
 
 	templates := m.markdownTemplates
 
-	for _, result := range results {
+	for i, result := range results {
 		resultData := projectResultTmplData{
 			Workspace:   result.Workspace,
 			RepoRelDir:  result.RepoRelDir,
 			ProjectName: result.ProjectName,
 		}
+		if ownersHeadings != nil {
+			resultData.OwnersHeading = ownersHeadings[i]
+		}
 		if result.PlanSuccess != nil {
 			result.PlanSuccess.TerraformOutput = strings.TrimSpace(result.PlanSuccess.TerraformOutput)
 			data := planSuccessData{
@@ -213,6 +423,7 @@ func (m *MarkdownRenderer) renderProjectResults(results []command.ProjectResult,
 				resultData.Rendered = m.renderTemplateTrimSpace(templates.Lookup("planSuccessUnwrapped"), data)
 			}
 			resultData.NoChanges = result.PlanSuccess.NoChanges()
+			resultData.PlanSummary = strings.TrimPrefix(result.PlanSuccess.DiffSummary(), "Plan: ")
 			numPlanSuccesses++
 		} else if result.PolicyCheckResults != nil && common.Command == policyCheckCommandTitle {
 			policyCheckResults := policyCheckResultsData{
@@ -263,6 +474,14 @@ func (m *MarkdownRenderer) renderProjectResults(results []command.ProjectResult,
 				resultData.Rendered = m.renderTemplateTrimSpace(templates.Lookup("versionUnwrappedSuccess"), struct{ Output string }{output})
 			}
 			numVersionSuccesses++
+		} else if result.ValidateSuccess != "" {
+			output := strings.TrimSpace(result.ValidateSuccess)
+			if m.shouldUseWrappedTmpl(vcsHost, output) {
+				resultData.Rendered = m.renderTemplateTrimSpace(templates.Lookup("validateWrappedSuccess"), struct{ Output string }{output})
+			} else {
+				resultData.Rendered = m.renderTemplateTrimSpace(templates.Lookup("validateUnwrappedSuccess"), struct{ Output string }{output})
+			}
+			numValidateSuccesses++
 		} else if result.ImportSuccess != nil {
 			result.ImportSuccess.Output = strings.TrimSpace(result.ImportSuccess.Output)
 			if m.shouldUseWrappedTmpl(vcsHost, result.ImportSuccess.Output) {
@@ -270,6 +489,16 @@ func (m *MarkdownRenderer) renderProjectResults(results []command.ProjectResult,
 			} else {
 				resultData.Rendered = m.renderTemplateTrimSpace(templates.Lookup("importSuccessUnwrapped"), result.ImportSuccess)
 			}
+		} else if result.PlanComparison != nil {
+			data := struct {
+				models.PlanComparison
+				Output string
+			}{*result.PlanComparison, result.PlanComparison.Output()}
+			if m.shouldUseWrappedTmpl(vcsHost, data.Output) {
+				resultData.Rendered = m.renderTemplateTrimSpace(templates.Lookup("planComparisonWrapped"), data)
+			} else {
+				resultData.Rendered = m.renderTemplateTrimSpace(templates.Lookup("planComparisonUnwrapped"), data)
+			}
 		} else if result.StateRmSuccess != nil {
 			result.StateRmSuccess.Output = strings.TrimSpace(result.StateRmSuccess.Output)
 			if m.shouldUseWrappedTmpl(vcsHost, result.StateRmSuccess.Output) {
@@ -292,6 +521,15 @@ func (m *MarkdownRenderer) renderProjectResults(results []command.ProjectResult,
 		} else if result.Failure != "" {
 			resultData.Rendered = m.renderTemplateTrimSpace(templates.Lookup("failure"), failureData{result.Failure, resultData.Rendered, common})
 		}
+		if result.OnFailureStepsFailed {
+			resultData.Rendered = m.renderTemplateTrimSpace(templates.Lookup("onFailureStepsFailed"), nil) + "\n\n" + resultData.Rendered
+		}
+		if result.MergedPolicyCheck != nil {
+			resultData.Rendered += "\n\n" + m.renderMergedPolicyCheck(*result.MergedPolicyCheck, common, vcsHost)
+		}
+		if result.ReplanSuccess != nil {
+			resultData.Rendered += "\n\n" + m.renderTemplateTrimSpace(templates.Lookup("replannedPlan"), result.ReplanSuccess)
+		}
 		resultsTmplData = append(resultsTmplData, resultData)
 	}
 
@@ -309,6 +547,10 @@ func (m *MarkdownRenderer) renderProjectResults(results []command.ProjectResult,
 		tmpl = templates.Lookup("singleProjectVersionSuccess")
 	case len(resultsTmplData) == 1 && common.Command == versionCommandTitle && numVersionSuccesses == 0:
 		tmpl = templates.Lookup("singleProjectVersionUnsuccessful")
+	case len(resultsTmplData) == 1 && common.Command == validateCommandTitle && numValidateSuccesses > 0:
+		tmpl = templates.Lookup("singleProjectValidateSuccess")
+	case len(resultsTmplData) == 1 && common.Command == validateCommandTitle && numValidateSuccesses == 0:
+		tmpl = templates.Lookup("singleProjectValidateUnsuccessful")
 	case len(resultsTmplData) == 1 && common.Command == applyCommandTitle:
 		tmpl = templates.Lookup("singleProjectApply")
 	case len(resultsTmplData) == 1 && common.Command == importCommandTitle:
@@ -338,6 +580,8 @@ func (m *MarkdownRenderer) renderProjectResults(results []command.ProjectResult,
 		tmpl = templates.Lookup("multiProjectApply")
 	case common.Command == versionCommandTitle:
 		tmpl = templates.Lookup("multiProjectVersion")
+	case common.Command == validateCommandTitle:
+		tmpl = templates.Lookup("multiProjectValidate")
 	case common.Command == importCommandTitle:
 		tmpl = templates.Lookup("multiProjectImport")
 	case common.Command == stateCommandTitle:
@@ -353,6 +597,83 @@ func (m *MarkdownRenderer) renderProjectResults(results []command.ProjectResult,
 	return m.renderTemplateTrimSpace(tmpl, resultData{resultsTmplData, common})
 }
 
+// renderMergedPolicyCheck renders the result of a project's policy check
+// for the project's plan comment.
+func (m *MarkdownRenderer) renderMergedPolicyCheck(result command.ProjectResult, common commonData, vcsHost models.VCSHostType) string {
+	templates := m.markdownTemplates
+	common.Command = policyCheckCommandTitle
+
+	var rendered string
+	if result.PolicyCheckResults != nil {
+		data := policyCheckResultsData{
+			PreConftestOutput:     result.PolicyCheckResults.PreConftestOutput,
+			PostConftestOutput:    result.PolicyCheckResults.PostConftestOutput,
+			PolicyCheckResults:    *result.PolicyCheckResults,
+			PolicyCheckSummary:    result.PolicyCheckResults.Summary(),
+			PolicyApprovalSummary: result.PolicyCheckResults.PolicySummary(),
+			PolicyCleared:         result.PolicyCheckResults.PolicyCleared(),
+			commonData:            common,
+		}
+		if m.shouldUseWrappedTmpl(vcsHost, result.PolicyCheckResults.CombinedOutput()) {
+			rendered = m.renderTemplateTrimSpace(templates.Lookup("mergedPolicyCheckResultsWrapped"), data)
+		} else {
+			rendered = m.renderTemplateTrimSpace(templates.Lookup("mergedPolicyCheckResultsUnwrapped"), data)
+		}
+	}
+	if result.Error != nil {
+		tmpl := templates.Lookup("unwrappedErr")
+		if m.shouldUseWrappedTmpl(vcsHost, result.Error.Error()) {
+			tmpl = templates.Lookup("wrappedErr")
+		}
+		rendered = m.renderTemplateTrimSpace(tmpl, errData{result.Error.Error(), rendered, common})
+	} else if result.Failure != "" {
+		rendered = m.renderTemplateTrimSpace(templates.Lookup("failure"), failureData{result.Failure, rendered, common})
+	}
+	return m.renderTemplateTrimSpace(templates.Lookup("mergedPolicyCheck"), struct{ Rendered string }{rendered})
+}
+
+// RenderWorkflowHookFailure renders the comment posted when a workflow hook
+// fails. hookType is ex. "Pre workflow hook" and url links to the hook's
+// output.
+func (m *MarkdownRenderer) RenderWorkflowHookFailure(hookType string, hookDescription string, runtimeDescription string, url string, cmdName string) string {
+	return m.renderTemplateTrimSpace(m.markdownTemplates.Lookup("workflowHookFailure"), workflowHookFailureData{
+		HookType:           hookType,
+		HookDescription:    hookDescription,
+		RuntimeDescription: runtimeDescription,
+		URL:                url,
+		Command:            cmdName,
+		ExecutableName:     m.executableName,
+	})
+}
+
+// RenderLockFailure renders why a project couldn't be locked because lock is
+// held by another pull request. pullLink links to that pull request and
+// lockURL, if set, to the lock's page.
+func (m *MarkdownRenderer) RenderLockFailure(lock models.ProjectLock, pullLink string, lockURL string) string {
+	return m.renderTemplateTrimSpace(m.markdownTemplates.Lookup("lockFailure"), lockFailureData{
+		PullLink:       pullLink,
+		PullURL:        lock.Pull.URL,
+		Path:           lock.Project.Path,
+		Workspace:      lock.Workspace,
+		User:           lock.User.Username,
+		LockURL:        lockURL,
+		ExecutableName: m.executableName,
+	})
+}
+
+// RenderUnauthorized renders the comment posted when user isn't allowed to
+// run cmdName, ex. "apply". teamAllowlist is true if the user was denied by
+// the team allowlist, which has its own default wording.
+func (m *MarkdownRenderer) RenderUnauthorized(user string, cmdName string, teamAllowlist bool) string {
+	return m.renderTemplateTrimSpace(m.markdownTemplates.Lookup("unauthorized"), unauthorizedData{
+		User:           user,
+		Command:        cmdName,
+		Contact:        m.unauthorizedContact,
+		ExecutableName: m.executableName,
+		TeamAllowlist:  teamAllowlist,
+	})
+}
+
 // shouldUseWrappedTmpl returns true if we should use the wrapped markdown
 // templates that collapse the output to make the comment smaller on initial
 // load. Some VCS providers or versions of VCS providers don't support this
@@ -17,8 +17,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/command"
@@ -54,7 +56,7 @@ func TestRenderErr(t *testing.T) {
 		},
 	}
 
-	r := events.NewMarkdownRenderer(false, false, false, false, false, false, "", "atlantis", false)
+	r := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
 	for _, c := range cases {
 		res := command.Result{
 			Error: c.Error,
@@ -99,7 +101,7 @@ func TestRenderFailure(t *testing.T) {
 		},
 	}
 
-	r := events.NewMarkdownRenderer(false, false, false, false, false, false, "", "atlantis", false)
+	r := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
 	for _, c := range cases {
 		res := command.Result{
 			Failure: c.Failure,
@@ -118,7 +120,7 @@ func TestRenderFailure(t *testing.T) {
 }
 
 func TestRenderErrAndFailure(t *testing.T) {
-	r := events.NewMarkdownRenderer(false, false, false, false, false, false, "", "atlantis", false)
+	r := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
 	res := command.Result{
 		Error:   errors.New("error"),
 		Failure: "failure",
@@ -962,7 +964,7 @@ $$$
 		},
 	}
 
-	r := events.NewMarkdownRenderer(false, false, false, false, false, false, "", "atlantis", false)
+	r := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
 	for _, c := range cases {
 		t.Run(c.Description, func(t *testing.T) {
 			res := command.Result{
@@ -1108,17 +1110,10 @@ $$$
 `,
 		},
 	}
-	r := events.NewMarkdownRenderer(
-		false,      // gitlabSupportsCommonMark
-		true,       // disableApplyAll
-		false,      // disableApply
-		false,      // disableMarkdownFolding
-		false,      // disableRepoLocking
-		false,      // enableDiffMarkdownFormat
-		"",         // MarkdownTemplateOverridesDir
-		"atlantis", // executableName
-		false,      // hideUnchangedPlanComments
-	)
+	r := events.NewMarkdownRenderer(events.MarkdownRendererOptions{
+		DisableApplyAll: true,
+		ExecutableName:  "atlantis",
+	})
 	for _, c := range cases {
 		t.Run(c.Description, func(t *testing.T) {
 			res := command.Result{
@@ -1257,17 +1252,11 @@ $$$
 		},
 	}
 
-	r := events.NewMarkdownRenderer(
-		false,      // gitlabSupportsCommonMark
-		true,       // disableApplyAll
-		true,       // disableApply
-		false,      // disableMarkdownFolding
-		false,      // disableRepoLocking
-		false,      // enableDiffMarkdownFormat
-		"",         // MarkdownTemplateOverridesDir
-		"atlantis", // executableName
-		false,      // hideUnchangedPlanComments
-	)
+	r := events.NewMarkdownRenderer(events.MarkdownRendererOptions{
+		DisableApplyAll: true,
+		DisableApply:    true,
+		ExecutableName:  "atlantis",
+	})
 	for _, c := range cases {
 		t.Run(c.Description, func(t *testing.T) {
 			res := command.Result{
@@ -1297,17 +1286,12 @@ func TestRenderCustomPolicyCheckTemplate_DisableApplyAll(t *testing.T) {
 	Ok(t, err)
 	err = os.WriteFile(filePath, []byte("{{ define \"PolicyCheckResultsUnwrapped\" -}}somecustometext{{- end}}\n"), 0600)
 	Ok(t, err)
-	r := events.NewMarkdownRenderer(
-		false,      // gitlabSupportsCommonMark
-		true,       // disableApplyAll
-		true,       // disableApply
-		false,      // disableMarkdownFolding
-		false,      // disableRepoLocking
-		false,      // enableDiffMarkdownFormat
-		tmpDir,     // MarkdownTemplateOverridesDir
-		"atlantis", // executableName
-		false,      // hideUnchangedPlanComments
-	)
+	r := events.NewMarkdownRenderer(events.MarkdownRendererOptions{
+		DisableApplyAll:              true,
+		DisableApply:                 true,
+		MarkdownTemplateOverridesDir: tmpDir,
+		ExecutableName:               "atlantis",
+	})
 
 	rendered := r.Render(command.Result{
 		ProjectResults: []command.ProjectResult{
@@ -1348,17 +1332,10 @@ $$$
 
 // Test that if folding is disabled that it's not used.
 func TestRenderProjectResults_DisableFolding(t *testing.T) {
-	mr := events.NewMarkdownRenderer(
-		false,      // gitlabSupportsCommonMark
-		false,      // disableApplyAll
-		false,      // disableApply
-		true,       // disableMarkdownFolding
-		false,      // disableRepoLocking
-		false,      // enableDiffMarkdownFormat
-		"",         // MarkdownTemplateOverridesDir
-		"atlantis", // executableName
-		false,      // hideUnchangedPlanComments
-	)
+	mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{
+		DisableMarkdownFolding: true,
+		ExecutableName:         "atlantis",
+	})
 
 	rendered := mr.Render(command.Result{
 		ProjectResults: []command.ProjectResult{
@@ -1440,17 +1417,10 @@ func TestRenderProjectResults_WrappedErr(t *testing.T) {
 	for _, c := range cases {
 		t.Run(fmt.Sprintf("%s_%v", c.VCSHost.String(), c.ShouldWrap),
 			func(t *testing.T) {
-				mr := events.NewMarkdownRenderer(
-					c.GitlabCommonMarkSupport, // gitlabSupportsCommonMark
-					false,                     // disableApplyAll
-					false,                     // disableApply
-					false,                     // disableMarkdownFolding
-					false,                     // disableRepoLocking
-					false,                     // enableDiffMarkdownFormat
-					"",                        // MarkdownTemplateOverridesDir
-					"atlantis",                // executableName
-					false,                     // hideUnchangedPlanComments
-				)
+				mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{
+					GitlabSupportsCommonMark: c.GitlabCommonMarkSupport,
+					ExecutableName:           "atlantis",
+				})
 
 				rendered := mr.Render(command.Result{
 					ProjectResults: []command.ProjectResult{
@@ -1556,17 +1526,10 @@ func TestRenderProjectResults_WrapSingleProject(t *testing.T) {
 		for _, cmd := range []command.Name{command.Plan, command.Apply} {
 			t.Run(fmt.Sprintf("%s_%s_%v", c.VCSHost.String(), cmd.String(), c.ShouldWrap),
 				func(t *testing.T) {
-					mr := events.NewMarkdownRenderer(
-						c.GitlabCommonMarkSupport, // gitlabSupportsCommonMark
-						false,                     // disableApplyAll
-						false,                     // disableApply
-						false,                     // disableMarkdownFolding
-						false,                     // disableRepoLocking
-						false,                     // enableDiffMarkdownFormat
-						"",                        // MarkdownTemplateOverridesDir
-						"atlantis",                // executableName
-						false,                     // hideUnchangedPlanComments
-					)
+					mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{
+						GitlabSupportsCommonMark: c.GitlabCommonMarkSupport,
+						ExecutableName:           "atlantis",
+					})
 					var pr command.ProjectResult
 					switch cmd {
 					case command.Plan:
@@ -1664,17 +1627,9 @@ $$$`
 }
 
 func TestRenderProjectResults_MultiProjectApplyWrapped(t *testing.T) {
-	mr := events.NewMarkdownRenderer(
-		false,      // gitlabSupportsCommonMark
-		false,      // disableApplyAll
-		false,      // disableApply
-		false,      // disableMarkdownFolding
-		false,      // disableRepoLocking
-		false,      // enableDiffMarkdownFormat
-		"",         // MarkdownTemplateOverridesDir
-		"atlantis", // executableName
-		false,      // hideUnchangedPlanComments
-	)
+	mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{
+		ExecutableName: "atlantis",
+	})
 	tfOut := strings.Repeat("line\n", 13)
 	rendered := mr.Render(command.Result{
 		ProjectResults: []command.ProjectResult{
@@ -1720,17 +1675,9 @@ $$$
 }
 
 func TestRenderProjectResults_MultiProjectPlanWrapped(t *testing.T) {
-	mr := events.NewMarkdownRenderer(
-		false,      // gitlabSupportsCommonMark
-		false,      // disableApplyAll
-		false,      // disableApply
-		false,      // disableMarkdownFolding
-		false,      // disableRepoLocking
-		false,      // enableDiffMarkdownFormat
-		"",         // MarkdownTemplateOverridesDir
-		"atlantis", // executableName
-		false,      // hideUnchangedPlanComments
-	)
+	mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{
+		ExecutableName: "atlantis",
+	})
 	tfOut := strings.Repeat("line\n", 13) + "Plan: 1 to add, 0 to change, 0 to destroy."
 	rendered := mr.Render(command.Result{
 		ProjectResults: []command.ProjectResult{
@@ -1896,17 +1843,9 @@ This plan was not saved because one or more projects failed and automerge requir
 
 	for name, c := range cases {
 		t.Run(name, func(t *testing.T) {
-			mr := events.NewMarkdownRenderer(
-				false,      // gitlabSupportsCommonMark
-				false,      // disableApplyAll
-				false,      // disableApply
-				false,      // disableMarkdownFolding
-				false,      // disableRepoLocking
-				false,      // enableDiffMarkdownFormat
-				"",         // MarkdownTemplateOverridesDir
-				"atlantis", // executableName
-				false,      // hideUnchangedPlanComments
-			)
+			mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{
+				ExecutableName: "atlantis",
+			})
 			rendered := mr.Render(c.cr, command.Plan, "", "log", false, models.Github)
 			expWithBackticks := strings.Replace(c.exp, "$", "`", -1)
 			Equals(t, expWithBackticks, rendered)
@@ -1914,6 +1853,402 @@ This plan was not saved because one or more projects failed and automerge requir
 	}
 }
 
+func TestRenderProjectResults_Historical(t *testing.T) {
+	mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{DisableRepoLocking: true, ExecutableName: "atlantis"})
+	cr := command.Result{
+		ProjectResults: []command.ProjectResult{
+			{
+				RepoRelDir: ".",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "tf out",
+					LockURL:         "lock-url",
+					RePlanCmd:       "re-plan cmd",
+					ApplyCmd:        "apply cmd",
+				},
+			},
+		},
+		HistoricalSHA: "abcdef1",
+	}
+	rendered := mr.Render(cr, command.Plan, "", "log", false, models.Github)
+	exp := `:warning: This is a historical plan for commit $abcdef1$, not the head of this pull request. It can't be applied.
+
+Ran Plan for dir: $.$ workspace: $default$
+
+$$$diff
+tf out
+$$$
+
+* :repeat: To **plan** this project again, comment:
+    * $re-plan cmd$`
+	Equals(t, strings.Replace(exp, "$", "`", -1), rendered)
+}
+
+func TestRenderProjectResults_PlanOnly(t *testing.T) {
+	mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{DisableRepoLocking: true, ExecutableName: "atlantis"})
+	cr := command.Result{
+		ProjectResults: []command.ProjectResult{
+			{
+				RepoRelDir: ".",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "tf out",
+					LockURL:         "lock-url",
+					RePlanCmd:       "re-plan cmd",
+					ApplyCmd:        "apply cmd",
+				},
+			},
+		},
+		PlanOnly: true,
+	}
+	rendered := mr.Render(cr, command.Plan, "", "log", false, models.Github)
+	exp := `Ran Plan for dir: $.$ workspace: $default$
+
+$$$diff
+tf out
+$$$
+
+* :repeat: To **plan** this project again, comment:
+    * $re-plan cmd$`
+	Equals(t, strings.Replace(exp, "$", "`", -1), rendered)
+}
+
+func TestRenderProjectResults_MinimizedPlanHeaders(t *testing.T) {
+	mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis", MinimizePlanHeaders: true})
+	changed := command.ProjectResult{
+		RepoRelDir:  "app",
+		Workspace:   "default",
+		ProjectName: "app",
+		PlanSuccess: &models.PlanSuccess{
+			TerraformOutput: "+ null_resource.app\n\nPlan: 1 to add, 0 to change, 0 to destroy.",
+			LockURL:         "lock-url",
+			RePlanCmd:       "atlantis plan -p app",
+			ApplyCmd:        "atlantis apply -p app",
+		},
+	}
+	unchanged := command.ProjectResult{
+		RepoRelDir: "network",
+		Workspace:  "staging",
+		PlanSuccess: &models.PlanSuccess{
+			TerraformOutput: "No changes. Your infrastructure matches the configuration.",
+			LockURL:         "lock-url",
+			RePlanCmd:       "atlantis plan -d network -w staging",
+			ApplyCmd:        "atlantis apply -d network -w staging",
+		},
+	}
+
+	rendered := mr.Render(command.Result{ProjectResults: []command.ProjectResult{changed}}, command.Plan, "", "log", false, models.Github)
+	exp := `Plan $app$: 1 to add, 0 to change, 0 to destroy.
+
+$$$diff
++ null_resource.app
+
+Plan: 1 to add, 0 to change, 0 to destroy.
+$$$
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $atlantis apply -p app$
+* :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $atlantis plan -p app$
+
+---
+* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+* :put_litter_in_its_place: To delete all plans and locks for the PR, comment:
+    * $atlantis unlock$`
+	Equals(t, strings.Replace(exp, "$", "`", -1), rendered)
+
+	rendered = mr.Render(command.Result{ProjectResults: []command.ProjectResult{changed, unchanged}}, command.Plan, "", "log", false, models.Github)
+	exp = `Plan for 2 projects:
+
+### $app$: 1 to add, 0 to change, 0 to destroy.
+$$$diff
++ null_resource.app
+
+Plan: 1 to add, 0 to change, 0 to destroy.
+$$$
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $atlantis apply -p app$
+* :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $atlantis plan -p app$
+
+---
+### $network$ workspace: $staging$: No changes. Your infrastructure matches the configuration.
+$$$diff
+No changes. Your infrastructure matches the configuration.
+$$$
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $atlantis apply -d network -w staging$
+* :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $atlantis plan -d network -w staging$
+
+---
+* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+* :put_litter_in_its_place: To delete all plans and locks for the PR, comment:
+    * $atlantis unlock$`
+	Equals(t, strings.Replace(exp, "$", "`", -1), rendered)
+
+	// Policy checks reuse the plan templates but keep their headers.
+	rendered = mr.Render(command.Result{ProjectResults: []command.ProjectResult{changed}}, command.PolicyCheck, "", "log", false, models.Github)
+	Assert(t, strings.HasPrefix(rendered, "Ran Policy Check for project: `app` dir: `app` workspace: `default`"), "exp policy check header, got %q", rendered)
+}
+
+func TestRenderProjectResults_ByOwners(t *testing.T) {
+	mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
+	codeOwners := events.ParseCodeOwners("/network/ @org/network\n/app/ @org/app\n")
+	var projectResults []command.ProjectResult
+	for _, dir := range []string{"misc", "network", "app"} {
+		projectResults = append(projectResults, command.ProjectResult{
+			RepoRelDir: dir,
+			Workspace:  "default",
+			PlanSuccess: &models.PlanSuccess{
+				TerraformOutput: dir + " out",
+				LockURL:         "lock-url",
+				RePlanCmd:       "atlantis plan -d " + dir,
+				ApplyCmd:        "atlantis apply -d " + dir,
+			},
+		})
+	}
+	rendered := mr.RenderByOwners(command.Result{ProjectResults: projectResults}, codeOwners, command.Plan, "", "log", false, models.Github)
+	exp := `Ran Plan for 3 projects:
+
+1. dir: $app$ workspace: $default$
+1. dir: $network$ workspace: $default$
+1. dir: $misc$ workspace: $default$
+
+## Projects owned by $@org/app$
+### 1. dir: $app$ workspace: $default$
+$$$diff
+app out
+$$$
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $atlantis apply -d app$
+* :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $atlantis plan -d app$
+
+---
+## Projects owned by $@org/network$
+### 2. dir: $network$ workspace: $default$
+$$$diff
+network out
+$$$
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $atlantis apply -d network$
+* :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $atlantis plan -d network$
+
+---
+## Projects without owners
+### 3. dir: $misc$ workspace: $default$
+$$$diff
+misc out
+$$$
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $atlantis apply -d misc$
+* :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $atlantis plan -d misc$
+
+---
+* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+* :put_litter_in_its_place: To delete all plans and locks for the PR, comment:
+    * $atlantis unlock$`
+	Equals(t, strings.Replace(exp, "$", "`", -1), rendered)
+
+	// Without CODEOWNERS, the projects aren't grouped.
+	rendered = mr.RenderByOwners(command.Result{ProjectResults: projectResults}, nil, command.Plan, "", "log", false, models.Github)
+	Equals(t, mr.Render(command.Result{ProjectResults: projectResults}, command.Plan, "", "log", false, models.Github), rendered)
+	Assert(t, !strings.Contains(rendered, "owners"), "exp no owners headings, got %q", rendered)
+}
+
+// test that plan output uploaded to a gist is linked instead of rendered
+func TestRenderProjectResults_GistURL(t *testing.T) {
+	mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
+	cr := command.Result{
+		ProjectResults: []command.ProjectResult{
+			{
+				RepoRelDir: ".",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "tf out",
+					LockURL:         "lock-url",
+					RePlanCmd:       "re-plan cmd",
+					ApplyCmd:        "apply cmd",
+					GistURL:         "https://gist.github.com/abc123",
+				},
+			},
+		},
+	}
+	rendered := mr.Render(cr, command.Plan, "", "log", false, models.Github)
+	exp := `Ran Plan for dir: $.$ workspace: $default$
+
+The plan output is too large to comment, see the [full output](https://gist.github.com/abc123).
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $apply cmd$
+* :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $re-plan cmd$
+
+---
+* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+* :put_litter_in_its_place: To delete all plans and locks for the PR, comment:
+    * $atlantis unlock$`
+	Equals(t, strings.Replace(exp, "$", "`", -1), rendered)
+}
+
+// test that the cost estimate from the infracost step is added after the plan
+func TestRenderProjectResults_CostSummary(t *testing.T) {
+	mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
+	cr := command.Result{
+		ProjectResults: []command.ProjectResult{
+			{
+				RepoRelDir: ".",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "tf out",
+					LockURL:         "lock-url",
+					RePlanCmd:       "re-plan cmd",
+					ApplyCmd:        "apply cmd",
+					CostSummary:     "**Cost estimate:** monthly cost will increase by 10.00 USD (10.00 USD → 20.00 USD).",
+				},
+			},
+		},
+	}
+	rendered := mr.Render(cr, command.Plan, "", "log", false, models.Github)
+	exp := `Ran Plan for dir: $.$ workspace: $default$
+
+$$$diff
+tf out
+$$$
+
+:moneybag: **Cost estimate:** monthly cost will increase by 10.00 USD (10.00 USD → 20.00 USD).
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $apply cmd$
+* :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $re-plan cmd$
+
+---
+* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+* :put_litter_in_its_place: To delete all plans and locks for the PR, comment:
+    * $atlantis unlock$`
+	Equals(t, strings.Replace(exp, "$", "`", -1), rendered)
+}
+
+// test that the plan JSON is linked after the plan
+func TestRenderProjectResults_PlanJSONURL(t *testing.T) {
+	mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
+	cr := command.Result{
+		ProjectResults: []command.ProjectResult{
+			{
+				RepoRelDir: ".",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "tf out",
+					LockURL:         "lock-url",
+					RePlanCmd:       "re-plan cmd",
+					ApplyCmd:        "apply cmd",
+					PlanJSONURL:     "https://atlantis.example.com/plan-json/abc",
+				},
+			},
+		},
+	}
+	rendered := mr.Render(cr, command.Plan, "", "log", false, models.Github)
+	exp := `Ran Plan for dir: $.$ workspace: $default$
+
+$$$diff
+tf out
+$$$
+
+:page_facing_up: The plan is also available [as JSON](https://atlantis.example.com/plan-json/abc).
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $apply cmd$
+* :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $re-plan cmd$
+
+---
+* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+* :put_litter_in_its_place: To delete all plans and locks for the PR, comment:
+    * $atlantis unlock$`
+	Equals(t, strings.Replace(exp, "$", "`", -1), rendered)
+}
+
+func TestRenderProjectResults_PlanComparison(t *testing.T) {
+	mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
+	cr := command.Result{
+		ProjectResults: []command.ProjectResult{
+			{
+				Command:    command.Plan,
+				RepoRelDir: "dir1",
+				Workspace:  "default",
+				PlanComparison: &models.PlanComparison{
+					ComparePull: 12,
+					Diffs: []models.ResourceChangeDiff{
+						{Address: "null_resource.a", Action: "create"},
+						{Address: "null_resource.b", CompareAction: "delete"},
+					},
+				},
+			},
+			{
+				Command:        command.Plan,
+				RepoRelDir:     "dir2",
+				Workspace:      "default",
+				PlanComparison: &models.PlanComparison{ComparePull: 12},
+			},
+			{
+				Command:    command.Plan,
+				RepoRelDir: "dir3",
+				Workspace:  "default",
+				Error:      errors.New(`no plan found for dir "dir3" workspace "default" in pull request #12, it must be planned first`),
+			},
+		},
+		ComparePull: 12,
+	}
+	rendered := mr.Render(cr, command.Plan, "", "log", false, models.Github)
+	exp := `:mag: Compared the plans of this pull request with the plans of #12. Resources that aren't listed are changed the same way by both.
+
+Ran Plan for 3 projects:
+
+1. dir: $dir1$ workspace: $default$
+1. dir: $dir2$ workspace: $default$
+1. dir: $dir3$ workspace: $default$
+
+### 1. dir: $dir1$ workspace: $default$
+$$$diff
++ null_resource.a: create only in this pull request
+- null_resource.b: delete only in #12
+$$$
+
+### 2. dir: $dir2$ workspace: $default$
+The plans make the same changes.
+
+### 3. dir: $dir3$ workspace: $default$
+**Plan Error**
+$$$
+no plan found for dir "dir3" workspace "default" in pull request #12, it must be planned first
+$$$`
+	Equals(t, strings.Replace(exp, "$", "`", -1), rendered)
+}
+
 // test that id repo locking is disabled the link to unlock the project is not rendered
 func TestRenderProjectResultsWithRepoLockingDisabled(t *testing.T) {
 	cases := []struct {
@@ -2355,17 +2690,10 @@ $$$
 		},
 	}
 
-	r := events.NewMarkdownRenderer(
-		false,      // gitlabSupportsCommonMark
-		false,      // disableApplyAll
-		false,      // disableApply
-		false,      // disableMarkdownFolding
-		true,       // disableRepoLocking
-		false,      // enableDiffMarkdownFormat
-		"",         // MarkdownTemplateOverridesDir
-		"atlantis", // executableName
-		false,      // hideUnchangedPlanComments
-	)
+	r := events.NewMarkdownRenderer(events.MarkdownRendererOptions{
+		DisableRepoLocking: true,
+		ExecutableName:     "atlantis",
+	})
 	for _, c := range cases {
 		t.Run(c.Description, func(t *testing.T) {
 			res := command.Result{
@@ -2792,17 +3120,12 @@ Plan: 1 to add, 2 to change, 1 to destroy.
 }
 
 func TestRenderProjectResultsWithEnableDiffMarkdownFormat(t *testing.T) {
-	r := events.NewMarkdownRenderer(
-		false,      // gitlabSupportsCommonMark
-		true,       // disableApplyAll
-		true,       // disableApply
-		false,      // disableMarkdownFolding
-		false,      // disableRepoLocking
-		true,       // enableDiffMarkdownFormat
-		"",         // MarkdownTemplateOverridesDir
-		"atlantis", // executableName
-		false,      // hideUnchangedPlanComments
-	)
+	r := events.NewMarkdownRenderer(events.MarkdownRendererOptions{
+		DisableApplyAll:          true,
+		DisableApply:             true,
+		EnableDiffMarkdownFormat: true,
+		ExecutableName:           "atlantis",
+	})
 
 	for _, c := range cases {
 		t.Run(c.Description, func(t *testing.T) {
@@ -2829,17 +3152,12 @@ var Render string
 func BenchmarkRenderProjectResultsWithEnableDiffMarkdownFormat(b *testing.B) {
 	var render string
 
-	r := events.NewMarkdownRenderer(
-		false,      // gitlabSupportsCommonMark
-		true,       // disableApplyAll
-		true,       // disableApply
-		false,      // disableMarkdownFolding
-		false,      // disableRepoLocking
-		true,       // enableDiffMarkdownFormat
-		"",         // MarkdownTemplateOverridesDir
-		"atlantis", // executableName
-		false,      // hideUnchangedPlanComments
-	)
+	r := events.NewMarkdownRenderer(events.MarkdownRendererOptions{
+		DisableApplyAll:          true,
+		DisableApply:             true,
+		EnableDiffMarkdownFormat: true,
+		ExecutableName:           "atlantis",
+	})
 
 	for _, c := range cases {
 		b.Run(c.Description, func(b *testing.B) {
@@ -2996,7 +3314,7 @@ $$$
 		},
 	}
 
-	r := events.NewMarkdownRenderer(false, false, false, false, false, false, "", "atlantis", true)
+	r := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis", HideUnchangedPlanComments: true})
 	for _, c := range cases {
 		t.Run(c.Description, func(t *testing.T) {
 			res := command.Result{
@@ -3016,3 +3334,245 @@ $$$
 		})
 	}
 }
+
+func TestRenderWorkflowHookFailure(t *testing.T) {
+	r := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
+	Equals(t, "**Post workflow hook Failed**: notify\n\ncurl exited with 7\n\n[View the hook's output](https://atlantis/jobs/1)\n\nFix what made the hook fail, then comment `atlantis apply` to run it again.",
+		r.RenderWorkflowHookFailure("Post workflow hook", "notify", "curl exited with 7", "https://atlantis/jobs/1", "apply"))
+	Equals(t, "**Pre workflow hook Failed**: check tags\n\nFix what made the hook fail, then comment `atlantis plan` to run it again.",
+		r.RenderWorkflowHookFailure("Pre workflow hook", "check tags", "", "", "plan"))
+
+	// The comment can be customized with a template override.
+	tmpDir := t.TempDir()
+	Ok(t, os.WriteFile(filepath.Join(tmpDir, "hooks.tmpl"), []byte("{{ define \"workflowHookFailure\" -}}{{ .HookDescription }} failed, ask #infra for help.{{- end}}\n"), 0600))
+	r = events.NewMarkdownRenderer(events.MarkdownRendererOptions{MarkdownTemplateOverridesDir: tmpDir, ExecutableName: "atlantis"})
+	Equals(t, "check tags failed, ask #infra for help.", r.RenderWorkflowHookFailure("Pre workflow hook", "check tags", "", "", "plan"))
+}
+
+func TestRenderUnauthorized(t *testing.T) {
+	r := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
+	Equals(t, "**Error:** User @alice is not allowed to run `atlantis apply`.", r.RenderUnauthorized("alice", "apply", false))
+	Equals(t, "```\nError: User @alice does not have permissions to execute 'plan' command.\n```", r.RenderUnauthorized("alice", "plan", true))
+
+	r = events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis", UnauthorizedContact: "@org/platform-team"})
+	Equals(t, "**Error:** User @alice is not allowed to run `atlantis plan`.\n\nTo get access, contact @org/platform-team.",
+		r.RenderUnauthorized("alice", "plan", false))
+	Equals(t, "```\nError: User @alice does not have permissions to execute 'plan' command.\n```\n\nTo get access, contact @org/platform-team.",
+		r.RenderUnauthorized("alice", "plan", true))
+
+	// The comment can be customized with a template override.
+	tmpDir := t.TempDir()
+	Ok(t, os.WriteFile(filepath.Join(tmpDir, "unauthorized.tmpl"), []byte("{{ define \"unauthorized\" -}}Sorry @{{ .User }}, only {{ .Contact }} can run `{{ .Command }}`.{{- end}}\n"), 0600))
+	r = events.NewMarkdownRenderer(events.MarkdownRendererOptions{MarkdownTemplateOverridesDir: tmpDir, ExecutableName: "atlantis", UnauthorizedContact: "@org/platform-team"})
+	Equals(t, "Sorry @alice, only @org/platform-team can run `apply`.", r.RenderUnauthorized("alice", "apply", false))
+	Equals(t, "Sorry @alice, only @org/platform-team can run `plan`.", r.RenderUnauthorized("alice", "plan", true))
+}
+
+func TestRenderProjectResults_PlanCommentFooter(t *testing.T) {
+	planResult := func(dir string, tfVersion string) command.ProjectResult {
+		return command.ProjectResult{
+			RepoRelDir: dir,
+			Workspace:  "default",
+			PlanSuccess: &models.PlanSuccess{
+				TerraformOutput:  "No changes. Your infrastructure matches the configuration.",
+				LockURL:          "lock-url",
+				TerraformVersion: tfVersion,
+			},
+		}
+	}
+	cases := []struct {
+		description string
+		res         command.Result
+		expFooter   string
+	}{
+		{
+			description: "default terraform version",
+			res: command.Result{
+				ProjectResults: []command.ProjectResult{planResult("app", "")},
+				Duration:       62*time.Second + 300*time.Millisecond,
+			},
+			expFooter: "\n\n---\n<sub>Atlantis 0.27.0 · Terraform 1.5.7 · Planned in 1m2s</sub>",
+		},
+		{
+			description: "projects' terraform versions",
+			res: command.Result{
+				ProjectResults: []command.ProjectResult{planResult("app", "1.6.0"), planResult("network", "")},
+				Duration:       5 * time.Second,
+			},
+			expFooter: "\n\n---\n<sub>Atlantis 0.27.0 · Terraform 1.6.0, 1.5.7 · Planned in 5s</sub>",
+		},
+		{
+			description: "error",
+			res:         command.Result{Error: errors.New("error")},
+			expFooter:   "\n\n---\n<sub>Atlantis 0.27.0</sub>",
+		},
+	}
+	mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis", PlanCommentFooter: true, AtlantisVersion: "0.27.0", DefaultTFVersion: "1.5.7"})
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			rendered := mr.Render(c.res, command.Plan, "", "log", false, models.Github)
+			Assert(t, strings.HasSuffix(rendered, c.expFooter), "exp %q to end with footer %q", rendered, c.expFooter)
+		})
+	}
+
+	// Only plan comments have a footer.
+	rendered := mr.Render(command.Result{ProjectResults: []command.ProjectResult{{RepoRelDir: "app", Workspace: "default", ApplySuccess: "applied"}}}, command.Apply, "", "log", false, models.Github)
+	Assert(t, !strings.Contains(rendered, "<sub>"), "exp no footer in %q", rendered)
+
+	// The footer is off by default.
+	mr = events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis", AtlantisVersion: "0.27.0", DefaultTFVersion: "1.5.7"})
+	rendered = mr.Render(cases[0].res, command.Plan, "", "log", false, models.Github)
+	Assert(t, !strings.Contains(rendered, "<sub>"), "exp no footer in %q", rendered)
+}
+
+func TestRenderApplySummary(t *testing.T) {
+	res := command.Result{
+		ProjectResults: []command.ProjectResult{
+			{
+				RepoRelDir:   "app",
+				Workspace:    "default",
+				ApplySuccess: "Apply complete! Resources: 3 imported, 0 added, 0 changed, 0 destroyed.",
+			},
+			{
+				RepoRelDir: "network",
+				Workspace:  "staging",
+				Failure:    "Pull request must be approved before running apply.",
+			},
+		},
+	}
+	mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
+
+	exp := "Ran Apply for 2 projects: 1 succeeded, 1 failed.\n\n" +
+		"| Project | Result | Changes |\n|---------|--------|---------|\n" +
+		"| dir: `app` workspace: `default` | :heavy_check_mark: Applied | 3 imported, 0 added, 0 changed, 0 destroyed. |\n" +
+		"| dir: `network` workspace: `staging` | :x: Failed |  |"
+	Equals(t, exp, mr.RenderApplySummary(res, "log", false))
+
+	rendered := mr.RenderApplySummary(res, "log", true)
+	Assert(t, strings.HasPrefix(rendered, exp), "exp %q to start with %q", rendered, exp)
+	Assert(t, strings.Contains(rendered, "<details><summary>Log</summary>"), "exp log in %q", rendered)
+}
+
+func TestRenderProjectResults_MergedPolicyCheck(t *testing.T) {
+	mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
+	cr := command.Result{
+		ProjectResults: []command.ProjectResult{
+			{
+				RepoRelDir: ".",
+				Workspace:  "default",
+				PlanSuccess: &models.PlanSuccess{
+					TerraformOutput: "tf out",
+					LockURL:         "lock-url",
+					RePlanCmd:       "re-plan cmd",
+					ApplyCmd:        "apply cmd",
+				},
+				MergedPolicyCheck: &command.ProjectResult{
+					Command:    command.PolicyCheck,
+					RepoRelDir: ".",
+					Workspace:  "default",
+					Failure:    "Some policy sets did not pass.",
+					PolicyCheckResults: &models.PolicyCheckResults{
+						PolicySetResults: []models.PolicySetResult{
+							{PolicySetName: "policy1", PolicyOutput: "4 tests, 2 passed, 0 warnings, 2 failures, 0 exceptions", ReqApprovals: 1},
+						},
+						ApprovePoliciesCmd: "approve cmd",
+					},
+				},
+			},
+		},
+	}
+	rendered := mr.Render(cr, command.Plan, "", "log", false, models.Github)
+	exp := `Ran Plan for dir: $.$ workspace: $default$
+
+$$$diff
+tf out
+$$$
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $apply cmd$
+* :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $re-plan cmd$
+
+#### Policy Check:
+**Policy Check Failed**: Some policy sets did not pass.
+#### Policy Set: $policy1$
+$$$diff
+4 tests, 2 passed, 0 warnings, 2 failures, 0 exceptions
+$$$
+
+
+#### Policy Approval Status:
+$$$
+policy set: policy1: requires: 1 approval(s), have: 0.
+$$$
+* :heavy_check_mark: To **approve** this project, comment:
+    * $approve cmd$
+
+---
+* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+* :put_litter_in_its_place: To delete all plans and locks for the PR, comment:
+    * $atlantis unlock$`
+	Equals(t, strings.Replace(exp, "$", "`", -1), rendered)
+}
+
+func TestRenderProjectResults_ReplanSuccess(t *testing.T) {
+	mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
+	cr := command.Result{
+		ProjectResults: []command.ProjectResult{
+			{
+				Command:      command.Apply,
+				RepoRelDir:   ".",
+				Workspace:    "default",
+				ApplySuccess: "Apply complete!",
+				ReplanSuccess: &models.PlanSuccess{
+					TerraformOutput: "tf out",
+				},
+			},
+		},
+	}
+	rendered := mr.Render(cr, command.Apply, "", "log", false, models.Github)
+	exp := `Ran Apply for dir: $.$ workspace: $default$
+
+$$$diff
+Apply complete!
+$$$
+
+#### Stale Plan:
+The plan was stale, so this project was planned again and the new plan was applied.
+<details><summary>Show Plan</summary>
+
+$$$diff
+tf out
+$$$
+</details>`
+	Equals(t, strings.Replace(exp, "$", "`", -1), rendered)
+}
+
+func TestRenderProjectResults_OnFailureStepsFailed(t *testing.T) {
+	mr := events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
+	cr := command.Result{
+		ProjectResults: []command.ProjectResult{
+			{
+				Command:              command.Apply,
+				RepoRelDir:           ".",
+				Workspace:            "default",
+				Error:                errors.New("health check failed\n\nthe on_failure steps failed too: rollback failed"),
+				OnFailureStepsFailed: true,
+			},
+		},
+	}
+	rendered := mr.Render(cr, command.Apply, "", "log", false, models.Github)
+	exp := `Ran Apply for dir: $.$ workspace: $default$
+
+:rotating_light: **The on_failure steps failed too, the project may be left in a broken state and needs manual attention.**
+
+**Apply Error**
+$$$
+health check failed
+
+the on_failure steps failed too: rollback failed
+$$$`
+	Equals(t, strings.Replace(exp, "$", "`", -1), rendered)
+}
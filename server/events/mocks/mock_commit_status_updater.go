@@ -41,6 +41,36 @@ func (mock *MockCommitStatusUpdater) UpdateCombined(repo models.Repo, pull model
 	return ret0
 }
 
+func (mock *MockCommitStatusUpdater) UpdateDestroyCheck(repo models.Repo, pull models.PullRequest, status models.CommitStatus, numDestroying int) error {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockCommitStatusUpdater().")
+	}
+	params := []pegomock.Param{repo, pull, status, numDestroying}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("UpdateDestroyCheck", params, []reflect.Type{reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(error)
+		}
+	}
+	return ret0
+}
+
+func (mock *MockCommitStatusUpdater) UpdateFmtCheck(repo models.Repo, pull models.PullRequest, status models.CommitStatus, numUnformatted int) error {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockCommitStatusUpdater().")
+	}
+	params := []pegomock.Param{repo, pull, status, numUnformatted}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("UpdateFmtCheck", params, []reflect.Type{reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(error)
+		}
+	}
+	return ret0
+}
+
 func (mock *MockCommitStatusUpdater) UpdateCombinedCount(repo models.Repo, pull models.PullRequest, status models.CommitStatus, cmdName command.Name, numSuccess int, numTotal int) error {
 	if mock == nil {
 		panic("mock must not be nil. Use myMock := NewMockCommitStatusUpdater().")
@@ -162,6 +192,84 @@ func (c *MockCommitStatusUpdater_UpdateCombined_OngoingVerification) GetAllCaptu
 	return
 }
 
+func (verifier *VerifierMockCommitStatusUpdater) UpdateDestroyCheck(repo models.Repo, pull models.PullRequest, status models.CommitStatus, numDestroying int) *MockCommitStatusUpdater_UpdateDestroyCheck_OngoingVerification {
+	params := []pegomock.Param{repo, pull, status, numDestroying}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "UpdateDestroyCheck", params, verifier.timeout)
+	return &MockCommitStatusUpdater_UpdateDestroyCheck_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type MockCommitStatusUpdater_UpdateDestroyCheck_OngoingVerification struct {
+	mock              *MockCommitStatusUpdater
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *MockCommitStatusUpdater_UpdateDestroyCheck_OngoingVerification) GetCapturedArguments() (models.Repo, models.PullRequest, models.CommitStatus, int) {
+	repo, pull, status, numDestroying := c.GetAllCapturedArguments()
+	return repo[len(repo)-1], pull[len(pull)-1], status[len(status)-1], numDestroying[len(numDestroying)-1]
+}
+
+func (c *MockCommitStatusUpdater_UpdateDestroyCheck_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Repo, _param1 []models.PullRequest, _param2 []models.CommitStatus, _param3 []int) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]models.Repo, len(c.methodInvocations))
+		for u, param := range params[0] {
+			_param0[u] = param.(models.Repo)
+		}
+		_param1 = make([]models.PullRequest, len(c.methodInvocations))
+		for u, param := range params[1] {
+			_param1[u] = param.(models.PullRequest)
+		}
+		_param2 = make([]models.CommitStatus, len(c.methodInvocations))
+		for u, param := range params[2] {
+			_param2[u] = param.(models.CommitStatus)
+		}
+		_param3 = make([]int, len(c.methodInvocations))
+		for u, param := range params[3] {
+			_param3[u] = param.(int)
+		}
+	}
+	return
+}
+
+func (verifier *VerifierMockCommitStatusUpdater) UpdateFmtCheck(repo models.Repo, pull models.PullRequest, status models.CommitStatus, numUnformatted int) *MockCommitStatusUpdater_UpdateFmtCheck_OngoingVerification {
+	params := []pegomock.Param{repo, pull, status, numUnformatted}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "UpdateFmtCheck", params, verifier.timeout)
+	return &MockCommitStatusUpdater_UpdateFmtCheck_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type MockCommitStatusUpdater_UpdateFmtCheck_OngoingVerification struct {
+	mock              *MockCommitStatusUpdater
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *MockCommitStatusUpdater_UpdateFmtCheck_OngoingVerification) GetCapturedArguments() (models.Repo, models.PullRequest, models.CommitStatus, int) {
+	repo, pull, status, numUnformatted := c.GetAllCapturedArguments()
+	return repo[len(repo)-1], pull[len(pull)-1], status[len(status)-1], numUnformatted[len(numUnformatted)-1]
+}
+
+func (c *MockCommitStatusUpdater_UpdateFmtCheck_OngoingVerification) GetAllCapturedArguments() (_param0 []models.Repo, _param1 []models.PullRequest, _param2 []models.CommitStatus, _param3 []int) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]models.Repo, len(c.methodInvocations))
+		for u, param := range params[0] {
+			_param0[u] = param.(models.Repo)
+		}
+		_param1 = make([]models.PullRequest, len(c.methodInvocations))
+		for u, param := range params[1] {
+			_param1[u] = param.(models.PullRequest)
+		}
+		_param2 = make([]models.CommitStatus, len(c.methodInvocations))
+		for u, param := range params[2] {
+			_param2[u] = param.(models.CommitStatus)
+		}
+		_param3 = make([]int, len(c.methodInvocations))
+		for u, param := range params[3] {
+			_param3[u] = param.(int)
+		}
+	}
+	return
+}
+
 func (verifier *VerifierMockCommitStatusUpdater) UpdateCombinedCount(repo models.Repo, pull models.PullRequest, status models.CommitStatus, cmdName command.Name, numSuccess int, numTotal int) *MockCommitStatusUpdater_UpdateCombinedCount_OngoingVerification {
 	params := []pegomock.Param{repo, pull, status, cmdName, numSuccess, numTotal}
 	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "UpdateCombinedCount", params, verifier.timeout)
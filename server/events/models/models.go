@@ -68,6 +68,23 @@ func (r Repo) ID() string {
 	return fmt.Sprintf("%s/%s", r.VCSHost.Hostname, r.FullName)
 }
 
+// endsWithSegments returns true if the last segments of the URL path p are
+// the segments of suffix. Segments are compared whole so ex. "/group/repo.git"
+// doesn't end with "/oup/repo.git".
+func endsWithSegments(p string, suffix string) bool {
+	pSegments := strings.Split(strings.Trim(p, "/"), "/")
+	suffixSegments := strings.Split(strings.Trim(suffix, "/"), "/")
+	if len(suffixSegments) > len(pSegments) {
+		return false
+	}
+	for i, segment := range pSegments[len(pSegments)-len(suffixSegments):] {
+		if segment != suffixSegments[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // NewRepo constructs a Repo object. repoFullName is the owner/repo form,
 // cloneURL can be with or without .git at the end
 // ex. https://github.com/runatlantis/atlantis.git OR
@@ -96,9 +113,16 @@ func NewRepo(vcsHostType VCSHostType, repoFullName string, cloneURL string, vcsU
 	// and because the caller in that case actually constructs the clone url
 	// from the repo name and so there's no point checking if they match.
 	// Azure DevOps also does not require .git at the end of clone urls.
+	// GitLab can be served under a relative URL root, ex.
+	// https://example.com/gitlab/group/subgroup/repo.git, so the last
+	// segments of its clone urls' paths only need to be the repo's path.
 	if vcsHostType != BitbucketServer && vcsHostType != AzureDevops {
 		expClonePath := fmt.Sprintf("/%s.git", repoFullName)
-		if expClonePath != cloneURLParsed.Path {
+		matches := expClonePath == cloneURLParsed.Path
+		if vcsHostType == Gitlab {
+			matches = endsWithSegments(cloneURLParsed.Path, expClonePath)
+		}
+		if !matches {
 			return Repo{}, fmt.Errorf("expected clone url to have path %q but had %q", expClonePath, cloneURLParsed.Path)
 		}
 	}
@@ -149,6 +173,9 @@ type ApprovalStatus struct {
 	IsApproved bool
 	ApprovedBy string
 	Date       time.Time
+	// ApprovalRules are whether each of the pull request's approval rules is
+	// approved, by the rule's name. Only GitLab has approval rules.
+	ApprovalRules map[string]bool
 }
 
 // PullRequest is a VCS pull request.
@@ -172,12 +199,20 @@ type PullRequest struct {
 	BaseBranch string
 	// Author is the username of the pull request author.
 	Author string
+	// Body is the description of the pull request. It's only set for GitHub,
+	// GitLab and Azure DevOps pull requests.
+	Body string
 	// State will be one of Open or Closed.
 	// Gitlab supports an additional "merged" state but Github doesn't so we map
 	// merged to Closed.
 	State PullRequestState
 	// BaseRepo is the repository that the pull request will be merged into.
 	BaseRepo Repo
+	// Historical is true if HeadCommit was set to a previous commit of the
+	// pull request, ex. by `atlantis plan --sha`, instead of the head of the
+	// branch. Historical pulls get their own working dirs and can't be
+	// applied.
+	Historical bool
 }
 
 // PullRequestOptions is used to set optional paralmeters for PullRequest
@@ -269,6 +304,11 @@ type Plan struct {
 	LocalPath string
 }
 
+// GenerateLockKey creates a consistent lock key from a project and workspace.
+func GenerateLockKey(project Project, workspace string) string {
+	return fmt.Sprintf("%s/%s/%s", project.RepoFullName, project.Path, workspace)
+}
+
 // NewProject constructs a Project. Use this constructor because it
 // sets Path correctly.
 func NewProject(repoFullName string, path string) Project {
@@ -365,6 +405,20 @@ type PlanSuccess struct {
 	// branch we're merging into had been updated, and we had to merge again
 	// before planning
 	MergedAgain bool
+	// GistURL is the URL of a gist with the full TerraformOutput if it was
+	// too large to comment.
+	GistURL string
+	// CostSummary is the cost estimate from the infracost step, if the
+	// workflow ran one.
+	CostSummary string
+	// PlannedAt is when the plan was created.
+	PlannedAt time.Time
+	// TerraformVersion is the version of Terraform the project was planned
+	// with. It's empty if the project uses the default version.
+	TerraformVersion string
+	// PlanJSONURL is the URL of the plan's JSON, from terraform show -json,
+	// for tools to download. It's empty if it isn't stored.
+	PlanJSONURL string
 }
 
 type PolicySetResult struct {
@@ -387,8 +441,19 @@ var (
 	reChangesOutside = regexp.MustCompile(`Note: Objects have changed outside of Terraform`)
 	rePlanChanges    = regexp.MustCompile(`Plan: (?:(\d+) to import, )?(\d+) to add, (\d+) to change, (\d+) to destroy.`)
 	reNoChanges      = regexp.MustCompile(`No changes. (Infrastructure is up-to-date|Your infrastructure matches the configuration).`)
+	reApplyChanges   = regexp.MustCompile(`Resources: ((?:\d+ imported, )?\d+ added, \d+ changed, \d+ destroyed\.)`)
 )
 
+// ApplyDiffSummary extracts the one line summary of the changes an apply made
+// from its output, ex. "1 added, 0 changed, 0 destroyed.". It's empty if the
+// output has no summary.
+func ApplyDiffSummary(output string) string {
+	if match := reApplyChanges.FindStringSubmatch(output); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
 // Summary extracts summaries of plan changes from TerraformOutput.
 func (p *PlanSuccess) Summary() string {
 	note := ""
@@ -468,6 +533,70 @@ type StateRmSuccess struct {
 	RePlanCmd string
 }
 
+// PlanComparison is the difference between the plans of a project in two
+// pull requests.
+type PlanComparison struct {
+	// ComparePull is the number of the pull request whose plan this pull
+	// request's plan was compared with.
+	ComparePull int
+	// Diffs are the resources whose planned changes differ, sorted by
+	// address.
+	Diffs []ResourceChangeDiff
+}
+
+// ResourceChangeDiff is a resource whose planned change differs between two
+// plans.
+type ResourceChangeDiff struct {
+	Address string
+	// Action is the planned action in this pull request's plan, ex. create
+	// or replace. It's empty if the plan doesn't change the resource.
+	Action string
+	// CompareAction is the planned action in the other pull request's plan.
+	CompareAction string
+}
+
+// Output returns the diffs in the diff format, one resource per line.
+// Resources only changed by this pull request are prefixed with + and
+// resources only changed by the other pull request are prefixed with -.
+func (p PlanComparison) Output() string {
+	var lines []string
+	for _, d := range p.Diffs {
+		switch {
+		case d.CompareAction == "":
+			lines = append(lines, fmt.Sprintf("+ %s: %s only in this pull request", d.Address, d.Action))
+		case d.Action == "":
+			lines = append(lines, fmt.Sprintf("- %s: %s only in #%d", d.Address, d.CompareAction, p.ComparePull))
+		case d.Action == d.CompareAction:
+			lines = append(lines, fmt.Sprintf("! %s: %s in both with different values", d.Address, d.Action))
+		default:
+			lines = append(lines, fmt.Sprintf("! %s: %s in this pull request, %s in #%d", d.Address, d.Action, d.CompareAction, p.ComparePull))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FmtCheckResult is the result of checking that a project's Terraform files
+// are formatted with terraform fmt.
+type FmtCheckResult struct {
+	// UnformattedFiles are the files terraform fmt would change, relative to
+	// the project's directory.
+	UnformattedFiles []string
+}
+
+// Failure returns the failure message for a plan that didn't run because of
+// unformatted files, or an empty string if all the files are formatted.
+func (f FmtCheckResult) Failure() string {
+	if len(f.UnformattedFiles) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("Terraform files aren't formatted, run `terraform fmt` and push the changes or plan with `--skip-fmt-check`:\n")
+	for _, file := range f.UnformattedFiles {
+		fmt.Fprintf(&sb, "\n* `%s`", file)
+	}
+	return sb.String()
+}
+
 func (p *PolicyCheckResults) CombinedOutput() string {
 	combinedOutput := ""
 	for _, psResult := range p.PolicySetResults {
@@ -549,6 +678,14 @@ type ProjectStatus struct {
 	PolicyStatus []PolicySetStatus
 	// Status is the status of where this project is at in the planning cycle.
 	Status ProjectPlanStatus
+	// PlanDestroys is the number of resources the latest plan destroys.
+	PlanDestroys int
+	// NoDestroy is true if the project had the no_destroy apply requirement
+	// when it was last planned.
+	NoDestroy bool
+	// PlannedAt is when the latest successful plan was created. It's zero if
+	// the project hasn't been planned successfully.
+	PlannedAt time.Time
 }
 
 // ProjectPlanStatus is the status of where this project is at in the planning
@@ -580,6 +717,9 @@ const (
 	// PassedPolicyCheckStatus means that there was an unapplied plan that was
 	// discarded due to a project being unlocked
 	PassedPolicyCheckStatus
+	// CanceledPlanStatus means that a plan or apply was canceled by a user
+	// while it was running.
+	CanceledPlanStatus
 )
 
 // String returns a string representation of the status.
@@ -601,6 +741,8 @@ func (p ProjectPlanStatus) String() string {
 		return "policy_check_errored"
 	case PassedPolicyCheckStatus:
 		return "policy_check_passed"
+	case CanceledPlanStatus:
+		return "canceled"
 	default:
 		panic("missing String() impl for ProjectPlanStatus")
 	}
@@ -630,8 +772,38 @@ type WorkflowHookCommandContext struct {
 	EscapedCommentArgs []string
 	// UUID for reference
 	HookID string
+	// Image is the container image the hook runs in. If it's empty the hook
+	// runs on the host.
+	Image string
 	// The name of the command that is being executed, i.e. 'plan', 'apply' etc.
 	CommandName string
+	// PlannedProjects are the names, or dirs for projects without names, of
+	// the projects that the pull request modifies. It's only set for pre
+	// workflow hooks.
+	PlannedProjects []string
+	// ProjectName, Workspace and RepoRelDir are only set for project-scoped
+	// hooks, which run once for each project the command ran for.
+	ProjectName string
+	Workspace   string
+	RepoRelDir  string
+	// HeadCommit is the pull request's head commit, read from the clone. It's
+	// empty if it couldn't be read.
+	HeadCommit CommitMetadata
+	// CleanEnv is true if the hook runs with only the env vars Atlantis sets
+	// and AllowedEnvVars instead of the server's whole environment.
+	CleanEnv bool
+	// AllowedEnvVars are the names of the server's env vars that are passed
+	// to the hook when CleanEnv is true.
+	AllowedEnvVars []string
+}
+
+// CommitMetadata describes a git commit.
+type CommitMetadata struct {
+	SHA         string
+	AuthorName  string
+	AuthorEmail string
+	// Message is the full commit message, subject and body.
+	Message string
 }
 
 // PlanSuccessStats holds stats for a plan.
@@ -42,6 +42,34 @@ func TestNewRepo_CloneURLWrongRepo(t *testing.T) {
 	ErrEquals(t, `expected clone url to have path "/owner/repo.git" but had "/notowner/repo.git"`, err)
 }
 
+func TestNewRepo_CloneURLGitlabRelativeURLRoot(t *testing.T) {
+	repo, err := models.NewRepo(models.Gitlab, "group/subgroup/subsubgroup/repo", "https://mycorp.com/gitlab/group/subgroup/subsubgroup/repo.git", "u", "p")
+	Ok(t, err)
+	Equals(t, models.Repo{
+		FullName:          "group/subgroup/subsubgroup/repo",
+		Owner:             "group/subgroup/subsubgroup",
+		Name:              "repo",
+		CloneURL:          "https://u:p@mycorp.com/gitlab/group/subgroup/subsubgroup/repo.git",
+		SanitizedCloneURL: "https://u:<redacted>@mycorp.com/gitlab/group/subgroup/subsubgroup/repo.git",
+		VCSHost: models.VCSHost{
+			Hostname: "mycorp.com",
+			Type:     models.Gitlab,
+		},
+	}, repo)
+	Equals(t, "mycorp.com/group/subgroup/subsubgroup/repo", repo.ID())
+
+	_, err = models.NewRepo(models.Gitlab, "subgroup/repo", "https://mycorp.com/group/othersubgroup/repo.git", "u", "p")
+	ErrEquals(t, `expected clone url to have path "/subgroup/repo.git" but had "/group/othersubgroup/repo.git"`, err)
+
+	// Segments are compared whole.
+	_, err = models.NewRepo(models.Gitlab, "group/repo", "https://mycorp.com/gitlab/mygroup/repo.git", "u", "p")
+	ErrEquals(t, `expected clone url to have path "/group/repo.git" but had "/gitlab/mygroup/repo.git"`, err)
+	_, err = models.NewRepo(models.Gitlab, "group/repo", "https://mycorp.com/gitlab/group/myrepo.git", "u", "p")
+	ErrEquals(t, `expected clone url to have path "/group/repo.git" but had "/gitlab/group/myrepo.git"`, err)
+	_, err = models.NewRepo(models.Gitlab, "group/repo", "https://mycorp.com/repo.git", "u", "p")
+	ErrEquals(t, `expected clone url to have path "/group/repo.git" but had "/repo.git"`, err)
+}
+
 func TestNewRepo_EmptyAzureDevopsProject(t *testing.T) {
 	_, err := models.NewRepo(models.AzureDevops, "", "https://dev.azure.com/notowner/project/_git/repo", "u", "p")
 	ErrEquals(t, "repoFullName can't be empty", err)
@@ -423,6 +451,35 @@ func TestPlanSuccess_DiffSummary(t *testing.T) {
 	}
 }
 
+func TestApplyDiffSummary(t *testing.T) {
+	cases := []struct {
+		input string
+		exp   string
+	}{
+		{
+			"dummy\nApply complete! Resources: 1 added, 2 changed, 3 destroyed.",
+			"1 added, 2 changed, 3 destroyed.",
+		},
+		{
+			"dummy\nApply complete! Resources: 4 imported, 0 added, 0 changed, 0 destroyed.",
+			"4 imported, 0 added, 0 changed, 0 destroyed.",
+		},
+		{
+			"dummy\nDestroy complete! Resources: 5 destroyed.",
+			"",
+		},
+		{
+			"",
+			"",
+		},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("summary %d", i), func(t *testing.T) {
+			Equals(t, c.exp, models.ApplyDiffSummary(c.input))
+		})
+	}
+}
+
 func TestPolicyCheckResults_Summary(t *testing.T) {
 	cases := []struct {
 		description      string
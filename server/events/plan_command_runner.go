@@ -1,12 +1,24 @@
 package events
 
 import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/config/raw"
 	"github.com/runatlantis/atlantis/server/core/locking"
 	"github.com/runatlantis/atlantis/server/events/command"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/utils"
 )
 
+// noProjectsAutoplanComment is commented by autoplan when it finds no projects
+// to plan and autoplanCommentNoProjects is enabled.
+const noProjectsAutoplanComment = "No Terraform projects were affected by this pull request so Atlantis didn't run plan."
+
 func NewPlanCommandRunner(
 	silenceVCSStatusNoPlans bool,
 	silenceVCSStatusNoProjects bool,
@@ -26,6 +38,7 @@ func NewPlanCommandRunner(
 	lockingLocker locking.Locker,
 	discardApprovalOnPlan bool,
 	pullReqStatusFetcher vcs.PullReqStatusFetcher,
+	autoplanCommentNoProjects bool,
 ) *PlanCommandRunner {
 	return &PlanCommandRunner{
 		silenceVCSStatusNoPlans:    silenceVCSStatusNoPlans,
@@ -46,6 +59,7 @@ func NewPlanCommandRunner(
 		lockingLocker:              lockingLocker,
 		DiscardApprovalOnPlan:      discardApprovalOnPlan,
 		pullReqStatusFetcher:       pullReqStatusFetcher,
+		autoplanCommentNoProjects:  autoplanCommentNoProjects,
 	}
 }
 
@@ -76,6 +90,24 @@ type PlanCommandRunner struct {
 	// a plan.
 	DiscardApprovalOnPlan bool
 	pullReqStatusFetcher  vcs.PullReqStatusFetcher
+	// autoplanCommentNoProjects is whether autoplan should comment on PRs if
+	// no projects are found
+	autoplanCommentNoProjects bool
+	// PlanComparer compares plans for `atlantis plan --compare`. If nil,
+	// comparing plans isn't supported.
+	PlanComparer *PlanComparer
+	// AutoplanLabelPrefix, if set, limits autoplan to the projects named by the
+	// pull request's labels that start with it, ex. "atlantis/plan:networking"
+	// for the project named networking or, if it's unnamed, in the networking
+	// dir. The other projects are left to be planned with a plan comment. If
+	// the pull request has no such labels all projects are autoplanned.
+	AutoplanLabelPrefix string
+	// PlanRateLimiter limits how often plans run for each repo. Plans over the
+	// limit are rejected. If nil, plans aren't limited.
+	PlanRateLimiter *PlanRateLimiter
+	// PolicyCheckCommentOrder is where the policy check results are commented
+	// relative to the plan comment. If empty, they're commented after it.
+	PolicyCheckCommentOrder PolicyCheckCommentOrder
 }
 
 func (p *PlanCommandRunner) runAutoplan(ctx *command.Context) {
@@ -91,6 +123,17 @@ func (p *PlanCommandRunner) runAutoplan(ctx *command.Context) {
 		return
 	}
 
+	projectCmds, unselectedCmds := p.selectAutoplanProjectsByLabel(ctx, projectCmds)
+	if len(projectCmds) == 0 && len(unselectedCmds) > 0 {
+		ctx.Log.Info("none of the %d modified projects are selected by labels with prefix %q, not autoplanning", len(unselectedCmds), p.AutoplanLabelPrefix)
+		// The projects still have to be planned with a comment so the plan
+		// status mustn't pass.
+		if err := p.commitStatusUpdater.UpdateCombinedCount(baseRepo, pull, models.PendingCommitStatus, command.Plan, 0, len(unselectedCmds)); err != nil {
+			ctx.Log.Warn("unable to update commit status: %s", err)
+		}
+		return
+	}
+
 	projectCmds, policyCheckCmds := p.partitionProjectCmds(ctx, projectCmds)
 
 	if len(projectCmds) == 0 {
@@ -110,6 +153,11 @@ func (p *PlanCommandRunner) runAutoplan(ctx *command.Context) {
 				ctx.Log.Warn("unable to update commit status: %s", err)
 			}
 		}
+		if p.autoplanCommentNoProjects {
+			if err := p.vcsClient.CreateComment(baseRepo, pull.Num, noProjectsAutoplanComment, command.Plan.String()); err != nil {
+				ctx.Log.Err("unable to comment: %s", err)
+			}
+		}
 		return
 	}
 
@@ -128,12 +176,16 @@ func (p *PlanCommandRunner) runAutoplan(ctx *command.Context) {
 
 	// Only run commands in parallel if enabled
 	var result command.Result
+	start := time.Now()
 	if p.isParallelEnabled(projectCmds) {
 		ctx.Log.Info("Running plans in parallel")
 		result = runProjectCmdsParallelGroups(ctx, projectCmds, p.prjCmdRunner.Plan, p.parallelPoolSize)
 	} else {
 		result = runProjectCmds(projectCmds, p.prjCmdRunner.Plan)
 	}
+	result.Duration = time.Since(start)
+	result.PlanOnly = p.isPlanOnly(projectCmds)
+	ctx.ProjectResults = result.ProjectResults
 
 	if p.autoMerger.automergeEnabled(projectCmds) && result.HasErrors() {
 		ctx.Log.Info("deleting plans because there were errors and automerge requires all plans succeed")
@@ -141,15 +193,20 @@ func (p *PlanCommandRunner) runAutoplan(ctx *command.Context) {
 		result.PlansDeleted = true
 	}
 
-	p.pullUpdater.updatePull(ctx, AutoplanCommand{}, result)
+	if p.commentsPlanFirst() {
+		p.pullUpdater.updatePull(ctx, AutoplanCommand{}, result)
+	}
 
+	markNoDestroy(projectCmds, result.ProjectResults)
 	pullStatus, err := p.dbUpdater.updateDB(ctx, ctx.Pull, result.ProjectResults)
 	if err != nil {
 		ctx.Log.Err("writing results: %s", err)
 	}
 
-	p.updateCommitStatus(ctx, pullStatus, command.Plan)
+	p.updateAutoplanCommitStatus(ctx, pullStatus, unselectedCmds)
 	p.updateCommitStatus(ctx, pullStatus, command.Apply)
+	p.updateDestroyCheckStatus(ctx, pullStatus)
+	p.updateFmtCheckStatus(ctx, result.ProjectResults)
 
 	// Check if there are any planned projects and if there are any errors or if plans are being deleted
 	if len(policyCheckCmds) > 0 &&
@@ -163,7 +220,82 @@ func (p *PlanCommandRunner) runAutoplan(ctx *command.Context) {
 		// we need to better structure how this command works.
 		ctx.PullStatus = &pullStatus
 
-		p.policyCheckCommandRunner.Run(ctx, policyCheckCmds)
+		p.runPolicyChecks(ctx, AutoplanCommand{}, result, policyCheckCmds)
+	} else if !p.commentsPlanFirst() {
+		p.pullUpdater.updatePull(ctx, AutoplanCommand{}, result)
+	}
+}
+
+// selectAutoplanProjectsByLabel returns the projects of projectCmds selected
+// by the pull request's labels with AutoplanLabelPrefix, and the ones that
+// weren't. If there are no such labels, all of projectCmds are selected.
+func (p *PlanCommandRunner) selectAutoplanProjectsByLabel(ctx *command.Context, projectCmds []command.ProjectContext) ([]command.ProjectContext, []command.ProjectContext) {
+	if p.AutoplanLabelPrefix == "" || len(projectCmds) == 0 {
+		return projectCmds, nil
+	}
+	labels, err := p.vcsClient.GetPullLabels(ctx.Pull.BaseRepo, ctx.Pull)
+	if err != nil {
+		ctx.Log.Warn("unable to get pull labels, autoplanning all projects: %s", err)
+		return projectCmds, nil
+	}
+	selected := make(map[string]bool)
+	for _, label := range labels {
+		if name, ok := strings.CutPrefix(label, p.AutoplanLabelPrefix); ok && name != "" {
+			selected[name] = true
+		}
+	}
+	if len(selected) == 0 {
+		return projectCmds, nil
+	}
+
+	var selectedCmds, unselectedCmds []command.ProjectContext
+	for _, cmd := range projectCmds {
+		name := cmd.ProjectName
+		if name == "" {
+			name = cmd.RepoRelDir
+		}
+		if selected[name] {
+			selectedCmds = append(selectedCmds, cmd)
+		} else {
+			unselectedCmds = append(unselectedCmds, cmd)
+		}
+	}
+	ctx.Log.Info("autoplanning %d of %d modified projects selected by labels, the others can be planned with a plan comment", len(selectedCmds), len(projectCmds))
+	return selectedCmds, unselectedCmds
+}
+
+// updateAutoplanCommitStatus updates the plan status like updateCommitStatus
+// but leaves it pending while projects that weren't selected by labels haven't
+// been planned. They aren't in pullStatus so the status would pass without them.
+func (p *PlanCommandRunner) updateAutoplanCommitStatus(ctx *command.Context, pullStatus models.PullStatus, unselectedCmds []command.ProjectContext) {
+	numUnplanned := 0
+	for _, cmd := range unselectedCmds {
+		planned := false
+		for _, project := range pullStatus.Projects {
+			if project.RepoRelDir == cmd.RepoRelDir && project.Workspace == cmd.Workspace && project.ProjectName == cmd.ProjectName {
+				planned = true
+				break
+			}
+		}
+		if !planned {
+			numUnplanned++
+		}
+	}
+	numErrored := pullStatus.StatusCount(models.ErroredPlanStatus) + pullStatus.StatusCount(models.CanceledPlanStatus)
+	// A failed plan takes precedence over the unplanned projects.
+	if numUnplanned == 0 || numErrored > 0 {
+		p.updateCommitStatus(ctx, pullStatus, command.Plan)
+		return
+	}
+	if err := p.commitStatusUpdater.UpdateCombinedCount(
+		ctx.Pull.BaseRepo,
+		ctx.Pull,
+		models.PendingCommitStatus,
+		command.Plan,
+		len(pullStatus.Projects),
+		len(pullStatus.Projects)+numUnplanned,
+	); err != nil {
+		ctx.Log.Warn("unable to update commit status: %s", err)
 	}
 }
 
@@ -181,6 +313,16 @@ func (p *PlanCommandRunner) run(ctx *command.Context, cmd *CommentCommand) {
 		ctx.Log.Warn("unable to get pull request status: %s. Continuing with mergeable and approved assumed false", err)
 	}
 
+	if cmd.SHA != "" {
+		p.runHistorical(ctx, cmd)
+		return
+	}
+
+	if cmd.ComparePull != 0 {
+		p.runCompare(ctx, cmd)
+		return
+	}
+
 	if p.DiscardApprovalOnPlan {
 		if err = p.pullUpdater.VCSClient.DiscardReviews(baseRepo, pull); err != nil {
 			ctx.Log.Err("failed to remove approvals: %s", err)
@@ -191,7 +333,7 @@ func (p *PlanCommandRunner) run(ctx *command.Context, cmd *CommentCommand) {
 		ctx.Log.Warn("unable to update commit status: %s", err)
 	}
 
-	projectCmds, err := p.prjCmdBuilder.BuildPlanCommands(ctx, cmd)
+	projectCmds, err := p.buildPlanCommands(ctx, cmd)
 	if err != nil {
 		if statusErr := p.commitStatusUpdater.UpdateCombined(ctx.Pull.BaseRepo, ctx.Pull, models.FailedCommitStatus, command.Plan); statusErr != nil {
 			ctx.Log.Warn("unable to update commit status: %s", statusErr)
@@ -249,12 +391,16 @@ func (p *PlanCommandRunner) run(ctx *command.Context, cmd *CommentCommand) {
 
 	// Only run commands in parallel if enabled
 	var result command.Result
+	start := time.Now()
 	if p.isParallelEnabled(projectCmds) {
 		ctx.Log.Info("Running plans in parallel")
 		result = runProjectCmdsParallelGroups(ctx, projectCmds, p.prjCmdRunner.Plan, p.parallelPoolSize)
 	} else {
 		result = runProjectCmds(projectCmds, p.prjCmdRunner.Plan)
 	}
+	result.Duration = time.Since(start)
+	result.PlanOnly = p.isPlanOnly(projectCmds)
+	ctx.ProjectResults = result.ProjectResults
 
 	if p.autoMerger.automergeEnabled(projectCmds) && result.HasErrors() {
 		ctx.Log.Info("deleting plans because there were errors and automerge requires all plans succeed")
@@ -262,30 +408,151 @@ func (p *PlanCommandRunner) run(ctx *command.Context, cmd *CommentCommand) {
 		result.PlansDeleted = true
 	}
 
-	p.pullUpdater.updatePull(
-		ctx,
-		cmd,
-		result)
+	if p.commentsPlanFirst() {
+		p.pullUpdater.updatePull(
+			ctx,
+			cmd,
+			result)
+	}
 
+	markNoDestroy(projectCmds, result.ProjectResults)
 	pullStatus, err := p.dbUpdater.updateDB(ctx, pull, result.ProjectResults)
 	if err != nil {
 		ctx.Log.Err("writing results: %s", err)
+		if !p.commentsPlanFirst() {
+			p.pullUpdater.updatePull(ctx, cmd, result)
+		}
 		return
 	}
 
 	p.updateCommitStatus(ctx, pullStatus, command.Plan)
 	p.updateCommitStatus(ctx, pullStatus, command.Apply)
+	p.updateDestroyCheckStatus(ctx, pullStatus)
+	p.updateFmtCheckStatus(ctx, result.ProjectResults)
 
 	// Runs policy checks step after all plans are successful.
 	// This step does not approve any policies that require approval.
 	if len(result.ProjectResults) > 0 &&
 		!(result.HasErrors() || result.PlansDeleted) {
 		ctx.Log.Info("Running policy check for %s", cmd.String())
-		p.policyCheckCommandRunner.Run(ctx, policyCheckCmds)
+		p.runPolicyChecks(ctx, cmd, result, policyCheckCmds)
+	} else if !p.commentsPlanFirst() {
+		p.pullUpdater.updatePull(ctx, cmd, result)
+	}
+}
+
+// buildPlanCommands builds the project commands to plan for cmd. If cmd
+// specifies several workspaces, the commands of each workspace are built in
+// turn so each project and workspace gets its own result.
+func (p *PlanCommandRunner) buildPlanCommands(ctx *command.Context, cmd *CommentCommand) ([]command.ProjectContext, error) {
+	if len(cmd.Workspaces) == 0 {
+		return p.prjCmdBuilder.BuildPlanCommands(ctx, cmd)
+	}
+	var projectCmds []command.ProjectContext
+	for _, workspace := range cmd.Workspaces {
+		workspaceCmd := *cmd
+		workspaceCmd.Workspace = workspace
+		cmds, err := p.prjCmdBuilder.BuildPlanCommands(ctx, &workspaceCmd)
+		if err != nil {
+			return nil, errors.Wrapf(err, "workspace %q", workspace)
+		}
+		projectCmds = append(projectCmds, cmds...)
+	}
+	return projectCmds, nil
+}
+
+// runHistorical plans cmd.SHA, a previous commit of the pull request, ex. for
+// auditing. The plans are made in separate working dirs and aren't saved in
+// the pull's status so they can't be applied and don't change any commit
+// statuses.
+func (p *PlanCommandRunner) runHistorical(ctx *command.Context, cmd *CommentCommand) {
+	historicalCtx := *ctx
+	historicalCtx.Pull.HeadCommit = cmd.SHA
+	historicalCtx.Pull.Historical = true
+	ctx.Log.Info("planning historical commit %q", cmd.SHA)
+
+	// Plan commands for specific projects are built from the default
+	// workspace so it has to be cloned at the commit first.
+	if _, _, err := p.workingDir.Clone(ctx.HeadRepo, historicalCtx.Pull, DefaultWorkspace); err != nil {
+		p.pullUpdater.updatePull(ctx, cmd, command.Result{Error: err})
+		return
+	}
+
+	projectCmds, err := p.buildPlanCommands(&historicalCtx, cmd)
+	if err != nil {
+		p.pullUpdater.updatePull(ctx, cmd, command.Result{Error: err})
+		return
 	}
+	// Policies are only checked for plans that can be applied.
+	projectCmds, _ = p.partitionProjectCmds(ctx, projectCmds)
+
+	var result command.Result
+	if p.isParallelEnabled(projectCmds) {
+		ctx.Log.Info("Running plans in parallel")
+		result = runProjectCmdsParallelGroups(&historicalCtx, projectCmds, p.prjCmdRunner.Plan, p.parallelPoolSize)
+	} else {
+		result = runProjectCmds(projectCmds, p.prjCmdRunner.Plan)
+	}
+	result.HistoricalSHA = cmd.SHA
+
+	p.pullUpdater.updatePull(ctx, cmd, result)
+}
+
+// runCompare compares the plans of the pull request with the plans of the
+// same projects in pull request cmd.ComparePull. Nothing is planned so the
+// pull's status and commit statuses don't change.
+func (p *PlanCommandRunner) runCompare(ctx *command.Context, cmd *CommentCommand) {
+	if p.PlanComparer == nil {
+		p.pullUpdater.updatePull(ctx, cmd, command.Result{Error: errors.New("comparing plans isn't supported")})
+		return
+	}
+	if cmd.ComparePull == ctx.Pull.Num {
+		p.pullUpdater.updatePull(ctx, cmd, command.Result{Failure: "Can't compare the plans of a pull request with its own plans."})
+		return
+	}
+
+	var projects []models.ProjectStatus
+	if ctx.PullStatus != nil {
+		for _, project := range ctx.PullStatus.Projects {
+			if project.Status != models.PlannedPlanStatus && project.Status != models.PlannedNoChangesPlanStatus {
+				continue
+			}
+			if (cmd.RepoRelDir != "" && cmd.RepoRelDir != project.RepoRelDir) ||
+				(cmd.Workspace != "" && cmd.Workspace != project.Workspace) ||
+				(cmd.ProjectName != "" && cmd.ProjectName != project.ProjectName) {
+				continue
+			}
+			projects = append(projects, project)
+		}
+	}
+	if len(projects) == 0 {
+		p.pullUpdater.updatePull(ctx, cmd, command.Result{Failure: "No plans to compare, run plan first."})
+		return
+	}
+
+	ctx.Log.Info("comparing plans of %d projects with pull request #%d", len(projects), cmd.ComparePull)
+	result := command.Result{ComparePull: cmd.ComparePull}
+	for _, project := range projects {
+		comparison, err := p.PlanComparer.Compare(ctx, project, cmd.ComparePull)
+		result.ProjectResults = append(result.ProjectResults, command.ProjectResult{
+			Command:        command.Plan,
+			RepoRelDir:     project.RepoRelDir,
+			Workspace:      project.Workspace,
+			ProjectName:    project.ProjectName,
+			PlanComparison: comparison,
+			Error:          err,
+		})
+	}
+	p.pullUpdater.updatePull(ctx, cmd, result)
 }
 
 func (p *PlanCommandRunner) Run(ctx *command.Context, cmd *CommentCommand) {
+	if p.PlanRateLimiter != nil {
+		if ok, wait := p.PlanRateLimiter.Allow(ctx.Pull.BaseRepo.FullName); !ok {
+			p.rejectRateLimited(ctx, cmd, wait)
+			return
+		}
+	}
 	if ctx.Trigger == command.AutoTrigger {
 		p.runAutoplan(ctx)
 	} else {
@@ -293,13 +560,29 @@ func (p *PlanCommandRunner) Run(ctx *command.Context, cmd *CommentCommand) {
 	}
 }
 
+// rejectRateLimited fails the plan of ctx because its repo ran too many plans
+// recently and comments how long to wait before planning again.
+func (p *PlanCommandRunner) rejectRateLimited(ctx *command.Context, cmd *CommentCommand, wait time.Duration) {
+	ctx.Log.Warn("plan rate limit of repo %s reached, not planning", ctx.Pull.BaseRepo.FullName)
+	if err := p.commitStatusUpdater.UpdateCombined(ctx.Pull.BaseRepo, ctx.Pull, models.FailedCommitStatus, command.Plan); err != nil {
+		ctx.Log.Warn("unable to update commit status: %s", err)
+	}
+	wait = time.Duration(math.Ceil(wait.Seconds())) * time.Second
+	failure := fmt.Sprintf("This repo has reached its limit of plans per minute. Try again in %s by commenting `atlantis plan`.", wait)
+	var pullCmd PullCommand = AutoplanCommand{}
+	if ctx.Trigger != command.AutoTrigger {
+		pullCmd = cmd
+	}
+	p.pullUpdater.updatePull(ctx, pullCmd, command.Result{Failure: failure})
+}
+
 func (p *PlanCommandRunner) updateCommitStatus(ctx *command.Context, pullStatus models.PullStatus, commandName command.Name) {
 	var numSuccess int
 	var numErrored int
 	status := models.SuccessCommitStatus
 
 	if commandName == command.Plan {
-		numErrored = pullStatus.StatusCount(models.ErroredPlanStatus)
+		numErrored = pullStatus.StatusCount(models.ErroredPlanStatus) + pullStatus.StatusCount(models.CanceledPlanStatus)
 		// We consider anything that isn't a plan error as a plan success.
 		// For example, if there is an apply error, that means that at least a
 		// plan was generated successfully.
@@ -310,7 +593,7 @@ func (p *PlanCommandRunner) updateCommitStatus(ctx *command.Context, pullStatus
 		}
 	} else if commandName == command.Apply {
 		numSuccess = pullStatus.StatusCount(models.AppliedPlanStatus) + pullStatus.StatusCount(models.PlannedNoChangesPlanStatus)
-		numErrored = pullStatus.StatusCount(models.ErroredApplyStatus)
+		numErrored = pullStatus.StatusCount(models.ErroredApplyStatus) + pullStatus.StatusCount(models.CanceledPlanStatus)
 
 		if numErrored > 0 {
 			status = models.FailedCommitStatus
@@ -366,3 +649,81 @@ func (p *PlanCommandRunner) partitionProjectCmds(
 func (p *PlanCommandRunner) isParallelEnabled(projectCmds []command.ProjectContext) bool {
 	return len(projectCmds) > 0 && projectCmds[0].ParallelPlanEnabled
 }
+
+// isPlanOnly returns true if the projects are in a plan-only repo. It's a
+// repo setting so all projects share it.
+func (p *PlanCommandRunner) isPlanOnly(projectCmds []command.ProjectContext) bool {
+	return len(projectCmds) > 0 && projectCmds[0].PlanOnly
+}
+
+// updateFmtCheckStatus sets the fmt check commit status to failed if any of
+// the projects have unformatted Terraform files. It's left alone if none of
+// the projects were checked, ex. because the check was skipped.
+func (p *PlanCommandRunner) updateFmtCheckStatus(ctx *command.Context, results []command.ProjectResult) {
+	checked := false
+	numUnformatted := 0
+	for _, res := range results {
+		if res.FmtCheck == nil {
+			continue
+		}
+		checked = true
+		if len(res.FmtCheck.UnformattedFiles) > 0 {
+			numUnformatted++
+		}
+	}
+	if !checked {
+		return
+	}
+
+	status := models.SuccessCommitStatus
+	if numUnformatted > 0 {
+		status = models.FailedCommitStatus
+	}
+	if err := p.commitStatusUpdater.UpdateFmtCheck(ctx.Pull.BaseRepo, ctx.Pull, status, numUnformatted); err != nil {
+		ctx.Log.Warn("unable to update fmt check commit status: %s", err)
+	}
+}
+
+// markNoDestroy marks the results of the projects in projectCmds with the
+// no_destroy apply requirement so it's stored with their plans.
+func markNoDestroy(projectCmds []command.ProjectContext, results []command.ProjectResult) {
+	for _, projCmd := range projectCmds {
+		if !utils.SlicesContains(projCmd.ApplyRequirements, raw.NoDestroyRequirement) {
+			continue
+		}
+		for i, res := range results {
+			if res.RepoRelDir == projCmd.RepoRelDir && res.Workspace == projCmd.Workspace && res.ProjectName == projCmd.ProjectName {
+				results[i].NoDestroy = true
+			}
+		}
+	}
+}
+
+// updateDestroyCheckStatus sets the destroy check commit status to failed if
+// any of the latest plans of the pull request's projects that require
+// no_destroy destroy resources, including projects that weren't planned this
+// time. It's left alone if none of the projects require no_destroy.
+func (p *PlanCommandRunner) updateDestroyCheckStatus(ctx *command.Context, pullStatus models.PullStatus) {
+	checked := false
+	numDestroying := 0
+	for _, project := range pullStatus.Projects {
+		if !project.NoDestroy {
+			continue
+		}
+		checked = true
+		if project.PlanDestroys > 0 {
+			numDestroying++
+		}
+	}
+	if !checked {
+		return
+	}
+
+	status := models.SuccessCommitStatus
+	if numDestroying > 0 {
+		status = models.FailedCommitStatus
+	}
+	if err := p.commitStatusUpdater.UpdateDestroyCheck(ctx.Pull.BaseRepo, ctx.Pull, status, numDestroying); err != nil {
+		ctx.Log.Warn("unable to update destroy check commit status: %s", err)
+	}
+}
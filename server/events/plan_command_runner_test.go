@@ -2,6 +2,9 @@ package events_test
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-github/v54/github"
@@ -11,6 +14,7 @@ import (
 	"github.com/runatlantis/atlantis/server/events/command"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/models/testdata"
+	"github.com/runatlantis/atlantis/server/events/vcs"
 	"github.com/runatlantis/atlantis/server/logging"
 	"github.com/runatlantis/atlantis/server/metrics"
 	. "github.com/runatlantis/atlantis/testing"
@@ -767,3 +771,463 @@ func TestPlanCommandRunner_AtlantisApplyStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestPlanCommandRunner_Compare(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	RegisterMockTestingT(t)
+
+	cases := []struct {
+		Description string
+		Comparer    *events.PlanComparer
+		ComparePull int
+		PullStatus  *models.PullStatus
+		ExpComment  string
+	}{
+		{
+			Description: "When comparing isn't configured, comment with an error",
+			ComparePull: 2,
+			ExpComment:  "comparing plans isn't supported",
+		},
+		{
+			Description: "When comparing with the same pull request, comment with a failure",
+			Comparer:    &events.PlanComparer{},
+			ComparePull: testdata.Pull.Num,
+			ExpComment:  "Can't compare the plans of a pull request with its own plans.",
+		},
+		{
+			Description: "When no projects are planned, comment with a failure",
+			Comparer:    &events.PlanComparer{},
+			ComparePull: 2,
+			PullStatus: &models.PullStatus{
+				Projects: []models.ProjectStatus{{RepoRelDir: "mydir", Workspace: "default", Status: models.ErroredPlanStatus}},
+			},
+			ExpComment: "No plans to compare, run plan first.",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Description, func(t *testing.T) {
+			vcsClient := setup(t)
+			planCommandRunner.PlanComparer = c.Comparer
+
+			scopeNull, _, _ := metrics.NewLoggingScope(logger, "atlantis")
+			modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+			ctx := &command.Context{
+				User:       testdata.User,
+				Log:        logging.NewNoopLogger(t),
+				Scope:      scopeNull,
+				Pull:       modelPull,
+				PullStatus: c.PullStatus,
+				HeadRepo:   testdata.GithubRepo,
+				Trigger:    command.CommentTrigger,
+			}
+
+			planCommandRunner.Run(ctx, &events.CommentCommand{Name: command.Plan, ComparePull: c.ComparePull})
+
+			_, _, comment, _ := vcsClient.VerifyWasCalledOnce().CreateComment(Any[models.Repo](), Eq(modelPull.Num), Any[string](), Eq("plan")).GetCapturedArguments()
+			Assert(t, strings.Contains(comment, c.ExpComment), "exp %q to be contained in %q", c.ExpComment, comment)
+			projectCommandRunner.VerifyWasCalled(Never()).Plan(Any[command.ProjectContext]())
+		})
+	}
+}
+
+func TestPlanCommandRunner_EditPlanComments(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	RegisterMockTestingT(t)
+	vcsClient := setup(t)
+	editor := &fakeCommentEditor{}
+	pullUpdater.PlanCommentEditor = &events.PlanCommentEditor{
+		Editors: map[models.VCSHostType]vcs.CommentEditor{models.Github: editor},
+	}
+
+	scopeNull, _, _ := metrics.NewLoggingScope(logger, "atlantis")
+	modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+	ctx := &command.Context{
+		User:     testdata.User,
+		Log:      logger,
+		Scope:    scopeNull,
+		Pull:     modelPull,
+		HeadRepo: testdata.GithubRepo,
+		Trigger:  command.CommentTrigger,
+	}
+	cmd := &events.CommentCommand{Name: command.Plan}
+	projectCtx := command.ProjectContext{CommandName: command.Plan, RepoRelDir: "mydir", Workspace: "default"}
+	When(projectCommandBuilder.BuildPlanCommands(ctx, cmd)).ThenReturn([]command.ProjectContext{projectCtx}, nil)
+	When(projectCommandRunner.Plan(projectCtx)).ThenReturn(command.ProjectResult{
+		Command:     command.Plan,
+		RepoRelDir:  "mydir",
+		Workspace:   "default",
+		PlanSuccess: &models.PlanSuccess{TerraformOutput: "Plan: 1 to add, 0 to change, 0 to destroy."},
+	})
+
+	planCommandRunner.Run(ctx, cmd)
+	Equals(t, 1, len(editor.comments))
+	Equals(t, 0, len(editor.edited))
+
+	planCommandRunner.Run(ctx, cmd)
+	Equals(t, 1, len(editor.comments))
+	Equals(t, []int64{1}, editor.edited)
+	Assert(t, strings.Contains(editor.comments[0], "Plan: 1 to add"), "exp the plan comment to be edited, got %q", editor.comments[0])
+
+	vcsClient.VerifyWasCalled(Never()).CreateComment(Any[models.Repo](), AnyInt(), AnyString(), AnyString())
+}
+
+func TestPlanCommandRunner_PlanReviews(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	RegisterMockTestingT(t)
+	vcsClient := setup(t)
+	creator := &fakeReviewCreator{}
+	pullUpdater.PlanReviewPoster = &events.PlanReviewPoster{ReviewCreator: creator}
+
+	scopeNull, _, _ := metrics.NewLoggingScope(logger, "atlantis")
+	modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+	ctx := &command.Context{
+		User:     testdata.User,
+		Log:      logger,
+		Scope:    scopeNull,
+		Pull:     modelPull,
+		HeadRepo: testdata.GithubRepo,
+		Trigger:  command.CommentTrigger,
+	}
+	cmd := &events.CommentCommand{Name: command.Plan}
+	var projectCtxs []command.ProjectContext
+	for _, dir := range []string{"app", "network"} {
+		projectCtx := command.ProjectContext{CommandName: command.Plan, RepoRelDir: dir, Workspace: "default"}
+		projectCtxs = append(projectCtxs, projectCtx)
+		When(projectCommandRunner.Plan(projectCtx)).ThenReturn(command.ProjectResult{
+			Command:     command.Plan,
+			RepoRelDir:  dir,
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{TerraformOutput: "Plan: 1 to add, 0 to change, 0 to destroy."},
+		})
+	}
+	When(projectCommandBuilder.BuildPlanCommands(ctx, cmd)).ThenReturn(projectCtxs, nil)
+
+	planCommandRunner.Run(ctx, cmd)
+	Equals(t, 1, len(creator.reviews))
+	for _, dir := range []string{"app", "network"} {
+		Assert(t, strings.Contains(creator.reviews[0], "dir: `"+dir+"`"), "exp the review to include the plan of %s, got %q", dir, creator.reviews[0])
+	}
+	vcsClient.VerifyWasCalled(Never()).CreateComment(Any[models.Repo](), AnyInt(), AnyString(), AnyString())
+
+	// Plans are commented if the review can't be created.
+	creator.err = errors.New("review of 70000 characters is longer than the maximum of 65536")
+	planCommandRunner.Run(ctx, cmd)
+	vcsClient.VerifyWasCalledOnce().CreateComment(Any[models.Repo](), AnyInt(), AnyString(), Eq("plan"))
+}
+
+func TestPlanCommandRunner_SplitCommentsByCodeOwners(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	RegisterMockTestingT(t)
+	vcsClient := setup(t)
+	repoDir := t.TempDir()
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "CODEOWNERS"), []byte("/network/ @org/network\n"), 0600))
+	When(workingDir.GetWorkingDir(Any[models.Repo](), Any[models.PullRequest](), Eq(events.DefaultWorkspace))).ThenReturn(repoDir, nil)
+	pullUpdater.CodeOwnersGrouper = &events.CodeOwnersGrouper{
+		WorkingDir: workingDir,
+		Comments:   events.SplitCodeOwnersComments,
+	}
+
+	scopeNull, _, _ := metrics.NewLoggingScope(logger, "atlantis")
+	modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+	ctx := &command.Context{
+		User:     testdata.User,
+		Log:      logger,
+		Scope:    scopeNull,
+		Pull:     modelPull,
+		HeadRepo: testdata.GithubRepo,
+		Trigger:  command.CommentTrigger,
+	}
+	cmd := &events.CommentCommand{Name: command.Plan}
+	var projectCtxs []command.ProjectContext
+	for _, dir := range []string{"app", "network"} {
+		projectCtx := command.ProjectContext{CommandName: command.Plan, RepoRelDir: dir, Workspace: "default"}
+		projectCtxs = append(projectCtxs, projectCtx)
+		When(projectCommandRunner.Plan(projectCtx)).ThenReturn(command.ProjectResult{
+			Command:     command.Plan,
+			RepoRelDir:  dir,
+			Workspace:   "default",
+			PlanSuccess: &models.PlanSuccess{TerraformOutput: "Plan: 1 to add, 0 to change, 0 to destroy."},
+		})
+	}
+	When(projectCommandBuilder.BuildPlanCommands(ctx, cmd)).ThenReturn(projectCtxs, nil)
+
+	planCommandRunner.Run(ctx, cmd)
+	_, _, comments, _ := vcsClient.VerifyWasCalled(Times(2)).CreateComment(Any[models.Repo](), Eq(modelPull.Num), Any[string](), Eq("plan")).GetAllCapturedArguments()
+	Assert(t, strings.HasPrefix(comments[0], "## Plan: Projects owned by `@org/network`"), "exp the network team's comment first, got %q", comments[0])
+	Assert(t, strings.Contains(comments[0], "dir: `network`") && !strings.Contains(comments[0], "dir: `app`"), "exp only the network project, got %q", comments[0])
+	Assert(t, strings.HasPrefix(comments[1], "## Plan: Projects without owners"), "exp the unowned projects' comment last, got %q", comments[1])
+	Assert(t, strings.Contains(comments[1], "dir: `app`") && !strings.Contains(comments[1], "dir: `network`"), "exp only the app project, got %q", comments[1])
+
+	// With plan reviews, each owners' comment is posted as a review.
+	creator := &fakeReviewCreator{}
+	pullUpdater.PlanReviewPoster = &events.PlanReviewPoster{ReviewCreator: creator}
+	planCommandRunner.Run(ctx, cmd)
+	Equals(t, 2, len(creator.reviews))
+	Assert(t, strings.HasPrefix(creator.reviews[0], "## Plan: Projects owned by `@org/network`"), "exp the network team's review first, got %q", creator.reviews[0])
+	Assert(t, strings.HasPrefix(creator.reviews[1], "## Plan: Projects without owners"), "exp the unowned projects' review last, got %q", creator.reviews[1])
+	vcsClient.VerifyWasCalled(Times(2)).CreateComment(Any[models.Repo](), Eq(modelPull.Num), Any[string](), Eq("plan"))
+}
+
+func TestPlanCommandRunner_AutoplanLabelPrefix(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	scopeNull, _, _ := metrics.NewLoggingScope(logger, "atlantis")
+	modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+	networking := command.ProjectContext{CommandName: command.Plan, ProjectName: "networking", RepoRelDir: "networking", Workspace: "default"}
+	prod := command.ProjectContext{CommandName: command.Plan, RepoRelDir: "envs/prod", Workspace: "default"}
+	dns := command.ProjectContext{CommandName: command.Plan, ProjectName: "dns", RepoRelDir: "dns", Workspace: "default"}
+
+	cases := []struct {
+		description string
+		prefix      string
+		labels      []string
+		expPlanned  []command.ProjectContext
+		// expStatus is the plan status set with expNumSuccess of the 3 projects.
+		expStatus     models.CommitStatus
+		expNumSuccess int
+	}{
+		{
+			description:   "no prefix",
+			labels:        []string{"atlantis/plan:networking"},
+			expPlanned:    []command.ProjectContext{networking, prod, dns},
+			expStatus:     models.SuccessCommitStatus,
+			expNumSuccess: 3,
+		},
+		{
+			description:   "labels select projects by name or dir",
+			prefix:        "atlantis/plan:",
+			labels:        []string{"bug", "atlantis/plan:networking", "atlantis/plan:envs/prod"},
+			expPlanned:    []command.ProjectContext{networking, prod},
+			expStatus:     models.PendingCommitStatus,
+			expNumSuccess: 2,
+		},
+		{
+			description:   "no labels with the prefix",
+			prefix:        "atlantis/plan:",
+			labels:        []string{"bug"},
+			expPlanned:    []command.ProjectContext{networking, prod, dns},
+			expStatus:     models.SuccessCommitStatus,
+			expNumSuccess: 3,
+		},
+		{
+			description:   "labels select no modified projects",
+			prefix:        "atlantis/plan:",
+			labels:        []string{"atlantis/plan:other"},
+			expStatus:     models.PendingCommitStatus,
+			expNumSuccess: 0,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			vcsClient := setup(t)
+			planCommandRunner.AutoplanLabelPrefix = c.prefix
+			ctx := &command.Context{
+				User:     testdata.User,
+				Log:      logger,
+				Scope:    scopeNull,
+				Pull:     modelPull,
+				HeadRepo: testdata.GithubRepo,
+				Trigger:  command.AutoTrigger,
+			}
+			When(vcsClient.GetPullLabels(testdata.GithubRepo, modelPull)).ThenReturn(c.labels, nil)
+			When(projectCommandBuilder.BuildAutoplanCommands(ctx)).ThenReturn([]command.ProjectContext{networking, prod, dns}, nil)
+			When(projectCommandRunner.Plan(Any[command.ProjectContext]())).Then(func(args []Param) ReturnValues {
+				projectCtx := args[0].(command.ProjectContext)
+				return ReturnValues{command.ProjectResult{
+					Command:     command.Plan,
+					ProjectName: projectCtx.ProjectName,
+					RepoRelDir:  projectCtx.RepoRelDir,
+					Workspace:   projectCtx.Workspace,
+					PlanSuccess: &models.PlanSuccess{},
+				}}
+			})
+
+			planCommandRunner.Run(ctx, nil)
+
+			for _, p := range []command.ProjectContext{networking, prod, dns} {
+				times := 0
+				for _, exp := range c.expPlanned {
+					if exp.RepoRelDir == p.RepoRelDir {
+						times = 1
+					}
+				}
+				projectCommandRunner.VerifyWasCalled(Times(times)).Plan(p)
+			}
+			commitUpdater.VerifyWasCalledOnce().UpdateCombinedCount(
+				Any[models.Repo](), Any[models.PullRequest](), Eq[models.CommitStatus](c.expStatus), Eq[command.Name](command.Plan), Eq(c.expNumSuccess), Eq(3))
+		})
+	}
+}
+
+// Test that plans over the rate limit of their repo are rejected before the
+// projects are built.
+func TestPlanCommandRunner_RateLimit(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	RegisterMockTestingT(t)
+	vcsClient := setup(t)
+	planCommandRunner.PlanRateLimiter = events.NewPlanRateLimiter(1)
+	scopeNull, _, _ := metrics.NewLoggingScope(logger, "atlantis")
+	modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+	ctx := &command.Context{
+		User:     testdata.User,
+		Log:      logger,
+		Scope:    scopeNull,
+		Pull:     modelPull,
+		HeadRepo: testdata.GithubRepo,
+		Trigger:  command.CommentTrigger,
+	}
+	cmd := &events.CommentCommand{Name: command.Plan}
+	When(projectCommandBuilder.BuildPlanCommands(ctx, cmd)).ThenReturn([]command.ProjectContext{}, nil)
+
+	planCommandRunner.Run(ctx, cmd)
+	planCommandRunner.Run(ctx, cmd)
+
+	projectCommandBuilder.VerifyWasCalledOnce().BuildPlanCommands(ctx, cmd)
+	commitUpdater.VerifyWasCalledOnce().UpdateCombined(modelPull.BaseRepo, modelPull, models.FailedCommitStatus, command.Plan)
+	_, _, comments, _ := vcsClient.VerifyWasCalled(AtLeast(1)).CreateComment(Any[models.Repo](), Eq(modelPull.Num), Any[string](), Eq("plan")).GetAllCapturedArguments()
+	comment := comments[len(comments)-1]
+	Assert(t, strings.Contains(comment, "This repo has reached its limit of plans per minute. Try again in 1m0s by commenting `atlantis plan`."), "exp rate limit comment, got %q", comment)
+}
+
+func TestPlanCommandRunner_Workspaces(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	RegisterMockTestingT(t)
+	vcsClient := setup(t)
+	scopeNull, _, _ := metrics.NewLoggingScope(logger, "atlantis")
+	modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+	ctx := &command.Context{
+		User:     testdata.User,
+		Log:      logger,
+		Scope:    scopeNull,
+		Pull:     modelPull,
+		HeadRepo: testdata.GithubRepo,
+		Trigger:  command.CommentTrigger,
+	}
+	staging := command.ProjectContext{CommandName: command.Plan, RepoRelDir: "foo", Workspace: "staging"}
+	prod := command.ProjectContext{CommandName: command.Plan, RepoRelDir: "foo", Workspace: "prod"}
+	When(projectCommandBuilder.BuildPlanCommands(ctx, &events.CommentCommand{Name: command.Plan, ProjectName: "foo", Workspace: "staging", Workspaces: []string{"staging", "prod"}})).
+		ThenReturn([]command.ProjectContext{staging}, nil)
+	When(projectCommandBuilder.BuildPlanCommands(ctx, &events.CommentCommand{Name: command.Plan, ProjectName: "foo", Workspace: "prod", Workspaces: []string{"staging", "prod"}})).
+		ThenReturn([]command.ProjectContext{prod}, nil)
+	When(projectCommandRunner.Plan(Any[command.ProjectContext]())).Then(func(args []Param) ReturnValues {
+		projectCtx := args[0].(command.ProjectContext)
+		return ReturnValues{command.ProjectResult{
+			Command:     command.Plan,
+			RepoRelDir:  projectCtx.RepoRelDir,
+			Workspace:   projectCtx.Workspace,
+			PlanSuccess: &models.PlanSuccess{TerraformOutput: "Plan: 1 to add, 0 to change, 0 to destroy."},
+		}}
+	})
+
+	planCommandRunner.Run(ctx, &events.CommentCommand{Name: command.Plan, ProjectName: "foo", Workspaces: []string{"staging", "prod"}})
+
+	projectCommandRunner.VerifyWasCalledOnce().Plan(staging)
+	projectCommandRunner.VerifyWasCalledOnce().Plan(prod)
+	_, _, comment, _ := vcsClient.VerifyWasCalledOnce().CreateComment(Any[models.Repo](), Eq(modelPull.Num), Any[string](), Eq("plan")).GetCapturedArguments()
+	Assert(t, strings.Contains(comment, "dir: `foo` workspace: `staging`"), "exp staging section in %q", comment)
+	Assert(t, strings.Contains(comment, "dir: `foo` workspace: `prod`"), "exp prod section in %q", comment)
+}
+
+func TestPlanCommandRunner_WorkspacesBuildError(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	RegisterMockTestingT(t)
+	vcsClient := setup(t)
+	scopeNull, _, _ := metrics.NewLoggingScope(logger, "atlantis")
+	modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+	ctx := &command.Context{
+		User:     testdata.User,
+		Log:      logger,
+		Scope:    scopeNull,
+		Pull:     modelPull,
+		HeadRepo: testdata.GithubRepo,
+		Trigger:  command.CommentTrigger,
+	}
+	When(projectCommandBuilder.BuildPlanCommands(ctx, &events.CommentCommand{Name: command.Plan, RepoRelDir: "foo", Workspace: "staging", Workspaces: []string{"staging", "typo"}})).
+		ThenReturn([]command.ProjectContext{{CommandName: command.Plan, RepoRelDir: "foo", Workspace: "staging"}}, nil)
+	When(projectCommandBuilder.BuildPlanCommands(ctx, &events.CommentCommand{Name: command.Plan, RepoRelDir: "foo", Workspace: "typo", Workspaces: []string{"staging", "typo"}})).
+		ThenReturn(nil, errors.New("running commands in workspace \"typo\" is not allowed"))
+
+	planCommandRunner.Run(ctx, &events.CommentCommand{Name: command.Plan, RepoRelDir: "foo", Workspaces: []string{"staging", "typo"}})
+
+	projectCommandRunner.VerifyWasCalled(Never()).Plan(Any[command.ProjectContext]())
+	_, _, comment, _ := vcsClient.VerifyWasCalledOnce().CreateComment(Any[models.Repo](), Eq(modelPull.Num), Any[string](), Eq("plan")).GetCapturedArguments()
+	Assert(t, strings.Contains(comment, `workspace "typo": running commands in workspace "typo" is not allowed`), "exp error in %q", comment)
+}
+
+func TestPlanCommandRunner_PolicyCheckCommentOrder(t *testing.T) {
+	cases := []struct {
+		order       events.PolicyCheckCommentOrder
+		expCommands []string
+	}{
+		{
+			order:       events.AfterPlanPolicyCheckCommentOrder,
+			expCommands: []string{"plan", "policy_check"},
+		},
+		{
+			order:       events.BeforePlanPolicyCheckCommentOrder,
+			expCommands: []string{"policy_check", "plan"},
+		},
+		{
+			order:       events.MergedPolicyCheckCommentOrder,
+			expCommands: []string{"plan"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(string(c.order), func(t *testing.T) {
+			logger := logging.NewNoopLogger(t)
+			RegisterMockTestingT(t)
+			vcsClient := setup(t)
+			planCommandRunner.PolicyCheckCommentOrder = c.order
+
+			scopeNull, _, _ := metrics.NewLoggingScope(logger, "atlantis")
+			modelPull := models.PullRequest{BaseRepo: testdata.GithubRepo, State: models.OpenPullState, Num: testdata.Pull.Num}
+			ctx := &command.Context{
+				User:     testdata.User,
+				Log:      logger,
+				Scope:    scopeNull,
+				Pull:     modelPull,
+				HeadRepo: testdata.GithubRepo,
+				Trigger:  command.CommentTrigger,
+			}
+			cmd := &events.CommentCommand{Name: command.Plan}
+			planCtx := command.ProjectContext{CommandName: command.Plan, RepoRelDir: "mydir", Workspace: "default"}
+			policyCheckCtx := command.ProjectContext{CommandName: command.PolicyCheck, RepoRelDir: "mydir", Workspace: "default"}
+			When(projectCommandBuilder.BuildPlanCommands(ctx, cmd)).ThenReturn([]command.ProjectContext{planCtx, policyCheckCtx}, nil)
+			When(projectCommandRunner.Plan(planCtx)).ThenReturn(command.ProjectResult{
+				Command:     command.Plan,
+				RepoRelDir:  "mydir",
+				Workspace:   "default",
+				PlanSuccess: &models.PlanSuccess{TerraformOutput: "Plan: 1 to add, 0 to change, 0 to destroy."},
+			})
+			When(projectCommandRunner.PolicyCheck(policyCheckCtx)).ThenReturn(command.ProjectResult{
+				Command:    command.PolicyCheck,
+				RepoRelDir: "mydir",
+				Workspace:  "default",
+				PolicyCheckResults: &models.PolicyCheckResults{
+					PolicySetResults: []models.PolicySetResult{
+						{PolicySetName: "policy1", PolicyOutput: "1 test, 1 passed, 0 warnings, 0 failures, 0 exceptions", Passed: true},
+					},
+				},
+			})
+
+			planCommandRunner.Run(ctx, cmd)
+
+			_, _, comments, commands := vcsClient.VerifyWasCalled(Times(len(c.expCommands))).
+				CreateComment(Any[models.Repo](), Eq(modelPull.Num), Any[string](), Any[string]()).GetAllCapturedArguments()
+			Equals(t, c.expCommands, commands)
+			var planComment string
+			for i := range commands {
+				if commands[i] == "plan" {
+					planComment = comments[i]
+				}
+			}
+			Assert(t, strings.Contains(planComment, "Plan: 1 to add"), "exp the plan in %q", planComment)
+			if c.order == events.MergedPolicyCheckCommentOrder {
+				Assert(t, strings.Contains(planComment, "#### Policy Set: `policy1`"), "exp the policy check results in %q", planComment)
+			} else {
+				Assert(t, !strings.Contains(planComment, "#### Policy Set: `policy1`"), "exp no policy check results in %q", planComment)
+			}
+		})
+	}
+}
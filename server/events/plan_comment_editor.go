@@ -0,0 +1,88 @@
+package events
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+)
+
+// PlanCommentEditor edits the plan comment of a pull request in place when
+// the same projects are planned again instead of adding another comment.
+// Plan comments end with a hidden marker of their projects so they can be
+// found on the pull request again, even by another Atlantis server.
+// A nil *PlanCommentEditor edits nothing.
+type PlanCommentEditor struct {
+	// Editors are the clients that can edit comments by VCS host type. Plan
+	// comments on other hosts are always added as new comments.
+	Editors map[models.VCSHostType]vcs.CommentEditor
+}
+
+// Edit edits the plan comment that was created for the same projects as res
+// to be comment. It returns false if there's no such comment or it couldn't
+// be edited, ex. because it was deleted.
+func (p *PlanCommentEditor) Edit(ctx *command.Context, res command.Result, comment string) bool {
+	editor, marker, ok := p.editor(ctx, res)
+	if !ok {
+		return false
+	}
+	commentID, err := editor.FindComment(ctx.Pull.BaseRepo, ctx.Pull.Num, marker)
+	if err != nil {
+		ctx.Log.Warn("unable to find the plan comment to edit, adding a new comment: %s", err)
+		return false
+	}
+	if commentID == 0 {
+		return false
+	}
+	if err := editor.EditComment(ctx.Pull.BaseRepo, ctx.Pull.Num, commentID, comment+marker); err != nil {
+		ctx.Log.Warn("unable to edit plan comment %d, adding a new comment: %s", commentID, err)
+		return false
+	}
+	return true
+}
+
+// Create creates comment as the plan comment of the projects in res so the
+// next plan of the same projects edits it. It returns false if it didn't
+// comment, ex. because the VCS host can't edit comments or comment is too
+// long for a single comment, in which case the caller must create it.
+func (p *PlanCommentEditor) Create(ctx *command.Context, res command.Result, comment string) bool {
+	editor, marker, ok := p.editor(ctx, res)
+	if !ok {
+		return false
+	}
+	if _, err := editor.CreateCommentWithID(ctx.Pull.BaseRepo, ctx.Pull.Num, comment+marker); err != nil {
+		ctx.Log.Warn("unable to create an editable plan comment: %s", err)
+		return false
+	}
+	return true
+}
+
+// editor returns the editor for the VCS host of ctx and the marker of the
+// plan comment of res, or false if the comment of res isn't editable.
+func (p *PlanCommentEditor) editor(ctx *command.Context, res command.Result) (vcs.CommentEditor, string, bool) {
+	// Plans of a previous commit and plan comparisons don't replace the
+	// plans of the pull request so they always get their own comments.
+	if p == nil || len(res.ProjectResults) == 0 || res.HistoricalSHA != "" || res.ComparePull != 0 {
+		return nil, "", false
+	}
+	editor, ok := p.Editors[ctx.Pull.BaseRepo.VCSHost.Type]
+	if !ok {
+		return nil, "", false
+	}
+	return editor, planCommentMarker(res), true
+}
+
+// planCommentMarker returns the hidden marker that identifies the plan comment
+// of the projects in res, in any order.
+func planCommentMarker(res command.Result) string {
+	var projects []string
+	for _, result := range res.ProjectResults {
+		projects = append(projects, fmt.Sprintf("%s/%s/%s", result.RepoRelDir, result.Workspace, result.ProjectName))
+	}
+	sort.Strings(projects)
+	return fmt.Sprintf("\n<!-- atlantis-plan-comment: %x -->", sha256.Sum256([]byte(strings.Join(projects, ","))))
+}
@@ -0,0 +1,143 @@
+package events_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+type fakeCommentEditor struct {
+	createErr error
+	findErr   error
+	editErr   error
+	// comments are the comments on the pull request, their IDs are their
+	// index plus one. Deleted comments are empty.
+	comments []string
+	// edited are the IDs of the edited comments.
+	edited []int64
+}
+
+func (f *fakeCommentEditor) CreateCommentWithID(_ models.Repo, _ int, comment string) (int64, error) {
+	if f.createErr != nil {
+		return 0, f.createErr
+	}
+	f.comments = append(f.comments, comment)
+	return int64(len(f.comments)), nil
+}
+
+func (f *fakeCommentEditor) FindComment(_ models.Repo, _ int, marker string) (int64, error) {
+	if f.findErr != nil {
+		return 0, f.findErr
+	}
+	var commentID int64
+	for i, comment := range f.comments {
+		if strings.Contains(comment, marker) {
+			commentID = int64(i + 1)
+		}
+	}
+	return commentID, nil
+}
+
+func (f *fakeCommentEditor) EditComment(_ models.Repo, _ int, commentID int64, comment string) error {
+	if f.editErr != nil {
+		return f.editErr
+	}
+	f.comments[commentID-1] = comment
+	f.edited = append(f.edited, commentID)
+	return nil
+}
+
+func TestPlanCommentEditor(t *testing.T) {
+	ctx := &command.Context{
+		Log: logging.NewNoopLogger(t),
+		Pull: models.PullRequest{
+			Num:      1,
+			BaseRepo: models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Type: models.Github}},
+		},
+	}
+	res := command.Result{ProjectResults: []command.ProjectResult{
+		{RepoRelDir: "a", Workspace: "default"},
+		{RepoRelDir: "b", Workspace: "default"},
+	}}
+	editor := &fakeCommentEditor{}
+	planCommentEditor := &events.PlanCommentEditor{
+		Editors: map[models.VCSHostType]vcs.CommentEditor{models.Github: editor},
+	}
+
+	// The first plan creates the comment.
+	Equals(t, false, planCommentEditor.Edit(ctx, res, "plan 1"))
+	Equals(t, true, planCommentEditor.Create(ctx, res, "plan 1"))
+	Equals(t, 1, len(editor.comments))
+	Assert(t, strings.HasPrefix(editor.comments[0], "plan 1\n<!-- atlantis-plan-comment: "), "exp a hidden marker, got %q", editor.comments[0])
+
+	// Planning the same projects in a different order edits it, even from
+	// another Atlantis server.
+	reordered := command.Result{ProjectResults: []command.ProjectResult{res.ProjectResults[1], res.ProjectResults[0]}}
+	other := &events.PlanCommentEditor{Editors: planCommentEditor.Editors}
+	Equals(t, true, other.Edit(ctx, reordered, "plan 2"))
+	Equals(t, []int64{1}, editor.edited)
+	Assert(t, strings.HasPrefix(editor.comments[0], "plan 2\n<!-- atlantis-plan-comment: "), "exp the comment to be edited, got %q", editor.comments[0])
+
+	// Planning other projects doesn't.
+	otherProjects := command.Result{ProjectResults: []command.ProjectResult{res.ProjectResults[0]}}
+	Equals(t, false, planCommentEditor.Edit(ctx, otherProjects, "plan 3"))
+
+	// Plans of a previous commit and comparisons don't either.
+	historical := res
+	historical.HistoricalSHA = "abc123"
+	Equals(t, false, planCommentEditor.Edit(ctx, historical, "plan 4"))
+	Equals(t, false, planCommentEditor.Create(ctx, historical, "plan 4"))
+	comparison := res
+	comparison.ComparePull = 2
+	Equals(t, false, planCommentEditor.Edit(ctx, comparison, "plan 5"))
+
+	// If the comment can't be found or edited, a new one is created and the
+	// latest one is edited next.
+	editor.findErr = errors.New("500 Internal Server Error")
+	Equals(t, false, planCommentEditor.Edit(ctx, res, "plan 6"))
+	editor.findErr = nil
+	editor.editErr = errors.New("404 Not Found")
+	Equals(t, false, planCommentEditor.Edit(ctx, res, "plan 6"))
+	Equals(t, true, planCommentEditor.Create(ctx, res, "plan 6"))
+	Equals(t, 2, len(editor.comments))
+	editor.editErr = nil
+	Equals(t, true, planCommentEditor.Edit(ctx, res, "plan 7"))
+	Equals(t, []int64{1, 2}, editor.edited)
+
+	// If the comment was deleted, there's nothing to edit.
+	editor.comments = []string{"", ""}
+	Equals(t, false, planCommentEditor.Edit(ctx, res, "plan 8"))
+
+	// If the comment can't be created, ex. because it's too long, the caller
+	// creates it.
+	editor.createErr = errors.New("comment is longer than the maximum of 65536 characters")
+	Equals(t, false, planCommentEditor.Create(ctx, res, "plan 9"))
+}
+
+func TestPlanCommentEditor_Unsupported(t *testing.T) {
+	ctx := &command.Context{
+		Log: logging.NewNoopLogger(t),
+		Pull: models.PullRequest{
+			Num:      1,
+			BaseRepo: models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Type: models.BitbucketCloud}},
+		},
+	}
+	res := command.Result{ProjectResults: []command.ProjectResult{{RepoRelDir: "a", Workspace: "default"}}}
+	editor := &fakeCommentEditor{}
+	planCommentEditor := &events.PlanCommentEditor{
+		Editors: map[models.VCSHostType]vcs.CommentEditor{models.Github: editor},
+	}
+	Equals(t, false, planCommentEditor.Create(ctx, res, "plan"))
+	Equals(t, 0, len(editor.comments))
+
+	var disabled *events.PlanCommentEditor
+	Equals(t, false, disabled.Edit(ctx, res, "plan"))
+	Equals(t, false, disabled.Create(ctx, res, "plan"))
+}
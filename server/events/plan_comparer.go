@@ -0,0 +1,195 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/runtime"
+	"github.com/runatlantis/atlantis/server/core/terraform"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// PlanComparer compares the stored plans of a project in two pull requests,
+// ex. to review how two pull requests that change the same project differ.
+type PlanComparer struct {
+	WorkingDir        WorkingDir
+	WorkingDirLocker  WorkingDirLocker
+	TerraformExecutor terraform.Client
+	// PlanEncryptor decrypts planfiles that are encrypted at rest. If nil,
+	// planfiles are stored unencrypted.
+	PlanEncryptor *runtime.PlanEncryptor
+}
+
+// Compare compares the plan of project in the pull request of ctx with the
+// plan of the same project in pull request comparePullNum. Both pull
+// requests must have been planned.
+func (c *PlanComparer) Compare(ctx *command.Context, project models.ProjectStatus, comparePullNum int) (*models.PlanComparison, error) {
+	planJSON, err := c.showPlan(ctx, ctx.Pull, project)
+	if err != nil {
+		return nil, err
+	}
+	comparePull := models.PullRequest{
+		Num:      comparePullNum,
+		BaseRepo: ctx.Pull.BaseRepo,
+	}
+	comparePlanJSON, err := c.showPlan(ctx, comparePull, project)
+	if err != nil {
+		return nil, err
+	}
+	diffs, err := ComparePlans(planJSON, comparePlanJSON)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PlanComparison{
+		ComparePull: comparePullNum,
+		Diffs:       diffs,
+	}, nil
+}
+
+// showPlan returns the JSON representation of the plan of project in pull.
+func (c *PlanComparer) showPlan(ctx *command.Context, pull models.PullRequest, project models.ProjectStatus) ([]byte, error) {
+	unlockFn, err := c.WorkingDirLocker.TryLock(pull.BaseRepo.FullName, pull.Num, project.Workspace, project.RepoRelDir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockFn()
+
+	notFoundErr := fmt.Errorf("no plan found for dir %q workspace %q in pull request #%d, it must be planned first", project.RepoRelDir, project.Workspace, pull.Num)
+	repoDir, err := c.WorkingDir.GetWorkingDir(pull.BaseRepo, pull, project.Workspace)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return nil, notFoundErr
+		}
+		return nil, err
+	}
+	projDir := filepath.Join(repoDir, project.RepoRelDir)
+	planFile := runtime.GetPlanFilename(project.Workspace, project.ProjectName)
+	planPath := filepath.Join(projDir, planFile)
+	if _, err := os.Stat(planPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil, notFoundErr
+		}
+		return nil, err
+	}
+
+	if err := c.PlanEncryptor.DecryptFile(planPath); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := c.PlanEncryptor.EncryptFile(planPath); err != nil {
+			ctx.Log.Err("encrypting planfile: %s", err)
+		}
+	}()
+
+	projCtx := command.ProjectContext{
+		CommandName: command.Plan,
+		BaseRepo:    pull.BaseRepo,
+		Pull:        pull,
+		Log:         ctx.Log,
+		RepoRelDir:  project.RepoRelDir,
+		Workspace:   project.Workspace,
+		ProjectName: project.ProjectName,
+	}
+	tfVersion := c.TerraformExecutor.DetectVersion(ctx.Log, projDir)
+	out, err := c.TerraformExecutor.RunCommandWithVersion(projCtx, projDir, []string{"show", "-json", planFile}, map[string]string{}, tfVersion, project.Workspace)
+	if err != nil {
+		return nil, errors.Wrapf(err, "showing plan of pull request #%d", pull.Num)
+	}
+	return []byte(out), nil
+}
+
+// planJSON is the part of the output of `terraform show -json <planfile>`
+// that's compared.
+type planJSON struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Change  struct {
+			Actions []string    `json:"actions"`
+			After   interface{} `json:"after"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// plannedChange is the change a plan makes to a resource.
+type plannedChange struct {
+	action string
+	after  interface{}
+}
+
+// ComparePlans compares the change sets of two plans in the JSON format of
+// `terraform show -json`. It returns the resources whose planned action or
+// planned values differ, sorted by address.
+func ComparePlans(plan []byte, comparePlan []byte) ([]models.ResourceChangeDiff, error) {
+	changes, err := plannedChanges(plan)
+	if err != nil {
+		return nil, err
+	}
+	compareChanges, err := plannedChanges(comparePlan)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []models.ResourceChangeDiff
+	for address, change := range changes {
+		compareChange, ok := compareChanges[address]
+		if ok && change.action == compareChange.action && reflect.DeepEqual(change.after, compareChange.after) {
+			continue
+		}
+		diffs = append(diffs, models.ResourceChangeDiff{
+			Address:       address,
+			Action:        change.action,
+			CompareAction: compareChange.action,
+		})
+	}
+	for address, compareChange := range compareChanges {
+		if _, ok := changes[address]; ok {
+			continue
+		}
+		diffs = append(diffs, models.ResourceChangeDiff{
+			Address:       address,
+			CompareAction: compareChange.action,
+		})
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Address < diffs[j].Address
+	})
+	return diffs, nil
+}
+
+// plannedChanges returns the changes plan makes by resource address.
+// Resources that aren't changed are skipped.
+func plannedChanges(plan []byte) (map[string]plannedChange, error) {
+	var p planJSON
+	if err := json.Unmarshal(plan, &p); err != nil {
+		return nil, errors.Wrap(err, "parsing plan JSON")
+	}
+	changes := make(map[string]plannedChange)
+	for _, rc := range p.ResourceChanges {
+		action := planAction(rc.Change.Actions)
+		if action == "" {
+			continue
+		}
+		changes[rc.Address] = plannedChange{action: action, after: rc.Change.After}
+	}
+	return changes, nil
+}
+
+// planAction returns a single word for the actions of a resource change, or
+// an empty string if the resource isn't changed.
+func planAction(actions []string) string {
+	switch strings.Join(actions, ",") {
+	case "", "no-op", "read":
+		return ""
+	case "delete,create", "create,delete":
+		return "replace"
+	default:
+		return strings.Join(actions, ",")
+	}
+}
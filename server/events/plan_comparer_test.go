@@ -0,0 +1,149 @@
+package events_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock/v4"
+	tmocks "github.com/runatlantis/atlantis/server/core/terraform/mocks"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestComparePlans(t *testing.T) {
+	cases := []struct {
+		description string
+		plan        string
+		comparePlan string
+		exp         []models.ResourceChangeDiff
+		expErr      string
+	}{
+		{
+			description: "same changes",
+			plan:        `{"resource_changes":[{"address":"null_resource.a","change":{"actions":["create"],"after":{"triggers":{"a":"1"}}}}]}`,
+			comparePlan: `{"resource_changes":[{"address":"null_resource.a","change":{"actions":["create"],"after":{"triggers":{"a":"1"}}}}]}`,
+		},
+		{
+			description: "no changes",
+			plan:        `{"resource_changes":[{"address":"null_resource.a","change":{"actions":["no-op"]}}]}`,
+			comparePlan: `{}`,
+		},
+		{
+			description: "only changed in one plan",
+			plan:        `{"resource_changes":[{"address":"null_resource.b","change":{"actions":["create"]}},{"address":"null_resource.a","change":{"actions":["no-op"]}}]}`,
+			comparePlan: `{"resource_changes":[{"address":"null_resource.a","change":{"actions":["delete"]}},{"address":"data.null_data_source.c","change":{"actions":["read"]}}]}`,
+			exp: []models.ResourceChangeDiff{
+				{Address: "null_resource.a", CompareAction: "delete"},
+				{Address: "null_resource.b", Action: "create"},
+			},
+		},
+		{
+			description: "different actions",
+			plan:        `{"resource_changes":[{"address":"null_resource.a","change":{"actions":["update"]}}]}`,
+			comparePlan: `{"resource_changes":[{"address":"null_resource.a","change":{"actions":["delete","create"]}}]}`,
+			exp: []models.ResourceChangeDiff{
+				{Address: "null_resource.a", Action: "update", CompareAction: "replace"},
+			},
+		},
+		{
+			description: "different values",
+			plan:        `{"resource_changes":[{"address":"null_resource.a","change":{"actions":["update"],"after":{"triggers":{"a":"1"}}}}]}`,
+			comparePlan: `{"resource_changes":[{"address":"null_resource.a","change":{"actions":["update"],"after":{"triggers":{"a":"2"}}}}]}`,
+			exp: []models.ResourceChangeDiff{
+				{Address: "null_resource.a", Action: "update", CompareAction: "update"},
+			},
+		},
+		{
+			description: "invalid json",
+			plan:        `{}`,
+			comparePlan: `Error: Failed to read plan`,
+			expErr:      "parsing plan JSON: invalid character 'E' looking for beginning of value",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			diffs, err := events.ComparePlans([]byte(c.plan), []byte(c.comparePlan))
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.exp, diffs)
+		})
+	}
+}
+
+func TestPlanComparison_Output(t *testing.T) {
+	comparison := models.PlanComparison{
+		ComparePull: 12,
+		Diffs: []models.ResourceChangeDiff{
+			{Address: "null_resource.a", CompareAction: "delete"},
+			{Address: "null_resource.b", Action: "create"},
+			{Address: "null_resource.c", Action: "update", CompareAction: "replace"},
+			{Address: "null_resource.d", Action: "update", CompareAction: "update"},
+		},
+	}
+	Equals(t, `- null_resource.a: delete only in #12
++ null_resource.b: create only in this pull request
+! null_resource.c: update in this pull request, replace in #12
+! null_resource.d: update in both with different values`, comparison.Output())
+}
+
+func TestPlanComparer_Compare(t *testing.T) {
+	RegisterMockTestingT(t)
+	repo := models.Repo{FullName: "owner/repo"}
+	project := models.ProjectStatus{RepoRelDir: "dir", Workspace: "default", ProjectName: "proj"}
+	pullDirs := map[int]string{1: t.TempDir(), 2: t.TempDir(), 3: t.TempDir()}
+	plans := map[string]string{
+		filepath.Join(pullDirs[1], "dir"): `{"resource_changes":[{"address":"null_resource.a","change":{"actions":["create"]}}]}`,
+		filepath.Join(pullDirs[2], "dir"): `{"resource_changes":[{"address":"null_resource.a","change":{"actions":["delete","create"]}}]}`,
+	}
+	for num, dir := range pullDirs {
+		Ok(t, os.MkdirAll(filepath.Join(dir, "dir"), 0700))
+		// Pull request 3 has been cloned but not planned.
+		if num != 3 {
+			Ok(t, os.WriteFile(filepath.Join(dir, "dir", "proj-default.tfplan"), []byte("plan"), 0600))
+		}
+	}
+
+	workingDir := mocks.NewMockWorkingDir()
+	When(workingDir.GetWorkingDir(Any[models.Repo](), Any[models.PullRequest](), Eq("default"))).Then(func(params []Param) ReturnValues {
+		if dir, ok := pullDirs[params[1].(models.PullRequest).Num]; ok {
+			return []ReturnValue{dir, nil}
+		}
+		return []ReturnValue{"", os.ErrNotExist}
+	})
+	tfClient := tmocks.NewMockClient()
+	When(tfClient.RunCommandWithVersion(Any[command.ProjectContext](), Any[string](), Eq([]string{"show", "-json", "proj-default.tfplan"}), Any[map[string]string](), Any[*version.Version](), Eq("default"))).Then(func(params []Param) ReturnValues {
+		return []ReturnValue{plans[params[1].(string)], nil}
+	})
+
+	comparer := events.PlanComparer{
+		WorkingDir:        workingDir,
+		WorkingDirLocker:  events.NewDefaultWorkingDirLocker(),
+		TerraformExecutor: tfClient,
+	}
+	ctx := &command.Context{
+		Log:  logging.NewNoopLogger(t),
+		Pull: models.PullRequest{Num: 1, BaseRepo: repo},
+	}
+
+	comparison, err := comparer.Compare(ctx, project, 2)
+	Ok(t, err)
+	Equals(t, &models.PlanComparison{
+		ComparePull: 2,
+		Diffs:       []models.ResourceChangeDiff{{Address: "null_resource.a", Action: "create", CompareAction: "replace"}},
+	}, comparison)
+
+	_, err = comparer.Compare(ctx, project, 3)
+	ErrEquals(t, `no plan found for dir "dir" workspace "default" in pull request #3, it must be planned first`, err)
+
+	_, err = comparer.Compare(ctx, project, 4)
+	ErrEquals(t, `no plan found for dir "dir" workspace "default" in pull request #4, it must be planned first`, err)
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// GistCreator creates gists.
+type GistCreator interface {
+	// CreateGist creates a gist with a single file and returns its URL.
+	CreateGist(description string, filename string, content string) (string, error)
+}
+
+// PlanGistUploader uploads plan output that's too large to comment on a
+// GitHub pull request to a gist so the comment can link to it instead.
+// A nil *PlanGistUploader uploads nothing.
+type PlanGistUploader struct {
+	GistCreator GistCreator
+	// Threshold is the length of plan output, in characters, above which
+	// it's uploaded to a gist.
+	Threshold int
+}
+
+// planGistTruncatedNote is prepended to plan output that was truncated
+// because it couldn't be uploaded to a gist.
+const planGistTruncatedNote = "... %d characters truncated, the plan output was too large to comment ...\n"
+
+// Upload uploads the output of each successful plan in res that's longer
+// than the threshold to a gist and sets its GistURL. If the gist can't be
+// created the output is truncated instead so that the comment stays small.
+func (p *PlanGistUploader) Upload(ctx *command.Context, res *command.Result) {
+	if p == nil || p.Threshold <= 0 || ctx.Pull.BaseRepo.VCSHost.Type != models.Github {
+		return
+	}
+	for _, result := range res.ProjectResults {
+		planSuccess := result.PlanSuccess
+		if planSuccess == nil || len(planSuccess.TerraformOutput) <= p.Threshold {
+			continue
+		}
+		description := fmt.Sprintf("Atlantis plan for %s#%d, dir: %s, workspace: %s",
+			ctx.Pull.BaseRepo.FullName, ctx.Pull.Num, result.RepoRelDir, result.Workspace)
+		gistURL, err := p.GistCreator.CreateGist(description, planGistFilename(result), planSuccess.TerraformOutput)
+		if err != nil {
+			ctx.Log.Warn("unable to upload plan output for dir %q workspace %q to a gist, truncating it instead: %s", result.RepoRelDir, result.Workspace, err)
+			planSuccess.TerraformOutput = truncatePlanOutput(planSuccess.TerraformOutput, p.Threshold)
+			continue
+		}
+		planSuccess.GistURL = gistURL
+	}
+}
+
+// planGistFilename returns the name of the gist file for result. The .diff
+// extension makes GitHub highlight it like the comments do.
+func planGistFilename(result command.ProjectResult) string {
+	name := result.ProjectName
+	if name == "" {
+		name = strings.ReplaceAll(result.RepoRelDir, "/", "_")
+	}
+	return fmt.Sprintf("%s-%s.diff", name, result.Workspace)
+}
+
+// truncatePlanOutput keeps the end of output, which has the plan summary,
+// so that it's at most maxLen characters long plus a note.
+func truncatePlanOutput(output string, maxLen int) string {
+	if len(output) <= maxLen {
+		return output
+	}
+	cut := len(output) - maxLen
+	// Don't start in the middle of a line.
+	if i := strings.IndexByte(output[cut:], '\n'); i >= 0 {
+		cut += i + 1
+	}
+	return fmt.Sprintf(planGistTruncatedNote, cut) + output[cut:]
+}
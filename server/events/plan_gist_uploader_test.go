@@ -0,0 +1,103 @@
+package events_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+type fakeGistCreator struct {
+	url       string
+	err       error
+	filenames []string
+}
+
+func (f *fakeGistCreator) CreateGist(_ string, filename string, _ string) (string, error) {
+	f.filenames = append(f.filenames, filename)
+	return f.url, f.err
+}
+
+func TestPlanGistUploader_Upload(t *testing.T) {
+	largeOutput := strings.Repeat("+ resource\n", 10) + "Plan: 10 to add, 0 to change, 0 to destroy."
+	cases := []struct {
+		description  string
+		vcsHost      models.VCSHostType
+		output       string
+		gistErr      error
+		expGistURL   string
+		expOutput    string
+		expFilenames []string
+	}{
+		{
+			description: "output below threshold",
+			vcsHost:     models.Github,
+			output:      "Plan: 1 to add, 0 to change, 0 to destroy.",
+			expOutput:   "Plan: 1 to add, 0 to change, 0 to destroy.",
+		},
+		{
+			description:  "output above threshold is uploaded",
+			vcsHost:      models.Github,
+			output:       largeOutput,
+			expGistURL:   "https://gist.github.com/abc123",
+			expOutput:    largeOutput,
+			expFilenames: []string{"path_to_project-default.diff"},
+		},
+		{
+			description:  "gist creation fails so output is truncated",
+			vcsHost:      models.Github,
+			output:       largeOutput,
+			gistErr:      errors.New("403 Resource not accessible by integration"),
+			expOutput:    "... 99 characters truncated, the plan output was too large to comment ...\n+ resource\nPlan: 10 to add, 0 to change, 0 to destroy.",
+			expFilenames: []string{"path_to_project-default.diff"},
+		},
+		{
+			description: "not github",
+			vcsHost:     models.Gitlab,
+			output:      largeOutput,
+			expOutput:   largeOutput,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			creator := &fakeGistCreator{url: "https://gist.github.com/abc123", err: c.gistErr}
+			uploader := &events.PlanGistUploader{
+				GistCreator: creator,
+				Threshold:   60,
+			}
+			ctx := &command.Context{
+				Log: logging.NewNoopLogger(t),
+				Pull: models.PullRequest{
+					Num:      1,
+					BaseRepo: models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Type: c.vcsHost}},
+				},
+			}
+			planSuccess := &models.PlanSuccess{TerraformOutput: c.output}
+			res := command.Result{
+				ProjectResults: []command.ProjectResult{
+					{RepoRelDir: "path/to/project", Workspace: "default", PlanSuccess: planSuccess},
+					{RepoRelDir: "failed", Workspace: "default", Error: errors.New("error")},
+				},
+			}
+
+			uploader.Upload(ctx, &res)
+			Equals(t, c.expGistURL, planSuccess.GistURL)
+			Equals(t, c.expOutput, planSuccess.TerraformOutput)
+			Equals(t, c.expFilenames, creator.filenames)
+		})
+	}
+}
+
+func TestPlanGistUploader_NilUploadsNothing(t *testing.T) {
+	var uploader *events.PlanGistUploader
+	planSuccess := &models.PlanSuccess{TerraformOutput: strings.Repeat("+ resource\n", 100)}
+	res := command.Result{ProjectResults: []command.ProjectResult{{PlanSuccess: planSuccess}}}
+	uploader.Upload(&command.Context{}, &res)
+	Equals(t, "", planSuccess.GistURL)
+}
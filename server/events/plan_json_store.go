@@ -0,0 +1,120 @@
+package events
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/runtime"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// PlanJSONStore stores the JSON of plans, from `terraform show -json`, so that
+// tools can download them from the link in the plan comment.
+type PlanJSONStore interface {
+	// Save stores planJSON for project of pull, replacing the project's
+	// earlier plan JSON, and returns its id. project identifies the project
+	// within pull, ex. by its dir, workspace and name.
+	Save(pull models.PullRequest, project string, planJSON []byte) (string, error)
+	// Get returns the plan JSON with id. It returns an error wrapping
+	// os.ErrNotExist if there's none.
+	Get(id string) ([]byte, error)
+	// DeleteForPull deletes the plan JSON of all the projects of pull.
+	DeleteForPull(pull models.PullRequest) error
+}
+
+// PlanJSONURLGenerator generates urls to plan JSON.
+type PlanJSONURLGenerator interface {
+	// GeneratePlanJSONURL returns the full URL to the plan JSON with id.
+	GeneratePlanJSONURL(id string) string
+}
+
+// planJSONIDRegex matches the ids of FilePlanJSONStore, which are the keys of
+// the pull request and the project followed by a random UUID.
+var planJSONIDRegex = regexp.MustCompile(`^[0-9a-f]{16}-[0-9a-f]{16}-[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// FilePlanJSONStore stores plan JSON as files in Dir. The ids are random so
+// that the links to the plans can't be guessed. Dir is local to the Atlantis
+// server so plan JSON can only be downloaded from the server that planned it
+// unless Dir is shared.
+type FilePlanJSONStore struct {
+	Dir string
+	// PlanEncryptor encrypts the plan JSON at rest since it contains the
+	// same sensitive values as the planfiles. If nil, it's stored in
+	// plaintext.
+	PlanEncryptor *runtime.PlanEncryptor
+}
+
+// Save stores planJSON in a new file named after its id and deletes the
+// project's earlier plan JSON.
+func (f *FilePlanJSONStore) Save(pull models.PullRequest, project string, planJSON []byte) (string, error) {
+	if err := os.MkdirAll(f.Dir, 0700); err != nil {
+		return "", errors.Wrap(err, "creating plan json dir")
+	}
+	content, err := f.PlanEncryptor.Encrypt(planJSON)
+	if err != nil {
+		return "", errors.Wrap(err, "encrypting plan json")
+	}
+	prefix := fmt.Sprintf("%s-%s", f.pullKey(pull), f.key(project))
+	superseded, err := filepath.Glob(filepath.Join(f.Dir, prefix+"-*.json"))
+	if err != nil {
+		return "", err
+	}
+	id := fmt.Sprintf("%s-%s", prefix, uuid.NewString())
+	if err := os.WriteFile(f.path(id), content, 0600); err != nil {
+		return "", errors.Wrap(err, "writing plan json")
+	}
+	for _, file := range superseded {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return "", errors.Wrap(err, "deleting superseded plan json")
+		}
+	}
+	return id, nil
+}
+
+// Get reads and decrypts the file of the plan JSON with id.
+func (f *FilePlanJSONStore) Get(id string) ([]byte, error) {
+	if !planJSONIDRegex.MatchString(id) {
+		return nil, fmt.Errorf("invalid plan json id %q: %w", id, os.ErrNotExist)
+	}
+	content, err := os.ReadFile(f.path(id))
+	if err != nil {
+		return nil, err
+	}
+	return f.PlanEncryptor.Decrypt(content)
+}
+
+// DeleteForPull deletes the files of the plan JSON of pull.
+func (f *FilePlanJSONStore) DeleteForPull(pull models.PullRequest) error {
+	files, err := filepath.Glob(filepath.Join(f.Dir, f.pullKey(pull)+"-*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "deleting plan json")
+		}
+	}
+	return nil
+}
+
+// pullKey returns the prefix of the ids of pull's plan JSON. It's a hash so
+// that ids don't reveal the repo.
+func (f *FilePlanJSONStore) pullKey(pull models.PullRequest) string {
+	return f.key(fmt.Sprintf("%s#%d", pull.BaseRepo.ID(), pull.Num))
+}
+
+// key returns a short hash of s for ids.
+func (f *FilePlanJSONStore) key(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func (f *FilePlanJSONStore) path(id string) string {
+	return filepath.Join(f.Dir, id+".json")
+}
@@ -0,0 +1,88 @@
+package events_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/core/runtime"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestFilePlanJSONStore_Encrypted(t *testing.T) {
+	encryptor, err := runtime.NewPlanEncryptor("key", nil)
+	Ok(t, err)
+	store := &events.FilePlanJSONStore{Dir: t.TempDir(), PlanEncryptor: encryptor}
+	pull := models.PullRequest{Num: 1, BaseRepo: models.Repo{FullName: "owner/repo"}}
+
+	id, err := store.Save(pull, "./default/", []byte(`{"format_version":"1.2"}`))
+	Ok(t, err)
+	content, err := os.ReadFile(filepath.Join(store.Dir, id+".json"))
+	Ok(t, err)
+	Assert(t, !strings.Contains(string(content), "format_version"), "exp plan json to be encrypted, got %s", content)
+
+	planJSON, err := store.Get(id)
+	Ok(t, err)
+	Equals(t, `{"format_version":"1.2"}`, string(planJSON))
+}
+
+func TestFilePlanJSONStore(t *testing.T) {
+	store := &events.FilePlanJSONStore{Dir: t.TempDir()}
+	repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com", Type: models.Github}}
+	pull := models.PullRequest{Num: 1, BaseRepo: repo}
+	otherPull := models.PullRequest{Num: 2, BaseRepo: repo}
+
+	id, err := store.Save(pull, "./default/", []byte(`{"format_version":"1.2"}`))
+	Ok(t, err)
+	otherID, err := store.Save(otherPull, "./default/", []byte(`{"format_version":"1.1"}`))
+	Ok(t, err)
+	Assert(t, id != otherID, "exp unique ids")
+
+	planJSON, err := store.Get(id)
+	Ok(t, err)
+	Equals(t, `{"format_version":"1.2"}`, string(planJSON))
+
+	t.Run("unknown id", func(t *testing.T) {
+		_, err := store.Get("0123456789abcdef-0123456789abcdef-00000000-0000-0000-0000-000000000000")
+		Assert(t, errors.Is(err, os.ErrNotExist), "exp not exist error, got %v", err)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		_, err := store.Get("../../etc/passwd")
+		Assert(t, errors.Is(err, os.ErrNotExist), "exp not exist error, got %v", err)
+	})
+
+	t.Run("replan replaces the project's plan json", func(t *testing.T) {
+		otherProjectID, err := store.Save(pull, "dir/default/", []byte(`{"format_version":"1.0"}`))
+		Ok(t, err)
+		replannedID, err := store.Save(otherPull, "./default/", []byte(`{"format_version":"1.3"}`))
+		Ok(t, err)
+		_, err = store.Get(otherID)
+		Assert(t, errors.Is(err, os.ErrNotExist), "exp not exist error, got %v", err)
+		planJSON, err := store.Get(replannedID)
+		Ok(t, err)
+		Equals(t, `{"format_version":"1.3"}`, string(planJSON))
+		planJSON, err = store.Get(id)
+		Ok(t, err)
+		Equals(t, `{"format_version":"1.2"}`, string(planJSON))
+		Ok(t, os.Remove(filepath.Join(store.Dir, otherProjectID+".json")))
+		otherID = replannedID
+	})
+
+	t.Run("delete for pull", func(t *testing.T) {
+		Ok(t, store.DeleteForPull(pull))
+		_, err := store.Get(id)
+		Assert(t, errors.Is(err, os.ErrNotExist), "exp not exist error, got %v", err)
+		planJSON, err := store.Get(otherID)
+		Ok(t, err)
+		Equals(t, `{"format_version":"1.3"}`, string(planJSON))
+	})
+
+	t.Run("delete for pull without plan json", func(t *testing.T) {
+		Ok(t, (&events.FilePlanJSONStore{Dir: t.TempDir() + "/missing"}).DeleteForPull(pull))
+	})
+}
@@ -0,0 +1,49 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// PlanRateLimiter limits how often plans run for each repo. Each repo has a
+// token bucket that holds up to PerMinute plans and is refilled at PerMinute
+// plans per minute, so bursts are allowed as long as the rate stays below it.
+type PlanRateLimiter struct {
+	perMinute int
+	mu        sync.Mutex
+	limiters  map[string]*rate.Limiter
+	// now returns the current time. It's overridden in tests.
+	now func() time.Time
+}
+
+// NewPlanRateLimiter returns a PlanRateLimiter allowing perMinute plans per
+// minute for each repo.
+func NewPlanRateLimiter(perMinute int) *PlanRateLimiter {
+	return &PlanRateLimiter{
+		perMinute: perMinute,
+		limiters:  make(map[string]*rate.Limiter),
+		now:       time.Now,
+	}
+}
+
+// Allow takes a plan from the bucket of repoFullName. If the bucket is empty
+// it returns false and how long until a plan can run.
+func (l *PlanRateLimiter) Allow(repoFullName string) (bool, time.Duration) {
+	l.mu.Lock()
+	limiter, ok := l.limiters[repoFullName]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Every(time.Minute/time.Duration(l.perMinute)), l.perMinute)
+		l.limiters[repoFullName] = limiter
+	}
+	l.mu.Unlock()
+
+	now := l.now()
+	reservation := limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
@@ -0,0 +1,41 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestPlanRateLimiter_Allow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewPlanRateLimiter(2)
+	limiter.now = func() time.Time { return now }
+
+	// The bucket starts full so a burst up to the limit is allowed.
+	for i := 0; i < 2; i++ {
+		ok, wait := limiter.Allow("owner/repo")
+		Assert(t, ok, "exp plan %d to be allowed", i)
+		Equals(t, time.Duration(0), wait)
+	}
+	ok, wait := limiter.Allow("owner/repo")
+	Assert(t, !ok, "exp plan over the limit to be rejected")
+	Equals(t, 30*time.Second, wait)
+
+	// Other repos have their own bucket.
+	ok, _ = limiter.Allow("owner/other")
+	Assert(t, ok, "exp plan of other repo to be allowed")
+
+	// Rejected plans don't take from the bucket, so it's refilled at the
+	// limit's rate.
+	now = now.Add(20 * time.Second)
+	ok, wait = limiter.Allow("owner/repo")
+	Assert(t, !ok, "exp plan before refill to be rejected")
+	Equals(t, 10*time.Second, wait)
+
+	now = now.Add(10 * time.Second)
+	ok, _ = limiter.Allow("owner/repo")
+	Assert(t, ok, "exp plan after refill to be allowed")
+	ok, _ = limiter.Allow("owner/repo")
+	Assert(t, !ok, "exp second plan after refill to be rejected")
+}
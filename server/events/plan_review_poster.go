@@ -0,0 +1,50 @@
+package events
+
+import (
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// ReviewCreator creates pull request reviews.
+type ReviewCreator interface {
+	// CreateCommentReview adds a review that comments body on the pull request
+	// without approving it or requesting changes.
+	CreateCommentReview(repo models.Repo, pullNum int, body string) error
+	// HidePrevPlanReviews collapses the plan reviews that were added to the
+	// pull request before, since reviews can't be minimized like comments.
+	HidePrevPlanReviews(repo models.Repo, pullNum int) error
+}
+
+// PlanReviewPoster posts plan comments on GitHub pull requests as a single
+// review instead of an issue comment.
+// A nil *PlanReviewPoster posts nothing.
+type PlanReviewPoster struct {
+	ReviewCreator ReviewCreator
+	// HidePrevPlanReviews is true if the earlier plan reviews are collapsed
+	// when a plan is posted, like earlier comments with
+	// --hide-prev-plan-comments.
+	HidePrevPlanReviews bool
+}
+
+// Post posts comments, the plan comments of the projects that were planned,
+// as reviews. There's more than one comment if the projects are commented by
+// their code owners. It returns how many of the comments it posted, ex. 0
+// because the pull request isn't on GitHub, in which case the caller must
+// comment the others instead.
+func (p *PlanReviewPoster) Post(ctx *command.Context, comments ...string) int {
+	if p == nil || ctx.Pull.BaseRepo.VCSHost.Type != models.Github {
+		return 0
+	}
+	if p.HidePrevPlanReviews {
+		if err := p.ReviewCreator.HidePrevPlanReviews(ctx.Pull.BaseRepo, ctx.Pull.Num); err != nil {
+			ctx.Log.Err("unable to hide old plan reviews: %s", err)
+		}
+	}
+	for i, comment := range comments {
+		if err := p.ReviewCreator.CreateCommentReview(ctx.Pull.BaseRepo, ctx.Pull.Num, comment); err != nil {
+			ctx.Log.Warn("unable to post plan as a review, commenting it instead: %s", err)
+			return i
+		}
+	}
+	return len(comments)
+}
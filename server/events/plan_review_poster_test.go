@@ -0,0 +1,91 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+type fakeReviewCreator struct {
+	err     error
+	reviews []string
+	hidden  int
+}
+
+func (f *fakeReviewCreator) HidePrevPlanReviews(_ models.Repo, _ int) error {
+	f.hidden += len(f.reviews)
+	return nil
+}
+
+func (f *fakeReviewCreator) CreateCommentReview(_ models.Repo, _ int, body string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.reviews = append(f.reviews, body)
+	return nil
+}
+
+func TestPlanReviewPoster(t *testing.T) {
+	ctx := &command.Context{
+		Log: logging.NewNoopLogger(t),
+		Pull: models.PullRequest{
+			Num:      1,
+			BaseRepo: models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Type: models.Github}},
+		},
+	}
+	creator := &fakeReviewCreator{}
+	poster := &events.PlanReviewPoster{ReviewCreator: creator}
+
+	Equals(t, 1, poster.Post(ctx, "plan 1"))
+	Equals(t, []string{"plan 1"}, creator.reviews)
+	Equals(t, 0, creator.hidden)
+
+	// The comments of each code owners are posted as separate reviews.
+	Equals(t, 2, poster.Post(ctx, "plan 2", "plan 3"))
+	Equals(t, []string{"plan 1", "plan 2", "plan 3"}, creator.reviews)
+
+	// If the review can't be created, ex. because it's too long, the caller
+	// comments it.
+	creator.err = errors.New("review of 70000 characters is longer than the maximum of 65536")
+	Equals(t, 0, poster.Post(ctx, "plan 4"))
+	Equals(t, []string{"plan 1", "plan 2", "plan 3"}, creator.reviews)
+}
+
+func TestPlanReviewPoster_HidePrevPlanReviews(t *testing.T) {
+	ctx := &command.Context{
+		Log: logging.NewNoopLogger(t),
+		Pull: models.PullRequest{
+			Num:      1,
+			BaseRepo: models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Type: models.Github}},
+		},
+	}
+	creator := &fakeReviewCreator{}
+	poster := &events.PlanReviewPoster{ReviewCreator: creator, HidePrevPlanReviews: true}
+
+	Equals(t, 1, poster.Post(ctx, "plan 1"))
+	Equals(t, 0, creator.hidden)
+	Equals(t, 1, poster.Post(ctx, "plan 2"))
+	Equals(t, 1, creator.hidden)
+}
+
+func TestPlanReviewPoster_Unsupported(t *testing.T) {
+	ctx := &command.Context{
+		Log: logging.NewNoopLogger(t),
+		Pull: models.PullRequest{
+			Num:      1,
+			BaseRepo: models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Type: models.Gitlab}},
+		},
+	}
+	creator := &fakeReviewCreator{}
+	poster := &events.PlanReviewPoster{ReviewCreator: creator}
+	Equals(t, 0, poster.Post(ctx, "plan"))
+	Equals(t, 0, len(creator.reviews))
+
+	var disabled *events.PlanReviewPoster
+	Equals(t, 0, disabled.Post(ctx, "plan"))
+}
@@ -37,7 +37,18 @@ type PolicyCheckCommandRunner struct {
 	quietPolicyChecks          bool
 }
 
+// Run checks the plans of cmds against their policies and comments the
+// results.
 func (p *PolicyCheckCommandRunner) Run(ctx *command.Context, cmds []command.ProjectContext) {
+	result, ok := p.runWithoutComment(ctx, cmds)
+	if ok && !p.isQuiet(result) {
+		p.pullUpdater.updatePull(ctx, PolicyCheckCommand{}, result)
+	}
+}
+
+// runWithoutComment is like Run but returns the results instead of
+// commenting them. It returns false if there were no projects to check.
+func (p *PolicyCheckCommandRunner) runWithoutComment(ctx *command.Context, cmds []command.ProjectContext) (command.Result, bool) {
 	if len(cmds) == 0 {
 		ctx.Log.Info("no projects to run policy_check in")
 		if !p.silenceVCSStatusNoProjects {
@@ -49,7 +60,7 @@ func (p *PolicyCheckCommandRunner) Run(ctx *command.Context, cmds []command.Proj
 				ctx.Log.Warn("unable to update commit status: %s", err)
 			}
 		}
-		return
+		return command.Result{}, false
 	}
 
 	// So set policy_check commit status to pending
@@ -65,17 +76,19 @@ func (p *PolicyCheckCommandRunner) Run(ctx *command.Context, cmds []command.Proj
 		result = runProjectCmds(cmds, p.prjCmdRunner.PolicyCheck)
 	}
 
-	// Quiet policy checks unless there's an error
-	if result.HasErrors() || !p.quietPolicyChecks {
-		p.pullUpdater.updatePull(ctx, PolicyCheckCommand{}, result)
-	}
-
 	pullStatus, err := p.dbUpdater.updateDB(ctx, ctx.Pull, result.ProjectResults)
 	if err != nil {
 		ctx.Log.Err("writing results: %s", err)
 	}
 
 	p.updateCommitStatus(ctx, pullStatus)
+	return result, true
+}
+
+// isQuiet returns true if the policy check results shouldn't be commented,
+// which with --quiet-policy-checks is unless there's an error.
+func (p *PolicyCheckCommandRunner) isQuiet(result command.Result) bool {
+	return p.quietPolicyChecks && !result.HasErrors()
 }
 
 func (p *PolicyCheckCommandRunner) updateCommitStatus(ctx *command.Context, pullStatus models.PullStatus) {
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"github.com/runatlantis/atlantis/server/events/command"
+)
+
+// PolicyCheckCommentOrder is where the results of the policy checks that run
+// after a plan are commented relative to the plan comment.
+type PolicyCheckCommentOrder string
+
+const (
+	// AfterPlanPolicyCheckCommentOrder comments the policy check results in
+	// their own comment after the plan comment.
+	AfterPlanPolicyCheckCommentOrder PolicyCheckCommentOrder = "after"
+	// BeforePlanPolicyCheckCommentOrder comments the policy check results in
+	// their own comment before the plan comment.
+	BeforePlanPolicyCheckCommentOrder PolicyCheckCommentOrder = "before"
+	// MergedPolicyCheckCommentOrder adds the policy check result of each
+	// project below its plan in the plan comment.
+	MergedPolicyCheckCommentOrder PolicyCheckCommentOrder = "merged"
+)
+
+// commentsPlanFirst returns true if the plan comment is posted before the
+// policy checks run.
+func (p *PlanCommandRunner) commentsPlanFirst() bool {
+	return p.PolicyCheckCommentOrder == "" || p.PolicyCheckCommentOrder == AfterPlanPolicyCheckCommentOrder
+}
+
+// runPolicyChecks runs policyCheckCmds for the plans in result. Unless the
+// plans were already commented, they're commented before or together with
+// the policy check results, depending on PolicyCheckCommentOrder.
+func (p *PlanCommandRunner) runPolicyChecks(ctx *command.Context, cmd PullCommand, result command.Result, policyCheckCmds []command.ProjectContext) {
+	switch p.PolicyCheckCommentOrder {
+	case BeforePlanPolicyCheckCommentOrder:
+		p.policyCheckCommandRunner.Run(ctx, policyCheckCmds)
+		p.pullUpdater.updatePull(ctx, cmd, result)
+	case MergedPolicyCheckCommentOrder:
+		policyCheckResult, ok := p.policyCheckCommandRunner.runWithoutComment(ctx, policyCheckCmds)
+		if ok && !p.policyCheckCommandRunner.isQuiet(policyCheckResult) {
+			result = mergePolicyCheckResults(result, policyCheckResult)
+		}
+		p.pullUpdater.updatePull(ctx, cmd, result)
+	default:
+		p.policyCheckCommandRunner.Run(ctx, policyCheckCmds)
+	}
+}
+
+// mergePolicyCheckResults returns planResult with the result of each
+// project in policyCheckResult set as MergedPolicyCheck of the project's
+// plan result.
+func mergePolicyCheckResults(planResult command.Result, policyCheckResult command.Result) command.Result {
+	projectResults := make([]command.ProjectResult, len(planResult.ProjectResults))
+	copy(projectResults, planResult.ProjectResults)
+	for i := range policyCheckResult.ProjectResults {
+		policyCheck := policyCheckResult.ProjectResults[i]
+		for j := range projectResults {
+			if projectResults[j].RepoRelDir == policyCheck.RepoRelDir &&
+				projectResults[j].Workspace == policyCheck.Workspace &&
+				projectResults[j].ProjectName == policyCheck.ProjectName {
+				projectResults[j].MergedPolicyCheck = &policyCheck
+				break
+			}
+		}
+	}
+	planResult.ProjectResults = projectResults
+	return planResult
+}
@@ -34,6 +34,12 @@ type DefaultPostWorkflowHooksCommandRunner struct {
 	PostWorkflowHookRunner runtime.PostWorkflowHookRunner
 	CommitStatusUpdater    CommitStatusUpdater
 	Router                 PostWorkflowHookURLGenerator
+	// MarkdownRenderer, if set, renders the comment posted on the pull
+	// request when a hook fails.
+	MarkdownRenderer *MarkdownRenderer
+	// DefaultShellArgs are the shell args of hooks that don't set shellArgs.
+	// If empty, "-c" is used.
+	DefaultShellArgs string
 }
 
 // RunPostHooks runs post_workflow_hooks after a plan/apply has completed
@@ -77,6 +83,13 @@ func (w *DefaultPostWorkflowHooksCommandRunner) RunPostHooks(
 		escapedArgs = escapeArgs(cmd.Flags)
 	}
 
+	headCommit, err := headCommitMetadata(repoDir, pull)
+	if err != nil {
+		log.Warn("unable to read head commit for post workflow hooks: %s", err)
+	}
+
+	cleanEnv, allowedEnvVars := w.GlobalCfg.CleanEnv(baseRepo.ID())
+
 	err = w.runHooks(
 		models.WorkflowHookCommandContext{
 			BaseRepo:           baseRepo,
@@ -87,8 +100,11 @@ func (w *DefaultPostWorkflowHooksCommandRunner) RunPostHooks(
 			Verbose:            false,
 			EscapedCommentArgs: escapedArgs,
 			CommandName:        cmd.Name.String(),
+			HeadCommit:         headCommit,
+			CleanEnv:           cleanEnv,
+			AllowedEnvVars:     allowedEnvVars,
 		},
-		postWorkflowHooks, repoDir)
+		postWorkflowHooks, ctx.ProjectResults, repoDir)
 
 	if err != nil {
 		return err
@@ -100,9 +116,10 @@ func (w *DefaultPostWorkflowHooksCommandRunner) RunPostHooks(
 func (w *DefaultPostWorkflowHooksCommandRunner) runHooks(
 	ctx models.WorkflowHookCommandContext,
 	postWorkflowHooks []*valid.WorkflowHook,
+	projectResults []command.ProjectResult,
 	repoDir string,
 ) error {
-
+	authors := &hookAuthorMatcher{vcsClient: w.VCSClient, ctx: ctx}
 	for i, hook := range postWorkflowHooks {
 		hookDescription := hook.StepDescription
 		if hookDescription == "" {
@@ -116,40 +133,90 @@ func (w *DefaultPostWorkflowHooksCommandRunner) runHooks(
 				hookDescription, ctx.CommandName, hook.Commands)
 			continue
 		}
-
-		ctx.Log.Debug("Running post workflow hook: '%s'", hookDescription)
-		ctx.HookID = uuid.NewString()
-		shell := hook.Shell
-		if shell == "" {
-			ctx.Log.Debug("Setting shell to default: %q", shell)
-			shell = "sh"
-		}
-		shellArgs := hook.ShellArgs
-		if shellArgs == "" {
-			ctx.Log.Debug("Setting shellArgs to default: %q", shellArgs)
-			shellArgs = "-c"
-		}
-		url, err := w.Router.GenerateProjectWorkflowHookURL(ctx.HookID)
+		runsForUser, err := authors.runsHook(hook)
 		if err != nil {
-			return err
+			return fmt.Errorf("post workflow hook '%s': %w", hookDescription, err)
 		}
-
-		if err := w.CommitStatusUpdater.UpdatePostWorkflowHook(ctx.Pull, models.PendingCommitStatus, hookDescription, "", url); err != nil {
-			ctx.Log.Warn("unable to update post workflow hook status: %s", err)
+		if !runsForUser {
+			ctx.Log.Debug("Skipping post workflow hook '%s' as it doesn't run for user %s", hookDescription, ctx.User.Username)
+			continue
 		}
 
-		_, runtimeDesc, err := w.PostWorkflowHookRunner.Run(ctx, hook.RunCommand, shell, shellArgs, repoDir)
+		if !hook.PerProject {
+			if err := w.runHook(ctx, hook, hookDescription, repoDir); err != nil {
+				return err
+			}
+			continue
+		}
 
-		if err != nil {
-			if err := w.CommitStatusUpdater.UpdatePostWorkflowHook(ctx.Pull, models.FailedCommitStatus, hookDescription, runtimeDesc, url); err != nil {
-				ctx.Log.Warn("unable to update post workflow hook status: %s", err)
+		// Project-scoped hooks run once for each project the command ran for.
+		for _, result := range projectResults {
+			projectCtx := ctx
+			projectCtx.ProjectName = result.ProjectName
+			projectCtx.Workspace = result.Workspace
+			projectCtx.RepoRelDir = result.RepoRelDir
+			projectDescription := fmt.Sprintf("%s (dir: %s, workspace: %s)", hookDescription, result.RepoRelDir, result.Workspace)
+			if result.ProjectName != "" {
+				projectDescription = fmt.Sprintf("%s (project: %s)", hookDescription, result.ProjectName)
+			}
+			if err := w.runHook(projectCtx, hook, projectDescription, repoDir); err != nil {
+				return err
 			}
-			return err
 		}
+	}
+	return nil
+}
+
+func (w *DefaultPostWorkflowHooksCommandRunner) runHook(
+	ctx models.WorkflowHookCommandContext,
+	hook *valid.WorkflowHook,
+	hookDescription string,
+	repoDir string,
+) error {
+	ctx.Log.Debug("Running post workflow hook: '%s'", hookDescription)
+	ctx.HookID = uuid.NewString()
+	ctx.Image = hook.Image
+	shell := hook.Shell
+	if shell == "" {
+		ctx.Log.Debug("Setting shell to default: %q", shell)
+		shell = "sh"
+	}
+	if err := validateHookShell(shell); err != nil {
+		return fmt.Errorf("post workflow hook '%s': %w", hookDescription, err)
+	}
+	shellArgs := hook.ShellArgs
+	if shellArgs == "" {
+		shellArgs = w.DefaultShellArgs
+		if shellArgs == "" {
+			shellArgs = "-c"
+		}
+		ctx.Log.Debug("Setting shellArgs to default: %q", shellArgs)
+	}
+	url, err := w.Router.GenerateProjectWorkflowHookURL(ctx.HookID)
+	if err != nil {
+		return err
+	}
+
+	if err := w.CommitStatusUpdater.UpdatePostWorkflowHook(ctx.Pull, models.PendingCommitStatus, hookDescription, "", url); err != nil {
+		ctx.Log.Warn("unable to update post workflow hook status: %s", err)
+	}
+
+	_, runtimeDesc, err := w.PostWorkflowHookRunner.Run(ctx, hook.RunCommand, shell, shellArgs, repoDir)
 
-		if err := w.CommitStatusUpdater.UpdatePostWorkflowHook(ctx.Pull, models.SuccessCommitStatus, hookDescription, runtimeDesc, url); err != nil {
+	if err != nil && hookExitedWithSuccessCode(hook, err) {
+		ctx.Log.Info("post workflow hook '%s' exited with a configured success code: %s", hookDescription, err)
+		err = nil
+	}
+	if err != nil {
+		if err := w.CommitStatusUpdater.UpdatePostWorkflowHook(ctx.Pull, models.FailedCommitStatus, hookDescription, runtimeDesc, url); err != nil {
 			ctx.Log.Warn("unable to update post workflow hook status: %s", err)
 		}
+		commentHookFailure(w.VCSClient, w.MarkdownRenderer, ctx, "Post workflow hook", hookDescription, runtimeDesc, url)
+		return err
+	}
+
+	if err := w.CommitStatusUpdater.UpdatePostWorkflowHook(ctx.Pull, models.SuccessCommitStatus, hookDescription, runtimeDesc, url); err != nil {
+		ctx.Log.Warn("unable to update post workflow hook status: %s", err)
 	}
 	return nil
 }
@@ -417,6 +417,39 @@ func TestRunPostHooks_Clone(t *testing.T) {
 		Assert(t, *unlockCalled == true, "unlock function called")
 	})
 
+	t.Run("configured default shellArgs passed to webhooks", func(t *testing.T) {
+		postWorkflowHooksSetup(t)
+		postWh.DefaultShellArgs = "-ec"
+
+		globalCfg := valid.GlobalCfg{
+			Repos: []valid.Repo{
+				{
+					ID: testdata.GithubRepo.ID(),
+					PostWorkflowHooks: []*valid.WorkflowHook{
+						&testHook,
+						&testHookWithShellArgs,
+					},
+				},
+			},
+		}
+
+		postWh.GlobalCfg = globalCfg
+
+		When(postWhWorkingDirLocker.TryLock(testdata.GithubRepo.FullName, newPull.Num, events.DefaultWorkspace, events.DefaultRepoRelDir)).ThenReturn(func() {}, nil)
+		When(postWhWorkingDir.Clone(testdata.GithubRepo, newPull, events.DefaultWorkspace)).ThenReturn(repoDir, false, nil)
+		When(whPostWorkflowHookRunner.Run(Any[models.WorkflowHookCommandContext](), Any[string](),
+			Any[string](), Any[string](), Eq(repoDir))).ThenReturn(result, runtimeDesc, nil)
+
+		err := postWh.RunPostHooks(ctx, planCmd)
+
+		Ok(t, err)
+		whPostWorkflowHookRunner.VerifyWasCalledOnce().Run(Any[models.WorkflowHookCommandContext](),
+			Eq(testHook.RunCommand), Eq(defaultShell), Eq("-ec"), Eq(repoDir))
+		// The hook's own shellArgs still override the default.
+		whPostWorkflowHookRunner.VerifyWasCalledOnce().Run(Any[models.WorkflowHookCommandContext](),
+			Eq(testHookWithShellArgs.RunCommand), Eq(defaultShell), Eq(testHookWithShellArgs.ShellArgs), Eq(repoDir))
+	})
+
 	t.Run("Commands 'plan' set on webhook and plan command", func(t *testing.T) {
 		preWorkflowHooksSetup(t)
 
@@ -518,4 +551,55 @@ func TestRunPostHooks_Clone(t *testing.T) {
 			Eq(testHookWithPlanApplyCommands.RunCommand), Any[string](), Any[string](), Eq(repoDir))
 		Assert(t, *unlockCalled == true, "unlock function called")
 	})
+
+	t.Run("per project hook runs once for each project", func(t *testing.T) {
+		postWorkflowHooksSetup(t)
+
+		testHookPerProject := valid.WorkflowHook{
+			StepName:   "test6",
+			RunCommand: "echo test6",
+			PerProject: true,
+		}
+
+		globalCfg := valid.GlobalCfg{
+			Repos: []valid.Repo{
+				{
+					ID: testdata.GithubRepo.ID(),
+					PostWorkflowHooks: []*valid.WorkflowHook{
+						&testHook,
+						&testHookPerProject,
+					},
+				},
+			},
+		}
+
+		postWh.GlobalCfg = globalCfg
+
+		projectCtx := *ctx
+		projectCtx.ProjectResults = []command.ProjectResult{
+			{ProjectName: "foo", RepoRelDir: "dir1", Workspace: "default"},
+			{RepoRelDir: "dir2", Workspace: "staging"},
+		}
+
+		When(postWhWorkingDirLocker.TryLock(testdata.GithubRepo.FullName, newPull.Num, events.DefaultWorkspace, events.DefaultRepoRelDir)).ThenReturn(func() {}, nil)
+		When(postWhWorkingDir.Clone(testdata.GithubRepo, newPull, events.DefaultWorkspace)).ThenReturn(repoDir, false, nil)
+		When(whPostWorkflowHookRunner.Run(Any[models.WorkflowHookCommandContext](), Any[string](),
+			Any[string](), Any[string](), Eq(repoDir))).ThenReturn(result, runtimeDesc, nil)
+
+		err := postWh.RunPostHooks(&projectCtx, planCmd)
+
+		Ok(t, err)
+		whPostWorkflowHookRunner.VerifyWasCalledOnce().Run(Any[models.WorkflowHookCommandContext](),
+			Eq(testHook.RunCommand), Eq(defaultShell), Eq(defaultShellArgs), Eq(repoDir))
+		hookCtxs, _, _, _, _ := whPostWorkflowHookRunner.VerifyWasCalled(Times(2)).Run(Any[models.WorkflowHookCommandContext](),
+			Eq(testHookPerProject.RunCommand), Eq(defaultShell), Eq(defaultShellArgs), Eq(repoDir)).GetAllCapturedArguments()
+		Equals(t, "foo", hookCtxs[0].ProjectName)
+		Equals(t, "dir1", hookCtxs[0].RepoRelDir)
+		Equals(t, "default", hookCtxs[0].Workspace)
+		Equals(t, "", hookCtxs[1].ProjectName)
+		Equals(t, "dir2", hookCtxs[1].RepoRelDir)
+		Equals(t, "staging", hookCtxs[1].Workspace)
+		postCommitStatusUpdater.VerifyWasCalledOnce().UpdatePostWorkflowHook(Any[models.PullRequest](), Eq(models.SuccessCommitStatus),
+			Eq("Post workflow hook #1 (project: foo)"), Any[string](), Any[string]())
+	})
 }
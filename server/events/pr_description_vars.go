@@ -0,0 +1,88 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// prDescriptionFrontMatterRegex matches a YAML front-matter block delimited by
+// "---" lines at the start of a pull request description.
+var prDescriptionFrontMatterRegex = regexp.MustCompile(`(?s)^---[ \t]*\n(.*?\n)?---[ \t]*(\n|$)`)
+
+// terraformVarNameRegex matches valid Terraform variable names.
+var terraformVarNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+
+// parsePRDescriptionVars parses the Terraform variables in the YAML
+// front-matter block at the start of the pull request description body, ex.
+//
+//	---
+//	instance_count: 3
+//	tags: {team: platform}
+//	---
+//
+// Strings, numbers and bools are passed as is and lists and maps as JSON,
+// which Terraform parses like HCL. It returns nil if body has no front-matter.
+func parsePRDescriptionVars(body string) (map[string]string, error) {
+	body = strings.TrimLeft(strings.ReplaceAll(body, "\r\n", "\n"), " \t\n")
+	match := prDescriptionFrontMatterRegex.FindStringSubmatch(body)
+	if match == nil {
+		return nil, nil
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal([]byte(match[1]), &values); err != nil {
+		return nil, errors.Wrap(err, "parsing front-matter")
+	}
+	vars := make(map[string]string, len(values))
+	for name, value := range values {
+		if !terraformVarNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("%q isn't a valid variable name", name)
+		}
+		switch v := value.(type) {
+		case nil:
+			return nil, fmt.Errorf("variable %q has no value", name)
+		case string:
+			vars[name] = v
+		case []interface{}, map[string]interface{}:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "encoding variable %q", name)
+			}
+			vars[name] = string(encoded)
+		default:
+			vars[name] = fmt.Sprint(v)
+		}
+	}
+	return vars, nil
+}
+
+// prDescriptionVarArgs returns the "-var" args for the Terraform variables in
+// the front-matter of the pull request description body, escaped like
+// comment args so they can be used within sh -c safely. Malformed front-matter
+// is logged and ignored so that it doesn't fail the plan.
+func prDescriptionVarArgs(log logging.SimpleLogging, body string) []string {
+	vars, err := parsePRDescriptionVars(body)
+	if err != nil {
+		log.Warn("ignoring variables in pull request description: %s", err)
+		return nil
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var args []string
+	for _, name := range names {
+		args = append(args, "-var")
+		args = append(args, escapeArgs([]string{fmt.Sprintf("%s=%s", name, vars[name])})...)
+	}
+	return args
+}
@@ -0,0 +1,94 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestParsePRDescriptionVars(t *testing.T) {
+	cases := []struct {
+		description string
+		body        string
+		exp         map[string]string
+		expErr      string
+	}{
+		{
+			description: "no front-matter",
+			body:        "Adds a bucket.\n\n---\nsize: 3\n---",
+		},
+		{
+			description: "empty body",
+			body:        "",
+		},
+		{
+			description: "empty front-matter",
+			body:        "---\n---\nAdds a bucket.",
+			exp:         map[string]string{},
+		},
+		{
+			description: "scalars",
+			body:        "---\nname: logs\nsize: 3\nratio: 0.5\nversioned: true\n---\nAdds a bucket.",
+			exp: map[string]string{
+				"name":      "logs",
+				"size":      "3",
+				"ratio":     "0.5",
+				"versioned": "true",
+			},
+		},
+		{
+			description: "lists and maps",
+			body:        "---\nzones: [a, b]\ntags:\n  team: platform\n---",
+			exp: map[string]string{
+				"zones": `["a","b"]`,
+				"tags":  `{"team":"platform"}`,
+			},
+		},
+		{
+			description: "windows line endings and leading whitespace",
+			body:        "\r\n---\r\nsize: 3\r\n---\r\nAdds a bucket.",
+			exp:         map[string]string{"size": "3"},
+		},
+		{
+			description: "malformed yaml",
+			body:        "---\nsize: [3\n---",
+			expErr:      "parsing front-matter: yaml: line 1: did not find expected ',' or ']'",
+		},
+		{
+			description: "not a map",
+			body:        "---\n- size\n---",
+			expErr:      "parsing front-matter: yaml: unmarshal errors:\n  line 1: cannot unmarshal !!seq into map[string]interface {}",
+		},
+		{
+			description: "invalid variable name",
+			body:        "---\n\"size; rm -rf /\": 3\n---",
+			expErr:      `"size; rm -rf /" isn't a valid variable name`,
+		},
+		{
+			description: "no value",
+			body:        "---\nsize:\n---",
+			expErr:      `variable "size" has no value`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			vars, err := parsePRDescriptionVars(c.body)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.exp, vars)
+		})
+	}
+}
+
+func TestPRDescriptionVarArgs(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	args := prDescriptionVarArgs(logger, "---\nsize: 3\nname: a b\n---")
+	Equals(t, []string{"-var", `\n\a\m\e\=\a\ \b`, "-var", `\s\i\z\e\=\3`}, args)
+
+	// Malformed front-matter is ignored.
+	Equals(t, []string(nil), prDescriptionVarArgs(logger, "---\nsize: [3\n---"))
+}
@@ -1,8 +1,15 @@
 package events
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/runatlantis/atlantis/server/core/config/valid"
@@ -34,6 +41,28 @@ type DefaultPreWorkflowHooksCommandRunner struct {
 	PreWorkflowHookRunner runtime.PreWorkflowHookRunner
 	CommitStatusUpdater   CommitStatusUpdater
 	Router                PreWorkflowHookURLGenerator
+	// MaxConcurrentHooks limits how many pull requests can be running their
+	// pre workflow hooks at the same time. 0 means unlimited.
+	MaxConcurrentHooks int
+	// StatusUpdateRetries is how many times a failed pre workflow hook
+	// commit status update is retried before the hook fails.
+	StatusUpdateRetries int
+	// StatusUpdateRetryDelay is how long to wait between status update
+	// retries.
+	StatusUpdateRetryDelay time.Duration
+	// MarkdownRenderer, if set, renders the comment posted on the pull
+	// request when a hook fails.
+	MarkdownRenderer *MarkdownRenderer
+	// ProjectCommandBuilder, if set, selects the projects that the command
+	// runs for, like the command itself, so hooks can read them from
+	// $PLANNED_PROJECTS.
+	ProjectCommandBuilder ProjectCommandBuilder
+	// DefaultShellArgs are the shell args of hooks that don't set shellArgs.
+	// If empty, "-c" is used.
+	DefaultShellArgs string
+
+	hookSlotsOnce sync.Once
+	hookSlots     chan struct{}
 }
 
 // RunPreHooks runs pre_workflow_hooks when PR is opened or updated.
@@ -44,10 +73,21 @@ func (w *DefaultPreWorkflowHooksCommandRunner) RunPreHooks(ctx *command.Context,
 	user := ctx.User
 	log := ctx.Log
 
+	event := valid.CommentHookEvent
+	if cmd.Name == command.Autoplan {
+		event = valid.AutoplanHookEvent
+	}
 	preWorkflowHooks := make([]*valid.WorkflowHook, 0)
 	for _, repo := range w.GlobalCfg.Repos {
-		if repo.IDMatches(baseRepo.ID()) && len(repo.PreWorkflowHooks) > 0 {
-			preWorkflowHooks = append(preWorkflowHooks, repo.PreWorkflowHooks...)
+		if !repo.IDMatches(baseRepo.ID()) {
+			continue
+		}
+		for _, hook := range repo.PreWorkflowHooks {
+			if !hook.RunsForEvent(event) {
+				log.Debug("skipping pre workflow hook '%s' as it doesn't run for %s events", hook.StepDescription, event)
+				continue
+			}
+			preWorkflowHooks = append(preWorkflowHooks, hook)
 		}
 	}
 
@@ -58,6 +98,10 @@ func (w *DefaultPreWorkflowHooksCommandRunner) RunPreHooks(ctx *command.Context,
 
 	log.Debug("pre-hooks configured, running...")
 
+	// The builder locks and clones the default workspace itself so the
+	// projects are selected before it's locked for the hooks.
+	plannedProjects := w.plannedProjects(ctx, cmd)
+
 	unlockFn, err := w.WorkingDirLocker.TryLock(baseRepo.FullName, pull.Num, DefaultWorkspace, DefaultRepoRelDir)
 	if err != nil {
 		return err
@@ -75,6 +119,11 @@ func (w *DefaultPreWorkflowHooksCommandRunner) RunPreHooks(ctx *command.Context,
 		escapedArgs = escapeArgs(cmd.Flags)
 	}
 
+	headCommit, err := headCommitMetadata(repoDir, pull)
+	if err != nil {
+		log.Warn("unable to read head commit for pre workflow hooks: %s", err)
+	}
+
 	// Update the plan or apply commit status to pending whilst the pre workflow hook is running
 	switch cmd.Name {
 	case command.Plan:
@@ -87,6 +136,11 @@ func (w *DefaultPreWorkflowHooksCommandRunner) RunPreHooks(ctx *command.Context,
 		}
 	}
 
+	cleanEnv, allowedEnvVars := w.GlobalCfg.CleanEnv(baseRepo.ID())
+
+	releaseSlot := w.acquireHookSlot(ctx)
+	defer releaseSlot()
+
 	err = w.runHooks(
 		models.WorkflowHookCommandContext{
 			BaseRepo:           baseRepo,
@@ -97,6 +151,10 @@ func (w *DefaultPreWorkflowHooksCommandRunner) RunPreHooks(ctx *command.Context,
 			Verbose:            false,
 			EscapedCommentArgs: escapedArgs,
 			CommandName:        cmd.Name.String(),
+			PlannedProjects:    plannedProjects,
+			HeadCommit:         headCommit,
+			CleanEnv:           cleanEnv,
+			AllowedEnvVars:     allowedEnvVars,
 		},
 		preWorkflowHooks, repoDir)
 
@@ -107,12 +165,90 @@ func (w *DefaultPreWorkflowHooksCommandRunner) RunPreHooks(ctx *command.Context,
 	return nil
 }
 
+// plannedProjects returns the names, or dirs for projects without names, of
+// the projects that cmd plans or applies, selected like the command selects
+// them, ex. with its -p and -d flags. They're selected before the hooks run,
+// so projects that the hooks generate aren't included. Errors are logged
+// rather than failing the hooks.
+func (w *DefaultPreWorkflowHooksCommandRunner) plannedProjects(ctx *command.Context, cmd *CommentCommand) []string {
+	if w.ProjectCommandBuilder == nil {
+		return nil
+	}
+	var projectCmds []command.ProjectContext
+	var err error
+	switch {
+	case cmd.Name == command.Autoplan:
+		projectCmds, err = w.ProjectCommandBuilder.BuildAutoplanCommands(ctx)
+	case cmd.Name == command.Plan && len(cmd.Workspaces) > 0:
+		for _, workspace := range cmd.Workspaces {
+			workspaceCmd := *cmd
+			workspaceCmd.Workspace = workspace
+			var cmds []command.ProjectContext
+			if cmds, err = w.ProjectCommandBuilder.BuildPlanCommands(ctx, &workspaceCmd); err != nil {
+				break
+			}
+			projectCmds = append(projectCmds, cmds...)
+		}
+	case cmd.Name == command.Plan:
+		projectCmds, err = w.ProjectCommandBuilder.BuildPlanCommands(ctx, cmd)
+	case cmd.Name == command.Apply:
+		projectCmds, err = w.ProjectCommandBuilder.BuildApplyCommands(ctx, cmd)
+	default:
+		return nil
+	}
+	if err != nil {
+		ctx.Log.Warn("unable to find planned projects for pre workflow hooks: %s", err)
+		return nil
+	}
+
+	var planned []string
+	seen := make(map[string]bool)
+	for _, projectCmd := range projectCmds {
+		project := projectCmd.ProjectName
+		if project == "" {
+			project = projectCmd.RepoRelDir
+		}
+		if !seen[project] {
+			seen[project] = true
+			planned = append(planned, project)
+		}
+	}
+	return planned
+}
+
+// acquireHookSlot blocks until fewer than MaxConcurrentHooks pull requests
+// are running their hooks and returns a func that releases the slot.
+func (w *DefaultPreWorkflowHooksCommandRunner) acquireHookSlot(ctx *command.Context) func() {
+	if w.MaxConcurrentHooks <= 0 {
+		return func() {}
+	}
+	w.hookSlotsOnce.Do(func() {
+		w.hookSlots = make(chan struct{}, w.MaxConcurrentHooks)
+	})
+	select {
+	case w.hookSlots <- struct{}{}:
+	default:
+		ctx.Log.Info("%d pre workflow hook runs are already running, waiting for one to finish", w.MaxConcurrentHooks)
+		w.hookSlots <- struct{}{}
+	}
+	return func() { <-w.hookSlots }
+}
+
 func (w *DefaultPreWorkflowHooksCommandRunner) runHooks(
 	ctx models.WorkflowHookCommandContext,
 	preWorkflowHooks []*valid.WorkflowHook,
 	repoDir string,
 ) error {
-	for i, hook := range preWorkflowHooks {
+	// Hooks are collected from every repo config that matches, so order them
+	// by priority, keeping the configured order for equal priorities.
+	hooks := make([]*valid.WorkflowHook, len(preWorkflowHooks))
+	copy(hooks, preWorkflowHooks)
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].Priority < hooks[j].Priority
+	})
+
+	authors := &hookAuthorMatcher{vcsClient: w.VCSClient, ctx: ctx}
+	for i, hook := range hooks {
 		hookDescription := hook.StepDescription
 		if hookDescription == "" {
 			hookDescription = fmt.Sprintf("Pre workflow hook #%d", i)
@@ -125,39 +261,59 @@ func (w *DefaultPreWorkflowHooksCommandRunner) runHooks(
 				hookDescription, ctx.CommandName, hook.Commands)
 			continue
 		}
+		runsForUser, err := authors.runsHook(hook)
+		if err != nil {
+			return fmt.Errorf("pre workflow hook '%s': %w", hookDescription, err)
+		}
+		if !runsForUser {
+			ctx.Log.Debug("Skipping pre workflow hook '%s' as it doesn't run for user %s", hookDescription, ctx.User.Username)
+			continue
+		}
 
 		ctx.Log.Debug("Running pre workflow hook: '%s'", hookDescription)
 		ctx.HookID = uuid.NewString()
+		ctx.Image = hook.Image
 		shell := hook.Shell
 		if shell == "" {
 			ctx.Log.Debug("Setting shell to default: %q", shell)
 			shell = "sh"
 		}
+		if err := validateHookShell(shell); err != nil {
+			return fmt.Errorf("pre workflow hook '%s': %w", hookDescription, err)
+		}
 		shellArgs := hook.ShellArgs
 		if shellArgs == "" {
+			shellArgs = w.DefaultShellArgs
+			if shellArgs == "" {
+				shellArgs = "-c"
+			}
 			ctx.Log.Debug("Setting shellArgs to default: %q", shellArgs)
-			shellArgs = "-c"
 		}
 		url, err := w.Router.GenerateProjectWorkflowHookURL(ctx.HookID)
 		if err != nil {
 			return err
 		}
 
-		if err := w.CommitStatusUpdater.UpdatePreWorkflowHook(ctx.Pull, models.PendingCommitStatus, hookDescription, "", url); err != nil {
+		if err := w.updateHookStatus(ctx, models.PendingCommitStatus, hookDescription, "", url); err != nil {
 			ctx.Log.Warn("unable to update pre workflow hook status: %s", err)
 			return err
 		}
 
 		_, runtimeDesc, err := w.PreWorkflowHookRunner.Run(ctx, hook.RunCommand, shell, shellArgs, repoDir)
 
+		if err != nil && hookExitedWithSuccessCode(hook, err) {
+			ctx.Log.Info("pre workflow hook '%s' exited with a configured success code: %s", hookDescription, err)
+			err = nil
+		}
 		if err != nil {
-			if err := w.CommitStatusUpdater.UpdatePreWorkflowHook(ctx.Pull, models.FailedCommitStatus, hookDescription, runtimeDesc, url); err != nil {
+			if err := w.updateHookStatus(ctx, models.FailedCommitStatus, hookDescription, runtimeDesc, url); err != nil {
 				ctx.Log.Warn("unable to update pre workflow hook status: %s", err)
 			}
+			commentHookFailure(w.VCSClient, w.MarkdownRenderer, ctx, "Pre workflow hook", hookDescription, runtimeDesc, url)
 			return err
 		}
 
-		if err := w.CommitStatusUpdater.UpdatePreWorkflowHook(ctx.Pull, models.SuccessCommitStatus, hookDescription, runtimeDesc, url); err != nil {
+		if err := w.updateHookStatus(ctx, models.SuccessCommitStatus, hookDescription, runtimeDesc, url); err != nil {
 			ctx.Log.Warn("unable to update pre workflow hook status: %s", err)
 			return err
 		}
@@ -165,3 +321,101 @@ func (w *DefaultPreWorkflowHooksCommandRunner) runHooks(
 
 	return nil
 }
+
+// updateHookStatus updates the commit status of a pre workflow hook, retrying
+// up to StatusUpdateRetries times so that a transient VCS error doesn't fail
+// an otherwise successful hook. It returns the last error if all attempts fail.
+func (w *DefaultPreWorkflowHooksCommandRunner) updateHookStatus(ctx models.WorkflowHookCommandContext, status models.CommitStatus, hookDescription string, runtimeDescription string, url string) error {
+	var err error
+	for attempt := 0; attempt <= w.StatusUpdateRetries; attempt++ {
+		if attempt > 0 {
+			ctx.Log.Debug("retrying pre workflow hook status update in %s after error: %s", w.StatusUpdateRetryDelay, err)
+			time.Sleep(w.StatusUpdateRetryDelay)
+		}
+		if err = w.CommitStatusUpdater.UpdatePreWorkflowHook(ctx.Pull, status, hookDescription, runtimeDescription, url); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// hookAuthorMatcher checks whether workflow hooks run for the user that ran
+// the command, based on the hooks' authors and skip_authors. The user's teams
+// are looked up at most once.
+type hookAuthorMatcher struct {
+	vcsClient   vcs.Client
+	ctx         models.WorkflowHookCommandContext
+	teams       []string
+	teamsLoaded bool
+}
+
+// runsHook returns true if hook runs for the user.
+func (m *hookAuthorMatcher) runsHook(hook *valid.WorkflowHook) (bool, error) {
+	if hook.Authors != "" {
+		isAuthor, err := NewUserAllowlistChecker(hook.Authors).IsAllowed(m.ctx.User.Username, m.userTeams)
+		if err != nil || !isAuthor {
+			return false, err
+		}
+	}
+	if hook.SkipAuthors != "" {
+		isSkipped, err := NewUserAllowlistChecker(hook.SkipAuthors).IsAllowed(m.ctx.User.Username, m.userTeams)
+		if err != nil || isSkipped {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (m *hookAuthorMatcher) userTeams() ([]string, error) {
+	if m.teamsLoaded {
+		return m.teams, nil
+	}
+	teams, err := m.vcsClient.GetTeamNamesForUser(m.ctx.BaseRepo, m.ctx.User)
+	if err != nil {
+		return nil, fmt.Errorf("getting teams of user %s: %w", m.ctx.User.Username, err)
+	}
+	m.teams, m.teamsLoaded = teams, true
+	return teams, nil
+}
+
+// commentHookFailure comments on the pull request that the hook failed if
+// renderer is set.
+func commentHookFailure(vcsClient vcs.Client, renderer *MarkdownRenderer, ctx models.WorkflowHookCommandContext, hookType string, hookDescription string, runtimeDescription string, url string) {
+	if renderer == nil {
+		return
+	}
+	comment := renderer.RenderWorkflowHookFailure(hookType, hookDescription, runtimeDescription, url, ctx.CommandName)
+	if err := vcsClient.CreateComment(ctx.Pull.BaseRepo, ctx.Pull.Num, comment, ctx.CommandName); err != nil {
+		ctx.Log.Warn("unable to comment that %s '%s' failed: %s", strings.ToLower(hookType), hookDescription, err)
+	}
+}
+
+// hookExitedWithSuccessCode returns true if err is from the hook's command
+// exiting with one of the hook's configured success codes.
+func hookExitedWithSuccessCode(hook *valid.WorkflowHook, err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	return hook.IsSuccessCode(exitErr.ExitCode())
+}
+
+// validateHookShell checks that shell, if it's an absolute path, exists and is
+// executable so a misconfigured hook fails with a clear error. Other shells
+// are looked up in $PATH when the hook runs.
+func validateHookShell(shell string) error {
+	if !filepath.IsAbs(shell) {
+		return nil
+	}
+	info, err := os.Stat(shell)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("shell %q does not exist", shell)
+	}
+	if err != nil {
+		return fmt.Errorf("checking shell %q: %w", shell, err)
+	}
+	if info.IsDir() || info.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("shell %q is not executable", shell)
+	}
+	return nil
+}
@@ -2,7 +2,15 @@ package events_test
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/petergtz/pegomock/v4"
 	"github.com/runatlantis/atlantis/server/core/config/valid"
@@ -22,10 +30,12 @@ var preWhWorkingDir *mocks.MockWorkingDir
 var preWhWorkingDirLocker *mocks.MockWorkingDirLocker
 var whPreWorkflowHookRunner *runtime_mocks.MockPreWorkflowHookRunner
 var preCommitStatusUpdater *mocks.MockCommitStatusUpdater
+var preWhVCSClient *vcsmocks.MockClient
 
 func preWorkflowHooksSetup(t *testing.T) {
 	RegisterMockTestingT(t)
 	vcsClient := vcsmocks.NewMockClient()
+	preWhVCSClient = vcsClient
 	preWhWorkingDir = mocks.NewMockWorkingDir()
 	preWhWorkingDirLocker = mocks.NewMockWorkingDirLocker()
 	whPreWorkflowHookRunner = runtime_mocks.NewMockPreWorkflowHookRunner()
@@ -46,6 +56,88 @@ func newBool(b bool) *bool {
 	return &b
 }
 
+func TestRunPreHooks_HeadCommit(t *testing.T) {
+	preWorkflowHooksSetup(t)
+	repoDir := initRepo(t)
+	runCmd(t, repoDir, "git", "commit", "--allow-empty", "--author", "Jane Doe <jane@example.com>", "-m", "Add feature", "-m", "Longer description.")
+	headCommit := strings.TrimSpace(runCmd(t, repoDir, "git", "rev-parse", "HEAD"))
+	// With the merge checkout strategy the clone's HEAD is a merge commit
+	// rather than the pull request's head commit.
+	runCmd(t, repoDir, "git", "commit", "--allow-empty", "-m", "Merge branch")
+
+	pull := testdata.Pull
+	pull.BaseRepo = testdata.GithubRepo
+	pull.HeadCommit = headCommit
+	ctx := &command.Context{
+		Pull:     pull,
+		HeadRepo: testdata.GithubRepo,
+		User:     testdata.User,
+		Log:      logging.NewNoopLogger(t),
+	}
+	preWh.GlobalCfg = valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{
+				ID: testdata.GithubRepo.ID(),
+				PreWorkflowHooks: []*valid.WorkflowHook{
+					{StepName: "test", RunCommand: "some command"},
+				},
+			},
+		},
+	}
+	When(preWhWorkingDirLocker.TryLock(testdata.GithubRepo.FullName, pull.Num, events.DefaultWorkspace, events.DefaultRepoRelDir)).ThenReturn(func() {}, nil)
+	When(preWhWorkingDir.Clone(testdata.GithubRepo, pull, events.DefaultWorkspace)).ThenReturn(repoDir, false, nil)
+	When(whPreWorkflowHookRunner.Run(Any[models.WorkflowHookCommandContext](), Any[string](), Any[string](), Any[string](), Eq(repoDir))).ThenReturn("", "", nil)
+
+	err := preWh.RunPreHooks(ctx, &events.CommentCommand{Name: command.Plan})
+
+	Ok(t, err)
+	hookCtx, _, _, _, _ := whPreWorkflowHookRunner.VerifyWasCalledOnce().Run(Any[models.WorkflowHookCommandContext](), Any[string](), Any[string](), Any[string](), Eq(repoDir)).GetCapturedArguments()
+	Equals(t, models.CommitMetadata{
+		SHA:         headCommit,
+		AuthorName:  "Jane Doe",
+		AuthorEmail: "jane@example.com",
+		Message:     "Add feature\n\nLonger description.",
+	}, hookCtx.HeadCommit)
+}
+
+func TestRunPreHooks_CleanEnv(t *testing.T) {
+	preWorkflowHooksSetup(t)
+	repoDir := t.TempDir()
+	pull := testdata.Pull
+	pull.BaseRepo = testdata.GithubRepo
+	ctx := &command.Context{
+		Pull:     pull,
+		HeadRepo: testdata.GithubRepo,
+		User:     testdata.User,
+		Log:      logging.NewNoopLogger(t),
+	}
+	preWh.GlobalCfg = valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{
+				IDRegex: regexp.MustCompile(".*"),
+				PreWorkflowHooks: []*valid.WorkflowHook{
+					{StepName: "test", RunCommand: "some command"},
+				},
+			},
+			{
+				ID:                  testdata.GithubRepo.ID(),
+				CleanEnv:            newBool(true),
+				CleanEnvAllowedVars: []string{"PATH"},
+			},
+		},
+	}
+	When(preWhWorkingDirLocker.TryLock(testdata.GithubRepo.FullName, pull.Num, events.DefaultWorkspace, events.DefaultRepoRelDir)).ThenReturn(func() {}, nil)
+	When(preWhWorkingDir.Clone(testdata.GithubRepo, pull, events.DefaultWorkspace)).ThenReturn(repoDir, false, nil)
+	When(whPreWorkflowHookRunner.Run(Any[models.WorkflowHookCommandContext](), Any[string](), Any[string](), Any[string](), Eq(repoDir))).ThenReturn("", "", nil)
+
+	err := preWh.RunPreHooks(ctx, &events.CommentCommand{Name: command.Plan})
+
+	Ok(t, err)
+	hookCtx, _, _, _, _ := whPreWorkflowHookRunner.VerifyWasCalledOnce().Run(Any[models.WorkflowHookCommandContext](), Any[string](), Any[string](), Any[string](), Eq(repoDir)).GetCapturedArguments()
+	Equals(t, true, hookCtx.CleanEnv)
+	Equals(t, []string{"PATH"}, hookCtx.AllowedEnvVars)
+}
+
 func TestRunPreHooks_Clone(t *testing.T) {
 
 	log := logging.NewNoopLogger(t)
@@ -155,6 +247,42 @@ func TestRunPreHooks_Clone(t *testing.T) {
 		Assert(t, *unlockCalled == true, "unlock function called")
 	})
 
+	t.Run("success hooks in cfg for gitlab subgroup repo under a relative url root", func(t *testing.T) {
+		preWorkflowHooksSetup(t)
+
+		subgroupRepo, err := models.NewRepo(models.Gitlab, "group/subgroup/subsubgroup/repo", "https://mycorp.com/gitlab/group/subgroup/subsubgroup/repo.git", "user", "token")
+		Ok(t, err)
+		subgroupPull := newPull
+		subgroupPull.BaseRepo = subgroupRepo
+		subgroupCtx := *ctx
+		subgroupCtx.Pull = subgroupPull
+		subgroupCtx.HeadRepo = subgroupRepo
+
+		globalCfg := valid.GlobalCfg{
+			Repos: []valid.Repo{
+				{
+					ID: "mycorp.com/group/subgroup/subsubgroup/repo",
+					PreWorkflowHooks: []*valid.WorkflowHook{
+						&testHook,
+					},
+				},
+			},
+		}
+
+		preWh.GlobalCfg = globalCfg
+
+		When(preWhWorkingDirLocker.TryLock(subgroupRepo.FullName, subgroupPull.Num, events.DefaultWorkspace, events.DefaultRepoRelDir)).ThenReturn(func() {}, nil)
+		When(preWhWorkingDir.Clone(subgroupRepo, subgroupPull, events.DefaultWorkspace)).ThenReturn(repoDir, false, nil)
+		When(whPreWorkflowHookRunner.Run(Any[models.WorkflowHookCommandContext](), Eq(testHook.RunCommand),
+			Any[string](), Any[string](), Eq(repoDir))).ThenReturn(result, runtimeDesc, nil)
+
+		err = preWh.RunPreHooks(&subgroupCtx, planCmd)
+
+		Ok(t, err)
+		whPreWorkflowHookRunner.VerifyWasCalledOnce().Run(Any[models.WorkflowHookCommandContext](),
+			Eq(testHook.RunCommand), Eq(defaultShell), Eq(defaultShellArgs), Eq(repoDir))
+	})
+
 	t.Run("success hooks not in cfg", func(t *testing.T) {
 		preWorkflowHooksSetup(t)
 		globalCfg := valid.GlobalCfg{
@@ -274,6 +402,268 @@ func TestRunPreHooks_Clone(t *testing.T) {
 		Assert(t, *unlockCalled == true, "unlock function called")
 	})
 
+	t.Run("failed pre hook is commented on", func(t *testing.T) {
+		preWorkflowHooksSetup(t)
+		preWh.MarkdownRenderer = events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
+		preWh.GlobalCfg = valid.GlobalCfg{
+			Repos: []valid.Repo{
+				{
+					ID:               testdata.GithubRepo.ID(),
+					PreWorkflowHooks: []*valid.WorkflowHook{{StepDescription: "check tags", RunCommand: "some command"}},
+				},
+			},
+		}
+
+		When(preWhWorkingDirLocker.TryLock(testdata.GithubRepo.FullName, newPull.Num, events.DefaultWorkspace, events.DefaultRepoRelDir)).ThenReturn(func() {}, nil)
+		When(preWhWorkingDir.Clone(testdata.GithubRepo, newPull, events.DefaultWorkspace)).ThenReturn(repoDir, false, nil)
+		When(whPreWorkflowHookRunner.Run(Any[models.WorkflowHookCommandContext](), Eq("some command"),
+			Any[string](), Any[string](), Eq(repoDir))).ThenReturn(result, "missing owner tag", errors.New("some error"))
+
+		err := preWh.RunPreHooks(ctx, planCmd)
+
+		ErrEquals(t, "some error", err)
+		preWhVCSClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, newPull.Num,
+			"**Pre workflow hook Failed**: check tags\n\nmissing owner tag\n\nFix what made the hook fail, then comment `atlantis plan` to run it again.", "plan")
+	})
+
+	t.Run("successful pre hook isn't commented on", func(t *testing.T) {
+		preWorkflowHooksSetup(t)
+		preWh.MarkdownRenderer = events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"})
+		preWh.GlobalCfg = valid.GlobalCfg{
+			Repos: []valid.Repo{
+				{
+					ID:               testdata.GithubRepo.ID(),
+					PreWorkflowHooks: []*valid.WorkflowHook{&testHook},
+				},
+			},
+		}
+
+		When(preWhWorkingDirLocker.TryLock(testdata.GithubRepo.FullName, newPull.Num, events.DefaultWorkspace, events.DefaultRepoRelDir)).ThenReturn(func() {}, nil)
+		When(preWhWorkingDir.Clone(testdata.GithubRepo, newPull, events.DefaultWorkspace)).ThenReturn(repoDir, false, nil)
+		When(whPreWorkflowHookRunner.Run(Any[models.WorkflowHookCommandContext](), Eq(testHook.RunCommand),
+			Any[string](), Any[string](), Eq(repoDir))).ThenReturn(result, runtimeDesc, nil)
+
+		Ok(t, preWh.RunPreHooks(ctx, planCmd))
+		preWhVCSClient.VerifyWasCalled(Never()).CreateComment(Any[models.Repo](), Any[int](), Any[string](), Any[string]())
+	})
+
+	t.Run("status update retried then succeeds", func(t *testing.T) {
+		preWorkflowHooksSetup(t)
+		preWh.StatusUpdateRetries = 2
+
+		preWh.GlobalCfg = valid.GlobalCfg{
+			Repos: []valid.Repo{
+				{
+					ID: testdata.GithubRepo.ID(),
+					PreWorkflowHooks: []*valid.WorkflowHook{
+						&testHook,
+					},
+				},
+			},
+		}
+
+		When(preWhWorkingDirLocker.TryLock(testdata.GithubRepo.FullName, newPull.Num, events.DefaultWorkspace, events.DefaultRepoRelDir)).ThenReturn(func() {}, nil)
+		When(preWhWorkingDir.Clone(testdata.GithubRepo, newPull, events.DefaultWorkspace)).ThenReturn(repoDir, false, nil)
+		When(whPreWorkflowHookRunner.Run(Any[models.WorkflowHookCommandContext](), Eq(testHook.RunCommand),
+			Any[string](), Any[string](), Eq(repoDir))).ThenReturn(result, runtimeDesc, nil)
+		When(preCommitStatusUpdater.UpdatePreWorkflowHook(Any[models.PullRequest](), Eq(models.PendingCommitStatus),
+			Any[string](), Any[string](), Any[string]())).
+			ThenReturn(errors.New("502 bad gateway")).
+			ThenReturn(nil)
+
+		err := preWh.RunPreHooks(ctx, planCmd)
+
+		Ok(t, err)
+		preCommitStatusUpdater.VerifyWasCalled(Times(2)).UpdatePreWorkflowHook(Any[models.PullRequest](), Eq(models.PendingCommitStatus),
+			Any[string](), Any[string](), Any[string]())
+		whPreWorkflowHookRunner.VerifyWasCalledOnce().Run(Any[models.WorkflowHookCommandContext](),
+			Eq(testHook.RunCommand), Eq(defaultShell), Eq(defaultShellArgs), Eq(repoDir))
+	})
+
+	t.Run("status update retries exhausted", func(t *testing.T) {
+		preWorkflowHooksSetup(t)
+		preWh.StatusUpdateRetries = 2
+
+		preWh.GlobalCfg = valid.GlobalCfg{
+			Repos: []valid.Repo{
+				{
+					ID: testdata.GithubRepo.ID(),
+					PreWorkflowHooks: []*valid.WorkflowHook{
+						&testHook,
+					},
+				},
+			},
+		}
+
+		When(preWhWorkingDirLocker.TryLock(testdata.GithubRepo.FullName, newPull.Num, events.DefaultWorkspace, events.DefaultRepoRelDir)).ThenReturn(func() {}, nil)
+		When(preWhWorkingDir.Clone(testdata.GithubRepo, newPull, events.DefaultWorkspace)).ThenReturn(repoDir, false, nil)
+		When(preCommitStatusUpdater.UpdatePreWorkflowHook(Any[models.PullRequest](), Eq(models.PendingCommitStatus),
+			Any[string](), Any[string](), Any[string]())).ThenReturn(errors.New("502 bad gateway"))
+
+		err := preWh.RunPreHooks(ctx, planCmd)
+
+		ErrEquals(t, "502 bad gateway", err)
+		preCommitStatusUpdater.VerifyWasCalled(Times(3)).UpdatePreWorkflowHook(Any[models.PullRequest](), Eq(models.PendingCommitStatus),
+			Any[string](), Any[string](), Any[string]())
+		whPreWorkflowHookRunner.VerifyWasCalled(Never()).Run(Any[models.WorkflowHookCommandContext](),
+			Any[string](), Any[string](), Any[string](), Any[string]())
+	})
+
+	t.Run("pre hook exit codes with success codes", func(t *testing.T) {
+		testHookWithSuccessCodes := valid.WorkflowHook{
+			StepName:     "test",
+			RunCommand:   "some command",
+			SuccessCodes: []int{2},
+		}
+		cases := []struct {
+			exitCode int
+			expErr   bool
+		}{
+			{exitCode: 2, expErr: false},
+			{exitCode: 3, expErr: true},
+		}
+		for _, c := range cases {
+			preWorkflowHooksSetup(t)
+
+			globalCfg := valid.GlobalCfg{
+				Repos: []valid.Repo{
+					{
+						ID: testdata.GithubRepo.ID(),
+						PreWorkflowHooks: []*valid.WorkflowHook{
+							&testHookWithSuccessCodes,
+						},
+					},
+				},
+			}
+
+			preWh.GlobalCfg = globalCfg
+
+			exitErr := exec.Command("sh", "-c", fmt.Sprintf("exit %d", c.exitCode)).Run()
+			runErr := fmt.Errorf("%w: running %q", exitErr, testHookWithSuccessCodes.RunCommand)
+
+			When(preWhWorkingDirLocker.TryLock(testdata.GithubRepo.FullName, newPull.Num, events.DefaultWorkspace, events.DefaultRepoRelDir)).ThenReturn(func() {}, nil)
+			When(preWhWorkingDir.Clone(testdata.GithubRepo, newPull, events.DefaultWorkspace)).ThenReturn(repoDir, false, nil)
+			When(whPreWorkflowHookRunner.Run(Any[models.WorkflowHookCommandContext](), Eq(testHookWithSuccessCodes.RunCommand),
+				Any[string](), Any[string](), Eq(repoDir))).ThenReturn(result, runtimeDesc, runErr)
+
+			err := preWh.RunPreHooks(ctx, planCmd)
+
+			if c.expErr {
+				ErrContains(t, "exit status 3", err)
+				preCommitStatusUpdater.VerifyWasCalledOnce().UpdatePreWorkflowHook(Any[models.PullRequest](), Eq(models.FailedCommitStatus), Any[string](), Any[string](), Any[string]())
+			} else {
+				Ok(t, err)
+				preCommitStatusUpdater.VerifyWasCalledOnce().UpdatePreWorkflowHook(Any[models.PullRequest](), Eq(models.SuccessCommitStatus), Any[string](), Any[string](), Any[string]())
+			}
+		}
+	})
+
+	t.Run("hooks from all matching repos run in priority order", func(t *testing.T) {
+		preWorkflowHooksSetup(t)
+
+		hookA := valid.WorkflowHook{StepName: "run", RunCommand: "echo a", Priority: 10}
+		hookB := valid.WorkflowHook{StepName: "run", RunCommand: "echo b"}
+		hookC := valid.WorkflowHook{StepName: "run", RunCommand: "echo c", Priority: -5}
+		hookD := valid.WorkflowHook{StepName: "run", RunCommand: "echo d"}
+
+		globalCfg := valid.GlobalCfg{
+			Repos: []valid.Repo{
+				{
+					IDRegex:          regexp.MustCompile(".*"),
+					PreWorkflowHooks: []*valid.WorkflowHook{&hookA, &hookB},
+				},
+				{
+					ID:               testdata.GithubRepo.ID(),
+					PreWorkflowHooks: []*valid.WorkflowHook{&hookC, &hookD},
+				},
+			},
+		}
+
+		preWh.GlobalCfg = globalCfg
+
+		When(preWhWorkingDirLocker.TryLock(testdata.GithubRepo.FullName, newPull.Num, events.DefaultWorkspace, events.DefaultRepoRelDir)).ThenReturn(func() {}, nil)
+		When(preWhWorkingDir.Clone(testdata.GithubRepo, newPull, events.DefaultWorkspace)).ThenReturn(repoDir, false, nil)
+		When(whPreWorkflowHookRunner.Run(Any[models.WorkflowHookCommandContext](), Any[string](),
+			Any[string](), Any[string](), Eq(repoDir))).ThenReturn(result, runtimeDesc, nil)
+
+		err := preWh.RunPreHooks(ctx, planCmd)
+
+		Ok(t, err)
+		inOrder := new(InOrderContext)
+		for _, cmd := range []string{"echo c", "echo b", "echo d", "echo a"} {
+			whPreWorkflowHookRunner.VerifyWasCalledInOrder(Once(), inOrder).Run(Any[models.WorkflowHookCommandContext](),
+				Eq(cmd), Eq(defaultShell), Eq(defaultShellArgs), Eq(repoDir))
+		}
+		// The configured order isn't changed.
+		Equals(t, []*valid.WorkflowHook{&hookA, &hookB}, globalCfg.Repos[0].PreWorkflowHooks)
+	})
+
+	t.Run("hooks run only for their authors", func(t *testing.T) {
+		preWorkflowHooksSetup(t)
+
+		globalCfg := valid.GlobalCfg{
+			Repos: []valid.Repo{
+				{
+					ID: testdata.GithubRepo.ID(),
+					PreWorkflowHooks: []*valid.WorkflowHook{
+						{StepName: "run", RunCommand: "echo user", Authors: testdata.User.Username},
+						{StepName: "run", RunCommand: "echo other user", Authors: "someone-else"},
+						{StepName: "run", RunCommand: "echo contributors", Authors: "team:contributors"},
+						{StepName: "run", RunCommand: "echo not platform", SkipAuthors: "team:platform"},
+						{StepName: "run", RunCommand: "echo not contributors", SkipAuthors: "someone-else, team:contributors"},
+					},
+				},
+			},
+		}
+
+		preWh.GlobalCfg = globalCfg
+
+		When(preWhWorkingDirLocker.TryLock(testdata.GithubRepo.FullName, newPull.Num, events.DefaultWorkspace, events.DefaultRepoRelDir)).ThenReturn(func() {}, nil)
+		When(preWhWorkingDir.Clone(testdata.GithubRepo, newPull, events.DefaultWorkspace)).ThenReturn(repoDir, false, nil)
+		When(whPreWorkflowHookRunner.Run(Any[models.WorkflowHookCommandContext](), Any[string](),
+			Any[string](), Any[string](), Eq(repoDir))).ThenReturn(result, runtimeDesc, nil)
+		When(preWhVCSClient.GetTeamNamesForUser(testdata.GithubRepo, testdata.User)).ThenReturn([]string{"contributors"}, nil)
+
+		err := preWh.RunPreHooks(ctx, planCmd)
+
+		Ok(t, err)
+		for _, cmd := range []string{"echo user", "echo contributors", "echo not platform"} {
+			whPreWorkflowHookRunner.VerifyWasCalledOnce().Run(Any[models.WorkflowHookCommandContext](),
+				Eq(cmd), Eq(defaultShell), Eq(defaultShellArgs), Eq(repoDir))
+		}
+		for _, cmd := range []string{"echo other user", "echo not contributors"} {
+			whPreWorkflowHookRunner.VerifyWasCalled(Never()).Run(Any[models.WorkflowHookCommandContext](),
+				Eq(cmd), Any[string](), Any[string](), Any[string]())
+		}
+		// The user's teams are only looked up once.
+		preWhVCSClient.VerifyWasCalledOnce().GetTeamNamesForUser(Any[models.Repo](), Any[models.User]())
+	})
+
+	t.Run("hooks fail if the author's teams can't be looked up", func(t *testing.T) {
+		preWorkflowHooksSetup(t)
+
+		preWh.GlobalCfg = valid.GlobalCfg{
+			Repos: []valid.Repo{
+				{
+					ID: testdata.GithubRepo.ID(),
+					PreWorkflowHooks: []*valid.WorkflowHook{
+						{StepName: "run", RunCommand: "echo contributors", StepDescription: "contributors", Authors: "team:contributors"},
+					},
+				},
+			},
+		}
+
+		When(preWhWorkingDirLocker.TryLock(testdata.GithubRepo.FullName, newPull.Num, events.DefaultWorkspace, events.DefaultRepoRelDir)).ThenReturn(func() {}, nil)
+		When(preWhWorkingDir.Clone(testdata.GithubRepo, newPull, events.DefaultWorkspace)).ThenReturn(repoDir, false, nil)
+		When(preWhVCSClient.GetTeamNamesForUser(testdata.GithubRepo, testdata.User)).ThenReturn(nil, errors.New("403 Forbidden"))
+
+		err := preWh.RunPreHooks(ctx, planCmd)
+
+		ErrEquals(t, "pre workflow hook 'contributors': getting teams of user lkysow: 403 Forbidden", err)
+		whPreWorkflowHookRunner.VerifyWasCalled(Never()).Run(Any[models.WorkflowHookCommandContext](),
+			Any[string](), Any[string](), Any[string](), Any[string]())
+	})
+
 	t.Run("comment args passed to webhooks", func(t *testing.T) {
 		preWorkflowHooksSetup(t)
 
@@ -417,6 +807,90 @@ func TestRunPreHooks_Clone(t *testing.T) {
 		Assert(t, *unlockCalled == true, "unlock function called")
 	})
 
+	t.Run("configured default shellArgs passed to webhooks", func(t *testing.T) {
+		preWorkflowHooksSetup(t)
+		preWh.DefaultShellArgs = "-ec"
+
+		globalCfg := valid.GlobalCfg{
+			Repos: []valid.Repo{
+				{
+					ID: testdata.GithubRepo.ID(),
+					PreWorkflowHooks: []*valid.WorkflowHook{
+						&testHook,
+						&testHookWithShellArgs,
+					},
+				},
+			},
+		}
+
+		preWh.GlobalCfg = globalCfg
+
+		When(preWhWorkingDirLocker.TryLock(testdata.GithubRepo.FullName, newPull.Num, events.DefaultWorkspace, events.DefaultRepoRelDir)).ThenReturn(func() {}, nil)
+		When(preWhWorkingDir.Clone(testdata.GithubRepo, newPull, events.DefaultWorkspace)).ThenReturn(repoDir, false, nil)
+		When(whPreWorkflowHookRunner.Run(Any[models.WorkflowHookCommandContext](), Any[string](),
+			Any[string](), Any[string](), Eq(repoDir))).ThenReturn(result, runtimeDesc, nil)
+
+		err := preWh.RunPreHooks(ctx, planCmd)
+
+		Ok(t, err)
+		whPreWorkflowHookRunner.VerifyWasCalledOnce().Run(Any[models.WorkflowHookCommandContext](),
+			Eq(testHook.RunCommand), Eq(defaultShell), Eq("-ec"), Eq(repoDir))
+		// The hook's own shellArgs still override the default.
+		whPreWorkflowHookRunner.VerifyWasCalledOnce().Run(Any[models.WorkflowHookCommandContext](),
+			Eq(testHookWithShellArgs.RunCommand), Eq(defaultShell), Eq(testHookWithShellArgs.ShellArgs), Eq(repoDir))
+	})
+
+	t.Run("absolute shell path is validated", func(t *testing.T) {
+		tmp := t.TempDir()
+		executableShell := filepath.Join(tmp, "bash")
+		Ok(t, os.WriteFile(executableShell, []byte("#!/bin/sh\n"), 0700)) // nolint: gosec
+		nonExecutableShell := filepath.Join(tmp, "zsh")
+		Ok(t, os.WriteFile(nonExecutableShell, []byte("#!/bin/sh\n"), 0600))
+
+		cases := []struct {
+			shell  string
+			expErr string
+		}{
+			{shell: executableShell},
+			{shell: filepath.Join(tmp, "missing"), expErr: fmt.Sprintf("shell %q does not exist", filepath.Join(tmp, "missing"))},
+			{shell: nonExecutableShell, expErr: fmt.Sprintf("shell %q is not executable", nonExecutableShell)},
+			{shell: tmp, expErr: fmt.Sprintf("shell %q is not executable", tmp)},
+		}
+		for _, c := range cases {
+			preWorkflowHooksSetup(t)
+
+			hook := valid.WorkflowHook{
+				StepName:   "test",
+				RunCommand: "some command",
+				Shell:      c.shell,
+			}
+			preWh.GlobalCfg = valid.GlobalCfg{
+				Repos: []valid.Repo{
+					{
+						ID:               testdata.GithubRepo.ID(),
+						PreWorkflowHooks: []*valid.WorkflowHook{&hook},
+					},
+				},
+			}
+
+			When(preWhWorkingDirLocker.TryLock(testdata.GithubRepo.FullName, newPull.Num, events.DefaultWorkspace, events.DefaultRepoRelDir)).ThenReturn(func() {}, nil)
+			When(preWhWorkingDir.Clone(testdata.GithubRepo, newPull, events.DefaultWorkspace)).ThenReturn(repoDir, false, nil)
+			When(whPreWorkflowHookRunner.Run(Any[models.WorkflowHookCommandContext](), Eq(hook.RunCommand),
+				Any[string](), Any[string](), Eq(repoDir))).ThenReturn(result, runtimeDesc, nil)
+
+			err := preWh.RunPreHooks(ctx, planCmd)
+
+			if c.expErr != "" {
+				ErrContains(t, c.expErr, err)
+				whPreWorkflowHookRunner.VerifyWasCalled(Never()).Run(Any[models.WorkflowHookCommandContext](), Any[string](), Any[string](), Any[string](), Any[string]())
+			} else {
+				Ok(t, err)
+				whPreWorkflowHookRunner.VerifyWasCalledOnce().Run(Any[models.WorkflowHookCommandContext](),
+					Eq(hook.RunCommand), Eq(c.shell), Eq(defaultShellArgs), Eq(repoDir))
+			}
+		}
+	})
+
 	t.Run("Commands 'plan' set on webhook and plan command", func(t *testing.T) {
 		preWorkflowHooksSetup(t)
 
@@ -519,3 +993,263 @@ func TestRunPreHooks_Clone(t *testing.T) {
 		Assert(t, *unlockCalled == true, "unlock function called")
 	})
 }
+
+// concurrencyTrackingHookRunner records the most hooks it saw running at once.
+type concurrencyTrackingHookRunner struct {
+	mu      sync.Mutex
+	calls   int
+	running int
+	max     int
+}
+
+func (r *concurrencyTrackingHookRunner) Run(_ models.WorkflowHookCommandContext, _ string, _ string, _ string, _ string) (string, string, error) {
+	r.mu.Lock()
+	r.calls++
+	r.running++
+	if r.running > r.max {
+		r.max = r.running
+	}
+	r.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	r.mu.Lock()
+	r.running--
+	r.mu.Unlock()
+	return "", "", nil
+}
+
+func TestRunPreHooks_MaxConcurrentHooks(t *testing.T) {
+	RegisterMockTestingT(t)
+	workingDir := mocks.NewMockWorkingDir()
+	When(workingDir.Clone(Any[models.Repo](), Any[models.PullRequest](), Any[string]())).ThenReturn("path/to/repo", false, nil)
+	hookRunner := &concurrencyTrackingHookRunner{}
+	runner := &events.DefaultPreWorkflowHooksCommandRunner{
+		VCSClient:        vcsmocks.NewMockClient(),
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		WorkingDir:       workingDir,
+		GlobalCfg: valid.GlobalCfg{
+			Repos: []valid.Repo{
+				{
+					ID:               testdata.GithubRepo.ID(),
+					PreWorkflowHooks: []*valid.WorkflowHook{{StepName: "test", RunCommand: "echo test"}},
+				},
+			},
+		},
+		PreWorkflowHookRunner: hookRunner,
+		CommitStatusUpdater:   mocks.NewMockCommitStatusUpdater(),
+		Router:                mocks.NewMockPreWorkflowHookURLGenerator(),
+		MaxConcurrentHooks:    2,
+	}
+
+	// Run the hooks for several pull requests at once.
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 1; i <= 5; i++ {
+		pull := testdata.Pull
+		pull.Num = i
+		pull.BaseRepo = testdata.GithubRepo
+		ctx := &command.Context{
+			Pull:     pull,
+			HeadRepo: testdata.GithubRepo,
+			User:     testdata.User,
+			Log:      logging.NewNoopLogger(t),
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- runner.RunPreHooks(ctx, &events.CommentCommand{Name: command.Plan})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		Ok(t, err)
+	}
+
+	Equals(t, 5, hookRunner.calls)
+	Assert(t, hookRunner.max <= 2, "expected at most 2 hooks to run at once, got %d", hookRunner.max)
+}
+
+// ctxRecordingHookRunner records the context of the last hook it ran.
+type ctxRecordingHookRunner struct {
+	ctx models.WorkflowHookCommandContext
+}
+
+func (r *ctxRecordingHookRunner) Run(ctx models.WorkflowHookCommandContext, _ string, _ string, _ string, _ string) (string, string, error) {
+	r.ctx = ctx
+	return "", "", nil
+}
+
+func TestRunPreHooks_PlannedProjects(t *testing.T) {
+	stagingCmd := command.ProjectContext{ProjectName: "staging", RepoRelDir: "staging", Workspace: "default"}
+	productionCmd := command.ProjectContext{RepoRelDir: "production", Workspace: "default"}
+	cases := []struct {
+		description string
+		cmd         *events.CommentCommand
+		noBuilder   bool
+		exp         []string
+	}{
+		{
+			description: "autoplan",
+			cmd:         &events.CommentCommand{Name: command.Autoplan},
+			exp:         []string{"staging", "production"},
+		},
+		{
+			description: "plan of a project",
+			cmd:         &events.CommentCommand{Name: command.Plan, ProjectName: "staging"},
+			exp:         []string{"staging"},
+		},
+		{
+			description: "plan in several workspaces",
+			cmd:         &events.CommentCommand{Name: command.Plan, RepoRelDir: "production", Workspaces: []string{"default", "prod"}},
+			exp:         []string{"production"},
+		},
+		{
+			description: "apply of a dir",
+			cmd:         &events.CommentCommand{Name: command.Apply, RepoRelDir: "production"},
+			exp:         []string{"production"},
+		},
+		{
+			description: "other commands",
+			cmd:         &events.CommentCommand{Name: command.Version},
+		},
+		{
+			description: "builder error",
+			cmd:         &events.CommentCommand{Name: command.Plan, ProjectName: "missing"},
+		},
+		{
+			description: "no builder",
+			cmd:         &events.CommentCommand{Name: command.Autoplan},
+			noBuilder:   true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			pull := testdata.Pull
+			pull.BaseRepo = testdata.GithubRepo
+			workingDir := mocks.NewMockWorkingDir()
+			When(workingDir.Clone(Any[models.Repo](), Any[models.PullRequest](), Any[string]())).ThenReturn(t.TempDir(), false, nil)
+			globalCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+			globalCfg.Repos[0].PreWorkflowHooks = []*valid.WorkflowHook{{StepName: "test", RunCommand: "echo test"}}
+			hookRunner := &ctxRecordingHookRunner{}
+			ctx := &command.Context{
+				Pull:     pull,
+				HeadRepo: testdata.GithubRepo,
+				User:     testdata.User,
+				Log:      logging.NewNoopLogger(t),
+			}
+
+			builder := mocks.NewMockProjectCommandBuilder()
+			When(builder.BuildAutoplanCommands(ctx)).ThenReturn([]command.ProjectContext{stagingCmd, productionCmd}, nil)
+			When(builder.BuildPlanCommands(ctx, &events.CommentCommand{Name: command.Plan, ProjectName: "staging"})).
+				ThenReturn([]command.ProjectContext{stagingCmd}, nil)
+			When(builder.BuildPlanCommands(ctx, &events.CommentCommand{Name: command.Plan, RepoRelDir: "production", Workspace: "default", Workspaces: []string{"default", "prod"}})).
+				ThenReturn([]command.ProjectContext{productionCmd}, nil)
+			prodCmd := productionCmd
+			prodCmd.Workspace = "prod"
+			When(builder.BuildPlanCommands(ctx, &events.CommentCommand{Name: command.Plan, RepoRelDir: "production", Workspace: "prod", Workspaces: []string{"default", "prod"}})).
+				ThenReturn([]command.ProjectContext{prodCmd}, nil)
+			When(builder.BuildPlanCommands(ctx, &events.CommentCommand{Name: command.Plan, ProjectName: "missing"})).
+				ThenReturn(nil, errors.New("no project with name missing"))
+			When(builder.BuildApplyCommands(ctx, &events.CommentCommand{Name: command.Apply, RepoRelDir: "production"})).
+				ThenReturn([]command.ProjectContext{productionCmd}, nil)
+
+			runner := &events.DefaultPreWorkflowHooksCommandRunner{
+				VCSClient:             vcsmocks.NewMockClient(),
+				WorkingDirLocker:      events.NewDefaultWorkingDirLocker(),
+				WorkingDir:            workingDir,
+				GlobalCfg:             globalCfg,
+				PreWorkflowHookRunner: hookRunner,
+				CommitStatusUpdater:   mocks.NewMockCommitStatusUpdater(),
+				Router:                mocks.NewMockPreWorkflowHookURLGenerator(),
+				ProjectCommandBuilder: builder,
+			}
+			if c.noBuilder {
+				runner.ProjectCommandBuilder = nil
+			}
+
+			Ok(t, runner.RunPreHooks(ctx, c.cmd))
+			Equals(t, c.exp, hookRunner.ctx.PlannedProjects)
+		})
+	}
+}
+
+func TestRunPreHooks_Events(t *testing.T) {
+	autoplanHook := &valid.WorkflowHook{StepName: "autoplan", RunCommand: "echo autoplan", Events: []string{valid.AutoplanHookEvent}}
+	commentHook := &valid.WorkflowHook{StepName: "comment", RunCommand: "echo comment", Events: []string{valid.CommentHookEvent}}
+	allHook := &valid.WorkflowHook{StepName: "all", RunCommand: "echo all"}
+
+	cases := []struct {
+		description string
+		cmd         *events.CommentCommand
+		expRun      []string
+		expNotRun   []string
+	}{
+		{
+			description: "autoplan",
+			cmd:         &events.CommentCommand{Name: command.Autoplan},
+			expRun:      []string{"echo autoplan", "echo all"},
+			expNotRun:   []string{"echo comment"},
+		},
+		{
+			description: "comment",
+			cmd:         &events.CommentCommand{Name: command.Plan},
+			expRun:      []string{"echo comment", "echo all"},
+			expNotRun:   []string{"echo autoplan"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			preWorkflowHooksSetup(t)
+			pull := testdata.Pull
+			pull.BaseRepo = testdata.GithubRepo
+			ctx := &command.Context{
+				Pull:     pull,
+				HeadRepo: testdata.GithubRepo,
+				User:     testdata.User,
+				Log:      logging.NewNoopLogger(t),
+			}
+			preWh.GlobalCfg = valid.GlobalCfg{
+				Repos: []valid.Repo{
+					{
+						ID:               testdata.GithubRepo.ID(),
+						PreWorkflowHooks: []*valid.WorkflowHook{autoplanHook, commentHook, allHook},
+					},
+				},
+			}
+			When(preWhWorkingDirLocker.TryLock(testdata.GithubRepo.FullName, pull.Num, events.DefaultWorkspace, events.DefaultRepoRelDir)).ThenReturn(func() {}, nil)
+			When(preWhWorkingDir.Clone(testdata.GithubRepo, pull, events.DefaultWorkspace)).ThenReturn("path/to/repo", false, nil)
+			When(whPreWorkflowHookRunner.Run(Any[models.WorkflowHookCommandContext](), Any[string](),
+				Any[string](), Any[string](), Any[string]())).ThenReturn("", "", nil)
+
+			Ok(t, preWh.RunPreHooks(ctx, c.cmd))
+
+			for _, run := range c.expRun {
+				whPreWorkflowHookRunner.VerifyWasCalledOnce().Run(Any[models.WorkflowHookCommandContext](), Eq(run),
+					Any[string](), Any[string](), Any[string]())
+			}
+			for _, notRun := range c.expNotRun {
+				whPreWorkflowHookRunner.VerifyWasCalled(Never()).Run(Any[models.WorkflowHookCommandContext](), Eq(notRun),
+					Any[string](), Any[string](), Any[string]())
+			}
+		})
+	}
+
+	// Nothing is cloned if no hooks run for the event.
+	preWorkflowHooksSetup(t)
+	preWh.GlobalCfg = valid.GlobalCfg{
+		Repos: []valid.Repo{
+			{
+				ID:               testdata.GithubRepo.ID(),
+				PreWorkflowHooks: []*valid.WorkflowHook{commentHook},
+			},
+		},
+	}
+	pull := testdata.Pull
+	pull.BaseRepo = testdata.GithubRepo
+	ctx := &command.Context{Pull: pull, HeadRepo: testdata.GithubRepo, User: testdata.User, Log: logging.NewNoopLogger(t)}
+	Ok(t, preWh.RunPreHooks(ctx, &events.CommentCommand{Name: command.Autoplan}))
+	preWhWorkingDir.VerifyWasCalled(Never()).Clone(Any[models.Repo](), Any[models.PullRequest](), Any[string]())
+}
@@ -54,6 +54,7 @@ func NewInstrumentedProjectCommandBuilder(
 	RestrictFileList bool,
 	SilenceNoProjects bool,
 	IncludeGitUntrackedFiles bool,
+	EnableAdhocWorkspaces bool,
 	scope tally.Scope,
 	logger logging.SimpleLogging,
 	terraformClient terraform.Client,
@@ -85,6 +86,7 @@ func NewInstrumentedProjectCommandBuilder(
 			RestrictFileList,
 			SilenceNoProjects,
 			IncludeGitUntrackedFiles,
+			EnableAdhocWorkspaces,
 			scope,
 			logger,
 			terraformClient,
@@ -114,6 +116,7 @@ func NewProjectCommandBuilder(
 	RestrictFileList bool,
 	SilenceNoProjects bool,
 	IncludeGitUntrackedFiles bool,
+	EnableAdhocWorkspaces bool,
 	scope tally.Scope,
 	logger logging.SimpleLogging,
 	terraformClient terraform.Client,
@@ -136,6 +139,7 @@ func NewProjectCommandBuilder(
 		RestrictFileList:         RestrictFileList,
 		SilenceNoProjects:        SilenceNoProjects,
 		IncludeGitUntrackedFiles: IncludeGitUntrackedFiles,
+		EnableAdhocWorkspaces:    EnableAdhocWorkspaces,
 		ProjectCommandContextBuilder: NewProjectCommandContextBuilder(
 			policyChecksSupported,
 			commentBuilder,
@@ -188,6 +192,13 @@ type ProjectStateCommandBuilder interface {
 	BuildStateRmCommands(ctx *command.Context, comment *CommentCommand) ([]command.ProjectContext, error)
 }
 
+type ProjectValidateCommandBuilder interface {
+	// BuildValidateCommands builds project validate commands for this ctx and comment. If
+	// comment doesn't specify one project then there may be multiple commands
+	// to be run.
+	BuildValidateCommands(ctx *command.Context, comment *CommentCommand) ([]command.ProjectContext, error)
+}
+
 //go:generate pegomock generate github.com/runatlantis/atlantis/server/events --package mocks -o mocks/mock_project_command_builder.go ProjectCommandBuilder
 
 // ProjectCommandBuilder builds commands that run on individual projects.
@@ -198,6 +209,7 @@ type ProjectCommandBuilder interface {
 	ProjectVersionCommandBuilder
 	ProjectImportCommandBuilder
 	ProjectStateCommandBuilder
+	ProjectValidateCommandBuilder
 }
 
 // DefaultProjectCommandBuilder implements ProjectCommandBuilder.
@@ -242,13 +254,15 @@ type DefaultProjectCommandBuilder struct {
 	SilenceNoProjects bool
 	// User config option: Include git untracked files in the modified file list.
 	IncludeGitUntrackedFiles bool
+	// User config option: Allow commands to run in workspaces that aren't configured for a project's dir.
+	EnableAdhocWorkspaces bool
 	// Handles the actual running of Terraform commands.
 	TerraformExecutor terraform.Client
 }
 
 // See ProjectCommandBuilder.BuildAutoplanCommands.
 func (p *DefaultProjectCommandBuilder) BuildAutoplanCommands(ctx *command.Context) ([]command.ProjectContext, error) {
-	projCtxs, err := p.buildAllCommandsByCfg(ctx, command.Plan, "", nil, false)
+	projCtxs, err := p.buildAllCommandsByCfg(ctx, command.Plan, "", nil, false, true)
 	if err != nil {
 		return nil, err
 	}
@@ -258,6 +272,7 @@ func (p *DefaultProjectCommandBuilder) BuildAutoplanCommands(ctx *command.Contex
 			ctx.Log.Debug("ignoring project at dir %q, workspace: %q because autoplan is disabled", projCtx.RepoRelDir, projCtx.Workspace)
 			continue
 		}
+		projCtx.Autoplan = true
 		autoplanEnabled = append(autoplanEnabled, projCtx)
 	}
 	return autoplanEnabled, nil
@@ -265,22 +280,47 @@ func (p *DefaultProjectCommandBuilder) BuildAutoplanCommands(ctx *command.Contex
 
 // See ProjectCommandBuilder.BuildPlanCommands.
 func (p *DefaultProjectCommandBuilder) BuildPlanCommands(ctx *command.Context, cmd *CommentCommand) ([]command.ProjectContext, error) {
+	var pcc []command.ProjectContext
+	var err error
 	if !cmd.IsForSpecificProject() {
 		ctx.Log.Debug("Building plan command for all affected projects")
-		return p.buildAllCommandsByCfg(ctx, cmd.CommandName(), cmd.SubName, cmd.Flags, cmd.Verbose)
+		pcc, err = p.buildAllCommandsByCfg(ctx, cmd.CommandName(), cmd.SubName, cmd.Flags, cmd.Verbose, false)
+	} else {
+		ctx.Log.Debug("Building plan command for specific project with directory: '%v', workspace: '%v', project: '%v'",
+			cmd.RepoRelDir, cmd.Workspace, cmd.ProjectName)
+		pcc, err = p.buildProjectPlanCommand(ctx, cmd)
+	}
+	if err != nil {
+		return pcc, err
+	}
+	for i := range pcc {
+		pcc[i].SkipFmtCheck = cmd.SkipFmtCheck
+		if cmd.PlanPreset == "" {
+			continue
+		}
+		args, ok := pcc[i].PlanPresets[cmd.PlanPreset]
+		if !ok {
+			return nil, fmt.Errorf("plan preset %q isn't configured for project at dir %q, workspace %q", cmd.PlanPreset, pcc[i].RepoRelDir, pcc[i].Workspace)
+		}
+		pcc[i].PlanPresetArgs = escapeArgs(args)
 	}
-	ctx.Log.Debug("Building plan command for specific project with directory: '%v', workspace: '%v', project: '%v'",
-		cmd.RepoRelDir, cmd.Workspace, cmd.ProjectName)
-	pcc, err := p.buildProjectPlanCommand(ctx, cmd)
-	return pcc, err
+	return pcc, nil
 }
 
 // See ProjectCommandBuilder.BuildApplyCommands.
 func (p *DefaultProjectCommandBuilder) BuildApplyCommands(ctx *command.Context, cmd *CommentCommand) ([]command.ProjectContext, error) {
+	var pac []command.ProjectContext
+	var err error
 	if !cmd.IsForSpecificProject() {
-		return p.buildAllProjectCommandsByPlan(ctx, cmd)
+		pac, err = p.buildAllProjectCommandsByPlan(ctx, cmd)
+	} else {
+		pac, err = p.buildProjectCommand(ctx, cmd)
+	}
+	for i := range pac {
+		pac[i].AllowDestroy = cmd.AllowDestroy
+		pac[i].StateLockTimeout = cmd.StateLockTimeout
+		pac[i].SkipStateLock = cmd.SkipStateLock
 	}
-	pac, err := p.buildProjectCommand(ctx, cmd)
 	return pac, err
 }
 
@@ -303,7 +343,7 @@ func (p *DefaultProjectCommandBuilder) BuildVersionCommands(ctx *command.Context
 func (p *DefaultProjectCommandBuilder) BuildImportCommands(ctx *command.Context, cmd *CommentCommand) ([]command.ProjectContext, error) {
 	if !cmd.IsForSpecificProject() {
 		// import discard a plan file, so use buildAllCommandsByCfg instead buildAllProjectCommandsByPlan.
-		return p.buildAllCommandsByCfg(ctx, cmd.CommandName(), cmd.SubName, cmd.Flags, cmd.Verbose)
+		return p.buildAllCommandsByCfg(ctx, cmd.CommandName(), cmd.SubName, cmd.Flags, cmd.Verbose, false)
 	}
 	return p.buildProjectCommand(ctx, cmd)
 }
@@ -311,14 +351,24 @@ func (p *DefaultProjectCommandBuilder) BuildImportCommands(ctx *command.Context,
 func (p *DefaultProjectCommandBuilder) BuildStateRmCommands(ctx *command.Context, cmd *CommentCommand) ([]command.ProjectContext, error) {
 	if !cmd.IsForSpecificProject() {
 		// state rm discard a plan file, so use buildAllCommandsByCfg instead buildAllProjectCommandsByPlan.
-		return p.buildAllCommandsByCfg(ctx, cmd.CommandName(), cmd.SubName, cmd.Flags, cmd.Verbose)
+		return p.buildAllCommandsByCfg(ctx, cmd.CommandName(), cmd.SubName, cmd.Flags, cmd.Verbose, false)
 	}
 	return p.buildProjectCommand(ctx, cmd)
 }
 
+func (p *DefaultProjectCommandBuilder) BuildValidateCommands(ctx *command.Context, cmd *CommentCommand) ([]command.ProjectContext, error) {
+	// validate doesn't need a plan so, like plan, it clones the pull request
+	// if needed instead of requiring an existing working directory.
+	if !cmd.IsForSpecificProject() {
+		return p.buildAllCommandsByCfg(ctx, cmd.CommandName(), cmd.SubName, cmd.Flags, cmd.Verbose, false)
+	}
+	return p.buildProjectPlanCommand(ctx, cmd)
+}
+
 // buildAllCommandsByCfg builds init contexts for all projects we determine were
-// modified in this ctx.
-func (p *DefaultProjectCommandBuilder) buildAllCommandsByCfg(ctx *command.Context, cmdName command.Name, subCmdName string, commentFlags []string, verbose bool) ([]command.ProjectContext, error) {
+// modified in this ctx. If autoplan is true, projects with autoplan disabled
+// don't count as modified when deciding whether the clone can be skipped.
+func (p *DefaultProjectCommandBuilder) buildAllCommandsByCfg(ctx *command.Context, cmdName command.Name, subCmdName string, commentFlags []string, verbose bool, autoplan bool) ([]command.ProjectContext, error) {
 	// We'll need the list of modified files.
 	modifiedFiles, err := p.VCSClient.GetModifiedFiles(ctx.Pull.BaseRepo, ctx.Pull)
 	if err != nil {
@@ -336,7 +386,9 @@ func (p *DefaultProjectCommandBuilder) buildAllCommandsByCfg(ctx *command.Contex
 
 	ctx.Log.Debug("%d files were modified in this pull request. Modified files: %v", len(modifiedFiles), modifiedFiles)
 
-	if p.SkipCloneNoChanges && p.VCSClient.SupportsSingleFileDownload(ctx.Pull.BaseRepo) {
+	// Generated projects are only known after cloning so the clone can't be
+	// skipped for repos with a project_generator.
+	if p.SkipCloneNoChanges && p.VCSClient.SupportsSingleFileDownload(ctx.Pull.BaseRepo) && p.GlobalCfg.ProjectGenerator(ctx.Pull.BaseRepo.ID()) == "" {
 		repoCfgFile := p.GlobalCfg.RepoConfigFile(ctx.Pull.BaseRepo.ID())
 		hasRepoCfg, repoCfgData, err := p.VCSClient.GetFileContent(ctx.Pull, repoCfgFile)
 		if err != nil {
@@ -355,6 +407,9 @@ func (p *DefaultProjectCommandBuilder) buildAllCommandsByCfg(ctx *command.Contex
 					return nil, err
 				}
 				ctx.Log.Info("%d projects are changed on MR %q based on their when_modified config", len(matchingProjects), ctx.Pull.Num)
+				if autoplan {
+					matchingProjects = autoplanEnabledProjects(ctx.Log, matchingProjects)
+				}
 				if len(matchingProjects) == 0 {
 					ctx.Log.Info("skipping repo clone since no project was modified")
 					return []command.ProjectContext{}, nil
@@ -403,6 +458,10 @@ func (p *DefaultProjectCommandBuilder) buildAllCommandsByCfg(ctx *command.Contex
 		}
 		ctx.Log.Info("successfully parsed %s file", repoCfgFile)
 	}
+	repoCfg, hasRepoCfg, err = p.addGeneratedProjects(ctx, repoDir, repoCfg, hasRepoCfg)
+	if err != nil {
+		return nil, err
+	}
 
 	moduleInfo, err := FindModuleProjects(repoDir, p.AutoDetectModuleFiles)
 	if err != nil {
@@ -465,6 +524,9 @@ func (p *DefaultProjectCommandBuilder) buildAllCommandsByCfg(ctx *command.Contex
 		// build a module index for projects that are explicitly included
 		modifiedProjects := p.ProjectFinder.DetermineProjects(ctx.Log, modifiedFiles, ctx.Pull.BaseRepo.FullName, repoDir, p.AutoplanFileList, moduleInfo)
 		ctx.Log.Info("automatically determined that there were %d projects modified in this pull request: %s", len(modifiedProjects), modifiedProjects)
+		// Tracks which dir each generated project name came from so we can
+		// report collisions.
+		projectNameDirs := make(map[string]string)
 		for _, mp := range modifiedProjects {
 			ctx.Log.Debug("determining config for project at dir: %q", mp.Path)
 			pWorkspace, err := p.ProjectFinder.DetermineWorkspaceFromHCL(ctx.Log, repoDir)
@@ -473,6 +535,16 @@ func (p *DefaultProjectCommandBuilder) buildAllCommandsByCfg(ctx *command.Contex
 			}
 
 			pCfg := p.GlobalCfg.DefaultProjCfg(ctx.Log, ctx.Pull.BaseRepo.ID(), mp.Path, pWorkspace)
+			pCfg.Name, err = p.GlobalCfg.AutodiscoveredProjectName(ctx.Pull.BaseRepo.ID(), ctx.Pull.BaseRepo.FullName, mp.Path)
+			if err != nil {
+				return nil, err
+			}
+			if pCfg.Name != "" {
+				if otherDir, ok := projectNameDirs[pCfg.Name]; ok {
+					return nil, fmt.Errorf("projects at dirs %q and %q both have the name %q, %s must generate unique names", otherDir, mp.Path, pCfg.Name, valid.ProjectNameTemplateKey)
+				}
+				projectNameDirs[pCfg.Name] = mp.Path
+			}
 
 			projCtxs = append(projCtxs,
 				p.ProjectCommandContextBuilder.BuildProjectContext(
@@ -499,7 +571,21 @@ func (p *DefaultProjectCommandBuilder) buildAllCommandsByCfg(ctx *command.Contex
 	return projCtxs, nil
 }
 
-// buildProjectPlanCommand builds a plan context for a single project.
+// autoplanEnabledProjects returns the projects that have autoplan enabled.
+func autoplanEnabledProjects(log logging.SimpleLogging, projects []valid.Project) []valid.Project {
+	var enabled []valid.Project
+	for _, project := range projects {
+		if !project.Autoplan.Enabled {
+			log.Debug("ignoring project at dir %q, workspace: %q because autoplan is disabled", project.Dir, project.Workspace)
+			continue
+		}
+		enabled = append(enabled, project)
+	}
+	return enabled
+}
+
+// buildProjectPlanCommand builds a plan context for a single project. It's
+// also used for other commands that clone the pull request, ex. validate.
 // cmd must be for only one project.
 func (p *DefaultProjectCommandBuilder) buildProjectPlanCommand(ctx *command.Context, cmd *CommentCommand) ([]command.ProjectContext, error) {
 	workspace := DefaultWorkspace
@@ -598,11 +684,31 @@ func (p *DefaultProjectCommandBuilder) buildProjectPlanCommand(ctx *command.Cont
 		repoRelDir = cmd.RepoRelDir
 	}
 
+	// A project's workspace is set by its config so when it's planned in
+	// several workspaces we plan its dir in each workspace instead, which
+	// uses the project configured for that dir and workspace if there is one.
+	projectName := cmd.ProjectName
+	if projectName != "" && len(cmd.Workspaces) > 0 {
+		projects, _, autodiscoveredDir, err := p.getCfg(ctx, projectName, repoRelDir, workspace, defaultRepoDir)
+		if err != nil {
+			return pcc, err
+		}
+		switch {
+		case autodiscoveredDir != "":
+			repoRelDir = autodiscoveredDir
+		case len(projects) != 1:
+			return pcc, fmt.Errorf("project %q must match exactly one project to plan it in workspace %q, matched %d", projectName, workspace, len(projects))
+		default:
+			repoRelDir = projects[0].Dir
+		}
+		projectName = ""
+	}
+
 	return p.buildProjectCommandCtx(
 		ctx,
-		command.Plan,
+		cmd.CommandName(),
 		"",
-		cmd.ProjectName,
+		projectName,
 		cmd.Flags,
 		defaultRepoDir,
 		repoRelDir,
@@ -612,31 +718,46 @@ func (p *DefaultProjectCommandBuilder) buildProjectPlanCommand(ctx *command.Cont
 }
 
 // getCfg returns the atlantis.yaml config (if it exists) for this project. If
-// there is no config, then projectCfg and repoCfg will be nil.
-func (p *DefaultProjectCommandBuilder) getCfg(ctx *command.Context, projectName string, dir string, workspace string, repoDir string) (projectsCfg []valid.Project, repoCfg *valid.RepoCfg, err error) {
+// there is no config, then projectCfg and repoCfg will be nil. If projectName
+// is the name of a project found via autodiscovery, autodiscoveredDir is its
+// dir.
+func (p *DefaultProjectCommandBuilder) getCfg(ctx *command.Context, projectName string, dir string, workspace string, repoDir string) (projectsCfg []valid.Project, repoCfg *valid.RepoCfg, autodiscoveredDir string, err error) {
 	repoCfgFile := p.GlobalCfg.RepoConfigFile(ctx.Pull.BaseRepo.ID())
 	hasRepoCfg, err := p.ParserValidator.HasRepoCfg(repoDir, repoCfgFile)
 	if err != nil {
 		err = errors.Wrapf(err, "looking for %s file in %q", repoCfgFile, repoDir)
 		return
 	}
-	if !hasRepoCfg {
-		if projectName != "" {
-			err = fmt.Errorf("cannot specify a project name unless an %s file exists to configure projects", repoCfgFile)
+
+	var repoConfig valid.RepoCfg
+	if hasRepoCfg {
+		repoConfig, err = p.ParserValidator.ParseRepoCfg(repoDir, p.GlobalCfg, ctx.Pull.BaseRepo.ID(), ctx.Pull.BaseBranch)
+		if err != nil {
 			return
 		}
-		return
 	}
-
-	var repoConfig valid.RepoCfg
-	repoConfig, err = p.ParserValidator.ParseRepoCfg(repoDir, p.GlobalCfg, ctx.Pull.BaseRepo.ID(), ctx.Pull.BaseBranch)
+	repoConfig, hasRepoCfg, err = p.addGeneratedProjects(ctx, repoDir, repoConfig, hasRepoCfg)
 	if err != nil {
 		return
 	}
+	if !hasRepoCfg {
+		if projectName == "" {
+			return
+		}
+		if autodiscoveredDir, err = p.autodiscoveredProjectDir(ctx, projectName, repoDir); err == nil && autodiscoveredDir == "" {
+			err = fmt.Errorf("cannot specify a project name unless an %s file exists to configure projects", repoCfgFile)
+		}
+		return
+	}
 	repoCfg = &repoConfig
 
 	// If they've specified a project by name we look it up. Otherwise we
 	// use the dir and workspace.
+	if projectName != "" && len(repoCfg.Projects) == 0 {
+		if autodiscoveredDir, err = p.autodiscoveredProjectDir(ctx, projectName, repoDir); err != nil || autodiscoveredDir != "" {
+			return
+		}
+	}
 	if projectName != "" {
 		if p.EnableRegExpCmd {
 			projectsCfg = repoCfg.FindProjectsByName(projectName)
@@ -668,6 +789,58 @@ func (p *DefaultProjectCommandBuilder) getCfg(ctx *command.Context, projectName
 	return
 }
 
+// addGeneratedProjects adds the projects generated by the server-side
+// project_generator of the repo cloned at repoDir, if it has one, to repoCfg.
+// hasRepoCfg is whether the repo has an atlantis.yaml. Generated projects are
+// treated as if they were defined in it, so it returns true if it ran a
+// generator.
+func (p *DefaultProjectCommandBuilder) addGeneratedProjects(ctx *command.Context, repoDir string, repoCfg valid.RepoCfg, hasRepoCfg bool) (valid.RepoCfg, bool, error) {
+	repoID := ctx.Pull.BaseRepo.ID()
+	generator := p.GlobalCfg.ProjectGenerator(repoID)
+	if generator == "" {
+		return repoCfg, hasRepoCfg, nil
+	}
+	projectsData, err := runProjectGenerator(ctx, generator, repoDir)
+	if err != nil {
+		return repoCfg, hasRepoCfg, err
+	}
+	numProjects := len(repoCfg.Projects)
+	repoCfg, err = p.ParserValidator.AddGeneratedProjects(repoCfg, projectsData, p.GlobalCfg, repoID, ctx.Pull.BaseBranch)
+	if err != nil {
+		return repoCfg, hasRepoCfg, errors.Wrapf(err, "parsing projects generated by %s", valid.ProjectGeneratorKey)
+	}
+	ctx.Log.Info("%s generated %d projects", valid.ProjectGeneratorKey, len(repoCfg.Projects)-numProjects)
+	return repoCfg, true, nil
+}
+
+// autodiscoveredProjectDir returns the dir of the project, among the projects
+// autodiscovery finds in the pull request cloned at repoDir, that the
+// server-side project_name_template names projectName. It returns an empty
+// dir if there's no such project.
+func (p *DefaultProjectCommandBuilder) autodiscoveredProjectDir(ctx *command.Context, projectName string, repoDir string) (string, error) {
+	if repo := p.GlobalCfg.MatchingRepo(ctx.Pull.BaseRepo.ID()); repo == nil || repo.ProjectNameTemplate == "" {
+		return "", nil
+	}
+	modifiedFiles, err := p.VCSClient.GetModifiedFiles(ctx.Pull.BaseRepo, ctx.Pull)
+	if err != nil {
+		return "", err
+	}
+	moduleInfo, err := FindModuleProjects(repoDir, p.AutoDetectModuleFiles)
+	if err != nil {
+		ctx.Log.Warn("error(s) loading project module dependencies: %s", err)
+	}
+	for _, mp := range p.ProjectFinder.DetermineProjects(ctx.Log, modifiedFiles, ctx.Pull.BaseRepo.FullName, repoDir, p.AutoplanFileList, moduleInfo) {
+		name, err := p.GlobalCfg.AutodiscoveredProjectName(ctx.Pull.BaseRepo.ID(), ctx.Pull.BaseRepo.FullName, mp.Path)
+		if err != nil {
+			return "", err
+		}
+		if name == projectName {
+			return mp.Path, nil
+		}
+	}
+	return "", nil
+}
+
 // buildAllProjectCommandsByPlan builds contexts for a command for every project that has
 // pending plans in this ctx.
 func (p *DefaultProjectCommandBuilder) buildAllProjectCommandsByPlan(ctx *command.Context, commentCmd *CommentCommand) ([]command.ProjectContext, error) {
@@ -766,10 +939,16 @@ func (p *DefaultProjectCommandBuilder) buildProjectCommandCtx(ctx *command.Conte
 	workspace string,
 	verbose bool) ([]command.ProjectContext, error) {
 
-	matchingProjects, repoCfgPtr, err := p.getCfg(ctx, projectName, repoRelDir, workspace, repoDir)
+	matchingProjects, repoCfgPtr, autodiscoveredDir, err := p.getCfg(ctx, projectName, repoRelDir, workspace, repoDir)
 	if err != nil {
 		return []command.ProjectContext{}, err
 	}
+	if autodiscoveredDir != "" {
+		repoRelDir = autodiscoveredDir
+	}
+	if len(matchingProjects) == 0 && projectName == "" && p.EnableAdhocWorkspaces {
+		matchingProjects = p.adhocWorkspaceProjects(ctx, repoCfgPtr, repoRelDir, workspace)
+	}
 	var projCtxs []command.ProjectContext
 	var projCfg valid.MergedProjectCfg
 	automerge := p.EnableAutoMerge
@@ -823,6 +1002,12 @@ func (p *DefaultProjectCommandBuilder) buildProjectCommandCtx(ctx *command.Conte
 		}
 
 		projCfg = p.GlobalCfg.DefaultProjCfg(ctx.Log, ctx.Pull.BaseRepo.ID(), repoRelDir, workspace)
+		if repoCfgPtr == nil || len(repoCfgPtr.Projects) == 0 {
+			projCfg.Name, err = p.GlobalCfg.AutodiscoveredProjectName(ctx.Pull.BaseRepo.ID(), ctx.Pull.BaseRepo.FullName, repoRelDir)
+			if err != nil {
+				return []command.ProjectContext{}, err
+			}
+		}
 		projCtxs = append(projCtxs,
 			p.ProjectCommandContextBuilder.BuildProjectContext(
 				ctx,
@@ -853,9 +1038,32 @@ func (p *DefaultProjectCommandBuilder) buildProjectCommandCtx(ctx *command.Conte
 // then it's likely that if we're running a command for a workspace that isn't
 // defined then they probably just typed the workspace name wrong.
 func (p *DefaultProjectCommandBuilder) validateWorkspaceAllowed(repoCfg *valid.RepoCfg, repoRelDir string, workspace string) error {
-	if repoCfg == nil {
+	if repoCfg == nil || p.EnableAdhocWorkspaces {
 		return nil
 	}
 
 	return repoCfg.ValidateWorkspaceAllowed(repoRelDir, workspace)
 }
+
+// adhocWorkspaceProjects returns the project configured in repoRelDir with
+// its workspace replaced by workspace, so that running a command in a
+// workspace that isn't configured still uses the dir's workflow, versions,
+// etc. It returns nil if repoRelDir doesn't have exactly one project, in which
+// case the default project config is used.
+func (p *DefaultProjectCommandBuilder) adhocWorkspaceProjects(ctx *command.Context, repoCfg *valid.RepoCfg, repoRelDir string, workspace string) []valid.Project {
+	if repoCfg == nil {
+		return nil
+	}
+	dirProjects := repoCfg.FindProjectsByDir(repoRelDir)
+	if len(dirProjects) != 1 {
+		return nil
+	}
+	project := dirProjects[0]
+	ctx.Log.Debug("using config of project in dir %q for ad-hoc workspace %q", repoRelDir, workspace)
+	project.Workspace = workspace
+	// The project's name refers to its configured workspace so we don't
+	// keep it, otherwise commands that look up the plan by project name
+	// would run in the configured workspace instead of this one.
+	project.Name = nil
+	return []valid.Project{project}
+}
@@ -442,6 +442,7 @@ repos:
 - id: /.*/
   workflow: default
   allowed_overrides: [workflow]
+  allowed_workflows: [custom]
 workflows:
   default:
     plan:
@@ -673,6 +674,7 @@ projects:
 				false,
 				false,
 				false,
+				false,
 				statsScope,
 				logger,
 				terraformClient,
@@ -888,6 +890,7 @@ projects:
 				false,
 				false,
 				false,
+				false,
 				statsScope,
 				logger,
 				terraformClient,
@@ -1137,6 +1140,7 @@ workflows:
 				false,
 				false,
 				false,
+				false,
 				statsScope,
 				logger,
 				terraformClient,
@@ -1293,6 +1297,7 @@ projects:
 				false,
 				true,
 				false,
+				false,
 				statsScope,
 				logger,
 				terraformClient,
@@ -33,6 +33,7 @@ var defaultUserConfig = struct {
 	RestrictFileList         bool
 	SilenceNoProjects        bool
 	IncludeGitUntrackedFiles bool
+	EnableAdhocWorkspaces    bool
 }{
 	SkipCloneNoChanges:       false,
 	EnableRegExpCmd:          false,
@@ -44,6 +45,7 @@ var defaultUserConfig = struct {
 	RestrictFileList:         false,
 	SilenceNoProjects:        false,
 	IncludeGitUntrackedFiles: true,
+	EnableAdhocWorkspaces:    false,
 }
 
 func TestDefaultProjectCommandBuilder_BuildAutoplanCommands(t *testing.T) {
@@ -194,6 +196,7 @@ projects:
 				userConfig.RestrictFileList,
 				userConfig.SilenceNoProjects,
 				userConfig.IncludeGitUntrackedFiles,
+				userConfig.EnableAdhocWorkspaces,
 				scope,
 				logger,
 				terraformClient,
@@ -213,11 +216,361 @@ projects:
 				Equals(t, expCtx.ProjectName, actCtx.ProjectName)
 				Equals(t, expCtx.RepoRelDir, actCtx.RepoRelDir)
 				Equals(t, expCtx.Workspace, actCtx.Workspace)
+				Assert(t, actCtx.Autoplan, "exp autoplan project ctx")
 			}
 		})
 	}
 }
 
+// Test that autodiscovered projects are named using the server-side
+// project_name_template and that name collisions are reported.
+func TestDefaultProjectCommandBuilder_BuildAutoplanCommands_ProjectNameTemplate(t *testing.T) {
+	cases := []struct {
+		Description         string
+		ProjectNameTemplate string
+		ModifiedFiles       []string
+		expNames            []string
+		expErr              string
+	}{
+		{
+			Description:         "no template",
+			ProjectNameTemplate: "",
+			ModifiedFiles:       []string{"envs/prod/main.tf"},
+			expNames:            []string{""},
+		},
+		{
+			Description:         "strip prefix and join with dashes",
+			ProjectNameTemplate: `{{ .Path | trimPrefix "envs/" | replace "/" "-" }}`,
+			ModifiedFiles:       []string{"envs/prod/main.tf", "envs/staging/app/main.tf"},
+			expNames:            []string{"prod", "staging-app"},
+		},
+		{
+			Description:         "collision",
+			ProjectNameTemplate: `{{ .Path | base }}`,
+			ModifiedFiles:       []string{"envs/prod/main.tf", "regions/prod/main.tf"},
+			expErr:              "projects at dirs \"envs/prod\" and \"regions/prod\" both have the name \"prod\", project_name_template must generate unique names",
+		},
+	}
+
+	logger := logging.NewNoopLogger(t)
+	scope, _, _ := metrics.NewLoggingScope(logger, "atlantis")
+	userConfig := defaultUserConfig
+
+	terraformClient := terraform_mocks.NewMockClient()
+	When(terraformClient.ListAvailableVersions(Any[logging.SimpleLogging]())).ThenReturn([]string{}, nil)
+
+	for _, c := range cases {
+		t.Run(c.Description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			tmpDir := DirStructure(t, map[string]interface{}{
+				"envs": map[string]interface{}{
+					"prod": map[string]interface{}{
+						"main.tf": nil,
+					},
+					"staging": map[string]interface{}{
+						"app": map[string]interface{}{
+							"main.tf": nil,
+						},
+					},
+				},
+				"regions": map[string]interface{}{
+					"prod": map[string]interface{}{
+						"main.tf": nil,
+					},
+				},
+			})
+
+			workingDir := mocks.NewMockWorkingDir()
+			When(workingDir.Clone(Any[models.Repo](), Any[models.PullRequest](), Any[string]())).ThenReturn(tmpDir, false, nil)
+			vcsClient := vcsmocks.NewMockClient()
+			When(vcsClient.GetModifiedFiles(Any[models.Repo](), Any[models.PullRequest]())).ThenReturn(c.ModifiedFiles, nil)
+
+			globalCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+			globalCfg.Repos[0].ProjectNameTemplate = c.ProjectNameTemplate
+
+			builder := events.NewProjectCommandBuilder(
+				false,
+				&config.ParserValidator{},
+				&events.DefaultProjectFinder{},
+				vcsClient,
+				workingDir,
+				events.NewDefaultWorkingDirLocker(),
+				globalCfg,
+				&events.DefaultPendingPlanFinder{},
+				&events.CommentParser{ExecutableName: "atlantis"},
+				userConfig.SkipCloneNoChanges,
+				userConfig.EnableRegExpCmd,
+				userConfig.EnableAutoMerge,
+				userConfig.EnableParallelPlan,
+				userConfig.EnableParallelApply,
+				userConfig.AutoDetectModuleFiles,
+				userConfig.AutoplanFileList,
+				userConfig.RestrictFileList,
+				userConfig.SilenceNoProjects,
+				false,
+				userConfig.EnableAdhocWorkspaces,
+				scope,
+				logger,
+				terraformClient,
+			)
+
+			ctxs, err := builder.BuildAutoplanCommands(&command.Context{
+				PullRequestStatus: models.PullReqStatus{
+					Mergeable: true,
+				},
+				Log:   logger,
+				Scope: scope,
+			})
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, len(c.expNames), len(ctxs))
+			for i, actCtx := range ctxs {
+				Equals(t, c.expNames[i], actCtx.ProjectName)
+			}
+		})
+	}
+}
+
+func TestDefaultProjectCommandBuilder_BuildAutoplanCommands_ProjectGenerator(t *testing.T) {
+	cases := []struct {
+		Description      string
+		AtlantisYAML     string
+		ProjectGenerator string
+		ModifiedFiles    []string
+		expNames         []string
+		expErr           string
+	}{
+		{
+			Description:      "generated projects",
+			ProjectGenerator: `echo '[{"name": "prod", "dir": "envs/prod"}, {"name": "staging", "dir": "envs/staging", "autoplan": {"when_modified": ["*.tf", "../modules/**/*.tf"]}}]'`,
+			ModifiedFiles:    []string{"envs/prod/main.tf", "envs/modules/vpc/main.tf"},
+			expNames:         []string{"prod", "staging"},
+		},
+		{
+			Description: "generated projects are added to atlantis.yaml projects",
+			AtlantisYAML: `version: 3
+projects:
+- name: app
+  dir: app
+`,
+			ProjectGenerator: `echo '[{"name": "prod", "dir": "envs/prod"}]'`,
+			ModifiedFiles:    []string{"envs/prod/main.tf", "app/main.tf"},
+			expNames:         []string{"app", "prod"},
+		},
+		{
+			Description:      "generator reads the repo",
+			ProjectGenerator: `for d in envs/*; do echo "{\"name\": \"$(basename $d)\", \"dir\": \"$d\"}"; done | paste -sd, | sed 's/.*/[&]/'`,
+			ModifiedFiles:    []string{"envs/prod/main.tf", "envs/staging/main.tf"},
+			expNames:         []string{"prod", "staging"},
+		},
+		{
+			Description:      "generator fails",
+			ProjectGenerator: `echo "no envs found" >&2; exit 1`,
+			ModifiedFiles:    []string{"envs/prod/main.tf"},
+			expErr:           `running project_generator "echo \"no envs found\" >&2; exit 1": exit status 1: no envs found`,
+		},
+		{
+			Description:      "invalid output",
+			ProjectGenerator: `echo '[{"dir": "envs/prod", "unknown": true}]'`,
+			ModifiedFiles:    []string{"envs/prod/main.tf"},
+			expErr:           "parsing projects generated by project_generator: yaml: unmarshal errors:\n  line 1: field unknown not found in type raw.Project",
+		},
+		{
+			Description: "duplicate names",
+			AtlantisYAML: `version: 3
+projects:
+- name: prod
+  dir: app
+`,
+			ProjectGenerator: `echo '[{"name": "prod", "dir": "envs/prod"}]'`,
+			ModifiedFiles:    []string{"envs/prod/main.tf"},
+			expErr:           "parsing projects generated by project_generator: found two or more projects with name \"prod\"; project names must be unique",
+		},
+	}
+
+	logger := logging.NewNoopLogger(t)
+	scope, _, _ := metrics.NewLoggingScope(logger, "atlantis")
+	userConfig := defaultUserConfig
+
+	terraformClient := terraform_mocks.NewMockClient()
+	When(terraformClient.ListAvailableVersions(Any[logging.SimpleLogging]())).ThenReturn([]string{}, nil)
+
+	for _, c := range cases {
+		t.Run(c.Description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			tmpDir := DirStructure(t, map[string]interface{}{
+				"app": map[string]interface{}{
+					"main.tf": nil,
+				},
+				"envs": map[string]interface{}{
+					"prod": map[string]interface{}{
+						"main.tf": nil,
+					},
+					"staging": map[string]interface{}{
+						"main.tf": nil,
+					},
+				},
+			})
+			if c.AtlantisYAML != "" {
+				Ok(t, os.WriteFile(filepath.Join(tmpDir, valid.DefaultAtlantisFile), []byte(c.AtlantisYAML), 0600))
+			}
+
+			workingDir := mocks.NewMockWorkingDir()
+			When(workingDir.Clone(Any[models.Repo](), Any[models.PullRequest](), Any[string]())).ThenReturn(tmpDir, false, nil)
+			vcsClient := vcsmocks.NewMockClient()
+			When(vcsClient.GetModifiedFiles(Any[models.Repo](), Any[models.PullRequest]())).ThenReturn(c.ModifiedFiles, nil)
+
+			globalCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+			globalCfg.Repos[0].ProjectGenerator = c.ProjectGenerator
+
+			builder := events.NewProjectCommandBuilder(
+				false,
+				&config.ParserValidator{},
+				&events.DefaultProjectFinder{},
+				vcsClient,
+				workingDir,
+				events.NewDefaultWorkingDirLocker(),
+				globalCfg,
+				&events.DefaultPendingPlanFinder{},
+				&events.CommentParser{ExecutableName: "atlantis"},
+				userConfig.SkipCloneNoChanges,
+				userConfig.EnableRegExpCmd,
+				userConfig.EnableAutoMerge,
+				userConfig.EnableParallelPlan,
+				userConfig.EnableParallelApply,
+				userConfig.AutoDetectModuleFiles,
+				userConfig.AutoplanFileList,
+				userConfig.RestrictFileList,
+				userConfig.SilenceNoProjects,
+				false,
+				userConfig.EnableAdhocWorkspaces,
+				scope,
+				logger,
+				terraformClient,
+			)
+
+			ctxs, err := builder.BuildAutoplanCommands(&command.Context{
+				PullRequestStatus: models.PullReqStatus{
+					Mergeable: true,
+				},
+				Log:   logger,
+				Scope: scope,
+			})
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			var names []string
+			for _, actCtx := range ctxs {
+				names = append(names, actCtx.ProjectName)
+			}
+			Equals(t, c.expNames, names)
+		})
+	}
+}
+
+// Test that comment commands can select an autodiscovered project by the name
+// project_name_template generates for it.
+func TestDefaultProjectCommandBuilder_BuildPlanCommands_ProjectNameTemplate(t *testing.T) {
+	cases := []struct {
+		Description   string
+		ProjectName   string
+		expRepoRelDir string
+		expErr        string
+	}{
+		{
+			Description:   "generated name",
+			ProjectName:   "staging-app",
+			expRepoRelDir: "envs/staging/app",
+		},
+		{
+			Description: "unknown name",
+			ProjectName: "dev",
+			expErr:      "cannot specify a project name unless an atlantis.yaml file exists to configure projects",
+		},
+	}
+
+	logger := logging.NewNoopLogger(t)
+	scope, _, _ := metrics.NewLoggingScope(logger, "atlantis")
+	userConfig := defaultUserConfig
+
+	terraformClient := terraform_mocks.NewMockClient()
+	When(terraformClient.ListAvailableVersions(Any[logging.SimpleLogging]())).ThenReturn([]string{}, nil)
+
+	for _, c := range cases {
+		t.Run(c.Description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			tmpDir := DirStructure(t, map[string]interface{}{
+				"envs": map[string]interface{}{
+					"prod": map[string]interface{}{
+						"main.tf": nil,
+					},
+					"staging": map[string]interface{}{
+						"app": map[string]interface{}{
+							"main.tf": nil,
+						},
+					},
+				},
+			})
+
+			workingDir := mocks.NewMockWorkingDir()
+			When(workingDir.Clone(Any[models.Repo](), Any[models.PullRequest](), Any[string]())).ThenReturn(tmpDir, false, nil)
+			When(workingDir.GetWorkingDir(Any[models.Repo](), Any[models.PullRequest](), Any[string]())).ThenReturn(tmpDir, nil)
+			vcsClient := vcsmocks.NewMockClient()
+			When(vcsClient.GetModifiedFiles(Any[models.Repo](), Any[models.PullRequest]())).ThenReturn([]string{"envs/prod/main.tf", "envs/staging/app/main.tf"}, nil)
+
+			globalCfg := valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{})
+			globalCfg.Repos[0].ProjectNameTemplate = `{{ .Path | trimPrefix "envs/" | replace "/" "-" }}`
+
+			builder := events.NewProjectCommandBuilder(
+				false,
+				&config.ParserValidator{},
+				&events.DefaultProjectFinder{},
+				vcsClient,
+				workingDir,
+				events.NewDefaultWorkingDirLocker(),
+				globalCfg,
+				&events.DefaultPendingPlanFinder{},
+				&events.CommentParser{ExecutableName: "atlantis"},
+				userConfig.SkipCloneNoChanges,
+				userConfig.EnableRegExpCmd,
+				userConfig.EnableAutoMerge,
+				userConfig.EnableParallelPlan,
+				userConfig.EnableParallelApply,
+				userConfig.AutoDetectModuleFiles,
+				userConfig.AutoplanFileList,
+				userConfig.RestrictFileList,
+				userConfig.SilenceNoProjects,
+				false,
+				userConfig.EnableAdhocWorkspaces,
+				scope,
+				logger,
+				terraformClient,
+			)
+
+			ctxs, err := builder.BuildPlanCommands(&command.Context{
+				Log:   logger,
+				Scope: scope,
+			}, &events.CommentCommand{
+				Name:        command.Plan,
+				ProjectName: c.ProjectName,
+			})
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, 1, len(ctxs))
+			Equals(t, c.ProjectName, ctxs[0].ProjectName)
+			Equals(t, c.expRepoRelDir, ctxs[0].RepoRelDir)
+		})
+	}
+}
+
 // Test building a plan and apply command for one project.
 func TestDefaultProjectCommandBuilder_BuildSinglePlanApplyCommand(t *testing.T) {
 	cases := []struct {
@@ -550,6 +903,7 @@ projects:
 					userConfig.RestrictFileList,
 					c.Silenced,
 					userConfig.IncludeGitUntrackedFiles,
+					userConfig.EnableAdhocWorkspaces,
 					scope,
 					logger,
 					terraformClient,
@@ -739,6 +1093,7 @@ projects:
 				userConfig.RestrictFileList,
 				userConfig.SilenceNoProjects,
 				userConfig.IncludeGitUntrackedFiles,
+				userConfig.EnableAdhocWorkspaces,
 				scope,
 				logger,
 				terraformClient,
@@ -1007,6 +1362,7 @@ projects:
 				userConfig.RestrictFileList,
 				userConfig.SilenceNoProjects,
 				userConfig.IncludeGitUntrackedFiles,
+				userConfig.EnableAdhocWorkspaces,
 				scope,
 				logger,
 				terraformClient,
@@ -1111,6 +1467,7 @@ func TestDefaultProjectCommandBuilder_BuildMultiApply(t *testing.T) {
 		userConfig.RestrictFileList,
 		userConfig.SilenceNoProjects,
 		userConfig.IncludeGitUntrackedFiles,
+		userConfig.EnableAdhocWorkspaces,
 		scope,
 		logger,
 		terraformClient,
@@ -1206,6 +1563,7 @@ projects:
 		userConfig.RestrictFileList,
 		userConfig.SilenceNoProjects,
 		userConfig.IncludeGitUntrackedFiles,
+		userConfig.EnableAdhocWorkspaces,
 		scope,
 		logger,
 		terraformClient,
@@ -1229,6 +1587,260 @@ projects:
 	ErrEquals(t, "running commands in workspace \"notconfigured\" is not allowed because this directory is only configured for the following workspaces: default, staging", err)
 }
 
+// Test that with ad-hoc workspaces enabled, plans can run in workspaces that
+// aren't configured and use the dir's project config.
+func TestDefaultProjectCommandBuilder_AdhocWorkspace(t *testing.T) {
+	cases := []struct {
+		description     string
+		yamlCfg         string
+		workspace       string
+		lockedWorkspace string
+		expErr          string
+		expTFVersion    string
+	}{
+		{
+			description: "inherits config of single project in dir",
+			yamlCfg: `version: 3
+projects:
+- name: infra
+  dir: .
+  terraform_version: v1.5.0
+`,
+			workspace:    "staging",
+			expTFVersion: "1.5.0",
+		},
+		{
+			description: "multiple projects in dir uses default config",
+			yamlCfg: `version: 3
+projects:
+- dir: .
+  workspace: default
+- dir: .
+  workspace: production
+`,
+			workspace: "staging",
+		},
+		{
+			description: "chosen workspace is locked",
+			yamlCfg: `version: 3
+projects:
+- dir: .
+`,
+			workspace:       "staging",
+			lockedWorkspace: "staging",
+			expErr:          "the staging workspace at path . is currently locked by another command that is running for this pull request.\nWait until the previous command is complete and try again",
+		},
+		{
+			description: "other workspace is locked",
+			yamlCfg: `version: 3
+projects:
+- dir: .
+`,
+			workspace:       "staging",
+			lockedWorkspace: "default",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			workingDir := mocks.NewMockWorkingDir()
+			repoDir := DirStructure(t, map[string]interface{}{
+				"main.tf": nil,
+			})
+			err := os.WriteFile(filepath.Join(repoDir, valid.DefaultAtlantisFile), []byte(c.yamlCfg), 0600)
+			Ok(t, err)
+			When(workingDir.Clone(
+				Any[models.Repo](),
+				Any[models.PullRequest](),
+				Any[string]())).ThenReturn(repoDir, false, nil)
+			When(workingDir.GetWorkingDir(
+				Any[models.Repo](),
+				Any[models.PullRequest](),
+				Any[string]())).ThenReturn(repoDir, nil)
+
+			logger := logging.NewNoopLogger(t)
+			scope, _, _ := metrics.NewLoggingScope(logger, "atlantis")
+			userConfig := defaultUserConfig
+			userConfig.EnableAdhocWorkspaces = true
+
+			terraformClient := terraform_mocks.NewMockClient()
+			When(terraformClient.ListAvailableVersions(Any[logging.SimpleLogging]())).ThenReturn([]string{}, nil)
+
+			workingDirLocker := events.NewDefaultWorkingDirLocker()
+			if c.lockedWorkspace != "" {
+				unlockFn, err := workingDirLocker.TryLock("", 0, c.lockedWorkspace, events.DefaultRepoRelDir)
+				Ok(t, err)
+				defer unlockFn()
+			}
+
+			builder := events.NewProjectCommandBuilder(
+				false,
+				&config.ParserValidator{},
+				&events.DefaultProjectFinder{},
+				nil,
+				workingDir,
+				workingDirLocker,
+				valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{AllowRepoCfg: true}),
+				&events.DefaultPendingPlanFinder{},
+				&events.CommentParser{ExecutableName: "atlantis"},
+				userConfig.SkipCloneNoChanges,
+				userConfig.EnableRegExpCmd,
+				userConfig.EnableAutoMerge,
+				userConfig.EnableParallelPlan,
+				userConfig.EnableParallelApply,
+				userConfig.AutoDetectModuleFiles,
+				userConfig.AutoplanFileList,
+				userConfig.RestrictFileList,
+				userConfig.SilenceNoProjects,
+				userConfig.IncludeGitUntrackedFiles,
+				userConfig.EnableAdhocWorkspaces,
+				scope,
+				logger,
+				terraformClient,
+			)
+
+			ctxs, err := builder.BuildPlanCommands(&command.Context{
+				Log:   logger,
+				Scope: scope,
+			}, &events.CommentCommand{
+				RepoRelDir: ".",
+				Name:       command.Plan,
+				Workspace:  c.workspace,
+			})
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, 1, len(ctxs))
+			Equals(t, c.workspace, ctxs[0].Workspace)
+			Equals(t, ".", ctxs[0].RepoRelDir)
+			Equals(t, "", ctxs[0].ProjectName)
+			if c.expTFVersion != "" {
+				Equals(t, c.expTFVersion, ctxs[0].TerraformVersion.String())
+			}
+		})
+	}
+}
+
+// Test that a project planned in a workspace, ex. with --workspaces, plans
+// its dir in that workspace.
+func TestDefaultProjectCommandBuilder_ProjectInWorkspace(t *testing.T) {
+	yamlCfg := `version: 3
+projects:
+- name: foo
+  dir: .
+- name: foo-staging
+  dir: .
+  workspace: staging
+`
+	cases := []struct {
+		description    string
+		projectName    string
+		workspace      string
+		expErr         string
+		expProjectName string
+	}{
+		{
+			description:    "project's own workspace",
+			projectName:    "foo",
+			workspace:      "default",
+			expProjectName: "foo",
+		},
+		{
+			description:    "workspace of another project in the dir",
+			projectName:    "foo",
+			workspace:      "staging",
+			expProjectName: "foo-staging",
+		},
+		{
+			description: "workspace not configured for the dir",
+			projectName: "foo",
+			workspace:   "prod",
+			expErr:      "running commands in workspace \"prod\" is not allowed because this directory is only configured for the following workspaces: default, staging",
+		},
+		{
+			description: "unknown project",
+			projectName: "bar",
+			workspace:   "staging",
+			expErr:      "no project with name \"bar\" is defined in atlantis.yaml",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			workingDir := mocks.NewMockWorkingDir()
+			repoDir := DirStructure(t, map[string]interface{}{
+				"main.tf": nil,
+			})
+			err := os.WriteFile(filepath.Join(repoDir, valid.DefaultAtlantisFile), []byte(yamlCfg), 0600)
+			Ok(t, err)
+			When(workingDir.Clone(
+				Any[models.Repo](),
+				Any[models.PullRequest](),
+				Any[string]())).ThenReturn(repoDir, false, nil)
+			When(workingDir.GetWorkingDir(
+				Any[models.Repo](),
+				Any[models.PullRequest](),
+				Any[string]())).ThenReturn(repoDir, nil)
+
+			logger := logging.NewNoopLogger(t)
+			scope, _, _ := metrics.NewLoggingScope(logger, "atlantis")
+			userConfig := defaultUserConfig
+
+			terraformClient := terraform_mocks.NewMockClient()
+			When(terraformClient.ListAvailableVersions(Any[logging.SimpleLogging]())).ThenReturn([]string{}, nil)
+
+			builder := events.NewProjectCommandBuilder(
+				false,
+				&config.ParserValidator{},
+				&events.DefaultProjectFinder{},
+				nil,
+				workingDir,
+				events.NewDefaultWorkingDirLocker(),
+				valid.NewGlobalCfgFromArgs(valid.GlobalCfgArgs{AllowRepoCfg: true}),
+				&events.DefaultPendingPlanFinder{},
+				&events.CommentParser{ExecutableName: "atlantis"},
+				userConfig.SkipCloneNoChanges,
+				userConfig.EnableRegExpCmd,
+				userConfig.EnableAutoMerge,
+				userConfig.EnableParallelPlan,
+				userConfig.EnableParallelApply,
+				userConfig.AutoDetectModuleFiles,
+				userConfig.AutoplanFileList,
+				userConfig.RestrictFileList,
+				userConfig.SilenceNoProjects,
+				userConfig.IncludeGitUntrackedFiles,
+				userConfig.EnableAdhocWorkspaces,
+				scope,
+				logger,
+				terraformClient,
+			)
+
+			ctxs, err := builder.BuildPlanCommands(&command.Context{
+				Log:   logger,
+				Scope: scope,
+			}, &events.CommentCommand{
+				Name:        command.Plan,
+				ProjectName: c.projectName,
+				Workspace:   c.workspace,
+				Workspaces:  []string{c.workspace},
+			})
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, 1, len(ctxs))
+			Equals(t, c.workspace, ctxs[0].Workspace)
+			Equals(t, ".", ctxs[0].RepoRelDir)
+			Equals(t, c.expProjectName, ctxs[0].ProjectName)
+		})
+	}
+}
+
 // Test that extra comment args are escaped.
 func TestDefaultProjectCommandBuilder_EscapeArgs(t *testing.T) {
 	cases := []struct {
@@ -1296,6 +1908,7 @@ func TestDefaultProjectCommandBuilder_EscapeArgs(t *testing.T) {
 				userConfig.RestrictFileList,
 				userConfig.SilenceNoProjects,
 				userConfig.IncludeGitUntrackedFiles,
+				userConfig.EnableAdhocWorkspaces,
 				scope,
 				logger,
 				terraformClient,
@@ -1321,6 +1934,114 @@ func TestDefaultProjectCommandBuilder_EscapeArgs(t *testing.T) {
 	}
 }
 
+func TestDefaultProjectCommandBuilder_PlanPreset(t *testing.T) {
+	atlantisYAML := `
+version: 3
+projects:
+- name: foo
+  dir: .
+  plan_presets:
+    emergency: ["-refresh=false", "-lock-timeout=10m"]
+    injected: ["-var=a;touch pwned"]
+`
+	cases := []struct {
+		description   string
+		preset        string
+		expPresetArgs []string
+		expErr        string
+	}{
+		{
+			description: "no preset",
+		},
+		{
+			description:   "configured preset",
+			preset:        "emergency",
+			expPresetArgs: []string{`\-\r\e\f\r\e\s\h\=\f\a\l\s\e`, `\-\l\o\c\k\-\t\i\m\e\o\u\t\=\1\0\m`},
+		},
+		{
+			// Preset args are escaped like comment args so they can't run
+			// shell commands.
+			description:   "preset with shell characters",
+			preset:        "injected",
+			expPresetArgs: []string{`\-\v\a\r\=\a\;\t\o\u\c\h\ \p\w\n\e\d`},
+		},
+		{
+			description: "unknown preset",
+			preset:      "fast",
+			expErr:      `plan preset "fast" isn't configured for project at dir ".", workspace "default"`,
+		},
+	}
+
+	logger := logging.NewNoopLogger(t)
+	scope, _, _ := metrics.NewLoggingScope(logger, "atlantis")
+	userConfig := defaultUserConfig
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			tmpDir := DirStructure(t, map[string]interface{}{
+				"main.tf": nil,
+			})
+			Ok(t, os.WriteFile(filepath.Join(tmpDir, valid.DefaultAtlantisFile), []byte(atlantisYAML), 0600))
+
+			workingDir := mocks.NewMockWorkingDir()
+			When(workingDir.Clone(Any[models.Repo](), Any[models.PullRequest](), Any[string]())).ThenReturn(tmpDir, false, nil)
+			When(workingDir.GetWorkingDir(Any[models.Repo](), Any[models.PullRequest](), Any[string]())).ThenReturn(tmpDir, nil)
+			vcsClient := vcsmocks.NewMockClient()
+			When(vcsClient.GetModifiedFiles(Any[models.Repo](), Any[models.PullRequest]())).ThenReturn([]string{"main.tf"}, nil)
+
+			globalCfgArgs := valid.GlobalCfgArgs{
+				AllowRepoCfg: true,
+			}
+
+			terraformClient := terraform_mocks.NewMockClient()
+			When(terraformClient.ListAvailableVersions(Any[logging.SimpleLogging]())).ThenReturn([]string{}, nil)
+
+			builder := events.NewProjectCommandBuilder(
+				false,
+				&config.ParserValidator{},
+				&events.DefaultProjectFinder{},
+				vcsClient,
+				workingDir,
+				events.NewDefaultWorkingDirLocker(),
+				valid.NewGlobalCfgFromArgs(globalCfgArgs),
+				&events.DefaultPendingPlanFinder{},
+				&events.CommentParser{ExecutableName: "atlantis"},
+				userConfig.SkipCloneNoChanges,
+				userConfig.EnableRegExpCmd,
+				userConfig.EnableAutoMerge,
+				userConfig.EnableParallelPlan,
+				userConfig.EnableParallelApply,
+				userConfig.AutoDetectModuleFiles,
+				userConfig.AutoplanFileList,
+				userConfig.RestrictFileList,
+				userConfig.SilenceNoProjects,
+				userConfig.IncludeGitUntrackedFiles,
+				userConfig.EnableAdhocWorkspaces,
+				scope,
+				logger,
+				terraformClient,
+			)
+
+			actCtxs, err := builder.BuildPlanCommands(&command.Context{
+				Log:   logger,
+				Scope: scope,
+			}, &events.CommentCommand{
+				ProjectName: "foo",
+				Name:        command.Plan,
+				PlanPreset:  c.preset,
+			})
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, 1, len(actCtxs))
+			Equals(t, c.expPresetArgs, actCtxs[0].PlanPresetArgs)
+		})
+	}
+}
+
 // Test that terraform version is used when specified in terraform configuration
 func TestDefaultProjectCommandBuilder_TerraformVersion(t *testing.T) {
 	// For the following tests:
@@ -1467,6 +2188,7 @@ projects:
 				userConfig.RestrictFileList,
 				userConfig.SilenceNoProjects,
 				userConfig.IncludeGitUntrackedFiles,
+				userConfig.EnableAdhocWorkspaces,
 				scope,
 				logger,
 				terraformClient,
@@ -1518,6 +2240,18 @@ projects:
 		{
 			AtlantisYAML: `
 version: 3
+projects:
+- dir: dir1
+  autoplan:
+    enabled: false
+- dir: dir2`,
+			ExpectedCtxs:   0,
+			ExpectedClones: Never(),
+			ModifiedFiles:  []string{"dir1/main.tf"},
+		},
+		{
+			AtlantisYAML: `
+version: 3
 parallel_plan: true`,
 			ExpectedCtxs:   0,
 			ExpectedClones: Once(),
@@ -1568,6 +2302,7 @@ parallel_plan: true`,
 			userConfig.RestrictFileList,
 			userConfig.SilenceNoProjects,
 			userConfig.IncludeGitUntrackedFiles,
+			userConfig.EnableAdhocWorkspaces,
 			scope,
 			logger,
 			terraformClient,
@@ -1638,6 +2373,7 @@ func TestDefaultProjectCommandBuilder_WithPolicyCheckEnabled_BuildAutoplanComman
 		userConfig.RestrictFileList,
 		userConfig.SilenceNoProjects,
 		userConfig.IncludeGitUntrackedFiles,
+		userConfig.EnableAdhocWorkspaces,
 		scope,
 		logger,
 		terraformClient,
@@ -1730,6 +2466,7 @@ func TestDefaultProjectCommandBuilder_BuildVersionCommand(t *testing.T) {
 		userConfig.RestrictFileList,
 		userConfig.SilenceNoProjects,
 		userConfig.IncludeGitUntrackedFiles,
+		userConfig.EnableAdhocWorkspaces,
 		scope,
 		logger,
 		terraformClient,
@@ -1861,6 +2598,7 @@ func TestDefaultProjectCommandBuilder_BuildPlanCommands_Single_With_RestrictFile
 				userConfig.RestrictFileList,
 				userConfig.SilenceNoProjects,
 				userConfig.IncludeGitUntrackedFiles,
+				userConfig.EnableAdhocWorkspaces,
 				scope,
 				logger,
 				terraformClient,
@@ -1972,6 +2710,7 @@ func TestDefaultProjectCommandBuilder_BuildPlanCommands_with_IncludeGitUntracked
 				userConfig.RestrictFileList,
 				userConfig.SilenceNoProjects,
 				userConfig.IncludeGitUntrackedFiles,
+				userConfig.EnableAdhocWorkspaces,
 				scope,
 				logger,
 				terraformClient,
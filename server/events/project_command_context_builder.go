@@ -2,6 +2,7 @@ package events
 
 import (
 	"path/filepath"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/runatlantis/atlantis/server/core/config/valid"
@@ -108,6 +109,13 @@ func (cb *DefaultProjectCommandContextBuilder) BuildProjectContext(
 		steps = []valid.Step{{
 			StepName: "version",
 		}}
+	case command.Validate:
+		// validate only needs providers and modules so the backend isn't
+		// initialized, which also means it doesn't need backend credentials.
+		steps = []valid.Step{
+			{StepName: "init", ExtraArgs: []string{"-backend=false"}},
+			{StepName: "validate"},
+		}
 	case command.Import:
 		steps = prjCfg.Workflow.Import.Steps
 	case command.State:
@@ -243,6 +251,8 @@ func newProjectCommandContext(ctx *command.Context,
 
 	var projectPlanStatus models.ProjectPlanStatus
 	var projectPolicyStatus []models.PolicySetStatus
+	var projectPlanDestroys int
+	var projectPlannedAt time.Time
 
 	if ctx.PullStatus != nil {
 		for _, project := range ctx.PullStatus.Projects {
@@ -251,17 +261,39 @@ func newProjectCommandContext(ctx *command.Context,
 			if projCfg.Name == "" && project.RepoRelDir == projCfg.RepoRelDir {
 				projectPlanStatus = project.Status
 				projectPolicyStatus = project.PolicyStatus
+				projectPlanDestroys = project.PlanDestroys
+				projectPlannedAt = project.PlannedAt
 				break
 			}
 
 			if projCfg.Name != "" && project.ProjectName == projCfg.Name {
 				projectPlanStatus = project.Status
 				projectPolicyStatus = project.PolicyStatus
+				projectPlanDestroys = project.PlanDestroys
+				projectPlannedAt = project.PlannedAt
 				break
 			}
 		}
 	}
 
+	var commandTimeout time.Duration
+	var onFailureSteps []valid.Step
+	var descriptionVarArgs []string
+	executionOrderGroup := projCfg.ExecutionOrderGroup
+	switch cmd {
+	case command.Plan:
+		commandTimeout = projCfg.PlanTimeout
+		if projCfg.PRDescriptionVars {
+			descriptionVarArgs = prDescriptionVarArgs(ctx.Log, ctx.Pull.Body)
+		}
+	case command.Apply:
+		commandTimeout = projCfg.ApplyTimeout
+		onFailureSteps = projCfg.Workflow.Apply.OnFailure
+		if projCfg.ApplyOrder != nil {
+			executionOrderGroup = *projCfg.ApplyOrder
+		}
+	}
+
 	return command.ProjectContext{
 		CommandName:                cmd,
 		ApplyCmd:                   applyCmd,
@@ -270,18 +302,22 @@ func newProjectCommandContext(ctx *command.Context,
 		EscapedCommentArgs:         escapedCommentArgs,
 		AutomergeEnabled:           automergeEnabled,
 		DeleteSourceBranchOnMerge:  projCfg.DeleteSourceBranchOnMerge,
-		RepoLocking:                projCfg.RepoLocking,
+		RepoLocking:                projCfg.RepoLocking && !projCfg.PlanOnly,
+		PlanOnly:                   projCfg.PlanOnly,
 		CustomPolicyCheck:          projCfg.CustomPolicyCheck,
 		ParallelApplyEnabled:       parallelApplyEnabled,
 		ParallelPlanEnabled:        parallelPlanEnabled,
 		ParallelPolicyCheckEnabled: parallelPlanEnabled,
 		AutoplanEnabled:            projCfg.AutoplanEnabled,
 		Steps:                      steps,
+		OnFailureSteps:             onFailureSteps,
 		HeadRepo:                   ctx.HeadRepo,
 		Log:                        ctx.Log,
 		Scope:                      scope,
 		ProjectPlanStatus:          projectPlanStatus,
 		ProjectPolicyStatus:        projectPolicyStatus,
+		ProjectPlanDestroys:        projectPlanDestroys,
+		ProjectPlannedAt:           projectPlannedAt,
 		Pull:                       ctx.Pull,
 		ProjectName:                projCfg.Name,
 		PlanRequirements:           projCfg.PlanRequirements,
@@ -291,6 +327,9 @@ func newProjectCommandContext(ctx *command.Context,
 		RepoRelDir:                 projCfg.RepoRelDir,
 		RepoConfigVersion:          projCfg.RepoCfgVersion,
 		TerraformVersion:           projCfg.TerraformVersion,
+		TerraformBinary:            projCfg.TerraformBinary,
+		AllowedTerraformVersions:   projCfg.AllowedTerraformVersions,
+		Terragrunt:                 projCfg.Workflow.Terragrunt,
 		User:                       ctx.User,
 		Verbose:                    verbose,
 		Workspace:                  projCfg.Workspace,
@@ -299,8 +338,15 @@ func newProjectCommandContext(ctx *command.Context,
 		ClearPolicyApproval:        ctx.ClearPolicyApproval,
 		PullReqStatus:              pullStatus,
 		JobID:                      uuid.New().String(),
-		ExecutionOrderGroup:        projCfg.ExecutionOrderGroup,
+		ExecutionOrderGroup:        executionOrderGroup,
 		AbortOnExcecutionOrderFail: abortOnExcecutionOrderFail,
+		StatusContextSuffix:        projCfg.StatusContextSuffix,
+		DeploymentEnvironment:      projCfg.DeploymentEnvironment,
+		CommandTimeout:             commandTimeout,
+		TerraformParallelism:       projCfg.TerraformParallelism,
+		PlanPresets:                projCfg.PlanPresets,
+		PRDescriptionVarArgs:       descriptionVarArgs,
+		PlanWarningsAsErrors:       projCfg.PlanWarningsAsErrors,
 	}
 }
 
@@ -2,6 +2,7 @@ package events_test
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/petergtz/pegomock/v4"
 	"github.com/runatlantis/atlantis/server/core/config/valid"
@@ -127,3 +128,119 @@ func TestProjectCommandContextBuilder_PullStatus(t *testing.T) {
 		assert.True(t, result[0].AbortOnExcecutionOrderFail)
 	})
 }
+
+func TestProjectCommandContextBuilder_CommandTimeout(t *testing.T) {
+	RegisterMockTestingT(t)
+	subject := events.DefaultProjectCommandContextBuilder{
+		CommentBuilder: mocks.NewMockCommentBuilder(),
+	}
+	projCfg := valid.MergedProjectCfg{
+		RepoRelDir: "dir1",
+		Workspace:  "default",
+		Workflow: valid.Workflow{
+			Name:  valid.DefaultWorkflowName,
+			Plan:  valid.DefaultPlanStage,
+			Apply: valid.DefaultApplyStage,
+		},
+		PlanTimeout:  10 * time.Minute,
+		ApplyTimeout: time.Hour,
+	}
+	commandCtx := &command.Context{
+		Log: logging.NewNoopLogger(t),
+	}
+	terraformClient := terraform_mocks.NewMockClient()
+
+	result := subject.BuildProjectContext(commandCtx, command.Plan, "", projCfg, []string{}, "some/dir", false, false, false, false, false, terraformClient)
+	assert.Equal(t, 10*time.Minute, result[0].CommandTimeout)
+
+	result = subject.BuildProjectContext(commandCtx, command.Apply, "", projCfg, []string{}, "some/dir", false, false, false, false, false, terraformClient)
+	assert.Equal(t, time.Hour, result[0].CommandTimeout)
+
+	result = subject.BuildProjectContext(commandCtx, command.Version, "", projCfg, []string{}, "some/dir", false, false, false, false, false, terraformClient)
+	assert.Equal(t, time.Duration(0), result[0].CommandTimeout)
+}
+
+func TestProjectCommandContextBuilder_PlanOnly(t *testing.T) {
+	RegisterMockTestingT(t)
+	subject := events.DefaultProjectCommandContextBuilder{
+		CommentBuilder: mocks.NewMockCommentBuilder(),
+	}
+	projCfg := valid.MergedProjectCfg{
+		RepoRelDir: "dir1",
+		Workspace:  "default",
+		Workflow: valid.Workflow{
+			Name: valid.DefaultWorkflowName,
+			Plan: valid.DefaultPlanStage,
+		},
+		RepoLocking: true,
+	}
+	commandCtx := &command.Context{
+		Log: logging.NewNoopLogger(t),
+	}
+	terraformClient := terraform_mocks.NewMockClient()
+
+	result := subject.BuildProjectContext(commandCtx, command.Plan, "", projCfg, []string{}, "some/dir", false, false, false, false, false, terraformClient)
+	assert.True(t, result[0].RepoLocking)
+	assert.False(t, result[0].PlanOnly)
+
+	// Plans in plan-only repos never lock the project.
+	projCfg.PlanOnly = true
+	result = subject.BuildProjectContext(commandCtx, command.Plan, "", projCfg, []string{}, "some/dir", false, false, false, false, false, terraformClient)
+	assert.False(t, result[0].RepoLocking)
+	assert.True(t, result[0].PlanOnly)
+}
+
+func TestProjectCommandContextBuilder_TerraformBinary(t *testing.T) {
+	RegisterMockTestingT(t)
+	subject := events.DefaultProjectCommandContextBuilder{
+		CommentBuilder: mocks.NewMockCommentBuilder(),
+	}
+	projCfg := valid.MergedProjectCfg{
+		RepoRelDir: "dir1",
+		Workspace:  "default",
+		Workflow: valid.Workflow{
+			Name: valid.DefaultWorkflowName,
+			Plan: valid.DefaultPlanStage,
+		},
+		TerraformBinary: "/opt/terraform/1.5.7/terraform",
+	}
+	commandCtx := &command.Context{
+		Log: logging.NewNoopLogger(t),
+	}
+
+	result := subject.BuildProjectContext(commandCtx, command.Plan, "", projCfg, []string{}, "some/dir", false, false, false, false, false, terraform_mocks.NewMockClient())
+	assert.Equal(t, "/opt/terraform/1.5.7/terraform", result[0].TerraformBinary)
+}
+
+func TestProjectCommandContextBuilder_ApplyOrder(t *testing.T) {
+	RegisterMockTestingT(t)
+	subject := events.DefaultProjectCommandContextBuilder{
+		CommentBuilder: mocks.NewMockCommentBuilder(),
+	}
+	applyOrder := 0
+	projCfg := valid.MergedProjectCfg{
+		RepoRelDir: "dir1",
+		Workspace:  "default",
+		Workflow: valid.Workflow{
+			Name:  valid.DefaultWorkflowName,
+			Plan:  valid.DefaultPlanStage,
+			Apply: valid.DefaultApplyStage,
+		},
+		ExecutionOrderGroup: 2,
+		ApplyOrder:          &applyOrder,
+	}
+	commandCtx := &command.Context{
+		Log: logging.NewNoopLogger(t),
+	}
+
+	// Only apply is ordered by apply_order.
+	result := subject.BuildProjectContext(commandCtx, command.Plan, "", projCfg, []string{}, "some/dir", false, false, false, false, false, terraform_mocks.NewMockClient())
+	assert.Equal(t, 2, result[0].ExecutionOrderGroup)
+	result = subject.BuildProjectContext(commandCtx, command.Apply, "", projCfg, []string{}, "some/dir", false, false, false, false, false, terraform_mocks.NewMockClient())
+	assert.Equal(t, 0, result[0].ExecutionOrderGroup)
+
+	// Without apply_order, apply is ordered by execution_order_group.
+	projCfg.ApplyOrder = nil
+	result = subject.BuildProjectContext(commandCtx, command.Apply, "", projCfg, []string{}, "some/dir", false, false, false, false, false, terraform_mocks.NewMockClient())
+	assert.Equal(t, 2, result[0].ExecutionOrderGroup)
+}
@@ -18,12 +18,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/config/raw"
 	"github.com/runatlantis/atlantis/server/core/config/valid"
 	"github.com/runatlantis/atlantis/server/core/runtime"
+	runtimemodels "github.com/runatlantis/atlantis/server/core/runtime/models"
 	"github.com/runatlantis/atlantis/server/events/command"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
@@ -34,6 +38,10 @@ import (
 const OperationComplete = true
 
 // DirNotExistErr is an error caused by the directory not existing.
+// errOnFailureStepsFailed is wrapped by the apply error when the on_failure
+// steps run after it fail too.
+var errOnFailureStepsFailed = errors.New("the on_failure steps failed too")
+
 type DirNotExistErr struct {
 	RepoRelDir string
 }
@@ -116,6 +124,11 @@ type ProjectVersionCommandRunner interface {
 	Version(ctx command.ProjectContext) command.ProjectResult
 }
 
+type ProjectValidateCommandRunner interface {
+	// Validate runs terraform validate for the project described by ctx.
+	Validate(ctx command.ProjectContext) command.ProjectResult
+}
+
 type ProjectImportCommandRunner interface {
 	// Import runs terraform import for the project described by ctx.
 	Import(ctx command.ProjectContext) command.ProjectResult
@@ -136,6 +149,7 @@ type ProjectCommandRunner interface {
 	ProjectVersionCommandRunner
 	ProjectImportCommandRunner
 	ProjectStateCommandRunner
+	ProjectValidateCommandRunner
 }
 
 //go:generate pegomock generate --package mocks -o mocks/mock_job_url_setter.go JobURLSetter
@@ -211,6 +225,8 @@ type DefaultProjectCommandRunner struct {
 	VersionStepRunner         StepRunner
 	ImportStepRunner          StepRunner
 	StateRmStepRunner         StepRunner
+	ValidateStepRunner        StepRunner
+	InfracostStepRunner       StepRunner
 	RunStepRunner             CustomStepRunner
 	EnvStepRunner             EnvStepRunner
 	MultiEnvStepRunner        MultiEnvStepRunner
@@ -219,16 +235,51 @@ type DefaultProjectCommandRunner struct {
 	Webhooks                  WebhooksSender
 	WorkingDirLocker          WorkingDirLocker
 	CommandRequirementHandler CommandRequirementHandler
+	// PlanEncryptor encrypts planfiles at rest. If nil, planfiles are stored
+	// unencrypted.
+	PlanEncryptor *runtime.PlanEncryptor
+	// DeploymentGate waits for projects with a deployment environment to be
+	// approved before they're applied. If nil, deployments aren't created.
+	DeploymentGate *GithubDeploymentGate
+	// PlanTimeout and ApplyTimeout limit how long each process run for a
+	// plan or apply may take for projects that don't set their own timeout.
+	// 0 means no limit.
+	PlanTimeout  time.Duration
+	ApplyTimeout time.Duration
+	// FmtCheckStepRunner checks that the Terraform files of a project are
+	// formatted before it's planned. If nil, they aren't checked.
+	FmtCheckStepRunner StepRunner
+	// TerraformParallelism is the -parallelism of terraform plan and apply
+	// for projects that don't set their own. 0 uses Terraform's default.
+	TerraformParallelism int
+	// EnvVars are set for every step run for projects. The env and multienv
+	// steps of a project's workflow override them.
+	EnvVars map[string]string
+	// ApplyFromPlanSnapshot is true if the working dir of a project is copied
+	// once it's planned and the project is applied from that copy, so the
+	// apply runs on exactly the files that were planned even if the clone
+	// changed since.
+	ApplyFromPlanSnapshot bool
+	// PlanJSONStore stores the JSON of each plan, from ShowStepRunner, and
+	// PlanJSONURLGenerator links to it from the plan comment. If
+	// PlanJSONStore is nil, the JSON isn't stored.
+	PlanJSONStore        PlanJSONStore
+	PlanJSONURLGenerator PlanJSONURLGenerator
+	// TerraformVersionChecker rejects projects whose Terraform version their
+	// repo doesn't allow before they're run. If nil, any version can run.
+	TerraformVersionChecker *runtime.TerraformVersionChecker
 }
 
 // Plan runs terraform plan for the project described by ctx.
 func (p *DefaultProjectCommandRunner) Plan(ctx command.ProjectContext) command.ProjectResult {
-	planSuccess, failure, err := p.doPlan(ctx)
+	planSuccess, fmtCheck, failure, err := p.doPlan(ctx)
 	return command.ProjectResult{
 		Command:     command.Plan,
 		PlanSuccess: planSuccess,
+		FmtCheck:    fmtCheck,
 		Error:       err,
 		Failure:     failure,
+		Canceled:    errors.Is(err, runtimemodels.ErrCommandCanceled),
 		RepoRelDir:  ctx.RepoRelDir,
 		Workspace:   ctx.Workspace,
 		ProjectName: ctx.ProjectName,
@@ -256,10 +307,13 @@ func (p *DefaultProjectCommandRunner) Apply(ctx command.ProjectContext) command.
 		Command:      command.Apply,
 		Failure:      failure,
 		Error:        err,
+		Canceled:     errors.Is(err, runtimemodels.ErrCommandCanceled),
 		ApplySuccess: applyOut,
 		RepoRelDir:   ctx.RepoRelDir,
 		Workspace:    ctx.Workspace,
 		ProjectName:  ctx.ProjectName,
+
+		OnFailureStepsFailed: errors.Is(err, errOnFailureStepsFailed),
 	}
 }
 
@@ -289,6 +343,20 @@ func (p *DefaultProjectCommandRunner) Version(ctx command.ProjectContext) comman
 	}
 }
 
+// Validate runs terraform validate for the project described by ctx.
+func (p *DefaultProjectCommandRunner) Validate(ctx command.ProjectContext) command.ProjectResult {
+	validateOut, failure, err := p.doValidate(ctx)
+	return command.ProjectResult{
+		Command:         command.Validate,
+		Failure:         failure,
+		Error:           err,
+		ValidateSuccess: validateOut,
+		RepoRelDir:      ctx.RepoRelDir,
+		Workspace:       ctx.Workspace,
+		ProjectName:     ctx.ProjectName,
+	}
+}
+
 // Import runs terraform import for the project described by ctx.
 func (p *DefaultProjectCommandRunner) Import(ctx command.ProjectContext) command.ProjectResult {
 	importSuccess, failure, err := p.doImport(ctx)
@@ -463,7 +531,7 @@ func (p *DefaultProjectCommandRunner) doPolicyCheck(ctx command.ProjectContext)
 	}
 
 	var failure string
-	outputs, err := p.runSteps(ctx.Steps, ctx, absPath)
+	outputs, err := p.runStepsWithPlan(ctx.Steps, ctx, absPath)
 	var errs error
 	if err != nil {
 		for {
@@ -534,21 +602,27 @@ func (p *DefaultProjectCommandRunner) doPolicyCheck(ctx command.ProjectContext)
 	return result, failure, nil
 }
 
-func (p *DefaultProjectCommandRunner) doPlan(ctx command.ProjectContext) (*models.PlanSuccess, string, error) {
+// doPlan also returns the result of checking the formatting of the project's
+// files if it was checked.
+func (p *DefaultProjectCommandRunner) doPlan(ctx command.ProjectContext) (*models.PlanSuccess, *models.FmtCheckResult, string, error) {
+	if failure := p.TerraformVersionChecker.Check(ctx); failure != "" {
+		return nil, nil, failure, nil
+	}
+
 	// Acquire Atlantis lock for this repo/dir/workspace.
 	lockAttempt, err := p.Locker.TryLock(ctx.Log, ctx.Pull, ctx.User, ctx.Workspace, models.NewProject(ctx.Pull.BaseRepo.FullName, ctx.RepoRelDir), ctx.RepoLocking)
 	if err != nil {
-		return nil, "", errors.Wrap(err, "acquiring lock")
+		return nil, nil, "", errors.Wrap(err, "acquiring lock")
 	}
 	if !lockAttempt.LockAcquired {
-		return nil, lockAttempt.LockFailureReason, nil
+		return nil, nil, lockAttempt.LockFailureReason, nil
 	}
 	ctx.Log.Debug("acquired lock for project")
 
 	// Acquire internal lock for the directory we're going to operate in.
 	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.Pull.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace, ctx.RepoRelDir)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, "", err
 	}
 	defer unlockFn()
 
@@ -559,43 +633,178 @@ func (p *DefaultProjectCommandRunner) doPlan(ctx command.ProjectContext) (*model
 		if unlockErr := lockAttempt.UnlockFn(); unlockErr != nil {
 			ctx.Log.Err("error unlocking state after plan error: %v", unlockErr)
 		}
-		return nil, "", cloneErr
+		return nil, nil, "", cloneErr
 	}
 	projAbsPath := filepath.Join(repoDir, ctx.RepoRelDir)
 	if _, err = os.Stat(projAbsPath); os.IsNotExist(err) {
-		return nil, "", DirNotExistErr{RepoRelDir: ctx.RepoRelDir}
+		return nil, nil, "", DirNotExistErr{RepoRelDir: ctx.RepoRelDir}
 	}
 
 	failure, err := p.CommandRequirementHandler.ValidatePlanProject(repoDir, ctx)
 	if failure != "" || err != nil {
-		return nil, failure, err
+		return nil, nil, failure, err
 	}
 
-	outputs, err := p.runSteps(ctx.Steps, ctx, projAbsPath)
+	var fmtCheck *models.FmtCheckResult
+	if p.FmtCheckStepRunner != nil && !ctx.SkipFmtCheck {
+		fmtCheck, err = p.checkFmt(ctx, projAbsPath)
+		if err != nil || len(fmtCheck.UnformattedFiles) > 0 {
+			if unlockErr := lockAttempt.UnlockFn(); unlockErr != nil {
+				ctx.Log.Err("error unlocking state after fmt check: %v", unlockErr)
+			}
+			if err != nil {
+				return nil, nil, "", errors.Wrap(err, "checking terraform fmt")
+			}
+			return nil, fmtCheck, fmtCheck.Failure(), nil
+		}
+	}
+
+	// Remove the cost summary from a previous plan so it isn't shown if the
+	// infracost step doesn't run this time.
+	costSummaryFile := filepath.Join(projAbsPath, ctx.GetCostSummaryFileName())
+	if err := os.Remove(costSummaryFile); err != nil && !os.IsNotExist(err) {
+		return nil, nil, "", errors.Wrap(err, "removing previous cost summary")
+	}
+
+	if ctx.CommandTimeout == 0 {
+		ctx.CommandTimeout = p.PlanTimeout
+	}
+	if ctx.TerraformParallelism == 0 {
+		ctx.TerraformParallelism = p.TerraformParallelism
+	}
+	outputs, err := p.runStepsWithPlan(ctx.Steps, ctx, projAbsPath)
 
 	if err != nil {
 		if unlockErr := lockAttempt.UnlockFn(); unlockErr != nil {
 			ctx.Log.Err("error unlocking state after plan error: %v", unlockErr)
 		}
-		return nil, "", fmt.Errorf("%s\n%s", err, strings.Join(outputs, "\n"))
+		return nil, nil, "", fmt.Errorf("%w\n%s", err, strings.Join(outputs, "\n"))
+	}
+
+	// Files generated while planning are recorded so the apply can check
+	// they didn't change since.
+	if slices.Contains(ctx.ApplyRequirements, raw.CleanWorkingTreeRequirement) {
+		if err := saveWorkingTreeSnapshot(p.WorkingDir, repoDir, ctx); err != nil {
+			if unlockErr := lockAttempt.UnlockFn(); unlockErr != nil {
+				ctx.Log.Err("error unlocking state after plan error: %v", unlockErr)
+			}
+			return nil, nil, "", errors.Wrap(err, "recording working tree")
+		}
+	}
+
+	if p.ApplyFromPlanSnapshot && !ctx.Pull.Historical {
+		if _, err := p.WorkingDir.SavePlanSnapshot(ctx.Pull.BaseRepo, ctx.Pull, ctx.Workspace, ctx.RepoRelDir, ctx.ProjectName); err != nil {
+			if unlockErr := lockAttempt.UnlockFn(); unlockErr != nil {
+				ctx.Log.Err("error unlocking state after plan error: %v", unlockErr)
+			}
+			return nil, nil, "", errors.Wrap(err, "saving plan snapshot")
+		}
+	}
+
+	var planJSONURL string
+	if p.PlanJSONStore != nil {
+		if planJSONURL, err = p.savePlanJSON(ctx, projAbsPath); err != nil {
+			if unlockErr := lockAttempt.UnlockFn(); unlockErr != nil {
+				ctx.Log.Err("error unlocking state after plan error: %v", unlockErr)
+			}
+			return nil, nil, "", err
+		}
+	}
+
+	var costSummary string
+	if summary, err := os.ReadFile(costSummaryFile); err == nil {
+		costSummary = string(summary)
+	}
+	var tfVersion string
+	if ctx.TerraformVersion != nil {
+		tfVersion = ctx.TerraformVersion.String()
 	}
 
 	return &models.PlanSuccess{
-		LockURL:         p.LockURLGenerator.GenerateLockURL(lockAttempt.LockKey),
-		TerraformOutput: strings.Join(outputs, "\n"),
-		RePlanCmd:       ctx.RePlanCmd,
-		ApplyCmd:        ctx.ApplyCmd,
-		MergedAgain:     mergedAgain,
-	}, "", nil
+		LockURL:          p.LockURLGenerator.GenerateLockURL(lockAttempt.LockKey),
+		TerraformOutput:  strings.Join(outputs, "\n"),
+		RePlanCmd:        ctx.RePlanCmd,
+		ApplyCmd:         ctx.ApplyCmd,
+		MergedAgain:      mergedAgain,
+		CostSummary:      costSummary,
+		PlannedAt:        time.Now(),
+		TerraformVersion: tfVersion,
+		PlanJSONURL:      planJSONURL,
+	}, fmtCheck, "", nil
 }
 
-func (p *DefaultProjectCommandRunner) doApply(ctx command.ProjectContext) (applyOut string, failure string, err error) {
-	repoDir, err := p.WorkingDir.GetWorkingDir(ctx.Pull.BaseRepo, ctx.Pull, ctx.Workspace)
+// savePlanJSON stores the JSON of the project's plan and returns the URL to
+// it. Since the JSON is only extra information for tools, failing to create
+// or store it is logged rather than failing the plan, and the URL is empty.
+// It only returns an error if the planfile can't be encrypted again.
+func (p *DefaultProjectCommandRunner) savePlanJSON(ctx command.ProjectContext, projAbsPath string) (string, error) {
+	planPath := filepath.Join(projAbsPath, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+	if err := p.PlanEncryptor.DecryptFile(planPath); err != nil {
+		ctx.Log.Warn("unable to decrypt planfile to show it as json: %s", err)
+		return "", nil
+	}
+	planJSON, showErr := p.ShowStepRunner.Run(ctx, nil, projAbsPath, p.stepEnvs())
+	for _, path := range encryptedFiles(ctx, projAbsPath) {
+		if err := p.PlanEncryptor.EncryptFile(path); err != nil {
+			return "", errors.Wrapf(err, "encrypting %s", filepath.Base(path))
+		}
+	}
+	if showErr != nil {
+		ctx.Log.Warn("unable to show plan as json: %s", showErr)
+		return "", nil
+	}
+	// Remote plans and old Terraform versions can't be shown as json.
+	if planJSON == "" {
+		return "", nil
+	}
+	id, err := p.PlanJSONStore.Save(ctx.Pull, fmt.Sprintf("%s/%s/%s", ctx.RepoRelDir, ctx.Workspace, ctx.ProjectName), []byte(planJSON))
 	if err != nil {
-		if os.IsNotExist(err) {
-			return "", "", errors.New("project has not been cloned–did you run plan?")
+		ctx.Log.Warn("unable to store plan json: %s", err)
+		return "", nil
+	}
+	return p.PlanJSONURLGenerator.GeneratePlanJSONURL(id), nil
+}
+
+// checkFmt checks that the Terraform files in projAbsPath are formatted.
+func (p *DefaultProjectCommandRunner) checkFmt(ctx command.ProjectContext, projAbsPath string) (*models.FmtCheckResult, error) {
+	_, err := p.FmtCheckStepRunner.Run(ctx, nil, projAbsPath, p.stepEnvs())
+	var unformatted runtime.UnformattedFilesError
+	if errors.As(err, &unformatted) {
+		return &models.FmtCheckResult{UnformattedFiles: unformatted.Files}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &models.FmtCheckResult{}, nil
+}
+
+func (p *DefaultProjectCommandRunner) doApply(ctx command.ProjectContext) (applyOut string, failure string, err error) {
+	if ctx.Pull.Historical {
+		return "", "", errors.New("plans for historical commits can't be applied")
+	}
+	if ctx.PlanOnly {
+		return "", "This repo is plan-only, its projects can't be applied.", nil
+	}
+	if failure = p.TerraformVersionChecker.Check(ctx); failure != "" {
+		return "", failure, nil
+	}
+	var repoDir string
+	if p.ApplyFromPlanSnapshot {
+		repoDir, err = p.WorkingDir.GetPlanSnapshot(ctx.Pull.BaseRepo, ctx.Pull, ctx.Workspace, ctx.RepoRelDir, ctx.ProjectName)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return "", "", errors.New("project has no snapshot of the files it was planned with–did you run plan?")
+			}
+			return "", "", err
+		}
+	} else {
+		repoDir, err = p.WorkingDir.GetWorkingDir(ctx.Pull.BaseRepo, ctx.Pull, ctx.Workspace)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return "", "", errors.New("project has not been cloned–did you run plan?")
+			}
+			return "", "", err
 		}
-		return "", "", err
 	}
 	absPath := filepath.Join(repoDir, ctx.RepoRelDir)
 	if _, err = os.Stat(absPath); os.IsNotExist(err) {
@@ -614,7 +823,27 @@ func (p *DefaultProjectCommandRunner) doApply(ctx command.ProjectContext) (apply
 	}
 	defer unlockFn()
 
-	outputs, err := p.runSteps(ctx.Steps, ctx, absPath)
+	// Wait for the deployment environment's approval while holding the lock
+	// so the plan can't change in the meantime.
+	deploymentID, failure, err := p.DeploymentGate.Wait(ctx)
+	if failure != "" || err != nil {
+		return "", failure, err
+	}
+
+	if ctx.CommandTimeout == 0 {
+		ctx.CommandTimeout = p.ApplyTimeout
+	}
+	if ctx.TerraformParallelism == 0 {
+		ctx.TerraformParallelism = p.TerraformParallelism
+	}
+	outputs, err := p.runStepsWithPlan(ctx.Steps, ctx, absPath)
+	p.DeploymentGate.Finish(ctx, deploymentID, err)
+	if err != nil {
+		err = fmt.Errorf("%w\n%s", err, strings.Join(outputs, "\n"))
+		if len(ctx.OnFailureSteps) > 0 {
+			err = p.runOnFailureSteps(ctx, absPath, err)
+		}
+	}
 
 	p.Webhooks.Send(ctx.Log, webhooks.ApplyResult{ // nolint: errcheck
 		Workspace: ctx.Workspace,
@@ -626,12 +855,37 @@ func (p *DefaultProjectCommandRunner) doApply(ctx command.ProjectContext) (apply
 	})
 
 	if err != nil {
-		return "", "", fmt.Errorf("%s\n%s", err, strings.Join(outputs, "\n"))
+		return "", "", err
+	}
+
+	// The planfiles in the clone and the snapshot were applied so they're
+	// deleted to not be applied again.
+	if p.ApplyFromPlanSnapshot {
+		if err := p.WorkingDir.DeletePlan(ctx.Pull.BaseRepo, ctx.Pull, ctx.Workspace, ctx.RepoRelDir, ctx.ProjectName); err != nil && !os.IsNotExist(err) {
+			ctx.Log.Warn("failed to delete applied plan from working dir: %s", err)
+		}
+		if err := os.Remove(filepath.Join(absPath, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName))); err != nil && !os.IsNotExist(err) {
+			ctx.Log.Warn("failed to delete applied plan from plan snapshot: %s", err)
+		}
 	}
 
 	return strings.Join(outputs, "\n"), "", nil
 }
 
+// runOnFailureSteps runs the on_failure steps of the project after its apply
+// failed with applyErr and returns applyErr with their outcome. If they fail
+// too the error wraps errOnFailureStepsFailed so the comment can warn that the
+// project may be left broken.
+func (p *DefaultProjectCommandRunner) runOnFailureSteps(ctx command.ProjectContext, absPath string, applyErr error) error {
+	ctx.Log.Info("apply failed, running on_failure steps")
+	outputs, err := p.runSteps(ctx.OnFailureSteps, ctx, absPath)
+	if err != nil {
+		ctx.Log.Err("on_failure steps failed: %s", err)
+		return fmt.Errorf("%w\n\n%w: %s\n%s", applyErr, errOnFailureStepsFailed, err, strings.Join(outputs, "\n"))
+	}
+	return fmt.Errorf("%w\n\nThe on_failure steps ran successfully:\n%s", applyErr, strings.Join(outputs, "\n"))
+}
+
 func (p *DefaultProjectCommandRunner) doVersion(ctx command.ProjectContext) (versionOut string, failure string, err error) {
 	repoDir, err := p.WorkingDir.GetWorkingDir(ctx.Pull.BaseRepo, ctx.Pull, ctx.Workspace)
 	if err != nil {
@@ -660,7 +914,39 @@ func (p *DefaultProjectCommandRunner) doVersion(ctx command.ProjectContext) (ver
 	return strings.Join(outputs, "\n"), "", nil
 }
 
+// doValidate doesn't acquire the project lock since validate doesn't touch
+// state so it can run while another pull request holds the lock.
+func (p *DefaultProjectCommandRunner) doValidate(ctx command.ProjectContext) (validateOut string, failure string, err error) {
+	// Acquire internal lock for the directory we're going to operate in.
+	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.Pull.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace, ctx.RepoRelDir)
+	if err != nil {
+		return "", "", err
+	}
+	defer unlockFn()
+
+	// Clone is idempotent so okay to run even if the repo was already cloned.
+	repoDir, _, cloneErr := p.WorkingDir.Clone(ctx.HeadRepo, ctx.Pull, ctx.Workspace)
+	if cloneErr != nil {
+		return "", "", cloneErr
+	}
+	absPath := filepath.Join(repoDir, ctx.RepoRelDir)
+	if _, err = os.Stat(absPath); os.IsNotExist(err) {
+		return "", "", DirNotExistErr{RepoRelDir: ctx.RepoRelDir}
+	}
+
+	outputs, err := p.runSteps(ctx.Steps, ctx, absPath)
+	if err != nil {
+		return "", "", fmt.Errorf("%s\n%s", err, strings.Join(outputs, "\n"))
+	}
+
+	return strings.Join(outputs, "\n"), "", nil
+}
+
 func (p *DefaultProjectCommandRunner) doImport(ctx command.ProjectContext) (out *models.ImportSuccess, failure string, err error) {
+	if failure = p.TerraformVersionChecker.Check(ctx); failure != "" {
+		return nil, failure, nil
+	}
+
 	// Clone is idempotent so okay to run even if the repo was already cloned.
 	repoDir, _, cloneErr := p.WorkingDir.Clone(ctx.HeadRepo, ctx.Pull, ctx.Workspace)
 	if cloneErr != nil {
@@ -707,6 +993,10 @@ func (p *DefaultProjectCommandRunner) doImport(ctx command.ProjectContext) (out
 }
 
 func (p *DefaultProjectCommandRunner) doStateRm(ctx command.ProjectContext) (out *models.StateRmSuccess, failure string, err error) {
+	if failure = p.TerraformVersionChecker.Check(ctx); failure != "" {
+		return nil, failure, nil
+	}
+
 	// Clone is idempotent so okay to run even if the repo was already cloned.
 	repoDir, _, cloneErr := p.WorkingDir.Clone(ctx.HeadRepo, ctx.Pull, ctx.Workspace)
 	if cloneErr != nil {
@@ -747,11 +1037,66 @@ func (p *DefaultProjectCommandRunner) doStateRm(ctx command.ProjectContext) (out
 	}, "", nil
 }
 
+// runStepsWithPlan runs steps with the project's planfile and plan JSON
+// decrypted and encrypts them again afterwards if they still exist.
+func (p *DefaultProjectCommandRunner) runStepsWithPlan(steps []valid.Step, ctx command.ProjectContext, absPath string) ([]string, error) {
+	paths := encryptedFiles(ctx, absPath)
+	for _, path := range paths {
+		if err := p.PlanEncryptor.DecryptFile(path); err != nil {
+			return nil, err
+		}
+	}
+	outputs, err := p.runSteps(steps, ctx, absPath)
+	for _, path := range paths {
+		if encryptErr := p.PlanEncryptor.EncryptFile(path); encryptErr != nil {
+			ctx.Log.Err("encrypting %s: %s", filepath.Base(path), encryptErr)
+			if err == nil {
+				err = encryptErr
+			}
+		}
+	}
+	return outputs, err
+}
+
+// encryptedFiles returns the paths of the project's files that are encrypted
+// at rest: its planfile and the JSON of the plan written by the show step,
+// which contains the same values.
+func encryptedFiles(ctx command.ProjectContext, absPath string) []string {
+	return []string{
+		filepath.Join(absPath, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName)),
+		filepath.Join(absPath, ctx.GetShowResultFileName()),
+	}
+}
+
+// stepEnvs returns the environment variables to start a project's steps with.
+// It's a copy of EnvVars, since steps add to it.
+func (p *DefaultProjectCommandRunner) stepEnvs() map[string]string {
+	envs := make(map[string]string, len(p.EnvVars))
+	for key, val := range p.EnvVars {
+		envs[key] = val
+	}
+	return envs
+}
+
 func (p *DefaultProjectCommandRunner) runSteps(steps []valid.Step, ctx command.ProjectContext, absPath string) ([]string, error) {
 	var outputs []string
 
-	envs := make(map[string]string)
+	envs := p.stepEnvs()
+	// planOutput is the output of the last plan step, which step conditions
+	// are evaluated against. It's nil until a plan step runs.
+	var planOutput *string
 	for _, step := range steps {
+		if step.When != "" {
+			met, err := stepConditionMet(step.When, planOutput)
+			if err != nil {
+				return outputs, err
+			}
+			if !met {
+				ctx.Log.Info("skipping %s step since its condition %q isn't met", step.StepName, step.When)
+				continue
+			}
+		}
+
 		var out string
 		var err error
 		switch step.StepName {
@@ -759,6 +1104,7 @@ func (p *DefaultProjectCommandRunner) runSteps(steps []valid.Step, ctx command.P
 			out, err = p.InitStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
 		case "plan":
 			out, err = p.PlanStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
+			planOutput = &out
 		case "show":
 			_, err = p.ShowStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
 		case "policy_check":
@@ -771,6 +1117,10 @@ func (p *DefaultProjectCommandRunner) runSteps(steps []valid.Step, ctx command.P
 			out, err = p.ImportStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
 		case "state_rm":
 			out, err = p.StateRmStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
+		case "validate":
+			out, err = p.ValidateStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
+		case "infracost":
+			_, err = p.InfracostStepRunner.Run(ctx, step.ExtraArgs, absPath, envs)
 		case "run":
 			out, err = p.RunStepRunner.Run(ctx, step.RunCommand, absPath, envs, true, step.Output)
 		case "env":
@@ -792,3 +1142,19 @@ func (p *DefaultProjectCommandRunner) runSteps(steps []valid.Step, ctx command.P
 	}
 	return outputs, nil
 }
+
+// stepConditionMet returns whether condition is met by planOutput, the output
+// of the last plan step. It errors if no plan step ran yet.
+func stepConditionMet(condition valid.StepCondition, planOutput *string) (bool, error) {
+	if planOutput == nil {
+		return false, fmt.Errorf("step condition %q requires a plan step to run before it", condition)
+	}
+	noChanges := (&models.PlanSuccess{TerraformOutput: *planOutput}).NoChanges()
+	switch condition {
+	case valid.StepConditionPlanHasChanges:
+		return !noChanges, nil
+	case valid.StepConditionPlanHasNoChanges:
+		return noChanges, nil
+	}
+	return false, fmt.Errorf("unknown step condition %q", condition)
+}
@@ -17,7 +17,9 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-version"
 	. "github.com/petergtz/pegomock/v4"
@@ -132,6 +134,305 @@ func TestDefaultProjectCommandRunner_Plan(t *testing.T) {
 	}
 }
 
+func TestDefaultProjectCommandRunner_PlanJSON(t *testing.T) {
+	cases := []struct {
+		description string
+		showOut     string
+		showErr     error
+		expURL      string
+		expSaved    map[string]string
+	}{
+		{
+			description: "plan json stored",
+			showOut:     `{"format_version":"1.2"}`,
+			expURL:      "https://plan-json/2-0",
+			expSaved:    map[string]string{"2-0": `{"format_version":"1.2"}`},
+		},
+		{
+			description: "remote plan without json",
+			showOut:     "",
+			expSaved:    map[string]string{},
+		},
+		{
+			description: "show fails",
+			showErr:     errors.New("show failed"),
+			expSaved:    map[string]string{},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			mockPlan := mocks.NewMockStepRunner()
+			mockShow := mocks.NewMockStepRunner()
+			mockWorkingDir := mocks.NewMockWorkingDir()
+			mockLocker := mocks.NewMockProjectLocker()
+			store := &fakePlanJSONStore{saved: map[string]string{}}
+
+			runner := events.DefaultProjectCommandRunner{
+				Locker:                    mockLocker,
+				LockURLGenerator:          mockURLGenerator{},
+				PlanStepRunner:            mockPlan,
+				ShowStepRunner:            mockShow,
+				WorkingDir:                mockWorkingDir,
+				WorkingDirLocker:          events.NewDefaultWorkingDirLocker(),
+				CommandRequirementHandler: mocks.NewMockCommandRequirementHandler(),
+				PlanJSONStore:             store,
+				PlanJSONURLGenerator:      mockURLGenerator{},
+			}
+
+			repoDir := t.TempDir()
+			When(mockWorkingDir.Clone(Any[models.Repo](), Any[models.PullRequest](), Any[string]())).ThenReturn(repoDir, false, nil)
+			When(mockLocker.TryLock(Any[logging.SimpleLogging](), Any[models.PullRequest](), Any[models.User](), Any[string](), Any[models.Project](), AnyBool())).
+				ThenReturn(&events.TryLockResponse{LockAcquired: true, LockKey: "lock-key"}, nil)
+
+			ctx := command.ProjectContext{
+				Log:        logging.NewNoopLogger(t),
+				Steps:      []valid.Step{{StepName: "plan"}},
+				Workspace:  "default",
+				RepoRelDir: ".",
+				Pull:       models.PullRequest{Num: 2},
+			}
+			When(mockPlan.Run(Any[command.ProjectContext](), Any[[]string](), Any[string](), Any[map[string]string]())).ThenReturn("plan", nil)
+			When(mockShow.Run(Any[command.ProjectContext](), Any[[]string](), Eq(repoDir), Any[map[string]string]())).ThenReturn(c.showOut, c.showErr)
+
+			res := runner.Plan(ctx)
+
+			Assert(t, res.PlanSuccess != nil, "exp plan success, got failure %q and error %v", res.Failure, res.Error)
+			Equals(t, "plan", res.PlanSuccess.TerraformOutput)
+			Equals(t, c.expURL, res.PlanSuccess.PlanJSONURL)
+			Equals(t, c.expSaved, store.saved)
+			mockShow.VerifyWasCalledOnce().Run(Any[command.ProjectContext](), Any[[]string](), Eq(repoDir), Any[map[string]string]())
+		})
+	}
+}
+
+// Test that the plan JSON written by the show step is encrypted at rest along
+// with the planfile since it contains the same values.
+func TestDefaultProjectCommandRunner_PlanJSONEncrypted(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockPlan := mocks.NewMockStepRunner()
+	mockShow := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	encryptor, err := runtime.NewPlanEncryptor("key", nil)
+	Ok(t, err)
+
+	runner := events.DefaultProjectCommandRunner{
+		Locker:                    mockLocker,
+		LockURLGenerator:          mockURLGenerator{},
+		PlanStepRunner:            mockPlan,
+		ShowStepRunner:            mockShow,
+		WorkingDir:                mockWorkingDir,
+		WorkingDirLocker:          events.NewDefaultWorkingDirLocker(),
+		CommandRequirementHandler: mocks.NewMockCommandRequirementHandler(),
+		PlanJSONStore:             &fakePlanJSONStore{saved: map[string]string{}},
+		PlanJSONURLGenerator:      mockURLGenerator{},
+		PlanEncryptor:             encryptor,
+	}
+
+	repoDir := t.TempDir()
+	When(mockWorkingDir.Clone(Any[models.Repo](), Any[models.PullRequest](), Any[string]())).ThenReturn(repoDir, false, nil)
+	When(mockLocker.TryLock(Any[logging.SimpleLogging](), Any[models.PullRequest](), Any[models.User](), Any[string](), Any[models.Project](), AnyBool())).
+		ThenReturn(&events.TryLockResponse{LockAcquired: true, LockKey: "lock-key"}, nil)
+
+	ctx := command.ProjectContext{
+		Log:        logging.NewNoopLogger(t),
+		Steps:      []valid.Step{{StepName: "plan"}},
+		Workspace:  "default",
+		RepoRelDir: ".",
+		Pull:       models.PullRequest{Num: 2},
+	}
+	planPath := filepath.Join(repoDir, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName))
+	showPath := filepath.Join(repoDir, ctx.GetShowResultFileName())
+	planJSON := `{"format_version":"1.2"}`
+	When(mockPlan.Run(Any[command.ProjectContext](), Any[[]string](), Any[string](), Any[map[string]string]())).Then(func(_ []Param) ReturnValues {
+		Ok(t, os.WriteFile(planPath, []byte("planfile"), 0600))
+		return ReturnValues{"plan", nil}
+	})
+	When(mockShow.Run(Any[command.ProjectContext](), Any[[]string](), Eq(repoDir), Any[map[string]string]())).Then(func(_ []Param) ReturnValues {
+		Ok(t, os.WriteFile(showPath, []byte(planJSON), 0600))
+		return ReturnValues{planJSON, nil}
+	})
+
+	res := runner.Plan(ctx)
+
+	Assert(t, res.PlanSuccess != nil, "exp plan success, got failure %q and error %v", res.Failure, res.Error)
+	for path, plaintext := range map[string]string{planPath: "planfile", showPath: planJSON} {
+		data, err := os.ReadFile(path)
+		Ok(t, err)
+		Assert(t, string(data) != plaintext, "exp %s to be encrypted", path)
+		decrypted, err := encryptor.Decrypt(data)
+		Ok(t, err)
+		Equals(t, plaintext, string(decrypted))
+	}
+}
+
+func TestDefaultProjectCommandRunner_PlanFmtCheck(t *testing.T) {
+	cases := []struct {
+		description  string
+		skipFmtCheck bool
+		fmtErr       error
+		expFmtCheck  *models.FmtCheckResult
+		expFailure   string
+		expPlanned   bool
+	}{
+		{
+			description: "formatted",
+			expFmtCheck: &models.FmtCheckResult{},
+			expPlanned:  true,
+		},
+		{
+			description: "unformatted",
+			fmtErr:      runtime.UnformattedFilesError{Files: []string{"main.tf", "variables.tf"}},
+			expFmtCheck: &models.FmtCheckResult{UnformattedFiles: []string{"main.tf", "variables.tf"}},
+			expFailure:  "Terraform files aren't formatted, run `terraform fmt` and push the changes or plan with `--skip-fmt-check`:\n\n* `main.tf`\n* `variables.tf`",
+		},
+		{
+			description:  "skipped",
+			skipFmtCheck: true,
+			fmtErr:       runtime.UnformattedFilesError{Files: []string{"main.tf"}},
+			expPlanned:   true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			mockFmtCheck := mocks.NewMockStepRunner()
+			mockPlan := mocks.NewMockStepRunner()
+			mockWorkingDir := mocks.NewMockWorkingDir()
+			mockLocker := mocks.NewMockProjectLocker()
+			runner := events.DefaultProjectCommandRunner{
+				Locker:                    mockLocker,
+				LockURLGenerator:          mockURLGenerator{},
+				PlanStepRunner:            mockPlan,
+				FmtCheckStepRunner:        mockFmtCheck,
+				WorkingDir:                mockWorkingDir,
+				WorkingDirLocker:          events.NewDefaultWorkingDirLocker(),
+				CommandRequirementHandler: mocks.NewMockCommandRequirementHandler(),
+			}
+
+			repoDir := t.TempDir()
+			When(mockWorkingDir.Clone(Any[models.Repo](), Any[models.PullRequest](), Any[string]())).ThenReturn(repoDir, false, nil)
+			unlocked := false
+			When(mockLocker.TryLock(Any[logging.SimpleLogging](), Any[models.PullRequest](), Any[models.User](), Any[string](), Any[models.Project](), AnyBool())).
+				ThenReturn(&events.TryLockResponse{
+					LockAcquired: true,
+					LockKey:      "lock-key",
+					UnlockFn: func() error {
+						unlocked = true
+						return nil
+					},
+				}, nil)
+
+			ctx := command.ProjectContext{
+				Log:          logging.NewNoopLogger(t),
+				Steps:        []valid.Step{{StepName: "plan"}},
+				Workspace:    "default",
+				RepoRelDir:   ".",
+				SkipFmtCheck: c.skipFmtCheck,
+			}
+			When(mockFmtCheck.Run(ctx, nil, repoDir, map[string]string{})).ThenReturn("", c.fmtErr)
+			When(mockPlan.Run(ctx, nil, repoDir, map[string]string{})).ThenReturn("plan", nil)
+
+			res := runner.Plan(ctx)
+
+			Ok(t, res.Error)
+			Equals(t, c.expFailure, res.Failure)
+			Equals(t, c.expFmtCheck, res.FmtCheck)
+			Equals(t, c.expPlanned, res.PlanSuccess != nil)
+			// The project lock is released if the plan doesn't run.
+			Equals(t, !c.expPlanned, unlocked)
+			if c.skipFmtCheck {
+				mockFmtCheck.VerifyWasCalled(Never()).Run(Any[command.ProjectContext](), Any[[]string](), Any[string](), Any[map[string]string]())
+			}
+		})
+	}
+}
+
+func TestDefaultProjectCommandRunner_PlanStepConditions(t *testing.T) {
+	cases := []struct {
+		description string
+		steps       []valid.Step
+		planOut     string
+		expOut      string
+		expErr      string
+	}{
+		{
+			description: "plan has changes",
+			steps: []valid.Step{
+				{StepName: "plan"},
+				{StepName: "run", RunCommand: "on-changes", When: valid.StepConditionPlanHasChanges},
+				{StepName: "run", RunCommand: "on-no-changes", When: valid.StepConditionPlanHasNoChanges},
+			},
+			planOut: "Plan: 1 to add, 0 to change, 0 to destroy.",
+			expOut:  "Plan: 1 to add, 0 to change, 0 to destroy.\non-changes",
+		},
+		{
+			description: "plan has no changes",
+			steps: []valid.Step{
+				{StepName: "plan"},
+				{StepName: "run", RunCommand: "on-changes", When: valid.StepConditionPlanHasChanges},
+				{StepName: "run", RunCommand: "on-no-changes", When: valid.StepConditionPlanHasNoChanges},
+			},
+			planOut: "No changes. Your infrastructure matches the configuration.",
+			expOut:  "No changes. Your infrastructure matches the configuration.\non-no-changes",
+		},
+		{
+			description: "condition before plan",
+			steps: []valid.Step{
+				{StepName: "run", RunCommand: "on-changes", When: valid.StepConditionPlanHasChanges},
+				{StepName: "plan"},
+			},
+			planOut: "Plan: 1 to add, 0 to change, 0 to destroy.",
+			expErr:  `step condition "plan_has_changes" requires a plan step to run before it`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			mockPlan := mocks.NewMockStepRunner()
+			mockRun := mocks.NewMockCustomStepRunner()
+			mockWorkingDir := mocks.NewMockWorkingDir()
+			mockLocker := mocks.NewMockProjectLocker()
+			runner := events.DefaultProjectCommandRunner{
+				Locker:                    mockLocker,
+				LockURLGenerator:          mockURLGenerator{},
+				PlanStepRunner:            mockPlan,
+				RunStepRunner:             mockRun,
+				WorkingDir:                mockWorkingDir,
+				WorkingDirLocker:          events.NewDefaultWorkingDirLocker(),
+				CommandRequirementHandler: mocks.NewMockCommandRequirementHandler(),
+			}
+
+			repoDir := t.TempDir()
+			When(mockWorkingDir.Clone(Any[models.Repo](), Any[models.PullRequest](), Any[string]())).ThenReturn(repoDir, false, nil)
+			When(mockLocker.TryLock(Any[logging.SimpleLogging](), Any[models.PullRequest](), Any[models.User](), Any[string](), Any[models.Project](), AnyBool())).
+				ThenReturn(&events.TryLockResponse{LockAcquired: true, LockKey: "lock-key", UnlockFn: func() error { return nil }}, nil)
+
+			ctx := command.ProjectContext{
+				Log:        logging.NewNoopLogger(t),
+				Steps:      c.steps,
+				Workspace:  "default",
+				RepoRelDir: ".",
+			}
+			When(mockPlan.Run(ctx, nil, repoDir, map[string]string{})).ThenReturn(c.planOut, nil)
+			for _, cmd := range []string{"on-changes", "on-no-changes"} {
+				When(mockRun.Run(ctx, cmd, repoDir, map[string]string{}, true, "")).ThenReturn(cmd, nil)
+			}
+
+			res := runner.Plan(ctx)
+
+			if c.expErr != "" {
+				ErrContains(t, c.expErr, res.Error)
+				mockRun.VerifyWasCalled(Never()).Run(Any[command.ProjectContext](), Any[string](), Any[string](), Any[map[string]string](), AnyBool(), Any[valid.PostProcessRunOutputOption]())
+				return
+			}
+			Ok(t, res.Error)
+			Equals(t, c.expOut, res.PlanSuccess.TerraformOutput)
+		})
+	}
+}
+
 func TestProjectOutputWrapper(t *testing.T) {
 	RegisterMockTestingT(t)
 	ctx := command.ProjectContext{
@@ -254,6 +555,56 @@ func TestDefaultProjectCommandRunner_ApplyNotCloned(t *testing.T) {
 	ErrEquals(t, "project has not been cloned–did you run plan?", res.Error)
 }
 
+// Test that projects in plan-only repos can't be applied.
+func TestDefaultProjectCommandRunner_ApplyPlanOnly(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	runner := &events.DefaultProjectCommandRunner{
+		WorkingDir: mockWorkingDir,
+	}
+	ctx := command.ProjectContext{
+		PlanOnly: true,
+	}
+
+	res := runner.Apply(ctx)
+	Equals(t, "This repo is plan-only, its projects can't be applied.", res.Failure)
+	mockWorkingDir.VerifyWasCalled(Never()).GetWorkingDir(Any[models.Repo](), Any[models.PullRequest](), Any[string]())
+}
+
+// Test that projects whose Terraform version their repo doesn't allow are
+// rejected before they're locked or cloned.
+func TestDefaultProjectCommandRunner_AllowedTerraformVersions(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	runner := &events.DefaultProjectCommandRunner{
+		WorkingDir:              mockWorkingDir,
+		Locker:                  mockLocker,
+		TerraformVersionChecker: &runtime.TerraformVersionChecker{DefaultTFVersion: version.Must(version.NewVersion("1.5.7"))},
+	}
+	allowed, err := version.NewConstraint(">= 1.3.0, < 2.0.0")
+	Ok(t, err)
+	ctx := command.ProjectContext{
+		Log:                      logging.NewNoopLogger(t),
+		TerraformVersion:         version.Must(version.NewVersion("1.2.9")),
+		AllowedTerraformVersions: allowed,
+	}
+	expFailure := `Terraform version 1.2.9 isn't allowed for this repo, which requires ">= 1.3.0, < 2.0.0". Set terraform_version in the repo config` +
+		" or the required_version of the project's terraform block to an allowed version."
+
+	res := runner.Plan(ctx)
+	Equals(t, expFailure, res.Failure)
+	res = runner.Apply(ctx)
+	Equals(t, expFailure, res.Failure)
+	res = runner.Import(ctx)
+	Equals(t, expFailure, res.Failure)
+	res = runner.StateRm(ctx)
+	Equals(t, expFailure, res.Failure)
+	mockLocker.VerifyWasCalled(Never()).TryLock(Any[logging.SimpleLogging](), Any[models.PullRequest](), Any[models.User](), Any[string](), Any[models.Project](), Any[bool]())
+	mockWorkingDir.VerifyWasCalled(Never()).Clone(Any[models.Repo](), Any[models.PullRequest](), Any[string]())
+	mockWorkingDir.VerifyWasCalled(Never()).GetWorkingDir(Any[models.Repo](), Any[models.PullRequest](), Any[string]())
+}
+
 // Test that if approval is required and the PR isn't approved we give an error.
 func TestDefaultProjectCommandRunner_ApplyNotApproved(t *testing.T) {
 	RegisterMockTestingT(t)
@@ -528,6 +879,253 @@ func TestDefaultProjectCommandRunner_ApplyRunStepFailure(t *testing.T) {
 	mockApply.VerifyWasCalledOnce().Run(ctx, nil, repoDir, expEnvs)
 }
 
+// Test that the on_failure steps run if a step of the apply fails and that
+// their outcome is added to the error.
+func TestDefaultProjectCommandRunner_ApplyOnFailure(t *testing.T) {
+	cases := []struct {
+		description   string
+		verifyErr     error
+		rollbackErr   error
+		expRollback   bool
+		expErrContain string
+		expFailed     bool
+	}{
+		{
+			description: "verification succeeds",
+		},
+		{
+			description:   "verification fails and rollback succeeds",
+			verifyErr:     errors.New("health check failed"),
+			expRollback:   true,
+			expErrContain: "health check failed\napplied\nverifying\n\nThe on_failure steps ran successfully:\nrolling back",
+		},
+		{
+			description:   "verification and rollback fail",
+			verifyErr:     errors.New("health check failed"),
+			rollbackErr:   errors.New("rollback failed"),
+			expRollback:   true,
+			expErrContain: "health check failed\napplied\nverifying\n\nthe on_failure steps failed too: rollback failed\nrolling back",
+			expFailed:     true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			mockApply := mocks.NewMockStepRunner()
+			mockRun := mocks.NewMockCustomStepRunner()
+			mockWorkingDir := mocks.NewMockWorkingDir()
+			runner := events.DefaultProjectCommandRunner{
+				Locker:                    mocks.NewMockProjectLocker(),
+				LockURLGenerator:          mockURLGenerator{},
+				ApplyStepRunner:           mockApply,
+				RunStepRunner:             mockRun,
+				WorkingDir:                mockWorkingDir,
+				WorkingDirLocker:          events.NewDefaultWorkingDirLocker(),
+				CommandRequirementHandler: &events.DefaultCommandRequirementHandler{WorkingDir: mockWorkingDir},
+				Webhooks:                  mocks.NewMockWebhooksSender(),
+			}
+			repoDir := t.TempDir()
+			When(mockWorkingDir.GetWorkingDir(Any[models.Repo](), Any[models.PullRequest](), Any[string]())).ThenReturn(repoDir, nil)
+
+			ctx := command.ProjectContext{
+				Log: logging.NewNoopLogger(t),
+				Steps: []valid.Step{
+					{StepName: "apply"},
+					{StepName: "run", RunCommand: "./verify.sh"},
+				},
+				OnFailureSteps: []valid.Step{
+					{StepName: "run", RunCommand: "./rollback.sh"},
+				},
+				Workspace:  "default",
+				RepoRelDir: ".",
+			}
+			When(mockApply.Run(ctx, nil, repoDir, map[string]string{})).ThenReturn("applied", nil)
+			When(mockRun.Run(ctx, "./verify.sh", repoDir, map[string]string{}, true, "")).ThenReturn("verifying", c.verifyErr)
+			When(mockRun.Run(ctx, "./rollback.sh", repoDir, map[string]string{}, true, "")).ThenReturn("rolling back", c.rollbackErr)
+
+			res := runner.Apply(ctx)
+			if c.expErrContain == "" {
+				Equals(t, "applied\nverifying", res.ApplySuccess)
+				Assert(t, res.Error == nil, "exp no error, got %v", res.Error)
+			} else {
+				Equals(t, "", res.ApplySuccess)
+				ErrContains(t, c.expErrContain, res.Error)
+			}
+			Equals(t, c.expFailed, res.OnFailureStepsFailed)
+			times := 0
+			if c.expRollback {
+				times = 1
+			}
+			mockRun.VerifyWasCalled(Times(times)).Run(ctx, "./rollback.sh", repoDir, map[string]string{}, true, "")
+		})
+	}
+}
+
+// Test that apply waits for the project's deployment environment to approve a
+// deployment and only runs the apply steps if it was approved.
+func TestDefaultProjectCommandRunner_ApplyDeploymentGate(t *testing.T) {
+	cases := []struct {
+		description string
+		state       string
+		expFailure  string
+		expApplied  bool
+		expStatuses []string
+	}{
+		{
+			description: "approved",
+			state:       "in_progress",
+			expApplied:  true,
+			expStatuses: []string{"success"},
+		},
+		{
+			description: "rejected",
+			state:       "failure",
+			expFailure:  `Deployment to environment "production" was not approved, its status is "failure".`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			mockApply := mocks.NewMockStepRunner()
+			mockWorkingDir := mocks.NewMockWorkingDir()
+			deployments := &fakeDeploymentClient{states: []string{c.state}}
+
+			runner := events.DefaultProjectCommandRunner{
+				Locker:                    mocks.NewMockProjectLocker(),
+				LockURLGenerator:          mockURLGenerator{},
+				ApplyStepRunner:           mockApply,
+				WorkingDir:                mockWorkingDir,
+				WorkingDirLocker:          events.NewDefaultWorkingDirLocker(),
+				CommandRequirementHandler: &events.DefaultCommandRequirementHandler{WorkingDir: mockWorkingDir},
+				Webhooks:                  mocks.NewMockWebhooksSender(),
+				DeploymentGate: &events.GithubDeploymentGate{
+					Client:       deployments,
+					Timeout:      time.Second,
+					PollInterval: time.Millisecond,
+				},
+			}
+			repoDir := t.TempDir()
+			When(mockWorkingDir.GetWorkingDir(
+				Any[models.Repo](),
+				Any[models.PullRequest](),
+				Any[string](),
+			)).ThenReturn(repoDir, nil)
+
+			ctx := deploymentGateCtx(t, "production", models.Github)
+			ctx.RepoRelDir = "."
+			ctx.Steps = []valid.Step{{StepName: "apply"}}
+			expEnvs := map[string]string{}
+			When(mockApply.Run(ctx, nil, repoDir, expEnvs)).ThenReturn("applied", nil)
+
+			res := runner.Apply(ctx)
+			Equals(t, c.expFailure, res.Failure)
+			Ok(t, res.Error)
+			if c.expApplied {
+				Equals(t, "applied", res.ApplySuccess)
+				mockApply.VerifyWasCalledOnce().Run(ctx, nil, repoDir, expEnvs)
+			} else {
+				mockApply.VerifyWasCalled(Never()).Run(Any[command.ProjectContext](), Any[[]string](), Any[string](), Any[map[string]string]())
+			}
+			Equals(t, c.expStatuses, deployments.statuses)
+		})
+	}
+}
+
+// Test that with ApplyFromPlanSnapshot a project's snapshot is saved when it's
+// planned and it's applied from the snapshot instead of the clone.
+func TestDefaultProjectCommandRunner_ApplyFromPlanSnapshot(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockPlan := mocks.NewMockStepRunner()
+	mockApply := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+
+	runner := events.DefaultProjectCommandRunner{
+		Locker:                    mockLocker,
+		LockURLGenerator:          mockURLGenerator{},
+		PlanStepRunner:            mockPlan,
+		ApplyStepRunner:           mockApply,
+		WorkingDir:                mockWorkingDir,
+		WorkingDirLocker:          events.NewDefaultWorkingDirLocker(),
+		CommandRequirementHandler: &events.DefaultCommandRequirementHandler{WorkingDir: mockWorkingDir},
+		Webhooks:                  mocks.NewMockWebhooksSender(),
+		ApplyFromPlanSnapshot:     true,
+	}
+	repoDir := t.TempDir()
+	snapshotDir := t.TempDir()
+	When(mockWorkingDir.Clone(
+		Any[models.Repo](),
+		Any[models.PullRequest](),
+		Any[string](),
+	)).ThenReturn(repoDir, false, nil)
+	When(mockLocker.TryLock(
+		Any[logging.SimpleLogging](),
+		Any[models.PullRequest](),
+		Any[models.User](),
+		Any[string](),
+		Any[models.Project](),
+		AnyBool(),
+	)).ThenReturn(&events.TryLockResponse{
+		LockAcquired: true,
+		LockKey:      "lock-key",
+	}, nil)
+
+	ctx := command.ProjectContext{
+		Log:         logging.NewNoopLogger(t),
+		Steps:       []valid.Step{{StepName: "plan"}},
+		Workspace:   "default",
+		RepoRelDir:  ".",
+		ProjectName: "project",
+	}
+	expEnvs := map[string]string{}
+	When(mockPlan.Run(ctx, nil, repoDir, expEnvs)).ThenReturn("plan", nil)
+	When(mockWorkingDir.SavePlanSnapshot(ctx.Pull.BaseRepo, ctx.Pull, "default", ".", "project")).ThenReturn(snapshotDir, nil)
+
+	res := runner.Plan(ctx)
+	Ok(t, res.Error)
+	Assert(t, res.PlanSuccess != nil, "exp plan success")
+	mockWorkingDir.VerifyWasCalledOnce().SavePlanSnapshot(ctx.Pull.BaseRepo, ctx.Pull, "default", ".", "project")
+
+	ctx.Steps = []valid.Step{{StepName: "apply"}}
+	When(mockWorkingDir.GetPlanSnapshot(ctx.Pull.BaseRepo, ctx.Pull, "default", ".", "project")).ThenReturn(snapshotDir, nil)
+	When(mockApply.Run(ctx, nil, snapshotDir, expEnvs)).ThenReturn("applied", nil)
+	snapshotPlan := filepath.Join(snapshotDir, "project-default.tfplan")
+	Ok(t, os.WriteFile(snapshotPlan, []byte("plan"), 0600))
+
+	res = runner.Apply(ctx)
+	Ok(t, res.Error)
+	Equals(t, "applied", res.ApplySuccess)
+	mockApply.VerifyWasCalledOnce().Run(ctx, nil, snapshotDir, expEnvs)
+	mockWorkingDir.VerifyWasCalled(Never()).GetWorkingDir(Any[models.Repo](), Any[models.PullRequest](), Any[string]())
+	mockWorkingDir.VerifyWasCalledOnce().DeletePlan(ctx.Pull.BaseRepo, ctx.Pull, "default", ".", "project")
+	_, err := os.Stat(snapshotPlan)
+	Assert(t, os.IsNotExist(err), "exp the snapshot's planfile to be deleted, got %v", err)
+}
+
+// Test that with ApplyFromPlanSnapshot a project without a snapshot isn't
+// applied from the clone.
+func TestDefaultProjectCommandRunner_ApplyFromPlanSnapshotMissing(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockApply := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	runner := &events.DefaultProjectCommandRunner{
+		ApplyStepRunner:       mockApply,
+		WorkingDir:            mockWorkingDir,
+		ApplyFromPlanSnapshot: true,
+	}
+	ctx := command.ProjectContext{
+		Steps:      []valid.Step{{StepName: "apply"}},
+		Workspace:  "default",
+		RepoRelDir: ".",
+	}
+	When(mockWorkingDir.GetPlanSnapshot(ctx.Pull.BaseRepo, ctx.Pull, "default", ".", "")).ThenReturn("", fmt.Errorf("checking if plan snapshot exists: %w", os.ErrNotExist))
+
+	res := runner.Apply(ctx)
+	ErrEquals(t, "project has no snapshot of the files it was planned with–did you run plan?", res.Error)
+	mockWorkingDir.VerifyWasCalled(Never()).GetWorkingDir(Any[models.Repo](), Any[models.PullRequest](), Any[string]())
+	mockApply.VerifyWasCalled(Never()).Run(Any[command.ProjectContext](), Any[[]string](), Any[string](), Any[map[string]string]())
+}
+
 // Test run and env steps. We don't use mocks for this test since we're
 // not running any Terraform.
 func TestDefaultProjectCommandRunner_RunEnvSteps(t *testing.T) {
@@ -622,6 +1220,144 @@ func TestDefaultProjectCommandRunner_RunEnvSteps(t *testing.T) {
 	Equals(t, "var=\n\nvar=value\n\ndynamic_var=dynamic_value\n\ndynamic_var=overridden\n", res.PlanSuccess.TerraformOutput)
 }
 
+// Test that the runner's env vars are set for every step and that env steps
+// override them.
+func TestDefaultProjectCommandRunner_EnvVars(t *testing.T) {
+	RegisterMockTestingT(t)
+	run := runtime.RunStepRunner{
+		TerraformExecutor:       tmocks.NewMockClient(),
+		DefaultTFVersion:        version.Must(version.NewVersion("0.12.0")),
+		ProjectCmdOutputHandler: jobmocks.NewMockProjectCommandOutputHandler(),
+	}
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	envVars := map[string]string{"TF_IN_AUTOMATION": "1", "HTTPS_PROXY": "http://proxy:3128"}
+	runner := events.DefaultProjectCommandRunner{
+		Locker:                    mockLocker,
+		LockURLGenerator:          mockURLGenerator{},
+		RunStepRunner:             &run,
+		EnvStepRunner:             &runtime.EnvStepRunner{RunStepRunner: &run},
+		WorkingDir:                mockWorkingDir,
+		WorkingDirLocker:          events.NewDefaultWorkingDirLocker(),
+		CommandRequirementHandler: mocks.NewMockCommandRequirementHandler(),
+		EnvVars:                   envVars,
+	}
+	When(mockWorkingDir.Clone(Any[models.Repo](), Any[models.PullRequest](), Any[string]())).ThenReturn(t.TempDir(), false, nil)
+	When(mockLocker.TryLock(
+		Any[logging.SimpleLogging](),
+		Any[models.PullRequest](),
+		Any[models.User](),
+		Any[string](),
+		Any[models.Project](),
+		AnyBool(),
+	)).ThenReturn(&events.TryLockResponse{LockAcquired: true, LockKey: "lock-key"}, nil)
+
+	ctx := command.ProjectContext{
+		Log: logging.NewNoopLogger(t),
+		Steps: []valid.Step{
+			{StepName: "run", RunCommand: "echo $TF_IN_AUTOMATION $HTTPS_PROXY"},
+			{StepName: "env", EnvVarName: "HTTPS_PROXY", EnvVarValue: "http://project-proxy:3128"},
+			{StepName: "run", RunCommand: "echo $TF_IN_AUTOMATION $HTTPS_PROXY"},
+		},
+		Workspace:  "default",
+		RepoRelDir: ".",
+	}
+	res := runner.Plan(ctx)
+	Assert(t, res.PlanSuccess != nil, "exp plan success")
+	Equals(t, "1 http://proxy:3128\n\n1 http://project-proxy:3128\n", res.PlanSuccess.TerraformOutput)
+	// The project's env step doesn't leak into other projects.
+	Equals(t, "http://proxy:3128", envVars["HTTPS_PROXY"])
+}
+
+// Test that a step that runs for longer than the timeout is stopped and the
+// command fails.
+func TestDefaultProjectCommandRunner_Timeout(t *testing.T) {
+	cases := []struct {
+		description    string
+		runnerTimeout  time.Duration
+		commandTimeout time.Duration
+		runCommand     string
+		expErr         string
+	}{
+		{
+			description: "no timeout",
+			runCommand:  "echo ok",
+		},
+		{
+			description:   "server timeout",
+			runnerTimeout: 100 * time.Millisecond,
+			runCommand:    "exec sleep 10",
+			expErr:        "for longer than 100ms: command timed out",
+		},
+		{
+			description:    "project timeout overrides server timeout",
+			runnerTimeout:  time.Hour,
+			commandTimeout: 100 * time.Millisecond,
+			runCommand:     "exec sleep 10",
+			expErr:         "for longer than 100ms: command timed out",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			run := runtime.RunStepRunner{
+				TerraformExecutor:       tmocks.NewMockClient(),
+				DefaultTFVersion:        version.Must(version.NewVersion("1.0.0")),
+				ProjectCmdOutputHandler: jobmocks.NewMockProjectCommandOutputHandler(),
+			}
+			mockWorkingDir := mocks.NewMockWorkingDir()
+			mockLocker := mocks.NewMockProjectLocker()
+
+			runner := events.DefaultProjectCommandRunner{
+				Locker:                    mockLocker,
+				LockURLGenerator:          mockURLGenerator{},
+				RunStepRunner:             &run,
+				WorkingDir:                mockWorkingDir,
+				WorkingDirLocker:          events.NewDefaultWorkingDirLocker(),
+				CommandRequirementHandler: mocks.NewMockCommandRequirementHandler(),
+				PlanTimeout:               c.runnerTimeout,
+			}
+
+			repoDir := t.TempDir()
+			When(mockWorkingDir.Clone(
+				Any[models.Repo](),
+				Any[models.PullRequest](),
+				Any[string](),
+			)).ThenReturn(repoDir, false, nil)
+			When(mockLocker.TryLock(
+				Any[logging.SimpleLogging](),
+				Any[models.PullRequest](),
+				Any[models.User](),
+				Any[string](),
+				Any[models.Project](),
+				AnyBool(),
+			)).ThenReturn(&events.TryLockResponse{
+				LockAcquired: true,
+				LockKey:      "lock-key",
+				UnlockFn:     func() error { return nil },
+			}, nil)
+
+			ctx := command.ProjectContext{
+				Log:            logging.NewNoopLogger(t),
+				Steps:          []valid.Step{{StepName: "run", RunCommand: c.runCommand}},
+				Workspace:      "default",
+				RepoRelDir:     ".",
+				CommandTimeout: c.commandTimeout,
+			}
+			start := time.Now()
+			res := runner.Plan(ctx)
+			Assert(t, time.Since(start) < 5*time.Second, "expected step to be stopped, took %s", time.Since(start))
+			if c.expErr != "" {
+				ErrContains(t, c.expErr, res.Error)
+				Assert(t, res.PlanSuccess == nil, "exp no plan success")
+			} else {
+				Ok(t, res.Error)
+				Equals(t, "ok\n", res.PlanSuccess.TerraformOutput)
+			}
+		})
+	}
+}
+
 // Test that it runs the expected import steps.
 func TestDefaultProjectCommandRunner_Import(t *testing.T) {
 	expEnvs := map[string]string{}
@@ -739,12 +1475,75 @@ func TestDefaultProjectCommandRunner_Import(t *testing.T) {
 	}
 }
 
+func TestDefaultProjectCommandRunner_Validate(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockInit := mocks.NewMockStepRunner()
+	mockValidate := mocks.NewMockStepRunner()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	runner := events.DefaultProjectCommandRunner{
+		Locker:             mockLocker,
+		InitStepRunner:     mockInit,
+		ValidateStepRunner: mockValidate,
+		WorkingDir:         mockWorkingDir,
+		WorkingDirLocker:   events.NewDefaultWorkingDirLocker(),
+	}
+	ctx := command.ProjectContext{
+		Log: logging.NewNoopLogger(t),
+		Steps: []valid.Step{
+			{StepName: "init", ExtraArgs: []string{"-backend=false"}},
+			{StepName: "validate"},
+		},
+		Workspace:  "default",
+		RepoRelDir: ".",
+	}
+	repoDir := t.TempDir()
+	When(mockWorkingDir.Clone(Any[models.Repo](), Any[models.PullRequest](), Any[string]())).ThenReturn(repoDir, false, nil)
+	When(mockInit.Run(ctx, []string{"-backend=false"}, repoDir, map[string]string{})).ThenReturn("init", nil)
+	When(mockValidate.Run(ctx, nil, repoDir, map[string]string{})).ThenReturn("Success! The configuration is valid.", nil)
+
+	res := runner.Validate(ctx)
+	Ok(t, res.Error)
+	Equals(t, command.Validate, res.Command)
+	Equals(t, "init\nSuccess! The configuration is valid.", res.ValidateSuccess)
+	// validate doesn't touch state so it must not take the project lock.
+	mockLocker.VerifyWasCalled(Never()).TryLock(Any[logging.SimpleLogging](), Any[models.PullRequest](), Any[models.User](), Any[string](), Any[models.Project](), AnyBool())
+
+	When(mockValidate.Run(ctx, nil, repoDir, map[string]string{})).ThenReturn("Error: Missing required argument", errors.New("configuration is invalid: 1 error(s), 0 warning(s)"))
+	res = runner.Validate(ctx)
+	Equals(t, "", res.ValidateSuccess)
+	ErrEquals(t, "configuration is invalid: 1 error(s), 0 warning(s)\ninit\nError: Missing required argument", res.Error)
+}
+
 type mockURLGenerator struct{}
 
 func (m mockURLGenerator) GenerateLockURL(lockID string) string {
 	return "https://" + lockID
 }
 
+func (m mockURLGenerator) GeneratePlanJSONURL(id string) string {
+	return "https://plan-json/" + id
+}
+
+// fakePlanJSONStore stores plan JSON in memory.
+type fakePlanJSONStore struct {
+	saved map[string]string
+}
+
+func (f *fakePlanJSONStore) Save(pull models.PullRequest, _ string, planJSON []byte) (string, error) {
+	id := fmt.Sprintf("%d-%d", pull.Num, len(f.saved))
+	f.saved[id] = string(planJSON)
+	return id, nil
+}
+
+func (f *fakePlanJSONStore) Get(id string) ([]byte, error) {
+	return []byte(f.saved[id]), nil
+}
+
+func (f *fakePlanJSONStore) DeleteForPull(_ models.PullRequest) error {
+	return nil
+}
+
 // Test approve policies logic.
 func TestDefaultProjectCommandRunner_ApprovePolicies(t *testing.T) {
 	cases := []struct {
@@ -0,0 +1,38 @@
+package events
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/core/config/valid"
+	"github.com/runatlantis/atlantis/server/events/command"
+)
+
+// runProjectGenerator runs generator, a repo's project_generator command, in
+// repoDir, the root of the cloned repo, and returns what it printed to
+// stdout. It's expected to print a JSON array of projects.
+func runProjectGenerator(ctx *command.Context, generator string, repoDir string) ([]byte, error) {
+	cmd := exec.Command("sh", "-c", generator) // #nosec
+	cmd.Dir = repoDir
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("BASE_BRANCH_NAME=%s", ctx.Pull.BaseBranch),
+		fmt.Sprintf("BASE_REPO_NAME=%s", ctx.Pull.BaseRepo.Name),
+		fmt.Sprintf("BASE_REPO_OWNER=%s", ctx.Pull.BaseRepo.Owner),
+		fmt.Sprintf("DIR=%s", repoDir),
+		fmt.Sprintf("HEAD_BRANCH_NAME=%s", ctx.Pull.HeadBranch),
+		fmt.Sprintf("HEAD_COMMIT=%s", ctx.Pull.HeadCommit),
+		fmt.Sprintf("PULL_NUM=%d", ctx.Pull.Num),
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	ctx.Log.Debug("running %s %q in %q", valid.ProjectGeneratorKey, generator, repoDir)
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running %s %q: %s: %s", valid.ProjectGeneratorKey, generator, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
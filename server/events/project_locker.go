@@ -41,6 +41,13 @@ type DefaultProjectLocker struct {
 	Locker     locking.Locker
 	NoOpLocker locking.Locker
 	VCSClient  vcs.Client
+	// MarkdownRenderer, if set, renders why the lock couldn't be acquired
+	// with details of the lock that's held. Otherwise a shorter reason is
+	// used.
+	MarkdownRenderer *MarkdownRenderer
+	// LockURLGenerator, if set, links the reason to the page of the lock
+	// that's held.
+	LockURLGenerator LockURLGenerator
 }
 
 // TryLockResponse is the result of trying to lock a project.
@@ -78,6 +85,13 @@ func (p *DefaultProjectLocker) TryLock(log logging.SimpleLogging, pull models.Pu
 			"This project is currently locked by an unapplied plan from pull %s. To continue, delete the lock from %s or apply that plan and merge the pull request.\n\nOnce the lock is released, comment `atlantis plan` here to re-plan.",
 			link,
 			link)
+		if p.MarkdownRenderer != nil {
+			var lockURL string
+			if p.LockURLGenerator != nil {
+				lockURL = p.LockURLGenerator.GenerateLockURL(lockAttempt.LockKey)
+			}
+			failureMsg = p.MarkdownRenderer.RenderLockFailure(lockAttempt.CurrLock, link, lockURL)
+		}
 		return &TryLockResponse{
 			LockAcquired:      false,
 			LockFailureReason: failureMsg,
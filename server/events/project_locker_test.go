@@ -62,6 +62,41 @@ func TestDefaultProjectLocker_TryLockWhenLocked(t *testing.T) {
 	}, res)
 }
 
+func TestDefaultProjectLocker_TryLockWhenLockedRendersHolder(t *testing.T) {
+	RegisterMockTestingT(t)
+	var githubClient *vcs.GithubClient
+	mockClient := vcs.NewClientProxy(githubClient, nil, nil, nil, nil)
+	mockLocker := mocks.NewMockLocker()
+	locker := events.DefaultProjectLocker{
+		Locker:           mockLocker,
+		VCSClient:        mockClient,
+		MarkdownRenderer: events.NewMarkdownRenderer(events.MarkdownRendererOptions{ExecutableName: "atlantis"}),
+		LockURLGenerator: mockURLGenerator{},
+	}
+	expProject := models.Project{Path: "dir"}
+	expPull := models.PullRequest{}
+	expUser := models.User{}
+	When(mockLocker.TryLock(expProject, "staging", expPull, expUser)).ThenReturn(
+		locking.TryLockResponse{
+			LockAcquired: false,
+			CurrLock: models.ProjectLock{
+				Pull:      models.PullRequest{Num: 2},
+				User:      models.User{Username: "lkysow"},
+				Workspace: "staging",
+				Project:   expProject,
+			},
+			LockKey: "owner/repo/dir/staging",
+		},
+		nil,
+	)
+	res, err := locker.TryLock(logging.NewNoopLogger(t), expPull, expUser, "staging", expProject, true)
+	Ok(t, err)
+	Equals(t, &events.TryLockResponse{
+		LockAcquired:      false,
+		LockFailureReason: "This project is currently locked by an unapplied plan from pull #2 in dir `dir`, workspace `staging`, planned by **lkysow**. To continue, [delete the lock](https://owner/repo/dir/staging) or apply that plan and merge the pull request.\n\nOnce the lock is released, comment `atlantis plan` here to re-plan.",
+	}, res)
+}
+
 func TestDefaultProjectLocker_TryLockWhenLockedSamePull(t *testing.T) {
 	RegisterMockTestingT(t)
 	var githubClient *vcs.GithubClient
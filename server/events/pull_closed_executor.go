@@ -17,6 +17,8 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"text/template"
@@ -24,7 +26,11 @@ import (
 	"github.com/runatlantis/atlantis/server/logging"
 
 	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/config"
+	"github.com/runatlantis/atlantis/server/core/config/valid"
 	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/core/terraform"
+	"github.com/runatlantis/atlantis/server/events/command"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
 	"github.com/runatlantis/atlantis/server/jobs"
@@ -45,6 +51,13 @@ type PullCleaner interface {
 	CleanUpPull(repo models.Repo, pull models.PullRequest) error
 }
 
+// WorkspaceDeleter deletes the Terraform workspace of a project.
+type WorkspaceDeleter interface {
+	// Delete deletes the workspace ctx.Workspace of the initialized project
+	// in path, running Terraform with envs.
+	Delete(ctx command.ProjectContext, path string, envs map[string]string) error
+}
+
 // PullClosedExecutor executes the tasks required to clean up a closed pull
 // request.
 type PullClosedExecutor struct {
@@ -55,6 +68,30 @@ type PullClosedExecutor struct {
 	Backend                  locking.Backend
 	PullClosedTemplate       PullCleanupTemplate
 	LogStreamResourceCleaner ResourceCleaner
+	// GlobalCfg is the server-side repo config. Its delete_pr_workspaces
+	// setting decides which of the pull request's workspaces are deleted.
+	GlobalCfg valid.GlobalCfg
+	// WorkspaceDeleter deletes the pull request's Terraform workspaces in the
+	// background while holding the pull request's WorkingDirLocker lock. If
+	// it's nil, no workspaces are deleted.
+	WorkspaceDeleter WorkspaceDeleter
+	WorkingDirLocker WorkingDirLocker
+	// ParserValidator parses the repo config to find the Terraform version
+	// and workflow of the projects whose workspaces are deleted. If it's nil,
+	// only the server-side config and the projects' required_version are used.
+	ParserValidator *config.ParserValidator
+	// EnvVars, like --tf-env-vars, and the env and multienv steps of the
+	// projects' plan workflows set the environment of the Terraform commands
+	// that delete workspaces. Steps are skipped if their runner is nil.
+	EnvVars            map[string]string
+	EnvStepRunner      EnvStepRunner
+	MultiEnvStepRunner MultiEnvStepRunner
+	// TerraformClient detects the Terraform version of projects from their
+	// required_version. If it's nil, the default version is used.
+	TerraformClient terraform.Client
+	// PlanJSONStore stores the JSON of the pull request's plans. If it's nil,
+	// no plan JSON is stored.
+	PlanJSONStore PlanJSONStore
 }
 
 type templatedProject struct {
@@ -97,10 +134,23 @@ func (p *PullClosedExecutor) CleanUpPull(repo models.Repo, pull models.PullReque
 		}
 	}
 
-	if err := p.WorkingDir.Delete(repo, pull); err != nil {
+	// Terraform workspaces must be deleted before the working dir since
+	// they're deleted from the project's initialized directory. Deleting them
+	// runs Terraform so it's done in the background. Which ones to delete is
+	// decided first, while this pull request's locks and plans still exist.
+	if projects := p.prWorkspacesToDelete(repo, pull, pullStatus); len(projects) > 0 {
+		go p.deletePRWorkspacesAndWorkingDir(repo, pull, projects)
+	} else if err := p.WorkingDir.Delete(repo, pull); err != nil {
 		return errors.Wrap(err, "cleaning workspace")
 	}
 
+	if p.PlanJSONStore != nil {
+		if err := p.PlanJSONStore.DeleteForPull(pull); err != nil {
+			// Log and continue to clean up other resources.
+			p.Logger.Err("deleting plan json: %s", err)
+		}
+	}
+
 	// Finally, delete locks. We do this last because when someone
 	// unlocks a project, right now we don't actually delete the plan
 	// so we might have plans laying around but no locks.
@@ -127,6 +177,188 @@ func (p *PullClosedExecutor) CleanUpPull(repo models.Repo, pull models.PullReque
 	return p.VCSClient.CreateComment(repo, pull.Num, buf.String(), "")
 }
 
+// prWorkspacesToDelete returns the pull request's projects whose Terraform
+// workspaces match the repo's delete_pr_workspaces setting. The default
+// workspace and workspaces used by other pull requests, whether they lock them
+// or only planned them, are never deleted.
+func (p *PullClosedExecutor) prWorkspacesToDelete(repo models.Repo, pull models.PullRequest, pullStatus *models.PullStatus) []models.ProjectStatus {
+	if p.WorkspaceDeleter == nil || pullStatus == nil {
+		return nil
+	}
+	workspaceRegex := p.GlobalCfg.DeletePRWorkspaces(repo.ID())
+	if workspaceRegex == nil {
+		return nil
+	}
+
+	locks, err := p.Backend.List()
+	if err != nil {
+		p.Logger.Err("listing locks, not deleting workspaces: %s", err)
+		return nil
+	}
+	pullStatuses, err := p.Backend.GetPullStatuses()
+	if err != nil {
+		p.Logger.Err("listing pull statuses, not deleting workspaces: %s", err)
+		return nil
+	}
+	usedByOtherPull := func(project models.ProjectStatus) bool {
+		for _, l := range locks {
+			if l.Project.RepoFullName == repo.FullName && l.Project.Path == project.RepoRelDir &&
+				l.Workspace == project.Workspace && l.Pull.Num != pull.Num {
+				return true
+			}
+		}
+		for _, ps := range pullStatuses {
+			if ps.Pull.BaseRepo.FullName != repo.FullName || ps.Pull.Num == pull.Num {
+				continue
+			}
+			for _, other := range ps.Projects {
+				if other.RepoRelDir == project.RepoRelDir && other.Workspace == project.Workspace {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	var projects []models.ProjectStatus
+	seen := make(map[string]bool)
+	for _, project := range pullStatus.Projects {
+		key := project.RepoRelDir + "/" + project.Workspace
+		if seen[key] || project.Workspace == DefaultWorkspace || !workspaceRegex.MatchString(project.Workspace) {
+			continue
+		}
+		seen[key] = true
+		if usedByOtherPull(project) {
+			p.Logger.Info("not deleting workspace %q in dir %q: it is used by another pull request", project.Workspace, project.RepoRelDir)
+			continue
+		}
+		projects = append(projects, project)
+	}
+	return projects
+}
+
+// deletePRWorkspacesAndWorkingDir deletes the Terraform workspaces of projects
+// while holding the pull request's working dir lock, then deletes the working
+// dir. Errors are logged since this runs in the background.
+func (p *PullClosedExecutor) deletePRWorkspacesAndWorkingDir(repo models.Repo, pull models.PullRequest, projects []models.ProjectStatus) {
+	unlock, err := p.WorkingDirLocker.TryLockPull(repo.FullName, pull.Num)
+	if err != nil {
+		p.Logger.Warn("not deleting workspaces: %s", err)
+	} else {
+		defer unlock()
+		for _, project := range projects {
+			p.deletePRWorkspace(repo, pull, project)
+		}
+	}
+	if err := p.WorkingDir.Delete(repo, pull); err != nil {
+		p.Logger.Err("cleaning workspace: %s", err)
+	}
+}
+
+// deletePRWorkspace deletes the Terraform workspace of project with the
+// Terraform version and environment variables it was planned with.
+func (p *PullClosedExecutor) deletePRWorkspace(repo models.Repo, pull models.PullRequest, project models.ProjectStatus) {
+	workspaceDir, err := p.WorkingDir.GetWorkingDir(pull.BaseRepo, pull, project.Workspace)
+	if err != nil {
+		p.Logger.Warn("not deleting workspace %q in dir %q: %s", project.Workspace, project.RepoRelDir, err)
+		return
+	}
+	projectDir := filepath.Join(workspaceDir, project.RepoRelDir)
+	if _, err := os.Stat(projectDir); err != nil {
+		p.Logger.Warn("not deleting workspace %q in dir %q: %s", project.Workspace, project.RepoRelDir, err)
+		return
+	}
+
+	projCfg := p.projectCfg(repo, pull, workspaceDir, project)
+	ctx := command.ProjectContext{
+		Log:              p.Logger,
+		BaseRepo:         pull.BaseRepo,
+		Pull:             pull,
+		Workspace:        project.Workspace,
+		RepoRelDir:       project.RepoRelDir,
+		ProjectName:      project.ProjectName,
+		TerraformBinary:  projCfg.TerraformBinary,
+		TerraformVersion: projCfg.TerraformVersion,
+	}
+	if ctx.TerraformVersion == nil && p.TerraformClient != nil {
+		ctx.TerraformVersion = p.TerraformClient.DetectVersion(p.Logger, projectDir)
+	}
+	envs, err := p.projectEnvs(ctx, projCfg, projectDir)
+	if err != nil {
+		p.Logger.Warn("not deleting workspace %q in dir %q: %s", project.Workspace, project.RepoRelDir, err)
+		return
+	}
+	if err := p.WorkspaceDeleter.Delete(ctx, projectDir, envs); err != nil {
+		p.Logger.Warn("deleting workspace %q in dir %q: %s", project.Workspace, project.RepoRelDir, err)
+		return
+	}
+	p.Logger.Info("deleted workspace %q in dir %q", project.Workspace, project.RepoRelDir)
+}
+
+// projectEnvs returns the environment variables the project's Terraform
+// commands run with when it's planned: EnvVars and those set by the env and
+// multienv steps of its plan workflow.
+func (p *PullClosedExecutor) projectEnvs(ctx command.ProjectContext, projCfg valid.MergedProjectCfg, projectDir string) (map[string]string, error) {
+	envs := make(map[string]string, len(p.EnvVars))
+	for key, val := range p.EnvVars {
+		envs[key] = val
+	}
+	for _, step := range projCfg.Workflow.Plan.Steps {
+		switch step.StepName {
+		case "env":
+			if p.EnvStepRunner == nil {
+				continue
+			}
+			out, err := p.EnvStepRunner.Run(ctx, step.RunCommand, step.EnvVarValue, projectDir, envs)
+			if err != nil {
+				return nil, errors.Wrapf(err, "running env step for %s", step.EnvVarName)
+			}
+			envs[step.EnvVarName] = out
+		case "multienv":
+			if p.MultiEnvStepRunner == nil {
+				continue
+			}
+			if _, err := p.MultiEnvStepRunner.Run(ctx, step.RunCommand, projectDir, envs); err != nil {
+				return nil, errors.Wrap(err, "running multienv step")
+			}
+		}
+	}
+	return envs, nil
+}
+
+// projectCfg returns the config the project was planned with: its config in
+// the repo config in workspaceDir merged with the server-side config, or else
+// the server-side config.
+func (p *PullClosedExecutor) projectCfg(repo models.Repo, pull models.PullRequest, workspaceDir string, project models.ProjectStatus) valid.MergedProjectCfg {
+	if p.ParserValidator != nil {
+		if repoCfg, prj, ok := p.repoCfgProject(repo, pull, workspaceDir, project); ok {
+			return p.GlobalCfg.MergeProjectCfg(p.Logger, repo.ID(), prj, repoCfg)
+		}
+	}
+	return p.GlobalCfg.DefaultProjCfg(p.Logger, repo.ID(), project.RepoRelDir, project.Workspace)
+}
+
+// repoCfgProject returns the repo config in workspaceDir and the project in
+// it, or false if there's no such project.
+func (p *PullClosedExecutor) repoCfgProject(repo models.Repo, pull models.PullRequest, workspaceDir string, project models.ProjectStatus) (valid.RepoCfg, valid.Project, bool) {
+	repoCfgFile := p.GlobalCfg.RepoConfigFile(repo.ID())
+	hasRepoCfg, err := p.ParserValidator.HasRepoCfg(workspaceDir, repoCfgFile)
+	if err != nil || !hasRepoCfg {
+		return valid.RepoCfg{}, valid.Project{}, false
+	}
+	repoCfg, err := p.ParserValidator.ParseRepoCfg(workspaceDir, p.GlobalCfg, repo.ID(), pull.BaseBranch)
+	if err != nil {
+		p.Logger.Warn("parsing %s to find the config of dir %q: %s", repoCfgFile, project.RepoRelDir, err)
+		return valid.RepoCfg{}, valid.Project{}, false
+	}
+	for _, prj := range repoCfg.FindProjectsByDirWorkspace(project.RepoRelDir, project.Workspace) {
+		if project.ProjectName == "" || prj.GetName() == project.ProjectName {
+			return repoCfg, prj, true
+		}
+	}
+	return valid.RepoCfg{}, valid.Project{}, false
+}
+
 // buildTemplateData formats the lock data into a slice that can easily be
 // templated for the VCS comment. We organize all the workspaces by their
 // respective project paths so the comment can look like:
@@ -15,10 +15,16 @@ package events_test
 
 import (
 	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/config"
+	"github.com/runatlantis/atlantis/server/core/config/valid"
 	"github.com/runatlantis/atlantis/server/core/db"
+	"github.com/runatlantis/atlantis/server/core/runtime"
 	"github.com/runatlantis/atlantis/server/jobs"
 	"github.com/runatlantis/atlantis/server/logging"
 	"github.com/stretchr/testify/assert"
@@ -95,6 +101,32 @@ func TestCleanUpPullNoLocks(t *testing.T) {
 	cp.VerifyWasCalled(Never()).CreateComment(Any[models.Repo](), Any[int](), Any[string](), Any[string]())
 }
 
+func TestCleanUpPullPlanJSON(t *testing.T) {
+	t.Log("the plan json of the pull request is deleted")
+	RegisterMockTestingT(t)
+	w := mocks.NewMockWorkingDir()
+	l := lockmocks.NewMockLocker()
+	cp := vcsmocks.NewMockClient()
+	db, err := db.New(t.TempDir())
+	Ok(t, err)
+	store := &events.FilePlanJSONStore{Dir: t.TempDir()}
+	pull := testdata.Pull
+	pull.BaseRepo = testdata.GithubRepo
+	id, err := store.Save(pull, "./default/", []byte("{}"))
+	Ok(t, err)
+	pce := events.PullClosedExecutor{
+		Locker:        l,
+		VCSClient:     cp,
+		WorkingDir:    w,
+		Backend:       db,
+		PlanJSONStore: store,
+	}
+	When(l.UnlockByPull(testdata.GithubRepo.FullName, pull.Num)).ThenReturn(nil, nil)
+	Ok(t, pce.CleanUpPull(testdata.GithubRepo, pull))
+	_, err = store.Get(id)
+	Assert(t, os.IsNotExist(err), "exp plan json to be deleted, got %v", err)
+}
+
 func TestCleanUpPullComments(t *testing.T) {
 	t.Log("should comment correctly")
 	RegisterMockTestingT(t)
@@ -281,3 +313,140 @@ func TestCleanUpLogStreaming(t *testing.T) {
 		assert.Empty(t, dfPrjCmdOutputHandler.GetReceiverBufferForPull(ctx.PullInfo()))
 	})
 }
+
+type fakeWorkspaceDeleter struct {
+	deleted  []string
+	versions []string
+	envs     []map[string]string
+}
+
+func (d *fakeWorkspaceDeleter) Delete(ctx command.ProjectContext, path string, envs map[string]string) error {
+	d.deleted = append(d.deleted, ctx.RepoRelDir+":"+ctx.Workspace)
+	if ctx.TerraformVersion != nil {
+		d.versions = append(d.versions, ctx.TerraformVersion.String())
+	}
+	d.envs = append(d.envs, envs)
+	return nil
+}
+
+func TestCleanUpPullDeletePRWorkspaces(t *testing.T) {
+	t.Log("should delete the pull request's workspaces that match delete_pr_workspaces")
+	RegisterMockTestingT(t)
+	w := mocks.NewMockWorkingDir()
+	l := lockmocks.NewMockLocker()
+	cp := vcsmocks.NewMockClient()
+	db, err := db.New(t.TempDir())
+	Ok(t, err)
+
+	// pr-2 is also locked by another pull request and pr-3 was planned by
+	// another one so they mustn't be deleted.
+	otherPull := testdata.Pull
+	otherPull.Num = testdata.Pull.Num + 1
+	_, _, err = db.TryLock(models.ProjectLock{
+		Project:   models.NewProject(testdata.GithubRepo.FullName, "dir"),
+		Workspace: "pr-2",
+		Pull:      otherPull,
+	})
+	Ok(t, err)
+	thirdPull := testdata.Pull
+	thirdPull.Num = testdata.Pull.Num + 2
+	thirdPull.BaseRepo = testdata.GithubRepo
+	_, err = db.UpdatePullWithResults(thirdPull, []command.ProjectResult{
+		{RepoRelDir: "dir", Workspace: "pr-3"},
+	})
+	Ok(t, err)
+	_, err = db.UpdatePullWithResults(testdata.Pull, []command.ProjectResult{
+		{RepoRelDir: "dir", Workspace: "pr-1"},
+		{RepoRelDir: "dir", Workspace: "pr-2"},
+		{RepoRelDir: "dir", Workspace: "pr-3"},
+		{RepoRelDir: "dir", Workspace: "default"},
+		{RepoRelDir: "dir", Workspace: "staging"},
+	})
+	Ok(t, err)
+
+	workingDir := t.TempDir()
+	Ok(t, os.Mkdir(filepath.Join(workingDir, "dir"), 0700))
+	repoCfg := "version: 3\nprojects:\n- dir: dir\n  workspace: pr-1\n  terraform_version: v1.5.7\n"
+	Ok(t, os.WriteFile(filepath.Join(workingDir, "atlantis.yaml"), []byte(repoCfg), 0600))
+	When(w.GetWorkingDir(Any[models.Repo](), Any[models.PullRequest](), Any[string]())).ThenReturn(workingDir, nil)
+	When(l.UnlockByPull(testdata.GithubRepo.FullName, testdata.Pull.Num)).ThenReturn(nil, nil)
+
+	deleter := &fakeWorkspaceDeleter{}
+	pce := events.PullClosedExecutor{
+		Locker:                   l,
+		VCSClient:                cp,
+		WorkingDir:               w,
+		Backend:                  db,
+		Logger:                   logging.NewNoopLogger(t),
+		LogStreamResourceCleaner: mocks.NewMockResourceCleaner(),
+		GlobalCfg: valid.GlobalCfg{
+			Repos: []valid.Repo{
+				{
+					IDRegex:            regexp.MustCompile(".*"),
+					DeletePRWorkspaces: regexp.MustCompile(`^pr-\d+$`),
+					Workflow: &valid.Workflow{
+						Plan: valid.Stage{Steps: []valid.Step{
+							{StepName: "env", EnvVarName: "TF_VAR_env", EnvVarValue: "pr"},
+							{StepName: "plan"},
+						}},
+					},
+				},
+			},
+		},
+		WorkspaceDeleter: deleter,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		ParserValidator:  &config.ParserValidator{},
+		EnvVars:          map[string]string{"TF_TOKEN": "token"},
+		EnvStepRunner:    &runtime.EnvStepRunner{},
+	}
+	err = pce.CleanUpPull(testdata.GithubRepo, testdata.Pull)
+	Ok(t, err)
+
+	// Workspaces are deleted in the background, then the working dir is.
+	w.VerifyWasCalledEventually(Once(), 2*time.Second).Delete(testdata.GithubRepo, testdata.Pull)
+	Equals(t, []string{"dir:pr-1"}, deleter.deleted)
+	Equals(t, []string{"1.5.7"}, deleter.versions)
+	Equals(t, []map[string]string{{"TF_TOKEN": "token", "TF_VAR_env": "pr"}}, deleter.envs)
+}
+
+func TestCleanUpPullDeletePRWorkspaces_WorkingDirLocked(t *testing.T) {
+	t.Log("should not delete workspaces while a command runs for the pull request")
+	RegisterMockTestingT(t)
+	w := mocks.NewMockWorkingDir()
+	l := lockmocks.NewMockLocker()
+	db, err := db.New(t.TempDir())
+	Ok(t, err)
+	_, err = db.UpdatePullWithResults(testdata.Pull, []command.ProjectResult{
+		{RepoRelDir: "dir", Workspace: "pr-1"},
+	})
+	Ok(t, err)
+	When(l.UnlockByPull(testdata.GithubRepo.FullName, testdata.Pull.Num)).ThenReturn(nil, nil)
+
+	workingDirLocker := events.NewDefaultWorkingDirLocker()
+	unlock, err := workingDirLocker.TryLock(testdata.GithubRepo.FullName, testdata.Pull.Num, "pr-1", "dir")
+	Ok(t, err)
+	defer unlock()
+
+	deleter := &fakeWorkspaceDeleter{}
+	pce := events.PullClosedExecutor{
+		Locker:                   l,
+		VCSClient:                vcsmocks.NewMockClient(),
+		WorkingDir:               w,
+		Backend:                  db,
+		Logger:                   logging.NewNoopLogger(t),
+		LogStreamResourceCleaner: mocks.NewMockResourceCleaner(),
+		GlobalCfg: valid.GlobalCfg{
+			Repos: []valid.Repo{
+				{
+					IDRegex:            regexp.MustCompile(".*"),
+					DeletePRWorkspaces: regexp.MustCompile(`^pr-\d+$`),
+				},
+			},
+		},
+		WorkspaceDeleter: deleter,
+		WorkingDirLocker: workingDirLocker,
+	}
+	Ok(t, pce.CleanUpPull(testdata.GithubRepo, testdata.Pull))
+	w.VerifyWasCalledEventually(Once(), 2*time.Second).Delete(testdata.GithubRepo, testdata.Pull)
+	Equals(t, 0, len(deleter.deleted))
+}
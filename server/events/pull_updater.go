@@ -1,6 +1,8 @@
 package events
 
 import (
+	"fmt"
+
 	"github.com/runatlantis/atlantis/server/events/command"
 	"github.com/runatlantis/atlantis/server/events/vcs"
 )
@@ -9,6 +11,21 @@ type PullUpdater struct {
 	HidePrevPlanComments bool
 	VCSClient            vcs.Client
 	MarkdownRenderer     *MarkdownRenderer
+	// PlanGistUploader uploads plan output that's too large to comment to a
+	// gist. It's nil if this is disabled.
+	PlanGistUploader *PlanGistUploader
+	// PlanCommentEditor edits the previous plan comment in place instead of
+	// adding a new comment. It's nil if this is disabled.
+	PlanCommentEditor *PlanCommentEditor
+	// PlanReviewPoster posts plan comments as pull request reviews. It's nil
+	// if this is disabled.
+	PlanReviewPoster *PlanReviewPoster
+	// CodeOwnersGrouper groups the projects in plan comments by their owners
+	// in CODEOWNERS. It's nil if this is disabled.
+	CodeOwnersGrouper *CodeOwnersGrouper
+	// ApplySummaryComments is how the applies of more than one project are
+	// summarized in a comment. It's empty if they aren't.
+	ApplySummaryComments ApplySummaryComments
 }
 
 func (c *PullUpdater) updatePull(ctx *command.Context, cmd PullCommand, res command.Result) {
@@ -18,6 +35,32 @@ func (c *PullUpdater) updatePull(ctx *command.Context, cmd PullCommand, res comm
 	} else if res.Failure != "" {
 		ctx.Log.Warn(res.Failure)
 	}
+	addAuditResult(ctx, res)
+
+	if c.hasApplySummary(cmd, res) {
+		c.updatePullWithApplySummary(ctx, cmd, res)
+		return
+	}
+
+	isPlan := cmd.CommandName() == command.Plan
+	if isPlan {
+		c.PlanGistUploader.Upload(ctx, &res)
+	}
+
+	var codeOwners *CodeOwners
+	if isPlan && len(res.ProjectResults) > 1 {
+		codeOwners = c.CodeOwnersGrouper.CodeOwners(ctx)
+	}
+	if codeOwners != nil && c.CodeOwnersGrouper.Comments == SplitCodeOwnersComments {
+		c.updatePullByOwners(ctx, cmd, res, codeOwners)
+		return
+	}
+
+	comment := c.MarkdownRenderer.RenderByOwners(res, codeOwners, cmd.CommandName(), cmd.SubCommandName(), ctx.Log.GetHistory(), cmd.IsVerbose(), ctx.Pull.BaseRepo.VCSHost.Type)
+	// An edited plan comment must not be hidden below.
+	if isPlan && c.PlanCommentEditor.Edit(ctx, res, comment) {
+		return
+	}
 
 	// HidePrevCommandComments will hide old comments left from previous runs to reduce
 	// clutter in a pull/merge request. This will not delete the comment, since the
@@ -28,8 +71,62 @@ func (c *PullUpdater) updatePull(ctx *command.Context, cmd PullCommand, res comm
 		}
 	}
 
-	comment := c.MarkdownRenderer.Render(res, cmd.CommandName(), cmd.SubCommandName(), ctx.Log.GetHistory(), cmd.IsVerbose(), ctx.Pull.BaseRepo.VCSHost.Type)
+	if isPlan && c.PlanReviewPoster.Post(ctx, comment) == 1 {
+		return
+	}
+	if isPlan && c.PlanCommentEditor.Create(ctx, res, comment) {
+		return
+	}
+	c.createComment(ctx, cmd, comment)
+}
+
+// createComment comments on ctx's pull request. If it fails, ctx is marked so
+// the command can be failed with --fail-on-comment-error.
+func (c *PullUpdater) createComment(ctx *command.Context, cmd PullCommand, comment string) {
 	if err := c.VCSClient.CreateComment(ctx.Pull.BaseRepo, ctx.Pull.Num, comment, cmd.CommandName().String()); err != nil {
 		ctx.Log.Err("unable to comment: %s", err)
+		ctx.CommentFailed = true
+	}
+}
+
+// updatePullByOwners comments the plans of the projects of each owners in
+// codeOwners separately.
+func (c *PullUpdater) updatePullByOwners(ctx *command.Context, cmd PullCommand, res command.Result, codeOwners *CodeOwners) {
+	var results []command.Result
+	var comments []string
+	for _, group := range codeOwners.Group(res.ProjectResults) {
+		groupRes := res
+		groupRes.ProjectResults = group.ProjectResults
+		// The first line must contain the command name for the comment to be
+		// hidden by the next command.
+		comment := fmt.Sprintf("## %s: %s\n\n%s", cmd.CommandName().TitleString(), codeOwnersHeading(group.Owners),
+			c.MarkdownRenderer.Render(groupRes, cmd.CommandName(), cmd.SubCommandName(), ctx.Log.GetHistory(), cmd.IsVerbose(), ctx.Pull.BaseRepo.VCSHost.Type))
+		results = append(results, groupRes)
+		comments = append(comments, comment)
+	}
+
+	// Unless all the plan comments could be edited, they're all commented
+	// again so the ones that were edited aren't hidden among the new ones.
+	edited := true
+	for i := range results {
+		if !c.PlanCommentEditor.Edit(ctx, results[i], comments[i]) {
+			edited = false
+			break
+		}
+	}
+	if edited {
+		return
+	}
+
+	if c.HidePrevPlanComments {
+		if err := c.VCSClient.HidePrevCommandComments(ctx.Pull.BaseRepo, ctx.Pull.Num, cmd.CommandName().TitleString()); err != nil {
+			ctx.Log.Err("unable to hide old comments: %s", err)
+		}
+	}
+	for i := c.PlanReviewPoster.Post(ctx, comments...); i < len(results); i++ {
+		if c.PlanCommentEditor.Create(ctx, results[i], comments[i]) {
+			continue
+		}
+		c.createComment(ctx, cmd, comments[i])
 	}
 }
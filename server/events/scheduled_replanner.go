@@ -0,0 +1,132 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/scheduled"
+)
+
+// scheduledReplanJobName is the name the runs of the ScheduledReplanner are
+// claimed with in the backend.
+const scheduledReplanJobName = "scheduled-replan"
+
+// ScheduledReplanner re-plans open pull requests on a cron schedule so drift
+// on long-lived pull requests shows up without someone commenting. Only pull
+// requests of allowlisted repos that have been planned before are re-planned.
+// The plans go through CommandRunner like a plan comment by the pull
+// request's author, so locks, permissions, fork checks and the repo config are
+// respected.
+type ScheduledReplanner struct {
+	schedule      *scheduled.CronSchedule
+	label         string
+	backend       locking.Backend
+	vcsClient     vcs.Client
+	commandRunner CommandRunner
+	repoAllowlist *RepoAllowlistChecker
+	logger        logging.SimpleLogging
+
+	mu sync.Mutex
+	// lastRun is the minute the schedule last matched so it doesn't run
+	// twice in the same minute.
+	lastRun time.Time
+	// now returns the current time. It's overridden in tests.
+	now func() time.Time
+}
+
+// NewScheduledReplanner returns a ScheduledReplanner that re-plans the pull
+// requests with label on schedule. If label is empty, all planned pull
+// requests are re-planned.
+func NewScheduledReplanner(schedule *scheduled.CronSchedule, label string, backend locking.Backend, vcsClient vcs.Client, commandRunner CommandRunner, repoAllowlist *RepoAllowlistChecker, logger logging.SimpleLogging) *ScheduledReplanner {
+	return &ScheduledReplanner{
+		schedule:      schedule,
+		label:         label,
+		backend:       backend,
+		vcsClient:     vcsClient,
+		commandRunner: commandRunner,
+		repoAllowlist: repoAllowlist,
+		logger:        logger,
+		now:           time.Now,
+	}
+}
+
+// Run re-plans the pull requests if the schedule matches the current minute.
+// It's run every minute by the scheduled executor service of every Atlantis
+// server, so the server that claims the minute in the backend first re-plans
+// and the others skip it.
+func (r *ScheduledReplanner) Run() {
+	minute := r.now().Truncate(time.Minute)
+	r.mu.Lock()
+	if !r.schedule.Matches(minute) || !minute.After(r.lastRun) {
+		r.mu.Unlock()
+		return
+	}
+	r.lastRun = minute
+	r.mu.Unlock()
+
+	claimed, err := r.backend.TryLockScheduledRun(scheduledReplanJobName, minute)
+	if err != nil {
+		r.logger.Err("claiming scheduled re-plan: %s", err)
+		return
+	}
+	if !claimed {
+		r.logger.Debug("skipping scheduled re-plan, another Atlantis server is running it")
+		return
+	}
+
+	statuses, err := r.backend.GetPullStatuses()
+	if err != nil {
+		r.logger.Err("listing pull requests to re-plan: %s", err)
+		return
+	}
+	for _, status := range statuses {
+		pull := status.Pull
+		if pull.State != models.OpenPullState || len(status.Projects) == 0 {
+			continue
+		}
+		if !replannable(pull.BaseRepo.VCSHost.Type) {
+			r.logger.Debug("skipping scheduled re-plan of pull request %s#%d, its current head can't be fetched from %s", pull.BaseRepo.FullName, pull.Num, pull.BaseRepo.VCSHost.Type.String())
+			continue
+		}
+		if !r.repoAllowlist.IsAllowlisted(pull.BaseRepo.FullName, pull.BaseRepo.VCSHost.Hostname) {
+			r.logger.Debug("skipping scheduled re-plan of pull request %s#%d, its repo isn't allowlisted", pull.BaseRepo.FullName, pull.Num)
+			continue
+		}
+		if r.label != "" {
+			labels, err := r.vcsClient.GetPullLabels(pull.BaseRepo, pull)
+			if err != nil {
+				r.logger.Err("getting labels of pull request %s#%d: %s", pull.BaseRepo.FullName, pull.Num, err)
+				continue
+			}
+			if !containsString(labels, r.label) {
+				continue
+			}
+		}
+		r.logger.Info("running scheduled re-plan of pull request %s#%d", pull.BaseRepo.FullName, pull.Num)
+		// The stored pull request may be outdated and the head repo isn't
+		// stored at all, so neither is passed and CommandRunner fetches both.
+		r.commandRunner.RunCommentCommand(pull.BaseRepo, nil, nil, models.User{Username: pull.Author}, pull.Num, &CommentCommand{Name: command.Plan})
+	}
+}
+
+// replannable returns true if CommandRunner fetches the current pull request
+// and its head repo from vcsHostType when they aren't passed. Other VCSs
+// rely on the webhook's data, so their pull requests would be planned at an
+// outdated commit or, if from a fork, from the base repo.
+func replannable(vcsHostType models.VCSHostType) bool {
+	return vcsHostType == models.Github || vcsHostType == models.AzureDevops
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock/v4"
+	lockmocks "github.com/runatlantis/atlantis/server/core/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/scheduled"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// fakeCommandRunner records the comment commands it's asked to run.
+type fakeCommandRunner struct {
+	pullNums []int
+	users    []models.User
+	cmds     []*CommentCommand
+}
+
+func (f *fakeCommandRunner) RunCommentCommand(_ models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, cmd *CommentCommand) {
+	if maybeHeadRepo != nil || maybePull != nil {
+		panic("the head repo and pull request should be fetched by the command runner")
+	}
+	f.pullNums = append(f.pullNums, pullNum)
+	f.users = append(f.users, user)
+	f.cmds = append(f.cmds, cmd)
+}
+
+func (f *fakeCommandRunner) RunAutoplanCommand(models.Repo, models.Repo, models.PullRequest, models.User) {
+}
+
+func replannerTestAllowlist(t *testing.T, allowlist string) *RepoAllowlistChecker {
+	checker, err := NewRepoAllowlistChecker(allowlist)
+	Ok(t, err)
+	return checker
+}
+
+func replannerTestStatus(num int, state models.PullRequestState, planned bool) models.PullStatus {
+	status := models.PullStatus{
+		Pull: models.PullRequest{
+			Num:    num,
+			Author: "author",
+			State:  state,
+			BaseRepo: models.Repo{
+				FullName: "owner/repo",
+				VCSHost:  models.VCSHost{Type: models.Github, Hostname: "github.com"},
+			},
+		},
+	}
+	if planned {
+		status.Projects = []models.ProjectStatus{{RepoRelDir: ".", Workspace: "default", Status: models.PlannedPlanStatus}}
+	}
+	return status
+}
+
+// replannerTestBackend returns a backend mock whose scheduled runs are claimed
+// if claimed is true.
+func replannerTestBackend(claimed bool) *lockmocks.MockBackend {
+	backend := lockmocks.NewMockBackend()
+	When(backend.TryLockScheduledRun(Eq(scheduledReplanJobName), Any[time.Time]())).ThenReturn(claimed, nil)
+	return backend
+}
+
+func TestScheduledReplanner_Run(t *testing.T) {
+	RegisterMockTestingT(t)
+	schedule, err := scheduled.ParseCronSchedule("0 2 * * *")
+	Ok(t, err)
+	backend := replannerTestBackend(true)
+	When(backend.GetPullStatuses()).ThenReturn([]models.PullStatus{
+		replannerTestStatus(1, models.OpenPullState, true),
+		replannerTestStatus(2, models.ClosedPullState, true),
+		replannerTestStatus(3, models.OpenPullState, false),
+		replannerTestStatus(4, models.OpenPullState, true),
+	}, nil)
+	runner := &fakeCommandRunner{}
+	replanner := NewScheduledReplanner(schedule, "", backend, vcsmocks.NewMockClient(), runner, replannerTestAllowlist(t, "*"), logging.NewNoopLogger(t))
+
+	now := time.Date(2024, 1, 1, 1, 59, 30, 0, time.UTC)
+	replanner.now = func() time.Time { return now }
+	replanner.Run()
+	Equals(t, 0, len(runner.pullNums))
+
+	// Only the open pulls that have been planned are re-planned, as their
+	// author.
+	now = now.Add(time.Minute)
+	replanner.Run()
+	Equals(t, 2, len(runner.pullNums))
+	Equals(t, 1, runner.pullNums[0])
+	Equals(t, 4, runner.pullNums[1])
+	Equals(t, models.User{Username: "author"}, runner.users[0])
+	Equals(t, command.Plan, runner.cmds[0].Name)
+
+	// It doesn't run twice in the same minute.
+	now = now.Add(20 * time.Second)
+	replanner.Run()
+	Equals(t, 2, len(runner.pullNums))
+
+	now = now.Add(24 * time.Hour)
+	replanner.Run()
+	Equals(t, 4, len(runner.pullNums))
+}
+
+func TestScheduledReplanner_RunLabel(t *testing.T) {
+	RegisterMockTestingT(t)
+	schedule, err := scheduled.ParseCronSchedule("* * * * *")
+	Ok(t, err)
+	backend := replannerTestBackend(true)
+	statuses := []models.PullStatus{
+		replannerTestStatus(1, models.OpenPullState, true),
+		replannerTestStatus(2, models.OpenPullState, true),
+		replannerTestStatus(3, models.OpenPullState, true),
+	}
+	When(backend.GetPullStatuses()).ThenReturn(statuses, nil)
+	vcsClient := vcsmocks.NewMockClient()
+	When(vcsClient.GetPullLabels(statuses[0].Pull.BaseRepo, statuses[0].Pull)).ThenReturn([]string{"bug", "nightly-replan"}, nil)
+	When(vcsClient.GetPullLabels(statuses[1].Pull.BaseRepo, statuses[1].Pull)).ThenReturn([]string{"bug"}, nil)
+	When(vcsClient.GetPullLabels(statuses[2].Pull.BaseRepo, statuses[2].Pull)).ThenReturn(nil, errors.New("err"))
+	runner := &fakeCommandRunner{}
+	replanner := NewScheduledReplanner(schedule, "nightly-replan", backend, vcsClient, runner, replannerTestAllowlist(t, "*"), logging.NewNoopLogger(t))
+
+	replanner.Run()
+	Equals(t, 1, len(runner.pullNums))
+	Equals(t, 1, runner.pullNums[0])
+}
+
+func TestScheduledReplanner_RunSkipped(t *testing.T) {
+	RegisterMockTestingT(t)
+	schedule, err := scheduled.ParseCronSchedule("* * * * *")
+	Ok(t, err)
+	backend := replannerTestBackend(true)
+	statuses := []models.PullStatus{
+		replannerTestStatus(1, models.OpenPullState, true),
+		replannerTestStatus(2, models.OpenPullState, true),
+		replannerTestStatus(3, models.OpenPullState, true),
+		replannerTestStatus(4, models.OpenPullState, true),
+	}
+	statuses[1].Pull.BaseRepo.FullName = "other/repo"
+	statuses[2].Pull.BaseRepo.VCSHost = models.VCSHost{Type: models.Gitlab, Hostname: "gitlab.com"}
+	statuses[3].Pull.BaseRepo.VCSHost = models.VCSHost{Type: models.BitbucketCloud, Hostname: "bitbucket.org"}
+	When(backend.GetPullStatuses()).ThenReturn(statuses, nil)
+	runner := &fakeCommandRunner{}
+	replanner := NewScheduledReplanner(schedule, "", backend, vcsmocks.NewMockClient(), runner, replannerTestAllowlist(t, "github.com/owner/*,gitlab.com/*,bitbucket.org/*"), logging.NewNoopLogger(t))
+
+	// Pull requests of repos that aren't allowlisted and of VCSs whose
+	// current pull requests can't be fetched aren't re-planned.
+	replanner.Run()
+	Equals(t, []int{1}, runner.pullNums)
+}
+
+func TestScheduledReplanner_RunListErr(t *testing.T) {
+	RegisterMockTestingT(t)
+	schedule, err := scheduled.ParseCronSchedule("* * * * *")
+	Ok(t, err)
+	backend := replannerTestBackend(true)
+	When(backend.GetPullStatuses()).ThenReturn(nil, errors.New("err"))
+	runner := &fakeCommandRunner{}
+	replanner := NewScheduledReplanner(schedule, "", backend, vcsmocks.NewMockClient(), runner, replannerTestAllowlist(t, "*"), logging.NewNoopLogger(t))
+
+	replanner.Run()
+	Equals(t, 0, len(runner.pullNums))
+}
+
+// Test that only the Atlantis server that claims the scheduled minute
+// re-plans.
+func TestScheduledReplanner_RunClaimedByOtherServer(t *testing.T) {
+	RegisterMockTestingT(t)
+	schedule, err := scheduled.ParseCronSchedule("* * * * *")
+	Ok(t, err)
+	backend := replannerTestBackend(false)
+	runner := &fakeCommandRunner{}
+	replanner := NewScheduledReplanner(schedule, "", backend, vcsmocks.NewMockClient(), runner, replannerTestAllowlist(t, "*"), logging.NewNoopLogger(t))
+	minute := time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)
+	replanner.now = func() time.Time { return minute.Add(30 * time.Second) }
+
+	replanner.Run()
+	backend.VerifyWasCalledOnce().TryLockScheduledRun(scheduledReplanJobName, minute)
+	backend.VerifyWasCalled(Never()).GetPullStatuses()
+	Equals(t, 0, len(runner.pullNums))
+}
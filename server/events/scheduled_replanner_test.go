@@ -0,0 +1,57 @@
+package events_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v54/github"
+	. "github.com/petergtz/pegomock/v4"
+	lockmocks "github.com/runatlantis/atlantis/server/core/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/models/testdata"
+	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/scheduled"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// Test that scheduled re-plans of fork pull requests are rejected like plan
+// comments since the head repo is fetched from the VCS.
+func TestScheduledReplanner_ForkPR(t *testing.T) {
+	vcsClient := setup(t)
+	ch.AllowForkPRs = false
+	ch.SilenceForkPRErrors = false
+	schedule, err := scheduled.ParseCronSchedule("* * * * *")
+	Ok(t, err)
+	allowlist, err := events.NewRepoAllowlistChecker("*")
+	Ok(t, err)
+
+	storedPull := models.PullRequest{
+		Num:      testdata.Pull.Num,
+		Author:   "author",
+		State:    models.OpenPullState,
+		BaseRepo: testdata.GithubRepo,
+	}
+	backend := lockmocks.NewMockBackend()
+	When(backend.TryLockScheduledRun(Eq("scheduled-replan"), Any[time.Time]())).ThenReturn(true, nil)
+	When(backend.GetPullStatuses()).ThenReturn([]models.PullStatus{{
+		Pull:     storedPull,
+		Projects: []models.ProjectStatus{{RepoRelDir: ".", Workspace: "default", Status: models.PlannedPlanStatus}},
+	}}, nil)
+
+	var pull github.PullRequest
+	When(githubGetter.GetPullRequest(testdata.GithubRepo, testdata.Pull.Num)).ThenReturn(&pull, nil)
+	headRepo := testdata.GithubRepo
+	headRepo.FullName = "forkrepo/atlantis"
+	headRepo.Owner = "forkrepo"
+	When(eventParsing.ParseGithubPull(&pull)).ThenReturn(storedPull, testdata.GithubRepo, headRepo, nil)
+
+	replanner := events.NewScheduledReplanner(schedule, "", backend, vcsClient, &ch, allowlist, logging.NewNoopLogger(t))
+	replanner.Run()
+
+	commentMessage := fmt.Sprintf("Atlantis commands can't be run on fork pull requests. To enable, set --%s  or, to disable this message, set --%s", ch.AllowForkPRsFlag, ch.SilenceForkPRErrorsFlag)
+	vcsClient.VerifyWasCalledOnce().CreateComment(testdata.GithubRepo, testdata.Pull.Num, commentMessage, "")
+	projectCommandBuilder.VerifyWasCalled(Never()).BuildPlanCommands(Any[*command.Context](), Any[*events.CommentCommand]())
+}
@@ -0,0 +1,129 @@
+package events
+
+import (
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/command"
+)
+
+// stalePlanErr is printed by Terraform when a saved plan can't be applied
+// because the state changed after the plan was created, ex. because another
+// pull request was applied.
+const stalePlanErr = "Saved plan is stale"
+
+// StalePlanRetrier plans and applies again the projects whose apply failed
+// because their plan was stale. Each project is retried at most once per
+// apply command. Projects whose plans are policy checked aren't retried since
+// the new plan would be applied before its policies are checked.
+// A nil *StalePlanRetrier retries nothing.
+type StalePlanRetrier struct {
+	PlanCommandBuilder  ProjectPlanCommandBuilder
+	PlanCommandRunner   ProjectPlanCommandRunner
+	ApplyCommandBuilder ProjectApplyCommandBuilder
+	ApplyCommandRunner  ProjectApplyCommandRunner
+	// DBUpdater stores the new plans so the apply is built from them, ex.
+	// with the number of resources they destroy.
+	DBUpdater *DBUpdater
+}
+
+// IsStalePlan returns true if res is an apply that failed because its plan
+// was stale.
+func IsStalePlan(res command.ProjectResult) bool {
+	return res.Command == command.Apply && res.Error != nil && strings.Contains(res.Error.Error(), stalePlanErr)
+}
+
+// Retry plans and applies again the projects of projectCmds whose results in
+// result have stale plans and returns result with their results replaced.
+// cmd is the apply command that was run. If planning a project again fails,
+// or it can't be applied, its plan result is returned instead.
+func (r *StalePlanRetrier) Retry(ctx *command.Context, cmd *CommentCommand, projectCmds []command.ProjectContext, result command.Result) command.Result {
+	if r == nil {
+		return result
+	}
+	retried := result
+	retried.ProjectResults = append([]command.ProjectResult(nil), result.ProjectResults...)
+	for i, res := range retried.ProjectResults {
+		if !IsStalePlan(res) {
+			continue
+		}
+		applyCtx, ok := findProjectCmd(projectCmds, res)
+		if !ok {
+			continue
+		}
+		retried.ProjectResults[i] = r.retry(ctx, cmd, applyCtx, res)
+	}
+	return retried
+}
+
+// retry plans and applies the project of applyCtx again. res is the result of
+// the apply that failed.
+func (r *StalePlanRetrier) retry(ctx *command.Context, cmd *CommentCommand, applyCtx command.ProjectContext, res command.ProjectResult) command.ProjectResult {
+	planCtxs, err := r.PlanCommandBuilder.BuildPlanCommands(ctx, selectProject(&CommentCommand{Name: command.Plan}, applyCtx))
+	if err != nil {
+		ctx.Log.Warn("unable to plan project at dir %q, workspace %q again: %s", res.RepoRelDir, res.Workspace, err)
+		return res
+	}
+	for _, planCtx := range planCtxs {
+		if planCtx.CommandName == command.PolicyCheck {
+			ctx.Log.Info("plan for project at dir %q, workspace %q is stale but it's not planned again since its policies are checked", res.RepoRelDir, res.Workspace)
+			return res
+		}
+	}
+	if len(planCtxs) != 1 {
+		ctx.Log.Warn("unable to plan project at dir %q, workspace %q again: found %d projects", res.RepoRelDir, res.Workspace, len(planCtxs))
+		return res
+	}
+
+	ctx.Log.Info("plan for project at dir %q, workspace %q is stale, planning and applying it again", res.RepoRelDir, res.Workspace)
+	planRes := r.PlanCommandRunner.Plan(planCtxs[0])
+	if planRes.Error != nil || planRes.Failure != "" {
+		return planRes
+	}
+
+	// The apply context is built again from the stored new plan so it
+	// reflects it, ex. the no_destroy requirement checks its destroys.
+	results := []command.ProjectResult{planRes}
+	markNoDestroy(planCtxs, results)
+	pullStatus, err := r.DBUpdater.updateDB(ctx, ctx.Pull, results)
+	if err != nil {
+		ctx.Log.Warn("unable to store the new plan of project at dir %q, workspace %q: %s", res.RepoRelDir, res.Workspace, err)
+		return planRes
+	}
+	ctx.PullStatus = &pullStatus
+	applyCmd := &CommentCommand{Name: command.Apply}
+	if cmd != nil {
+		c := *cmd
+		applyCmd = &c
+	}
+	applyCtxs, err := r.ApplyCommandBuilder.BuildApplyCommands(ctx, selectProject(applyCmd, applyCtx))
+	if err != nil || len(applyCtxs) != 1 {
+		ctx.Log.Warn("unable to apply project at dir %q, workspace %q again: %v", res.RepoRelDir, res.Workspace, err)
+		return planRes
+	}
+	applyRes := r.ApplyCommandRunner.Apply(applyCtxs[0])
+	applyRes.ReplanSuccess = planRes.PlanSuccess
+	return applyRes
+}
+
+// selectProject returns cmd set to run only the project of projectCtx.
+func selectProject(cmd *CommentCommand, projectCtx command.ProjectContext) *CommentCommand {
+	cmd.ProjectName = projectCtx.ProjectName
+	cmd.RepoRelDir = ""
+	cmd.Workspace = ""
+	if projectCtx.ProjectName == "" {
+		cmd.RepoRelDir = projectCtx.RepoRelDir
+		cmd.Workspace = projectCtx.Workspace
+	}
+	return cmd
+}
+
+// findProjectCmd returns the project command in projectCmds that res is the
+// result of.
+func findProjectCmd(projectCmds []command.ProjectContext, res command.ProjectResult) (command.ProjectContext, bool) {
+	for _, p := range projectCmds {
+		if p.RepoRelDir == res.RepoRelDir && p.Workspace == res.Workspace && p.ProjectName == res.ProjectName {
+			return p, true
+		}
+	}
+	return command.ProjectContext{}, false
+}
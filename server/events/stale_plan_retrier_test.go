@@ -0,0 +1,146 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/petergtz/pegomock/v4"
+	"github.com/runatlantis/atlantis/server/core/db"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var staleApplyErr = errors.New("exit status 1: running \"terraform apply\" in \"/tmp/repo\"\nError: Saved plan is stale\n\nThe given plan file can no longer be applied because the state was changed by another operation after the plan was created.")
+
+func TestIsStalePlan(t *testing.T) {
+	Equals(t, true, events.IsStalePlan(command.ProjectResult{Command: command.Apply, Error: staleApplyErr}))
+	Equals(t, false, events.IsStalePlan(command.ProjectResult{Command: command.Apply, Error: errors.New("exit status 1")}))
+	Equals(t, false, events.IsStalePlan(command.ProjectResult{Command: command.Apply, ApplySuccess: "Apply complete!"}))
+	Equals(t, false, events.IsStalePlan(command.ProjectResult{Command: command.Plan, Error: staleApplyErr}))
+}
+
+func TestStalePlanRetrier_Retry(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	ctx := &command.Context{Log: logger, Pull: models.PullRequest{Num: 1}}
+	projectCmds := []command.ProjectContext{
+		{CommandName: command.Apply, RepoRelDir: "stale", Workspace: "default", ProjectName: "stale"},
+		{CommandName: command.Apply, RepoRelDir: "failed", Workspace: "default"},
+	}
+	staleRes := command.ProjectResult{Command: command.Apply, RepoRelDir: "stale", Workspace: "default", ProjectName: "stale", Error: staleApplyErr}
+	failedRes := command.ProjectResult{Command: command.Apply, RepoRelDir: "failed", Workspace: "default", Error: errors.New("exit status 1")}
+	result := command.Result{ProjectResults: []command.ProjectResult{staleRes, failedRes}}
+	planCtx := command.ProjectContext{CommandName: command.Plan, RepoRelDir: "stale", Workspace: "default", ProjectName: "stale"}
+	// The apply context is built again after the project is planned.
+	replannedApplyCtx := command.ProjectContext{CommandName: command.Apply, RepoRelDir: "stale", Workspace: "default", ProjectName: "stale", Verbose: true}
+	applyCmd := &events.CommentCommand{Name: command.Apply, Flags: []string{"-lock=false"}}
+	planSuccess := &models.PlanSuccess{TerraformOutput: "Plan: 1 to add, 0 to change, 1 to destroy."}
+
+	cases := []struct {
+		description string
+		planRes     command.ProjectResult
+		applyRes    command.ProjectResult
+		expApplied  bool
+		expRes      command.ProjectResult
+	}{
+		{
+			description: "plan and apply succeed",
+			planRes:     command.ProjectResult{Command: command.Plan, RepoRelDir: "stale", Workspace: "default", ProjectName: "stale", PlanSuccess: planSuccess},
+			applyRes:    command.ProjectResult{Command: command.Apply, ApplySuccess: "Apply complete!"},
+			expApplied:  true,
+			expRes:      command.ProjectResult{Command: command.Apply, ApplySuccess: "Apply complete!", ReplanSuccess: planSuccess},
+		},
+		{
+			description: "plan fails",
+			planRes:     command.ProjectResult{Command: command.Plan, Error: errors.New("plan failed")},
+			expRes:      command.ProjectResult{Command: command.Plan, Error: errors.New("plan failed")},
+		},
+		{
+			description: "apply is stale again",
+			planRes:     command.ProjectResult{Command: command.Plan, RepoRelDir: "stale", Workspace: "default", ProjectName: "stale", PlanSuccess: planSuccess},
+			applyRes:    staleRes,
+			expApplied:  true,
+			expRes: command.ProjectResult{Command: command.Apply, RepoRelDir: "stale", Workspace: "default", ProjectName: "stale",
+				Error: staleApplyErr, ReplanSuccess: planSuccess},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			builder := mocks.NewMockProjectCommandBuilder()
+			runner := mocks.NewMockProjectCommandRunner()
+			boltDB, err := db.New(t.TempDir())
+			Ok(t, err)
+			ctx.PullStatus = nil
+			retrier := &events.StalePlanRetrier{
+				PlanCommandBuilder:  builder,
+				PlanCommandRunner:   runner,
+				ApplyCommandBuilder: builder,
+				ApplyCommandRunner:  runner,
+				DBUpdater:           &events.DBUpdater{Backend: boltDB},
+			}
+			When(builder.BuildPlanCommands(Eq(ctx), Eq(&events.CommentCommand{Name: command.Plan, ProjectName: "stale"}))).
+				ThenReturn([]command.ProjectContext{planCtx}, nil)
+			When(builder.BuildApplyCommands(Eq(ctx), Eq(&events.CommentCommand{Name: command.Apply, Flags: []string{"-lock=false"}, ProjectName: "stale"}))).
+				ThenReturn([]command.ProjectContext{replannedApplyCtx}, nil)
+			When(runner.Plan(planCtx)).ThenReturn(c.planRes)
+			When(runner.Apply(replannedApplyCtx)).ThenReturn(c.applyRes)
+
+			retried := retrier.Retry(ctx, applyCmd, projectCmds, result)
+			Equals(t, []command.ProjectResult{c.expRes, failedRes}, retried.ProjectResults)
+			Equals(t, []command.ProjectResult{staleRes, failedRes}, result.ProjectResults)
+			runner.VerifyWasCalledOnce().Plan(Any[command.ProjectContext]())
+			if c.expApplied {
+				runner.VerifyWasCalledOnce().Apply(replannedApplyCtx)
+			} else {
+				runner.VerifyWasCalled(Never()).Apply(Any[command.ProjectContext]())
+			}
+			Equals(t, &events.CommentCommand{Name: command.Apply, Flags: []string{"-lock=false"}}, applyCmd)
+			if c.expApplied {
+				// The apply is built from the stored new plan.
+				Assert(t, ctx.PullStatus != nil, "exp the new plan to be stored")
+				Equals(t, 1, len(ctx.PullStatus.Projects))
+				Equals(t, "stale", ctx.PullStatus.Projects[0].ProjectName)
+				Equals(t, 1, ctx.PullStatus.Projects[0].PlanDestroys)
+			} else {
+				Assert(t, ctx.PullStatus == nil, "exp no plan to be stored")
+			}
+		})
+	}
+}
+
+func TestStalePlanRetrier_RetryPolicyChecked(t *testing.T) {
+	RegisterMockTestingT(t)
+	logger := logging.NewNoopLogger(t)
+	ctx := &command.Context{Log: logger, Pull: models.PullRequest{Num: 1}}
+	projectCmds := []command.ProjectContext{{CommandName: command.Apply, RepoRelDir: "stale", Workspace: "default"}}
+	staleRes := command.ProjectResult{Command: command.Apply, RepoRelDir: "stale", Workspace: "default", Error: staleApplyErr}
+	result := command.Result{ProjectResults: []command.ProjectResult{staleRes}}
+	builder := mocks.NewMockProjectCommandBuilder()
+	runner := mocks.NewMockProjectCommandRunner()
+	retrier := &events.StalePlanRetrier{
+		PlanCommandBuilder:  builder,
+		PlanCommandRunner:   runner,
+		ApplyCommandBuilder: builder,
+		ApplyCommandRunner:  runner,
+	}
+	When(builder.BuildPlanCommands(Eq(ctx), Eq(&events.CommentCommand{Name: command.Plan, RepoRelDir: "stale", Workspace: "default"}))).
+		ThenReturn([]command.ProjectContext{
+			{CommandName: command.Plan, RepoRelDir: "stale", Workspace: "default"},
+			{CommandName: command.PolicyCheck, RepoRelDir: "stale", Workspace: "default"},
+		}, nil)
+
+	retried := retrier.Retry(ctx, &events.CommentCommand{Name: command.Apply}, projectCmds, result)
+	Equals(t, result, retried)
+	runner.VerifyWasCalled(Never()).Plan(Any[command.ProjectContext]())
+	runner.VerifyWasCalled(Never()).Apply(Any[command.ProjectContext]())
+}
+
+func TestStalePlanRetrier_Nil(t *testing.T) {
+	result := command.Result{ProjectResults: []command.ProjectResult{{Command: command.Apply, Error: staleApplyErr}}}
+	var retrier *events.StalePlanRetrier
+	Equals(t, result, retrier.Retry(&command.Context{}, nil, nil, result))
+}
@@ -0,0 +1,41 @@
+package events
+
+import (
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// ThrottledWorkingDir implements WorkingDir.
+// It acts as a proxy to an instance of WorkingDir that limits how many clones
+// can run at the same time across all pull requests so that many pull
+// requests updated at once don't exhaust the server's IO.
+type ThrottledWorkingDir struct {
+	WorkingDir
+	maxConcurrentClones int
+	cloneSlots          chan struct{}
+	logger              logging.SimpleLogging
+}
+
+// NewThrottledWorkingDir returns a ThrottledWorkingDir that runs at most
+// maxConcurrentClones clones of workingDir at the same time.
+func NewThrottledWorkingDir(workingDir WorkingDir, maxConcurrentClones int, logger logging.SimpleLogging) *ThrottledWorkingDir {
+	return &ThrottledWorkingDir{
+		WorkingDir:          workingDir,
+		maxConcurrentClones: maxConcurrentClones,
+		cloneSlots:          make(chan struct{}, maxConcurrentClones),
+		logger:              logger,
+	}
+}
+
+// Clone blocks until fewer than the max number of clones are running and then
+// clones.
+func (t *ThrottledWorkingDir) Clone(headRepo models.Repo, p models.PullRequest, workspace string) (string, bool, error) {
+	select {
+	case t.cloneSlots <- struct{}{}:
+	default:
+		t.logger.Info("%d clones are already running, waiting for one to finish before cloning %s#%d", t.maxConcurrentClones, p.BaseRepo.FullName, p.Num)
+		t.cloneSlots <- struct{}{}
+	}
+	defer func() { <-t.cloneSlots }()
+	return t.WorkingDir.Clone(headRepo, p, workspace)
+}
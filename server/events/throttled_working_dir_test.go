@@ -0,0 +1,67 @@
+package events_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// concurrencyTrackingWorkingDir records the most clones it saw running at once.
+type concurrencyTrackingWorkingDir struct {
+	events.WorkingDir
+	mu      sync.Mutex
+	calls   int
+	running int
+	max     int
+}
+
+func (w *concurrencyTrackingWorkingDir) Clone(_ models.Repo, _ models.PullRequest, _ string) (string, bool, error) {
+	w.mu.Lock()
+	w.calls++
+	w.running++
+	if w.running > w.max {
+		w.max = w.running
+	}
+	w.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	w.mu.Lock()
+	w.running--
+	w.mu.Unlock()
+	return "path/to/repo", false, nil
+}
+
+func TestThrottledWorkingDir_Clone(t *testing.T) {
+	workingDir := &concurrencyTrackingWorkingDir{}
+	throttled := events.NewThrottledWorkingDir(workingDir, 2, logging.NewNoopLogger(t))
+
+	// Clone for several pull requests at once.
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 1; i <= 5; i++ {
+		pull := models.PullRequest{Num: i}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			repoDir, _, err := throttled.Clone(models.Repo{}, pull, "default")
+			if err == nil && repoDir != "path/to/repo" {
+				t.Errorf("unexpected repo dir %q", repoDir)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		Ok(t, err)
+	}
+
+	Equals(t, 5, workingDir.calls)
+	Equals(t, 2, workingDir.max)
+}
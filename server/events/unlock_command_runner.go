@@ -1,6 +1,7 @@
 package events
 
 import (
+	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/command"
 	"github.com/runatlantis/atlantis/server/events/vcs"
 	"slices"
@@ -73,6 +74,13 @@ func (u *UnlockCommandRunner) Run(
 		}
 	}
 
+	switch {
+	case err != nil:
+		addAuditResult(ctx, command.Result{Error: errors.Wrap(err, vcsMessage)})
+	case hasLabel:
+		addAuditResult(ctx, command.Result{Failure: vcsMessage})
+	}
+
 	if commentErr := u.vcsClient.CreateComment(baseRepo, pullNum, vcsMessage, command.Unlock.String()); commentErr != nil {
 		ctx.Log.Err("unable to comment: %s", commentErr)
 	}
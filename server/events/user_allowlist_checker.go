@@ -0,0 +1,68 @@
+package events
+
+import (
+	"strings"
+)
+
+// teamPrefix marks an allowlist entry as a team instead of a username.
+const teamPrefix = "team:"
+
+// UserAllowlistChecker checks if a user may run a command based on their
+// username or the teams they're a member of.
+// A nil *UserAllowlistChecker allows everyone.
+type UserAllowlistChecker struct {
+	users []string
+	teams []string
+}
+
+// NewUserAllowlistChecker parses a comma separated allowlist of usernames and
+// teams. Teams are prefixed with "team:", ex. "alice, team:platform".
+func NewUserAllowlistChecker(allowlist string) *UserAllowlistChecker {
+	checker := &UserAllowlistChecker{}
+	for _, entry := range strings.Split(allowlist, ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+		case strings.HasPrefix(entry, teamPrefix):
+			checker.teams = append(checker.teams, strings.TrimSpace(strings.TrimPrefix(entry, teamPrefix)))
+		default:
+			checker.users = append(checker.users, strings.TrimPrefix(entry, "@"))
+		}
+	}
+	return checker
+}
+
+// HasRules returns true if the allowlist restricts who can run the command.
+func (c *UserAllowlistChecker) HasRules() bool {
+	return c != nil && (len(c.users) > 0 || len(c.teams) > 0)
+}
+
+// IsAllowed returns true if username is in the allowlist or is a member of
+// one of its teams. getTeams is only called if the allowlist has teams and
+// the username itself isn't allowed so that the VCS host isn't queried
+// needlessly.
+func (c *UserAllowlistChecker) IsAllowed(username string, getTeams func() ([]string, error)) (bool, error) {
+	if !c.HasRules() {
+		return true, nil
+	}
+	for _, u := range c.users {
+		if u == wildcard || strings.EqualFold(u, username) {
+			return true, nil
+		}
+	}
+	if len(c.teams) == 0 {
+		return false, nil
+	}
+	userTeams, err := getTeams()
+	if err != nil {
+		return false, err
+	}
+	for _, t := range c.teams {
+		for _, userTeam := range userTeams {
+			if strings.EqualFold(t, userTeam) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
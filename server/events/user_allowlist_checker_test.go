@@ -0,0 +1,104 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestUserAllowlistChecker_IsAllowed(t *testing.T) {
+	cases := []struct {
+		description   string
+		allowlist     string
+		username      string
+		teams         []string
+		teamsErr      error
+		exp           bool
+		expErr        string
+		expTeamLookup bool
+	}{
+		{
+			description: "empty allowlist allows everyone",
+			allowlist:   "",
+			username:    "bob",
+			exp:         true,
+		},
+		{
+			description: "username match",
+			allowlist:   "alice, @Bob",
+			username:    "bob",
+			exp:         true,
+		},
+		{
+			description: "wildcard",
+			allowlist:   "*",
+			username:    "bob",
+			exp:         true,
+		},
+		{
+			description: "username not in allowlist without teams",
+			allowlist:   "alice",
+			username:    "bob",
+			exp:         false,
+		},
+		{
+			description: "username match doesn't look up teams",
+			allowlist:   "bob,team:platform",
+			username:    "bob",
+			exp:         true,
+		},
+		{
+			description:   "team match",
+			allowlist:     "alice,team:platform",
+			username:      "bob",
+			teams:         []string{"developers", "Platform"},
+			exp:           true,
+			expTeamLookup: true,
+		},
+		{
+			description:   "team not in allowlist",
+			allowlist:     "alice,team:platform",
+			username:      "bob",
+			teams:         []string{"developers"},
+			exp:           false,
+			expTeamLookup: true,
+		},
+		{
+			description:   "error looking up teams",
+			allowlist:     "team:platform",
+			username:      "bob",
+			teamsErr:      errors.New("api error"),
+			exp:           false,
+			expErr:        "api error",
+			expTeamLookup: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			lookedUpTeams := false
+			checker := events.NewUserAllowlistChecker(c.allowlist)
+			allowed, err := checker.IsAllowed(c.username, func() ([]string, error) {
+				lookedUpTeams = true
+				return c.teams, c.teamsErr
+			})
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+			} else {
+				Ok(t, err)
+			}
+			Equals(t, c.exp, allowed)
+			Equals(t, c.expTeamLookup, lookedUpTeams)
+		})
+	}
+}
+
+func TestUserAllowlistChecker_NilAllowsEveryone(t *testing.T) {
+	var checker *events.UserAllowlistChecker
+	Equals(t, false, checker.HasRules())
+	allowed, err := checker.IsAllowed("bob", nil)
+	Ok(t, err)
+	Assert(t, allowed, "exp nil checker to allow everyone")
+}
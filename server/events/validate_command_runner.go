@@ -0,0 +1,57 @@
+package events
+
+import (
+	"github.com/runatlantis/atlantis/server/events/command"
+)
+
+func NewValidateCommandRunner(
+	pullUpdater *PullUpdater,
+	prjCmdBuilder ProjectValidateCommandBuilder,
+	prjCmdRunner ProjectValidateCommandRunner,
+	parallelPoolSize int,
+) *ValidateCommandRunner {
+	return &ValidateCommandRunner{
+		pullUpdater:      pullUpdater,
+		prjCmdBuilder:    prjCmdBuilder,
+		prjCmdRunner:     prjCmdRunner,
+		parallelPoolSize: parallelPoolSize,
+	}
+}
+
+// ValidateCommandRunner runs terraform validate for the projects affected by
+// a pull request. Unlike plan, it doesn't lock projects or write planfiles.
+type ValidateCommandRunner struct {
+	pullUpdater      *PullUpdater
+	prjCmdBuilder    ProjectValidateCommandBuilder
+	prjCmdRunner     ProjectValidateCommandRunner
+	parallelPoolSize int
+}
+
+func (v *ValidateCommandRunner) Run(ctx *command.Context, cmd *CommentCommand) {
+	projectCmds, err := v.prjCmdBuilder.BuildValidateCommands(ctx, cmd)
+	if err != nil {
+		ctx.Log.Warn("Error %s", err)
+		v.pullUpdater.updatePull(ctx, cmd, command.Result{Error: err})
+		return
+	}
+
+	if len(projectCmds) == 0 {
+		ctx.Log.Info("no projects to run validate in")
+		return
+	}
+
+	// Only run commands in parallel if enabled
+	var result command.Result
+	if v.isParallelEnabled(projectCmds) {
+		ctx.Log.Info("Running validate in parallel")
+		result = runProjectCmdsParallelGroups(ctx, projectCmds, v.prjCmdRunner.Validate, v.parallelPoolSize)
+	} else {
+		result = runProjectCmds(projectCmds, v.prjCmdRunner.Validate)
+	}
+
+	v.pullUpdater.updatePull(ctx, cmd, result)
+}
+
+func (v *ValidateCommandRunner) isParallelEnabled(cmds []command.ProjectContext) bool {
+	return len(cmds) > 0 && cmds[0].ParallelPlanEnabled
+}
@@ -23,10 +23,11 @@ type AzureDevopsClient struct {
 }
 
 // NewAzureDevopsClient returns a valid Azure DevOps client.
-func NewAzureDevopsClient(hostname string, userName string, token string) (*AzureDevopsClient, error) {
+func NewAzureDevopsClient(hostname string, userName string, token string, transport http.RoundTripper) (*AzureDevopsClient, error) {
 	tp := azuredevops.BasicAuthTransport{
-		Username: "",
-		Password: strings.TrimSpace(token),
+		Username:  "",
+		Password:  strings.TrimSpace(token),
+		Transport: transport,
 	}
 	httpClient := tp.Client()
 	httpClient.Timeout = time.Second * 10
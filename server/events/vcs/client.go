@@ -14,6 +14,8 @@
 package vcs
 
 import (
+	"strings"
+
 	"github.com/runatlantis/atlantis/server/events/models"
 )
 
@@ -53,3 +55,23 @@ type Client interface {
 	// GetPullLabels returns the labels of a pull request
 	GetPullLabels(repo models.Repo, pull models.PullRequest) ([]string, error)
 }
+
+// CommentEditor creates comments that can be found and edited later.
+type CommentEditor interface {
+	// CreateCommentWithID creates a single comment and returns its ID.
+	CreateCommentWithID(repo models.Repo, pullNum int, comment string) (int64, error)
+	// FindComment returns the ID of the latest comment Atlantis created on
+	// the pull request that contains marker, or 0 if there's none.
+	FindComment(repo models.Repo, pullNum int, marker string) (int64, error)
+	// EditComment replaces the body of the comment with ID commentID.
+	EditComment(repo models.Repo, pullNum int, commentID int64, comment string) error
+}
+
+// isPlanComment returns true if body is a comment with the results of a plan,
+// which starts with ex. "Ran Plan for dir: `.` workspace: `default`", or with
+// ex. "Plan `project`: 1 to add, 0 to change, 0 to destroy." if plan headers
+// are minimized.
+func isPlanComment(body string) bool {
+	firstLine, _, _ := strings.Cut(strings.ToLower(body), "\n")
+	return strings.HasPrefix(firstLine, "ran plan ") || strings.HasPrefix(firstLine, "plan ")
+}
@@ -18,6 +18,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -198,6 +199,202 @@ func (g *GithubClient) CreateComment(repo models.Repo, pullNum int, comment stri
 	return nil
 }
 
+// CreateCommentWithID creates a comment and returns its ID. Unlike
+// CreateComment it doesn't split comments that are too long.
+func (g *GithubClient) CreateCommentWithID(repo models.Repo, pullNum int, comment string) (int64, error) {
+	if len(comment) > maxCommentLength {
+		return 0, fmt.Errorf("comment is longer than the maximum of %d characters", maxCommentLength)
+	}
+	created, resp, err := g.client.Issues.CreateComment(g.ctx, repo.Owner, repo.Name, pullNum, &github.IssueComment{Body: github.String(comment)})
+	if resp != nil {
+		g.logger.Debug("POST /repos/%v/%v/issues/%d/comments returned: %v", repo.Owner, repo.Name, pullNum, resp.StatusCode)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return created.GetID(), nil
+}
+
+// FindComment returns the ID of the latest comment by the Atlantis user on
+// the pull request that contains marker, or 0 if there's none.
+func (g *GithubClient) FindComment(repo models.Repo, pullNum int, marker string) (int64, error) {
+	var commentID int64
+	nextPage := 0
+	for {
+		comments, resp, err := g.client.Issues.ListComments(g.ctx, repo.Owner, repo.Name, pullNum, &github.IssueListCommentsOptions{
+			Sort:        github.String("created"),
+			Direction:   github.String("asc"),
+			ListOptions: github.ListOptions{Page: nextPage},
+		})
+		if resp != nil {
+			g.logger.Debug("GET /repos/%v/%v/issues/%d/comments returned: %v", repo.Owner, repo.Name, pullNum, resp.StatusCode)
+		}
+		if err != nil {
+			return 0, errors.Wrap(err, "listing comments")
+		}
+		for _, comment := range comments {
+			if strings.EqualFold(comment.GetUser().GetLogin(), g.user) && strings.Contains(comment.GetBody(), marker) {
+				commentID = comment.GetID()
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+	return commentID, nil
+}
+
+// EditComment replaces the body of the comment with ID commentID.
+func (g *GithubClient) EditComment(repo models.Repo, _ int, commentID int64, comment string) error {
+	if len(comment) > maxCommentLength {
+		return fmt.Errorf("comment is longer than the maximum of %d characters", maxCommentLength)
+	}
+	_, resp, err := g.client.Issues.EditComment(g.ctx, repo.Owner, repo.Name, commentID, &github.IssueComment{Body: github.String(comment)})
+	if resp != nil {
+		g.logger.Debug("PATCH /repos/%v/%v/issues/comments/%d returned: %v", repo.Owner, repo.Name, commentID, resp.StatusCode)
+	}
+	return err
+}
+
+// CreateGist creates a secret gist with a single file and returns its URL.
+// Gists can only be created with user credentials, not by GitHub Apps.
+func (g *GithubClient) CreateGist(description string, filename string, content string) (string, error) {
+	gist, resp, err := g.client.Gists.Create(g.ctx, &github.Gist{
+		Description: github.String(description),
+		Public:      github.Bool(false),
+		Files: map[github.GistFilename]github.GistFile{
+			github.GistFilename(filename): {Content: github.String(content)},
+		},
+	})
+	if resp != nil {
+		g.logger.Debug("POST /gists returned: %v", resp.StatusCode)
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "creating gist")
+	}
+	return gist.GetHTMLURL(), nil
+}
+
+// CreateDeployment creates a deployment of ref to environment and returns its
+// ID. The deployment doesn't require any commit statuses to pass and doesn't
+// merge the default branch into ref.
+func (g *GithubClient) CreateDeployment(repo models.Repo, ref string, environment string, description string) (int64, error) {
+	deployment, resp, err := g.client.Repositories.CreateDeployment(g.ctx, repo.Owner, repo.Name, &github.DeploymentRequest{
+		Ref:              github.String(ref),
+		Environment:      github.String(environment),
+		Description:      github.String(description),
+		AutoMerge:        github.Bool(false),
+		RequiredContexts: &[]string{},
+	})
+	if resp != nil {
+		g.logger.Debug("POST /repos/%v/%v/deployments returned: %v", repo.Owner, repo.Name, resp.StatusCode)
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "creating deployment to environment %q", environment)
+	}
+	return deployment.GetID(), nil
+}
+
+// GetDeploymentState returns the state of the latest status of a deployment,
+// ex. "queued" or "success". It returns an empty string if the deployment
+// has no statuses yet.
+func (g *GithubClient) GetDeploymentState(repo models.Repo, deploymentID int64) (string, error) {
+	statuses, resp, err := g.client.Repositories.ListDeploymentStatuses(g.ctx, repo.Owner, repo.Name, deploymentID, &github.ListOptions{PerPage: 1})
+	if resp != nil {
+		g.logger.Debug("GET /repos/%v/%v/deployments/%d/statuses returned: %v", repo.Owner, repo.Name, deploymentID, resp.StatusCode)
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "getting statuses of deployment %d", deploymentID)
+	}
+	// Statuses are listed newest first.
+	if len(statuses) == 0 {
+		return "", nil
+	}
+	return statuses[0].GetState(), nil
+}
+
+// UpdateDeploymentStatus adds a status with state to a deployment.
+func (g *GithubClient) UpdateDeploymentStatus(repo models.Repo, deploymentID int64, state string, description string) error {
+	_, resp, err := g.client.Repositories.CreateDeploymentStatus(g.ctx, repo.Owner, repo.Name, deploymentID, &github.DeploymentStatusRequest{
+		State:       github.String(state),
+		Description: github.String(description),
+	})
+	if resp != nil {
+		g.logger.Debug("POST /repos/%v/%v/deployments/%d/statuses returned: %v", repo.Owner, repo.Name, deploymentID, resp.StatusCode)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "updating status of deployment %d", deploymentID)
+	}
+	return nil
+}
+
+// CreateCommentReview adds a review with body to the pull request that only
+// comments, without approving it or requesting changes. Unlike CreateComment,
+// body isn't split so it errors if body is longer than a comment can be.
+func (g *GithubClient) CreateCommentReview(repo models.Repo, pullNum int, body string) error {
+	if len(body) > maxCommentLength {
+		return fmt.Errorf("review of %d characters is longer than the maximum of %d", len(body), maxCommentLength)
+	}
+	_, resp, err := g.client.PullRequests.CreateReview(g.ctx, repo.Owner, repo.Name, pullNum, &github.PullRequestReviewRequest{
+		Body:  github.String(body),
+		Event: github.String("COMMENT"),
+	})
+	if resp != nil {
+		g.logger.Debug("POST /repos/%v/%v/pulls/%d/reviews returned: %v", repo.Owner, repo.Name, pullNum, resp.StatusCode)
+	}
+	if err != nil {
+		return errors.Wrap(err, "creating review")
+	}
+	return nil
+}
+
+// HidePrevPlanReviews collapses the plan reviews the Atlantis user added to
+// the pull request. Reviews can't be minimized so their body is replaced with
+// a note and the plan is moved into a collapsed section.
+func (g *GithubClient) HidePrevPlanReviews(repo models.Repo, pullNum int) error {
+	nextPage := 0
+	for {
+		reviews, resp, err := g.client.PullRequests.ListReviews(g.ctx, repo.Owner, repo.Name, pullNum, &github.ListOptions{Page: nextPage})
+		if resp != nil {
+			g.logger.Debug("GET /repos/%v/%v/pulls/%d/reviews returned: %v", repo.Owner, repo.Name, pullNum, resp.StatusCode)
+		}
+		if err != nil {
+			return errors.Wrap(err, "listing reviews")
+		}
+		for _, review := range reviews {
+			if !strings.EqualFold(review.GetUser().GetLogin(), g.user) || !isPlanReview(review.GetBody()) {
+				continue
+			}
+			body := fmt.Sprintf("%s\n\n<details><summary>Show outdated plan</summary>\n\n%s\n</details>", outdatedPlanReviewNote, review.GetBody())
+			if len(body) > maxCommentLength {
+				body = outdatedPlanReviewNote
+			}
+			_, resp, err := g.client.PullRequests.UpdateReview(g.ctx, repo.Owner, repo.Name, pullNum, review.GetID(), body)
+			if resp != nil {
+				g.logger.Debug("PUT /repos/%v/%v/pulls/%d/reviews/%d returned: %v", repo.Owner, repo.Name, pullNum, review.GetID(), resp.StatusCode)
+			}
+			if err != nil {
+				return errors.Wrapf(err, "hiding review %d", review.GetID())
+			}
+		}
+		if resp.NextPage == 0 {
+			return nil
+		}
+		nextPage = resp.NextPage
+	}
+}
+
+// outdatedPlanReviewNote replaces the body of plan reviews that were hidden.
+const outdatedPlanReviewNote = "This plan is outdated, see the latest plan."
+
+// isPlanReview returns true if body is the body of a plan review, including
+// the reviews of the projects of each code owners which start with
+// "## Plan: ".
+func isPlanReview(body string) bool {
+	return isPlanComment(body) || strings.HasPrefix(strings.ToLower(body), "## plan: ")
+}
+
 // ReactToComment adds a reaction to a comment.
 func (g *GithubClient) ReactToComment(repo models.Repo, pullNum int, commentID int64, reaction string) error {
 	_, resp, err := g.client.Reactions.CreateIssueCommentReaction(g.ctx, repo.Owner, repo.Name, commentID, reaction)
@@ -349,6 +546,49 @@ func (g *GithubClient) PullIsApproved(repo models.Repo, pull models.PullRequest)
 	return approvalStatus, nil
 }
 
+// GetPullApprovers returns the logins of the users whose latest review of the
+// pull request approves it, sorted. Comment reviews don't change whether a
+// user approved.
+func (g *GithubClient) GetPullApprovers(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	approved := make(map[string]bool)
+	nextPage := 0
+	for {
+		opts := github.ListOptions{
+			PerPage: 300,
+		}
+		if nextPage != 0 {
+			opts.Page = nextPage
+		}
+		pageReviews, resp, err := g.client.PullRequests.ListReviews(g.ctx, repo.Owner, repo.Name, pull.Num, &opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "getting reviews")
+		}
+		g.logger.Debug("GET /repos/%v/%v/pulls/%d/reviews returned: %v", repo.Owner, repo.Name, pull.Num, resp.StatusCode)
+		// Reviews are listed in the order they were submitted.
+		for _, review := range pageReviews {
+			switch review.GetState() {
+			case "APPROVED":
+				approved[review.GetUser().GetLogin()] = true
+			case "CHANGES_REQUESTED", "DISMISSED":
+				approved[review.GetUser().GetLogin()] = false
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+
+	var approvers []string
+	for login, ok := range approved {
+		if ok {
+			approvers = append(approvers, login)
+		}
+	}
+	sort.Strings(approvers)
+	return approvers, nil
+}
+
 // DiscardReviews dismisses all reviews on a pull request
 func (g *GithubClient) DiscardReviews(repo models.Repo, pull models.PullRequest) error {
 	reviewStatus, err := g.getPRReviews(repo, pull)
@@ -461,6 +701,208 @@ func (g *GithubClient) GetCombinedStatusMinusApply(repo models.Repo, pull *githu
 	return true, nil
 }
 
+// GetCommitStatuses returns the state of each commit status and check run of
+// pull's head commit, by context or check run name.
+func (g *GithubClient) GetCommitStatuses(repo models.Repo, pull models.PullRequest) (map[string]models.CommitStatus, error) {
+	statuses := make(map[string]models.CommitStatus)
+	nextPage := 0
+	for {
+		opts := github.ListOptions{
+			PerPage: 100,
+		}
+		if nextPage != 0 {
+			opts.Page = nextPage
+		}
+		combined, resp, err := g.client.Repositories.GetCombinedStatus(g.ctx, repo.Owner, repo.Name, pull.HeadCommit, &opts)
+		if resp != nil {
+			g.logger.Debug("GET /repos/%v/%v/commits/%s/status returned: %v", repo.Owner, repo.Name, pull.HeadCommit, resp.StatusCode)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "getting combined status")
+		}
+		for _, status := range combined.Statuses {
+			switch status.GetState() {
+			case "success":
+				statuses[status.GetContext()] = models.SuccessCommitStatus
+			case "pending":
+				statuses[status.GetContext()] = models.PendingCommitStatus
+			default:
+				statuses[status.GetContext()] = models.FailedCommitStatus
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+
+	nextPage = 0
+	for {
+		opts := github.ListCheckRunsOptions{
+			ListOptions: github.ListOptions{
+				PerPage: 100,
+			},
+		}
+		if nextPage != 0 {
+			opts.Page = nextPage
+		}
+		checkRuns, resp, err := g.client.Checks.ListCheckRunsForRef(g.ctx, repo.Owner, repo.Name, pull.HeadCommit, &opts)
+		if resp != nil {
+			g.logger.Debug("GET /repos/%v/%v/commits/%s/check-runs returned: %v", repo.Owner, repo.Name, pull.HeadCommit, resp.StatusCode)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "getting check runs")
+		}
+		for _, run := range checkRuns.CheckRuns {
+			switch {
+			case run.GetStatus() != "completed":
+				statuses[run.GetName()] = models.PendingCommitStatus
+			case run.GetConclusion() == "success" || run.GetConclusion() == "neutral" || run.GetConclusion() == "skipped":
+				statuses[run.GetName()] = models.SuccessCommitStatus
+			default:
+				statuses[run.GetName()] = models.FailedCommitStatus
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+	return statuses, nil
+}
+
+// GetUnsignedCommits returns the SHAs of pull's commits whose signatures
+// GitHub didn't verify, including commits that aren't signed.
+func (g *GithubClient) GetUnsignedCommits(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	var unsigned []string
+	nextPage := 0
+	for {
+		opts := github.ListOptions{
+			PerPage: 100,
+		}
+		if nextPage != 0 {
+			opts.Page = nextPage
+		}
+		commits, resp, err := g.client.PullRequests.ListCommits(g.ctx, repo.Owner, repo.Name, pull.Num, &opts)
+		if resp != nil {
+			g.logger.Debug("GET /repos/%v/%v/pulls/%d/commits returned: %v", repo.Owner, repo.Name, pull.Num, resp.StatusCode)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "listing pull request commits")
+		}
+		for _, commit := range commits {
+			if !commit.GetCommit().GetVerification().GetVerified() {
+				unsigned = append(unsigned, commit.GetSHA())
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+	return unsigned, nil
+}
+
+// githubReaction is a reaction with when it was created, which
+// github.Reaction doesn't have.
+type githubReaction struct {
+	Content   string       `json:"content"`
+	User      *github.User `json:"user"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// GetPlanCommentReactions returns the reactions that users with write access to
+// repo, other than the Atlantis user and pull's author, added to the latest
+// plan comment on pull since plannedAt. Edited plan comments keep the
+// reactions to the plans they showed before.
+func (g *GithubClient) GetPlanCommentReactions(repo models.Repo, pull models.PullRequest, plannedAt time.Time) ([]string, error) {
+	var planComment *github.IssueComment
+	nextPage := 0
+	for {
+		comments, resp, err := g.client.Issues.ListComments(g.ctx, repo.Owner, repo.Name, pull.Num, &github.IssueListCommentsOptions{
+			Sort:        github.String("created"),
+			Direction:   github.String("asc"),
+			ListOptions: github.ListOptions{Page: nextPage},
+		})
+		if resp != nil {
+			g.logger.Debug("GET /repos/%v/%v/issues/%d/comments returned: %v", repo.Owner, repo.Name, pull.Num, resp.StatusCode)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "listing comments")
+		}
+		for _, comment := range comments {
+			if strings.EqualFold(comment.GetUser().GetLogin(), g.user) && isPlanComment(comment.GetBody()) {
+				planComment = comment
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+	if planComment == nil {
+		return nil, nil
+	}
+
+	var reactions []string
+	canWrite := make(map[string]bool)
+	nextPage = 0
+	for {
+		u := fmt.Sprintf("repos/%v/%v/issues/comments/%d/reactions?per_page=100", repo.Owner, repo.Name, planComment.GetID())
+		if nextPage != 0 {
+			u = fmt.Sprintf("%s&page=%d", u, nextPage)
+		}
+		req, err := g.client.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		var commentReactions []githubReaction
+		resp, err := g.client.Do(g.ctx, req, &commentReactions)
+		if resp != nil {
+			g.logger.Debug("GET /repos/%v/%v/issues/comments/%d/reactions returned: %v", repo.Owner, repo.Name, planComment.GetID(), resp.StatusCode)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "listing plan comment reactions")
+		}
+		for _, reaction := range commentReactions {
+			login := strings.ToLower(reaction.User.GetLogin())
+			if strings.EqualFold(login, g.user) || strings.EqualFold(login, pull.Author) {
+				continue
+			}
+			if reaction.CreatedAt.Before(plannedAt) {
+				continue
+			}
+			if _, ok := canWrite[login]; !ok {
+				if canWrite[login], err = g.hasWriteAccess(repo, login); err != nil {
+					return nil, err
+				}
+			}
+			if canWrite[login] {
+				reactions = append(reactions, reaction.Content)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+	return reactions, nil
+}
+
+// hasWriteAccess returns true if user can push to repo.
+func (g *GithubClient) hasWriteAccess(repo models.Repo, user string) (bool, error) {
+	level, resp, err := g.client.Repositories.GetPermissionLevel(g.ctx, repo.Owner, repo.Name, user)
+	if resp != nil {
+		g.logger.Debug("GET /repos/%v/%v/collaborators/%v/permission returned: %v", repo.Owner, repo.Name, user, resp.StatusCode)
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "getting permission of %s", user)
+	}
+	// Users with the maintain role have the write permission.
+	permission := level.GetPermission()
+	return permission == "admin" || permission == "write", nil
+}
+
 // GetPullReviewDecision gets the pull review decision, which takes into account CODEOWNERS
 func (g *GithubClient) GetPullReviewDecision(repo models.Repo, pull models.PullRequest) (approvalStatus bool, err error) {
 	var query struct {
@@ -11,7 +11,9 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/go-github/v54/github"
 	"github.com/runatlantis/atlantis/server/events/command"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
@@ -63,7 +65,7 @@ func TestGithubClient_GetModifiedFiles(t *testing.T) {
 
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{}, logger)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logger)
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -118,7 +120,7 @@ func TestGithubClient_GetModifiedFilesMovedFile(t *testing.T) {
 
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -212,7 +214,7 @@ func TestGithubClient_PaginatesComments(t *testing.T) {
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
 
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -296,7 +298,7 @@ func TestGithubClient_HideOldComments(t *testing.T) {
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
 
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -362,7 +364,7 @@ func TestGithubClient_UpdateStatus(t *testing.T) {
 
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -448,7 +450,7 @@ func TestGithubClient_PullIsApproved(t *testing.T) {
 
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -469,6 +471,174 @@ func TestGithubClient_PullIsApproved(t *testing.T) {
 	Equals(t, false, approvalStatus.IsApproved)
 }
 
+func TestGithubClient_GetPullApprovers(t *testing.T) {
+	review := `{"id": %d, "user": {"login": %q}, "state": %q}`
+	firstResp := fmt.Sprintf("[%s,%s,%s]",
+		fmt.Sprintf(review, 1, "alice", "APPROVED"),
+		fmt.Sprintf(review, 2, "bob", "APPROVED"),
+		fmt.Sprintf(review, 3, "carol", "CHANGES_REQUESTED"))
+	secondResp := fmt.Sprintf("[%s,%s,%s]",
+		fmt.Sprintf(review, 4, "alice", "COMMENTED"),
+		fmt.Sprintf(review, 5, "bob", "CHANGES_REQUESTED"),
+		fmt.Sprintf(review, 6, "carol", "APPROVED"))
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/repos/owner/repo/pulls/1/reviews?per_page=300":
+				w.Header().Add("Link", `<https://api.github.com/resource?page=2>; rel="next"`)
+				w.Write([]byte(firstResp)) // nolint: errcheck
+			case "/api/v3/repos/owner/repo/pulls/1/reviews?page=2&per_page=300":
+				w.Write([]byte(secondResp)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
+	Ok(t, err)
+	defer disableSSLVerification()()
+
+	// Only the latest approving or rejecting review of each user counts.
+	approvers, err := client.GetPullApprovers(models.Repo{FullName: "owner/repo", Owner: "owner", Name: "repo"}, models.PullRequest{Num: 1})
+	Ok(t, err)
+	Equals(t, []string{"alice", "carol"}, approvers)
+}
+
+func TestGithubClient_GetCommitStatuses(t *testing.T) {
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/repos/owner/repo/commits/abc123/status?per_page=100":
+				// We write a header that means there's an additional page.
+				w.Header().Add("Link", `<https://api.github.com/resource?page=2>; rel="next"`)
+				w.Write([]byte(`{"state":"failure","statuses":[{"context":"ci/tests","state":"success"},{"context":"ci/lint","state":"failure"}]}`)) // nolint: errcheck
+			case "/api/v3/repos/owner/repo/commits/abc123/status?page=2&per_page=100":
+				w.Write([]byte(`{"state":"failure","statuses":[{"context":"ci/e2e","state":"pending"},{"context":"ci/build","state":"error"}]}`)) // nolint: errcheck
+			case "/api/v3/repos/owner/repo/commits/abc123/check-runs?per_page=100":
+				w.Write([]byte(`{"total_count":3,"check_runs":[{"name":"unit","status":"completed","conclusion":"success"},{"name":"docs","status":"completed","conclusion":"skipped"},{"name":"integration","status":"in_progress"},{"name":"security","status":"completed","conclusion":"timed_out"}]}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
+	Ok(t, err)
+	defer disableSSLVerification()()
+
+	statuses, err := client.GetCommitStatuses(models.Repo{
+		FullName: "owner/repo",
+		Owner:    "owner",
+		Name:     "repo",
+	}, models.PullRequest{
+		Num:        1,
+		HeadCommit: "abc123",
+	})
+	Ok(t, err)
+	Equals(t, map[string]models.CommitStatus{
+		"ci/tests":    models.SuccessCommitStatus,
+		"ci/lint":     models.FailedCommitStatus,
+		"ci/e2e":      models.PendingCommitStatus,
+		"ci/build":    models.FailedCommitStatus,
+		"unit":        models.SuccessCommitStatus,
+		"docs":        models.SuccessCommitStatus,
+		"integration": models.PendingCommitStatus,
+		"security":    models.FailedCommitStatus,
+	}, statuses)
+}
+
+func TestGithubClient_GetUnsignedCommits(t *testing.T) {
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/repos/owner/repo/pulls/1/commits?per_page=100":
+				// We write a header that means there's an additional page.
+				w.Header().Add("Link", `<https://api.github.com/resource?page=2>; rel="next"`)
+				w.Write([]byte(`[{"sha":"signed","commit":{"verification":{"verified":true,"reason":"valid"}}},{"sha":"unverified","commit":{"verification":{"verified":false,"reason":"unknown_key"}}}]`)) // nolint: errcheck
+			case "/api/v3/repos/owner/repo/pulls/1/commits?page=2&per_page=100":
+				w.Write([]byte(`[{"sha":"unsigned","commit":{"verification":{"verified":false,"reason":"unsigned"}}}]`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
+	Ok(t, err)
+	defer disableSSLVerification()()
+
+	unsigned, err := client.GetUnsignedCommits(models.Repo{
+		FullName: "owner/repo",
+		Owner:    "owner",
+		Name:     "repo",
+	}, models.PullRequest{
+		Num: 1,
+	})
+	Ok(t, err)
+	Equals(t, []string{"unverified", "unsigned"}, unsigned)
+}
+
+func TestGithubClient_GetPlanCommentReactions(t *testing.T) {
+	comments := `[
+		{"id":1,"body":"Ran Plan for dir: ` + "`.`" + ` workspace: ` + "`default`" + `","user":{"login":"user"}},
+		{"id":2,"body":"Plan ` + "`.`" + `: 1 to add, 0 to change, 0 to destroy.","user":{"login":"user"}},
+		{"id":3,"body":"Ran Plan for dir: ` + "`.`" + ` workspace: ` + "`default`" + `","user":{"login":"someone"}},
+		{"id":4,"body":"Ran Apply for dir: ` + "`.`" + ` workspace: ` + "`default`" + `","user":{"login":"user"}}
+	]`
+	planReactions := `[
+		{"content":"+1","user":{"login":"approver"},"created_at":"2024-01-02T00:00:00Z"},
+		{"content":"eyes","user":{"login":"user"},"created_at":"2024-01-02T00:00:00Z"},
+		{"content":"rocket","user":{"login":"author"},"created_at":"2024-01-02T00:00:00Z"},
+		{"content":"heart","user":{"login":"reader"},"created_at":"2024-01-02T00:00:00Z"},
+		{"content":"+1","user":{"login":"approver"},"created_at":"2024-01-02T00:00:00Z"},
+		{"content":"hooray","user":{"login":"approver"},"created_at":"2023-12-31T00:00:00Z"}
+	]`
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/repos/owner/repo/issues/1/comments?direction=asc&sort=created":
+				w.Write([]byte(comments)) // nolint: errcheck
+			case "/api/v3/repos/owner/repo/issues/comments/2/reactions?per_page=100":
+				w.Write([]byte(planReactions)) // nolint: errcheck
+			case "/api/v3/repos/owner/repo/collaborators/approver/permission":
+				w.Write([]byte(`{"permission":"write","user":{"login":"approver"}}`)) // nolint: errcheck
+			case "/api/v3/repos/owner/repo/collaborators/reader/permission":
+				w.Write([]byte(`{"permission":"read","user":{"login":"reader"}}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
+	Ok(t, err)
+	defer disableSSLVerification()()
+
+	reactions, err := client.GetPlanCommentReactions(models.Repo{
+		FullName: "owner/repo",
+		Owner:    "owner",
+		Name:     "repo",
+	}, models.PullRequest{
+		Num:    1,
+		Author: "author",
+	}, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	Ok(t, err)
+	// The reactions of the author, who can't approve their own plan, of users
+	// without write access and to an earlier plan the comment showed before it
+	// was edited aren't counted.
+	Equals(t, []string{"+1", "+1"}, reactions)
+}
+
 func TestGithubClient_PullIsMergeable(t *testing.T) {
 	vcsStatusName := "atlantis-test"
 	cases := []struct {
@@ -563,7 +733,7 @@ func TestGithubClient_PullIsMergeable(t *testing.T) {
 				}))
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -714,7 +884,7 @@ func TestGithubClient_PullIsMergeableWithAllowMergeableBypassApply(t *testing.T)
 				}))
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{AllowMergeableBypassApply: true}, logging.NewNoopLogger(t))
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{AllowMergeableBypassApply: true}, logging.NewNoopLogger(t))
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -822,7 +992,7 @@ func TestGithubClient_PullIsMergeableWithAllowMergeableBypassApplyButWithNoBranc
 				}))
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{AllowMergeableBypassApply: true}, logging.NewNoopLogger(t))
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{AllowMergeableBypassApply: true}, logging.NewNoopLogger(t))
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -904,7 +1074,7 @@ func TestGithubClient_MergePullHandlesError(t *testing.T) {
 
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -1027,7 +1197,7 @@ func TestGithubClient_MergePullCorrectMethod(t *testing.T) {
 
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
 			Ok(t, err)
 			defer disableSSLVerification()()
 
@@ -1055,7 +1225,7 @@ func TestGithubClient_MergePullCorrectMethod(t *testing.T) {
 }
 
 func TestGithubClient_MarkdownPullLink(t *testing.T) {
-	client, err := vcs.NewGithubClient("hostname", &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
+	client, err := vcs.NewGithubClient("hostname", &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
 	Ok(t, err)
 	pull := models.PullRequest{Num: 1}
 	s, _ := client.MarkdownPullLink(pull)
@@ -1110,7 +1280,7 @@ func TestGithubClient_SplitComments(t *testing.T) {
 
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
 	Ok(t, err)
 	defer disableSSLVerification()()
 	pull := models.PullRequest{Num: 1}
@@ -1168,7 +1338,7 @@ func TestGithubClient_Retry404(t *testing.T) {
 
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
 	Ok(t, err)
 	defer disableSSLVerification()()
 	repo := models.Repo{
@@ -1213,7 +1383,7 @@ func TestGithubClient_Retry404Files(t *testing.T) {
 
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
 	Ok(t, err)
 	defer disableSSLVerification()()
 	repo := models.Repo{
@@ -1266,7 +1436,7 @@ func TestGithubClient_GetTeamNamesForUser(t *testing.T) {
 		}))
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{}, logger)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logger)
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -1279,6 +1449,222 @@ func TestGithubClient_GetTeamNamesForUser(t *testing.T) {
 	Equals(t, []string{"Frontend Developers", "frontend-developers", "Employees", "employees"}, teams)
 }
 
+func TestGithubClient_CreateGist(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/gists":
+				body, err := io.ReadAll(r.Body)
+				Ok(t, err)
+				var gist github.Gist
+				Ok(t, json.Unmarshal(body, &gist))
+				Equals(t, "plan output", gist.GetDescription())
+				Equals(t, false, gist.GetPublic())
+				file := gist.Files["plan.diff"]
+				Equals(t, "+ null_resource.a", file.GetContent())
+				w.Write([]byte(`{"html_url": "https://gist.github.com/abc123"}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+		}))
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logger)
+	Ok(t, err)
+	defer disableSSLVerification()()
+
+	gistURL, err := client.CreateGist("plan output", "plan.diff", "+ null_resource.a")
+	Ok(t, err)
+	Equals(t, "https://gist.github.com/abc123", gistURL)
+}
+
+func TestGithubClient_CreateCommentReview(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/repos/owner/repo/pulls/1/reviews":
+				Equals(t, "POST", r.Method)
+				body, err := io.ReadAll(r.Body)
+				Ok(t, err)
+				var review github.PullRequestReviewRequest
+				Ok(t, json.Unmarshal(body, &review))
+				Equals(t, "COMMENT", review.GetEvent())
+				Equals(t, "Ran Plan for dir: `.` workspace: `default`", review.GetBody())
+				w.Write([]byte(`{"id": 1}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+		}))
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logger)
+	Ok(t, err)
+	defer disableSSLVerification()()
+	repo := models.Repo{FullName: "owner/repo", Owner: "owner", Name: "repo"}
+
+	Ok(t, client.CreateCommentReview(repo, 1, "Ran Plan for dir: `.` workspace: `default`"))
+
+	// Reviews that are too long aren't split like comments.
+	err = client.CreateCommentReview(repo, 1, strings.Repeat("a", 65537))
+	ErrEquals(t, "review of 65537 characters is longer than the maximum of 65536", err)
+}
+
+func TestGithubClient_HidePrevPlanReviews(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	var updated []string
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method + " " + r.RequestURI {
+			case "GET /api/v3/repos/owner/repo/pulls/1/reviews":
+				w.Write([]byte(`[
+					{"id": 1, "user": {"login": "user"}, "body": "Ran Plan for dir: ` + "`.`" + `"},
+					{"id": 2, "user": {"login": "user"}, "body": "## Plan: Projects without owners"},
+					{"id": 3, "user": {"login": "someone"}, "body": "Ran Plan for dir: ` + "`.`" + `"},
+					{"id": 4, "user": {"login": "user"}, "body": "Ran Apply for dir: ` + "`.`" + `"}
+				]`)) // nolint: errcheck
+			case "PUT /api/v3/repos/owner/repo/pulls/1/reviews/1", "PUT /api/v3/repos/owner/repo/pulls/1/reviews/2":
+				body, err := io.ReadAll(r.Body)
+				Ok(t, err)
+				var review struct{ Body string }
+				Ok(t, json.Unmarshal(body, &review))
+				updated = append(updated, review.Body)
+				w.Write([]byte(`{"id": 1}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+		}))
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logger)
+	Ok(t, err)
+	defer disableSSLVerification()()
+	repo := models.Repo{FullName: "owner/repo", Owner: "owner", Name: "repo"}
+
+	Ok(t, client.HidePrevPlanReviews(repo, 1))
+	Equals(t, []string{
+		"This plan is outdated, see the latest plan.\n\n<details><summary>Show outdated plan</summary>\n\nRan Plan for dir: `.`\n</details>",
+		"This plan is outdated, see the latest plan.\n\n<details><summary>Show outdated plan</summary>\n\n## Plan: Projects without owners\n</details>",
+	}, updated)
+}
+
+func TestGithubClient_EditComment(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			Ok(t, err)
+			var comment github.IssueComment
+			if len(body) > 0 {
+				Ok(t, json.Unmarshal(body, &comment))
+			}
+			switch r.Method + " " + r.RequestURI {
+			case "GET /api/v3/repos/owner/repo/issues/1/comments?direction=asc&sort=created":
+				w.Write([]byte(`[
+					{"id": 121, "body": "plan 0\n<!-- marker -->", "user": {"login": "user"}},
+					{"id": 122, "body": "plan 0\n<!-- marker -->", "user": {"login": "someone-else"}},
+					{"id": 123, "body": "plan 1\n<!-- marker -->", "user": {"login": "user"}},
+					{"id": 124, "body": "plan 1\n<!-- other-marker -->", "user": {"login": "user"}}
+				]`)) // nolint: errcheck
+			case "POST /api/v3/repos/owner/repo/issues/1/comments":
+				Equals(t, "plan 1", comment.GetBody())
+				w.Write([]byte(`{"id": 123}`)) // nolint: errcheck
+			case "PATCH /api/v3/repos/owner/repo/issues/comments/123":
+				Equals(t, "plan 2", comment.GetBody())
+				w.Write([]byte(`{"id": 123}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+		}))
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logger)
+	Ok(t, err)
+	defer disableSSLVerification()()
+	repo := models.Repo{FullName: "owner/repo", Owner: "owner", Name: "repo"}
+
+	commentID, err := client.CreateCommentWithID(repo, 1, "plan 1")
+	Ok(t, err)
+	Equals(t, int64(123), commentID)
+	Ok(t, client.EditComment(repo, 1, commentID, "plan 2"))
+
+	commentID, err = client.FindComment(repo, 1, "<!-- marker -->")
+	Ok(t, err)
+	Equals(t, int64(123), commentID)
+	commentID, err = client.FindComment(repo, 1, "<!-- missing-marker -->")
+	Ok(t, err)
+	Equals(t, int64(0), commentID)
+
+	_, err = client.CreateCommentWithID(repo, 1, strings.Repeat("a", 65537))
+	ErrEquals(t, "comment is longer than the maximum of 65536 characters", err)
+}
+
+func TestGithubClient_Deployments(t *testing.T) {
+	logger := logging.NewNoopLogger(t)
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method + " " + r.RequestURI {
+			case "POST /api/v3/repos/owner/repo/deployments":
+				body, err := io.ReadAll(r.Body)
+				Ok(t, err)
+				var req github.DeploymentRequest
+				Ok(t, json.Unmarshal(body, &req))
+				Equals(t, "abc123", req.GetRef())
+				Equals(t, "production", req.GetEnvironment())
+				Equals(t, false, req.GetAutoMerge())
+				Equals(t, []string{}, req.GetRequiredContexts())
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"id": 42}`)) // nolint: errcheck
+			case "GET /api/v3/repos/owner/repo/deployments/42/statuses?per_page=1":
+				w.Write([]byte(`[{"state": "in_progress"}]`)) // nolint: errcheck
+			case "GET /api/v3/repos/owner/repo/deployments/43/statuses?per_page=1":
+				w.Write([]byte(`[]`)) // nolint: errcheck
+			case "POST /api/v3/repos/owner/repo/deployments/42/statuses":
+				body, err := io.ReadAll(r.Body)
+				Ok(t, err)
+				var req github.DeploymentStatusRequest
+				Ok(t, json.Unmarshal(body, &req))
+				Equals(t, "success", req.GetState())
+				Equals(t, "applied", req.GetDescription())
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"state": "success"}`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request %s %q", r.Method, r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+		}))
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logger)
+	Ok(t, err)
+	defer disableSSLVerification()()
+	repo := models.Repo{Owner: "owner", Name: "repo", FullName: "owner/repo"}
+
+	id, err := client.CreateDeployment(repo, "abc123", "production", "apply")
+	Ok(t, err)
+	Equals(t, int64(42), id)
+
+	state, err := client.GetDeploymentState(repo, 42)
+	Ok(t, err)
+	Equals(t, "in_progress", state)
+
+	state, err = client.GetDeploymentState(repo, 43)
+	Ok(t, err)
+	Equals(t, "", state)
+
+	Ok(t, client.UpdateDeploymentStatus(repo, 42, "success", "applied"))
+}
+
 func TestGithubClient_DiscardReviews(t *testing.T) {
 	type ResponseDef struct {
 		httpCode int
@@ -1464,7 +1850,7 @@ func TestGithubClient_DiscardReviews(t *testing.T) {
 				}))
 			testServerURL, err := url.Parse(testServer.URL)
 			Ok(t, err)
-			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
+			client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logging.NewNoopLogger(t))
 			Ok(t, err)
 			defer disableSSLVerification()()
 			if err := client.DiscardReviews(tt.args.repo, tt.args.pull); (err != nil) != tt.wantErr {
@@ -1533,7 +1919,7 @@ func TestGithubClient_GetPullLabels(t *testing.T) {
 		}))
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{}, logger)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logger)
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -1568,7 +1954,7 @@ func TestGithubClient_GetPullLabels_EmptyResponse(t *testing.T) {
 		}))
 	testServerURL, err := url.Parse(testServer.URL)
 	Ok(t, err)
-	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{"user", "pass"}, vcs.GithubConfig{}, logger)
+	client, err := vcs.NewGithubClient(testServerURL.Host, &vcs.GithubUserCredentials{User: "user", Token: "pass"}, vcs.GithubConfig{}, logger)
 	Ok(t, err)
 	defer disableSSLVerification()()
 
@@ -59,16 +59,23 @@ var commonMarkSupported = MustConstraint(">=11.1")
 var gitlabClientUnderTest = false
 
 // NewGitlabClient returns a valid GitLab client.
-func NewGitlabClient(hostname string, token string, logger logging.SimpleLogging) (*GitlabClient, error) {
+func NewGitlabClient(hostname string, token string, transport http.RoundTripper, logger logging.SimpleLogging) (*GitlabClient, error) {
 	client := &GitlabClient{
 		PollingInterval: time.Second,
 		PollingTimeout:  time.Second * 30,
 		logger:          logger,
 	}
 
+	// Only override the HTTP client if we need a different transport so
+	// that the GitLab library's defaults are kept otherwise.
+	var opts []gitlab.ClientOptionFunc
+	if transport != nil {
+		opts = append(opts, gitlab.WithHTTPClient(&http.Client{Transport: transport}))
+	}
+
 	// Create the client differently depending on the base URL.
 	if hostname == "gitlab.com" {
-		glClient, err := gitlab.NewClient(token)
+		glClient, err := gitlab.NewClient(token, opts...)
 		if err != nil {
 			return nil, err
 		}
@@ -97,7 +104,7 @@ func NewGitlabClient(hostname string, token string, logger logging.SimpleLogging
 		// Now we're ready to construct the client.
 		absoluteURL = strings.TrimSuffix(absoluteURL, "/")
 		apiURL := fmt.Sprintf("%s/api/v4/", absoluteURL)
-		glClient, err := gitlab.NewClient(token, gitlab.WithBaseURL(apiURL))
+		glClient, err := gitlab.NewClient(token, append(opts, gitlab.WithBaseURL(apiURL))...)
 		if err != nil {
 			return nil, err
 		}
@@ -187,6 +194,77 @@ func (g *GitlabClient) CreateComment(repo models.Repo, pullNum int, comment stri
 	return nil
 }
 
+// CreateCommentWithID creates a comment and returns its ID. Unlike
+// CreateComment it doesn't split comments that are too long.
+func (g *GitlabClient) CreateCommentWithID(repo models.Repo, pullNum int, comment string) (int64, error) {
+	if len(comment) > gitlabMaxCommentLength {
+		return 0, fmt.Errorf("comment is longer than the maximum of %d characters", gitlabMaxCommentLength)
+	}
+	note, resp, err := g.Client.Notes.CreateMergeRequestNote(repo.FullName, pullNum, &gitlab.CreateMergeRequestNoteOptions{Body: gitlab.String(comment)})
+	if resp != nil {
+		g.logger.Debug("POST /projects/%s/merge_requests/%d/notes returned: %d", repo.FullName, pullNum, resp.StatusCode)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int64(note.ID), nil
+}
+
+// supersededCommentHeader starts the notes superseded by
+// HidePrevCommandComments.
+const supersededCommentHeader = "<!--- +-Superseded Command-+ --->"
+
+// FindComment returns the ID of the latest note by the Atlantis user on the
+// merge request that contains marker, or 0 if there's none. Notes that were
+// superseded by HidePrevCommandComments are skipped.
+func (g *GitlabClient) FindComment(repo models.Repo, pullNum int, marker string) (int64, error) {
+	currentUser, _, err := g.Client.Users.CurrentUser()
+	if err != nil {
+		return 0, errors.Wrap(err, "getting current user")
+	}
+	var commentID int64
+	nextPage := 0
+	for {
+		notes, resp, err := g.Client.Notes.ListMergeRequestNotes(repo.FullName, pullNum,
+			&gitlab.ListMergeRequestNotesOptions{
+				Sort:        gitlab.String("asc"),
+				OrderBy:     gitlab.String("created_at"),
+				ListOptions: gitlab.ListOptions{Page: nextPage},
+			})
+		if resp != nil {
+			g.logger.Debug("GET /projects/%s/merge_requests/%d/notes returned: %d", repo.FullName, pullNum, resp.StatusCode)
+		}
+		if err != nil {
+			return 0, errors.Wrap(err, "listing comments")
+		}
+		for _, note := range notes {
+			if note.System || !strings.EqualFold(note.Author.Username, currentUser.Username) {
+				continue
+			}
+			if strings.Contains(note.Body, marker) && !strings.HasPrefix(note.Body, supersededCommentHeader) {
+				commentID = int64(note.ID)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+	return commentID, nil
+}
+
+// EditComment replaces the body of the comment with ID commentID.
+func (g *GitlabClient) EditComment(repo models.Repo, pullNum int, commentID int64, comment string) error {
+	if len(comment) > gitlabMaxCommentLength {
+		return fmt.Errorf("comment is longer than the maximum of %d characters", gitlabMaxCommentLength)
+	}
+	_, resp, err := g.Client.Notes.UpdateMergeRequestNote(repo.FullName, pullNum, int(commentID), &gitlab.UpdateMergeRequestNoteOptions{Body: gitlab.String(comment)})
+	if resp != nil {
+		g.logger.Debug("PUT /projects/%s/merge_requests/%d/notes/%d returned: %d", repo.FullName, pullNum, commentID, resp.StatusCode)
+	}
+	return err
+}
+
 // ReactToComment adds a reaction to a comment.
 func (g *GitlabClient) ReactToComment(repo models.Repo, pullNum int, commentID int64, reaction string) error {
 	_, resp, err := g.Client.AwardEmoji.CreateMergeRequestAwardEmojiOnNote(repo.FullName, pullNum, int(commentID), &gitlab.CreateAwardEmojiOptions{Name: reaction})
@@ -222,7 +300,7 @@ func (g *GitlabClient) HidePrevCommandComments(repo models.Repo, pullNum int, co
 		return errors.Wrap(err, "error getting currentuser")
 	}
 
-	summaryHeader := fmt.Sprintf("<!--- +-Superseded Command-+ ---><details><summary>Superseded Atlantis %s</summary>", command)
+	summaryHeader := fmt.Sprintf("%s<details><summary>Superseded Atlantis %s</summary>", supersededCommentHeader, command)
 	summaryFooter := "</details>"
 	lineFeed := "\n"
 
@@ -262,14 +340,203 @@ func (g *GitlabClient) PullIsApproved(repo models.Repo, pull models.PullRequest)
 	if err != nil {
 		return approvalStatus, err
 	}
-	if approvals.ApprovalsLeft > 0 {
-		return approvalStatus, nil
-	}
 	return models.ApprovalStatus{
-		IsApproved: true,
+		IsApproved:    approvals.ApprovalsLeft <= 0,
+		ApprovalRules: g.pullApprovalRules(repo, pull),
 	}, nil
 }
 
+// pullApprovalRules returns whether each of the merge request's approval rules
+// is approved, by name. Approval rules need GitLab Premium so it returns nil
+// if they can't be fetched.
+func (g *GitlabClient) pullApprovalRules(repo models.Repo, pull models.PullRequest) map[string]bool {
+	state, resp, err := g.Client.MergeRequestApprovals.GetApprovalState(repo.FullName, pull.Num)
+	if resp != nil {
+		g.logger.Debug("GET /projects/%s/merge_requests/%d/approval_state returned: %d", repo.FullName, pull.Num, resp.StatusCode)
+	}
+	if err != nil {
+		g.logger.Debug("unable to get approval rules of merge request %d: %s", pull.Num, err)
+		return nil
+	}
+	approvalRules := make(map[string]bool, len(state.Rules))
+	for _, rule := range state.Rules {
+		approvalRules[rule.Name] = rule.Approved
+	}
+	return approvalRules
+}
+
+// GetCommitStatuses returns the state of each commit status of pull's head
+// commit, by name. Only the latest status with each name is returned.
+func (g *GitlabClient) GetCommitStatuses(repo models.Repo, pull models.PullRequest) (map[string]models.CommitStatus, error) {
+	statuses := make(map[string]models.CommitStatus)
+	nextPage := 0
+	for {
+		opts := gitlab.GetCommitStatusesOptions{
+			ListOptions: gitlab.ListOptions{PerPage: 100},
+		}
+		if nextPage != 0 {
+			opts.Page = nextPage
+		}
+		pageStatuses, resp, err := g.Client.Commits.GetCommitStatuses(repo.FullName, pull.HeadCommit, &opts)
+		if resp != nil {
+			g.logger.Debug("GET /projects/%s/repository/commits/%s/statuses returned: %d", repo.FullName, pull.HeadCommit, resp.StatusCode)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "getting commit statuses")
+		}
+		for _, status := range pageStatuses {
+			switch gitlab.BuildStateValue(status.Status) {
+			case gitlab.Success:
+				statuses[status.Name] = models.SuccessCommitStatus
+			case gitlab.Failed, gitlab.Canceled:
+				statuses[status.Name] = models.FailedCommitStatus
+			default:
+				statuses[status.Name] = models.PendingCommitStatus
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+	return statuses, nil
+}
+
+// GetUnsignedCommits returns the SHAs of pull's commits whose signatures
+// GitLab didn't verify, including commits that aren't signed.
+func (g *GitlabClient) GetUnsignedCommits(repo models.Repo, pull models.PullRequest) ([]string, error) {
+	var unsigned []string
+	nextPage := 0
+	for {
+		opts := gitlab.GetMergeRequestCommitsOptions{PerPage: 100}
+		if nextPage != 0 {
+			opts.Page = nextPage
+		}
+		commits, resp, err := g.Client.MergeRequests.GetMergeRequestCommits(repo.FullName, pull.Num, &opts)
+		if resp != nil {
+			g.logger.Debug("GET /projects/%s/merge_requests/%d/commits returned: %d", repo.FullName, pull.Num, resp.StatusCode)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "listing merge request commits")
+		}
+		for _, commit := range commits {
+			// The signature of commits that aren't signed isn't found.
+			signature, sigResp, err := g.Client.Commits.GetGPGSignature(repo.FullName, commit.ID)
+			if sigResp != nil {
+				g.logger.Debug("GET /projects/%s/repository/commits/%s/signature returned: %d", repo.FullName, commit.ID, sigResp.StatusCode)
+			}
+			if sigResp != nil && sigResp.StatusCode == http.StatusNotFound {
+				unsigned = append(unsigned, commit.ID)
+				continue
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "getting signature of commit %s", commit.ID)
+			}
+			if signature.VerificationStatus != "verified" {
+				unsigned = append(unsigned, commit.ID)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+	return unsigned, nil
+}
+
+// GetPlanCommentReactions returns the award emojis that users with at least the
+// Developer role in repo, other than the Atlantis user and pull's author, added
+// to the latest plan comment on pull since plannedAt. Edited plan comments keep
+// the award emojis of the plans they showed before.
+func (g *GitlabClient) GetPlanCommentReactions(repo models.Repo, pull models.PullRequest, plannedAt time.Time) ([]string, error) {
+	currentUser, _, err := g.Client.Users.CurrentUser()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting current user")
+	}
+
+	var planNote *gitlab.Note
+	nextPage := 0
+	for {
+		notes, resp, err := g.Client.Notes.ListMergeRequestNotes(repo.FullName, pull.Num, &gitlab.ListMergeRequestNotesOptions{
+			Sort:        gitlab.String("asc"),
+			OrderBy:     gitlab.String("created_at"),
+			ListOptions: gitlab.ListOptions{Page: nextPage},
+		})
+		if resp != nil {
+			g.logger.Debug("GET /projects/%s/merge_requests/%d/notes returned: %d", repo.FullName, pull.Num, resp.StatusCode)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "listing comments")
+		}
+		for _, note := range notes {
+			if !note.System && strings.EqualFold(note.Author.Username, currentUser.Username) && isPlanComment(note.Body) {
+				planNote = note
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+	if planNote == nil {
+		return nil, nil
+	}
+
+	var reactions []string
+	canWrite := make(map[int]bool)
+	nextPage = 0
+	for {
+		opts := gitlab.ListAwardEmojiOptions{PerPage: 100}
+		if nextPage != 0 {
+			opts.Page = nextPage
+		}
+		emojis, resp, err := g.Client.AwardEmoji.ListMergeRequestAwardEmojiOnNote(repo.FullName, pull.Num, planNote.ID, &opts)
+		if resp != nil {
+			g.logger.Debug("GET /projects/%s/merge_requests/%d/notes/%d/award_emoji returned: %d", repo.FullName, pull.Num, planNote.ID, resp.StatusCode)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "listing plan comment award emojis")
+		}
+		for _, emoji := range emojis {
+			if strings.EqualFold(emoji.User.Username, currentUser.Username) || strings.EqualFold(emoji.User.Username, pull.Author) {
+				continue
+			}
+			if emoji.CreatedAt != nil && emoji.CreatedAt.Before(plannedAt) {
+				continue
+			}
+			if _, ok := canWrite[emoji.User.ID]; !ok {
+				if canWrite[emoji.User.ID], err = g.isDeveloper(repo, emoji.User.ID); err != nil {
+					return nil, err
+				}
+			}
+			if canWrite[emoji.User.ID] {
+				reactions = append(reactions, emoji.Name)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+	return reactions, nil
+}
+
+// isDeveloper returns true if the user with userID has at least the Developer
+// role in repo, directly or through a group.
+func (g *GitlabClient) isDeveloper(repo models.Repo, userID int) (bool, error) {
+	member, resp, err := g.Client.ProjectMembers.GetInheritedProjectMember(repo.FullName, userID)
+	if resp != nil {
+		g.logger.Debug("GET /projects/%s/members/all/%d returned: %d", repo.FullName, userID, resp.StatusCode)
+		if resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "getting membership of user %d", userID)
+	}
+	return member.AccessLevel >= gitlab.DeveloperPermissions, nil
+}
+
 // PullIsMergeable returns true if the merge request can be merged.
 // In GitLab, there isn't a single field that tells us if the pull request is
 // mergeable so for now we check the merge_status and approvals_before_merge
@@ -61,7 +61,7 @@ func TestNewGitlabClient_BaseURL(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.Hostname, func(t *testing.T) {
 			log := logging.NewNoopLogger(t)
-			client, err := NewGitlabClient(c.Hostname, "token", log)
+			client, err := NewGitlabClient(c.Hostname, "token", nil, log)
 			Ok(t, err)
 			Equals(t, c.ExpBaseURL, client.Client.BaseURL().String())
 		})
@@ -480,10 +480,202 @@ func TestGitlabClient_PullIsMergeable(t *testing.T) {
 	}
 }
 
+func TestGitlabClient_PullIsApproved(t *testing.T) {
+	cases := []struct {
+		description      string
+		approvalsLeft    int
+		approvalState    string
+		expApprovalRules map[string]bool
+	}{
+		{
+			description:   "approved with approval rules",
+			approvalsLeft: 0,
+			approvalState: `{"approval_rules_overwritten":false,"rules":[{"id":1,"name":"Security","approved":true},{"id":2,"name":"Platform","approved":true}]}`,
+			expApprovalRules: map[string]bool{
+				"Security": true,
+				"Platform": true,
+			},
+		},
+		{
+			description:   "not approved with approval rules",
+			approvalsLeft: 1,
+			approvalState: `{"approval_rules_overwritten":false,"rules":[{"id":1,"name":"Security","approved":false},{"id":2,"name":"Platform","approved":true}]}`,
+			expApprovalRules: map[string]bool{
+				"Security": false,
+				"Platform": true,
+			},
+		},
+		{
+			description:   "approval rules unavailable",
+			approvalsLeft: 0,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			testServer := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.RequestURI {
+					case "/api/v4/projects/runatlantis%2Fatlantis/merge_requests/1/approvals":
+						w.WriteHeader(http.StatusOK)
+						w.Write([]byte(fmt.Sprintf(`{"id":1,"iid":1,"approvals_required":1,"approvals_left":%d}`, c.approvalsLeft))) // nolint: errcheck
+					case "/api/v4/projects/runatlantis%2Fatlantis/merge_requests/1/approval_state":
+						if c.approvalState == "" {
+							// Approval rules need GitLab Premium.
+							http.Error(w, `{"message":"403 Forbidden"}`, http.StatusForbidden)
+							return
+						}
+						w.WriteHeader(http.StatusOK)
+						w.Write([]byte(c.approvalState)) // nolint: errcheck
+					default:
+						t.Errorf("got unexpected request at %q", r.RequestURI)
+						http.Error(w, "not found", http.StatusNotFound)
+					}
+				}))
+
+			internalClient, err := gitlab.NewClient("token", gitlab.WithBaseURL(testServer.URL))
+			Ok(t, err)
+			client := &GitlabClient{
+				Client:  internalClient,
+				Version: nil,
+				logger:  logging.NewNoopLogger(t),
+			}
+			repo := models.Repo{FullName: "runatlantis/atlantis"}
+
+			approvalStatus, err := client.PullIsApproved(repo, models.PullRequest{Num: 1})
+			Ok(t, err)
+			Equals(t, models.ApprovalStatus{
+				IsApproved:    c.approvalsLeft == 0,
+				ApprovalRules: c.expApprovalRules,
+			}, approvalStatus)
+		})
+	}
+}
+
+func TestGitlabClient_GetCommitStatuses(t *testing.T) {
+	var testServer *httptest.Server
+	testServer = httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v4/projects/runatlantis%2Fatlantis/repository/commits/abc123/statuses?per_page=100":
+				// There's an additional page.
+				w.Header().Add("Link", fmt.Sprintf(`<%s/api/v4/projects/runatlantis%%2Fatlantis/repository/commits/abc123/statuses?page=2&per_page=100>; rel="next"`, testServer.URL))
+				w.Header().Add("X-Next-Page", "2")
+				w.Write([]byte(`[{"id":1,"name":"ci/tests","status":"success"},{"id":2,"name":"ci/lint","status":"failed"}]`)) // nolint: errcheck
+			case "/api/v4/projects/runatlantis%2Fatlantis/repository/commits/abc123/statuses?page=2&per_page=100":
+				w.Write([]byte(`[{"id":3,"name":"ci/e2e","status":"running"},{"id":4,"name":"ci/deploy","status":"canceled"}]`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+	defer testServer.Close()
+
+	internalClient, err := gitlab.NewClient("token", gitlab.WithBaseURL(testServer.URL))
+	Ok(t, err)
+	client := &GitlabClient{
+		Client:  internalClient,
+		Version: nil,
+		logger:  logging.NewNoopLogger(t),
+	}
+
+	statuses, err := client.GetCommitStatuses(models.Repo{FullName: "runatlantis/atlantis"}, models.PullRequest{Num: 1, HeadCommit: "abc123"})
+	Ok(t, err)
+	Equals(t, map[string]models.CommitStatus{
+		"ci/tests":  models.SuccessCommitStatus,
+		"ci/lint":   models.FailedCommitStatus,
+		"ci/e2e":    models.PendingCommitStatus,
+		"ci/deploy": models.FailedCommitStatus,
+	}, statuses)
+}
+
+func TestGitlabClient_GetUnsignedCommits(t *testing.T) {
+	var testServer *httptest.Server
+	testServer = httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v4/projects/runatlantis%2Fatlantis/merge_requests/1/commits?per_page=100":
+				// There's an additional page.
+				w.Header().Add("X-Next-Page", "2")
+				w.Write([]byte(`[{"id":"signed"},{"id":"unverified"}]`)) // nolint: errcheck
+			case "/api/v4/projects/runatlantis%2Fatlantis/merge_requests/1/commits?page=2&per_page=100":
+				w.Write([]byte(`[{"id":"unsigned"}]`)) // nolint: errcheck
+			case "/api/v4/projects/runatlantis%2Fatlantis/repository/commits/signed/signature":
+				w.Write([]byte(`{"signature_type":"SSH","verification_status":"verified"}`)) // nolint: errcheck
+			case "/api/v4/projects/runatlantis%2Fatlantis/repository/commits/unverified/signature":
+				w.Write([]byte(`{"signature_type":"PGP","verification_status":"unverified_key"}`)) // nolint: errcheck
+			case "/api/v4/projects/runatlantis%2Fatlantis/repository/commits/unsigned/signature":
+				http.Error(w, `{"message":"404 Signature Not Found"}`, http.StatusNotFound)
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+	defer testServer.Close()
+
+	internalClient, err := gitlab.NewClient("token", gitlab.WithBaseURL(testServer.URL))
+	Ok(t, err)
+	client := &GitlabClient{
+		Client:  internalClient,
+		Version: nil,
+		logger:  logging.NewNoopLogger(t),
+	}
+
+	unsigned, err := client.GetUnsignedCommits(models.Repo{FullName: "runatlantis/atlantis"}, models.PullRequest{Num: 1})
+	Ok(t, err)
+	Equals(t, []string{"unverified", "unsigned"}, unsigned)
+}
+
+func TestGitlabClient_GetPlanCommentReactions(t *testing.T) {
+	awardEmojis := `[
+		{"name":"thumbsup","user":{"id":1,"username":"approver"},"created_at":"2024-01-02T00:00:00Z"},
+		{"name":"rocket","user":{"id":1,"username":"approver"},"created_at":"2023-12-31T00:00:00Z"},
+		{"name":"eyes","user":{"id":2,"username":"atlantis"}},
+		{"name":"rocket","user":{"id":3,"username":"author"}},
+		{"name":"heart","user":{"id":4,"username":"reporter"}},
+		{"name":"tada","user":{"id":5,"username":"outsider"}}
+	]`
+	testServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v4/user":
+				w.Write([]byte(`{"username":"atlantis"}`)) // nolint: errcheck
+			case "/api/v4/projects/runatlantis%2Fatlantis/merge_requests/1/notes?order_by=created_at&sort=asc":
+				w.Write([]byte(`[{"id":1,"body":"Ran Plan for dir: .","author":{"username":"atlantis"}},{"id":2,"body":"Ran Plan for dir: .","author":{"username":"atlantis"}},{"id":3,"body":"Ran Plan for dir: .","author":{"username":"atlantis"},"system":true}]`)) // nolint: errcheck
+			case "/api/v4/projects/runatlantis%2Fatlantis/merge_requests/1/notes/2/award_emoji?per_page=100":
+				w.Write([]byte(awardEmojis)) // nolint: errcheck
+			case "/api/v4/projects/runatlantis%2Fatlantis/members/all/1":
+				w.Write([]byte(`{"id":1,"username":"approver","access_level":30}`)) // nolint: errcheck
+			case "/api/v4/projects/runatlantis%2Fatlantis/members/all/4":
+				w.Write([]byte(`{"id":4,"username":"reporter","access_level":20}`)) // nolint: errcheck
+			case "/api/v4/projects/runatlantis%2Fatlantis/members/all/5":
+				http.Error(w, `{"message":"404 Not found"}`, http.StatusNotFound)
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+	defer testServer.Close()
+
+	internalClient, err := gitlab.NewClient("token", gitlab.WithBaseURL(testServer.URL))
+	Ok(t, err)
+	client := &GitlabClient{
+		Client:  internalClient,
+		Version: nil,
+		logger:  logging.NewNoopLogger(t),
+	}
+
+	reactions, err := client.GetPlanCommentReactions(models.Repo{FullName: "runatlantis/atlantis"}, models.PullRequest{Num: 1, Author: "author"}, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	Ok(t, err)
+	// The award emojis of the author, who can't approve their own plan, of
+	// users without the Developer role and to an earlier plan the comment
+	// showed before it was edited aren't counted.
+	Equals(t, []string{"thumbsup"}, reactions)
+}
+
 func TestGitlabClient_MarkdownPullLink(t *testing.T) {
 	gitlabClientUnderTest = true
 	defer func() { gitlabClientUnderTest = false }()
-	client, err := NewGitlabClient("gitlab.com", "token", nil)
+	client, err := NewGitlabClient("gitlab.com", "token", nil, nil)
 	Ok(t, err)
 	pull := models.PullRequest{Num: 1}
 	s, _ := client.MarkdownPullLink(pull)
@@ -0,0 +1,53 @@
+package vcs
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ProxyConfig is the HTTP proxy that a VCS client's requests go through.
+type ProxyConfig struct {
+	// URL is the proxy's URL, ex. http://proxy.example.com:3128. If empty,
+	// the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are used
+	// like for any other request.
+	URL string
+	// NoProxy is a comma separated list of hosts, domains and CIDRs that
+	// are requested directly, in the same format as NO_PROXY. If empty,
+	// NO_PROXY is used.
+	NoProxy string
+}
+
+// Transport returns a transport that sends requests through the proxy. It
+// returns nil if no proxy URL is configured so that clients keep using their
+// default transport.
+func (p ProxyConfig) Transport() (http.RoundTripper, error) {
+	if p.URL == "" {
+		return nil, nil
+	}
+	proxyURL, err := url.Parse(p.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing proxy URL %q: %w", p.URL, err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("proxy URL %q must start with http://, https:// or socks5://", p.URL)
+	}
+
+	cfg := httpproxy.FromEnvironment()
+	cfg.HTTPProxy = p.URL
+	cfg.HTTPSProxy = p.URL
+	if p.NoProxy != "" {
+		cfg.NoProxy = p.NoProxy
+	}
+	proxyFunc := cfg.ProxyFunc()
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+	return tr, nil
+}
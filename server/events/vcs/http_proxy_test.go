@@ -0,0 +1,154 @@
+package vcs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs/testdata"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestProxyConfig_Transport(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("NO_PROXY", "env.example.com")
+
+	cases := []struct {
+		description string
+		cfg         ProxyConfig
+		reqURL      string
+		expProxy    string
+		expErr      string
+	}{
+		{
+			description: "no proxy configured",
+			cfg:         ProxyConfig{},
+		},
+		{
+			description: "unsupported scheme",
+			cfg:         ProxyConfig{URL: "ftp://proxy.example.com"},
+			expErr:      `proxy URL "ftp://proxy.example.com" must start with http://, https:// or socks5://`,
+		},
+		{
+			description: "proxied",
+			cfg:         ProxyConfig{URL: "http://proxy.example.com:3128"},
+			reqURL:      "https://github.com/api/v3/",
+			expProxy:    "http://proxy.example.com:3128",
+		},
+		{
+			description: "host in no proxy list",
+			cfg:         ProxyConfig{URL: "http://proxy.example.com:3128", NoProxy: "internal.example.com,10.0.0.0/8"},
+			reqURL:      "https://bitbucket.internal.example.com/rest/api/1.0/",
+		},
+		{
+			description: "ip in no proxy list",
+			cfg:         ProxyConfig{URL: "http://proxy.example.com:3128", NoProxy: "internal.example.com,10.0.0.0/8"},
+			reqURL:      "https://10.1.2.3/rest/api/1.0/",
+		},
+		{
+			description: "no proxy list defaults to NO_PROXY",
+			cfg:         ProxyConfig{URL: "http://proxy.example.com:3128"},
+			reqURL:      "https://env.example.com/api/v4/",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			tr, err := c.cfg.Transport()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			if c.reqURL == "" {
+				Assert(t, tr == nil, "expected no transport")
+				return
+			}
+
+			req, err := http.NewRequest("GET", c.reqURL, nil)
+			Ok(t, err)
+			proxyURL, err := tr.(*http.Transport).Proxy(req)
+			Ok(t, err)
+			if c.expProxy == "" {
+				Assert(t, proxyURL == nil, "expected no proxy, got %s", proxyURL)
+				return
+			}
+			Equals(t, c.expProxy, proxyURL.String())
+		})
+	}
+}
+
+// recordingProxy is a proxy that refuses every request and records the hosts
+// that were requested through it.
+type recordingProxy struct {
+	*httptest.Server
+	mu    sync.Mutex
+	hosts []string
+}
+
+func newRecordingProxy(t *testing.T) *recordingProxy {
+	p := &recordingProxy{}
+	p.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		p.hosts = append(p.hosts, r.Host)
+		p.mu.Unlock()
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *recordingProxy) requestedHosts() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.hosts
+}
+
+// Test that each VCS client sends its requests through its own proxy.
+func TestProxyConfig_VCSClients(t *testing.T) {
+	transport := func(t *testing.T, p *recordingProxy) http.RoundTripper {
+		tr, err := ProxyConfig{URL: p.URL}.Transport()
+		Ok(t, err)
+		return tr
+	}
+
+	t.Run("github user credentials", func(t *testing.T) {
+		p := newRecordingProxy(t)
+		creds := &GithubUserCredentials{User: "user", Token: "token", Transport: transport(t, p)}
+		client, err := creds.Client()
+		Ok(t, err)
+		_, err = client.Get("https://github.example.com/api/v3/user")
+		Assert(t, err != nil, "expected the proxy to refuse the request")
+		Equals(t, []string{"github.example.com:443"}, p.requestedHosts())
+	})
+
+	t.Run("github app credentials", func(t *testing.T) {
+		p := newRecordingProxy(t)
+		creds := &GithubAppCredentials{AppID: 1, Key: []byte(testdata.GithubPrivateKey), Hostname: "github.example.com", Transport: transport(t, p)}
+		_, err := creds.GetToken()
+		Assert(t, err != nil, "expected the proxy to refuse the request")
+		Equals(t, []string{"github.example.com:443"}, p.requestedHosts())
+	})
+
+	t.Run("gitlab", func(t *testing.T) {
+		gitlabClientUnderTest = true
+		defer func() { gitlabClientUnderTest = false }()
+		p := newRecordingProxy(t)
+		client, err := NewGitlabClient("gitlab.example.com", "token", transport(t, p), logging.NewNoopLogger(t))
+		Ok(t, err)
+		_, _, err = client.Client.Version.GetVersion()
+		Assert(t, err != nil, "expected the proxy to refuse the request")
+		Equals(t, []string{"gitlab.example.com:443"}, p.requestedHosts())
+	})
+
+	t.Run("azure devops", func(t *testing.T) {
+		p := newRecordingProxy(t)
+		client, err := NewAzureDevopsClient("devops.example.com", "user", "token", transport(t, p))
+		Ok(t, err)
+		_, err = client.GetPullRequest(models.Repo{FullName: "owner/project/repo"}, 1)
+		Assert(t, err != nil, "expected the proxy to refuse the request")
+		Equals(t, []string{"devops.example.com:443"}, p.requestedHosts())
+	})
+}
@@ -271,3 +271,84 @@ func SetGitScopeTags(scope tally.Scope, repoFullName string, pullNum int) tally.
 		"pr_number": strconv.Itoa(pullNum),
 	})
 }
+
+// NewInstrumentedCommentEditor returns a CommentEditor that gathers stats and
+// logs for editor's calls in statsScope.
+func NewInstrumentedCommentEditor(editor CommentEditor, statsScope tally.Scope, logger logging.SimpleLogging) CommentEditor {
+	return &InstrumentedCommentEditor{
+		CommentEditor: editor,
+		StatsScope:    statsScope,
+		Logger:        logger,
+	}
+}
+
+// InstrumentedCommentEditor gathers stats and logs for a CommentEditor, like
+// InstrumentedClient does for a Client.
+type InstrumentedCommentEditor struct {
+	CommentEditor
+	StatsScope tally.Scope
+	Logger     logging.SimpleLogging
+}
+
+func (c *InstrumentedCommentEditor) CreateCommentWithID(repo models.Repo, pullNum int, comment string) (int64, error) {
+	scope := c.StatsScope.SubScope("create_comment_with_id")
+	scope = SetGitScopeTags(scope, repo.FullName, pullNum)
+	logger := c.Logger.WithHistory(fmtLogSrc(repo, pullNum)...)
+
+	executionTime := scope.Timer(metrics.ExecutionTimeMetric).Start()
+	defer executionTime.Stop()
+
+	executionSuccess := scope.Counter(metrics.ExecutionSuccessMetric)
+	executionError := scope.Counter(metrics.ExecutionErrorMetric)
+
+	commentID, err := c.CommentEditor.CreateCommentWithID(repo, pullNum, comment)
+	if err != nil {
+		executionError.Inc(1)
+		logger.Err("Unable to create comment, error: %s", err.Error())
+	} else {
+		executionSuccess.Inc(1)
+	}
+	return commentID, err
+}
+
+func (c *InstrumentedCommentEditor) FindComment(repo models.Repo, pullNum int, marker string) (int64, error) {
+	scope := c.StatsScope.SubScope("find_comment")
+	scope = SetGitScopeTags(scope, repo.FullName, pullNum)
+	logger := c.Logger.WithHistory(fmtLogSrc(repo, pullNum)...)
+
+	executionTime := scope.Timer(metrics.ExecutionTimeMetric).Start()
+	defer executionTime.Stop()
+
+	executionSuccess := scope.Counter(metrics.ExecutionSuccessMetric)
+	executionError := scope.Counter(metrics.ExecutionErrorMetric)
+
+	commentID, err := c.CommentEditor.FindComment(repo, pullNum, marker)
+	if err != nil {
+		executionError.Inc(1)
+		logger.Err("Unable to find comment, error: %s", err.Error())
+	} else {
+		executionSuccess.Inc(1)
+	}
+	return commentID, err
+}
+
+func (c *InstrumentedCommentEditor) EditComment(repo models.Repo, pullNum int, commentID int64, comment string) error {
+	scope := c.StatsScope.SubScope("edit_comment")
+	scope = SetGitScopeTags(scope, repo.FullName, pullNum)
+	logger := c.Logger.WithHistory(fmtLogSrc(repo, pullNum)...)
+
+	executionTime := scope.Timer(metrics.ExecutionTimeMetric).Start()
+	defer executionTime.Stop()
+
+	executionSuccess := scope.Counter(metrics.ExecutionSuccessMetric)
+	executionError := scope.Counter(metrics.ExecutionErrorMetric)
+
+	if err := c.CommentEditor.EditComment(repo, pullNum, commentID, comment); err != nil {
+		executionError.Inc(1)
+		logger.Err("Unable to edit comment %d, error: %s", commentID, err.Error())
+		return err
+	}
+
+	executionSuccess.Inc(1)
+	return nil
+}
@@ -24,6 +24,9 @@ import (
 // config then this client will be used which will error if it's ever called.
 type NotConfiguredVCSClient struct {
 	Host models.VCSHostType
+	// BaseURL is the base URL of the instance of Host that isn't configured,
+	// if a repo's calls were routed to one.
+	BaseURL string
 }
 
 func (a *NotConfiguredVCSClient) GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]string, error) {
@@ -57,6 +60,9 @@ func (a *NotConfiguredVCSClient) MarkdownPullLink(pull models.PullRequest) (stri
 	return "", a.err()
 }
 func (a *NotConfiguredVCSClient) err() error {
+	if a.BaseURL != "" {
+		return fmt.Errorf("atlantis was not configured to support repos from the %s instance at %s", a.Host.String(), a.BaseURL)
+	}
 	return fmt.Errorf("atlantis was not configured to support repos from %s", a.Host.String())
 }
 func (a *NotConfiguredVCSClient) GetTeamNamesForUser(repo models.Repo, user models.User) ([]string, error) {
@@ -23,6 +23,12 @@ type ClientProxy struct {
 	// clients maps from the vcs host type to the client that implements the
 	// api for that host type, ex. github -> github client.
 	clients map[models.VCSHostType]Client
+	// instances maps from the base URL of another instance of a VCS host,
+	// ex. a second GitHub Enterprise, to the client for that instance.
+	instances map[string]Client
+	// instanceBaseURL returns the base URL of the instance that the calls for
+	// repo go to, or "" if they go to the client of its VCS host type.
+	instanceBaseURL func(repo models.Repo) string
 }
 
 func NewClientProxy(githubClient Client, gitlabClient Client, bitbucketCloudClient Client, bitbucketServerClient Client, azuredevopsClient Client) *ClientProxy {
@@ -52,56 +58,77 @@ func NewClientProxy(githubClient Client, gitlabClient Client, bitbucketCloudClie
 	}
 }
 
+// RouteToInstances makes the calls for each repo that instanceBaseURL returns
+// a base URL for go to the client for that base URL in instances. Calls for
+// repos whose base URL has no client fail.
+func (d *ClientProxy) RouteToInstances(instanceBaseURL func(repo models.Repo) string, instances map[string]Client) {
+	d.instanceBaseURL = instanceBaseURL
+	d.instances = instances
+}
+
+// client returns the client that the calls for repo go to.
+func (d *ClientProxy) client(repo models.Repo) Client {
+	if d.instanceBaseURL != nil {
+		if baseURL := d.instanceBaseURL(repo); baseURL != "" {
+			if client, ok := d.instances[baseURL]; ok {
+				return client
+			}
+			return &NotConfiguredVCSClient{Host: repo.VCSHost.Type, BaseURL: baseURL}
+		}
+	}
+	return d.clients[repo.VCSHost.Type]
+}
+
 func (d *ClientProxy) GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]string, error) {
-	return d.clients[repo.VCSHost.Type].GetModifiedFiles(repo, pull)
+	return d.client(repo).GetModifiedFiles(repo, pull)
 }
 
 func (d *ClientProxy) CreateComment(repo models.Repo, pullNum int, comment string, command string) error {
-	return d.clients[repo.VCSHost.Type].CreateComment(repo, pullNum, comment, command)
+	return d.client(repo).CreateComment(repo, pullNum, comment, command)
 }
 
 func (d *ClientProxy) HidePrevCommandComments(repo models.Repo, pullNum int, command string) error {
-	return d.clients[repo.VCSHost.Type].HidePrevCommandComments(repo, pullNum, command)
+	return d.client(repo).HidePrevCommandComments(repo, pullNum, command)
 }
 
 func (d *ClientProxy) ReactToComment(repo models.Repo, pullNum int, commentID int64, reaction string) error {
-	return d.clients[repo.VCSHost.Type].ReactToComment(repo, pullNum, commentID, reaction)
+	return d.client(repo).ReactToComment(repo, pullNum, commentID, reaction)
 }
 
 func (d *ClientProxy) PullIsApproved(repo models.Repo, pull models.PullRequest) (models.ApprovalStatus, error) {
-	return d.clients[repo.VCSHost.Type].PullIsApproved(repo, pull)
+	return d.client(repo).PullIsApproved(repo, pull)
 }
 
 func (d *ClientProxy) DiscardReviews(repo models.Repo, pull models.PullRequest) error {
-	return d.clients[repo.VCSHost.Type].DiscardReviews(repo, pull)
+	return d.client(repo).DiscardReviews(repo, pull)
 }
 
 func (d *ClientProxy) PullIsMergeable(repo models.Repo, pull models.PullRequest, vcsstatusname string) (bool, error) {
-	return d.clients[repo.VCSHost.Type].PullIsMergeable(repo, pull, vcsstatusname)
+	return d.client(repo).PullIsMergeable(repo, pull, vcsstatusname)
 }
 
 func (d *ClientProxy) UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, src string, description string, url string) error {
-	return d.clients[repo.VCSHost.Type].UpdateStatus(repo, pull, state, src, description, url)
+	return d.client(repo).UpdateStatus(repo, pull, state, src, description, url)
 }
 
 func (d *ClientProxy) MergePull(pull models.PullRequest, pullOptions models.PullRequestOptions) error {
-	return d.clients[pull.BaseRepo.VCSHost.Type].MergePull(pull, pullOptions)
+	return d.client(pull.BaseRepo).MergePull(pull, pullOptions)
 }
 
 func (d *ClientProxy) MarkdownPullLink(pull models.PullRequest) (string, error) {
-	return d.clients[pull.BaseRepo.VCSHost.Type].MarkdownPullLink(pull)
+	return d.client(pull.BaseRepo).MarkdownPullLink(pull)
 }
 
 func (d *ClientProxy) GetTeamNamesForUser(repo models.Repo, user models.User) ([]string, error) {
-	return d.clients[repo.VCSHost.Type].GetTeamNamesForUser(repo, user)
+	return d.client(repo).GetTeamNamesForUser(repo, user)
 }
 
 func (d *ClientProxy) GetFileContent(pull models.PullRequest, fileName string) (bool, []byte, error) {
-	return d.clients[pull.BaseRepo.VCSHost.Type].GetFileContent(pull, fileName)
+	return d.client(pull.BaseRepo).GetFileContent(pull, fileName)
 }
 
 func (d *ClientProxy) SupportsSingleFileDownload(repo models.Repo) bool {
-	return d.clients[repo.VCSHost.Type].SupportsSingleFileDownload(repo)
+	return d.client(repo).SupportsSingleFileDownload(repo)
 }
 
 func (d *ClientProxy) GetCloneURL(VCSHostType models.VCSHostType, repo string) (string, error) {
@@ -109,5 +136,5 @@ func (d *ClientProxy) GetCloneURL(VCSHostType models.VCSHostType, repo string) (
 }
 
 func (d *ClientProxy) GetPullLabels(repo models.Repo, pull models.PullRequest) ([]string, error) {
-	return d.clients[repo.VCSHost.Type].GetPullLabels(repo, pull)
+	return d.client(repo).GetPullLabels(repo, pull)
 }
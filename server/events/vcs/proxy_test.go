@@ -0,0 +1,46 @@
+package vcs_test
+
+import (
+	"testing"
+
+	. "github.com/petergtz/pegomock/v4"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestClientProxy_RouteToInstances(t *testing.T) {
+	RegisterMockTestingT(t)
+	defaultClient := mocks.NewMockClient()
+	alternateClient := mocks.NewMockClient()
+	proxy := vcs.NewClientProxy(defaultClient, nil, nil, nil, nil)
+	proxy.RouteToInstances(func(repo models.Repo) string {
+		switch repo.FullName {
+		case "owner/mirrored":
+			return "https://ghe2.example.com"
+		case "owner/unknown":
+			return "https://ghe3.example.com"
+		}
+		return ""
+	}, map[string]vcs.Client{"https://ghe2.example.com": alternateClient})
+
+	github := models.VCSHost{Type: models.Github, Hostname: "github.com"}
+	repo := models.Repo{FullName: "owner/repo", VCSHost: github}
+	mirrored := models.Repo{FullName: "owner/mirrored", VCSHost: github}
+	Ok(t, proxy.CreateComment(repo, 1, "comment", "plan"))
+	Ok(t, proxy.CreateComment(mirrored, 2, "comment", "plan"))
+	defaultClient.VerifyWasCalledOnce().CreateComment(repo, 1, "comment", "plan")
+	alternateClient.VerifyWasCalledOnce().CreateComment(mirrored, 2, "comment", "plan")
+
+	// Calls for a pull request go to its base repo's instance.
+	pull := models.PullRequest{Num: 2, BaseRepo: mirrored}
+	_, err := proxy.MarkdownPullLink(pull)
+	Ok(t, err)
+	alternateClient.VerifyWasCalledOnce().MarkdownPullLink(pull)
+	defaultClient.VerifyWasCalled(Never()).MarkdownPullLink(Any[models.PullRequest]())
+
+	unknown := models.Repo{FullName: "owner/unknown", VCSHost: github}
+	ErrEquals(t, "atlantis was not configured to support repos from the Github instance at https://ghe3.example.com",
+		proxy.CreateComment(unknown, 3, "comment", "plan"))
+}
@@ -14,15 +14,23 @@
 package events
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/config/valid"
 	"github.com/runatlantis/atlantis/server/core/runtime"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/logging"
@@ -30,6 +38,14 @@ import (
 
 const workingDirPrefix = "repos"
 
+// historicalWorkingDirPrefix is where historical pulls are cloned. It's kept
+// apart from workingDirPrefix so their plans are never found when applying.
+const historicalWorkingDirPrefix = "historical-repos"
+
+// planSnapshotsDirPrefix is where the working dirs of planned projects are
+// copied to so they can be applied from the same files they were planned with.
+const planSnapshotsDirPrefix = "plan-snapshots"
+
 var cloneLocks sync.Map
 
 //go:generate pegomock generate github.com/runatlantis/atlantis/server/events --package mocks -o mocks/mock_working_dir.go WorkingDir
@@ -58,6 +74,20 @@ type WorkingDir interface {
 	DeletePlan(r models.Repo, p models.PullRequest, workspace string, path string, projectName string) error
 	// GetGitUntrackedFiles returns a list of Git untracked files in the working dir.
 	GetGitUntrackedFiles(r models.Repo, p models.PullRequest, workspace string) ([]string, error)
+	// UncommittedFiles returns the files under path in cloneDir that differ
+	// from the checked out commit, including untracked and ignored files, by
+	// their path relative to cloneDir. Each file maps to the SHA-256 of its
+	// contents, or "" if it was deleted. Files in .terraform dirs are skipped.
+	UncommittedFiles(cloneDir string, path string) (map[string]string, error)
+	// SavePlanSnapshot copies the project at path, and the local modules it
+	// calls, from the working dir for this workspace to a snapshot for the
+	// project with projectName, replacing any previous snapshot of it, and
+	// returns the path to the snapshot.
+	SavePlanSnapshot(r models.Repo, p models.PullRequest, workspace string, path string, projectName string) (string, error)
+	// GetPlanSnapshot returns the path to the snapshot saved for the project
+	// at path with projectName. If there's no snapshot, error wraps
+	// os.ErrNotExist.
+	GetPlanSnapshot(r models.Repo, p models.PullRequest, workspace string, path string, projectName string) (string, error)
 }
 
 // FileWorkspace implements WorkingDir with the file system.
@@ -70,7 +100,9 @@ type FileWorkspace struct {
 	CheckoutMerge bool
 	// CheckoutDepth is how many commits of feature branch and main branch we'll
 	// retrieve by default. If their merge base is not retrieved with this depth,
-	// full fetch will be performed. Only matters if CheckoutMerge=true.
+	// full fetch will be performed. Only matters if CheckoutMerge=true or when
+	// cloning a historical pull, in which case the full history is fetched if
+	// the commit is further behind the head of the branch.
 	CheckoutDepth int
 	// TestingOverrideHeadCloneURL can be used during testing to override the
 	// URL of the head repo to be cloned. If it's empty then we clone normally.
@@ -87,6 +119,16 @@ type FileWorkspace struct {
 	// flag indicating if we have to merge with potential new changes upstream (directly after grabbing project lock)
 	CheckForUpstreamChanges bool
 	Logger                  logging.SimpleLogging
+	// GlobalCfg is the server-side repo config. Clones are set to the
+	// working_dir_permissions of their repo before they're used.
+	GlobalCfg valid.GlobalCfg
+	// CloneRetries is how many times a clone that failed with a transient
+	// git error, ex. the git server timing out, is retried. Authentication
+	// errors aren't retried.
+	CloneRetries int
+	// CloneRetryDelay is how long to wait before the first clone retry. The
+	// delay doubles after each retry.
+	CloneRetryDelay time.Duration
 }
 
 // Clone git clones headRepo, checks out the branch and then returns the absolute
@@ -103,6 +145,9 @@ func (w *FileWorkspace) Clone(
 	defer func() { w.CheckForUpstreamChanges = false }()
 
 	c := wrappedGitContext{cloneDir, headRepo, p}
+	if p.Historical {
+		return cloneDir, false, w.cloneAtCommit(c)
+	}
 	// If the directory already exists, check if it's at the right commit.
 	// If so, then we do nothing.
 	if _, err := os.Stat(cloneDir); err == nil {
@@ -221,7 +266,12 @@ func (w *FileWorkspace) forceClone(c wrappedGitContext) error {
 		mutex.Lock()
 		return nil
 	}
+	return w.retryTransientGitErrors(c, func() error { return w.cloneAndMerge(c) })
+}
 
+// cloneAndMerge clones the repo into a new c.dir and, if using the merge
+// strategy, merges the head branch into the base branch.
+func (w *FileWorkspace) cloneAndMerge(c wrappedGitContext) error {
 	err := os.RemoveAll(c.dir)
 	if err != nil {
 		return errors.Wrapf(err, "deleting dir %q before cloning", c.dir)
@@ -245,7 +295,10 @@ func (w *FileWorkspace) forceClone(c wrappedGitContext) error {
 
 	// if branch strategy, use depth=1
 	if !w.CheckoutMerge {
-		return w.wrappedGit(c, "clone", "--depth=1", "--branch", c.pr.HeadBranch, "--single-branch", headCloneURL, c.dir)
+		if err := w.wrappedGit(c, "clone", "--depth=1", "--branch", c.pr.HeadBranch, "--single-branch", headCloneURL, c.dir); err != nil {
+			return err
+		}
+		return w.setPermissions(c)
 	}
 
 	// if merge strategy...
@@ -270,7 +323,82 @@ func (w *FileWorkspace) forceClone(c wrappedGitContext) error {
 		}
 	}
 
-	return w.mergeToBaseBranch(c)
+	if err := w.mergeToBaseBranch(c); err != nil {
+		return err
+	}
+	return w.setPermissions(c)
+}
+
+// cloneAtCommit clones the head branch and checks out the pull request's
+// HeadCommit. It's used for historical pulls so the merge strategy doesn't
+// apply. If the repo is already checked out at the commit it does nothing.
+func (w *FileWorkspace) cloneAtCommit(c wrappedGitContext) error {
+	if _, err := os.Stat(c.dir); err == nil {
+		revParseCmd := exec.Command("git", "rev-parse", "HEAD") // #nosec
+		revParseCmd.Dir = c.dir
+		output, err := revParseCmd.CombinedOutput()
+		if err == nil && strings.HasPrefix(strings.TrimSpace(string(output)), c.pr.HeadCommit) {
+			w.Logger.Debug("repo is at historical commit %q so will not re-clone", c.pr.HeadCommit)
+			return nil
+		}
+	}
+
+	value, _ := cloneLocks.LoadOrStore(c.dir, new(sync.Mutex))
+	mutex := value.(*sync.Mutex)
+
+	defer mutex.Unlock()
+	if locked := mutex.TryLock(); !locked {
+		mutex.Lock()
+		return nil
+	}
+	return w.retryTransientGitErrors(c, func() error { return w.cloneHistorical(c) })
+}
+
+// cloneHistorical clones the head branch into a new c.dir and checks out the
+// pull request's HeadCommit.
+func (w *FileWorkspace) cloneHistorical(c wrappedGitContext) error {
+	if err := os.RemoveAll(c.dir); err != nil {
+		return errors.Wrapf(err, "deleting dir %q before cloning", c.dir)
+	}
+	w.Logger.Info("creating dir %q", c.dir)
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return errors.Wrap(err, "creating new workspace")
+	}
+
+	headCloneURL := c.head.CloneURL
+	if w.TestingOverrideHeadCloneURL != "" {
+		headCloneURL = w.TestingOverrideHeadCloneURL
+	}
+
+	// The commit can be anywhere on the branch so unless we're limited to
+	// CheckoutDepth commits, we need the branch's history.
+	cloneArgs := []string{"clone", "--branch", c.pr.HeadBranch, "--single-branch"}
+	if w.CheckoutDepth > 0 {
+		cloneArgs = append(cloneArgs, "--depth", fmt.Sprint(w.CheckoutDepth))
+	}
+	if err := w.wrappedGit(c, append(cloneArgs, headCloneURL, c.dir)...); err != nil {
+		return err
+	}
+	if err := w.wrappedGit(c, "checkout", "-q", "--detach", c.pr.HeadCommit); err != nil {
+		shallow, shallowErr := isShallowRepo(c.dir)
+		if shallowErr != nil {
+			return shallowErr
+		}
+		if !shallow {
+			return errors.Wrapf(err, "commit %q was not found on branch %q", c.pr.HeadCommit, c.pr.HeadBranch)
+		}
+
+		// The commit is further behind than CheckoutDepth commits so fall
+		// back to retrieving the branch's full history.
+		w.Logger.Info("commit %q is not in the last %d commits of branch %q, fetching full history", c.pr.HeadCommit, w.CheckoutDepth, c.pr.HeadBranch)
+		if err := w.wrappedGit(c, "fetch", "--unshallow", "origin"); err != nil {
+			return err
+		}
+		if err := w.wrappedGit(c, "checkout", "-q", "--detach", c.pr.HeadCommit); err != nil {
+			return errors.Wrapf(err, "commit %q was not found on branch %q", c.pr.HeadCommit, c.pr.HeadBranch)
+		}
+	}
+	return w.setPermissions(c)
 }
 
 // There is a new upstream update that we need, and we want to update to it
@@ -290,7 +418,76 @@ func (w *FileWorkspace) mergeAgain(c wrappedGitContext) error {
 		return err
 	}
 
-	return w.mergeToBaseBranch(c)
+	if err := w.mergeToBaseBranch(c); err != nil {
+		return err
+	}
+	return w.setPermissions(c)
+}
+
+// retryTransientGitErrors runs clone, retrying it up to CloneRetries times
+// with exponential backoff while it fails with a transient git error.
+func (w *FileWorkspace) retryTransientGitErrors(c wrappedGitContext, clone func() error) error {
+	delay := w.CloneRetryDelay
+	err := clone()
+	for attempt := 1; attempt <= w.CloneRetries && isTransientGitError(err); attempt++ {
+		w.Logger.Warn("retrying clone of %q in %s (attempt %d of %d) after transient error: %s", c.pr.BaseRepo.FullName, delay, attempt, w.CloneRetries, err)
+		time.Sleep(delay)
+		delay *= 2
+		err = clone()
+	}
+	return err
+}
+
+// gitAuthErrors are substrings of git's output when it fails to authenticate.
+// Retrying won't help so these errors are never treated as transient.
+var gitAuthErrors = []string{
+	"authentication failed",
+	"could not read username",
+	"could not read password",
+	"invalid username or password",
+	"permission denied",
+	"repository not found",
+	"the requested url returned error: 401",
+	"the requested url returned error: 403",
+}
+
+// gitTransientErrors are substrings of git's output when it fails because of
+// the network or the git server, which may succeed if retried.
+var gitTransientErrors = []string{
+	"could not resolve host",
+	"connection refused",
+	"connection reset",
+	"connection timed out",
+	"operation timed out",
+	"early eof",
+	"the remote end hung up unexpectedly",
+	"rpc failed",
+	"gnutls_handshake() failed",
+	"the requested url returned error: 429",
+	"the requested url returned error: 500",
+	"the requested url returned error: 502",
+	"the requested url returned error: 503",
+	"the requested url returned error: 504",
+}
+
+// isTransientGitError returns true if err is from a git command that failed
+// for a reason that may go away if it's retried.
+func isTransientGitError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range gitAuthErrors {
+		if strings.Contains(msg, s) {
+			return false
+		}
+	}
+	for _, s := range gitTransientErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
 }
 
 // wrappedGitContext is the configuration for wrappedGit that is typically unchanged
@@ -346,7 +543,13 @@ func (w *FileWorkspace) mergeToBaseBranch(c wrappedGitContext) error {
 	if err := w.wrappedGit(c, "merge-base", c.pr.BaseBranch, "FETCH_HEAD"); err != nil {
 		// git merge-base returning error means that we did not receive enough commits in shallow clone.
 		// Fall back to retrieving full repo history.
-		if err := w.wrappedGit(c, "fetch", "--unshallow"); err != nil {
+		w.Logger.Info("merge base of branches %q and %q is not in the last %d commits, fetching full history", c.pr.BaseBranch, c.pr.HeadBranch, w.CheckoutDepth)
+		if err := w.fetchFullHistory(c, "origin"); err != nil {
+			return err
+		}
+		// The head must be fetched again even if the repo isn't shallow
+		// anymore since fetching the base branch overwrote FETCH_HEAD.
+		if err := w.fetchFullHistory(c, fetchRemote, fetchRef); err != nil {
 			return err
 		}
 	}
@@ -360,6 +563,34 @@ func (w *FileWorkspace) mergeToBaseBranch(c wrappedGitContext) error {
 	return w.wrappedGit(c, "merge", "-q", "--no-ff", "-m", "atlantis-merge", "FETCH_HEAD")
 }
 
+// fetchFullHistory fetches refs from remote along with their full history,
+// converting the repo to a complete one if it's shallow.
+func (w *FileWorkspace) fetchFullHistory(c wrappedGitContext, remote string, refs ...string) error {
+	shallow, err := isShallowRepo(c.dir)
+	if err != nil {
+		return err
+	}
+	args := []string{"fetch"}
+	// git refuses to --unshallow a complete repo.
+	if shallow {
+		args = append(args, "--unshallow")
+	}
+	args = append(append(args, remote), refs...)
+	return w.wrappedGit(c, args...)
+}
+
+// isShallowRepo returns true if the repo in dir is a shallow clone, i.e. it
+// doesn't have the full history of its branches.
+func isShallowRepo(dir string) (bool, error) {
+	cmd := exec.Command("git", "rev-parse", "--is-shallow-repository") // #nosec
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return false, errors.Wrapf(err, "checking if %q is a shallow clone: %s", dir, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)) == "true", nil
+}
+
 // GetWorkingDir returns the path to the workspace for this repo and pull.
 func (w *FileWorkspace) GetWorkingDir(r models.Repo, p models.PullRequest, workspace string) (string, error) {
 	repoDir := w.cloneDir(r, p, workspace)
@@ -379,8 +610,18 @@ func (w *FileWorkspace) GetPullDir(r models.Repo, p models.PullRequest) (string,
 	return dir, nil
 }
 
-// Delete deletes the workspace for this repo and pull.
+// Delete deletes the workspace for this repo and pull, including the working
+// dirs of any historical commits of the pull.
 func (w *FileWorkspace) Delete(r models.Repo, p models.PullRequest) error {
+	if !p.Historical {
+		historicalPullDir := filepath.Join(w.DataDir, historicalWorkingDirPrefix, r.FullName, strconv.Itoa(p.Num))
+		if err := os.RemoveAll(historicalPullDir); err != nil {
+			return err
+		}
+	}
+	if err := os.RemoveAll(w.planSnapshotsPullDir(r, p)); err != nil {
+		return err
+	}
 	repoPullDir := w.repoPullDir(r, p)
 	w.Logger.Info("Deleting repo pull directory: " + repoPullDir)
 	return os.RemoveAll(repoPullDir)
@@ -388,12 +629,18 @@ func (w *FileWorkspace) Delete(r models.Repo, p models.PullRequest) error {
 
 // DeleteForWorkspace deletes the working dir for this workspace.
 func (w *FileWorkspace) DeleteForWorkspace(r models.Repo, p models.PullRequest, workspace string) error {
+	if err := os.RemoveAll(filepath.Join(w.planSnapshotsPullDir(r, p), workspace)); err != nil {
+		return err
+	}
 	workspaceDir := w.cloneDir(r, p, workspace)
 	w.Logger.Info("Deleting workspace directory: " + workspaceDir)
 	return os.RemoveAll(workspaceDir)
 }
 
 func (w *FileWorkspace) repoPullDir(r models.Repo, p models.PullRequest) string {
+	if p.Historical {
+		return filepath.Join(w.DataDir, historicalWorkingDirPrefix, r.FullName, strconv.Itoa(p.Num), p.HeadCommit)
+	}
 	return filepath.Join(w.DataDir, workingDirPrefix, r.FullName, strconv.Itoa(p.Num))
 }
 
@@ -401,6 +648,14 @@ func (w *FileWorkspace) cloneDir(r models.Repo, p models.PullRequest, workspace
 	return filepath.Join(w.repoPullDir(r, p), workspace)
 }
 
+func (w *FileWorkspace) planSnapshotsPullDir(r models.Repo, p models.PullRequest) string {
+	return filepath.Join(w.DataDir, planSnapshotsDirPrefix, r.FullName, strconv.Itoa(p.Num))
+}
+
+func (w *FileWorkspace) planSnapshotDir(r models.Repo, p models.PullRequest, workspace string, projectPath string, projectName string) string {
+	return filepath.Join(w.planSnapshotsPullDir(r, p), workspace, projectPath, runtime.GetPlanFilename(workspace, projectName))
+}
+
 // sanitizeGitCredentials replaces any git clone urls that contain credentials
 // in s with the sanitized versions.
 func (w *FileWorkspace) sanitizeGitCredentials(s string, base models.Repo, head models.Repo) string {
@@ -439,3 +694,265 @@ func (w *FileWorkspace) GetGitUntrackedFiles(r models.Repo, p models.PullRequest
 	w.Logger.Debug("Untracked files: '%s'", strings.Join(untrackedFiles, ","))
 	return untrackedFiles, nil
 }
+
+func (w *FileWorkspace) UncommittedFiles(cloneDir string, path string) (map[string]string, error) {
+	cmd := exec.Command("git", "status", "--porcelain", "-z", "--no-renames", "--untracked-files=all", "--ignored", "--", path)
+	cmd.Dir = cloneDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "running git status in %q", cloneDir)
+	}
+	files := make(map[string]string)
+	// Each entry is the two letter status of a file, a space and its path.
+	for _, entry := range strings.Split(string(output), "\x00") {
+		if len(entry) < 4 {
+			continue
+		}
+		file := entry[3:]
+		if slices.Contains(strings.Split(file, "/"), ".terraform") {
+			continue
+		}
+		contents, err := os.ReadFile(filepath.Join(cloneDir, file))
+		switch {
+		case os.IsNotExist(err):
+			files[file] = ""
+		case err != nil:
+			return nil, err
+		default:
+			files[file] = fmt.Sprintf("%x", sha256.Sum256(contents))
+		}
+	}
+	return files, nil
+}
+
+// SavePlanSnapshot copies the project at projectPath, and the local modules it
+// calls, from the working dir for this workspace to a snapshot for the project
+// with projectName and returns its path. The project's .terraform dir is
+// copied too so the snapshot is self-contained: the apply uses the backend,
+// providers and modules the plan was made with even if the working dir is
+// initialized again before it.
+func (w *FileWorkspace) SavePlanSnapshot(r models.Repo, p models.PullRequest, workspace string, projectPath string, projectName string) (string, error) {
+	cloneDir, err := w.GetWorkingDir(r, p, workspace)
+	if err != nil {
+		return "", err
+	}
+	snapshotDir := w.planSnapshotDir(r, p, workspace, projectPath, projectName)
+	if err := os.RemoveAll(snapshotDir); err != nil {
+		return "", errors.Wrap(err, "deleting previous snapshot")
+	}
+	dirs := snapshotDirs(cloneDir, projectPath)
+	for _, dir := range dirs {
+		w.Logger.Debug("Copying %q to plan snapshot %q", filepath.Join(cloneDir, dir), snapshotDir)
+		if err := copyDir(cloneDir, snapshotDir, dir, dirs); err != nil {
+			return "", errors.Wrapf(err, "copying %q to %q", filepath.Join(cloneDir, dir), snapshotDir)
+		}
+	}
+	dotTerraform := filepath.Join(cloneDir, projectPath, ".terraform")
+	if _, err := os.Stat(dotTerraform); err == nil {
+		if err := copyDotTerraform(dotTerraform, filepath.Join(snapshotDir, projectPath, ".terraform")); err != nil {
+			return "", errors.Wrap(err, "copying .terraform dir")
+		}
+	}
+	return snapshotDir, nil
+}
+
+// copyDotTerraform copies the .terraform dir src to dst. Unlike copyDir, its
+// symlinks are kept as they are since Terraform creates them, ex. to providers
+// in the plugin cache.
+func copyDotTerraform(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return nil
+		}
+	})
+}
+
+// snapshotDirs returns the dirs, relative to cloneDir, to copy to the snapshot
+// of the project at projectPath: the project itself and the local modules it
+// calls, directly or not. Dirs inside other dirs in the list are left out.
+func snapshotDirs(cloneDir string, projectPath string) []string {
+	projectPath = path.Clean(filepath.ToSlash(projectPath))
+	modules := make(moduleInfo)
+	// Modules that can't be parsed are skipped, planning would have failed
+	// if they were needed.
+	modules.load(os.DirFS(cloneDir), projectPath) // nolint: errcheck
+	var all []string
+	for dir := range modules {
+		all = append(all, filepath.FromSlash(dir))
+	}
+	// Sorting puts dirs before the dirs inside them.
+	sort.Strings(all)
+	var dirs []string
+	for _, dir := range all {
+		if !inDirs(dir, dirs) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// inDirs returns true if rel, a path relative to the clone, is one of dirs or
+// inside one of them.
+func inDirs(rel string, dirs []string) bool {
+	for _, dir := range dirs {
+		if dir == "." || rel == dir || strings.HasPrefix(rel, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPlanSnapshot returns the path to the snapshot saved for the project at
+// projectPath with projectName.
+func (w *FileWorkspace) GetPlanSnapshot(r models.Repo, p models.PullRequest, workspace string, projectPath string, projectName string) (string, error) {
+	snapshotDir := w.planSnapshotDir(r, p, workspace, projectPath, projectName)
+	if _, err := os.Stat(snapshotDir); err != nil {
+		return "", errors.Wrap(err, "checking if plan snapshot exists")
+	}
+	return snapshotDir, nil
+}
+
+// copyDir copies the files, dirs and symlinks in dir, relative to srcRoot, to
+// the same path in dstRoot, keeping their permissions. .terraform dirs are
+// skipped. Symlinks to files in copied, the dirs that are copied to dstRoot,
+// are kept. Symlinks to other files in srcRoot are replaced by a copy of the
+// file. Absolute symlinks and symlinks to other dirs or out of srcRoot aren't
+// supported since the snapshot couldn't be used on its own.
+func copyDir(srcRoot string, dstRoot string, dir string, copied []string) error {
+	return filepath.WalkDir(filepath.Join(srcRoot, dir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcRoot, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dstRoot, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir() && d.Name() == ".terraform":
+			return filepath.SkipDir
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			return copySymlink(srcRoot, rel, target, copied)
+		case d.Type().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return nil
+		}
+	})
+}
+
+// copySymlink copies the symlink at rel, relative to srcRoot, to target. See
+// copyDir for the symlinks that are supported.
+func copySymlink(srcRoot string, rel string, target string, copied []string) error {
+	link, err := os.Readlink(filepath.Join(srcRoot, rel))
+	if err != nil {
+		return err
+	}
+	if filepath.IsAbs(link) {
+		return fmt.Errorf("%q is a symlink to the absolute path %q", rel, link)
+	}
+	linkRel := filepath.Join(filepath.Dir(rel), link)
+	if linkRel == ".." || strings.HasPrefix(linkRel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%q is a symlink to %q, which is outside of the repo", rel, link)
+	}
+	if inDirs(linkRel, copied) {
+		return os.Symlink(link, target)
+	}
+	info, err := os.Stat(filepath.Join(srcRoot, linkRel))
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%q is a symlink to %q, which isn't a file in the project or its modules", rel, link)
+	}
+	return copyFile(filepath.Join(srcRoot, linkRel), target, info.Mode().Perm())
+}
+
+func copyFile(src string, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close() // nolint: errcheck
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close() // nolint: errcheck
+		return err
+	}
+	return out.Close()
+}
+
+// setPermissions sets the dirs and files of the clone in c to the
+// working_dir_permissions of its repo. The .git dir is left as git created it.
+func (w *FileWorkspace) setPermissions(c wrappedGitContext) error {
+	perms := w.GlobalCfg.WorkingDirPermissions(c.pr.BaseRepo.ID())
+	if perms == nil {
+		return nil
+	}
+	w.Logger.Debug("setting permissions of %q to dir mode %#o and file mode %#o", c.dir, perms.DirMode, perms.FileMode)
+	return errors.Wrap(setWorkingDirPermissions(c.dir, perms), "setting working dir permissions")
+}
+
+// setWorkingDirPermissions sets the modes of the dirs and files in dir to
+// perms. Files that are executable by their owner stay executable wherever
+// perms.FileMode grants read permissions. Symlinks aren't followed.
+func setWorkingDirPermissions(dir string, perms *valid.WorkingDirPermissions) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" && path != dir {
+				return filepath.SkipDir
+			}
+			if perms.DirMode == 0 {
+				return nil
+			}
+			return os.Chmod(path, perms.DirMode)
+		}
+		if !entry.Type().IsRegular() || perms.FileMode == 0 {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		mode := perms.FileMode
+		if info.Mode()&0100 != 0 {
+			mode |= (mode & 0444) >> 2
+		}
+		return os.Chmod(path, mode)
+	})
+}
@@ -0,0 +1,212 @@
+package events
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/core/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// WorkingDirCleaner deletes the working dirs of pull requests that its
+// retention policy doesn't keep. It's run periodically so the working dirs of
+// pull requests that aren't closed through Atlantis, ex. because the webhook
+// was missed, don't fill up the data dir. The working dirs of pull requests
+// that hold locks or are running a command are never deleted.
+type WorkingDirCleaner struct {
+	DataDir          string
+	WorkingDir       WorkingDir
+	WorkingDirLocker WorkingDirLocker
+	Locker           locking.Locker
+	Logger           logging.SimpleLogging
+	// KeepCount is how many of the most recently used working dirs are kept.
+	// If 0, working dirs aren't deleted because of how many there are.
+	KeepCount int
+	// KeepFor is how long working dirs are kept after they were last used.
+	// If 0, working dirs aren't deleted because of their age.
+	KeepFor time.Duration
+	// KeepOnFailure keeps the working dirs of pull requests with a failed
+	// project so the failure can be looked into. Failures are only known
+	// from when Atlantis started.
+	KeepOnFailure bool
+
+	mutex sync.Mutex
+	// failedPulls are the keys of the pull requests with a failed project.
+	failedPulls map[string]bool
+}
+
+// pullWorkingDir is the working dir of a pull request.
+type pullWorkingDir struct {
+	RepoFullName string
+	PullNum      int
+	LastUsed     time.Time
+}
+
+// Run deletes the working dirs that aren't kept. It's run by the scheduled
+// executor service.
+func (c *WorkingDirCleaner) Run() {
+	if err := c.Clean(); err != nil {
+		c.Logger.Err("cleaning up working dirs: %s", err)
+	}
+}
+
+// RecordPullStatus records whether the pull request of status has a failed
+// project, for KeepOnFailure. It's safe to call on a nil *WorkingDirCleaner.
+func (c *WorkingDirCleaner) RecordPullStatus(status models.PullStatus) {
+	if c == nil {
+		return
+	}
+	failed := status.StatusCount(models.ErroredPlanStatus) > 0 ||
+		status.StatusCount(models.ErroredApplyStatus) > 0 ||
+		status.StatusCount(models.ErroredPolicyCheckStatus) > 0
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.failedPulls == nil {
+		c.failedPulls = make(map[string]bool)
+	}
+	key := c.pullKey(status.Pull.BaseRepo.FullName, status.Pull.Num)
+	if failed {
+		c.failedPulls[key] = true
+	} else {
+		delete(c.failedPulls, key)
+	}
+}
+
+// Clean deletes the working dirs that aren't kept.
+func (c *WorkingDirCleaner) Clean() error {
+	dirs, err := c.pullWorkingDirs()
+	if err != nil {
+		return errors.Wrap(err, "listing working dirs")
+	}
+	locks, err := c.Locker.List()
+	if err != nil {
+		return errors.Wrap(err, "listing locks")
+	}
+	lockedPulls := make(map[string]bool)
+	for _, lock := range locks {
+		lockedPulls[c.pullKey(lock.Pull.BaseRepo.FullName, lock.Pull.Num)] = true
+	}
+
+	// The most recently used working dirs come first so they're kept by
+	// KeepCount.
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return dirs[i].LastUsed.After(dirs[j].LastUsed)
+	})
+	for i, dir := range dirs {
+		tooMany := c.KeepCount > 0 && i >= c.KeepCount
+		tooOld := c.KeepFor > 0 && time.Since(dir.LastUsed) > c.KeepFor
+		if !tooMany && !tooOld {
+			continue
+		}
+		key := c.pullKey(dir.RepoFullName, dir.PullNum)
+		if lockedPulls[key] {
+			c.Logger.Debug("keeping working dir of %s because the pull request holds locks", key)
+			continue
+		}
+		if c.KeepOnFailure && c.hasFailed(key) {
+			c.Logger.Debug("keeping working dir of %s because the pull request has a failed project", key)
+			continue
+		}
+		if err := c.delete(dir); err != nil {
+			c.Logger.Warn("unable to delete working dir of %s: %s", key, err)
+		}
+	}
+	return nil
+}
+
+// delete deletes dir unless a command is running in it.
+func (c *WorkingDirCleaner) delete(dir pullWorkingDir) error {
+	unlockFn, err := c.WorkingDirLocker.TryLockPull(dir.RepoFullName, dir.PullNum)
+	if err != nil {
+		return err
+	}
+	defer unlockFn()
+	c.Logger.Info("deleting working dir of %s last used at %s", c.pullKey(dir.RepoFullName, dir.PullNum), dir.LastUsed.Format(time.RFC3339))
+	return c.WorkingDir.Delete(models.Repo{FullName: dir.RepoFullName}, models.PullRequest{Num: dir.PullNum})
+}
+
+func (c *WorkingDirCleaner) hasFailed(key string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.failedPulls[key]
+}
+
+func (c *WorkingDirCleaner) pullKey(repoFullName string, pullNum int) string {
+	return fmt.Sprintf("%s#%d", repoFullName, pullNum)
+}
+
+// pullWorkingDirs returns the working dirs of the pull requests in DataDir. A
+// pull request's working dir is named after its number and contains a clone
+// for each workspace. Repo full names can have any number of parts, ex. with
+// GitLab subgroups, so they're found by walking the dirs.
+func (c *WorkingDirCleaner) pullWorkingDirs() ([]pullWorkingDir, error) {
+	root := filepath.Join(c.DataDir, workingDirPrefix)
+	var dirs []pullWorkingDir
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() || path == root {
+			return nil
+		}
+		pullNum, convErr := strconv.Atoi(d.Name())
+		if convErr != nil {
+			return nil
+		}
+		lastUsed, ok := c.cloneLastUsed(path)
+		if !ok {
+			return nil
+		}
+		repoFullName, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		dirs = append(dirs, pullWorkingDir{
+			RepoFullName: filepath.ToSlash(repoFullName),
+			PullNum:      pullNum,
+			LastUsed:     lastUsed,
+		})
+		return filepath.SkipDir
+	})
+	return dirs, err
+}
+
+// cloneLastUsed returns when the workspace clones in pullDir were last
+// modified, or false if pullDir has no clones and so isn't a pull request's
+// working dir.
+func (c *WorkingDirCleaner) cloneLastUsed(pullDir string) (time.Time, bool) {
+	entries, err := os.ReadDir(pullDir)
+	if err != nil {
+		return time.Time{}, false
+	}
+	var lastUsed time.Time
+	found := false
+	for _, entry := range entries {
+		cloneDir := filepath.Join(pullDir, entry.Name())
+		// Git updates the .git dir on every checkout, fetch and merge.
+		gitInfo, err := os.Stat(filepath.Join(cloneDir, ".git"))
+		if !entry.IsDir() || err != nil {
+			continue
+		}
+		found = true
+		if gitInfo.ModTime().After(lastUsed) {
+			lastUsed = gitInfo.ModTime()
+		}
+		if info, err := entry.Info(); err == nil && info.ModTime().After(lastUsed) {
+			lastUsed = info.ModTime()
+		}
+	}
+	return lastUsed, found
+}
@@ -0,0 +1,163 @@
+package events_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock/v4"
+	lockmocks "github.com/runatlantis/atlantis/server/core/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// cleanerTestPulls are the pull requests with working dirs, from the least to
+// the most recently used.
+var cleanerTestPulls = []struct {
+	repo string
+	num  int
+	age  time.Duration
+}{
+	{"group/subgroup/repo", 1, 72 * time.Hour},
+	{"owner/repo", 2, 48 * time.Hour},
+	{"owner/repo", 3, time.Hour},
+}
+
+// setupWorkingDirCleaner returns a cleaner for a data dir with the working
+// dirs of cleanerTestPulls.
+func setupWorkingDirCleaner(t *testing.T, locks map[string]models.ProjectLock) (*events.WorkingDirCleaner, string) {
+	RegisterMockTestingT(t)
+	logger := logging.NewNoopLogger(t)
+	dataDir := t.TempDir()
+	for _, p := range cleanerTestPulls {
+		cloneDir := filepath.Join(dataDir, "repos", p.repo, strconv.Itoa(p.num), "default")
+		Ok(t, os.MkdirAll(filepath.Join(cloneDir, ".git"), 0700))
+		usedAt := time.Now().Add(-p.age)
+		Ok(t, os.Chtimes(filepath.Join(cloneDir, ".git"), usedAt, usedAt))
+		Ok(t, os.Chtimes(cloneDir, usedAt, usedAt))
+	}
+	locker := lockmocks.NewMockLocker()
+	When(locker.List()).ThenReturn(locks, nil)
+	return &events.WorkingDirCleaner{
+		DataDir:          dataDir,
+		WorkingDir:       &events.FileWorkspace{DataDir: dataDir, Logger: logger},
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		Locker:           locker,
+		Logger:           logger,
+	}, dataDir
+}
+
+// remainingPulls returns the numbers of cleanerTestPulls whose working dirs
+// weren't deleted.
+func remainingPulls(t *testing.T, dataDir string) []int {
+	var nums []int
+	for _, p := range cleanerTestPulls {
+		_, err := os.Stat(filepath.Join(dataDir, "repos", p.repo, strconv.Itoa(p.num)))
+		if err == nil {
+			nums = append(nums, p.num)
+		} else {
+			Assert(t, os.IsNotExist(err), "unexpected error: %s", err)
+		}
+	}
+	return nums
+}
+
+func TestWorkingDirCleaner_Clean(t *testing.T) {
+	cases := []struct {
+		description  string
+		keepCount    int
+		keepFor      time.Duration
+		expRemaining []int
+	}{
+		{
+			description:  "no policy",
+			expRemaining: []int{1, 2, 3},
+		},
+		{
+			description:  "keep count",
+			keepCount:    2,
+			expRemaining: []int{2, 3},
+		},
+		{
+			description:  "keep for",
+			keepFor:      24 * time.Hour,
+			expRemaining: []int{3},
+		},
+		{
+			description:  "keep count and keep for",
+			keepCount:    2,
+			keepFor:      24 * time.Hour,
+			expRemaining: []int{3},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			cleaner, dataDir := setupWorkingDirCleaner(t, nil)
+			cleaner.KeepCount = c.keepCount
+			cleaner.KeepFor = c.keepFor
+			Ok(t, cleaner.Clean())
+			Equals(t, c.expRemaining, remainingPulls(t, dataDir))
+		})
+	}
+}
+
+func TestWorkingDirCleaner_KeepsLockedPulls(t *testing.T) {
+	cleaner, dataDir := setupWorkingDirCleaner(t, map[string]models.ProjectLock{
+		"group/subgroup/repo/./default": {
+			Pull: models.PullRequest{Num: 1, BaseRepo: models.Repo{FullName: "group/subgroup/repo"}},
+		},
+	})
+	cleaner.KeepCount = 1
+	Ok(t, cleaner.Clean())
+	Equals(t, []int{1, 3}, remainingPulls(t, dataDir))
+}
+
+func TestWorkingDirCleaner_KeepsPullsRunningCommands(t *testing.T) {
+	cleaner, dataDir := setupWorkingDirCleaner(t, nil)
+	cleaner.KeepCount = 1
+	unlockFn, err := cleaner.WorkingDirLocker.TryLock("owner/repo", 2, "default", ".")
+	Ok(t, err)
+	Ok(t, cleaner.Clean())
+	Equals(t, []int{2, 3}, remainingPulls(t, dataDir))
+
+	unlockFn()
+	Ok(t, cleaner.Clean())
+	Equals(t, []int{3}, remainingPulls(t, dataDir))
+}
+
+func TestWorkingDirCleaner_KeepOnFailure(t *testing.T) {
+	cleaner, dataDir := setupWorkingDirCleaner(t, nil)
+	cleaner.KeepCount = 1
+	cleaner.KeepOnFailure = true
+	pull := models.PullRequest{Num: 2, BaseRepo: models.Repo{FullName: "owner/repo"}}
+	cleaner.RecordPullStatus(models.PullStatus{
+		Pull:     pull,
+		Projects: []models.ProjectStatus{{Status: models.PlannedPlanStatus}, {Status: models.ErroredApplyStatus}},
+	})
+	Ok(t, cleaner.Clean())
+	Equals(t, []int{2, 3}, remainingPulls(t, dataDir))
+
+	// Once the pull request's projects succeed, its working dir isn't kept.
+	cleaner.RecordPullStatus(models.PullStatus{
+		Pull:     pull,
+		Projects: []models.ProjectStatus{{Status: models.PlannedPlanStatus}, {Status: models.AppliedPlanStatus}},
+	})
+	Ok(t, cleaner.Clean())
+	Equals(t, []int{3}, remainingPulls(t, dataDir))
+}
+
+func TestWorkingDirCleaner_NoDataDir(t *testing.T) {
+	cleaner, _ := setupWorkingDirCleaner(t, nil)
+	cleaner.DataDir = filepath.Join(t.TempDir(), "missing")
+	cleaner.KeepCount = 1
+	Ok(t, cleaner.Clean())
+}
+
+func TestWorkingDirCleaner_RecordPullStatusNil(t *testing.T) {
+	var cleaner *events.WorkingDirCleaner
+	cleaner.RecordPullStatus(models.PullStatus{Projects: []models.ProjectStatus{{Status: models.ErroredPlanStatus}}})
+}
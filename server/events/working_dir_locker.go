@@ -40,20 +40,46 @@ type WorkingDirLocker interface {
 	TryLockPull(repoFullName string, pullNum int) (func(), error)
 }
 
+// WorkingDirLockScope is how much of a pull request's working dir TryLock
+// locks. Commands that need a lock that's already held fail, so a broader
+// scope means fewer commands can run at the same time.
+type WorkingDirLockScope string
+
+const (
+	// RepoWorkingDirLockScope locks the whole pull request so only one command
+	// can run at a time.
+	RepoWorkingDirLockScope WorkingDirLockScope = "repo"
+	// WorkspaceWorkingDirLockScope locks all the projects in a workspace.
+	WorkspaceWorkingDirLockScope WorkingDirLockScope = "workspace"
+	// PathWorkingDirLockScope locks each path and workspace so commands for
+	// projects in other paths or workspaces can run at the same time. Projects
+	// in the same path and workspace share a lock since they share the path's
+	// .terraform dir. This is the default and the narrowest scope.
+	PathWorkingDirLockScope WorkingDirLockScope = "path"
+)
+
 // DefaultWorkingDirLocker implements WorkingDirLocker.
 type DefaultWorkingDirLocker struct {
 	// mutex prevents against multiple threads calling functions on this struct
 	// concurrently. It's only used for entry/exit to each function.
 	mutex sync.Mutex
+	// scope controls how the keys passed to TryLock are built.
+	scope WorkingDirLockScope
 	// locks is a list of the keys that are locked. We then use prefix
 	// matching to determine if something is locked. It's naive but that's okay
 	// because there won't be many locks at one time.
 	locks []string
 }
 
-// NewDefaultWorkingDirLocker is a constructor.
+// NewDefaultWorkingDirLocker is a constructor. It locks each path and workspace.
 func NewDefaultWorkingDirLocker() *DefaultWorkingDirLocker {
-	return &DefaultWorkingDirLocker{}
+	return NewDefaultWorkingDirLockerWithScope(PathWorkingDirLockScope)
+}
+
+// NewDefaultWorkingDirLockerWithScope returns a DefaultWorkingDirLocker whose
+// TryLock locks scope.
+func NewDefaultWorkingDirLockerWithScope(scope WorkingDirLockScope) *DefaultWorkingDirLocker {
+	return &DefaultWorkingDirLocker{scope: scope}
 }
 
 func (d *DefaultWorkingDirLocker) TryLockPull(repoFullName string, pullNum int) (func(), error) {
@@ -121,8 +147,18 @@ func (d *DefaultWorkingDirLocker) removeLock(key string) {
 	d.locks = newLocks
 }
 
+// workspaceKey returns the key TryLock locks. Broader scopes drop the parts
+// of the key they don't lock separately. Every key is prefixed with the pull
+// key so TryLockPull still conflicts with all of them.
 func (d *DefaultWorkingDirLocker) workspaceKey(repo string, pull int, workspace string, path string) string {
-	return fmt.Sprintf("%s/%s/%s", d.pullKey(repo, pull), workspace, path)
+	switch d.scope {
+	case RepoWorkingDirLockScope:
+		return d.pullKey(repo, pull)
+	case WorkspaceWorkingDirLockScope:
+		return fmt.Sprintf("%s/%s", d.pullKey(repo, pull), workspace)
+	default:
+		return fmt.Sprintf("%s/%s/%s", d.pullKey(repo, pull), workspace, path)
+	}
 }
 
 func (d *DefaultWorkingDirLocker) pullKey(repo string, pull int) string {
@@ -14,6 +14,7 @@
 package events_test
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/runatlantis/atlantis/server/events"
@@ -214,3 +215,96 @@ func TestLockPull_WorkspaceFirst(t *testing.T) {
 	_, err = locker.TryLockPull("owner/repo", 1)
 	Ok(t, err)
 }
+
+func TestTryLock_Scopes(t *testing.T) {
+	cases := []struct {
+		scope             events.WorkingDirLockScope
+		expOtherPath      bool
+		expOtherWorkspace bool
+	}{
+		{
+			scope:             events.PathWorkingDirLockScope,
+			expOtherPath:      true,
+			expOtherWorkspace: true,
+		},
+		{
+			scope:             events.WorkspaceWorkingDirLockScope,
+			expOtherPath:      false,
+			expOtherWorkspace: true,
+		},
+		{
+			scope:             events.RepoWorkingDirLockScope,
+			expOtherPath:      false,
+			expOtherWorkspace: false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(string(c.scope), func(t *testing.T) {
+			locker := events.NewDefaultWorkingDirLockerWithScope(c.scope)
+			unlock, err := locker.TryLock(repo, 1, workspace, "project1")
+			Ok(t, err)
+
+			_, err = locker.TryLock(repo, 1, workspace, "project1")
+			ErrContains(t, "currently locked", err)
+
+			unlockOtherPath, err := locker.TryLock(repo, 1, workspace, "project2")
+			Equals(t, c.expOtherPath, err == nil)
+			unlockOtherPath()
+
+			unlockOtherWorkspace, err := locker.TryLock(repo, 1, "staging", "project1")
+			Equals(t, c.expOtherWorkspace, err == nil)
+			unlockOtherWorkspace()
+
+			// Other pulls are never blocked.
+			_, err = locker.TryLock(repo, 2, workspace, "project1")
+			Ok(t, err)
+
+			// Pull locks are always blocked.
+			_, err = locker.TryLockPull(repo, 1)
+			Assert(t, err != nil, "exp err")
+
+			unlock()
+			_, err = locker.TryLock(repo, 1, workspace, "project1")
+			Ok(t, err)
+		})
+	}
+}
+
+// Commands for independent projects should be able to hold their locks at the
+// same time.
+func TestTryLock_PathScopeIndependentProjects(t *testing.T) {
+	locker := events.NewDefaultWorkingDirLockerWithScope(events.PathWorkingDirLockScope)
+	projects := []string{"project1", "project2", "nested/project3"}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(projects))
+	unlockFns := make(chan func(), len(projects))
+	for _, p := range projects {
+		wg.Add(1)
+		go func(p string) {
+			defer wg.Done()
+			unlockFn, err := locker.TryLock(repo, 1, workspace, p)
+			if err != nil {
+				errs <- err
+				return
+			}
+			unlockFns <- unlockFn
+		}(p)
+	}
+	wg.Wait()
+	close(errs)
+	close(unlockFns)
+	for err := range errs {
+		Ok(t, err)
+	}
+	Equals(t, len(projects), len(unlockFns))
+
+	// While they're all held the whole pull can't be locked.
+	_, err := locker.TryLockPull(repo, 1)
+	Assert(t, err != nil, "exp err")
+	for unlockFn := range unlockFns {
+		unlockFn()
+	}
+	_, err = locker.TryLockPull(repo, 1)
+	Ok(t, err)
+}
@@ -2,14 +2,20 @@ package events_test
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/runatlantis/atlantis/server/core/config/valid"
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/logging"
@@ -333,6 +339,8 @@ func TestClone_CheckoutMergeShallow(t *testing.T) {
 		Assert(t, gotBaseCommitType == "commit\n", "should have merge-base in shallow repo")
 		gotOldCommitType := runCmdErrCode(t, cloneDir, 128, "git", "cat-file", "-t", oldCommit)
 		Assert(t, strings.Contains(gotOldCommitType, "could not get object info"), "should not have old commit in shallow repo")
+		assert.FileExists(t, filepath.Join(cloneDir, "branch-file"))
+		assert.FileExists(t, filepath.Join(cloneDir, "main-file"))
 	})
 
 	// Test that we will check out full repo if CheckoutDepth is too small
@@ -364,6 +372,10 @@ func TestClone_CheckoutMergeShallow(t *testing.T) {
 		Assert(t, gotBaseCommitType == "commit\n", "should have merge-base in full repo")
 		gotOldCommitType := runCmd(t, cloneDir, "git", "cat-file", "-t", oldCommit)
 		Assert(t, gotOldCommitType == "commit\n", "should have old commit in full repo")
+		Equals(t, "false\n", runCmd(t, cloneDir, "git", "rev-parse", "--is-shallow-repository"))
+		// The branch is still merged after fetching the full history.
+		assert.FileExists(t, filepath.Join(cloneDir, "branch-file"))
+		assert.FileExists(t, filepath.Join(cloneDir, "main-file"))
 	})
 
 }
@@ -447,6 +459,135 @@ func TestClone_RecloneWrongCommit(t *testing.T) {
 	Equals(t, expCommit, actCommit)
 }
 
+// Test that historical pulls are cloned at their commit into a separate
+// working dir from the pull request's.
+func TestClone_Historical(t *testing.T) {
+	repoDir := initRepo(t)
+	runCmd(t, repoDir, "git", "checkout", "branch")
+	runCmd(t, repoDir, "touch", "first")
+	runCmd(t, repoDir, "git", "add", "first")
+	runCmd(t, repoDir, "git", "commit", "-m", "first")
+	historicalCommit := runCmd(t, repoDir, "git", "rev-parse", "HEAD")
+	runCmd(t, repoDir, "touch", "second")
+	runCmd(t, repoDir, "git", "add", "second")
+	runCmd(t, repoDir, "git", "commit", "-m", "second")
+	headCommit := runCmd(t, repoDir, "git", "rev-parse", "HEAD")
+
+	dataDir := t.TempDir()
+	wd := &events.FileWorkspace{
+		DataDir:                     dataDir,
+		CheckoutMerge:               true,
+		TestingOverrideHeadCloneURL: fmt.Sprintf("file://%s", repoDir),
+		GpgNoSigningEnabled:         true,
+		Logger:                      logging.NewNoopLogger(t),
+	}
+
+	pull := models.PullRequest{
+		BaseRepo:   models.Repo{},
+		HeadBranch: "branch",
+		HeadCommit: headCommit,
+	}
+	historicalPull := pull
+	historicalPull.HeadCommit = historicalCommit[:7]
+	historicalPull.Historical = true
+
+	cloneDir, mergedAgain, err := wd.Clone(models.Repo{}, historicalPull, "default")
+	Ok(t, err)
+	Equals(t, false, mergedAgain)
+	Equals(t, historicalCommit, runCmd(t, cloneDir, "git", "rev-parse", "HEAD"))
+	assert.FileExists(t, filepath.Join(cloneDir, "first"))
+	assert.NoFileExists(t, filepath.Join(cloneDir, "second"))
+
+	// The historical working dir must not be inside the pull's dir or its
+	// plans would be found when applying.
+	pullDir := filepath.Join(dataDir, "repos", "0")
+	Assert(t, !strings.HasPrefix(cloneDir, pullDir), "expected %q to not be in %q", cloneDir, pullDir)
+
+	// Cloning again at the same commit doesn't re-clone.
+	planFile := filepath.Join(cloneDir, "default.tfplan")
+	_, err = os.Create(planFile)
+	Ok(t, err)
+	_, _, err = wd.Clone(models.Repo{}, historicalPull, "default")
+	Ok(t, err)
+	assert.FileExists(t, planFile)
+
+	// Deleting the pull also deletes its historical working dirs.
+	Ok(t, wd.Delete(models.Repo{}, pull))
+	assert.NoDirExists(t, cloneDir)
+}
+
+// Test that historical pulls are cloned with CheckoutDepth commits and that
+// the full history is fetched if the commit is further behind.
+func TestClone_HistoricalShallow(t *testing.T) {
+	repoDir := initRepo(t)
+	runCmd(t, repoDir, "git", "checkout", "branch")
+	runCmd(t, repoDir, "touch", "first")
+	runCmd(t, repoDir, "git", "add", "first")
+	runCmd(t, repoDir, "git", "commit", "-m", "first")
+	oldCommit := strings.TrimSpace(runCmd(t, repoDir, "git", "rev-parse", "HEAD"))
+	runCmd(t, repoDir, "touch", "second")
+	runCmd(t, repoDir, "git", "add", "second")
+	runCmd(t, repoDir, "git", "commit", "-m", "second")
+	recentCommit := strings.TrimSpace(runCmd(t, repoDir, "git", "rev-parse", "HEAD"))
+	runCmd(t, repoDir, "git", "commit", "--allow-empty", "-m", "third")
+
+	cases := []struct {
+		description string
+		commit      string
+		expShallow  string
+	}{
+		{
+			description: "commit within depth",
+			commit:      recentCommit,
+			expShallow:  "true\n",
+		},
+		{
+			description: "commit further behind",
+			commit:      oldCommit,
+			expShallow:  "false\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			wd := &events.FileWorkspace{
+				DataDir:                     t.TempDir(),
+				CheckoutDepth:               2,
+				TestingOverrideHeadCloneURL: fmt.Sprintf("file://%s", repoDir),
+				GpgNoSigningEnabled:         true,
+				Logger:                      logging.NewNoopLogger(t),
+			}
+			cloneDir, _, err := wd.Clone(models.Repo{}, models.PullRequest{
+				BaseRepo:   models.Repo{},
+				HeadBranch: "branch",
+				HeadCommit: c.commit,
+				Historical: true,
+			}, "default")
+			Ok(t, err)
+			Equals(t, c.commit, strings.TrimSpace(runCmd(t, cloneDir, "git", "rev-parse", "HEAD")))
+			Equals(t, c.expShallow, runCmd(t, cloneDir, "git", "rev-parse", "--is-shallow-repository"))
+		})
+	}
+}
+
+// Test that cloning a historical pull fails if the commit isn't on the branch.
+func TestClone_HistoricalCommitNotFound(t *testing.T) {
+	repoDir := initRepo(t)
+	wd := &events.FileWorkspace{
+		DataDir:                     t.TempDir(),
+		TestingOverrideHeadCloneURL: fmt.Sprintf("file://%s", repoDir),
+		GpgNoSigningEnabled:         true,
+		Logger:                      logging.NewNoopLogger(t),
+	}
+
+	_, _, err := wd.Clone(models.Repo{}, models.PullRequest{
+		BaseRepo:   models.Repo{},
+		HeadBranch: "branch",
+		HeadCommit: "1234567",
+		Historical: true,
+	}, "default")
+	ErrContains(t, `commit "1234567" was not found on branch "branch"`, err)
+}
+
 // Test that if the branch we're merging into has diverged and we're using
 // checkout-strategy=merge, we actually merge the branch.
 // Also check that we do not merge if we are not using the merge strategy.
@@ -621,6 +762,151 @@ func TestHasDiverged_MasterHasDiverged(t *testing.T) {
 	Equals(t, hasDiverged, false)
 }
 
+// Test that clones are set to the working_dir_permissions of their repo.
+func TestClone_WorkingDirPermissions(t *testing.T) {
+	repoDir := initRepo(t)
+	Ok(t, os.MkdirAll(filepath.Join(repoDir, "project"), 0700))
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "project", "main.tf"), nil, 0600))
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "project", "hook.sh"), nil, 0700))
+	runCmd(t, repoDir, "git", "add", ".")
+	runCmd(t, repoDir, "git", "commit", "-m", "project")
+	runCmd(t, repoDir, "git", "branch", "-f", "branch")
+
+	for _, checkoutMerge := range []bool{false, true} {
+		t.Run(fmt.Sprintf("checkout merge %t", checkoutMerge), func(t *testing.T) {
+			wd := &events.FileWorkspace{
+				DataDir:                     t.TempDir(),
+				CheckoutMerge:               checkoutMerge,
+				TestingOverrideHeadCloneURL: fmt.Sprintf("file://%s", repoDir),
+				TestingOverrideBaseCloneURL: fmt.Sprintf("file://%s", repoDir),
+				GpgNoSigningEnabled:         true,
+				Logger:                      logging.NewNoopLogger(t),
+				GlobalCfg: valid.GlobalCfg{
+					Repos: []valid.Repo{
+						{IDRegex: regexp.MustCompile(".*"), WorkingDirPermissions: &valid.WorkingDirPermissions{DirMode: 0750, FileMode: 0640}},
+						{ID: "github.com/owner/other", WorkingDirPermissions: &valid.WorkingDirPermissions{DirMode: 0700}},
+					},
+				},
+			}
+
+			cloneDir, _, err := wd.Clone(models.Repo{}, models.PullRequest{
+				BaseRepo:   models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com"}},
+				HeadBranch: "branch",
+				BaseBranch: "main",
+			}, "default")
+			Ok(t, err)
+
+			for path, expMode := range map[string]os.FileMode{
+				".":               0750,
+				"project":         0750,
+				"project/main.tf": 0640,
+				"project/hook.sh": 0750,
+			} {
+				info, err := os.Stat(filepath.Join(cloneDir, path))
+				Ok(t, err)
+				Equals(t, expMode.String(), info.Mode().Perm().String())
+			}
+			// The .git dir is left as cloned.
+			info, err := os.Stat(filepath.Join(cloneDir, ".git"))
+			Ok(t, err)
+			Assert(t, info.Mode().Perm() != 0750, "exp .git dir to be left as cloned")
+		})
+	}
+}
+
+// Test that clones failing with transient errors are retried but clones
+// failing to authenticate aren't.
+func TestClone_Retries(t *testing.T) {
+	t.Setenv("GIT_TERMINAL_PROMPT", "0")
+	repoDir := initRepo(t)
+	runCmd(t, repoDir, "git", "checkout", "branch")
+	runCmd(t, repoDir, "git", "commit", "--allow-empty", "-m", "branch-commit")
+	// Serve the repo with git's dumb HTTP protocol so the server can fail
+	// requests.
+	bareDir := filepath.Join(t.TempDir(), "repo.git")
+	runCmd(t, repoDir, "git", "clone", "--bare", repoDir, bareDir)
+	runCmd(t, bareDir, "git", "update-server-info")
+
+	cases := []struct {
+		description string
+		// failStatus is the status the server fails the first failures
+		// attempts to clone with.
+		failStatus int
+		failures   int
+		retries    int
+		expErr     string
+		expClones  int
+	}{
+		{
+			description: "transient error is retried",
+			failStatus:  http.StatusServiceUnavailable,
+			failures:    2,
+			retries:     2,
+			expClones:   3,
+		},
+		{
+			description: "transient error fails after the retries",
+			failStatus:  http.StatusServiceUnavailable,
+			failures:    3,
+			retries:     2,
+			expErr:      "The requested URL returned error: 503",
+			expClones:   3,
+		},
+		{
+			description: "auth error isn't retried",
+			failStatus:  http.StatusUnauthorized,
+			failures:    1,
+			retries:     2,
+			expErr:      "could not read Username",
+			expClones:   1,
+		},
+		{
+			description: "transient error isn't retried by default",
+			failStatus:  http.StatusBadGateway,
+			failures:    1,
+			expErr:      "The requested URL returned error: 502",
+			expClones:   1,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			var clones atomic.Int32
+			fileServer := http.FileServer(http.Dir(bareDir))
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/info/refs" && int(clones.Add(1)) <= c.failures {
+					w.WriteHeader(c.failStatus)
+					return
+				}
+				fileServer.ServeHTTP(w, r)
+			}))
+			defer server.Close()
+
+			wd := &events.FileWorkspace{
+				DataDir:                     t.TempDir(),
+				CheckoutMerge:               true,
+				TestingOverrideHeadCloneURL: fmt.Sprintf("file://%s", repoDir),
+				TestingOverrideBaseCloneURL: server.URL,
+				GpgNoSigningEnabled:         true,
+				Logger:                      logging.NewNoopLogger(t),
+				CloneRetries:                c.retries,
+				CloneRetryDelay:             time.Millisecond,
+			}
+			cloneDir, _, err := wd.Clone(models.Repo{}, models.PullRequest{
+				BaseRepo:   models.Repo{},
+				HeadBranch: "branch",
+				BaseBranch: "main",
+			}, "default")
+			Equals(t, c.expClones, int(clones.Load()))
+			if c.expErr != "" {
+				ErrContains(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			runCmd(t, cloneDir, "git", "rev-parse", "HEAD^2")
+		})
+	}
+}
+
 func initRepo(t *testing.T) string {
 	repoDir := t.TempDir()
 	runCmd(t, repoDir, "git", "init", "--initial-branch=main")
@@ -633,3 +919,123 @@ func initRepo(t *testing.T) string {
 	runCmd(t, repoDir, "git", "branch", "branch")
 	return repoDir
 }
+
+func TestUncommittedFiles(t *testing.T) {
+	repoDir := initRepo(t)
+	Ok(t, os.MkdirAll(filepath.Join(repoDir, "project", ".terraform", "providers"), 0700))
+	Ok(t, os.MkdirAll(filepath.Join(repoDir, "other"), 0700))
+	Ok(t, os.WriteFile(filepath.Join(repoDir, ".gitignore"), []byte("*.gen.tf\n"), 0600))
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "project", "main.tf"), []byte("committed"), 0600))
+	runCmd(t, repoDir, "git", "add", ".")
+	runCmd(t, repoDir, "git", "commit", "-m", "project")
+
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "project", "main.tf"), []byte("changed"), 0600))
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "project", "untracked.tf"), []byte("untracked"), 0600))
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "project", "ignored.gen.tf"), []byte("ignored"), 0600))
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "project", ".terraform", "providers", "provider"), []byte("provider"), 0600))
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "other", "other.tf"), []byte("other"), 0600))
+	Ok(t, os.Remove(filepath.Join(repoDir, ".gitkeep")))
+
+	wd := &events.FileWorkspace{Logger: logging.NewNoopLogger(t)}
+	files, err := wd.UncommittedFiles(repoDir, "project")
+	Ok(t, err)
+	Equals(t, map[string]string{
+		"project/main.tf":        "d67e2e944994496c8d8ec76eed0cf9f09679448d584b532bebf941852a37f5ed",
+		"project/untracked.tf":   "86ed2df8017823dff5b258f8082cf4be80ad80fed3388b6818d9a631a49e464e",
+		"project/ignored.gen.tf": "3514cf816e5407a39cb7a1c1e1243f176dda121e06398a8934edb1dc426b0b34",
+	}, files)
+
+	files, err = wd.UncommittedFiles(repoDir, ".")
+	Ok(t, err)
+	Equals(t, "", files[".gitkeep"])
+	Assert(t, files["other/other.tf"] != "", "exp other/other.tf to be uncommitted")
+}
+
+// Test that the snapshot of a project keeps the files it was saved with when
+// the clone changes, and that it's deleted with the pull's working dir.
+func TestPlanSnapshot(t *testing.T) {
+	dataDir := t.TempDir()
+	repo := models.Repo{FullName: "owner/repo"}
+	pull := models.PullRequest{BaseRepo: repo, Num: 1}
+	wd := &events.FileWorkspace{
+		DataDir: dataDir,
+		Logger:  logging.NewNoopLogger(t),
+	}
+
+	_, err := wd.SavePlanSnapshot(repo, pull, "default", "project", "")
+	Assert(t, errors.Is(err, os.ErrNotExist), "exp not exist error if not cloned, got %v", err)
+
+	cloneDir := filepath.Join(dataDir, "repos", "owner", "repo", "1", "default")
+	for _, dir := range []string{filepath.Join("project", ".terraform"), filepath.Join("modules", "network"), "other", "shared"} {
+		Ok(t, os.MkdirAll(filepath.Join(cloneDir, dir), 0700))
+	}
+	mainTF := `module "network" { source = "../modules/network" }`
+	Ok(t, os.WriteFile(filepath.Join(cloneDir, "project", "main.tf"), []byte(mainTF), 0600))
+	Ok(t, os.WriteFile(filepath.Join(cloneDir, "project", "default.tfplan"), []byte("plan"), 0600))
+	Ok(t, os.WriteFile(filepath.Join(cloneDir, "project", ".terraform", "provider"), []byte("provider"), 0600))
+	Ok(t, os.Symlink("/plugin-cache/provider", filepath.Join(cloneDir, "project", ".terraform", "cached-provider")))
+	Ok(t, os.Symlink("main.tf", filepath.Join(cloneDir, "project", "link.tf")))
+	Ok(t, os.WriteFile(filepath.Join(cloneDir, "modules", "network", "main.tf"), []byte("network"), 0600))
+	Ok(t, os.WriteFile(filepath.Join(cloneDir, "other", "main.tf"), []byte("other"), 0600))
+	Ok(t, os.WriteFile(filepath.Join(cloneDir, "shared", "vars.tfvars"), []byte("shared"), 0600))
+	Ok(t, os.Symlink("../shared/vars.tfvars", filepath.Join(cloneDir, "project", "vars.tfvars")))
+
+	snapshotDir, err := wd.SavePlanSnapshot(repo, pull, "default", "project", "")
+	Ok(t, err)
+	Assert(t, !strings.HasPrefix(snapshotDir, cloneDir), "exp snapshot outside of the clone, got %s", snapshotDir)
+
+	Ok(t, os.WriteFile(filepath.Join(cloneDir, "project", "main.tf"), []byte("changed"), 0600))
+	dir, err := wd.GetPlanSnapshot(repo, pull, "default", "project", "")
+	Ok(t, err)
+	Equals(t, snapshotDir, dir)
+	for file, exp := range map[string]string{
+		filepath.Join("project", "main.tf"):        mainTF,
+		filepath.Join("project", "link.tf"):        mainTF,
+		filepath.Join("project", "default.tfplan"): "plan",
+		// Files linked from outside the snapshot are copied.
+		filepath.Join("project", "vars.tfvars"): "shared",
+		// Local modules the project calls are copied.
+		filepath.Join("modules", "network", "main.tf"): "network",
+		// The .terraform dir is copied.
+		filepath.Join("project", ".terraform", "provider"): "provider",
+	} {
+		contents, err := os.ReadFile(filepath.Join(dir, file))
+		Ok(t, err)
+		Equals(t, exp, string(contents))
+	}
+	link, err := os.Readlink(filepath.Join(dir, "project", ".terraform", "cached-provider"))
+	Ok(t, err)
+	Equals(t, "/plugin-cache/provider", link)
+	Ok(t, os.RemoveAll(filepath.Join(cloneDir, "project", ".terraform")))
+	contents, err := os.ReadFile(filepath.Join(dir, "project", ".terraform", "provider"))
+	Ok(t, err)
+	Equals(t, "provider", string(contents))
+	for _, other := range []string{"other", "shared"} {
+		_, err = os.Stat(filepath.Join(dir, other))
+		Assert(t, os.IsNotExist(err), "exp %s not to be copied, got %v", other, err)
+	}
+
+	// Saving the snapshot again replaces it.
+	Ok(t, os.Remove(filepath.Join(cloneDir, "project", "default.tfplan")))
+	_, err = wd.SavePlanSnapshot(repo, pull, "default", "project", "")
+	Ok(t, err)
+	_, err = os.Stat(filepath.Join(dir, "project", "default.tfplan"))
+	Assert(t, os.IsNotExist(err), "exp planfile to be removed from snapshot, got %v", err)
+
+	// Absolute symlinks and symlinks out of the repo aren't supported.
+	Ok(t, os.Symlink(filepath.Join(cloneDir, "shared", "vars.tfvars"), filepath.Join(cloneDir, "project", "abs.tfvars")))
+	_, err = wd.SavePlanSnapshot(repo, pull, "default", "project", "")
+	ErrContains(t, "symlink to the absolute path", err)
+	Ok(t, os.Remove(filepath.Join(cloneDir, "project", "abs.tfvars")))
+	Ok(t, os.Symlink("../../../secret", filepath.Join(cloneDir, "project", "secret")))
+	_, err = wd.SavePlanSnapshot(repo, pull, "default", "project", "")
+	ErrContains(t, "outside of the repo", err)
+	Ok(t, os.Remove(filepath.Join(cloneDir, "project", "secret")))
+
+	_, err = wd.GetPlanSnapshot(repo, pull, "default", "project", "other")
+	Assert(t, errors.Is(err, os.ErrNotExist), "exp not exist error for other project, got %v", err)
+
+	Ok(t, wd.Delete(repo, pull))
+	_, err = wd.GetPlanSnapshot(repo, pull, "default", "project", "")
+	Assert(t, errors.Is(err, os.ErrNotExist), "exp snapshot to be deleted, got %v", err)
+}
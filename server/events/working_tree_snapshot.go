@@ -0,0 +1,100 @@
+package events
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/core/runtime"
+	"github.com/runatlantis/atlantis/server/events/command"
+)
+
+// workingTreeSnapshotSuffix is added to the path of a project's planfile to
+// get the path of the snapshot of its working tree taken when it was planned.
+const workingTreeSnapshotSuffix = ".files"
+
+// workingTreeSnapshotPath returns the path of the snapshot of the working tree
+// of ctx's project in repoDir.
+func workingTreeSnapshotPath(repoDir string, ctx command.ProjectContext) string {
+	return filepath.Join(repoDir, ctx.RepoRelDir, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName)) + workingTreeSnapshotSuffix
+}
+
+// artifactSuffixes are added to the name of a planfile, without its
+// extension, to get the names of the files Atlantis writes next to it: the
+// planfile itself, its snapshot, the show output, the show output of the
+// changed resources, the policy check output and the cost summary.
+var artifactSuffixes = []string{".tfplan", ".tfplan" + workingTreeSnapshotSuffix, ".json", "-changed.json", "-policyout.json", "-cost.md"}
+
+// snapshotWorkingTree returns the files of ctx's project in repoDir that
+// aren't committed, without the files Atlantis writes for any project like
+// planfiles. Other projects and workspaces of the dir, or of dirs under it,
+// are planned in the same working tree so their files must be left out too.
+func snapshotWorkingTree(workingDir WorkingDir, repoDir string, ctx command.ProjectContext) (map[string]string, error) {
+	files, err := workingDir.UncommittedFiles(repoDir, ctx.RepoRelDir)
+	if err != nil {
+		return nil, err
+	}
+	// The planfile is deleted by the apply but its snapshot is kept, so
+	// either one marks the files of a project and workspace.
+	plans := []string{filepath.ToSlash(filepath.Join(ctx.RepoRelDir, runtime.GetPlanFilename(ctx.Workspace, ctx.ProjectName)))}
+	for file := range files {
+		for _, suffix := range artifactSuffixes[:2] {
+			if strings.HasSuffix(file, suffix) {
+				plans = append(plans, strings.TrimSuffix(file, suffix)+".tfplan")
+			}
+		}
+	}
+	for _, plan := range plans {
+		for _, suffix := range artifactSuffixes {
+			delete(files, strings.TrimSuffix(plan, ".tfplan")+suffix)
+		}
+	}
+	return files, nil
+}
+
+// saveWorkingTreeSnapshot saves the snapshot of the working tree of ctx's
+// project in repoDir so it can be compared when the project is applied.
+func saveWorkingTreeSnapshot(workingDir WorkingDir, repoDir string, ctx command.ProjectContext) error {
+	files, err := snapshotWorkingTree(workingDir, repoDir, ctx)
+	if err != nil {
+		return err
+	}
+	contents, err := json.Marshal(files)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(workingTreeSnapshotPath(repoDir, ctx), contents, 0600)
+}
+
+// changedSinceSnapshot returns the sorted files of ctx's project in repoDir
+// that were added, changed or deleted since its snapshot was saved. The error
+// satisfies os.IsNotExist if there's no snapshot.
+func changedSinceSnapshot(workingDir WorkingDir, repoDir string, ctx command.ProjectContext) ([]string, error) {
+	contents, err := os.ReadFile(workingTreeSnapshotPath(repoDir, ctx))
+	if err != nil {
+		return nil, err
+	}
+	var snapshot map[string]string
+	if err := json.Unmarshal(contents, &snapshot); err != nil {
+		return nil, err
+	}
+	files, err := snapshotWorkingTree(workingDir, repoDir, ctx)
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	for file, hash := range files {
+		if snapshotHash, ok := snapshot[file]; !ok || snapshotHash != hash {
+			changed = append(changed, file)
+		}
+	}
+	for file := range snapshot {
+		if _, ok := files[file]; !ok {
+			changed = append(changed, file)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
@@ -0,0 +1,128 @@
+package events
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/core/config/raw"
+	"github.com/runatlantis/atlantis/server/events/command"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// initSnapshotRepo returns a git repo with a committed project in the
+// "project" dir.
+func initSnapshotRepo(t *testing.T) string {
+	repoDir := t.TempDir()
+	Ok(t, os.MkdirAll(filepath.Join(repoDir, "project"), 0700))
+	Ok(t, os.WriteFile(filepath.Join(repoDir, "project", "main.tf"), []byte("committed"), 0600))
+	for _, args := range [][]string{
+		{"init", "--initial-branch=main"},
+		{"add", "."},
+		{"-c", "user.email=atlantisbot@runatlantis.io", "-c", "user.name=atlantisbot", "-c", "commit.gpgsign=false", "commit", "-m", "initial commit"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		out, err := cmd.CombinedOutput()
+		Assert(t, err == nil, "running git %v: %s", args, out)
+	}
+	return repoDir
+}
+
+func TestValidateApplyProject_CleanWorkingTree(t *testing.T) {
+	cases := []struct {
+		description string
+		// change changes the project's files after it's planned.
+		change     func(t *testing.T, projDir string)
+		expFailure string
+	}{
+		{
+			description: "clean",
+			change: func(t *testing.T, projDir string) {
+				// Atlantis' own files for the project are ignored.
+				Ok(t, os.WriteFile(filepath.Join(projDir, "default.json"), []byte("show"), 0600))
+			},
+		},
+		{
+			description: "other workspace planned and applied",
+			change: func(t *testing.T, projDir string) {
+				// Another workspace's files, which the apply keeps except
+				// for the planfile, are ignored.
+				for _, name := range []string{"staging.tfplan.files", "staging.json", "staging-changed.json", "staging-policyout.json", "staging-cost.md"} {
+					Ok(t, os.WriteFile(filepath.Join(projDir, name), []byte("staging"), 0600))
+				}
+			},
+		},
+		{
+			description: "project in a subdir planned",
+			change: func(t *testing.T, projDir string) {
+				Ok(t, os.MkdirAll(filepath.Join(projDir, "sub"), 0700))
+				for _, name := range []string{"sub-default.tfplan", "sub-default.json"} {
+					Ok(t, os.WriteFile(filepath.Join(projDir, "sub", name), []byte("sub"), 0600))
+				}
+			},
+		},
+		{
+			description: "json file added",
+			change: func(t *testing.T, projDir string) {
+				Ok(t, os.WriteFile(filepath.Join(projDir, "vars.json"), []byte("{}"), 0600))
+			},
+			expFailure: "Files changed since the project was planned: project/vars.json. To plan again, comment `atlantis plan -d project`.",
+		},
+		{
+			description: "generated file changed",
+			change: func(t *testing.T, projDir string) {
+				Ok(t, os.WriteFile(filepath.Join(projDir, "generated.tf"), []byte("changed"), 0600))
+			},
+			expFailure: "Files changed since the project was planned: project/generated.tf. To plan again, comment `atlantis plan -d project`.",
+		},
+		{
+			description: "files added and deleted",
+			change: func(t *testing.T, projDir string) {
+				Ok(t, os.Remove(filepath.Join(projDir, "generated.tf")))
+				Ok(t, os.WriteFile(filepath.Join(projDir, "new.tf"), []byte("new"), 0600))
+				Ok(t, os.WriteFile(filepath.Join(projDir, "main.tf"), []byte("changed"), 0600))
+			},
+			expFailure: "Files changed since the project was planned: project/generated.tf, project/main.tf, project/new.tf. To plan again, comment `atlantis plan -d project`.",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			repoDir := initSnapshotRepo(t)
+			projDir := filepath.Join(repoDir, "project")
+			workingDir := &FileWorkspace{Logger: logging.NewNoopLogger(t)}
+			ctx := command.ProjectContext{
+				RepoRelDir:        "project",
+				Workspace:         "default",
+				RePlanCmd:         "atlantis plan -d project",
+				ApplyRequirements: []string{raw.CleanWorkingTreeRequirement},
+			}
+
+			// Planning generates a file and the planfile.
+			Ok(t, os.WriteFile(filepath.Join(projDir, "generated.tf"), []byte("generated"), 0600))
+			Ok(t, os.WriteFile(filepath.Join(projDir, "default.tfplan"), []byte("plan"), 0600))
+			Ok(t, saveWorkingTreeSnapshot(workingDir, repoDir, ctx))
+			c.change(t, projDir)
+
+			handler := &DefaultCommandRequirementHandler{WorkingDir: workingDir}
+			failure, err := handler.ValidateApplyProject(repoDir, ctx)
+			Ok(t, err)
+			Equals(t, c.expFailure, failure)
+		})
+	}
+}
+
+func TestValidateApplyProject_CleanWorkingTreeNoSnapshot(t *testing.T) {
+	repoDir := initSnapshotRepo(t)
+	handler := &DefaultCommandRequirementHandler{WorkingDir: &FileWorkspace{Logger: logging.NewNoopLogger(t)}}
+	failure, err := handler.ValidateApplyProject(repoDir, command.ProjectContext{
+		RepoRelDir:        "project",
+		Workspace:         "default",
+		RePlanCmd:         "atlantis plan -d project",
+		ApplyRequirements: []string{raw.CleanWorkingTreeRequirement},
+	})
+	Ok(t, err)
+	Equals(t, "The project's files weren't recorded when it was planned. To plan again, comment `atlantis plan -d project`.", failure)
+}
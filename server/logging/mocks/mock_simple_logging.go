@@ -154,6 +154,21 @@ func (mock *MockSimpleLogging) WithHistory(a ...interface{}) logging.SimpleLoggi
 	return ret0
 }
 
+func (mock *MockSimpleLogging) WithModule(module string) logging.SimpleLogging {
+	if mock == nil {
+		panic("mock must not be nil. Use myMock := NewMockSimpleLogging().")
+	}
+	params := []pegomock.Param{module}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("WithModule", params, []reflect.Type{reflect.TypeOf((*logging.SimpleLogging)(nil)).Elem()})
+	var ret0 logging.SimpleLogging
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(logging.SimpleLogging)
+		}
+	}
+	return ret0
+}
+
 func (mock *MockSimpleLogging) VerifyWasCalledOnce() *VerifierMockSimpleLogging {
 	return &VerifierMockSimpleLogging{
 		mock:                   mock,
@@ -520,3 +535,30 @@ func (c *MockSimpleLogging_WithHistory_OngoingVerification) GetAllCapturedArgume
 	}
 	return
 }
+
+func (verifier *VerifierMockSimpleLogging) WithModule(module string) *MockSimpleLogging_WithModule_OngoingVerification {
+	params := []pegomock.Param{module}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "WithModule", params, verifier.timeout)
+	return &MockSimpleLogging_WithModule_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type MockSimpleLogging_WithModule_OngoingVerification struct {
+	mock              *MockSimpleLogging
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *MockSimpleLogging_WithModule_OngoingVerification) GetCapturedArguments() string {
+	module := c.GetAllCapturedArguments()
+	return module[len(module)-1]
+}
+
+func (c *MockSimpleLogging_WithModule_OngoingVerification) GetAllCapturedArguments() (_param0 []string) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]string, len(c.methodInvocations))
+		for u, param := range params[0] {
+			_param0[u] = param.(string)
+		}
+	}
+	return
+}
@@ -51,6 +51,10 @@ type SimpleLogging interface {
 	// keeping as a separate method to ensure that usage of history is completely intentional
 	WithHistory(a ...interface{}) SimpleLogging
 
+	// WithModule returns a logger for the named module. It logs at the level
+	// configured for that module, or at this logger's level if there's none.
+	WithModule(module string) SimpleLogging
+
 	// Fetches the history we've stored associated with the logging context
 	GetHistory() string
 
@@ -62,6 +66,9 @@ type StructuredLogger struct {
 	z           *zap.SugaredLogger
 	level       zap.AtomicLevel
 	keepHistory bool
+	// moduleLevels are the levels of the modules that override the global
+	// level. They're shared by all loggers derived from the same root.
+	moduleLevels map[string]zap.AtomicLevel
 	// History stores all log entries ever written using
 	// this logger. This is safe for short-lived loggers
 	// like those used during plan/apply commands.
@@ -74,21 +81,39 @@ type StructuredLogger struct {
 }
 
 func NewStructuredLoggerFromLevel(lvl LogLevel) (SimpleLogging, error) {
+	return NewStructuredLoggerFromLevels(lvl, nil)
+}
+
+// NewStructuredLoggerFromLevels creates a logger logging at lvl, except for
+// the loggers returned by WithModule for the modules in moduleLvls, which log
+// at their module's level.
+func NewStructuredLoggerFromLevels(lvl LogLevel, moduleLvls map[string]LogLevel) (SimpleLogging, error) {
 	cfg := zap.NewProductionConfig()
 
 	cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	cfg.Level = zap.NewAtomicLevelAt(lvl.zLevel)
-	return newStructuredLogger(cfg)
+	return newStructuredLogger(cfg, moduleLvls)
 }
 
 func NewStructuredLogger() (SimpleLogging, error) {
 	cfg := zap.NewProductionConfig()
 	cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-	return newStructuredLogger(cfg)
+	return newStructuredLogger(cfg, nil)
 }
 
-func newStructuredLogger(cfg zap.Config) (*StructuredLogger, error) {
-	baseLogger, err := cfg.Build()
+func newStructuredLogger(cfg zap.Config, moduleLvls map[string]LogLevel) (*StructuredLogger, error) {
+	// The core itself logs everything and the levelCore wrapping it does the
+	// filtering, so that modules can log below the global level.
+	level := cfg.Level
+	cfg.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	moduleLevels := make(map[string]zap.AtomicLevel, len(moduleLvls))
+	for module, lvl := range moduleLvls {
+		moduleLevels[module] = zap.NewAtomicLevelAt(lvl.zLevel)
+	}
+
+	baseLogger, err := cfg.Build(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return &levelCore{Core: c, level: level}
+	}))
 
 	baseLogger = baseLogger.
 		// ensures that the caller doesn't just say logging/simple_logger each time
@@ -102,22 +127,25 @@ func newStructuredLogger(cfg zap.Config) (*StructuredLogger, error) {
 	}
 
 	return &StructuredLogger{
-		z:     baseLogger.Sugar(),
-		level: cfg.Level,
+		z:            baseLogger.Sugar(),
+		level:        level,
+		moduleLevels: moduleLevels,
 	}, nil
 }
 
 func (l *StructuredLogger) With(a ...interface{}) SimpleLogging {
 	return &StructuredLogger{
-		z:     l.z.With(a...),
-		level: l.level,
+		z:            l.z.With(a...),
+		level:        l.level,
+		moduleLevels: l.moduleLevels,
 	}
 }
 
 func (l *StructuredLogger) WithHistory(a ...interface{}) SimpleLogging {
 	logger := &StructuredLogger{
-		z:     l.z.With(a...),
-		level: l.level,
+		z:            l.z.With(a...),
+		level:        l.level,
+		moduleLevels: l.moduleLevels,
 	}
 
 	// ensure that the history is kept across loggers.
@@ -127,6 +155,28 @@ func (l *StructuredLogger) WithHistory(a ...interface{}) SimpleLogging {
 	return logger
 }
 
+func (l *StructuredLogger) WithModule(module string) SimpleLogging {
+	level := l.level
+	if moduleLevel, ok := l.moduleLevels[module]; ok {
+		level = moduleLevel
+	}
+	z := l.z.Desugar().WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		// Replace rather than stack the level of a parent module.
+		if lc, ok := c.(*levelCore); ok {
+			c = lc.Core
+		}
+		return &levelCore{Core: c, level: level}
+	})).Sugar()
+
+	return &StructuredLogger{
+		z:            z.With("module", module),
+		level:        level,
+		keepHistory:  l.keepHistory,
+		history:      l.history,
+		moduleLevels: l.moduleLevels,
+	}
+}
+
 func (l *StructuredLogger) GetHistory() string {
 	return l.history.String()
 }
@@ -182,13 +232,36 @@ func (l *StructuredLogger) saveToHistory(lvl LogLevel, format string, a ...inter
 	l.history.WriteString(fmt.Sprintf("[%s] %s\n", lvl.shortStr, msg))
 }
 
+// levelCore only lets through the entries enabled by its level, which can
+// differ between loggers sharing the same underlying core.
+type levelCore struct {
+	zapcore.Core
+	level zap.AtomicLevel
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.level.Enabled(lvl)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
 // NewNoopLogger creates a logger instance that discards all logs and never
 // writes them. Used for testing.
 func NewNoopLogger(t *testing.T) SimpleLogging {
 	level := zap.DebugLevel
 	return &StructuredLogger{
-		z:     zaptest.NewLogger(t, zaptest.Level(level)).Sugar(),
-		level: zap.NewAtomicLevelAt(level),
+		z:            zaptest.NewLogger(t, zaptest.Level(level)).Sugar(),
+		level:        zap.NewAtomicLevelAt(level),
+		moduleLevels: map[string]zap.AtomicLevel{},
 	}
 }
 
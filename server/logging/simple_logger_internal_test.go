@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"testing"
+
+	. "github.com/runatlantis/atlantis/testing"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestStructuredLogger_WithModule(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	logger := &StructuredLogger{
+		z:     zap.New(&levelCore{Core: core, level: level}).Sugar(),
+		level: level,
+		moduleLevels: map[string]zap.AtomicLevel{
+			"events": zap.NewAtomicLevelAt(zapcore.DebugLevel),
+			"vcs":    zap.NewAtomicLevelAt(zapcore.WarnLevel),
+		},
+	}
+
+	logger.Debug("root debug")
+	logger.Info("root info")
+	events := logger.WithModule("events").With("repo", "owner/repo")
+	events.Debug("events debug")
+	vcs := events.WithModule("vcs")
+	vcs.Info("vcs info")
+	vcs.Warn("vcs warn")
+	other := logger.WithModule("other")
+	other.Debug("other debug")
+	other.Info("other info")
+
+	var msgs []string
+	for _, entry := range logs.All() {
+		msgs = append(msgs, entry.Message)
+	}
+	Equals(t, []string{"root info", "events debug", "vcs warn", "other info"}, msgs)
+	Equals(t, map[string]interface{}{"module": "events", "repo": "owner/repo"}, logs.All()[1].ContextMap())
+	Equals(t, map[string]interface{}{"module": "vcs", "repo": "owner/repo"}, logs.All()[2].ContextMap())
+}
+
+func TestStructuredLogger_WithModule_SetLevel(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	logger := &StructuredLogger{
+		z:     zap.New(&levelCore{Core: core, level: level}).Sugar(),
+		level: level,
+		moduleLevels: map[string]zap.AtomicLevel{
+			"vcs": zap.NewAtomicLevelAt(zapcore.WarnLevel),
+		},
+	}
+	events := logger.WithModule("events")
+	vcs := logger.WithModule("vcs")
+
+	// Modules without their own level follow the global level.
+	logger.SetLevel(Debug)
+	events.Debug("events debug")
+	vcs.Info("vcs info")
+
+	Equals(t, 1, logs.Len())
+	Equals(t, "events debug", logs.All()[0].Message)
+}
@@ -20,6 +20,8 @@ type Router struct {
 	LockViewRouteName string
 	// ProjectJobsViewRouteName is the named route for the projects active jobs
 	ProjectJobsViewRouteName string
+	// PlanJSONViewRouteName is the named route for the JSON of plans.
+	PlanJSONViewRouteName string
 	// LockViewRouteIDQueryParam is the query parameter needed to construct the
 	// lock view: underlying.Get(LockViewRouteName).URL(LockViewRouteIDQueryParam, "my id").
 	LockViewRouteIDQueryParam string
@@ -31,12 +33,7 @@ type Router struct {
 // GenerateLockURL returns a fully qualified URL to view the lock at lockID.
 func (r *Router) GenerateLockURL(lockID string) string {
 	lockURL, _ := r.Underlying.Get(r.LockViewRouteName).URL(r.LockViewRouteIDQueryParam, url.QueryEscape(lockID))
-	// At this point, lockURL will just be a path because r.Underlying isn't
-	// configured with host or scheme information. So to generate the fully
-	// qualified LockURL we just append the router's url to our base url.
-	// We're not doing anything fancy here with the actual url object because
-	// golang likes to double escape the lockURL path when using url.Parse().
-	return r.AtlantisURL.String() + lockURL.String()
+	return r.fullURL(lockURL)
 }
 
 func (r *Router) GenerateProjectJobURL(ctx command.ProjectContext) (string, error) {
@@ -50,7 +47,7 @@ func (r *Router) GenerateProjectJobURL(ctx command.ProjectContext) (string, erro
 		return "", errors.Wrapf(err, "creating job url for %s", ctx.JobID)
 	}
 
-	return r.AtlantisURL.String() + jobURL.String(), nil
+	return r.fullURL(jobURL), nil
 }
 
 func (r *Router) GenerateProjectWorkflowHookURL(hookID string) (string, error) {
@@ -61,5 +58,21 @@ func (r *Router) GenerateProjectWorkflowHookURL(hookID string) (string, error) {
 		return "", errors.Wrapf(err, "creating workflow hook url for %s", hookID)
 	}
 
-	return r.AtlantisURL.String() + jobURL.String(), nil
+	return r.fullURL(jobURL), nil
+}
+
+// GeneratePlanJSONURL returns a fully qualified URL to the plan JSON with id.
+func (r *Router) GeneratePlanJSONURL(id string) string {
+	planJSONURL, _ := r.Underlying.Get(r.PlanJSONViewRouteName).URL("id", id)
+	return r.fullURL(planJSONURL)
+}
+
+// fullURL returns the fully qualified URL of routeURL, which is just a path
+// because r.Underlying isn't configured with host or scheme information. So
+// we append it to AtlantisURL, which keeps its base path if Atlantis is
+// hosted under a path behind a reverse proxy.
+// We're not doing anything fancy here with the actual url object because
+// golang likes to double escape the path when using url.Parse().
+func (r *Router) fullURL(routeURL *url.URL) string {
+	return r.AtlantisURL.String() + routeURL.String()
 }
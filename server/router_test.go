@@ -67,7 +67,11 @@ func TestRouter_GenerateLockURL(t *testing.T) {
 }
 
 func setupJobsRouter(t *testing.T) *server.Router {
-	atlantisURL, err := server.ParseAtlantisURL("http://localhost:4141")
+	return setupJobsRouterWithURL(t, "http://localhost:4141")
+}
+
+func setupJobsRouterWithURL(t *testing.T, rawURL string) *server.Router {
+	atlantisURL, err := server.ParseAtlantisURL(rawURL)
 	Ok(t, err)
 
 	underlyingRouter := mux.NewRouter()
@@ -93,6 +97,41 @@ func TestGenerateProjectJobURL_ShouldGenerateURLWhenJobIDSpecified(t *testing.T)
 	Equals(t, expectedURL, gotURL)
 }
 
+func TestGenerateProjectJobURL_UsesBasePath(t *testing.T) {
+	router := setupJobsRouterWithURL(t, "https://example.com/basepath/")
+	gotURL, err := router.GenerateProjectJobURL(command.ProjectContext{JobID: "1234"})
+	Ok(t, err)
+	Equals(t, "https://example.com/basepath/jobs/1234", gotURL)
+}
+
+func TestGenerateProjectWorkflowHookURL(t *testing.T) {
+	cases := []struct {
+		AtlantisURL string
+		ExpURL      string
+	}{
+		{
+			"http://localhost:4141",
+			"http://localhost:4141/jobs/hook-id",
+		},
+		{
+			"https://example.com/basepath",
+			"https://example.com/basepath/jobs/hook-id",
+		},
+		{
+			"https://example.com/path/1/",
+			"https://example.com/path/1/jobs/hook-id",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.AtlantisURL, func(t *testing.T) {
+			router := setupJobsRouterWithURL(t, c.AtlantisURL)
+			gotURL, err := router.GenerateProjectWorkflowHookURL("hook-id")
+			Ok(t, err)
+			Equals(t, c.ExpURL, gotURL)
+		})
+	}
+}
+
 func TestGenerateProjectJobURL_ShouldReturnErrorWhenJobIDNotSpecified(t *testing.T) {
 	router := setupJobsRouter(t)
 	ctx := command.ProjectContext{
@@ -110,3 +149,16 @@ func TestGenerateProjectJobURL_ShouldReturnErrorWhenJobIDNotSpecified(t *testing
 	assert.EqualError(t, err, expectedErrString)
 	Equals(t, "", gotURL)
 }
+
+func TestRouter_GeneratePlanJSONURL(t *testing.T) {
+	atlantisURL, err := server.ParseAtlantisURL("https://example.com/basepath/")
+	Ok(t, err)
+	underlyingRouter := mux.NewRouter()
+	underlyingRouter.HandleFunc("/plan-json/{id}", func(_ http.ResponseWriter, _ *http.Request) {}).Methods("GET").Name("plan-json-detail")
+	router := &server.Router{
+		AtlantisURL:           atlantisURL,
+		Underlying:            underlyingRouter,
+		PlanJSONViewRouteName: "plan-json-detail",
+	}
+	Equals(t, "https://example.com/basepath/plan-json/abc-123", router.GeneratePlanJSONURL("abc-123"))
+}
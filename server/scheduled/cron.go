@@ -0,0 +1,106 @@
+package scheduled
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a standard five field cron schedule: minute, hour, day of
+// month, month and day of week. Each field is "*", a number, a range like
+// "1-5" or a list of them like "1,15", each optionally with a step like
+// "*/15". Names of months and days aren't supported.
+type CronSchedule struct {
+	minutes, hours, daysOfMonth, months, daysOfWeek uint64
+	// anyDayOfMonth and anyDayOfWeek are true if the field is "*". Like cron,
+	// if both fields are restricted, either one has to match.
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+// ParseCronSchedule parses a five field cron schedule, ex. "0 2 * * 1-5" for
+// 2am on weekdays.
+func ParseCronSchedule(spec string) (*CronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron schedule %q must have 5 fields: minute, hour, day of month, month and day of week", spec)
+	}
+	var s CronSchedule
+	var err error
+	if s.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if s.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if s.daysOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if s.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	// Sunday is both 0 and 7.
+	if s.daysOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	if s.daysOfWeek&(1<<7) != 0 {
+		s.daysOfWeek |= 1
+	}
+	s.anyDayOfMonth = fields[2] == "*"
+	s.anyDayOfWeek = fields[4] == "*"
+	return &s, nil
+}
+
+// Matches returns true if the schedule runs in the minute of t.
+func (s *CronSchedule) Matches(t time.Time) bool {
+	if s.minutes&(1<<t.Minute()) == 0 || s.hours&(1<<t.Hour()) == 0 || s.months&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	dayOfMonth := s.daysOfMonth&(1<<t.Day()) != 0
+	dayOfWeek := s.daysOfWeek&(1<<int(t.Weekday())) != 0
+	if !s.anyDayOfMonth && !s.anyDayOfWeek {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
+
+// parseCronField returns the values between min and max that field matches
+// as a bit set.
+func parseCronField(field string, min int, max int) (uint64, error) {
+	var values uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		start, end := min, max
+		if rangePart != "*" {
+			startPart, endPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = strconv.Atoi(startPart); err != nil {
+				return 0, fmt.Errorf("invalid value %q", startPart)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(endPart); err != nil {
+					return 0, fmt.Errorf("invalid value %q", endPart)
+				}
+			} else if hasStep {
+				// Like cron, "5/15" is "5-max/15".
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("%q is out of the range %d-%d", rangePart, min, max)
+		}
+		for i := start; i <= end; i += step {
+			values |= 1 << i
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,78 @@
+package scheduled_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/scheduled"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestParseCronSchedule_Matches(t *testing.T) {
+	// 2024-01-01 is a Monday.
+	monday2am := time.Date(2024, time.January, 1, 2, 0, 0, 0, time.UTC)
+	cases := []struct {
+		spec       string
+		matches    []time.Time
+		notMatches []time.Time
+	}{
+		{
+			spec:       "0 2 * * *",
+			matches:    []time.Time{monday2am, monday2am.Add(24 * time.Hour)},
+			notMatches: []time.Time{monday2am.Add(time.Minute), monday2am.Add(time.Hour)},
+		},
+		{
+			spec:       "*/15 9-17 * * 1-5",
+			matches:    []time.Time{monday2am.Add(7 * time.Hour), monday2am.Add(7*time.Hour + 45*time.Minute)},
+			notMatches: []time.Time{monday2am, monday2am.Add(7*time.Hour + 10*time.Minute), monday2am.Add(5*24*time.Hour + 7*time.Hour)},
+		},
+		{
+			// Sunday is both 0 and 7.
+			spec:       "30 1 * * 7",
+			matches:    []time.Time{time.Date(2024, time.January, 7, 1, 30, 0, 0, time.UTC)},
+			notMatches: []time.Time{time.Date(2024, time.January, 6, 1, 30, 0, 0, time.UTC)},
+		},
+		{
+			// If both days are restricted either one matches.
+			spec:       "0 2 15 * 1",
+			matches:    []time.Time{monday2am, time.Date(2024, time.January, 15, 2, 0, 0, 0, time.UTC), time.Date(2024, time.February, 15, 2, 0, 0, 0, time.UTC)},
+			notMatches: []time.Time{time.Date(2024, time.January, 16, 2, 0, 0, 0, time.UTC)},
+		},
+		{
+			spec:       "0,30 0 1 1,7 *",
+			matches:    []time.Time{time.Date(2024, time.July, 1, 0, 30, 0, 0, time.UTC)},
+			notMatches: []time.Time{time.Date(2024, time.June, 1, 0, 30, 0, 0, time.UTC), time.Date(2024, time.July, 1, 0, 15, 0, 0, time.UTC)},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.spec, func(t *testing.T) {
+			schedule, err := scheduled.ParseCronSchedule(c.spec)
+			Ok(t, err)
+			for _, tm := range c.matches {
+				Assert(t, schedule.Matches(tm), "exp %q to match %s", c.spec, tm)
+			}
+			for _, tm := range c.notMatches {
+				Assert(t, !schedule.Matches(tm), "exp %q not to match %s", c.spec, tm)
+			}
+		})
+	}
+}
+
+func TestParseCronSchedule_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"0 2 * *":     `cron schedule "0 2 * *" must have 5 fields: minute, hour, day of month, month and day of week`,
+		"60 2 * * *":  `minute: "60" is out of the range 0-59`,
+		"0 2-1 * * *": `hour: "2-1" is out of the range 0-23`,
+		"0 2 0 * *":   `day of month: "0" is out of the range 1-31`,
+		"0 2 * jan *": `month: invalid value "jan"`,
+		"0 2 * * */0": `day of week: invalid step "0"`,
+		"0 2 * * mon": `day of week: invalid value "mon"`,
+		"@daily":      `cron schedule "@daily" must have 5 fields: minute, hour, day of month, month and day of week`,
+	}
+	for spec, expErr := range cases {
+		t.Run(spec, func(t *testing.T) {
+			_, err := scheduled.ParseCronSchedule(spec)
+			ErrEquals(t, expErr, err)
+		})
+	}
+}
@@ -54,8 +54,10 @@ import (
 	"github.com/runatlantis/atlantis/server/controllers/websocket"
 	"github.com/runatlantis/atlantis/server/core/locking"
 	"github.com/runatlantis/atlantis/server/core/runtime"
+	runtimemodels "github.com/runatlantis/atlantis/server/core/runtime/models"
 	"github.com/runatlantis/atlantis/server/core/runtime/policy"
 	"github.com/runatlantis/atlantis/server/core/terraform"
+	"github.com/runatlantis/atlantis/server/core/terraform/tfc"
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/command"
 	"github.com/runatlantis/atlantis/server/events/models"
@@ -77,6 +79,12 @@ const (
 	LockViewRouteIDQueryParam = "id"
 	// ProjectJobsViewRouteName is the named route in mux.Router for the log stream view.
 	ProjectJobsViewRouteName = "project-jobs-detail"
+	// PlanJSONViewRouteName is the named route in mux.Router for the JSON of
+	// plans.
+	PlanJSONViewRouteName = "plan-json-detail"
+	// PlanJSONDirName is the name of the directory inside our data dir where
+	// the JSON of plans is stored.
+	PlanJSONDirName = "plan-json"
 	// binDirName is the name of the directory inside our data dir where
 	// we download binaries.
 	BinDirName = "bin"
@@ -106,6 +114,7 @@ type Server struct {
 	StatusController               *controllers.StatusController
 	JobsController                 *controllers.JobsController
 	APIController                  *controllers.APIController
+	PlanJSONController             *controllers.PlanJSONController
 	IndexTemplate                  templates.TemplateWriter
 	LockDetailTemplate             templates.TemplateWriter
 	ProjectJobsTemplate            templates.TemplateWriter
@@ -160,18 +169,28 @@ var staticAssets embed.FS
 // for the server CLI command because it injects all the dependencies.
 func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 	logging.SuppressDefaultLogging()
-	logger, err := logging.NewStructuredLoggerFromLevel(userConfig.ToLogLevel())
+	logModuleLevels, err := userConfig.ToLogModuleLevels()
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing --log-module-levels")
+	}
+	logger, err := logging.NewStructuredLoggerFromLevels(userConfig.ToLogLevel(), logModuleLevels)
 
 	if err != nil {
 		return nil, err
 	}
+	vcsLogger := logger.WithModule(VCSLogModule)
 
 	var supportedVCSHosts []models.VCSHostType
 	var githubClient vcs.IGithubClient
+	var rawGithubClient *vcs.GithubClient
 	var githubAppEnabled bool
 	var githubConfig vcs.GithubConfig
 	var githubCredentials vcs.GithubCredentials
 	var gitlabClient *vcs.GitlabClient
+	// statusGithubClient and statusGitlabClient are set if separate tokens are
+	// used to update commit statuses.
+	var statusGithubClient vcs.IGithubClient
+	var statusGitlabClient *vcs.GitlabClient
 	var bitbucketCloudClient *bitbucketcloud.Client
 	var bitbucketServerClient *bitbucketserver.Client
 	var azuredevopsClient *vcs.AzureDevopsClient
@@ -198,6 +217,7 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 
 	globalCfg := valid.NewGlobalCfgFromArgs(
 		valid.GlobalCfgArgs{
+			RepoConfigFile:     userConfig.RepoConfigFile,
 			AllowRepoCfg:       userConfig.AllowRepoConfig,
 			MergeableReq:       userConfig.RequireMergeable,
 			ApprovedReq:        userConfig.RequireApproval,
@@ -229,10 +249,15 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 			}
 		}
 		supportedVCSHosts = append(supportedVCSHosts, models.Github)
+		githubTransport, err := vcs.ProxyConfig{URL: userConfig.GithubHTTPProxy, NoProxy: userConfig.VCSNoProxy}.Transport()
+		if err != nil {
+			return nil, errors.Wrapf(err, "setting up GitHub proxy")
+		}
 		if userConfig.GithubUser != "" {
 			githubCredentials = &vcs.GithubUserCredentials{
-				User:  userConfig.GithubUser,
-				Token: userConfig.GithubToken,
+				User:      userConfig.GithubUser,
+				Token:     userConfig.GithubToken,
+				Transport: githubTransport,
 			}
 		} else if userConfig.GithubAppID != 0 && userConfig.GithubAppKeyFile != "" {
 			privateKey, err := os.ReadFile(userConfig.GithubAppKeyFile)
@@ -240,51 +265,80 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 				return nil, err
 			}
 			githubCredentials = &vcs.GithubAppCredentials{
-				AppID:    userConfig.GithubAppID,
-				Key:      privateKey,
-				Hostname: userConfig.GithubHostname,
-				AppSlug:  userConfig.GithubAppSlug,
+				AppID:     userConfig.GithubAppID,
+				Key:       privateKey,
+				Hostname:  userConfig.GithubHostname,
+				AppSlug:   userConfig.GithubAppSlug,
+				Transport: githubTransport,
 			}
 			githubAppEnabled = true
 		} else if userConfig.GithubAppID != 0 && userConfig.GithubAppKey != "" {
 			githubCredentials = &vcs.GithubAppCredentials{
-				AppID:    userConfig.GithubAppID,
-				Key:      []byte(userConfig.GithubAppKey),
-				Hostname: userConfig.GithubHostname,
-				AppSlug:  userConfig.GithubAppSlug,
+				AppID:     userConfig.GithubAppID,
+				Key:       []byte(userConfig.GithubAppKey),
+				Hostname:  userConfig.GithubHostname,
+				AppSlug:   userConfig.GithubAppSlug,
+				Transport: githubTransport,
 			}
 			githubAppEnabled = true
 		}
 
-		var err error
-		rawGithubClient, err := vcs.NewGithubClient(userConfig.GithubHostname, githubCredentials, githubConfig, logger)
+		rawGithubClient, err = vcs.NewGithubClient(userConfig.GithubHostname, githubCredentials, githubConfig, vcsLogger)
 		if err != nil {
 			return nil, err
 		}
 
-		githubClient = vcs.NewInstrumentedGithubClient(rawGithubClient, statsScope, logger)
+		githubClient = vcs.NewInstrumentedGithubClient(rawGithubClient, statsScope, vcsLogger)
+
+		if userConfig.GithubStatusToken != "" {
+			rawStatusGithubClient, err := vcs.NewGithubClient(userConfig.GithubHostname, &vcs.GithubUserCredentials{
+				User:      userConfig.GithubUser,
+				Token:     userConfig.GithubStatusToken,
+				Transport: githubTransport,
+			}, githubConfig, vcsLogger)
+			if err != nil {
+				return nil, errors.Wrap(err, "creating GitHub status client")
+			}
+			statusGithubClient = vcs.NewInstrumentedGithubClient(rawStatusGithubClient, statsScope, vcsLogger)
+		}
 	}
 	if userConfig.GitlabUser != "" {
 		supportedVCSHosts = append(supportedVCSHosts, models.Gitlab)
-		var err error
-		gitlabClient, err = vcs.NewGitlabClient(userConfig.GitlabHostname, userConfig.GitlabToken, logger)
+		gitlabTransport, err := vcs.ProxyConfig{URL: userConfig.GitlabHTTPProxy, NoProxy: userConfig.VCSNoProxy}.Transport()
+		if err != nil {
+			return nil, errors.Wrapf(err, "setting up GitLab proxy")
+		}
+		gitlabClient, err = vcs.NewGitlabClient(userConfig.GitlabHostname, userConfig.GitlabToken, gitlabTransport, vcsLogger)
 		if err != nil {
 			return nil, err
 		}
+		if userConfig.GitlabStatusToken != "" {
+			statusGitlabClient, err = vcs.NewGitlabClient(userConfig.GitlabHostname, userConfig.GitlabStatusToken, gitlabTransport, vcsLogger)
+			if err != nil {
+				return nil, errors.Wrap(err, "creating GitLab status client")
+			}
+		}
 	}
 	if userConfig.BitbucketUser != "" {
+		bitbucketTransport, err := vcs.ProxyConfig{URL: userConfig.BitbucketHTTPProxy, NoProxy: userConfig.VCSNoProxy}.Transport()
+		if err != nil {
+			return nil, errors.Wrapf(err, "setting up Bitbucket proxy")
+		}
+		bitbucketHTTPClient := http.DefaultClient
+		if bitbucketTransport != nil {
+			bitbucketHTTPClient = &http.Client{Transport: bitbucketTransport}
+		}
 		if userConfig.BitbucketBaseURL == bitbucketcloud.BaseURL {
 			supportedVCSHosts = append(supportedVCSHosts, models.BitbucketCloud)
 			bitbucketCloudClient = bitbucketcloud.NewClient(
-				http.DefaultClient,
+				bitbucketHTTPClient,
 				userConfig.BitbucketUser,
 				userConfig.BitbucketToken,
 				userConfig.AtlantisURL)
 		} else {
 			supportedVCSHosts = append(supportedVCSHosts, models.BitbucketServer)
-			var err error
 			bitbucketServerClient, err = bitbucketserver.NewClient(
-				http.DefaultClient,
+				bitbucketHTTPClient,
 				userConfig.BitbucketUser,
 				userConfig.BitbucketToken,
 				userConfig.BitbucketBaseURL,
@@ -297,8 +351,11 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 	if userConfig.AzureDevopsUser != "" {
 		supportedVCSHosts = append(supportedVCSHosts, models.AzureDevops)
 
-		var err error
-		azuredevopsClient, err = vcs.NewAzureDevopsClient(userConfig.AzureDevOpsHostname, userConfig.AzureDevopsUser, userConfig.AzureDevopsToken)
+		azuredevopsTransport, err := vcs.ProxyConfig{URL: userConfig.AzureDevopsHTTPProxy, NoProxy: userConfig.VCSNoProxy}.Transport()
+		if err != nil {
+			return nil, errors.Wrapf(err, "setting up Azure DevOps proxy")
+		}
+		azuredevopsClient, err = vcs.NewAzureDevopsClient(userConfig.AzureDevOpsHostname, userConfig.AzureDevopsUser, userConfig.AzureDevopsToken, azuredevopsTransport)
 		if err != nil {
 			return nil, err
 		}
@@ -360,7 +417,32 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		return nil, errors.Wrap(err, "initializing webhooks")
 	}
 	vcsClient := vcs.NewClientProxy(githubClient, gitlabClient, bitbucketCloudClient, bitbucketServerClient, azuredevopsClient)
+	vcsInstances, err := newGithubInstanceClients(userConfig, githubCredentials, githubConfig, globalCfg, statsScope, vcsLogger)
+	if err != nil {
+		return nil, err
+	}
+	// Only GitHub repos can be routed to another instance.
+	instanceBaseURL := func(repo models.Repo) string {
+		if repo.VCSHost.Type != models.Github {
+			return ""
+		}
+		return globalCfg.VCSBaseURL(repo.ID())
+	}
+	vcsClient.RouteToInstances(instanceBaseURL, vcsInstances)
 	commitStatusUpdater := &events.DefaultCommitStatusUpdater{Client: vcsClient, StatusName: userConfig.VCSStatusName}
+	if statusGithubClient != nil || statusGitlabClient != nil {
+		var statusGithubProxyClient vcs.Client = githubClient
+		if statusGithubClient != nil {
+			statusGithubProxyClient = statusGithubClient
+		}
+		var statusGitlabProxyClient vcs.Client = gitlabClient
+		if statusGitlabClient != nil {
+			statusGitlabProxyClient = statusGitlabClient
+		}
+		statusClient := vcs.NewClientProxy(statusGithubProxyClient, statusGitlabProxyClient, bitbucketCloudClient, bitbucketServerClient, azuredevopsClient)
+		statusClient.RouteToInstances(instanceBaseURL, vcsInstances)
+		commitStatusUpdater.StatusClient = statusClient
+	}
 
 	binDir, err := mkSubDir(userConfig.DataDir, BinDirName)
 
@@ -369,6 +451,9 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 	}
 
 	cacheDir, err := mkSubDir(userConfig.DataDir, TerraformPluginCacheDirName)
+	if userConfig.TFPluginCacheDir != "" {
+		cacheDir, err = mkSubDir(userConfig.TFPluginCacheDir, "")
+	}
 
 	if err != nil {
 		return nil, err
@@ -386,13 +471,16 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		LockViewRouteIDQueryParam: LockViewRouteIDQueryParam,
 		LockViewRouteName:         LockViewRouteName,
 		ProjectJobsViewRouteName:  ProjectJobsViewRouteName,
+		PlanJSONViewRouteName:     PlanJSONViewRouteName,
 		Underlying:                underlyingRouter,
 	}
 
 	var projectCmdOutputHandler jobs.ProjectCommandOutputHandler
 
-	if userConfig.TFEToken != "" && !userConfig.TFELocalExecutionMode {
-		// When TFE is enabled and using remote execution mode log streaming is not necessary.
+	if userConfig.TFEToken != "" && !userConfig.TFELocalExecutionMode && !userConfig.TFEAPIRuns {
+		// When TFE is enabled and using remote execution mode log streaming is
+		// not necessary, unless runs are driven through the API which streams
+		// their logs.
 		projectCmdOutputHandler = &jobs.NoopProjectOutputHandler{}
 	} else {
 		projectCmdOutput := make(chan *jobs.ProjectCmdOutputLine)
@@ -414,6 +502,7 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		&terraform.DefaultDownloader{},
 		userConfig.TFDownload,
 		userConfig.UseTFPluginCache,
+		userConfig.TFPluginCacheDir != "",
 		projectCmdOutputHandler)
 	// The flag.Lookup call is to detect if we're running in a unit test. If we
 	// are, then we don't error out because we don't have/want terraform
@@ -421,17 +510,26 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 	if err != nil && flag.Lookup("test.v") == nil {
 		return nil, errors.Wrap(err, "initializing terraform")
 	}
-	markdownRenderer := events.NewMarkdownRenderer(
-		gitlabClient.SupportsCommonMark(),
-		userConfig.DisableApplyAll,
-		disableApply,
-		userConfig.DisableMarkdownFolding,
-		userConfig.DisableRepoLocking,
-		userConfig.EnableDiffMarkdownFormat,
-		userConfig.MarkdownTemplateOverridesDir,
-		userConfig.ExecutableName,
-		userConfig.HideUnchangedPlanComments,
-	)
+	var defaultTFVersionStr string
+	if terraformClient != nil && terraformClient.DefaultVersion() != nil {
+		defaultTFVersionStr = terraformClient.DefaultVersion().String()
+	}
+	markdownRenderer := events.NewMarkdownRenderer(events.MarkdownRendererOptions{
+		GitlabSupportsCommonMark:     gitlabClient.SupportsCommonMark(),
+		DisableApplyAll:              userConfig.DisableApplyAll,
+		DisableApply:                 disableApply,
+		DisableMarkdownFolding:       userConfig.DisableMarkdownFolding,
+		DisableRepoLocking:           userConfig.DisableRepoLocking,
+		EnableDiffMarkdownFormat:     userConfig.EnableDiffMarkdownFormat,
+		MarkdownTemplateOverridesDir: userConfig.MarkdownTemplateOverridesDir,
+		ExecutableName:               userConfig.ExecutableName,
+		HideUnchangedPlanComments:    userConfig.HideUnchangedPlanComments,
+		MinimizePlanHeaders:          userConfig.MinimizePlanHeaders,
+		PlanCommentFooter:            userConfig.PlanCommentFooter,
+		AtlantisVersion:              config.AtlantisVersion,
+		DefaultTFVersion:             defaultTFVersionStr,
+		UnauthorizedContact:          userConfig.UnauthorizedCommentContact,
+	})
 
 	var lockingClient locking.Locker
 	var applyLockingClient locking.ApplyLocker
@@ -458,10 +556,13 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		lockingClient = noOpLocker
 	} else {
 		lockingClient = locking.NewClient(backend)
+		if userConfig.LockComments {
+			lockingClient = events.NewCommentingLocker(lockingClient, vcsClient, logger)
+		}
 	}
 
 	applyLockingClient = locking.NewApplyClient(backend, disableApply)
-	workingDirLocker := events.NewDefaultWorkingDirLocker()
+	workingDirLocker := events.NewDefaultWorkingDirLockerWithScope(events.WorkingDirLockScope(userConfig.WorkingDirLockScope))
 
 	var workingDir events.WorkingDir = &events.FileWorkspace{
 		DataDir:          userConfig.DataDir,
@@ -469,6 +570,9 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		CheckoutDepth:    userConfig.CheckoutDepth,
 		GithubAppEnabled: githubAppEnabled,
 		Logger:           logger,
+		GlobalCfg:        globalCfg,
+		CloneRetries:     userConfig.GitCloneRetries,
+		CloneRetryDelay:  time.Duration(userConfig.GitCloneRetryDelay) * time.Second,
 	}
 
 	scheduledExecutorService := scheduled.NewExecutorService(
@@ -494,11 +598,16 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		}
 		scheduledExecutorService.AddJob(tokenJd)
 	}
+	if userConfig.MaxConcurrentClones > 0 {
+		workingDir = events.NewThrottledWorkingDir(workingDir, userConfig.MaxConcurrentClones, logger)
+	}
 
 	projectLocker := &events.DefaultProjectLocker{
-		Locker:     lockingClient,
-		NoOpLocker: noOpLocker,
-		VCSClient:  vcsClient,
+		Locker:           lockingClient,
+		NoOpLocker:       noOpLocker,
+		VCSClient:        vcsClient,
+		MarkdownRenderer: markdownRenderer,
+		LockURLGenerator: router,
 	}
 	deleteLockCommand := &events.DefaultDeleteLockCommand{
 		Locker:           lockingClient,
@@ -508,6 +617,34 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		Backend:          backend,
 	}
 
+	var planEncryptor *runtime.PlanEncryptor
+	if userConfig.PlanEncryptionKey != "" {
+		planEncryptor, err = runtime.NewPlanEncryptor(userConfig.PlanEncryptionKey, strings.Split(userConfig.PlanEncryptionOldKeys, ","))
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing plan encryption")
+		}
+	}
+
+	var planJSONStore events.PlanJSONStore
+	if userConfig.PlanJSON {
+		planJSONStore = &events.FilePlanJSONStore{
+			Dir:           filepath.Join(userConfig.DataDir, PlanJSONDirName),
+			PlanEncryptor: planEncryptor,
+		}
+	}
+
+	tfEnvVars, err := userConfig.ToTFEnvVars()
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing terraform env vars")
+	}
+	defaultTfVersion := terraformClient.DefaultVersion()
+	runStepRunner := &runtime.RunStepRunner{
+		TerraformExecutor:       terraformClient,
+		DefaultTFVersion:        defaultTfVersion,
+		TerraformBinDir:         terraformClient.TerraformBinDir(),
+		ProjectCmdOutputHandler: projectCmdOutputHandler,
+	}
+
 	pullClosedExecutor := events.NewInstrumentedPullClosedExecutor(
 		statsScope,
 		logger,
@@ -519,6 +656,22 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 			PullClosedTemplate:       &events.PullClosedEventTemplate{},
 			LogStreamResourceCleaner: projectCmdOutputHandler,
 			VCSClient:                vcsClient,
+			GlobalCfg:                globalCfg,
+			PlanJSONStore:            planJSONStore,
+			WorkspaceDeleter: &runtime.WorkspaceDeleter{
+				TerraformExecutor: terraformClient,
+				DefaultTFVersion:  defaultTfVersion,
+			},
+			WorkingDirLocker: workingDirLocker,
+			ParserValidator:  validator,
+			TerraformClient:  terraformClient,
+			EnvVars:          tfEnvVars,
+			EnvStepRunner: &runtime.EnvStepRunner{
+				RunStepRunner: runStepRunner,
+			},
+			MultiEnvStepRunner: &runtime.MultiEnvStepRunner{
+				RunStepRunner: runStepRunner,
+			},
 		},
 	)
 	eventParser := &events.EventParser{
@@ -541,42 +694,14 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		userConfig.ExecutableName,
 		allowCommands,
 	)
-	defaultTfVersion := terraformClient.DefaultVersion()
+	commentParser.AllowSkipStateLock = userConfig.AllowSkipStateLock
 	pendingPlanFinder := &events.DefaultPendingPlanFinder{}
-	runStepRunner := &runtime.RunStepRunner{
-		TerraformExecutor:       terraformClient,
-		DefaultTFVersion:        defaultTfVersion,
-		TerraformBinDir:         terraformClient.TerraformBinDir(),
-		ProjectCmdOutputHandler: projectCmdOutputHandler,
-	}
 	drainer := &events.Drainer{}
 	statusController := &controllers.StatusController{
 		Logger:          logger,
 		Drainer:         drainer,
 		AtlantisVersion: config.AtlantisVersion,
 	}
-	preWorkflowHooksCommandRunner := &events.DefaultPreWorkflowHooksCommandRunner{
-		VCSClient:        vcsClient,
-		GlobalCfg:        globalCfg,
-		WorkingDirLocker: workingDirLocker,
-		WorkingDir:       workingDir,
-		PreWorkflowHookRunner: runtime.DefaultPreWorkflowHookRunner{
-			OutputHandler: projectCmdOutputHandler,
-		},
-		CommitStatusUpdater: commitStatusUpdater,
-		Router:              router,
-	}
-	postWorkflowHooksCommandRunner := &events.DefaultPostWorkflowHooksCommandRunner{
-		VCSClient:        vcsClient,
-		GlobalCfg:        globalCfg,
-		WorkingDirLocker: workingDirLocker,
-		WorkingDir:       workingDir,
-		PostWorkflowHookRunner: runtime.DefaultPostWorkflowHookRunner{
-			OutputHandler: projectCmdOutputHandler,
-		},
-		CommitStatusUpdater: commitStatusUpdater,
-		Router:              router,
-	}
 	projectCommandBuilder := events.NewInstrumentedProjectCommandBuilder(
 		policyChecksEnabled,
 		validator,
@@ -597,10 +722,44 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		userConfig.RestrictFileList,
 		userConfig.SilenceNoProjects,
 		userConfig.IncludeGitUntrackedFiles,
+		userConfig.EnableAdhocWorkspaces,
 		statsScope,
 		logger,
 		terraformClient,
 	)
+	preWorkflowHooksCommandRunner := &events.DefaultPreWorkflowHooksCommandRunner{
+		VCSClient:        vcsClient,
+		GlobalCfg:        globalCfg,
+		WorkingDirLocker: workingDirLocker,
+		WorkingDir:       workingDir,
+		PreWorkflowHookRunner: runtime.DefaultPreWorkflowHookRunner{
+			OutputHandler:  projectCmdOutputHandler,
+			MaxOutputBytes: userConfig.PreWorkflowHookMaxOutputBytes,
+		},
+		CommitStatusUpdater:    commitStatusUpdater,
+		Router:                 router,
+		MaxConcurrentHooks:     userConfig.MaxConcurrentPreWorkflowHooks,
+		StatusUpdateRetries:    userConfig.PreWorkflowHookStatusRetries,
+		StatusUpdateRetryDelay: time.Duration(userConfig.PreWorkflowHookStatusRetryDelay) * time.Second,
+		MarkdownRenderer:       markdownRenderer,
+		// The hooks select the planned projects without the instrumentation
+		// so they aren't counted as builds of the command.
+		ProjectCommandBuilder: projectCommandBuilder.ProjectCommandBuilder,
+		DefaultShellArgs:      userConfig.WorkflowHookShellArgs,
+	}
+	postWorkflowHooksCommandRunner := &events.DefaultPostWorkflowHooksCommandRunner{
+		VCSClient:        vcsClient,
+		GlobalCfg:        globalCfg,
+		WorkingDirLocker: workingDirLocker,
+		WorkingDir:       workingDir,
+		PostWorkflowHookRunner: runtime.DefaultPostWorkflowHookRunner{
+			OutputHandler: projectCmdOutputHandler,
+		},
+		CommitStatusUpdater: commitStatusUpdater,
+		Router:              router,
+		MarkdownRenderer:    markdownRenderer,
+		DefaultShellArgs:    userConfig.WorkflowHookShellArgs,
+	}
 
 	showStepRunner, err := runtime.NewShowStepRunner(terraformClient, defaultTfVersion)
 
@@ -617,8 +776,50 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		return nil, errors.Wrap(err, "initializing policy check step runner")
 	}
 
+	maxPlanAge, err := userConfig.ToMaxPlanAge()
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing max plan age")
+	}
 	applyRequirementHandler := &events.DefaultCommandRequirementHandler{
-		WorkingDir: workingDir,
+		WorkingDir:             workingDir,
+		MaxPlanAge:             maxPlanAge,
+		CommitStatusGetters:    make(map[models.VCSHostType]events.CommitStatusGetter),
+		CommitSignatureGetters: make(map[models.VCSHostType]events.CommitSignatureGetter),
+		PlanReactionGetters:    make(map[models.VCSHostType]events.PlanReactionGetter),
+		GithubPlanReviews:      userConfig.GithubPlanReviews,
+	}
+	if rawGithubClient != nil {
+		applyRequirementHandler.CommitStatusGetters[models.Github] = rawGithubClient
+		applyRequirementHandler.CommitSignatureGetters[models.Github] = rawGithubClient
+		applyRequirementHandler.PlanReactionGetters[models.Github] = rawGithubClient
+	}
+	if gitlabClient != nil {
+		applyRequirementHandler.CommitStatusGetters[models.Gitlab] = gitlabClient
+		applyRequirementHandler.CommitSignatureGetters[models.Gitlab] = gitlabClient
+		applyRequirementHandler.PlanReactionGetters[models.Gitlab] = gitlabClient
+	}
+
+	initBackendArgs, err := userConfig.ToInitBackendArgs()
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing init backend args")
+	}
+
+	planStepRunner := runtime.NewPlanStepRunner(terraformClient, defaultTfVersion, commitStatusUpdater, terraformClient)
+	var applyStepRunner runtime.Runner = &runtime.ApplyStepRunner{
+		TerraformExecutor:   terraformClient,
+		DefaultTFVersion:    defaultTfVersion,
+		CommitStatusUpdater: commitStatusUpdater,
+		AsyncTFExec:         terraformClient,
+	}
+	if userConfig.TFEAPIRuns {
+		tfcRuns := &runtime.TFCRuns{
+			Client:              tfc.NewClient("https://"+userConfig.TFEHostname, userConfig.TFEToken),
+			CommitStatusUpdater: commitStatusUpdater,
+			OutputHandler:       projectCmdOutputHandler,
+			PollInterval:        runtime.DefaultTFCPollInterval,
+		}
+		planStepRunner = tfcRuns.PlanStepRunner(planStepRunner)
+		applyStepRunner = tfcRuns.ApplyStepRunner(applyStepRunner)
 	}
 
 	projectCommandRunner := &events.DefaultProjectCommandRunner{
@@ -628,17 +829,14 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		InitStepRunner: &runtime.InitStepRunner{
 			TerraformExecutor: terraformClient,
 			DefaultTFVersion:  defaultTfVersion,
+			BackendArgs:       initBackendArgs,
+			UseInitCache:      userConfig.UseTFInitCache,
 		},
-		PlanStepRunner:        runtime.NewPlanStepRunner(terraformClient, defaultTfVersion, commitStatusUpdater, terraformClient),
+		PlanStepRunner:        planStepRunner,
 		ShowStepRunner:        showStepRunner,
 		PolicyCheckStepRunner: policyCheckStepRunner,
-		ApplyStepRunner: &runtime.ApplyStepRunner{
-			TerraformExecutor:   terraformClient,
-			DefaultTFVersion:    defaultTfVersion,
-			CommitStatusUpdater: commitStatusUpdater,
-			AsyncTFExec:         terraformClient,
-		},
-		RunStepRunner: runStepRunner,
+		ApplyStepRunner:       applyStepRunner,
+		RunStepRunner:         runStepRunner,
 		EnvStepRunner: &runtime.EnvStepRunner{
 			RunStepRunner: runStepRunner,
 		},
@@ -649,23 +847,108 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 			TerraformExecutor: terraformClient,
 			DefaultTFVersion:  defaultTfVersion,
 		},
-		ImportStepRunner:          runtime.NewImportStepRunner(terraformClient, defaultTfVersion),
-		StateRmStepRunner:         runtime.NewStateRmStepRunner(terraformClient, defaultTfVersion),
+		ImportStepRunner:  runtime.NewImportStepRunner(terraformClient, defaultTfVersion),
+		StateRmStepRunner: runtime.NewStateRmStepRunner(terraformClient, defaultTfVersion),
+		ValidateStepRunner: &runtime.ValidateStepRunner{
+			TerraformExecutor: terraformClient,
+			DefaultTFVersion:  defaultTfVersion,
+		},
+		InfracostStepRunner:       &runtime.InfracostStepRunner{},
 		WorkingDir:                workingDir,
 		Webhooks:                  webhooksManager,
 		WorkingDirLocker:          workingDirLocker,
 		CommandRequirementHandler: applyRequirementHandler,
+		PlanEncryptor:             planEncryptor,
+		PlanTimeout:               time.Duration(userConfig.PlanTimeoutSeconds) * time.Second,
+		ApplyTimeout:              time.Duration(userConfig.ApplyTimeoutSeconds) * time.Second,
+		EnvVars:                   tfEnvVars,
+		TerraformParallelism:      userConfig.TFParallelism,
+		ApplyFromPlanSnapshot:     userConfig.ApplyFromPlanSnapshot,
+		PlanJSONStore:             planJSONStore,
+		PlanJSONURLGenerator:      router,
+		TerraformVersionChecker:   &runtime.TerraformVersionChecker{DefaultTFVersion: defaultTfVersion},
+	}
+	if rawGithubClient != nil {
+		projectCommandRunner.DeploymentGate = &events.GithubDeploymentGate{
+			Client:       rawGithubClient,
+			Timeout:      time.Duration(userConfig.GithubDeploymentTimeout) * time.Second,
+			PollInterval: 10 * time.Second,
+		}
+	}
+	if userConfig.EnableFmtCheck {
+		projectCommandRunner.FmtCheckStepRunner = &runtime.FmtCheckStepRunner{
+			TerraformExecutor: terraformClient,
+			DefaultTFVersion:  defaultTfVersion,
+		}
 	}
 
 	dbUpdater := &events.DBUpdater{
 		Backend: backend,
 	}
+	if userConfig.WorkingDirKeepCount > 0 || userConfig.WorkingDirKeepHours > 0 {
+		dbUpdater.WorkingDirCleaner = &events.WorkingDirCleaner{
+			DataDir:          userConfig.DataDir,
+			WorkingDir:       workingDir,
+			WorkingDirLocker: workingDirLocker,
+			Locker:           lockingClient,
+			Logger:           logger,
+			KeepCount:        userConfig.WorkingDirKeepCount,
+			KeepFor:          time.Duration(userConfig.WorkingDirKeepHours) * time.Hour,
+			KeepOnFailure:    userConfig.WorkingDirKeepOnFailure,
+		}
+		scheduledExecutorService.AddJob(scheduled.JobDefinition{
+			Job:    dbUpdater.WorkingDirCleaner,
+			Period: 10 * time.Minute,
+		})
+	}
 
 	pullUpdater := &events.PullUpdater{
 		HidePrevPlanComments: userConfig.HidePrevPlanComments,
 		VCSClient:            vcsClient,
 		MarkdownRenderer:     markdownRenderer,
 	}
+	if rawGithubClient != nil && userConfig.GithubPlanGistThreshold > 0 {
+		pullUpdater.PlanGistUploader = &events.PlanGistUploader{
+			GistCreator: rawGithubClient,
+			Threshold:   userConfig.GithubPlanGistThreshold,
+		}
+	}
+	if rawGithubClient != nil && userConfig.GithubPlanReviews {
+		pullUpdater.PlanReviewPoster = &events.PlanReviewPoster{
+			ReviewCreator:       rawGithubClient,
+			HidePrevPlanReviews: userConfig.HidePrevPlanComments,
+		}
+	}
+	var auditLogger *events.AuditLogger
+	if userConfig.AuditLogFile != "" || userConfig.AuditLogURL != "" {
+		auditLogger = &events.AuditLogger{}
+		if userConfig.AuditLogFile != "" {
+			auditLogger.Sinks = append(auditLogger.Sinks, &events.FileAuditSink{Path: userConfig.AuditLogFile})
+		}
+		if userConfig.AuditLogURL != "" {
+			auditLogger.Sinks = append(auditLogger.Sinks, events.NewHTTPAuditSink(userConfig.AuditLogURL, &http.Client{Timeout: 10 * time.Second}, logger))
+		}
+	}
+	if userConfig.EditPlanComments {
+		pullUpdater.PlanCommentEditor = &events.PlanCommentEditor{
+			Editors: make(map[models.VCSHostType]vcs.CommentEditor),
+		}
+		if rawGithubClient != nil {
+			pullUpdater.PlanCommentEditor.Editors[models.Github] = vcs.NewInstrumentedCommentEditor(rawGithubClient, statsScope.SubScope("github"), vcsLogger)
+		}
+		if gitlabClient != nil {
+			pullUpdater.PlanCommentEditor.Editors[models.Gitlab] = vcs.NewInstrumentedCommentEditor(gitlabClient, statsScope.SubScope("gitlab"), vcsLogger)
+		}
+	}
+
+	pullUpdater.ApplySummaryComments = events.ApplySummaryComments(userConfig.ApplySummaryComment)
+
+	if userConfig.CodeOwnersPlanComments != "" {
+		pullUpdater.CodeOwnersGrouper = &events.CodeOwnersGrouper{
+			WorkingDir: workingDir,
+			Comments:   events.CodeOwnersComments(userConfig.CodeOwnersPlanComments),
+		}
+	}
 
 	autoMerger := &events.AutoMerger{
 		VCSClient:       vcsClient,
@@ -712,7 +995,19 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		lockingClient,
 		userConfig.DiscardApprovalOnPlanFlag,
 		pullReqStatusFetcher,
+		userConfig.AutoplanCommentNoProjects,
 	)
+	planCommandRunner.AutoplanLabelPrefix = userConfig.AutoplanLabelPrefix
+	planCommandRunner.PolicyCheckCommentOrder = events.PolicyCheckCommentOrder(userConfig.PolicyCheckCommentOrder)
+	if userConfig.PlanRateLimit > 0 {
+		planCommandRunner.PlanRateLimiter = events.NewPlanRateLimiter(userConfig.PlanRateLimit)
+	}
+	planCommandRunner.PlanComparer = &events.PlanComparer{
+		WorkingDir:        workingDir,
+		WorkingDirLocker:  workingDirLocker,
+		TerraformExecutor: terraformClient,
+		PlanEncryptor:     planEncryptor,
+	}
 
 	applyCommandRunner := events.NewApplyCommandRunner(
 		vcsClient,
@@ -729,7 +1024,28 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		userConfig.SilenceNoProjects,
 		userConfig.SilenceVCSStatusNoProjects,
 		pullReqStatusFetcher,
+		events.NewUserAllowlistChecker(userConfig.ApplyAllowlist),
 	)
+	applyCommandRunner.GlobalCfg = globalCfg
+	applyCommandRunner.PendingApplies = events.NewPendingApplies()
+	var codeOwnersApplyFilter *events.CodeOwnersApplyFilter
+	if rawGithubClient != nil && userConfig.CodeOwnersApprovedApply {
+		codeOwnersApplyFilter = &events.CodeOwnersApplyFilter{
+			VCSClient:       vcsClient,
+			ApproversGetter: rawGithubClient,
+			PullGetter:      rawGithubClient,
+		}
+		applyCommandRunner.CodeOwnersApplyFilter = codeOwnersApplyFilter
+	}
+	if userConfig.RetryStalePlans {
+		applyCommandRunner.StalePlanRetrier = &events.StalePlanRetrier{
+			PlanCommandBuilder:  projectCommandBuilder,
+			PlanCommandRunner:   instrumentedProjectCmdRunner,
+			ApplyCommandBuilder: projectCommandBuilder,
+			ApplyCommandRunner:  instrumentedProjectCmdRunner,
+			DBUpdater:           dbUpdater,
+		}
+	}
 
 	approvePoliciesCommandRunner := events.NewApprovePoliciesCommandRunner(
 		commitStatusUpdater,
@@ -757,6 +1073,13 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		userConfig.SilenceNoProjects,
 	)
 
+	validateCommandRunner := events.NewValidateCommandRunner(
+		pullUpdater,
+		projectCommandBuilder,
+		instrumentedProjectCmdRunner,
+		userConfig.ParallelPoolSize,
+	)
+
 	importCommandRunner := events.NewImportCommandRunner(
 		pullUpdater,
 		pullReqStatusFetcher,
@@ -771,6 +1094,19 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		instrumentedProjectCmdRunner,
 	)
 
+	cancelCommandRunner := events.NewCancelCommandRunner(
+		runtimemodels.DefaultProcessTracker,
+		deleteLockCommand,
+		vcsClient,
+		userConfig.SilenceNoProjects,
+	)
+
+	confirmCommandRunner := events.NewConfirmCommandRunner(
+		applyCommandRunner.PendingApplies,
+		applyCommandRunner,
+		vcsClient,
+	)
+
 	commentCommandRunnerByCmd := map[command.Name]events.CommentCommandRunner{
 		command.Plan:            planCommandRunner,
 		command.Apply:           applyCommandRunner,
@@ -779,6 +1115,9 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		command.Version:         versionCommandRunner,
 		command.Import:          importCommandRunner,
 		command.State:           stateCommandRunner,
+		command.Cancel:          cancelCommandRunner,
+		command.Validate:        validateCommandRunner,
+		command.Confirm:         confirmCommandRunner,
 	}
 
 	githubTeamAllowlistChecker, err := events.NewTeamAllowlistChecker(userConfig.GithubTeamAllowlist)
@@ -798,7 +1137,8 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		CommentCommandRunnerByCmd:      commentCommandRunnerByCmd,
 		EventParser:                    eventParser,
 		FailOnPreWorkflowHookError:     userConfig.FailOnPreWorkflowHookError,
-		Logger:                         logger,
+		FailOnCommentError:             userConfig.FailOnCommentError,
+		Logger:                         logger.WithModule(EventsLogModule),
 		GlobalCfg:                      globalCfg,
 		StatsScope:                     statsScope.SubScope("cmd"),
 		AllowForkPRs:                   userConfig.AllowForkPRs,
@@ -814,11 +1154,23 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		TeamAllowlistChecker:           githubTeamAllowlistChecker,
 		VarFileAllowlistChecker:        varFileAllowlistChecker,
 		CommitStatusUpdater:            commitStatusUpdater,
+		MarkdownRenderer:               markdownRenderer,
+		AuditLogger:                    auditLogger,
 	}
 	repoAllowlist, err := events.NewRepoAllowlistChecker(userConfig.RepoAllowlist)
 	if err != nil {
 		return nil, err
 	}
+	scheduledReplanCron, err := userConfig.ToScheduledReplanCron()
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing scheduled re-plan cron")
+	}
+	if scheduledReplanCron != nil {
+		scheduledExecutorService.AddJob(scheduled.JobDefinition{
+			Job:    events.NewScheduledReplanner(scheduledReplanCron, userConfig.ScheduledReplanLabel, backend, vcsClient, commandRunner, repoAllowlist, logger.WithModule("events")),
+			Period: time.Minute,
+		})
+	}
 	locksController := &controllers.LocksController{
 		AtlantisVersion:    config.AtlantisVersion,
 		AtlantisURL:        parsedURL,
@@ -851,8 +1203,19 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		KeyGenerator:             controllers.JobIDKeyGenerator{},
 		StatsScope:               statsScope.SubScope("api"),
 	}
+	var planJSONController *controllers.PlanJSONController
+	if planJSONStore != nil {
+		planJSONController = &controllers.PlanJSONController{
+			Logger:            logger,
+			Store:             planJSONStore,
+			APISecret:         []byte(userConfig.APISecret),
+			WebAuthentication: userConfig.WebBasicAuth,
+		}
+	}
+
 	apiController := &controllers.APIController{
 		APISecret:                 []byte(userConfig.APISecret),
+		ApplyLocker:               applyLockingClient,
 		Locker:                    lockingClient,
 		Logger:                    logger,
 		Parser:                    eventParser,
@@ -862,8 +1225,35 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		RepoAllowlistChecker:      repoAllowlist,
 		Scope:                     statsScope.SubScope("api"),
 		VCSClient:                 vcsClient,
+		CodeOwnersApplyFilter:     codeOwnersApplyFilter,
+		AuditLogger:               auditLogger,
+	}
+
+	var autoplanDebouncer *events_controllers.AutoplanDebouncer
+	if userConfig.AutoplanDebounceSeconds > 0 {
+		autoplanDebouncer = events_controllers.NewAutoplanDebouncer(
+			time.Duration(userConfig.AutoplanDebounceSeconds)*time.Second,
+			func(baseRepo models.Repo, pullNum int) {
+				// Only the superseded autoplan's plans are canceled, plans and
+				// applies run by comments or the API keep running.
+				if _, err := runtimemodels.DefaultProcessTracker.Cancel(runtimemodels.ProcessFilter{
+					RepoFullName: baseRepo.FullName,
+					PullNum:      pullNum,
+					CommandNames: []command.Name{command.Plan},
+					Autoplan:     true,
+				}); err != nil {
+					logger.Warn("failed to cancel running plans for superseded autoplan: %s", err)
+				}
+			},
+		)
+	}
+	var webhookRecorder *events_controllers.WebhookRecorder
+	if userConfig.WebhookCaptureDir != "" {
+		webhookRecorder, err = events_controllers.NewWebhookRecorder(userConfig.WebhookCaptureDir, userConfig.WebhookCaptureMaxCount)
+		if err != nil {
+			return nil, err
+		}
 	}
-
 	eventsController := &events_controllers.VCSEventsController{
 		CommandRunner:                   commandRunner,
 		PullCleaner:                     pullClosedExecutor,
@@ -886,6 +1276,10 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		AzureDevopsWebhookBasicUser:     []byte(userConfig.AzureDevopsWebhookUser),
 		AzureDevopsWebhookBasicPassword: []byte(userConfig.AzureDevopsWebhookPassword),
 		AzureDevopsRequestValidator:     &events_controllers.DefaultAzureDevopsRequestValidator{},
+		DeliveryDeduplicator:            events_controllers.NewDeliveryDeduplicator(events_controllers.DefaultDeliveryDedupTTL),
+		AutoplanDebouncer:               autoplanDebouncer,
+		WebhookRecorder:                 webhookRecorder,
+		APISecret:                       []byte(userConfig.APISecret),
 	}
 	githubAppController := &controllers.GithubAppController{
 		AtlantisURL:         parsedURL,
@@ -915,6 +1309,7 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		JobsController:                 jobsController,
 		StatusController:               statusController,
 		APIController:                  apiController,
+		PlanJSONController:             planJSONController,
 		IndexTemplate:                  templates.IndexTemplate,
 		LockDetailTemplate:             templates.LockTemplate,
 		ProjectJobsTemplate:            templates.ProjectJobsTemplate,
@@ -939,8 +1334,11 @@ func (s *Server) Start() error {
 	s.Router.HandleFunc("/status", s.StatusController.Get).Methods("GET")
 	s.Router.PathPrefix("/static/").Handler(http.FileServer(http.FS(staticAssets)))
 	s.Router.HandleFunc("/events", s.VCSEventsController.Post).Methods("POST")
+	s.Router.HandleFunc("/events/replay", s.VCSEventsController.Replay).Methods("POST")
 	s.Router.HandleFunc("/api/plan", s.APIController.Plan).Methods("POST")
 	s.Router.HandleFunc("/api/apply", s.APIController.Apply).Methods("POST")
+	s.Router.HandleFunc("/api/apply/lock", s.APIController.LockApply).Methods("POST")
+	s.Router.HandleFunc("/api/apply/unlock", s.APIController.UnlockApply).Methods("DELETE")
 	s.Router.HandleFunc("/github-app/exchange-code", s.GithubAppController.ExchangeCode).Methods("GET")
 	s.Router.HandleFunc("/github-app/setup", s.GithubAppController.New).Methods("GET")
 	s.Router.HandleFunc("/apply/lock", s.LocksController.LockApply).Methods("POST").Queries()
@@ -950,6 +1348,9 @@ func (s *Server) Start() error {
 		Queries(LockViewRouteIDQueryParam, fmt.Sprintf("{%s}", LockViewRouteIDQueryParam)).Name(LockViewRouteName)
 	s.Router.HandleFunc("/jobs/{job-id}", s.JobsController.GetProjectJobs).Methods("GET").Name(ProjectJobsViewRouteName)
 	s.Router.HandleFunc("/jobs/{job-id}/ws", s.JobsController.GetProjectJobsWS).Methods("GET")
+	if s.PlanJSONController != nil {
+		s.Router.HandleFunc("/plan-json/{id}", s.PlanJSONController.Get).Methods("GET").Name(PlanJSONViewRouteName)
+	}
 
 	r, ok := s.StatsReporter.(prometheus.Reporter)
 	if ok {
@@ -1083,6 +1484,33 @@ func (s *Server) Index(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
+// newGithubInstanceClients returns the clients for the GitHub Enterprise
+// instances in --gh-alternate-base-urls by base URL. It errors if a repo in
+// globalCfg sets a vcs_base_url that isn't one of them.
+func newGithubInstanceClients(userConfig UserConfig, credentials vcs.GithubCredentials, config vcs.GithubConfig, globalCfg valid.GlobalCfg, statsScope tally.Scope, logger logging.SimpleLogging) (map[string]vcs.Client, error) {
+	instances := make(map[string]vcs.Client)
+	if userConfig.GithubAlternateBaseURLs != "" {
+		for _, baseURL := range strings.Split(userConfig.GithubAlternateBaseURLs, ",") {
+			baseURL = strings.TrimSuffix(strings.TrimSpace(baseURL), "/")
+			parsed, err := url.Parse(baseURL)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parsing GitHub base URL %q", baseURL)
+			}
+			client, err := vcs.NewGithubClient(parsed.Host, credentials, config, logger)
+			if err != nil {
+				return nil, errors.Wrapf(err, "creating GitHub client for %s", baseURL)
+			}
+			instances[baseURL] = vcs.NewInstrumentedGithubClient(client, statsScope, logger)
+		}
+	}
+	for _, repo := range globalCfg.Repos {
+		if _, ok := instances[repo.VCSBaseURL]; repo.VCSBaseURL != "" && !ok {
+			return nil, fmt.Errorf("vcs_base_url %q of repo %q isn't one of the GitHub base URLs Atlantis was configured with", repo.VCSBaseURL, repo.IDString())
+		}
+	}
+	return instances, nil
+}
+
 func mkSubDir(parentDir string, subDir string) (string, error) {
 	fullDir := filepath.Join(parentDir, subDir)
 	if err := os.MkdirAll(fullDir, 0700); err != nil {
@@ -1138,6 +1566,11 @@ func ParseAtlantisURL(u string) (*url.URL, error) {
 	if !(parsed.Scheme == "http" || parsed.Scheme == "https") {
 		return nil, errors.New("http or https must be specified")
 	}
+	// The paths of generated URLs are appended to this URL so it can't have
+	// a query or fragment.
+	if parsed.RawQuery != "" || parsed.Fragment != "" {
+		return nil, errors.New("query and fragment must not be specified")
+	}
 	// We want the path to end without a trailing slash so we know how to
 	// use it in the rest of the program.
 	parsed.Path = strings.TrimSuffix(parsed.Path, "/")
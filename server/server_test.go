@@ -253,6 +253,16 @@ func TestParseAtlantisURL(t *testing.T) {
 			In:     "http0://localhost/test",
 			ExpErr: "http or https must be specified",
 		},
+
+		// Generated URLs are appended to the path.
+		{
+			In:     "http://example.com/baseurl?query=1",
+			ExpErr: "query and fragment must not be specified",
+		},
+		{
+			In:     "http://example.com/baseurl#fragment",
+			ExpErr: "query and fragment must not be specified",
+		},
 	}
 
 	for _, c := range cases {
@@ -1,10 +1,15 @@
 package server
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/runatlantis/atlantis/server/events/command"
 	"github.com/runatlantis/atlantis/server/logging"
+	"github.com/runatlantis/atlantis/server/scheduled"
 )
 
 // UserConfig holds config values passed in by the user.
@@ -13,10 +18,20 @@ import (
 type UserConfig struct {
 	AllowForkPRs                bool   `mapstructure:"allow-fork-prs"`
 	AllowRepoConfig             bool   `mapstructure:"allow-repo-config"`
+	AllowSkipStateLock          bool   `mapstructure:"allow-skip-state-lock"`
 	AllowCommands               string `mapstructure:"allow-commands"`
+	ApplyAllowlist              string `mapstructure:"apply-allowlist"`
+	ApplyFromPlanSnapshot       bool   `mapstructure:"apply-from-plan-snapshot"`
+	ApplySummaryComment         string `mapstructure:"apply-summary-comment"`
+	ApplyTimeoutSeconds         int    `mapstructure:"apply-timeout-seconds"`
 	AtlantisURL                 string `mapstructure:"atlantis-url"`
+	AuditLogFile                string `mapstructure:"audit-log-file"`
+	AuditLogURL                 string `mapstructure:"audit-log-url"`
 	Automerge                   bool   `mapstructure:"automerge"`
 	AutoplanFileList            string `mapstructure:"autoplan-file-list"`
+	AutoplanCommentNoProjects   bool   `mapstructure:"autoplan-comment-no-projects"`
+	AutoplanDebounceSeconds     int    `mapstructure:"autoplan-debounce-seconds"`
+	AutoplanLabelPrefix         string `mapstructure:"autoplan-label-prefix"`
 	AutoplanModules             bool   `mapstructure:"autoplan-modules"`
 	AutoplanModulesFromProjects string `mapstructure:"autoplan-modules-from-projects"`
 	AzureDevopsToken            string `mapstructure:"azuredevops-token"`
@@ -24,12 +39,16 @@ type UserConfig struct {
 	AzureDevopsWebhookPassword  string `mapstructure:"azuredevops-webhook-password"`
 	AzureDevopsWebhookUser      string `mapstructure:"azuredevops-webhook-user"`
 	AzureDevOpsHostname         string `mapstructure:"azuredevops-hostname"`
+	AzureDevopsHTTPProxy        string `mapstructure:"azuredevops-http-proxy"`
 	BitbucketBaseURL            string `mapstructure:"bitbucket-base-url"`
+	BitbucketHTTPProxy          string `mapstructure:"bitbucket-http-proxy"`
 	BitbucketToken              string `mapstructure:"bitbucket-token"`
 	BitbucketUser               string `mapstructure:"bitbucket-user"`
 	BitbucketWebhookSecret      string `mapstructure:"bitbucket-webhook-secret"`
 	CheckoutDepth               int    `mapstructure:"checkout-depth"`
 	CheckoutStrategy            string `mapstructure:"checkout-strategy"`
+	CodeOwnersApprovedApply     bool   `mapstructure:"codeowners-approved-apply"`
+	CodeOwnersPlanComments      string `mapstructure:"codeowners-plan-comments"`
 	DataDir                     string `mapstructure:"data-dir"`
 	DisableApplyAll             bool   `mapstructure:"disable-apply-all"`
 	DisableApply                bool   `mapstructure:"disable-apply"`
@@ -39,38 +58,70 @@ type UserConfig struct {
 	DisableRepoLocking          bool   `mapstructure:"disable-repo-locking"`
 	DisableUnlockLabel          string `mapstructure:"disable-unlock-label"`
 	DiscardApprovalOnPlanFlag   bool   `mapstructure:"discard-approval-on-plan"`
+	EditPlanComments            bool   `mapstructure:"edit-plan-comments"`
 	EmojiReaction               string `mapstructure:"emoji-reaction"`
+	EnableAdhocWorkspaces       bool   `mapstructure:"enable-adhoc-workspaces"`
+	EnableFmtCheck              bool   `mapstructure:"enable-fmt-check"`
 	EnablePolicyChecksFlag      bool   `mapstructure:"enable-policy-checks"`
 	EnableRegExpCmd             bool   `mapstructure:"enable-regexp-cmd"`
 	EnableDiffMarkdownFormat    bool   `mapstructure:"enable-diff-markdown-format"`
 	ExecutableName              string `mapstructure:"executable-name"`
+	// Fail the commit status of a command if its results can't be commented.
+	FailOnCommentError bool `mapstructure:"fail-on-comment-error"`
 	// Fail and do not run the Atlantis command request if any of the pre workflow hooks error.
 	FailOnPreWorkflowHookError      bool   `mapstructure:"fail-on-pre-workflow-hook-error"`
 	HideUnchangedPlanComments       bool   `mapstructure:"hide-unchanged-plan-comments"`
 	GithubAllowMergeableBypassApply bool   `mapstructure:"gh-allow-mergeable-bypass-apply"`
+	GithubAlternateBaseURLs         string `mapstructure:"gh-alternate-base-urls"`
+	GithubDeploymentTimeout         int    `mapstructure:"gh-deployment-timeout-seconds"`
 	GithubHostname                  string `mapstructure:"gh-hostname"`
+	GithubHTTPProxy                 string `mapstructure:"gh-http-proxy"`
+	GithubStatusToken               string `mapstructure:"gh-status-token"`
 	GithubToken                     string `mapstructure:"gh-token"`
 	GithubUser                      string `mapstructure:"gh-user"`
 	GithubWebhookSecret             string `mapstructure:"gh-webhook-secret"`
 	GithubOrg                       string `mapstructure:"gh-org"`
+	GithubPlanGistThreshold         int    `mapstructure:"gh-plan-gist-threshold"`
+	GithubPlanReviews               bool   `mapstructure:"gh-plan-reviews"`
 	GithubAppID                     int64  `mapstructure:"gh-app-id"`
 	GithubAppKey                    string `mapstructure:"gh-app-key"`
 	GithubAppKeyFile                string `mapstructure:"gh-app-key-file"`
 	GithubAppSlug                   string `mapstructure:"gh-app-slug"`
 	GithubTeamAllowlist             string `mapstructure:"gh-team-allowlist"`
+	GitCloneRetries                 int    `mapstructure:"git-clone-retries"`
+	GitCloneRetryDelay              int    `mapstructure:"git-clone-retry-delay-seconds"`
 	GitlabHostname                  string `mapstructure:"gitlab-hostname"`
+	GitlabHTTPProxy                 string `mapstructure:"gitlab-http-proxy"`
+	GitlabStatusToken               string `mapstructure:"gitlab-status-token"`
 	GitlabToken                     string `mapstructure:"gitlab-token"`
 	GitlabUser                      string `mapstructure:"gitlab-user"`
 	GitlabWebhookSecret             string `mapstructure:"gitlab-webhook-secret"`
 	IncludeGitUntrackedFiles        bool   `mapstructure:"include-git-untracked-files"`
+	InitBackendArgs                 string `mapstructure:"init-backend-args"`
 	APISecret                       string `mapstructure:"api-secret"`
 	HidePrevPlanComments            bool   `mapstructure:"hide-prev-plan-comments"`
+	LockComments                    bool   `mapstructure:"lock-comments"`
 	LockingDBType                   string `mapstructure:"locking-db-type"`
 	LogLevel                        string `mapstructure:"log-level"`
+	LogModuleLevels                 string `mapstructure:"log-module-levels"`
 	MarkdownTemplateOverridesDir    string `mapstructure:"markdown-template-overrides-dir"`
+	MaxConcurrentClones             int    `mapstructure:"max-concurrent-clones"`
+	MaxConcurrentPreWorkflowHooks   int    `mapstructure:"max-concurrent-pre-workflow-hooks"`
+	MaxPlanAge                      string `mapstructure:"max-plan-age"`
+	MinimizePlanHeaders             bool   `mapstructure:"minimize-plan-headers"`
 	ParallelPoolSize                int    `mapstructure:"parallel-pool-size"`
+	PreWorkflowHookMaxOutputBytes   int    `mapstructure:"pre-workflow-hook-max-output-bytes"`
+	PreWorkflowHookStatusRetries    int    `mapstructure:"pre-workflow-hook-status-retries"`
+	PreWorkflowHookStatusRetryDelay int    `mapstructure:"pre-workflow-hook-status-retry-delay-seconds"`
 	ParallelPlan                    bool   `mapstructure:"parallel-plan"`
 	ParallelApply                   bool   `mapstructure:"parallel-apply"`
+	PlanEncryptionKey               string `mapstructure:"plan-encryption-key"`
+	PlanEncryptionOldKeys           string `mapstructure:"plan-encryption-old-keys"`
+	PlanCommentFooter               bool   `mapstructure:"plan-comment-footer"`
+	PlanJSON                        bool   `mapstructure:"plan-json"`
+	PlanRateLimit                   int    `mapstructure:"plan-rate-limit"`
+	PlanTimeoutSeconds              int    `mapstructure:"plan-timeout-seconds"`
+	PolicyCheckCommentOrder         string `mapstructure:"policy-check-comment-order"`
 	StatsNamespace                  string `mapstructure:"stats-namespace"`
 	PlanDrafts                      bool   `mapstructure:"allow-draft-prs"`
 	Port                            int    `mapstructure:"port"`
@@ -83,6 +134,7 @@ type UserConfig struct {
 	RedisInsecureSkipVerify         bool   `mapstructure:"redis-insecure-skip-verify"`
 	RepoConfig                      string `mapstructure:"repo-config"`
 	RepoConfigJSON                  string `mapstructure:"repo-config-json"`
+	RepoConfigFile                  string `mapstructure:"repo-config-file"`
 	RepoAllowlist                   string `mapstructure:"repo-allowlist"`
 	// RepoWhitelist is deprecated in favour of RepoAllowlist.
 	RepoWhitelist string `mapstructure:"repo-whitelist"`
@@ -111,21 +163,38 @@ type UserConfig struct {
 	SSLCertFile                string          `mapstructure:"ssl-cert-file"`
 	SSLKeyFile                 string          `mapstructure:"ssl-key-file"`
 	RestrictFileList           bool            `mapstructure:"restrict-file-list"`
+	RetryStalePlans            bool            `mapstructure:"retry-stale-plans"`
+	ScheduledReplanCron        string          `mapstructure:"scheduled-replan-cron"`
+	ScheduledReplanLabel       string          `mapstructure:"scheduled-replan-label"`
 	TFDownload                 bool            `mapstructure:"tf-download"`
 	TFDownloadURL              string          `mapstructure:"tf-download-url"`
+	TFEnvVars                  string          `mapstructure:"tf-env-vars"`
+	TFParallelism              int             `mapstructure:"tf-parallelism"`
+	TFPluginCacheDir           string          `mapstructure:"tf-plugin-cache-dir"`
+	TFEAPIRuns                 bool            `mapstructure:"tfe-api-runs"`
 	TFEHostname                string          `mapstructure:"tfe-hostname"`
 	TFELocalExecutionMode      bool            `mapstructure:"tfe-local-execution-mode"`
 	TFEToken                   string          `mapstructure:"tfe-token"`
+	UnauthorizedCommentContact string          `mapstructure:"unauthorized-comment-contact"`
 	VarFileAllowlist           string          `mapstructure:"var-file-allowlist"`
+	VCSNoProxy                 string          `mapstructure:"vcs-no-proxy"`
 	VCSStatusName              string          `mapstructure:"vcs-status-name"`
 	DefaultTFVersion           string          `mapstructure:"default-tf-version"`
 	Webhooks                   []WebhookConfig `mapstructure:"webhooks"`
 	WebBasicAuth               bool            `mapstructure:"web-basic-auth"`
 	WebUsername                string          `mapstructure:"web-username"`
 	WebPassword                string          `mapstructure:"web-password"`
+	WebhookCaptureDir          string          `mapstructure:"webhook-capture-dir"`
+	WebhookCaptureMaxCount     int             `mapstructure:"webhook-capture-max-count"`
 	WriteGitCreds              bool            `mapstructure:"write-git-creds"`
 	WebsocketCheckOrigin       bool            `mapstructure:"websocket-check-origin"`
+	WorkingDirLockScope        string          `mapstructure:"working-dir-lock-scope"`
+	WorkingDirKeepCount        int             `mapstructure:"working-dir-keep-count"`
+	WorkingDirKeepHours        int             `mapstructure:"working-dir-keep-hours"`
+	WorkingDirKeepOnFailure    bool            `mapstructure:"working-dir-keep-on-failure"`
+	WorkflowHookShellArgs      string          `mapstructure:"workflow-hook-shell-args"`
 	UseTFPluginCache           bool            `mapstructure:"use-tf-plugin-cache"`
+	UseTFInitCache             bool            `mapstructure:"use-tf-init-cache"`
 }
 
 // ToAllowCommandNames parse AllowCommands into a slice of CommandName
@@ -152,18 +221,106 @@ func (u UserConfig) ToAllowCommandNames() ([]command.Name, error) {
 	return allowCommands, nil
 }
 
+// ToInitBackendArgs parses InitBackendArgs into a map from a backend type to
+// the args to add to terraform init for that backend.
+func (u UserConfig) ToInitBackendArgs() (map[string][]string, error) {
+	if u.InitBackendArgs == "" {
+		return nil, nil
+	}
+	var backendArgs map[string][]string
+	if err := json.Unmarshal([]byte(u.InitBackendArgs), &backendArgs); err != nil {
+		return nil, fmt.Errorf("must be a JSON object of backend types to lists of args: %w", err)
+	}
+	return backendArgs, nil
+}
+
+// ToMaxPlanAge parses MaxPlanAge. It returns 0 if plans don't have a max age.
+func (u UserConfig) ToMaxPlanAge() (time.Duration, error) {
+	if u.MaxPlanAge == "" {
+		return 0, nil
+	}
+	maxPlanAge, err := time.ParseDuration(u.MaxPlanAge)
+	if err != nil {
+		return 0, fmt.Errorf("must be a duration, ex. '24h': %w", err)
+	}
+	if maxPlanAge <= 0 {
+		return 0, errors.New("must be positive")
+	}
+	return maxPlanAge, nil
+}
+
+// ToScheduledReplanCron parses ScheduledReplanCron. It returns nil if pull
+// requests aren't re-planned on a schedule.
+func (u UserConfig) ToScheduledReplanCron() (*scheduled.CronSchedule, error) {
+	if u.ScheduledReplanCron == "" {
+		return nil, nil
+	}
+	return scheduled.ParseCronSchedule(u.ScheduledReplanCron)
+}
+
+// ToTFEnvVars parses TFEnvVars into a map from an environment variable's name
+// to its value.
+func (u UserConfig) ToTFEnvVars() (map[string]string, error) {
+	if u.TFEnvVars == "" {
+		return nil, nil
+	}
+	var envVars map[string]string
+	if err := json.Unmarshal([]byte(u.TFEnvVars), &envVars); err != nil {
+		return nil, fmt.Errorf("must be a JSON object of environment variable names to string values: %w", err)
+	}
+	return envVars, nil
+}
+
 // ToLogLevel returns the LogLevel object corresponding to the user-passed
 // log level.
 func (u UserConfig) ToLogLevel() logging.LogLevel {
-	switch u.LogLevel {
+	if lvl, ok := toLogLevel(u.LogLevel); ok {
+		return lvl
+	}
+	return logging.Info
+}
+
+// The modules that can be given their own log level with LogModuleLevels.
+const (
+	EventsLogModule = "events"
+	VCSLogModule    = "vcs"
+)
+
+// ToLogModuleLevels parses LogModuleLevels, a comma-separated list of
+// module=level pairs, into a map from a module's name to its log level.
+func (u UserConfig) ToLogModuleLevels() (map[string]logging.LogLevel, error) {
+	if u.LogModuleLevels == "" {
+		return nil, nil
+	}
+	levels := make(map[string]logging.LogLevel)
+	for _, pair := range strings.Split(u.LogModuleLevels, ",") {
+		module, lvl, found := strings.Cut(pair, "=")
+		module = strings.TrimSpace(module)
+		if !found || module == "" {
+			return nil, fmt.Errorf("%q must be of the form module=level", pair)
+		}
+		if module != EventsLogModule && module != VCSLogModule {
+			return nil, fmt.Errorf("unknown module %q: must be one of %s or %s", module, EventsLogModule, VCSLogModule)
+		}
+		level, ok := toLogLevel(strings.ToLower(strings.TrimSpace(lvl)))
+		if !ok {
+			return nil, fmt.Errorf("invalid log level %q for module %q: must be one of debug, info, warn or error", lvl, module)
+		}
+		levels[module] = level
+	}
+	return levels, nil
+}
+
+func toLogLevel(lvl string) (logging.LogLevel, bool) {
+	switch lvl {
 	case "debug":
-		return logging.Debug
+		return logging.Debug, true
 	case "info":
-		return logging.Info
+		return logging.Info, true
 	case "warn":
-		return logging.Warn
+		return logging.Warn, true
 	case "error":
-		return logging.Error
+		return logging.Error, true
 	}
-	return logging.Info
+	return logging.Info, false
 }
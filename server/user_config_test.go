@@ -2,6 +2,7 @@ package server_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/runatlantis/atlantis/server"
 	"github.com/runatlantis/atlantis/server/events/command"
@@ -28,14 +29,14 @@ func TestUserConfig_ToAllowCommandNames(t *testing.T) {
 			name:          "all",
 			allowCommands: "all",
 			want: []command.Name{
-				command.Version, command.Plan, command.Apply, command.Unlock, command.ApprovePolicies, command.Import, command.State,
+				command.Version, command.Plan, command.Apply, command.Unlock, command.ApprovePolicies, command.Import, command.State, command.Cancel, command.Validate, command.Confirm,
 			},
 		},
 		{
 			name:          "all with others returns same with all result",
 			allowCommands: "all,plan",
 			want: []command.Name{
-				command.Version, command.Plan, command.Apply, command.Unlock, command.ApprovePolicies, command.Import, command.State,
+				command.Version, command.Plan, command.Apply, command.Unlock, command.ApprovePolicies, command.Import, command.State, command.Cancel, command.Validate, command.Confirm,
 			},
 		},
 		{
@@ -68,6 +69,147 @@ func TestUserConfig_ToAllowCommandNames(t *testing.T) {
 	}
 }
 
+func TestUserConfig_ToInitBackendArgs(t *testing.T) {
+	tests := []struct {
+		name            string
+		initBackendArgs string
+		want            map[string][]string
+		wantErr         string
+	}{
+		{
+			name:            "empty",
+			initBackendArgs: "",
+			want:            nil,
+		},
+		{
+			name:            "args per backend",
+			initBackendArgs: `{"s3": ["-reconfigure", "-backend-config=s3.hcl"], "gcs": ["-backend-config=gcs.hcl"]}`,
+			want: map[string][]string{
+				"s3":  {"-reconfigure", "-backend-config=s3.hcl"},
+				"gcs": {"-backend-config=gcs.hcl"},
+			},
+		},
+		{
+			name:            "not a map of lists",
+			initBackendArgs: `{"s3": "-reconfigure"}`,
+			wantErr:         "must be a JSON object of backend types to lists of args",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := server.UserConfig{
+				InitBackendArgs: tt.initBackendArgs,
+			}
+			got, err := u.ToInitBackendArgs()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr, "ToInitBackendArgs()")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equalf(t, tt.want, got, "ToInitBackendArgs()")
+		})
+	}
+}
+
+func TestUserConfig_ToTFEnvVars(t *testing.T) {
+	tests := []struct {
+		name      string
+		tfEnvVars string
+		want      map[string]string
+		wantErr   string
+	}{
+		{
+			name:      "empty",
+			tfEnvVars: "",
+			want:      nil,
+		},
+		{
+			name:      "env vars",
+			tfEnvVars: `{"TF_IN_AUTOMATION": "1", "HTTPS_PROXY": "http://proxy:3128"}`,
+			want:      map[string]string{"TF_IN_AUTOMATION": "1", "HTTPS_PROXY": "http://proxy:3128"},
+		},
+		{
+			name:      "non-string value",
+			tfEnvVars: `{"TF_IN_AUTOMATION": 1}`,
+			wantErr:   "must be a JSON object of environment variable names to string values",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := server.UserConfig{
+				TFEnvVars: tt.tfEnvVars,
+			}
+			got, err := u.ToTFEnvVars()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr, "ToTFEnvVars()")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equalf(t, tt.want, got, "ToTFEnvVars()")
+		})
+	}
+}
+
+func TestUserConfig_ToMaxPlanAge(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxPlanAge string
+		want       time.Duration
+		wantErr    string
+	}{
+		{
+			name:       "empty",
+			maxPlanAge: "",
+			want:       0,
+		},
+		{
+			name:       "duration",
+			maxPlanAge: "1h30m",
+			want:       90 * time.Minute,
+		},
+		{
+			name:       "not a duration",
+			maxPlanAge: "2 days",
+			wantErr:    "must be a duration, ex. '24h'",
+		},
+		{
+			name:       "not positive",
+			maxPlanAge: "-1h",
+			wantErr:    "must be positive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := server.UserConfig{
+				MaxPlanAge: tt.maxPlanAge,
+			}
+			got, err := u.ToMaxPlanAge()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr, "ToMaxPlanAge()")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equalf(t, tt.want, got, "ToMaxPlanAge()")
+		})
+	}
+}
+
+func TestUserConfig_ToScheduledReplanCron(t *testing.T) {
+	u := server.UserConfig{}
+	schedule, err := u.ToScheduledReplanCron()
+	assert.NoError(t, err)
+	assert.Nil(t, schedule)
+
+	u.ScheduledReplanCron = "0 2 * * *"
+	schedule, err = u.ToScheduledReplanCron()
+	assert.NoError(t, err)
+	assert.True(t, schedule.Matches(time.Date(2024, 1, 1, 2, 0, 0, 0, time.Local)))
+
+	u.ScheduledReplanCron = "nightly"
+	_, err = u.ToScheduledReplanCron()
+	assert.ErrorContains(t, err, "must have 5 fields")
+}
+
 func TestUserConfig_ToLogLevel(t *testing.T) {
 	cases := []struct {
 		userLvl string
@@ -104,3 +246,59 @@ func TestUserConfig_ToLogLevel(t *testing.T) {
 		})
 	}
 }
+
+func TestUserConfig_ToLogModuleLevels(t *testing.T) {
+	cases := []struct {
+		levels    string
+		expLevels map[string]logging.LogLevel
+		expErr    string
+	}{
+		{
+			levels: "",
+		},
+		{
+			levels: "events=debug",
+			expLevels: map[string]logging.LogLevel{
+				"events": logging.Debug,
+			},
+		},
+		{
+			levels: "events=DEBUG, vcs = warn",
+			expLevels: map[string]logging.LogLevel{
+				"events": logging.Debug,
+				"vcs":    logging.Warn,
+			},
+		},
+		{
+			levels: "events",
+			expErr: `"events" must be of the form module=level`,
+		},
+		{
+			levels: "=debug",
+			expErr: `"=debug" must be of the form module=level`,
+		},
+		{
+			levels: "events=verbose",
+			expErr: `invalid log level "verbose" for module "events": must be one of debug, info, warn or error`,
+		},
+		{
+			levels: "events=debug,locking=debug",
+			expErr: `unknown module "locking": must be one of events or vcs`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.levels, func(t *testing.T) {
+			u := server.UserConfig{
+				LogModuleLevels: c.levels,
+			}
+			levels, err := u.ToLogModuleLevels()
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.expLevels, levels)
+		})
+	}
+}